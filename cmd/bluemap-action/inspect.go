@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/analyzer"
+	"github.com/EfinaServer/bluemap-action/internal/config"
+	"github.com/EfinaServer/bluemap-action/internal/extractor"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
+)
+
+// runInspectBackup implements the inspect-backup subcommand: it lists the
+// top-level folders and world candidates of a backup so world_name or
+// [worlds.<name>] can be filled in for an unfamiliar server.
+func runInspectBackup(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("inspect-backup", flag.ExitOnError)
+	serverDir := fs.String("dir", ".", "server directory whose config.toml provides server_id")
+	serverID := fs.String("server", "", "panel server ID (overrides server_id in config.toml)")
+	panelType := fs.String("panel", "", "panel type, \"pterodactyl\", \"pufferpanel\", \"crafty\" or \"amp\" (default panel_type in config.toml, or pterodactyl with -server)")
+	backupUUID := fs.String("backup", "", "backup UUID to inspect (default the latest successful backup)")
+	fs.Usage = usageFor(fs, "inspect-backup")
+	fs.Parse(args)
+
+	id, kind := *serverID, *panelType
+	ref, err := config.ReadPanel(*serverDir)
+	if id == "" {
+		if err != nil {
+			log.Printf("💥  %v (or pass -server)", err)
+			exit(1)
+		}
+		id = ref.ServerID
+		if kind == "" {
+			kind = ref.PanelType
+		}
+	}
+	if err := proxy.Apply(ref.ProxyURL); err != nil {
+		log.Printf("💥  proxy_url: %v", err)
+		exit(1)
+	}
+
+	client := panelClient(kind, false)
+
+	var backup *panel.Backup
+	if *backupUUID != "" {
+		backup, err = panel.FindBackup(ctx, client, id, *backupUUID)
+	} else {
+		backup, err = panel.LatestBackup(ctx, client, id)
+	}
+	if err != nil {
+		fatalf(ctx, "💥  error getting backup: %v", err)
+	}
+	fmt.Printf("💾  Backup: %s (%s, %s)\n", backup.Name, backup.UUID, analyzer.FormatSize(backup.Bytes))
+
+	dl, err := client.GetDownloadURL(ctx, id, backup.UUID)
+	if err != nil {
+		fatalf(ctx, "💥  error getting download URL: %v", err)
+	}
+
+	fmt.Println("🔎  Reading archive listing (streamed, nothing is written to disk)...")
+	inv, err := extractor.InspectBackup(ctx, dl.URL, dl.Header)
+	if err != nil {
+		fatalf(ctx, "💥  error inspecting backup: %v", err)
+	}
+
+	fmt.Printf("\n📦  %d files, %s uncompressed\n\n", inv.Files, analyzer.FormatSize(inv.Bytes))
+	fmt.Println("📁  Top-level entries:")
+	for _, e := range inv.TopLevel {
+		name := e.Name
+		if e.Dir {
+			name += "/"
+		}
+		fmt.Printf("    %-32s %10s  (%d files)\n", name, analyzer.FormatSize(e.Bytes), e.Files)
+	}
+
+	fmt.Println()
+	if len(inv.Worlds) == 0 {
+		fmt.Println("🌍  No folder with a level.dat was found")
+		return
+	}
+	fmt.Println("🌍  World candidates:")
+	for _, w := range inv.Worlds {
+		layout := w.Layout
+		if layout == extractor.LayoutUnknown {
+			layout = "overworld only"
+		}
+		fmt.Printf("    %-32s %10s  %s\n", w.Path, analyzer.FormatSize(w.Bytes), layout)
+		if len(w.Folders) > 1 {
+			fmt.Printf("    %-32s             folders: %s\n", "", strings.Join(w.Folders, ", "))
+		}
+	}
+
+	fmt.Printf("\n📝  Suggested config.toml:\n\n%s", suggestWorldConfig(inv.Worlds))
+}
+
+// suggestWorldConfig returns a config.toml snippet for the world candidates:
+// world_name for a single top-level world, [worlds.<name>] tables otherwise.
+func suggestWorldConfig(worlds []extractor.WorldCandidate) string {
+	serverType := config.ServerTypeVanilla
+	for _, w := range worlds {
+		if w.Layout != extractor.LayoutUnknown {
+			serverType = w.Layout
+			break
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "server_type = %q\n", serverType)
+	if len(worlds) == 1 && !strings.Contains(worlds[0].Path, "/") {
+		fmt.Fprintf(&sb, "world_name = %q\n", worlds[0].Path)
+		return sb.String()
+	}
+	for _, w := range worlds {
+		name := path.Base(w.Path)
+		fmt.Fprintf(&sb, "\n[worlds.%s]\n", tomlKey(name))
+		if name != w.Path {
+			fmt.Fprintf(&sb, "source = %q\n", w.Path)
+		}
+		if w.Layout != extractor.LayoutUnknown && w.Layout != serverType {
+			fmt.Fprintf(&sb, "type = %q\n", w.Layout)
+		}
+	}
+	return sb.String()
+}
+
+// tomlKey quotes a table key unless it is a valid TOML bare key.
+func tomlKey(s string) string {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Sprintf("%q", s)
+		}
+	}
+	return s
+}
@@ -1,23 +1,45 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // timezone in config.toml must resolve on hosts without a zoneinfo database
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/EfinaServer/bluemap-action/internal/access"
 	"github.com/EfinaServer/bluemap-action/internal/analyzer"
-	"github.com/EfinaServer/bluemap-action/internal/assets"
 	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/ci"
 	"github.com/EfinaServer/bluemap-action/internal/config"
+	"github.com/EfinaServer/bluemap-action/internal/deploy"
 	"github.com/EfinaServer/bluemap-action/internal/extractor"
-	"github.com/EfinaServer/bluemap-action/internal/lang"
-	"github.com/EfinaServer/bluemap-action/internal/netlify"
-	"github.com/EfinaServer/bluemap-action/internal/pterodactyl"
+	"github.com/EfinaServer/bluemap-action/internal/githubapp"
+	"github.com/EfinaServer/bluemap-action/internal/lastrender"
+	"github.com/EfinaServer/bluemap-action/internal/manifest"
+	"github.com/EfinaServer/bluemap-action/internal/markers"
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+	"github.com/EfinaServer/bluemap-action/internal/prune"
+	"github.com/EfinaServer/bluemap-action/internal/redact"
+	"github.com/EfinaServer/bluemap-action/internal/runreport"
+	"github.com/EfinaServer/bluemap-action/internal/snapshot"
+	"github.com/EfinaServer/bluemap-action/internal/webhook"
 )
 
 // version is set at build time via -ldflags "-X main.version=..."
@@ -57,47 +79,105 @@ func fmtDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", s)
 }
 
-// buildSummary collects data during the run for the GitHub Step Summary.
+// fmtStepDuration formats a step duration like fmtDuration, keeping tenths
+// of a second for steps shorter than a second.
+func fmtStepDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return fmtDuration(d)
+}
+
+// buildSummary collects data during the run for the CI summary.
 type buildSummary struct {
-	toolVersion    string
-	projectName    string
-	serverID       string
-	serverType     string
-	worldName      string
-	mcVersion      string
-	blueMapVersion string
-	renderTime     string
-	backupName     string
-	backupUUID     string
-	backupSize     int64
-	downloadDur    time.Duration
-	renderDur      time.Duration
-	worldRows      []analyzer.WorldSummaryRow
-	worldTotal     int64
-	webTotalSize   int64
-	webFileCount   int64
-	webMaxFileSize int64
-}
-
-// writeGitHubSummary writes a Markdown summary to $GITHUB_STEP_SUMMARY when
-// running inside a CI environment (CI=true). It is a no-op otherwise.
-func writeGitHubSummary(sum *buildSummary) {
-	if os.Getenv("CI") != "true" {
+	ToolVersion    string
+	ProjectName    string
+	ServerID       string
+	ServerType     string
+	WorldNames     []string
+	MCVersion      string
+	BlueMapVersion string
+	BlueMapSpec    string
+	Webapp         string // webapp_version ("v5.3") or webapp_url the webapp was replaced with; empty = the CLI's
+	WebappVersion  string // BlueMap version of the webapp when replaced by webapp_version
+	Maps           []string
+	RenderTime     string
+	RenderedAt     time.Time // RenderTime before formatting; zero in states saved by older versions
+	BackupName     string
+	BackupDate     string
+	BackupCreated  time.Time
+	BackupUUID     string
+	BackupChecksum string
+	BackupSize     int64
+	ConfigHash     string // lastrender.ConfigHash of the checkout, taken before the render changes config/
+	Unchanged      bool   // the backup was already rendered with this config; the run stopped after the lookup
+	DownloadDur    time.Duration
+	RenderDur      time.Duration
+	MapTimings     []bluemap.MapTiming // time spent on each map, in render order
+	WorldRows      []analyzer.WorldSummaryRow
+	WorldTotal     int64
+	RegionStats    []analyzer.RegionStats
+	WebTotalSize   int64
+	WebFileCount   int64
+	WebMaxFileSize int64
+	WebLargest     []analyzer.WebFile      // largest files of web/
+	WebTypes       []analyzer.WebGroup     // web/ per file type
+	WebMaps        []analyzer.WebMap       // web/ per map
+	ManifestDiff   *manifest.Diff          // nil unless file_manifest is enabled
+	Hosting        *analyzer.HostingReport // nil unless [hosting] is set
+	Changes        []runreport.Change      // since the previous deploy; nil without its report
+	ChangesSince   time.Time               // time of the previous deploy
+	Access         string                  // access.target and user count; empty = public
+	DeployedTo     string                  // destination published by a deploy.Deployer; empty = published by the workflow
+	Cleanup        string                  // space reclaimed per cleanup target; empty = cleanup off
+	Reclaimed      int64
+	BackupsDeleted int   // old panel backups deleted by backup_retention
+	BackupsFreed   int64 // their size
+	PrunedTiles    int
+	PrunedBytes    int64
+	PruneDryRun    bool
+	CorruptRegions int
+	TrimmedRegions int
+	Quarantined    bool
+	Markers        int
+	MissingWorlds  []string     // missing worlds with suggestions, formatted for the summary
+	WebProblems    []string     // differences from the expected BlueMap web output layout
+	Steps          []stepTiming // duration of each pipeline step, in the order they ran
+}
+
+// stepTiming is one row of the timing table in the summary.
+type stepTiming struct {
+	Step string
+	Dur  time.Duration
+}
+
+// addStep records how long a pipeline step took. A step recorded earlier,
+// e.g. by a phase re-run after a failed job, is replaced; steps that took no
+// time (a download streamed into the extraction) are left out.
+func (sum *buildSummary) addStep(step string, d time.Duration) {
+	if d <= 0 {
 		return
 	}
+	for i := range sum.Steps {
+		if sum.Steps[i].Step == step {
+			sum.Steps[i].Dur = d
+			return
+		}
+	}
+	sum.Steps = append(sum.Steps, stepTiming{Step: step, Dur: d})
+}
 
-	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
-	if summaryPath == "" {
-		fmt.Fprintln(os.Stderr, "⚠️  CI=true but GITHUB_STEP_SUMMARY is not set; skipping summary")
+// writeSummary writes a Markdown summary to the CI provider's summary
+// destination ($GITHUB_STEP_SUMMARY on GitHub Actions, a bluemap-summary.md
+// artifact on GitLab CI). It is a no-op outside CI.
+func writeSummary(env ci.Environment, sum *buildSummary) {
+	if !env.InCI() {
 		return
 	}
-
-	f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  could not open GITHUB_STEP_SUMMARY: %v\n", err)
+	if env.SummaryPath == "" {
+		warnf("running in %s CI but no summary destination is set; skipping summary", env.Provider)
 		return
 	}
-	defer f.Close()
 
 	var sb strings.Builder
 
@@ -107,227 +187,944 @@ func writeGitHubSummary(sum *buildSummary) {
 	sb.WriteString("### 📋 Server Configuration\n\n")
 	sb.WriteString("| Property | Value |\n")
 	sb.WriteString("|:---|:---|\n")
-	sb.WriteString(fmt.Sprintf("| **Project** | `%s` |\n", sum.projectName))
-	sb.WriteString(fmt.Sprintf("| **Server ID** | `%s` |\n", sum.serverID))
-	sb.WriteString(fmt.Sprintf("| **Server Type** | `%s` |\n", sum.serverType))
-	sb.WriteString(fmt.Sprintf("| **World** | `%s` |\n", sum.worldName))
-	sb.WriteString(fmt.Sprintf("| **Minecraft** | `%s` |\n", sum.mcVersion))
-	sb.WriteString(fmt.Sprintf("| **BlueMap CLI** | `v%s` |\n", sum.blueMapVersion))
-	sb.WriteString(fmt.Sprintf("| **Rendered At** | %s |\n", sum.renderTime))
+	sb.WriteString(fmt.Sprintf("| **Project** | `%s` |\n", sum.ProjectName))
+	sb.WriteString(fmt.Sprintf("| **Server ID** | `%s` |\n", sum.ServerID))
+	sb.WriteString(fmt.Sprintf("| **Server Type** | `%s` |\n", sum.ServerType))
+	sb.WriteString(fmt.Sprintf("| **World** | `%s` |\n", strings.Join(sum.WorldNames, "`, `")))
+	sb.WriteString(fmt.Sprintf("| **Minecraft** | `%s` |\n", sum.MCVersion))
+	if sum.BlueMapSpec != "" && sum.BlueMapSpec != sum.BlueMapVersion {
+		sb.WriteString(fmt.Sprintf("| **BlueMap CLI** | `v%s` (resolved from `%s`) |\n", sum.BlueMapVersion, sum.BlueMapSpec))
+	} else {
+		sb.WriteString(fmt.Sprintf("| **BlueMap CLI** | `v%s` |\n", sum.BlueMapVersion))
+	}
+	if sum.Webapp != "" {
+		sb.WriteString(fmt.Sprintf("| **Webapp** | `%s` |\n", sum.Webapp))
+	}
+	if _, tested := bluemap.CompatibleLayout(sum.BlueMapVersion); !tested {
+		sb.WriteString(fmt.Sprintf("| **Compatibility** | ⚠️ untested (tested: %s) |\n", bluemap.TestedVersions()))
+	}
+	if len(sum.WebProblems) > 0 {
+		sb.WriteString(fmt.Sprintf("| **Web Output** | ⚠️ %s |\n", strings.Join(sum.WebProblems, "<br>")))
+	}
+	if sum.Hosting != nil && len(sum.Hosting.Warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("| **Hosting Limits** | ⚠️ %s |\n", strings.Join(sum.Hosting.Warnings, "<br>")))
+	}
+	if sum.Access != "" {
+		sb.WriteString(fmt.Sprintf("| **Access** | 🔒 %s |\n", sum.Access))
+	}
+	if sum.DeployedTo != "" {
+		sb.WriteString(fmt.Sprintf("| **Deployed To** | `%s` |\n", sum.DeployedTo))
+	}
+	if sum.Cleanup != "" {
+		sb.WriteString(fmt.Sprintf("| **Cleanup** | 🧹 %s reclaimed (%s) |\n", analyzer.FormatSize(sum.Reclaimed), sum.Cleanup))
+	}
+	sb.WriteString(fmt.Sprintf("| **Rendered At** | %s |\n", sum.RenderTime))
 	sb.WriteString("\n")
 
 	// Backup section.
 	sb.WriteString("### 💾 Backup\n\n")
 	sb.WriteString("| Property | Value |\n")
 	sb.WriteString("|:---|:---|\n")
-	sb.WriteString(fmt.Sprintf("| **Name** | %s |\n", sum.backupName))
-	sb.WriteString(fmt.Sprintf("| **UUID** | `%s` |\n", sum.backupUUID))
-	sb.WriteString(fmt.Sprintf("| **Size** | %s |\n", analyzer.FormatSize(sum.backupSize)))
-	sb.WriteString(fmt.Sprintf("| **Download + Extraction** | %s |\n", fmtDuration(sum.downloadDur)))
+	sb.WriteString(fmt.Sprintf("| **Name** | %s |\n", sum.BackupName))
+	sb.WriteString(fmt.Sprintf("| **UUID** | `%s` |\n", sum.BackupUUID))
+	sb.WriteString(fmt.Sprintf("| **Size** | %s |\n", analyzer.FormatSize(sum.BackupSize)))
+	sb.WriteString(fmt.Sprintf("| **Download + Extraction** | %s |\n", fmtDuration(sum.DownloadDur)))
+	if len(sum.MissingWorlds) > 0 {
+		sb.WriteString(fmt.Sprintf("| **Missing Worlds** | ⚠️ %s |\n", strings.Join(sum.MissingWorlds, "<br>")))
+	}
+	if sum.TrimmedRegions > 0 {
+		sb.WriteString(fmt.Sprintf("| **Trimmed Regions** | %d |\n", sum.TrimmedRegions))
+	}
+	if sum.CorruptRegions > 0 {
+		label := "Corrupt Regions"
+		if sum.Quarantined {
+			label = "Quarantined Regions"
+		}
+		sb.WriteString(fmt.Sprintf("| **%s** | ⚠️ %d |\n", label, sum.CorruptRegions))
+	}
+	if sum.BackupsDeleted > 0 {
+		sb.WriteString(fmt.Sprintf("| **Old Backups Deleted** | 🗑️ %d (%s) |\n", sum.BackupsDeleted, analyzer.FormatSize(sum.BackupsFreed)))
+	}
 	sb.WriteString("\n")
 
 	// Render section.
 	sb.WriteString("### 🔨 Render\n\n")
 	sb.WriteString("| Property | Value |\n")
 	sb.WriteString("|:---|---:|\n")
-	sb.WriteString(fmt.Sprintf("| **BlueMap CLI Duration** | %s |\n", fmtDuration(sum.renderDur)))
+	sb.WriteString(fmt.Sprintf("| **BlueMap CLI Duration** | %s |\n", fmtDuration(sum.RenderDur)))
+	for _, t := range sum.MapTimings {
+		sb.WriteString(fmt.Sprintf("| **Map `%s`** | %s |\n", t.Map, fmtDuration(t.Duration)))
+	}
+	if sum.PrunedTiles > 0 {
+		label := "Pruned Tiles"
+		if sum.PruneDryRun {
+			label = "Stale Tiles (dry run)"
+		}
+		sb.WriteString(fmt.Sprintf("| **%s** | %d (%s) |\n", label, sum.PrunedTiles, analyzer.FormatSize(sum.PrunedBytes)))
+	}
+	if sum.Markers > 0 {
+		sb.WriteString(fmt.Sprintf("| **Markers** | %d |\n", sum.Markers))
+	}
+	if len(sum.Maps) > 0 {
+		sb.WriteString(fmt.Sprintf("| **Maps** | `%s` |\n", strings.Join(sum.Maps, "`, `")))
+	}
 	sb.WriteString("\n")
 
-	// World sizes section.
-	sb.WriteString("### 🌍 World Sizes\n\n")
-	sb.WriteString("| World | Size |\n")
-	sb.WriteString("|:---|---:|\n")
-	for _, row := range sum.worldRows {
-		if row.Found {
-			sb.WriteString(fmt.Sprintf("| %s | %s |\n", row.Label, analyzer.FormatSize(row.Size)))
-		} else {
-			sb.WriteString(fmt.Sprintf("| %s | *(not found)* |\n", row.Label))
+	// World sizes, chunk statistics and web output sections.
+	analyzer.WriteWorldsMarkdown(&sb, sum.WorldRows, sum.WorldTotal)
+	analyzer.WriteRegionsMarkdown(&sb, sum.RegionStats)
+	var changed [][2]string
+	if d := sum.ManifestDiff; d != nil {
+		changed = append(changed, [2]string{"Changed Files", fmt.Sprintf("%d (+%d new, %d modified, %d removed)",
+			len(d.Added)+len(d.Changed), len(d.Added), len(d.Changed), len(d.Removed))})
+	}
+	analyzer.WriteWebMarkdown(&sb, &analyzer.WebOutputReport{
+		TotalSize:   sum.WebTotalSize,
+		FileCount:   sum.WebFileCount,
+		MaxFileSize: sum.WebMaxFileSize,
+		Largest:     sum.WebLargest,
+		Types:       sum.WebTypes,
+		Maps:        sum.WebMaps,
+	}, changed...)
+	if h := sum.Hosting; h != nil {
+		writeHostingSummary(&sb, h)
+	}
+	if len(sum.Changes) > 0 {
+		writeChangesSummary(&sb, sum.Changes, sum.ChangesSince)
+	}
+
+	// Timing section.
+	if len(sum.Steps) > 0 {
+		var total time.Duration
+		for _, st := range sum.Steps {
+			total += st.Dur
 		}
+		sb.WriteString("### ⏱ Timing\n\n")
+		sb.WriteString("| Step | Duration | Share |\n")
+		sb.WriteString("|:---|---:|---:|\n")
+		for _, st := range sum.Steps {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %.1f%% |\n", st.Step, fmtStepDuration(st.Dur), 100*float64(st.Dur)/float64(total)))
+		}
+		sb.WriteString(fmt.Sprintf("| **TOTAL** | **%s** | |\n", fmtDuration(total)))
+		sb.WriteString("\n")
+	}
+
+	if env.JobURL != "" {
+		sb.WriteString(fmt.Sprintf("[View job log](%s)\n\n", env.JobURL))
+	}
+
+	if err := env.WriteSummary(sb.String()); err != nil {
+		warnf("could not write summary: %v", err)
+	}
+}
+
+// writeHostingSummary writes the hosting limits section of the summary.
+func writeHostingSummary(sb *strings.Builder, h *analyzer.HostingReport) {
+	limit := func(v int64, format func(int64) string) string {
+		if v <= 0 {
+			return "—"
+		}
+		return format(v)
+	}
+	count := func(n int64) string { return fmt.Sprintf("%d", n) }
+
+	sb.WriteString(fmt.Sprintf("### 🏷 Hosting Limits (%s)\n\n", h.Limits.Name))
+	sb.WriteString("| Property | Value | Limit |\n")
+	sb.WriteString("|:---|---:|---:|\n")
+	sb.WriteString(fmt.Sprintf("| **Files** | %d | %s |\n", h.Files, limit(h.Limits.MaxFiles, count)))
+	if h.ChangedFiles >= 0 {
+		sb.WriteString(fmt.Sprintf("| **Upload** | %d files, %s | |\n", h.ChangedFiles, analyzer.FormatSize(h.UploadSize)))
+	}
+	sb.WriteString(fmt.Sprintf("| **Largest File** | %s | %s |\n", analyzer.FormatSize(h.LargestSize), limit(h.Limits.MaxFileSize, analyzer.FormatSize)))
+	if h.MonthlyVisits > 0 {
+		sb.WriteString(fmt.Sprintf("| **Bandwidth** (est., %d visits) | %s/month | %s |\n", h.MonthlyVisits, analyzer.FormatSize(h.MonthlyBandwidth), limit(h.Limits.MonthlyBandwidth, analyzer.FormatSize)))
+	}
+	sb.WriteString("\n")
+}
+
+// writeChangesSummary writes the comparison with the previous deploy.
+func writeChangesSummary(sb *strings.Builder, changes []runreport.Change, since time.Time) {
+	sb.WriteString("### 📈 Since the Previous Deploy\n\n")
+	sb.WriteString(fmt.Sprintf("Compared with the deploy of %s.\n\n", since.UTC().Format("2006-01-02 15:04 UTC")))
+	sb.WriteString("| Metric | Previous | Current | Change |\n")
+	sb.WriteString("|:---|---:|---:|---:|\n")
+	for _, c := range changes {
+		metric := "**" + c.Metric + "**"
+		if c.Map != "" {
+			metric = fmt.Sprintf("`%s` %s", c.Map, strings.ToLower(c.Metric))
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", metric, fmtChangeValue(c.Kind, c.Previous), fmtChangeValue(c.Kind, c.Current), fmtChangeDelta(c)))
 	}
-	sb.WriteString(fmt.Sprintf("| **TOTAL** | **%s** |\n", analyzer.FormatSize(sum.worldTotal)))
 	sb.WriteString("\n")
+}
+
+// fmtChangeValue formats a value of a runreport.Change of the given kind.
+func fmtChangeValue(kind string, v float64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	switch kind {
+	case runreport.KindSize:
+		return sign + analyzer.FormatSize(int64(v))
+	case runreport.KindDuration:
+		return sign + fmtDuration(time.Duration(v*float64(time.Second)))
+	}
+	return fmt.Sprintf("%s%.0f", sign, v)
+}
+
+// fmtChangeDelta formats the change of c with its sign and, when there was
+// a previous value, the percentage.
+func fmtChangeDelta(c runreport.Change) string {
+	d := c.Delta()
+	if d == 0 {
+		return "±0"
+	}
+	s := fmtChangeValue(c.Kind, d)
+	if d > 0 {
+		s = "+" + s
+	}
+	if c.Previous != 0 {
+		s += fmt.Sprintf(" (%+.1f%%)", c.Percent())
+	}
+	return s
+}
 
-	// Web output section.
-	sb.WriteString("### 📊 Web Output\n\n")
+// writeUnchangedSummary writes the CI summary of a run that stopped because
+// the backup was already rendered (skip_if_unchanged).
+func writeUnchangedSummary(env ci.Environment, sum *buildSummary) {
+	if !env.InCI() || env.SummaryPath == "" {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 🗺 BlueMap Build Summary\n\n")
+	sb.WriteString("⏭ **Nothing to do:** the latest backup was already rendered with the current config.\n\n")
 	sb.WriteString("| Property | Value |\n")
-	sb.WriteString("|:---|---:|\n")
-	sb.WriteString(fmt.Sprintf("| **Total Size** | %s |\n", analyzer.FormatSize(sum.webTotalSize)))
-	sb.WriteString(fmt.Sprintf("| **File Count** | %d |\n", sum.webFileCount))
-	sb.WriteString(fmt.Sprintf("| **Largest File** | %s |\n", analyzer.FormatSize(sum.webMaxFileSize)))
+	sb.WriteString("|:---|:---|\n")
+	sb.WriteString(fmt.Sprintf("| **Project** | `%s` |\n", sum.ProjectName))
+	sb.WriteString(fmt.Sprintf("| **Server ID** | `%s` |\n", sum.ServerID))
+	sb.WriteString(fmt.Sprintf("| **Backup** | %s (`%s`, %s) |\n", sum.BackupName, sum.BackupUUID, sum.BackupDate))
+	sb.WriteString(fmt.Sprintf("| **BlueMap CLI** | `v%s` |\n", sum.BlueMapVersion))
+	sb.WriteString(fmt.Sprintf("| **Rendered At** | %s |\n", sum.RenderTime))
 	sb.WriteString("\n")
+	if env.JobURL != "" {
+		sb.WriteString(fmt.Sprintf("[View job log](%s)\n\n", env.JobURL))
+	}
 
-	if _, err := f.WriteString(sb.String()); err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  could not write to GITHUB_STEP_SUMMARY: %v\n", err)
+	if err := env.WriteSummary(sb.String()); err != nil {
+		warnf("could not write summary: %v", err)
 	}
 }
 
-func main() {
-	serverDir := flag.String("dir", ".", "server directory containing config.toml (e.g. onlinemap-01)")
-	flag.Parse()
+// writeRenderFailureSummary writes the CI summary of a run whose render
+// failed, quoting the BlueMap output that explains the failure.
+func writeRenderFailureSummary(env ci.Environment, sum *buildSummary, renderErr *bluemap.RenderError, logPath string) {
+	if !env.InCI() || env.SummaryPath == "" {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 🗺 BlueMap Build Failed\n\n")
+	sb.WriteString(fmt.Sprintf("BlueMap CLI `v%s` failed rendering `%s`", sum.BlueMapVersion, sum.ProjectName))
+	if renderErr.Exception != "" {
+		sb.WriteString(fmt.Sprintf(" with `%s`", renderErr.Exception))
+	}
+	sb.WriteString(".\n\n")
+	if len(renderErr.Excerpt) > 0 {
+		sb.WriteString("```text\n" + strings.Join(renderErr.Excerpt, "\n") + "\n```\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("The full output is in `%s`.\n\n", logPath))
+	if env.JobURL != "" {
+		sb.WriteString(fmt.Sprintf("[View job log](%s)\n\n", env.JobURL))
+	}
 
-	panelURL := os.Getenv("PTERODACTYL_PANEL_URL")
-	apiKey := os.Getenv("PTERODACTYL_API_KEY")
+	if err := env.WriteSummary(sb.String()); err != nil {
+		warnf("could not write summary: %v", err)
+	}
+}
+
+// writeOutputs publishes key run results as CI outputs (GITHUB_OUTPUT or a
+// dotenv artifact) so later jobs can consume them.
+func writeOutputs(env ci.Environment, sum *buildSummary) {
+	outputs := [][2]string{
+		{"project-name", sum.ProjectName},
+		{"bluemap-version", sum.BlueMapVersion},
+		{"render-time", sum.RenderTime},
+		{"backup-uuid", sum.BackupUUID},
+		{"web-size-bytes", fmt.Sprintf("%d", sum.WebTotalSize)},
+		{"skipped", strconv.FormatBool(sum.Unchanged)},
+	}
+	if sum.Cleanup != "" {
+		outputs = append(outputs, [2]string{"reclaimed-bytes", fmt.Sprintf("%d", sum.Reclaimed)})
+	}
+	if d := sum.ManifestDiff; d != nil {
+		outputs = append(outputs, [2]string{"changed-files", fmt.Sprintf("%d", len(d.Added)+len(d.Changed))})
+	}
+	for _, o := range outputs {
+		if err := env.SetOutput(o[0], o[1]); err != nil {
+			warnf("could not set output %s: %v", o[0], err)
+			return
+		}
+	}
+}
 
-	if panelURL == "" {
-		log.Fatal("PTERODACTYL_PANEL_URL environment variable is required")
+// exportGitHubAppToken mints a GitHub App installation token when
+// GITHUB_APP_ID is set and exposes it as the masked "github-app-token" CI
+// output, so later steps can push to other repositories without a broad
+// personal access token. It is a no-op when no App is configured. Where the
+// output cannot be masked, such as GitLab's dotenv artifact, the token is
+// not minted at all.
+func exportGitHubAppToken(ctx context.Context, env ci.Environment) error {
+	app, err := githubapp.FromEnv()
+	if err != nil || app == nil {
+		return err
 	}
-	if apiKey == "" {
-		log.Fatal("PTERODACTYL_API_KEY environment variable is required")
+	if env.OutputPath != "" && !env.CanMask() {
+		warnf("GITHUB_APP_ID: outputs cannot be masked here, so the token would be readable in %s; not minting it", env.OutputPath)
+		return nil
+	}
+
+	repo := os.Getenv("GITHUB_APP_REPOSITORY")
+	if repo == "" {
+		repo = os.Getenv("GITHUB_REPOSITORY")
 	}
 
-	toolVersion := getVersion()
-	loc, err := time.LoadLocation("Asia/Taipei")
+	fmt.Printf("\n🔑  Minting GitHub App installation token (app %s)\n", app.AppID)
+	tok, err := app.InstallationToken(ctx, repo)
 	if err != nil {
-		log.Fatalf("loading timezone: %v", err)
+		return err
 	}
 
-	renderTime := time.Now().In(loc).Format("2006-01-02 15:04 MST")
+	if env.OutputPath == "" {
+		warnf("no CI output destination is set; token minted but not exported")
+		return nil
+	}
 
-	fmt.Printf("🗺  bluemap-action %s\n\n", toolVersion)
+	// Mask the token before it can appear anywhere in the job log.
+	env.Mask(tok.Token)
+	redact.Add(tok.Token)
+	if err := env.SetOutput("github-app-token", tok.Token); err != nil {
+		return err
+	}
+	fmt.Printf("    exported as output github-app-token (expires %s)\n", tok.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
 
-	// Load config from the server directory.
-	srv, err := config.Load(*serverDir)
-	if err != nil {
-		log.Fatalf("loading config: %v", err)
+// saveTimeout bounds how long to wait for the server to confirm save-all.
+const saveTimeout = 2 * time.Minute
+
+// createFreshBackup triggers a new panel backup and waits for it to finish.
+// With pauseSaves, world saving is switched off and flushed through the
+// console websocket first, so the backup captures a consistent snapshot; it
+// is switched back on once the backup has finished (or failed), over a new
+// console connection since the first one may have been dropped while the
+// backup ran. With flushSaves, the world is only flushed and autosave stays
+// on.
+func createFreshBackup(ctx context.Context, client panel.Panel, serverID string, pauseSaves, flushSaves bool) (backup *panel.Backup, err error) {
+	if pauseSaves || flushSaves {
+		option := "pause_saves"
+		if pauseSaves {
+			fmt.Println("⏸   Pausing world saves via console")
+		} else {
+			option = "flush_saves"
+			fmt.Println("💽  Flushing world saves via console")
+		}
+		cp, ok := client.(panel.ConsolePanel)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s has no console support", option, client.Name())
+		}
+		var console panel.Console
+		console, err = cp.OpenConsole(ctx, serverID)
+		if err != nil {
+			return nil, fmt.Errorf("opening console: %w", err)
+		}
+		defer console.Close()
+
+		if state := console.State(); state != "" && state != panel.StateRunning {
+			fmt.Printf("    server is %s; nothing to save\n", state)
+		} else {
+			if pauseSaves {
+				if err := console.Command("save-off"); err != nil {
+					return nil, err
+				}
+				// Re-enable saving even if the backup fails or the run is
+				// cancelled, and fail the run if that does not work.
+				defer func() {
+					if resumeErr := resumeSaves(context.WithoutCancel(ctx), cp, serverID); resumeErr != nil {
+						msg := fmt.Sprintf("world saves are still off: run save-on on the console of server %s (%v)", serverID, resumeErr)
+						fmt.Fprintln(os.Stderr, "🛑  "+msg)
+						title, text := annotationText(msg)
+						ci.Detect().Annotate(ci.AnnotationError, title, text)
+						err = errors.Join(err, fmt.Errorf("re-enabling world saves: %w", resumeErr))
+						return
+					}
+					fmt.Println("▶️   World saves re-enabled (save-on)")
+				}()
+			}
+
+			if err := console.Command("save-all flush"); err != nil {
+				return nil, err
+			}
+			if err := console.WaitForOutput(ctx, "Saved the game", saveTimeout); err != nil {
+				return nil, err
+			}
+			if pauseSaves {
+				fmt.Println("    save-off, save-all flush: world saved")
+			} else {
+				fmt.Println("    save-all flush: world saved")
+			}
+		}
+		console.Close()
 	}
 
-	worlds := srv.Config.ResolveWorlds()
+	name := "bluemap-action " + time.Now().UTC().Format("2006-01-02 15:04:05")
+	fmt.Printf("💾  Creating backup %q\n", name)
+	return client.CreateBackup(ctx, serverID, name)
+}
+
+// resumeTimeout bounds how long re-enabling world saves may take.
+const resumeTimeout = 30 * time.Second
+
+// resumeSaves opens a new console connection, sends save-on and waits for
+// the server to confirm it.
+func resumeSaves(ctx context.Context, cp panel.ConsolePanel, serverID string) error {
+	ctx, cancel := context.WithTimeout(ctx, resumeTimeout)
+	defer cancel()
+
+	console, err := cp.OpenConsole(ctx, serverID)
+	if err != nil {
+		return fmt.Errorf("reconnecting console for save-on: %w", err)
+	}
+	defer console.Close()
+	if err := console.Command("save-on"); err != nil {
+		return err
+	}
+	if err := console.WaitForOutput(ctx, "Automatic saving is now enabled", resumeTimeout); err != nil {
+		return fmt.Errorf("save-on: %w", err)
+	}
+	return nil
+}
 
-	projectName := filepath.Base(srv.Dir)
-	if srv.Config.Name != "" {
-		projectName = srv.Config.Name
+// diffManifest hashes web/, compares it with the manifest of the last
+// published build, writes the list of added/changed files for deploy
+// backends and saves the new manifest as pending until the map is
+// published (see publishRecords).
+func diffManifest(serverDir string, sum *buildSummary) error {
+	path := manifest.Path(serverDir)
+	prev, err := manifest.Load(path)
+	if err != nil {
+		return err
+	}
+	cur, err := manifest.Build(filepath.Join(serverDir, "web"), 0)
+	if err != nil {
+		return err
 	}
+	diff := cur.Compare(prev)
 
-	sum := &buildSummary{
-		toolVersion:    toolVersion,
-		projectName:    projectName,
-		serverID:       srv.Config.ServerID,
-		serverType:     srv.Config.ServerType,
-		worldName:      srv.Config.WorldName,
-		mcVersion:      srv.Config.MinecraftVersion,
-		blueMapVersion: srv.Config.BlueMapVersion,
-		renderTime:     renderTime,
+	listPath := filepath.Join(serverDir, manifest.ChangedListName)
+	if err := manifest.WriteList(listPath, diff.Upload()); err != nil {
+		return err
+	}
+	if err := cur.Save(path + pendingSuffix); err != nil {
+		return err
 	}
 
-	fmt.Printf("📋  %s  (server: %s)\n", projectName, srv.Config.ServerID)
-	fmt.Printf("    server type:        %s\n", srv.Config.ServerType)
-	fmt.Printf("    world name:         %s\n", srv.Config.WorldName)
-	fmt.Printf("    worlds:             %v\n", worlds)
-	fmt.Printf("    minecraft version:  %s\n", srv.Config.MinecraftVersion)
-	fmt.Printf("    bluemap version:    %s\n", srv.Config.BlueMapVersion)
-	fmt.Printf("    download mode:      %s\n", srv.Config.ResolveDownloadMode())
-	if srv.Config.DownloadConnections > 0 {
-		fmt.Printf("    download conns:     %d (manual)\n\n", srv.Config.DownloadConnections)
+	sum.ManifestDiff = &diff
+	fmt.Printf("🧾  File Manifest\n")
+	if prev == nil {
+		fmt.Printf("    no previous manifest; all %d files are new\n", len(diff.Added))
 	} else {
-		fmt.Printf("    download conns:     auto\n\n")
+		fmt.Printf("    added: %d, changed: %d, removed: %d, unchanged: %d\n",
+			len(diff.Added), len(diff.Changed), len(diff.Removed), diff.Unchanged)
 	}
+	fmt.Printf("    changed file list: %s\n", listPath)
+	return nil
+}
 
-	// Step 1: Download and extract world data from Pterodactyl backup.
-	client := pterodactyl.NewClient(panelURL, apiKey)
+// pendingSuffix marks a record of this build that describes web/ as it is
+// about to be published. publishRecords moves it into place once the map is
+// published, so after a failed deploy the next build still compares with
+// what was last published.
+const pendingSuffix = ".pending"
 
-	backup, err := client.GetLatestBackup(srv.Config.ServerID)
+// publishRecords moves the pending records of this build into place, after
+// the deployer or, for targets the workflow publishes, its deploy step has
+// succeeded.
+func publishRecords(serverDir string) {
+	for _, path := range []string{manifest.Path(serverDir), runreport.Path(serverDir), lastrender.Path(serverDir)} {
+		err := os.Rename(path+pendingSuffix, path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			warnf("could not record the published build in %s: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// trimWorlds deletes region files entirely outside each world's bounds (or
+// render_bounds). Extraction already skips them; this also removes ones left
+// over in the server directory from earlier runs without bounds.
+func trimWorlds(serverDir string, worlds []config.WorldConfig, sum *buildSummary) error {
+	for _, w := range worlds {
+		if w.Bounds == nil {
+			continue
+		}
+		b := w.Bounds
+		removed, freed, err := mca.Trim(serverDir, w.Folders(), b.ContainsRegion)
+		if err != nil {
+			return fmt.Errorf("world %q: %w", w.Name, err)
+		}
+		fmt.Printf("✂️   Trimmed %q to x %d..%d, z %d..%d: %d region files removed (%s)\n",
+			w.Name, b.MinX, b.MaxX, b.MinZ, b.MaxZ, removed, analyzer.FormatSize(freed))
+		sum.TrimmedRegions += removed
+	}
+	return nil
+}
+
+// checkRegions validates the region files of the extracted worlds and, in
+// quarantine mode, moves corrupt ones out of the world so BlueMap does not
+// crash on them halfway through the render.
+func checkRegions(serverDir string, worlds []string, quarantine bool, sum *buildSummary) error {
+	fmt.Println("🩺  Checking region files")
+	checked, corrupt, err := mca.Scan(serverDir, worlds, 0)
 	if err != nil {
-		log.Fatalf("💥  error getting latest backup: %v", err)
+		return err
+	}
+	if len(corrupt) == 0 {
+		fmt.Printf("    %d region files OK\n", checked)
+		return nil
 	}
 
-	sum.backupName = backup.Name
-	sum.backupUUID = backup.UUID
-	sum.backupSize = backup.Bytes
+	for _, c := range corrupt {
+		fmt.Fprintf(os.Stderr, "  ⚠️  corrupt region %s: %s\n", c.Path, c.Reason)
+	}
+	sum.CorruptRegions = len(corrupt)
 
-	fmt.Printf("💾  Latest backup: %s (%s, %s)\n", backup.Name, backup.UUID, analyzer.FormatSize(backup.Bytes))
+	if !quarantine {
+		warnf("%d of %d region files are corrupt; set region_check = \"quarantine\" to skip them", len(corrupt), checked)
+		return nil
+	}
+
+	dir := filepath.Join(serverDir, mca.QuarantineDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := mca.Quarantine(serverDir, dir, corrupt); err != nil {
+		return err
+	}
+	sum.Quarantined = true
+	warnf("moved %d of %d region files to %s; those areas will not be rendered", len(corrupt), checked, dir)
+	return nil
+}
 
-	downloadURL, err := client.GetBackupDownloadURL(srv.Config.ServerID, backup.UUID)
+// generateMarkers builds marker sets from the plugin, player and sign data
+// extracted with the worlds, and from markers.toml when static is set. HOCON
+// output is written right away so the render
+// picks it up; JSON output is merged into the rendered maps afterwards by
+// writeMarkers.
+func generateMarkers(ctx context.Context, serverDir string, cfg config.MarkersConfig, static bool, sum *buildSummary) ([]markers.MapResult, error) {
+	from := slices.Clone(cfg.Sources)
+	if static {
+		from = append(from, markers.StaticFileName)
+	}
+	fmt.Printf("📍  Generating markers from %s\n", strings.Join(from, ", "))
+	opts := markers.Options{SignPrefix: cfg.ResolveSignPrefix()}
+	if slices.Contains(cfg.Sources, "changes") {
+		opts.Since = changesSince(serverDir)
+	}
+	results, err := markers.Generate(serverDir, cfg.Sources, opts)
 	if err != nil {
-		log.Fatalf("💥  error getting download URL: %v", err)
+		return nil, err
+	}
+	if slices.Contains(cfg.Sources, "players") {
+		stats, err := markers.FetchHeads(ctx, serverDir, markers.HeadCacheDir(), results)
+		if err != nil {
+			return nil, fmt.Errorf("fetching player heads: %w", err)
+		}
+		fmt.Printf("    player heads: %d fetched, %d cached, %d offline-mode\n", stats.Fetched, stats.Cached, stats.Offline)
+		if stats.Failed > 0 {
+			fmt.Fprintf(os.Stderr, "    ⚠️  could not fetch %d player heads; those markers use the default icon\n", stats.Failed)
+		}
+	}
+	for _, r := range results {
+		if len(r.Sets) == 0 {
+			continue
+		}
+		counts := make([]string, len(r.Sets))
+		for i, s := range r.Sets {
+			counts[i] = fmt.Sprintf("%s %d", s.Source.ID(), len(s.Markers))
+		}
+		fmt.Printf("    %-20s  %s (world %q)\n", r.MapID, strings.Join(counts, ", "), r.World)
 	}
 
-	fmt.Printf("⬇️   Downloading and extracting worlds: %v\n", worlds)
+	if cfg.ResolveFormat() == markers.FormatHOCON {
+		written, err := markers.WriteHOCON(serverDir, results)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range written {
+			fmt.Printf("    wrote %s/%s.conf; include it in config/maps/%s.conf\n", markers.HOCONDirName, id, id)
+		}
+		sum.Markers = countMarkers(results, written)
+	}
+	return results, nil
+}
 
-	downloadStart := time.Now()
-	dlOpts := extractor.DownloadOptions{
-		Mode:        srv.Config.ResolveDownloadMode(),
-		Connections: srv.Config.ResolveDownloadConnections(),
+// changesSince returns the time the changes marker source compares chunk
+// save times with: when the backup of the previous deploy was made, or the
+// deploy itself if the panel did not report it. Zero without a report.
+func changesSince(serverDir string) time.Time {
+	prev, err := runreport.Load(serverDir)
+	switch {
+	case err != nil:
+		warnf("could not read the previous report; the changes markers stay empty: %v", err)
+		return time.Time{}
+	case prev == nil:
+		fmt.Printf("    no report of an earlier deploy; the changes markers stay empty\n")
+		return time.Time{}
 	}
-	if err := extractor.DownloadAndExtractWorlds(downloadURL, srv.Dir, worlds, dlOpts); err != nil {
-		log.Fatalf("💥  error extracting worlds: %v", err)
+	since := prev.BackupCreated
+	if since.IsZero() {
+		since = prev.Time
 	}
-	downloadDur := time.Since(downloadStart)
+	fmt.Printf("    changes since %s\n", since.UTC().Format("2006-01-02 15:04 UTC"))
+	return since
+}
 
-	sum.downloadDur = downloadDur
-	fmt.Printf("⏱   Download + extraction took %s\n", fmtDuration(downloadDur))
+// writeMarkers merges JSON marker sets into the rendered maps' live data.
+func writeMarkers(serverDir string, results []markers.MapResult, sum *buildSummary) error {
+	written, err := markers.WriteJSON(serverDir, results)
+	if err != nil {
+		return err
+	}
+	sum.Markers = countMarkers(results, written)
+	fmt.Printf("📍  Markers written to %d maps (%d markers)\n", len(written), sum.Markers)
+	return nil
+}
 
-	// Step 2: Analyze extracted world sizes.
-	fmt.Println()
-	worldTotal, worldRows := analyzer.PrintWorldAnalysis(srv.Config.ServerType, srv.Dir, worlds)
-	sum.worldRows = worldRows
-	sum.worldTotal = worldTotal
+// countMarkers sums the markers of the maps that were written.
+func countMarkers(results []markers.MapResult, written []string) int {
+	n := 0
+	for _, r := range results {
+		for _, id := range written {
+			if id == r.MapID {
+				n += r.Count()
+			}
+		}
+	}
+	return n
+}
 
-	// Step 3: Download BlueMap CLI.
-	fmt.Println()
-	fmt.Printf("📦  BlueMap CLI v%s\n", srv.Config.BlueMapVersion)
-	jarPath, err := bluemap.EnsureCLI(srv.Dir, srv.Config.BlueMapVersion)
-	if err != nil {
-		log.Fatalf("💥  error downloading BlueMap CLI: %v", err)
+// pruneTiles removes (or, in dry-run mode, only reports) tiles restored from
+// the cache whose source regions were deleted or trimmed from the world.
+func pruneTiles(serverDir string, dryRun bool, sum *buildSummary) error {
+	if dryRun {
+		fmt.Println("✂️   Stale tile report (dry run)")
+	} else {
+		fmt.Println("✂️   Pruning stale tiles")
 	}
 
-	// Step 4: Deploy language files before rendering.
-	langDir := filepath.Join(srv.Dir, "web", "lang")
-	langCfg := lang.DeployConfig{
-		ToolVersion:      toolVersion,
-		MinecraftVersion: srv.Config.MinecraftVersion,
-		ProjectName:      projectName,
-		RenderTime:       renderTime,
+	results, err := prune.Run(serverDir, dryRun)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("\n📝  Deploying language files → %s\n", langDir)
-	if err := lang.Deploy(langDir, langCfg); err != nil {
-		log.Fatalf("💥  error deploying lang files: %v", err)
+	var total int
+	var bytes int64
+	for _, r := range results {
+		if r.Skipped != "" {
+			fmt.Printf("    %-25s  skipped: %s\n", r.MapID, r.Skipped)
+			continue
+		}
+		fmt.Printf("    %-25s  %d tiles, %s\n", r.MapID, len(r.Stale), analyzer.FormatSize(r.Bytes))
+		total += len(r.Stale)
+		bytes += r.Bytes
 	}
 
-	// Step 5: Deploy netlify.toml for static site hosting.
-	fmt.Printf("📝  Deploying netlify.toml → %s\n", filepath.Join(srv.Dir, "web"))
-	if err := netlify.DeployConfig(srv.Dir); err != nil {
-		log.Fatalf("💥  error deploying netlify.toml: %v", err)
+	sum.PrunedTiles = total
+	sum.PrunedBytes = bytes
+	sum.PruneDryRun = dryRun
+
+	if dryRun {
+		report := filepath.Join(serverDir, prune.ReportName)
+		if err := prune.WriteReport(report, results); err != nil {
+			return err
+		}
+		fmt.Printf("    %d tiles (%s) would be deleted; list written to %s\n", total, analyzer.FormatSize(bytes), report)
+	} else {
+		fmt.Printf("    deleted %d tiles (%s)\n", total, analyzer.FormatSize(bytes))
 	}
+	return nil
+}
 
-	// Step 6: Run custom scripts.
-	fmt.Printf("\n🔧  Running custom scripts...\n")
-	if err := bluemap.RunScripts(srv.Dir); err != nil {
-		log.Fatalf("💥  error running custom scripts: %v", err)
+// sendAnnouncement sends the configured announce_command to the server
+// console, substituting {projectName} and {renderTime}. It is a no-op when no
+// command is configured or the server is not running.
+func sendAnnouncement(ctx context.Context, client panel.Panel, cfg config.ServerConfig, projectName, renderTime string) error {
+	if cfg.AnnounceCommand == "" {
+		fmt.Println("📣  No announce_command configured; nothing to announce")
+		return nil
 	}
 
-	// Step 7: Execute BlueMap CLI rendering.
-	fmt.Printf("\n🔨  Running BlueMap CLI render...\n")
-	renderDur, err := bluemap.Render(jarPath, srv.Dir, srv.Config.MinecraftVersion)
+	command := strings.ReplaceAll(cfg.AnnounceCommand, "{projectName}", projectName)
+	command = strings.ReplaceAll(command, "{renderTime}", renderTime)
+
+	cp, ok := client.(panel.ConsolePanel)
+	if !ok {
+		return fmt.Errorf("announce_command: %s has no console support", client.Name())
+	}
+	console, err := cp.OpenConsole(ctx, cfg.ServerID)
 	if err != nil {
-		log.Fatalf("💥  error during rendering: %v", err)
+		return fmt.Errorf("opening console: %w", err)
+	}
+	defer console.Close()
+
+	if state := console.State(); state != "" && state != panel.StateRunning {
+		fmt.Printf("📣  Server is %s; skipping announcement\n", state)
+		return nil
+	}
+	if err := console.Command(command); err != nil {
+		return err
+	}
+	fmt.Printf("📣  Announced: %s\n", command)
+	return nil
+}
+
+// worldFilters returns the backup paths of every world folder that differs
+// from its name, and a filter that drops region files outside a world's
+// bounds, for the extractor.
+func worldFilters(worlds []config.WorldConfig) (map[string]string, func(folder, rel string) bool) {
+	sources := make(map[string]string)
+	owners := make(map[string]config.WorldConfig)
+	for _, w := range worlds {
+		for folder, src := range w.BackupFolders() {
+			if src != folder {
+				sources[folder] = src
+			}
+			owners[folder] = w
+		}
+	}
+	include := func(folder, rel string) bool {
+		return owners[folder].IncludesFile(rel)
 	}
-	sum.renderDur = renderDur
-	fmt.Printf("⏱   Render took %s\n", fmtDuration(renderDur))
+	return sources, include
+}
 
-	// Step 8: Rewrite asset references to compressed variants.
-	fmt.Printf("\n✏️   Rewriting asset references to compressed variants...\n")
-	if err := assets.RewriteCompressedRefs(srv.Dir); err != nil {
-		log.Fatalf("💥  error rewriting asset references: %v", err)
+// hasBounds reports whether any world is limited to bounds.
+func hasBounds(worlds []config.WorldConfig) bool {
+	for _, w := range worlds {
+		if w.Bounds != nil {
+			return true
+		}
 	}
+	return false
+}
 
-	// Step 9: Analyze web output size after rendering.
-	fmt.Println()
-	webReport, err := analyzer.AnalyzeWebOutput(srv.Dir)
+// fatalExtract aborts after a failed extraction. When a world is missing from
+// the backup it points at the config and also reports the world, with the
+// directories it may have meant, in the CI summary.
+func fatalExtract(ctx context.Context, env ci.Environment, err error) {
+	var missing *extractor.MissingWorldsError
+	if errors.As(err, &missing) {
+		if env.InCI() && env.SummaryPath != "" {
+			var sb strings.Builder
+			sb.WriteString("## 🗺 BlueMap Build Failed\n\n")
+			sb.WriteString("Required worlds were not found in the backup:\n\n")
+			for _, w := range missing.Worlds {
+				sb.WriteString("- " + missingWorldSummary(w, missing.Suggestions[w]) + "\n")
+			}
+			sb.WriteString("\nCheck `world_name` / `[worlds]` in config.toml.\n")
+			if err := env.WriteSummary(sb.String()); err != nil {
+				warnf("could not write summary: %v", err)
+			}
+		}
+		fatalf(ctx, "💥  %v\n    check world_name / [worlds] in config.toml, or set fail_on_missing_worlds = false to render the worlds that were found", err)
+	}
+	var limit *extractor.LimitError
+	if errors.As(err, &limit) {
+		fatalf(ctx, "💥  %v\n    if the backup is expected to be this large, raise %s in config.toml", err, limit.Limit)
+	}
+	fatalf(ctx, "💥  error extracting worlds: %v", err)
+}
+
+// missingWorldSummary formats a missing world and the directories suggested
+// for it as Markdown.
+func missingWorldSummary(world string, suggestions []string) string {
+	s := "`" + world + "`"
+	if len(suggestions) > 0 {
+		s += " (did you mean `" + strings.Join(suggestions, "` or `") + "`?)"
+	}
+	return s
+}
+
+// keptArchiveIndex returns the index of the archive kept by a previous
+// -keep-intermediate run when it belongs to the same backup, or nil when the
+// backup has to be downloaded.
+func keptArchiveIndex(snap *snapshot.Snapshot, backupUUID string) *extractor.Index {
+	if snap == nil {
+		return nil
+	}
+	if _, err := os.Stat(snap.ArchivePath()); err != nil {
+		return nil
+	}
+	idx, err := extractor.LoadIndex(snap.IndexPath())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  could not analyze web output: %v\n", err)
-	} else {
-		sum.webTotalSize = webReport.TotalSize
-		sum.webFileCount = webReport.FileCount
-		sum.webMaxFileSize = webReport.MaxFileSize
-		fmt.Printf("📊  Web Output Analysis\n")
-		fmt.Printf("    web/ total size:   %s\n", analyzer.FormatSize(webReport.TotalSize))
-		fmt.Printf("    web/ file count:   %d\n", webReport.FileCount)
-		fmt.Printf("    web/ largest file: %s\n", analyzer.FormatSize(webReport.MaxFileSize))
+		warnf("ignoring archive index: %v", err)
+		return nil
+	}
+	if idx == nil || idx.BackupUUID != backupUUID {
+		return nil
+	}
+	return idx
+}
+
+// newCacheBustToken returns a random per-run token for cache-busting query
+// strings.
+func newCacheBustToken() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().Unix(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// fatalf logs a pipeline error, attaches it to the workflow run as an error
+// annotation and exits. When the run was cancelled by SIGINT/SIGTERM, the
+// error is usually just a consequence of the cancellation, so a short notice
+// and exit code 130 are used instead.
+func fatalf(ctx context.Context, format string, args ...any) {
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "\n🛑  cancelled; exiting")
+		exit(130)
+	}
+	msg := fmt.Sprintf(format, args...)
+	if !stdoutIsData {
+		title, text := annotationText(msg)
+		ci.Detect().Annotate(ci.AnnotationError, title, text)
+	}
+	log.Print(msg)
+	exit(1)
+}
+
+// flushOutput waits until the redacted output written so far is out; main
+// sets it when it starts redacting.
+var flushOutput = func() {}
+
+// exit flushes the redacted output and exits with code. The commands use it
+// instead of os.Exit and log.Fatal, which would drop output still in the
+// redacting pipe.
+func exit(code int) {
+	flushOutput()
+	os.Exit(code)
+}
+
+// secretEnvs are the environment variables whose values are masked in all
+// output.
+var secretEnvs = []string{
+	panel.PterodactylKeyEnv, panel.PufferPanelClientSecretEnv, panel.CraftyTokenEnv, panel.AMPPasswordEnv,
+	deploy.FTPPasswordEnv, deploy.S3SecretKeyEnv, deploy.S3SessionTokenEnv, deploy.SSHKeyEnv,
+	access.DefaultCredentialsEnv, webhook.SecretEnv, config.EnvPrefix + "WEBHOOK_URL",
+	"GITHUB_TOKEN", "GITHUB_APP_PRIVATE_KEY", "NETLIFY_AUTH_TOKEN",
+}
+
+// warnf prints a non-fatal problem to stderr and attaches it to the workflow
+// run as a warning annotation.
+func warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintln(os.Stderr, "⚠️  "+msg)
+	if !stdoutIsData {
+		title, text := annotationText(msg)
+		ci.Detect().Annotate(ci.AnnotationWarning, title, text)
 	}
+}
 
-	// Write GitHub Step Summary (no-op if not running inside GitHub Actions).
-	writeGitHubSummary(sum)
+// stdoutIsData is set by commands whose stdout carries data, such as analyze
+// with a machine-readable -analysis-format. warnf and fatalf then only print
+// to stderr and leave out the CI annotation, a workflow command on stdout.
+var stdoutIsData bool
+
+// annotationText turns a log message into an annotation: the emoji prefix is
+// dropped, and the part before the first ": " (e.g. "error rendering")
+// becomes the title.
+func annotationText(msg string) (title, text string) {
+	text = strings.TrimLeftFunc(msg, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.So, r) || r == '\uFE0F'
+	})
+	if before, _, ok := strings.Cut(text, ": "); ok && !strings.Contains(before, "\n") {
+		r, size := utf8.DecodeRuneInString(before)
+		title = string(unicode.ToUpper(r)) + before[size:]
+	}
+	return title, text
+}
+
+// command is a bluemap-action subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string)
+}
+
+// commands lists the subcommands in the order the usage shows them. run is
+// the default when the first argument is a flag or missing.
+var commands = []command{
+	{"run", "download, render and post-process the map in one go (default)", runRun},
+	{"download", "download the backup and extract the worlds (steps 1–2)", runDownload},
+	{"render", "render the extracted worlds with the BlueMap CLI (steps 3–7)", runRender},
+	{"deploy", "post-process the rendered web output and write the CI summary (steps 8–9)", runDeploy},
+	{"analyze", "report world and web output sizes of the server directory", runAnalyze},
+	{"validate", "check config.toml, the panel, the backup and the BlueMap release", runValidate},
+	{"inspect-backup", "list a backup's folders and world candidates", runInspectBackup},
+	{"preview", "serve web/ locally the way the deploy target would", runPreview},
+	{"watch", "run the pipeline on watch.schedule until stopped, with a status endpoint", runWatch},
+}
 
-	fmt.Printf("\n✅  Done!\n")
+// usageFor returns the usage function of a subcommand's flag set, and sends
+// the flag set's errors and usage to the unredacted stderr: they hold no
+// secrets, and flag.ExitOnError exits before the redacting pipe could pass
+// them on.
+func usageFor(fs *flag.FlagSet, name string) func() {
+	fs.SetOutput(redact.Stderr())
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage: bluemap-action %s [flags]\n\n", name)
+		fs.PrintDefaults()
+	}
+}
+
+// printCommands lists the subcommands for "help" and unknown commands.
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: bluemap-action [command] [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun bluemap-action <command> -h for the flags of a command.\n")
+}
+
+func main() {
+	// Cancel the pipeline on SIGINT/SIGTERM: in-flight downloads abort, temp
+	// files are removed and the java child process is killed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Mask secrets and signed URLs in everything printed from here on,
+	// including the output of the child processes.
+	flushOutput = redact.Capture()
+	defer flushOutput()
+	redact.AddEnv(secretEnvs...)
+
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, c := range commands {
+		if c.name == name {
+			c.run(ctx, args)
+			return
+		}
+	}
+	if name == "help" {
+		printCommands(os.Stdout)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printCommands(os.Stderr)
+	exit(2)
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/analyzer"
+	"github.com/EfinaServer/bluemap-action/internal/lastrender"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+)
+
+// fakeConsolePanel records the commands sent over each console connection.
+// A connection stops accepting commands once a backup has been created, like
+// a websocket dropped by Wings while the backup ran.
+type fakeConsolePanel struct {
+	conns      [][]string
+	backedUp   bool
+	noConfirms bool // the server never confirms save-on
+}
+
+type fakeConsole struct {
+	p *fakeConsolePanel
+	i int
+}
+
+func (p *fakeConsolePanel) Name() string { return "Fake" }
+
+func (p *fakeConsolePanel) ListBackups(context.Context, string) ([]panel.Backup, error) {
+	return nil, nil
+}
+
+func (p *fakeConsolePanel) GetDownloadURL(context.Context, string, string) (panel.Download, error) {
+	return panel.Download{}, nil
+}
+
+func (p *fakeConsolePanel) CreateBackup(_ context.Context, _, name string) (*panel.Backup, error) {
+	p.backedUp = true
+	return &panel.Backup{UUID: "new", Name: name}, nil
+}
+
+func (p *fakeConsolePanel) OpenConsole(context.Context, string) (panel.Console, error) {
+	p.conns = append(p.conns, nil)
+	return &fakeConsole{p: p, i: len(p.conns) - 1}, nil
+}
+
+func (c *fakeConsole) State() string { return panel.StateRunning }
+func (c *fakeConsole) Close() error  { return nil }
+
+func (c *fakeConsole) Command(command string) error {
+	if c.p.backedUp && c.i == 0 {
+		return errors.New("websocket closed")
+	}
+	c.p.conns[c.i] = append(c.p.conns[c.i], command)
+	return nil
+}
+
+func (c *fakeConsole) WaitForOutput(_ context.Context, substr string, _ time.Duration) error {
+	if strings.Contains(substr, "Automatic saving") && c.p.noConfirms {
+		return errors.New("timed out")
+	}
+	return nil
+}
+
+func TestCreateFreshBackupResumesSaves(t *testing.T) {
+	p := &fakeConsolePanel{}
+	if _, err := createFreshBackup(context.Background(), p, "abc", true, false); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"save-off", "save-all flush"}, {"save-on"}}
+	if !slices.EqualFunc(p.conns, want, slices.Equal) {
+		t.Errorf("commands per connection = %q, want %q", p.conns, want)
+	}
+
+	p = &fakeConsolePanel{noConfirms: true}
+	if _, err := createFreshBackup(context.Background(), p, "abc", true, false); err == nil || !strings.Contains(err.Error(), "re-enabling world saves") {
+		t.Errorf("err = %v, want the failed save-on", err)
+	}
+}
+
+func TestAnalyzeJSONWithWarningsInCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITEA_ACTIONS", "")
+	t.Setenv("FORGEJO_ACTIONS", "")
+	t.Cleanup(func() { stdoutIsData = false })
+
+	dir := t.TempDir()
+	config := "server_id = \"abc\"\nserver_type = \"plugin\"\nworld_name = \"world\"\nmc_version = \"1.21.4\"\nbluemap_version = \"5.7\"\n"
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A record left in web/maps by an older version that cannot be removed
+	// next to the current one makes moveRecords warn.
+	legacy := filepath.Join(dir, "web", "maps", lastrender.FileName)
+	if err := os.MkdirAll(filepath.Join(legacy, "stuck"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lastrender.Path(dir), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	runAnalyze(context.Background(), []string{"-dir", dir, "-analysis-format", analyzer.FormatJSON})
+	os.Stdout = stdout
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report analyzer.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Errorf("stdout is not the JSON report: %v\n%s", err, data)
+	}
+}
@@ -0,0 +1,1563 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/access"
+	"github.com/EfinaServer/bluemap-action/internal/analyzer"
+	"github.com/EfinaServer/bluemap-action/internal/assets"
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/branding"
+	"github.com/EfinaServer/bluemap-action/internal/ci"
+	"github.com/EfinaServer/bluemap-action/internal/cleanup"
+	"github.com/EfinaServer/bluemap-action/internal/compress"
+	"github.com/EfinaServer/bluemap-action/internal/config"
+	"github.com/EfinaServer/bluemap-action/internal/deploy"
+	"github.com/EfinaServer/bluemap-action/internal/extractor"
+	"github.com/EfinaServer/bluemap-action/internal/freshness"
+	"github.com/EfinaServer/bluemap-action/internal/lang"
+	"github.com/EfinaServer/bluemap-action/internal/lastrender"
+	"github.com/EfinaServer/bluemap-action/internal/manifest"
+	"github.com/EfinaServer/bluemap-action/internal/markers"
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+	"github.com/EfinaServer/bluemap-action/internal/modrinth"
+	"github.com/EfinaServer/bluemap-action/internal/netlify"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
+	"github.com/EfinaServer/bluemap-action/internal/prune"
+	"github.com/EfinaServer/bluemap-action/internal/pwa"
+	"github.com/EfinaServer/bluemap-action/internal/redact"
+	"github.com/EfinaServer/bluemap-action/internal/runreport"
+	"github.com/EfinaServer/bluemap-action/internal/sharelink"
+	"github.com/EfinaServer/bluemap-action/internal/snapshot"
+	"github.com/EfinaServer/bluemap-action/internal/webhook"
+)
+
+// pipelineFlags are the flags shared by the pipeline subcommands. -dir is
+// registered for every one; the others only where they apply.
+type pipelineFlags struct {
+	serverDir        string
+	keepIntermediate bool
+	debugDir         string
+	maps             string
+	announce         bool
+	verbose          bool
+	quiet            bool // banner to stderr, leaving stdout to the data of a command
+}
+
+// newPipelineFlagSet returns the flag set of a pipeline subcommand with the
+// shared -dir flag registered.
+func newPipelineFlagSet(name string, f *pipelineFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&f.serverDir, "dir", ".", "server directory containing config.toml (e.g. onlinemap-01)")
+	fs.BoolVar(&f.verbose, "verbose", false, "log every panel API request with the rate limit budget left")
+	return fs
+}
+
+// addDebugFlags registers -keep-intermediate and -debug-dir.
+func (f *pipelineFlags) addDebugFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&f.keepIntermediate, "keep-intermediate", false, "preserve the backup archive, extracted worlds and render log in a debug directory")
+	fs.StringVar(&f.debugDir, "debug-dir", "", "debug directory for -keep-intermediate (default <dir>/"+snapshot.DefaultDirName+")")
+}
+
+// addMapsFlag registers -maps.
+func (f *pipelineFlags) addMapsFlag(fs *flag.FlagSet) {
+	fs.StringVar(&f.maps, "maps", "", "comma-separated map IDs to render, overriding maps in config.toml (default all maps)")
+}
+
+// pipeline holds what the steps of a build share for one server directory.
+// The run subcommand goes through every phase in one process; download,
+// render and deploy each go through one, so the build can be split across
+// workflow jobs that hand the server directory (extracted worlds, web/ and
+// the state file) on as a cache or artifact.
+type pipeline struct {
+	ctx          context.Context
+	ciEnv        ci.Environment
+	srv          config.LoadedServer
+	sum          *buildSummary
+	snap         *snapshot.Snapshot // nil without -keep-intermediate
+	worldConfigs []config.WorldConfig
+	worlds       []string
+	maps         []string
+	skippedMaps  []string // maps of dimensions their world leaves out
+	verbose      bool
+	client       panel.Panel // created on first use by panel()
+}
+
+// newPipeline loads the server config and prepares the build summary. With
+// resume, the summary saved by an earlier phase in the server directory is
+// loaded first, so the final job's CI summary covers the whole build.
+func newPipeline(ctx context.Context, f *pipelineFlags, resume bool) *pipeline {
+	toolVersion := getVersion()
+	out := os.Stdout
+	if f.quiet {
+		out = os.Stderr
+		stdoutIsData = true
+	}
+	fmt.Fprintf(out, "🗺  bluemap-action %s\n\n", toolVersion)
+
+	// Load config from the server directory.
+	srv, err := config.Load(f.serverDir)
+	if err != nil {
+		fatalf(ctx, "💥  loading config: %v", err)
+	}
+	if len(srv.EnvOverrides) > 0 {
+		fmt.Fprintf(out, "🔧  Overridden from the environment: %s\n\n", strings.Join(srv.EnvOverrides, ", "))
+	}
+	redact.Add(srv.Config.WebhookURL)
+	redact.AddEnv(srv.Config.Access.ResolveCredentialsEnv())
+	if err := proxy.Apply(srv.Config.ProxyURL); err != nil {
+		fatalf(ctx, "💥  proxy_url: %v", err)
+	}
+
+	moveRecords(srv.Dir)
+
+	if f.maps != "" {
+		var ids []string
+		for _, id := range strings.Split(f.maps, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if err := config.CheckMaps(srv.Dir, ids); err != nil {
+			fatalf(ctx, "💥  -maps: %v", err)
+		}
+		srv.Config.Maps = ids
+	}
+	maps, skippedMaps, err := srv.Config.ResolveRenderMaps(srv.Dir)
+	if err != nil {
+		fatalf(ctx, "💥  %v", err)
+	}
+	if len(skippedMaps) > 0 {
+		fmt.Fprintf(out, "🚫  Skipping maps of left-out dimensions: %s\n\n", strings.Join(skippedMaps, ", "))
+	}
+
+	p := &pipeline{
+		ctx:          ctx,
+		ciEnv:        ci.Detect(),
+		srv:          srv,
+		sum:          &buildSummary{},
+		worldConfigs: srv.Config.ResolveWorldConfigs(),
+		worlds:       srv.Config.ResolveWorlds(),
+		maps:         maps,
+		skippedMaps:  skippedMaps,
+		verbose:      f.verbose,
+	}
+	if resume {
+		if err := loadState(srv.Dir, srv.Config.ServerID, p.sum); err != nil {
+			warnf("could not load %s: %v", stateFileName, err)
+		}
+	}
+
+	projectName := filepath.Base(srv.Dir)
+	if srv.Config.Name != "" {
+		projectName = srv.Config.Name
+	}
+	worldNames := make([]string, len(p.worldConfigs))
+	for i, w := range p.worldConfigs {
+		worldNames[i] = w.Name
+	}
+	sum := p.sum
+	sum.ToolVersion = toolVersion
+	sum.ProjectName = projectName
+	sum.ServerID = srv.Config.ServerID
+	sum.ServerType = srv.Config.ServerType
+	sum.WorldNames = worldNames
+	sum.MCVersion = srv.Config.MinecraftVersion
+	sum.BlueMapSpec = srv.Config.BlueMapVersion
+	sum.Maps = p.maps
+	if sum.BlueMapVersion == "" {
+		sum.BlueMapVersion = srv.Config.BlueMapVersion
+	}
+	if sum.RenderTime == "" {
+		stampRender(sum, srv.Config)
+	}
+	return p
+}
+
+// stampRender records the current time as the render time of sum, and as
+// shown in the summary in the timezone and time_format of cfg. The lang
+// files format it again per locale.
+func stampRender(sum *buildSummary, cfg config.ServerConfig) {
+	sum.RenderedAt = time.Now()
+	sum.RenderTime = cfg.FormatTime(sum.RenderedAt)
+}
+
+// panelClient returns the panel of panel_type with the address and
+// credentials from its environment variables, exiting when one is missing.
+// With verbose, every Pterodactyl API request is logged with the rate limit
+// budget left.
+func panelClient(panelType string, verbose bool) panel.Panel {
+	client, err := panel.FromEnv(panelType)
+	if err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	if pt, ok := client.(*panel.Pterodactyl); ok && verbose {
+		pt.Client.Log = func(format string, args ...any) {
+			fmt.Printf("    🌐 "+format+"\n", args...)
+		}
+	}
+	return client
+}
+
+// printAPIBudget prints the rate limit budget a Pterodactyl client used.
+func printAPIBudget(client panel.Panel) {
+	if pt, ok := client.(*panel.Pterodactyl); ok {
+		fmt.Printf("🚦  Pterodactyl API: %s\n", pt.Client.Budget())
+	}
+}
+
+// panel returns the server's panel client, shared by the steps of a phase
+// so they draw on one rate limit budget.
+func (p *pipeline) panel() panel.Panel {
+	if p.client == nil {
+		p.client = panelClient(p.srv.Config.PanelType, p.verbose)
+	}
+	return p.client
+}
+
+// printHeader prints the server configuration at the start of a phase.
+func (p *pipeline) printHeader() {
+	cfg := p.srv.Config
+	fmt.Printf("📋  %s  (server: %s)\n", p.sum.ProjectName, cfg.ServerID)
+	fmt.Printf("    server type:        %s\n", cfg.ServerType)
+	fmt.Printf("    world name:         %s\n", strings.Join(p.sum.WorldNames, ", "))
+	fmt.Printf("    worlds:             %v\n", p.worlds)
+	fmt.Printf("    minecraft version:  %s\n", cfg.MinecraftVersion)
+	fmt.Printf("    bluemap version:    %s\n", cfg.BlueMapVersion)
+	if len(p.maps) > 0 {
+		fmt.Printf("    maps:               %s\n", strings.Join(p.maps, ", "))
+	}
+	fmt.Printf("    download mode:      %s\n", cfg.ResolveDownloadMode())
+	if pr := proxy.Describe(); pr != "" {
+		fmt.Printf("    proxy:              %s\n", pr)
+	}
+	if cfg.DownloadRateLimit != "" {
+		fmt.Printf("    download limit:     %s\n", cfg.DownloadRateLimit)
+	}
+	if cfg.DownloadConnections > 0 {
+		fmt.Printf("    download conns:     %d (manual)\n\n", cfg.DownloadConnections)
+	} else {
+		fmt.Printf("    download conns:     auto\n\n")
+	}
+}
+
+// keepIntermediate creates the debug directory for -keep-intermediate.
+func (p *pipeline) keepIntermediate(f *pipelineFlags) {
+	if !f.keepIntermediate {
+		return
+	}
+	dir := f.debugDir
+	if dir == "" {
+		dir = filepath.Join(p.srv.Dir, snapshot.DefaultDirName)
+	}
+	snap, err := snapshot.New(dir)
+	if err != nil {
+		fatalf(p.ctx, "💥  error creating debug directory: %v", err)
+	}
+	p.snap = snap
+	fmt.Printf("🐞  Keeping intermediate artifacts in %s\n\n", snap.Dir)
+}
+
+// download fetches the backup and extracts the worlds (step 1), applies the
+// optional world trimming and region checks, and reports the world sizes
+// (step 2). With skip_if_unchanged it returns false without downloading when
+// the backup was already rendered.
+func (p *pipeline) download(client panel.Panel) bool {
+	ctx, srv, sum := p.ctx, p.srv, p.sum
+
+	// Step 1: Download and extract world data from the panel backup.
+	var backup *panel.Backup
+	var err error
+	if srv.Config.FreshBackup {
+		backupStart := time.Now()
+		backup, err = createFreshBackup(ctx, client, srv.Config.ServerID, srv.Config.PauseSaves, srv.Config.FlushSaves)
+		if err != nil {
+			fatalf(ctx, "💥  error creating fresh backup: %v", err)
+		}
+		sum.addStep("Fresh Backup", time.Since(backupStart))
+		fmt.Printf("💾  Fresh backup: %s (%s, %s)\n", backup.Name, backup.UUID, analyzer.FormatSize(backup.Bytes))
+	} else {
+		backup, err = panel.LatestBackup(ctx, client, srv.Config.ServerID)
+		if err != nil {
+			fatalf(ctx, "💥  error getting latest backup: %v", err)
+		}
+		fmt.Printf("💾  Latest backup: %s (%s, %s)\n", backup.Name, backup.UUID, analyzer.FormatSize(backup.Bytes))
+	}
+
+	sum.BackupName = backup.Name
+	sum.BackupDate = srv.Config.FormatTime(backup.CreatedAt)
+	sum.BackupCreated = backup.CreatedAt
+	sum.BackupUUID = backup.UUID
+	sum.BackupChecksum = backup.Checksum
+	sum.BackupSize = backup.Bytes
+
+	if srv.Config.SkipIfUnchanged && p.unchanged() {
+		if p.verbose {
+			printAPIBudget(client)
+		}
+		return false
+	}
+
+	downloadStart := time.Now()
+	dlOpts := extractor.DownloadOptions{
+		Mode:        srv.Config.ResolveDownloadMode(),
+		Connections: srv.Config.ResolveDownloadConnections(),
+		RateLimit:   srv.Config.ResolveDownloadRateLimit(),
+		Checksum:    backup.Checksum,
+
+		StreamBuffer: srv.Config.ResolveDownloadBuffer(),
+	}
+	dlOpts.DecompressBlockSize, dlOpts.DecompressBlocks = srv.Config.ResolveDecompression()
+	dlOpts.Writers = srv.Config.ExtractWorkers
+	dlOpts.MaxFileSize, dlOpts.MaxTotalSize, dlOpts.MaxEntries = srv.Config.ResolveExtractLimits()
+	var timings extractor.Timings
+	dlOpts.Timings = &timings
+	dlOpts.Notice = func(message string) {
+		p.ciEnv.Annotate(ci.AnnotationNotice, "Backup download", message)
+	}
+	dlOpts.Sources, dlOpts.Include = worldFilters(p.worldConfigs)
+	dlOpts.Extra = append(slices.Clone(srv.Config.ExtraPaths), markers.DataPaths(srv.Config.Markers.Sources)...)
+	if srv.Config.ResolveFailOnMissingWorlds() {
+		for _, w := range p.worldConfigs {
+			dlOpts.RequireWorlds = append(dlOpts.RequireWorlds, w.RequiredFolders()...)
+		}
+	}
+	dlOpts.OnMissing = func(world string, suggestions []string) {
+		sum.MissingWorlds = append(sum.MissingWorlds, missingWorldSummary(world, suggestions))
+		p.ciEnv.Annotate(ci.AnnotationWarning, "Missing world", missingWorldSummary(world, suggestions)+" was not found in the backup")
+	}
+	dlOpts.OnRejected = func(entry, reason string) {
+		p.ciEnv.Annotate(ci.AnnotationWarning, "Unsafe backup entry", fmt.Sprintf("skipped %q: %s", entry, reason))
+	}
+	if dlOpts.Mode == config.DownloadModeIndexed {
+		dlOpts.SeekIndexPath = filepath.Join(srv.Dir, extractor.SeekIndexName)
+		dlOpts.BackupUUID = backup.UUID
+	}
+	if p.snap != nil {
+		dlOpts.KeepArchive = p.snap.ArchivePath()
+		dlOpts.IndexPath = p.snap.IndexPath()
+		dlOpts.BackupUUID = backup.UUID
+	}
+
+	if idx := keptArchiveIndex(p.snap, backup.UUID); idx != nil {
+		fmt.Printf("📂  Reusing kept archive %s (%d entries indexed)\n", p.snap.ArchivePath(), len(idx.Entries))
+		fmt.Printf("⬇️   Extracting worlds: %v\n", p.worlds)
+		if err := extractor.ExtractArchive(ctx, p.snap.ArchivePath(), idx, srv.Dir, p.worlds, dlOpts); err != nil {
+			fatalExtract(ctx, p.ciEnv, err)
+		}
+	} else {
+		// fatalf exits without running deferred calls, so the lock is
+		// released explicitly before each exit.
+		unlock := p.lockBackup(client, backup)
+		dl, err := client.GetDownloadURL(ctx, srv.Config.ServerID, backup.UUID)
+		if err != nil {
+			unlock()
+			fatalf(ctx, "💥  error getting download URL: %v", err)
+		}
+		dlOpts.Header = dl.Header
+
+		fmt.Printf("⬇️   Downloading and extracting worlds: %v\n", p.worlds)
+		if backup.Checksum == "" {
+			fmt.Println("  → the panel reported no checksum; the download is not verified")
+		}
+		err = extractor.DownloadAndExtractWorlds(ctx, dl.URL, srv.Dir, p.worlds, dlOpts)
+		unlock()
+		if err != nil {
+			fatalExtract(ctx, p.ciEnv, err)
+		}
+	}
+	downloadDur := time.Since(downloadStart)
+
+	sum.DownloadDur = downloadDur
+	sum.addStep("Probe", timings.Probe)
+	sum.addStep("Download", timings.Download)
+	sum.addStep("Extract", timings.Extract)
+	fmt.Printf("⏱   Download + extraction took %s\n", fmtDuration(downloadDur))
+	for _, path := range srv.Config.ExtraPaths {
+		if _, err := os.Stat(filepath.Join(srv.Dir, filepath.FromSlash(path))); err != nil {
+			warnf("extra path %q was not found in the backup", path)
+		}
+	}
+
+	if p.snap != nil {
+		if err := p.snap.KeepWorlds(srv.Dir, p.worlds); err != nil {
+			fatalf(ctx, "💥  error preserving extracted worlds: %v", err)
+		}
+		fmt.Printf("🐞  Extracted worlds preserved in %s\n", p.snap.WorldsDir())
+	}
+
+	// Optional: trim worlds to their bounds so areas outside are not rendered.
+	if hasBounds(p.worldConfigs) {
+		fmt.Println()
+		if err := trimWorlds(srv.Dir, p.worldConfigs, sum); err != nil {
+			fatalf(ctx, "💥  error trimming worlds: %v", err)
+		}
+	}
+
+	// Optional: check region files before spending hours on a render.
+	if mode := srv.Config.ResolveRegionCheck(); mode != mca.ModeOff {
+		fmt.Println()
+		if err := checkRegions(srv.Dir, p.worlds, mode == mca.ModeQuarantine, sum); err != nil {
+			fatalf(ctx, "💥  error checking region files: %v", err)
+		}
+	}
+
+	// Step 2: Analyze extracted world sizes.
+	p.analyzeWorlds()
+	worldBudget, _ := srv.Config.ResolveBudgets()
+	p.checkBudget("extracted worlds", "max_world_size", p.sum.WorldTotal, worldBudget)
+	if p.verbose {
+		fmt.Println()
+		printAPIBudget(client)
+	}
+	return true
+}
+
+// lockTimeout bounds the unlock request, which also runs after the run was
+// cancelled.
+const lockTimeout = 30 * time.Second
+
+// lockBackup locks the backup on the panel with lock_backup, so the panel's
+// backup rotation cannot delete it mid-download, and returns the function
+// restoring its previous state. A backup that was already locked stays
+// locked. Lock failures only warn: the download may still succeed.
+func (p *pipeline) lockBackup(client panel.Panel, backup *panel.Backup) (unlock func()) {
+	ctx, serverID := p.ctx, p.srv.Config.ServerID
+	if !p.srv.Config.LockBackup {
+		return func() {}
+	}
+	if backup.Locked {
+		fmt.Println("🔒  Backup is already locked")
+		return func() {}
+	}
+	locker, ok := client.(panel.BackupLocker)
+	if !ok {
+		warnf("lock_backup: %s cannot lock backups", client.Name())
+		return func() {}
+	}
+	if err := locker.SetBackupLocked(ctx, serverID, backup.UUID, true); err != nil {
+		warnf("lock_backup: could not lock backup %s: %v", backup.Name, err)
+		return func() {}
+	}
+	fmt.Println("🔒  Backup locked for the download")
+	return func() {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockTimeout)
+		defer cancel()
+		if err := locker.SetBackupLocked(unlockCtx, serverID, backup.UUID, false); err != nil {
+			warnf("lock_backup: could not unlock backup %s, unlock it in the panel: %v", backup.Name, err)
+			return
+		}
+		fmt.Println("🔓  Backup unlocked")
+	}
+}
+
+// renderRecord describes the input of this build for skip_if_unchanged,
+// with the given config hash.
+func (p *pipeline) renderRecord(hash string) lastrender.Record {
+	return lastrender.Record{
+		BackupUUID:     p.sum.BackupUUID,
+		BackupChecksum: p.sum.BackupChecksum,
+		ConfigHash:     hash,
+		Maps:           p.sum.Maps,
+		BlueMapVersion: p.sum.BlueMapVersion,
+		RenderTime:     p.sum.RenderTime,
+	}
+}
+
+// configHash hashes the configuration of the server directory for
+// skip_if_unchanged. It must run before the render: sqlite storage, resource
+// packs and mods write into config/, which a fresh checkout does not have.
+func (p *pipeline) configHash() (string, error) {
+	var assets []string
+	for _, img := range []string{p.srv.Config.Branding.Favicon, p.srv.Config.Branding.Logo} {
+		if img != "" {
+			assets = append(assets, img)
+		}
+	}
+	return lastrender.ConfigHash(p.srv.Dir, version, assets)
+}
+
+// unchanged reports whether the backup was already rendered with the current
+// config and maps, according to the record the last deploy left next to
+// config.toml. The summary then takes the render details from the record.
+// The config hash is kept in the summary for recordRender.
+func (p *pipeline) unchanged() bool {
+	rec, err := lastrender.Load(p.srv.Dir)
+	if err != nil {
+		warnf("could not read the last render: %v", err)
+		return false
+	}
+	hash, err := p.configHash()
+	if err != nil {
+		warnf("could not hash the config: %v", err)
+		return false
+	}
+	p.sum.ConfigHash = hash
+	if rec == nil {
+		fmt.Printf("  → no record of an earlier render in %s; rendering\n", filepath.Dir(lastrender.Path(p.srv.Dir)))
+		return false
+	}
+	if !rec.Matches(p.renderRecord(hash)) {
+		fmt.Println("  → the backup or config changed since the last render; rendering")
+		return false
+	}
+	p.sum.Unchanged = true
+	p.sum.BlueMapVersion = rec.BlueMapVersion
+	p.sum.RenderTime = rec.RenderTime
+	p.sum.RenderedAt = time.Time{}
+	return true
+}
+
+// recordRender saves the input of this build for skip_if_unchanged as
+// pending, like the file manifest, so it only replaces the record once the
+// map is published (see publishRecords). Without the config hash taken by
+// unchanged nothing is recorded, since hashing config/ after the render would
+// never match the next checkout. A failure only warns; the next run then
+// renders again.
+func (p *pipeline) recordRender() {
+	if p.sum.BackupUUID == "" || p.sum.ConfigHash == "" {
+		return
+	}
+	rec := p.renderRecord(p.sum.ConfigHash)
+	if err := rec.Save(lastrender.Path(p.srv.Dir) + pendingSuffix); err != nil {
+		warnf("could not record the render for skip_if_unchanged: %v", err)
+	}
+}
+
+// moveRecords moves the records of earlier builds from web/maps/, where
+// older versions kept them and the deploy published them, next to
+// config.toml.
+func moveRecords(serverDir string) {
+	for _, path := range []string{lastrender.Path(serverDir), manifest.Path(serverDir), runreport.Path(serverDir)} {
+		old := filepath.Join(serverDir, "web", "maps", filepath.Base(path))
+		if _, err := os.Stat(old); err != nil {
+			continue
+		}
+		var err error
+		if _, statErr := os.Stat(path); statErr == nil {
+			err = os.Remove(old)
+		} else {
+			err = os.Rename(old, path)
+		}
+		if err != nil {
+			warnf("could not move %s out of web/maps: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// finishUnchanged ends a build whose backup was already rendered, with a
+// short CI summary and the skipped output set.
+func (p *pipeline) finishUnchanged() {
+	fmt.Printf("\n⏭   Backup %s was already rendered at %s with this config; nothing to do\n", p.sum.BackupUUID, p.sum.RenderTime)
+	writeUnchangedSummary(p.ciEnv, p.sum)
+	writeOutputs(p.ciEnv, p.sum)
+	fmt.Printf("\n✅  Done!\n")
+}
+
+// analyzeWorlds reports the size and chunk statistics of the extracted
+// worlds.
+func (p *pipeline) analyzeWorlds() {
+	fmt.Println()
+	worldRows, worldTotal := analyzer.AnalyzeWorldSizes(p.srv.Dir, p.worldConfigs)
+	analyzer.PrintWorldAnalysis(worldRows, worldTotal)
+	p.sum.WorldRows = worldRows
+	p.sum.WorldTotal = worldTotal
+
+	fmt.Println()
+	regionStats, err := analyzer.AnalyzeRegions(p.srv.Dir, p.worlds, p.srv.Config.InhabitedStats)
+	if err != nil {
+		warnf("could not read chunk statistics: %v", err)
+	} else {
+		analyzer.PrintRegionStats(regionStats)
+		p.sum.RegionStats = regionStats
+	}
+}
+
+// render fetches the BlueMap CLI, deploys the files BlueMap and the hosting
+// need, runs the custom scripts and renders the maps (steps 3–7), with the
+// optional marker generation and tile pruning.
+func (p *pipeline) render() {
+	ctx, srv, sum := p.ctx, p.srv, p.sum
+
+	// Step 3: Download BlueMap CLI.
+	fmt.Println()
+	if bluemap.IsDynamicVersion(srv.Config.BlueMapVersion) {
+		fmt.Printf("📦  BlueMap CLI (%s)\n", srv.Config.BlueMapVersion)
+	} else {
+		fmt.Printf("📦  BlueMap CLI v%s\n", srv.Config.BlueMapVersion)
+	}
+	jarStart := time.Now()
+	blueMapVersion, err := bluemap.ResolveVersion(ctx, srv.Dir, srv.Config.BlueMapVersion, srv.Config.BlueMapLock)
+	if err != nil {
+		fatalf(ctx, "💥  error resolving BlueMap version: %v", err)
+	}
+	sum.BlueMapVersion = blueMapVersion
+	if _, tested := bluemap.CompatibleLayout(blueMapVersion); !tested {
+		warnf("BlueMap v%s is untested with this tool (tested: %s); the web output checks below will flag layout changes",
+			blueMapVersion, bluemap.TestedVersions())
+	}
+
+	jarURLs := bluemap.DownloadURLs(blueMapVersion, srv.Config.BlueMapDownloadURL, srv.Config.BlueMapMirrors)
+	jarPath, err := bluemap.EnsureCLI(ctx, srv.Dir, blueMapVersion, srv.Config.BlueMapSHA256, jarURLs)
+	if err != nil {
+		fatalf(ctx, "💥  error downloading BlueMap CLI: %v", err)
+	}
+	sum.addStep("Jar Fetch", time.Since(jarStart))
+
+	// Step 4: Deploy language files before rendering.
+	langDir := filepath.Join(srv.Dir, "web", "lang")
+	langCfg := lang.DeployConfig{
+		ToolVersion:      sum.ToolVersion,
+		MinecraftVersion: srv.Config.MinecraftVersion,
+		ProjectName:      sum.ProjectName,
+		RenderTime:       sum.RenderTime,
+		BackupName:       sum.BackupName,
+		BackupDate:       sum.BackupDate,
+		FormatTime:       srv.Config.FormatLocaleTime,
+		RenderedAt:       sum.RenderedAt,
+		BackupCreated:    sum.BackupCreated,
+		WorldSize:        analyzer.FormatSize(sum.WorldTotal),
+		MapURL:           srv.Config.MapURL,
+		Placeholders:     srv.Config.Placeholders,
+		OverrideDir:      filepath.Join(srv.Dir, lang.OverrideDirName),
+	}
+
+	fmt.Printf("\n📝  Deploying language files → %s\n", langDir)
+	if err := lang.Deploy(langDir, langCfg); err != nil {
+		fatalf(ctx, "💥  error deploying lang files: %v", err)
+	}
+
+	// Step 5: Deploy netlify.toml for static site hosting.
+	fmt.Printf("📝  Deploying netlify.toml → %s\n", filepath.Join(srv.Dir, "web"))
+	netlifyOpts := netlify.Options{
+		SecurityHeaders:       srv.Config.ResolveSecurityHeaders(),
+		ContentSecurityPolicy: srv.Config.ContentSecurityPolicy,
+		Cache:                 srv.Config.Cache.Policy(srv.Config.CacheBust),
+	}
+	if err := netlify.DeployConfig(srv.Dir, netlifyOpts); err != nil {
+		fatalf(ctx, "💥  error deploying netlify.toml: %v", err)
+	}
+
+	fmt.Printf("📝  Deploying share link helper → %s\n", filepath.Join(srv.Dir, "web", "go"))
+	if err := sharelink.Deploy(srv.Dir); err != nil {
+		fatalf(ctx, "💥  error deploying share link helper: %v", err)
+	}
+
+	// Optional: install the resource packs BlueMap needs for custom blocks.
+	if len(srv.Config.ResourcePacks) > 0 {
+		fmt.Printf("\n🎨  Installing resource packs → %s\n", filepath.Join(srv.Dir, bluemap.ResourcePackDir))
+		if err := bluemap.InstallResourcePacks(ctx, srv.Dir, srv.Config.ResourcePacks); err != nil {
+			fatalf(ctx, "💥  error installing resource packs: %v", err)
+		}
+	}
+
+	// Optional: install the mod and datapack jars of a modded world.
+	if len(srv.Config.Mods) > 0 {
+		fmt.Printf("\n🧩  Installing mods → %s\n", filepath.Join(srv.Dir, bluemap.ModDir))
+		client := modrinth.NewClient("bluemap-action/" + sum.ToolVersion)
+		if err := bluemap.InstallMods(ctx, client, srv.Dir, srv.Config.MinecraftVersion, srv.Config.ResolveMods()); err != nil {
+			fatalf(ctx, "💥  error installing mods: %v", err)
+		}
+	}
+
+	// Step 6: Run custom scripts.
+	fmt.Printf("\n🔧  Running custom scripts...\n")
+	failures, err := bluemap.RunScripts(ctx, srv.Dir)
+	for _, failure := range failures {
+		warnf("%v (fatal = false; continuing)", failure)
+	}
+	if err != nil {
+		fatalf(ctx, "💥  error running custom scripts: %v", err)
+	}
+
+	// Optional: generate markers from plugin, player and sign data and
+	// markers.toml, which the scripts above may also have written.
+	var markerResults []markers.MapResult
+	_, staticErr := os.Stat(markers.StaticPath(srv.Dir))
+	if len(srv.Config.Markers.Sources) > 0 || staticErr == nil {
+		fmt.Println()
+		markerResults, err = generateMarkers(ctx, srv.Dir, srv.Config.Markers, staticErr == nil, sum)
+		if err != nil {
+			warnf("could not generate markers: %v", err)
+		}
+	}
+
+	// Optional: render into the SQLite database kept between runs.
+	if srv.Config.ResolveStorage() == config.StorageSQLite {
+		switched, err := bluemap.ConfigureSQLite(srv.Dir)
+		if err != nil {
+			fatalf(ctx, "💥  error configuring SQLite storage: %v", err)
+		}
+		fmt.Printf("\n🗄  Rendering into %s", filepath.Join(srv.Dir, bluemap.SQLiteDBName))
+		if _, err := os.Stat(filepath.Join(srv.Dir, bluemap.SQLiteDBName)); err != nil {
+			fmt.Print(" (new database: full render)")
+		}
+		fmt.Println()
+		if len(switched) > 0 {
+			fmt.Printf("  → storage of map(s) %s set to %q\n", strings.Join(switched, ", "), bluemap.SQLiteStorageID)
+		}
+	}
+
+	// Step 7: Execute BlueMap CLI rendering.
+	fmt.Printf("\n🔨  Running BlueMap CLI render...\n")
+	stallTimeout, renderTimeout := srv.Config.ResolveRenderTimeouts()
+	renderOpts := bluemap.RenderOptions{
+		JavaArgs:     srv.Config.JavaArgs,
+		MaxMemory:    srv.Config.MaxMemory,
+		Maps:         p.maps,
+		StallTimeout: stallTimeout,
+		Timeout:      renderTimeout,
+
+		ProgressInterval: srv.Config.ResolveRenderProgress(),
+		MapTimings:       &sum.MapTimings,
+	}
+	renderOpts.Notice = func(message string) {
+		p.ciEnv.Annotate(ci.AnnotationNotice, "Render", message)
+	}
+	renderOpts.LogPath = filepath.Join(srv.Dir, bluemap.RenderLogName)
+	if p.snap != nil {
+		renderOpts.LogPath = p.snap.RenderLogPath()
+		script, err := p.snap.WriteReproScript(srv.Dir, p.worlds, bluemap.RenderCommand(jarPath, srv.Config.MinecraftVersion, renderOpts))
+		if err != nil {
+			fatalf(ctx, "💥  error writing reproduce script: %v", err)
+		}
+		fmt.Printf("🐞  Render log: %s\n", renderOpts.LogPath)
+		fmt.Printf("🐞  Reproduce locally with: sh %s\n", script)
+	}
+	renderDur, err := bluemap.Render(ctx, jarPath, srv.Dir, srv.Config.MinecraftVersion, renderOpts)
+	if err != nil {
+		var renderErr *bluemap.RenderError
+		if errors.As(err, &renderErr) && ctx.Err() == nil {
+			p.reportRenderFailure(renderErr, renderOpts.LogPath)
+		}
+		fatalf(ctx, "💥  error during rendering: %v", err)
+	}
+	sum.RenderDur = renderDur
+	sum.addStep("Render", renderDur)
+	fmt.Printf("⏱   Render took %s\n", fmtDuration(renderDur))
+
+	if srv.Config.ResolveStorage() == config.StorageSQLite {
+		fmt.Printf("\n🗄  Exporting %s → %s\n", bluemap.SQLiteDBName, filepath.Join(srv.Dir, "web", "maps"))
+		exportStart := time.Now()
+		files, changed, err := bluemap.ExportSQLite(ctx, srv.Dir)
+		if err != nil {
+			fatalf(ctx, "💥  error exporting the SQLite storage: %v", err)
+		}
+		sum.addStep("SQLite Export", time.Since(exportStart))
+		fmt.Printf("  ✔  %d file(s) exported in %s, %d changed\n", files, fmtDuration(time.Since(exportStart)), changed)
+	}
+
+	// Optional: swap in the webapp of another BlueMap release.
+	if srv.Config.WebappVersion != "" || srv.Config.WebappURL != "" {
+		p.replaceWebapp(langDir, langCfg)
+	}
+
+	if markerResults != nil && srv.Config.Markers.ResolveFormat() == markers.FormatJSON {
+		if err := writeMarkers(srv.Dir, markerResults, sum); err != nil {
+			warnf("could not write markers: %v", err)
+		}
+	}
+
+	// Optional: prune tiles whose source regions no longer exist.
+	if srv.Config.PruneTiles != prune.ModeOff {
+		fmt.Println()
+		dryRun := srv.Config.PruneTiles == prune.ModeDryRun
+		if sum.Quarantined && !dryRun {
+			// Quarantined regions look deleted; keep their tiles.
+			warnf("region files were quarantined; pruning as a dry run only")
+			dryRun = true
+		}
+		if err := pruneTiles(srv.Dir, dryRun, sum); err != nil {
+			fatalf(ctx, "💥  error pruning stale tiles: %v", err)
+		}
+	}
+}
+
+// replaceWebapp replaces the webapp the CLI generated with the one of
+// webapp_version or webapp_url, then deploys the language files again over
+// the ones the new webapp brought.
+func (p *pipeline) replaceWebapp(langDir string, langCfg lang.DeployConfig) {
+	ctx, srv, sum := p.ctx, p.srv, p.sum
+	var archive string
+	if spec := srv.Config.WebappVersion; spec != "" {
+		version := spec
+		if bluemap.IsDynamicVersion(spec) {
+			v, err := bluemap.CheckRelease(ctx, spec)
+			if err != nil {
+				fatalf(ctx, "💥  webapp_version: %v", err)
+			}
+			version = v
+		}
+		fmt.Printf("\n🖼   Replacing the webapp with the one of BlueMap v%s\n", version)
+		urls := bluemap.DownloadURLs(version, srv.Config.BlueMapDownloadURL, srv.Config.BlueMapMirrors)
+		jarPath, err := bluemap.EnsureCLI(ctx, srv.Dir, version, "", urls)
+		if err != nil {
+			fatalf(ctx, "💥  error downloading the webapp_version jar: %v", err)
+		}
+		archive = jarPath
+		sum.Webapp, sum.WebappVersion = "v"+version, version
+	} else {
+		fmt.Printf("\n🖼   Replacing the webapp with %s\n", srv.Config.WebappURL)
+		archive = filepath.Join(srv.Dir, ".webapp-download.zip")
+		defer os.Remove(archive)
+		if err := bluemap.FetchWebapp(ctx, srv.Config.WebappURL, archive); err != nil {
+			fatalf(ctx, "💥  error downloading webapp_url: %v", err)
+		}
+		sum.Webapp = srv.Config.WebappURL
+	}
+
+	files, err := bluemap.ReplaceWebapp(srv.Dir, archive)
+	if err != nil {
+		fatalf(ctx, "💥  error replacing the webapp: %v", err)
+	}
+	fmt.Printf("  ✔  %d webapp file(s) written to %s\n", files, filepath.Join(srv.Dir, "web"))
+	if err := lang.Deploy(langDir, langCfg); err != nil {
+		fatalf(ctx, "💥  error deploying lang files: %v", err)
+	}
+}
+
+// deploy post-processes the rendered web output for hosting (step 8),
+// reports its size (step 9) and writes the CI summary and outputs.
+func (p *pipeline) deploy() {
+	ctx, srv, sum := p.ctx, p.srv, p.sum
+	rewriteStart := time.Now()
+
+	// Check that BlueMap's web output still has the layout the rewrites
+	// below expect; otherwise they would silently change nothing.
+	// A webapp_version webapp has the layout of its own release.
+	webappVersion := cmp.Or(sum.WebappVersion, sum.BlueMapVersion)
+	if bluemap.IsDynamicVersion(webappVersion) {
+		warnf("BlueMap version %q was not resolved by a render in this directory; skipping the web output check", webappVersion)
+	} else {
+		problems, err := bluemap.CheckWebOutput(srv.Dir, webappVersion)
+		if err != nil {
+			warnf("could not check web output: %v", err)
+		}
+		for _, problem := range problems {
+			warnf("web output: %s", problem)
+		}
+		sum.WebProblems = problems
+	}
+
+	// Optional: apply the [branding] table to the webapp.
+	brandingOpts := branding.Options{
+		Title:       srv.Config.Branding.Title,
+		Favicon:     srv.Config.Branding.Favicon,
+		Logo:        srv.Config.Branding.Logo,
+		AccentColor: srv.Config.Branding.AccentColor,
+		MapURL:      srv.Config.MapURL,
+	}
+	if brandingOpts.Enabled() {
+		fmt.Printf("\n🎨  Applying branding to web/index.html...\n")
+		if err := branding.Apply(srv.Dir, brandingOpts); err != nil {
+			fatalf(ctx, "💥  error applying branding: %v", err)
+		}
+	}
+
+	// Optional: apply the [webapp] table to the generated settings.json files
+	// and hide the maps of left-out dimensions.
+	settings := srv.Config.Webapp.Settings()
+	settings.HiddenMaps = p.skippedMaps
+	if settings.Enabled() {
+		fmt.Printf("\n⚙️   Patching web/settings.json...\n")
+		if err := bluemap.PatchSettings(srv.Dir, settings); err != nil {
+			fatalf(ctx, "💥  error patching the webapp settings: %v", err)
+		}
+	}
+
+	// Optional: tell visitors how old the map data is.
+	if srv.Config.FreshnessBanner {
+		opts := freshness.Options{Rendered: sum.RenderTime}
+		if !sum.BackupCreated.IsZero() {
+			opts.Backup = sum.BackupDate
+		}
+		fmt.Printf("\n🕒  Adding the freshness banner to web/index.html: %s\n", freshness.Text(opts))
+		if err := freshness.Apply(srv.Dir, opts); err != nil {
+			fatalf(ctx, "💥  error adding the freshness banner: %v", err)
+		}
+	}
+
+	// Optional: make the map an installable web app.
+	if srv.Config.PWA {
+		fmt.Printf("\n📲  Generating web app manifest and service worker...\n")
+		pwaOpts := pwa.Options{
+			Name:       sum.ProjectName,
+			ThemeColor: srv.Config.Branding.AccentColor,
+			Version:    newCacheBustToken(),
+		}
+		if srv.Config.Branding.Title != "" {
+			pwaOpts.Name = srv.Config.Branding.Title
+		}
+		if srv.Config.Branding.Logo != "" {
+			pwaOpts.Icon = "logo" + strings.ToLower(filepath.Ext(srv.Config.Branding.Logo))
+		}
+		if err := pwa.Deploy(srv.Dir, pwaOpts); err != nil {
+			fatalf(ctx, "💥  error generating web app files: %v", err)
+		}
+	}
+
+	// Optional: restrict who can view the map.
+	if target := srv.Config.Access.Target; target != "" {
+		fmt.Printf("\n🔒  Setting up access protection (%s)...\n", target)
+		accessOpts := access.Options{
+			Target: target,
+			Emails: srv.Config.Access.Emails,
+			MapURL: srv.Config.MapURL,
+		}
+		sum.Access = target
+		if access.NeedsCredentials(target) {
+			creds, err := access.CredentialsFromEnv(srv.Config.Access.ResolveCredentialsEnv())
+			if err != nil {
+				fatalf(ctx, "💥  error reading access credentials: %v", err)
+			}
+			accessOpts.Credentials = creds
+			sum.Access = fmt.Sprintf("%s (%d users)", target, len(creds))
+		}
+		if err := access.Apply(srv.Dir, accessOpts); err != nil {
+			fatalf(ctx, "💥  error setting up access protection: %v", err)
+		}
+	}
+
+	// Step 8: Rewrite asset references to compressed variants.
+	if srv.Config.NeedsCompressedRefs() {
+		fmt.Printf("\n✏️   Rewriting asset references to compressed variants...\n")
+		if err := assets.RewriteCompressedRefs(srv.Dir); err != nil {
+			fatalf(ctx, "💥  error rewriting asset references: %v", err)
+		}
+		if srv.Config.ResolveDeployTarget() == config.DeployTargetFTP {
+			if err := deploy.WriteHtaccess(srv.Dir); err != nil {
+				fatalf(ctx, "💥  error writing .htaccess: %v", err)
+			}
+		}
+	} else {
+		fmt.Printf("\n✏️   Asset references left as rendered (deploy_target %q serves compressed files itself)\n", srv.Config.ResolveDeployTarget())
+		root := "/var/www/bluemap"
+		if srv.Config.ResolveDeployTarget() == config.DeployTargetSSH {
+			root = srv.Config.SSH.Path
+		}
+		path, err := deploy.WriteNginx(srv.Dir, deploy.NginxOptions{Root: root, Cache: srv.Config.Cache.Policy(srv.Config.CacheBust)})
+		if err != nil {
+			fatalf(ctx, "💥  error writing nginx snippet: %v", err)
+		}
+		fmt.Printf("  ✔  %s: include it in the nginx server block serving %s\n", path, root)
+	}
+	if srv.Config.CacheBust {
+		if err := assets.AddCacheBust(srv.Dir, newCacheBustToken()); err != nil {
+			fatalf(ctx, "💥  error adding cache-busting queries: %v", err)
+		}
+	}
+
+	sum.addStep("Rewrite", time.Since(rewriteStart))
+
+	// Optional: precompress web output with the configured codecs.
+	codecs, err := srv.Config.Compression.Codecs()
+	if err != nil {
+		fatalf(ctx, "💥  error configuring compression: %v", err)
+	}
+	if len(codecs) > 0 {
+		fmt.Printf("\n🗜   Precompressing web output...\n")
+		compressStart := time.Now()
+		res, err := compress.CompressTree(filepath.Join(srv.Dir, "web"), codecs, srv.Config.Compression.Workers)
+		if err != nil {
+			fatalf(ctx, "💥  error precompressing web output: %v", err)
+		}
+		fmt.Printf("    %d files, %s → %s\n", res.Files,
+			analyzer.FormatSize(res.OriginalBytes), analyzer.FormatSize(res.CompressedBytes))
+		sum.addStep("Compress", time.Since(compressStart))
+	}
+
+	// Step 9: Analyze web output size after rendering.
+	p.analyzeWeb()
+	_, webBudget := srv.Config.ResolveBudgets()
+	p.checkBudget("web/", "max_web_size", sum.WebTotalSize, webBudget)
+
+	// Optional: diff web output against the previous run's file manifest.
+	if srv.Config.FileManifest {
+		fmt.Println()
+		if err := diffManifest(srv.Dir, sum); err != nil {
+			warnf("could not update file manifest: %v", err)
+		}
+	}
+
+	// Optional: check web/ against the limits of the hosting plan.
+	if srv.Config.Hosting.Enabled() {
+		fmt.Println()
+		p.checkHosting()
+	}
+
+	// Compare with the previous deploy and record this one.
+	fmt.Println()
+	p.compareRuns()
+
+	p.analyzeAccessLogs()
+
+	// Optional: remember the backup, so the next run can skip it once the
+	// map is published.
+	if srv.Config.SkipIfUnchanged {
+		p.recordRender()
+	}
+
+	// Optional: publish web/ to a self-hosted target.
+	d := newDeployer(srv)
+	if d != nil {
+		fmt.Printf("\n🚀  Publishing web output → %s\n", d.Name())
+		start := time.Now()
+		if err := d.Deploy(ctx, filepath.Join(srv.Dir, "web")); err != nil {
+			fatalf(ctx, "💥  error publishing web output: %v", err)
+		}
+		deployDur := time.Since(start)
+		fmt.Printf("  ✔  published in %s\n", fmtDuration(deployDur))
+		publishRecords(srv.Dir)
+		sum.addStep("Deploy", deployDur)
+		sum.DeployedTo = d.Name()
+	}
+
+	// Optional: tell webhook_url the map was updated. Targets the workflow
+	// publishes send it with -announce after their deploy step instead.
+	if d != nil && srv.Config.WebhookURL != "" {
+		p.sendWebhook()
+	}
+
+	// Optional: delete old panel backups now the map is published. Targets
+	// the workflow publishes prune with -announce after their deploy step.
+	if d != nil && srv.Config.BackupRetention.Enabled() {
+		p.pruneBackups(p.panel())
+	}
+
+	// Optional: delete intermediates nothing after the deploy needs.
+	if len(srv.Config.Cleanup) > 0 {
+		cleanupStart := time.Now()
+		p.cleanup()
+		sum.addStep("Cleanup", time.Since(cleanupStart))
+	}
+
+	// Optional: mint a GitHub App installation token for later publishing steps.
+	if err := exportGitHubAppToken(ctx, p.ciEnv); err != nil {
+		fatalf(ctx, "💥  error minting GitHub App token: %v", err)
+	}
+
+	// Write the CI summary and outputs (no-op if not running inside CI).
+	writeSummary(p.ciEnv, sum)
+	writeOutputs(p.ciEnv, sum)
+}
+
+// compareRuns compares this build with the report the previous deploy left
+// next to config.toml for the summary, then saves the report of this one as
+// pending (see publishRecords). A failure only warns; the next run then has
+// nothing to compare with.
+func (p *pipeline) compareRuns() {
+	sum := p.sum
+	cur := runreport.Report{
+		Time:          time.Now().UTC(),
+		BackupUUID:    sum.BackupUUID,
+		BackupCreated: sum.BackupCreated,
+		WorldSize:     sum.WorldTotal,
+		WebSize:       sum.WebTotalSize,
+		WebFiles:      sum.WebFileCount,
+		RenderSeconds: sum.RenderDur.Seconds(),
+	}
+	for _, m := range sum.WebMaps {
+		cur.Maps = append(cur.Maps, runreport.Map{ID: m.ID, Size: m.Size, Tiles: m.Tiles()})
+		cur.Tiles += m.Tiles()
+	}
+
+	prev, err := runreport.Load(p.srv.Dir)
+	switch {
+	case err != nil:
+		warnf("could not read the previous report: %v", err)
+	case prev == nil:
+		fmt.Printf("📈  No report of an earlier deploy in %s; nothing to compare with\n", filepath.Dir(runreport.Path(p.srv.Dir)))
+	default:
+		sum.Changes = runreport.Compare(prev, &cur)
+		sum.ChangesSince = prev.Time
+		fmt.Printf("📈  Since the deploy of %s\n", prev.Time.UTC().Format("2006-01-02 15:04 UTC"))
+		for _, c := range sum.Changes {
+			metric := c.Metric
+			if c.Map != "" {
+				metric = c.Map + " " + strings.ToLower(c.Metric)
+			}
+			fmt.Printf("    %-25s  %s → %s (%s)\n", metric, fmtChangeValue(c.Kind, c.Previous), fmtChangeValue(c.Kind, c.Current), fmtChangeDelta(c))
+		}
+	}
+
+	// Like the file manifest, the report replaces the previous one once the
+	// map is published.
+	if err := cur.Save(runreport.Path(p.srv.Dir) + pendingSuffix); err != nil {
+		warnf("could not save the report for the next comparison: %v", err)
+	}
+}
+
+// sendWebhook posts the map_updated payload to webhook_url. A failure only
+// warns, since the map is already published.
+func (p *pipeline) sendWebhook() {
+	srv, sum := p.srv, p.sum
+	p.postWebhook(webhook.Payload{
+		Event:           webhook.EventMapUpdated,
+		Project:         sum.ProjectName,
+		ServerID:        sum.ServerID,
+		MapURL:          srv.Config.MapURL,
+		BlueMapVersion:  sum.BlueMapVersion,
+		BackupName:      sum.BackupName,
+		BackupCreatedAt: sum.BackupCreated,
+		RenderTime:      sum.RenderTime,
+		RenderSeconds:   sum.RenderDur.Seconds(),
+		DeployedTo:      sum.DeployedTo,
+	})
+}
+
+// reportRenderFailure writes a failed render, with the output that explains
+// it, to the CI summary and, when webhook_url is set, the webhook. It runs
+// before the run exits.
+func (p *pipeline) reportRenderFailure(renderErr *bluemap.RenderError, logPath string) {
+	srv, sum := p.srv, p.sum
+	writeRenderFailureSummary(p.ciEnv, sum, renderErr, logPath)
+	if srv.Config.WebhookURL == "" {
+		return
+	}
+	p.postWebhook(webhook.Payload{
+		Event:           webhook.EventRenderFailed,
+		Project:         sum.ProjectName,
+		ServerID:        sum.ServerID,
+		MapURL:          srv.Config.MapURL,
+		BlueMapVersion:  sum.BlueMapVersion,
+		BackupName:      sum.BackupName,
+		BackupCreatedAt: sum.BackupCreated,
+		RenderTime:      sum.RenderTime,
+		Error:           renderErr.Err.Error(),
+		Exception:       renderErr.Exception,
+		LogExcerpt:      renderErr.Excerpt,
+	})
+}
+
+// postWebhook posts payload to webhook_url. A failure only warns.
+func (p *pipeline) postWebhook(payload webhook.Payload) {
+	srv, sum := p.srv, p.sum
+	host := "webhook"
+	if u, err := url.Parse(srv.Config.WebhookURL); err == nil {
+		host = u.Host
+	}
+	fmt.Printf("\n📣  Sending webhook → %s\n", host)
+	if err := webhook.Send(p.ctx, srv.Config.WebhookURL, payload, os.Getenv(webhook.SecretEnv), "bluemap-action/"+sum.ToolVersion); err != nil {
+		warnf("could not send webhook: %v", err)
+		return
+	}
+	fmt.Println("  ✔  sent")
+}
+
+// pruneBackups deletes the panel backups backup_retention no longer keeps,
+// never the one this run rendered. A failure only warns, since the map is
+// already published.
+func (p *pipeline) pruneBackups(client panel.Panel) {
+	ctx, srv, sum := p.ctx, p.srv, p.sum
+	deleter, ok := client.(panel.BackupDeleter)
+	if !ok {
+		warnf("backup_retention: %s cannot delete backups", client.Name())
+		return
+	}
+	if sum.BackupUUID == "" {
+		warnf("backup_retention: the rendered backup is unknown; not deleting any backup")
+		return
+	}
+	r := srv.Config.BackupRetention
+
+	fmt.Printf("\n🗑   Applying backup retention (keep: %d, max age: %s)\n", r.Keep, cmp.Or(r.MaxAge, "none"))
+	backups, err := client.ListBackups(ctx, srv.Config.ServerID)
+	if err != nil {
+		warnf("backup_retention: could not list backups: %v", err)
+		return
+	}
+	expired := panel.ExpiredBackups(backups, r.Keep, r.ResolveMaxAge(), time.Now(), sum.BackupUUID)
+	for _, b := range expired {
+		if err := deleter.DeleteBackup(ctx, srv.Config.ServerID, b.UUID); err != nil {
+			warnf("backup_retention: could not delete backup %s: %v", b.Name, err)
+			continue
+		}
+		fmt.Printf("    deleted %s (%s, %s)\n", b.Name, b.CreatedAt.Format(time.DateOnly), analyzer.FormatSize(b.Bytes))
+		sum.BackupsDeleted++
+		sum.BackupsFreed += b.Bytes
+	}
+	fmt.Printf("  ✔  %d of %d backups deleted\n", sum.BackupsDeleted, len(backups))
+}
+
+// cleanup deletes the intermediates listed in cleanup and records the space
+// reclaimed. A failure only warns, since the map is already published.
+func (p *pipeline) cleanup() {
+	srv, sum := p.srv, p.sum
+	opts := cleanup.Options{
+		KeepArchive: p.snap != nil,
+	}
+	opts.Worlds = append(slices.Clone(p.worlds), srv.Config.ExtraPaths...)
+	opts.Worlds = append(opts.Worlds, markers.DataPaths(srv.Config.Markers.Sources)...)
+
+	fmt.Printf("\n🧹  Cleaning up: %s\n", strings.Join(srv.Config.Cleanup, ", "))
+	results, err := cleanup.Run(srv.Dir, srv.Config.Cleanup, opts)
+	var parts []string
+	for _, r := range results {
+		fmt.Printf("    %-8s %d removed, %s\n", r.Target, r.Paths, analyzer.FormatSize(r.Bytes))
+		parts = append(parts, fmt.Sprintf("%s %s", r.Target, analyzer.FormatSize(r.Bytes)))
+		sum.Reclaimed += r.Bytes
+	}
+	sum.Cleanup = strings.Join(parts, ", ")
+	if err != nil {
+		warnf("cleanup stopped: %v", err)
+		return
+	}
+	fmt.Printf("  ✔  reclaimed %s\n", analyzer.FormatSize(sum.Reclaimed))
+}
+
+// newDeployer returns the deployer publishing the web output for the
+// server's deploy_target, or nil when the workflow publishes it (Netlify) or
+// the user does (static).
+func newDeployer(srv config.LoadedServer) deploy.Deployer {
+	switch srv.Config.ResolveDeployTarget() {
+	case config.DeployTargetSSH:
+		s := srv.Config.SSH
+		identity := s.IdentityFile
+		if identity != "" && !filepath.IsAbs(identity) {
+			identity = filepath.Join(srv.Dir, identity)
+		}
+		return deploy.NewSSH(deploy.SSHOptions{
+			Host:         s.Host,
+			User:         s.User,
+			Port:         s.Port,
+			Path:         s.Path,
+			Delete:       s.ResolveDelete(),
+			BandwidthKiB: s.ResolveBandwidthKiB(),
+			IdentityFile: identity,
+		})
+	case config.DeployTargetFTP:
+		f := srv.Config.FTP
+		return deploy.NewFTP(deploy.FTPOptions{
+			Host:        f.Host,
+			Port:        f.Port,
+			User:        f.User,
+			Password:    os.Getenv(deploy.FTPPasswordEnv),
+			Path:        f.Path,
+			TLS:         f.TLS,
+			Connections: f.Connections,
+		})
+	case config.DeployTargetS3:
+		b := srv.Config.S3
+		return deploy.NewS3(deploy.S3Options{
+			Bucket:          b.Bucket,
+			Endpoint:        b.Endpoint,
+			Region:          b.Region,
+			Prefix:          b.Prefix,
+			Concurrency:     b.Concurrency,
+			Delete:          b.Delete,
+			Cache:           srv.Config.Cache.Policy(srv.Config.CacheBust),
+			AccessKeyID:     os.Getenv(deploy.S3AccessKeyEnv),
+			SecretAccessKey: os.Getenv(deploy.S3SecretKeyEnv),
+			SessionToken:    os.Getenv(deploy.S3SessionTokenEnv),
+		})
+	}
+	return nil
+}
+
+// analyzeWeb reports the size of the web output.
+func (p *pipeline) analyzeWeb() {
+	fmt.Println()
+	webReport, err := analyzer.AnalyzeWebOutput(p.srv.Dir)
+	if err != nil {
+		warnf("could not analyze web output: %v", err)
+		return
+	}
+	p.sum.WebTotalSize = webReport.TotalSize
+	p.sum.WebFileCount = webReport.FileCount
+	p.sum.WebMaxFileSize = webReport.MaxFileSize
+	p.sum.WebLargest = webReport.Largest
+	p.sum.WebTypes = webReport.Types
+	p.sum.WebMaps = webReport.Maps
+	analyzer.PrintWebOutput(webReport)
+}
+
+// checkBudget compares size with the budget set by key in config.toml and
+// aborts the run when it is over, or only warns with fail_over_budget =
+// false. A budget of 0 is not checked.
+func (p *pipeline) checkBudget(what, key string, size, budget int64) {
+	if budget <= 0 || size <= budget {
+		return
+	}
+	msg := fmt.Sprintf("%s is %s, over %s = %s", what, analyzer.FormatSize(size), key, analyzer.FormatSize(budget))
+	if p.srv.Config.ResolveFailOverBudget() {
+		fatalf(p.ctx, "💥  %s\n    reduce it (render_bounds, fewer worlds or maps) or raise %s in config.toml; set fail_over_budget = false to only warn", msg, key)
+	}
+	warnf("%s", msg)
+}
+
+// checkHosting measures web/ against the limits of the [hosting] plan and
+// warns about each one the deploy goes over, so a deploy the host would
+// reject shows up in the summary first.
+func (p *pipeline) checkHosting() {
+	h := p.srv.Config.Hosting
+	limits, ok := analyzer.HostingPlans[h.Plan]
+	if !ok {
+		limits.Name = "configured limits"
+	}
+	maxFiles, maxFileSize, bandwidth := h.ResolveOverrides()
+	if maxFiles > 0 {
+		limits.MaxFiles = maxFiles
+	}
+	if maxFileSize > 0 {
+		limits.MaxFileSize = maxFileSize
+	}
+	if bandwidth > 0 {
+		limits.MonthlyBandwidth = bandwidth
+	}
+
+	report, err := analyzer.AnalyzeHosting(p.srv.Dir, limits, int64(h.MonthlyVisits))
+	if err != nil {
+		warnf("could not check hosting limits: %v", err)
+		return
+	}
+	if d := p.sum.ManifestDiff; d != nil {
+		upload := d.Upload()
+		var size int64
+		for _, rel := range upload {
+			if info, err := os.Stat(filepath.Join(p.srv.Dir, "web", filepath.FromSlash(rel))); err == nil {
+				size += info.Size()
+			}
+		}
+		report.SetChanged(int64(len(upload)), size)
+	}
+	analyzer.PrintHostingAnalysis(report)
+	for _, w := range report.Warnings {
+		warnf("%s", w)
+	}
+	p.sum.Hosting = report
+}
+
+// analyzeAccessLogs reports tile usage from the hosting access logs listed
+// in access_logs, if any.
+func (p *pipeline) analyzeAccessLogs() {
+	if len(p.srv.Config.AccessLogs) == 0 {
+		return
+	}
+	fmt.Println()
+	logReport, err := analyzer.AnalyzeAccessLogs(p.srv.Dir, p.srv.Config.AccessLogs)
+	if err != nil {
+		warnf("could not analyze access logs: %v", err)
+		return
+	}
+	analyzer.PrintAccessLogAnalysis(logReport)
+}
+
+// stateFileName is the file in the server directory that carries the build
+// summary from one pipeline subcommand to the next when they run in separate
+// jobs.
+const stateFileName = ".bluemap-state.json"
+
+// loadState fills sum with the state saved by an earlier phase. A missing
+// file is not an error, and a state saved for another server is ignored.
+func loadState(dir, serverID string, sum *buildSummary) error {
+	data, err := os.ReadFile(filepath.Join(dir, stateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("  → no %s from an earlier phase; the summary only covers this one\n\n", stateFileName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved buildSummary
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	if saved.ServerID != serverID {
+		return fmt.Errorf("saved for server %q, not %q; ignoring it", saved.ServerID, serverID)
+	}
+	*sum = saved
+	return nil
+}
+
+// saveState writes sum to the state file for the next phase.
+func saveState(dir string, sum *buildSummary) error {
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, stateFileName), data, 0o644)
+}
+
+// saveStateOrWarn saves the state at the end of a split phase.
+func (p *pipeline) saveStateOrWarn() {
+	if err := saveState(p.srv.Dir, p.sum); err != nil {
+		warnf("could not save %s: %v", stateFileName, err)
+		return
+	}
+	fmt.Printf("\n💾  Saved build state to %s\n", filepath.Join(p.srv.Dir, stateFileName))
+}
+
+// afterWorkflowDeploy reports whether -announce has work besides the console
+// message: the webhook and backup retention of targets the workflow
+// publishes, which wait for its deploy step.
+func (p *pipeline) afterWorkflowDeploy() bool {
+	cfg := p.srv.Config
+	return newDeployer(p.srv) == nil && (cfg.WebhookURL != "" || cfg.BackupRetention.Enabled())
+}
+
+// runRun implements the run subcommand, the whole pipeline in one process.
+// It is also what runs when no subcommand is given.
+func runRun(ctx context.Context, args []string) {
+	var f pipelineFlags
+	fs := newPipelineFlagSet("run", &f)
+	f.addDebugFlags(fs)
+	f.addMapsFlag(fs)
+	fs.BoolVar(&f.announce, "announce", false, "only send announce_command to the server console, and webhook_url and backup_retention for maps the workflow publishes (run after a successful deploy), and exit")
+	fs.Usage = usageFor(fs, "run")
+	fs.Parse(args)
+
+	p := newPipeline(ctx, &f, false)
+	client := p.panel()
+
+	if f.announce {
+		// -announce runs after the workflow's deploy step succeeded.
+		publishRecords(p.srv.Dir)
+		if err := sendAnnouncement(ctx, client, p.srv.Config, p.sum.ProjectName, p.sum.RenderTime); err != nil {
+			// The map is already deployed; a failed announcement should not
+			// fail the job.
+			warnf("could not send announcement: %v", err)
+		}
+		if p.afterWorkflowDeploy() {
+			// The render details come from the state the run saved.
+			if err := loadState(p.srv.Dir, p.srv.Config.ServerID, p.sum); err != nil {
+				warnf("could not load %s: %v", stateFileName, err)
+			}
+			if p.srv.Config.WebhookURL != "" {
+				p.sendWebhook()
+			}
+			if p.srv.Config.BackupRetention.Enabled() {
+				p.pruneBackups(client)
+			}
+		}
+		return
+	}
+
+	p.printHeader()
+	p.keepIntermediate(&f)
+	if !p.download(client) {
+		p.finishUnchanged()
+		return
+	}
+	p.render()
+	p.deploy()
+	if p.afterWorkflowDeploy() {
+		// The workflow publishes the map; -announce sends the webhook and
+		// prunes backups afterwards from this state.
+		p.saveStateOrWarn()
+	}
+	fmt.Printf("\n✅  Done!\n")
+}
+
+// runDownload implements the download subcommand: steps 1–2.
+func runDownload(ctx context.Context, args []string) {
+	var f pipelineFlags
+	fs := newPipelineFlagSet("download", &f)
+	f.addDebugFlags(fs)
+	fs.Usage = usageFor(fs, "download")
+	fs.Parse(args)
+
+	p := newPipeline(ctx, &f, false)
+	client := p.panel()
+	p.printHeader()
+	p.keepIntermediate(&f)
+	if !p.download(client) {
+		// render and deploy skip the build from the saved state.
+		p.saveStateOrWarn()
+		p.finishUnchanged()
+		return
+	}
+	p.saveStateOrWarn()
+	fmt.Printf("\n✅  Done!\n")
+}
+
+// runRender implements the render subcommand: steps 3–7 on the worlds left
+// in the server directory by download.
+func runRender(ctx context.Context, args []string) {
+	var f pipelineFlags
+	fs := newPipelineFlagSet("render", &f)
+	f.addDebugFlags(fs)
+	f.addMapsFlag(fs)
+	fs.Usage = usageFor(fs, "render")
+	fs.Parse(args)
+
+	p := newPipeline(ctx, &f, true)
+	if p.sum.Unchanged {
+		fmt.Printf("⏭   Backup %s was already rendered; nothing to do\n", p.sum.BackupUUID)
+		return
+	}
+	stampRender(p.sum, p.srv.Config)
+	p.printHeader()
+	p.keepIntermediate(&f)
+	p.render()
+	p.saveStateOrWarn()
+	fmt.Printf("\n✅  Done!\n")
+}
+
+// runDeploy implements the deploy subcommand: post-processing, analysis and
+// the CI summary for the web output left by render.
+func runDeploy(ctx context.Context, args []string) {
+	var f pipelineFlags
+	fs := newPipelineFlagSet("deploy", &f)
+	fs.Usage = usageFor(fs, "deploy")
+	fs.Parse(args)
+
+	p := newPipeline(ctx, &f, true)
+	if p.sum.Unchanged {
+		p.finishUnchanged()
+		return
+	}
+	p.deploy()
+	fmt.Printf("\n✅  Done!\n")
+}
+
+// runAnalyze implements the analyze subcommand: it reports on the worlds and
+// web output already in the server directory without changing anything.
+func runAnalyze(ctx context.Context, args []string) {
+	var f pipelineFlags
+	fs := newPipelineFlagSet("analyze", &f)
+	format := fs.String("analysis-format", analyzer.FormatText, "output format: "+strings.Join(analyzer.Formats, ", "))
+	fs.Usage = usageFor(fs, "analyze")
+	fs.Parse(args)
+	if !slices.Contains(analyzer.Formats, *format) {
+		fatalf(ctx, "💥  -analysis-format: unknown format %q, want one of %s", *format, strings.Join(analyzer.Formats, ", "))
+	}
+
+	f.quiet = *format != analyzer.FormatText
+	p := newPipeline(ctx, &f, false)
+	if !f.quiet {
+		p.analyzeWorlds()
+		p.analyzeWeb()
+		p.analyzeAccessLogs()
+		return
+	}
+
+	// Machine-readable output: only the report goes to stdout; newPipeline
+	// has made warnf and fatalf leave out their CI annotations.
+	report := &analyzer.Report{}
+	report.Worlds, report.WorldTotal = analyzer.AnalyzeWorldSizes(p.srv.Dir, p.worldConfigs)
+	regionStats, err := analyzer.AnalyzeRegions(p.srv.Dir, p.worlds, p.srv.Config.InhabitedStats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  could not read chunk statistics: %v\n", err)
+	}
+	report.Regions = regionStats
+	if web, err := analyzer.AnalyzeWebOutput(p.srv.Dir); err == nil {
+		report.Web = web
+	}
+	if err := report.Write(os.Stdout, *format); err != nil {
+		fatalf(ctx, "💥  writing the analysis: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/assets"
+	"github.com/EfinaServer/bluemap-action/internal/config"
+	"github.com/EfinaServer/bluemap-action/internal/preview"
+)
+
+// runPreview implements the preview subcommand: it serves the server
+// directory's web/ on a local address with the Content-Encoding, gzip_static
+// and Cache-Control handling of the configured deploy target, so the output
+// of deploy can be checked in a browser before it is published.
+func runPreview(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	serverDir := fs.String("dir", ".", "server directory containing config.toml and the rendered web/")
+	listen := fs.String("listen", preview.DefaultAddr, "address to serve the preview on")
+	fs.Usage = usageFor(fs, "preview")
+	fs.Parse(args)
+
+	srv, err := config.Load(*serverDir)
+	if err != nil {
+		fatalf(ctx, "💥  loading config: %v", err)
+	}
+	webDir := filepath.Join(srv.Dir, "web")
+	if _, err := os.Stat(filepath.Join(webDir, "index.html")); err != nil {
+		fatalf(ctx, "💥  no rendered map in %s: %v", webDir, err)
+	}
+	target := srv.Config.ResolveDeployTarget()
+	if srv.Config.NeedsCompressedRefs() {
+		if _, err := os.Stat(filepath.Join(srv.Dir, assets.BackupDirName)); err != nil {
+			warnf("web/ has not been through deploy yet; deploy_target %q needs the rewritten asset references, so tiles may not load", target)
+		}
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatalf(ctx, "💥  %v", err)
+	}
+	server := &http.Server{
+		Handler: preview.Handler(webDir, preview.Options{
+			GzipStatic:   !srv.Config.NeedsCompressedRefs(),
+			HideDotfiles: target == config.DeployTargetNetlify,
+			Cache:        srv.Config.Cache.Policy(srv.Config.CacheBust),
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("🔭  Previewing %s as deploy_target %q on http://%s/ (Ctrl-C to stop)\n", webDir, target, ln.Addr())
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf(ctx, "💥  preview server: %v", err)
+	}
+	fmt.Println("\n🛑  preview stopped")
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/access"
+	"github.com/EfinaServer/bluemap-action/internal/analyzer"
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/config"
+	"github.com/EfinaServer/bluemap-action/internal/deploy"
+	"github.com/EfinaServer/bluemap-action/internal/modrinth"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
+	"github.com/EfinaServer/bluemap-action/internal/redact"
+)
+
+// runValidate implements the validate subcommand: it checks everything a run
+// needs before it starts — config.toml, the panel server and its latest
+// backup, and the BlueMap release — without downloading or rendering, and
+// exits non-zero with the list of problems. It is cheap enough for a pull
+// request check.
+func runValidate(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	serverDir := fs.String("dir", ".", "server directory containing config.toml, or the base directory with -all")
+	all := fs.Bool("all", false, "validate every subdirectory of -dir that contains a config.toml")
+	verbose := fs.Bool("verbose", false, "log every panel API request with the rate limit budget left")
+	fs.Usage = usageFor(fs, "validate")
+	fs.Parse(args)
+
+	dirs := []string{*serverDir}
+	if *all {
+		var err error
+		if dirs, err = config.ServerDirs(*serverDir); err != nil {
+			log.Printf("💥  %v", err)
+			exit(1)
+		}
+	}
+
+	var problems []string
+	// One client per panel type, so servers sharing an API key share its
+	// rate limit budget.
+	clients := make(map[string]panel.Panel)
+	for _, dir := range dirs {
+		name := filepath.Base(filepath.Clean(dir))
+		fmt.Printf("🔎  %s\n", name)
+		for _, p := range validateServer(ctx, dir, clients, *verbose) {
+			fmt.Printf("  ✖  %s\n", p)
+			problems = append(problems, name+": "+p)
+		}
+		fmt.Println()
+		if ctx.Err() != nil {
+			fatalf(ctx, "💥  validation cancelled")
+		}
+	}
+
+	if *verbose {
+		for _, client := range clients {
+			printAPIBudget(client)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("❌  %d problem(s) found:\n", len(problems))
+		for _, p := range problems {
+			fmt.Printf("    - %s\n", p)
+		}
+		exit(1)
+	}
+	fmt.Printf("✅  %d server(s) valid\n", len(dirs))
+}
+
+// validateServer runs the checks for one server directory, printing each
+// check that passes, and returns the problems found. The remote checks are
+// skipped when the config does not load, and the panel checks when its
+// credentials are not set. Panel clients are taken from and added to clients.
+func validateServer(ctx context.Context, dir string, clients map[string]panel.Panel, verbose bool) []string {
+	srv, err := config.Load(dir)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	fmt.Println("  ✔  config.toml")
+	if len(srv.EnvOverrides) > 0 {
+		fmt.Printf("  →  overridden from the environment: %s\n", strings.Join(srv.EnvOverrides, ", "))
+	}
+	redact.Add(srv.Config.WebhookURL)
+	redact.AddEnv(srv.Config.Access.ResolveCredentialsEnv())
+	// Every server gets its own proxy_url, or the environment's proxy.
+	if err := proxy.Apply(srv.Config.ProxyURL); err != nil {
+		return []string{fmt.Sprintf("proxy_url: %v", err)}
+	}
+
+	var problems []string
+	client, ok := clients[srv.Config.PanelType]
+	if !ok {
+		var err error
+		if client, err = panel.FromEnv(srv.Config.PanelType); err == nil {
+			if pt, ok := client.(*panel.Pterodactyl); ok && verbose {
+				pt.Client.Log = func(format string, args ...any) {
+					fmt.Printf("     🌐 "+format+"\n", args...)
+				}
+			}
+			clients[srv.Config.PanelType] = client
+		} else {
+			// Config-only runs, e.g. on pull requests without secrets,
+			// still pass.
+			warnf("%v; the panel was not checked", err)
+		}
+	}
+	if client != nil {
+		if err := validateBackup(ctx, client, srv.Config); err != nil {
+			problems = append(problems, fmt.Sprintf("%s server %s: %v", client.Name(), srv.Config.ServerID, err))
+		}
+	}
+
+	if a := srv.Config.Access; access.NeedsCredentials(a.Target) {
+		if creds, err := access.CredentialsFromEnv(a.ResolveCredentialsEnv()); err != nil {
+			problems = append(problems, fmt.Sprintf("access: %v", err))
+		} else {
+			fmt.Printf("  ✔  access credentials: %d user(s)\n", len(creds))
+		}
+	}
+
+	if srv.Config.ResolveStorage() == config.StorageSQLite {
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			problems = append(problems, "storage \"sqlite\": sqlite3 is not installed")
+		} else {
+			fmt.Println("  ✔  sqlite3 found")
+		}
+	}
+	if srv.Config.ResolveDeployTarget() == config.DeployTargetSSH {
+		if _, err := exec.LookPath("rsync"); err != nil {
+			problems = append(problems, "deploy_target \"ssh\": rsync is not installed")
+		} else {
+			fmt.Println("  ✔  rsync found")
+		}
+	}
+	if srv.Config.ResolveDeployTarget() == config.DeployTargetFTP {
+		if srv.Config.FTP.User != "" && os.Getenv(deploy.FTPPasswordEnv) == "" {
+			problems = append(problems, fmt.Sprintf("deploy_target \"ftp\": %s is not set", deploy.FTPPasswordEnv))
+		} else if d, ok := newDeployer(srv).(*deploy.FTP); ok {
+			if err := d.Check(ctx); err != nil {
+				problems = append(problems, fmt.Sprintf("deploy_target \"ftp\": %v", err))
+			} else {
+				fmt.Printf("  ✔  logged in to %s\n", d.Name())
+			}
+		}
+	}
+	if srv.Config.ResolveDeployTarget() == config.DeployTargetS3 {
+		if os.Getenv(deploy.S3AccessKeyEnv) == "" || os.Getenv(deploy.S3SecretKeyEnv) == "" {
+			problems = append(problems, fmt.Sprintf("deploy_target \"s3\": %s and %s must be set", deploy.S3AccessKeyEnv, deploy.S3SecretKeyEnv))
+		} else if d, ok := newDeployer(srv).(*deploy.S3); ok {
+			if err := d.Check(ctx); err != nil {
+				problems = append(problems, fmt.Sprintf("deploy_target \"s3\": %v", err))
+			} else {
+				fmt.Printf("  ✔  bucket %s is accessible\n", d.Name())
+			}
+		}
+	}
+
+	if n, err := bluemap.CheckScripts(srv.Dir); err != nil {
+		problems = append(problems, fmt.Sprintf("scripts: %v", err))
+	} else if n > 0 {
+		fmt.Printf("  ✔  %d custom script(s)\n", n)
+	}
+
+	if n, err := bluemap.CheckResourcePacks(srv.Dir, srv.Config.ResourcePacks); err != nil {
+		problems = append(problems, fmt.Sprintf("resourcepacks: %v", err))
+	} else if n > 0 {
+		fmt.Printf("  ✔  %d resource pack(s)\n", n)
+	}
+
+	if n, err := bluemap.CheckMods(ctx, modrinth.NewClient("bluemap-action/"+getVersion()), srv.Config.MinecraftVersion, srv.Config.ResolveMods()); err != nil {
+		problems = append(problems, fmt.Sprintf("mods: %v", err))
+	} else if n > 0 {
+		fmt.Printf("  ✔  %d mod(s)\n", n)
+	}
+
+	version, err := bluemap.CheckRelease(ctx, srv.Config.BlueMapVersion)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("bluemap_version: %v", err))
+	} else {
+		fmt.Printf("  ✔  BlueMap %s release found\n", version)
+		if _, tested := bluemap.CompatibleLayout(version); !tested {
+			warnf("BlueMap v%s is untested with this tool (tested: %s)", version, bluemap.TestedVersions())
+		}
+	}
+	if spec := srv.Config.WebappVersion; spec != "" {
+		version, err := bluemap.CheckRelease(ctx, spec)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("webapp_version: %v", err))
+		} else {
+			fmt.Printf("  ✔  BlueMap %s release found for webapp_version\n", version)
+		}
+	}
+	return problems
+}
+
+// validateBackup checks that the server is reachable with the API key and,
+// unless fresh_backup creates one per run, that its latest successful backup
+// can be downloaded.
+func validateBackup(ctx context.Context, client panel.Panel, cfg config.ServerConfig) error {
+	if cfg.FreshBackup {
+		if _, err := client.ListBackups(ctx, cfg.ServerID); err != nil {
+			return err
+		}
+		fmt.Printf("  ✔  %s server %s reachable (fresh_backup creates the backup)\n", client.Name(), cfg.ServerID)
+		return nil
+	}
+
+	backup, err := panel.LatestBackup(ctx, client, cfg.ServerID)
+	if err != nil {
+		return err
+	}
+	if _, err := client.GetDownloadURL(ctx, cfg.ServerID, backup.UUID); err != nil {
+		return fmt.Errorf("backup %s cannot be downloaded: %w", backup.UUID, err)
+	}
+	fmt.Printf("  ✔  latest backup: %s (%s, %s, %s)\n", backup.Name, backup.UUID,
+		analyzer.FormatSize(backup.Bytes), cfg.FormatTime(backup.CreatedAt))
+	return nil
+}
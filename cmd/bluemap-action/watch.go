@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/config"
+)
+
+// watchStatus is what the watch command's status endpoint reports.
+type watchStatus struct {
+	mu       sync.Mutex
+	State    string    `json:"state"` // "waiting" | "running"
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run,omitzero"`
+	LastRun  *watchRun `json:"last_run,omitempty"`
+	Runs     int       `json:"runs"`
+	Failures int       `json:"failures"`
+}
+
+// watchRun is the outcome of one scheduled run.
+type watchRun struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// runWatch implements the watch subcommand: for a server that builds the
+// map on its own machine, it runs the whole pipeline on watch.schedule
+// until it is stopped, serving the state of the loop over HTTP for health
+// checks. Each run is a run subcommand in a child process, so a failed run
+// does not end the loop and config.toml is read afresh every time; only the
+// [watch] table needs a restart. Runs skip backups that were already
+// rendered unless BLUEMAP_ACTION_SKIP_IF_UNCHANGED is set otherwise or the
+// server takes a fresh backup every run.
+func runWatch(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	serverDir := fs.String("dir", ".", "server directory containing config.toml (e.g. onlinemap-01)")
+	listen := fs.String("listen", "", "status endpoint address, overriding watch.listen (\"off\" = none)")
+	runNow := fs.Bool("run-now", false, "run once at startup instead of waiting for the first scheduled time")
+	verbose := fs.Bool("verbose", false, "log every panel API request with the rate limit budget left")
+	fs.Usage = usageFor(fs, "watch")
+	fs.Parse(args)
+
+	fmt.Printf("🗺  bluemap-action %s\n\n", getVersion())
+	srv, err := config.Load(*serverDir)
+	if err != nil {
+		fatalf(ctx, "💥  loading config: %v", err)
+	}
+	cfg := srv.Config
+	sched := cfg.Watch.ResolveSchedule()
+	if sched == nil {
+		fatalf(ctx, "💥  watch needs [watch] schedule in config.toml, e.g. schedule = \"0 4 * * *\"")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fatalf(ctx, "💥  locating the bluemap-action binary: %v", err)
+	}
+	runArgs := []string{"run", "-dir", srv.Dir}
+	if *verbose {
+		runArgs = append(runArgs, "-verbose")
+	}
+
+	status := &watchStatus{State: "waiting", Schedule: cfg.Watch.Schedule}
+	addr := cfg.Watch.ResolveListen()
+	if *listen == "off" {
+		addr = ""
+	} else if *listen != "" {
+		addr = *listen
+	}
+	if addr != "" {
+		stopServer, err := serveWatchStatus(addr, status)
+		if err != nil {
+			fatalf(ctx, "💥  status endpoint: %v", err)
+		}
+		defer stopServer()
+		fmt.Printf("🩺  Status on http://%s/status, health check on /healthz\n", addr)
+	}
+	fmt.Printf("⏰  Watching %s on schedule %q (%s)\n", srv.Dir, cfg.Watch.Schedule, cfg.ResolveTimezone())
+
+	if *runNow {
+		runScheduled(ctx, exe, runArgs, srv.Dir, status)
+	}
+	for ctx.Err() == nil {
+		next := sched.Next(time.Now().In(cfg.ResolveTimezone()))
+		if next.IsZero() {
+			fatalf(ctx, "💥  schedule %q never fires", cfg.Watch.Schedule)
+		}
+		status.mu.Lock()
+		status.State, status.NextRun = "waiting", next
+		status.mu.Unlock()
+		fmt.Printf("\n💤  Next run at %s\n", cfg.FormatTime(next))
+		if !sleepUntil(ctx, next) {
+			break
+		}
+		runScheduled(ctx, exe, runArgs, srv.Dir, status)
+	}
+	fmt.Println("\n🛑  watch stopped")
+}
+
+// sleepUntil waits until the wall clock reaches t, or ctx is done, and
+// reports whether it reached t. It wakes every minute to check, since
+// timers do not advance while the machine is suspended.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	for {
+		d := time.Until(t)
+		if d <= 0 {
+			return true
+		}
+		timer := time.NewTimer(min(d, time.Minute))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// runScheduled runs the pipeline for the server in dir once as a child
+// process and records the outcome in status. Cancelling ctx interrupts the
+// child, which cleans up as on Ctrl-C.
+func runScheduled(ctx context.Context, exe string, args []string, dir string, status *watchStatus) {
+	run := &watchRun{Started: time.Now()}
+	status.mu.Lock()
+	status.State, status.NextRun = "running", time.Time{}
+	status.mu.Unlock()
+	fmt.Printf("\n▶️  Scheduled run started at %s\n\n", run.Started.Format(time.RFC3339))
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = scheduledEnv(dir, os.Environ())
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+	err := cmd.Run()
+
+	run.Finished, run.OK = time.Now(), err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+	status.mu.Lock()
+	status.LastRun = run
+	status.Runs++
+	if !run.OK {
+		status.Failures++
+	}
+	status.mu.Unlock()
+
+	took := run.Finished.Sub(run.Started).Round(time.Second)
+	if run.OK {
+		fmt.Printf("\n✔  Scheduled run finished in %s\n", took)
+	} else if ctx.Err() == nil {
+		warnf("scheduled run failed after %s: %v", took, err)
+	}
+}
+
+// scheduledEnv returns the environment of a scheduled run of the server in
+// dir, which skips backups already rendered. A server with fresh_backup
+// takes a new backup every run, and Load rejects skip_if_unchanged with it,
+// so its runs are left as configured; so are runs whose environment sets
+// the variable. config.toml is read afresh, as the run will read it.
+func scheduledEnv(dir string, environ []string) []string {
+	skip := config.EnvPrefix + "SKIP_IF_UNCHANGED"
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, skip+"=") {
+			return environ
+		}
+	}
+	// A config that does not load fails the run, which reports why.
+	if srv, err := config.Load(dir); err != nil || srv.Config.FreshBackup {
+		return environ
+	}
+	return append(environ, skip+"=true")
+}
+
+// serveWatchStatus serves status on addr: /status as JSON, and /healthz,
+// which answers 503 while the last run failed. It returns a function that
+// stops the server.
+func serveWatchStatus(addr string, status *watchStatus) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		status.mu.Lock()
+		defer status.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(status)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		status.mu.Lock()
+		last := status.LastRun
+		status.mu.Unlock()
+		if last != nil && !last.OK {
+			http.Error(w, "last run failed: "+last.Error, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			warnf("status endpoint stopped: %v", err)
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
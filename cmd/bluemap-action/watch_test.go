@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/EfinaServer/bluemap-action/internal/config"
+)
+
+func TestScheduledEnv(t *testing.T) {
+	skip := config.EnvPrefix + "SKIP_IF_UNCHANGED"
+	t.Setenv(skip, "")
+	os.Unsetenv(skip)
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(extra string) {
+		t.Helper()
+		base := "server_id = \"abc\"\nserver_type = \"plugin\"\nworld_name = \"world\"\nmc_version = \"1.21.4\"\nbluemap_version = \"5.7\"\n"
+		if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(base+extra), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// load loads the config as the scheduled run does, with env.
+	load := func(env []string) error {
+		t.Helper()
+		for _, kv := range env {
+			if name, value, _ := strings.Cut(kv, "="); name == skip {
+				t.Setenv(skip, value)
+				defer os.Unsetenv(skip)
+			}
+		}
+		_, err := config.Load(dir)
+		return err
+	}
+
+	writeConfig("")
+	env := scheduledEnv(dir, []string{"PATH=/bin"})
+	if !slices.Contains(env, skip+"=true") {
+		t.Errorf("env = %q, want %s=true", env, skip)
+	}
+	if err := load(env); err != nil {
+		t.Errorf("scheduled run: %v", err)
+	}
+
+	// A fresh_backup server would fail at config load with the variable.
+	writeConfig("fresh_backup = true\n")
+	env = scheduledEnv(dir, []string{"PATH=/bin"})
+	if slices.ContainsFunc(env, func(kv string) bool { return strings.HasPrefix(kv, skip+"=") }) {
+		t.Errorf("env = %q, want no %s for a fresh_backup server", env, skip)
+	}
+	if err := load(env); err != nil {
+		t.Errorf("scheduled run of a fresh_backup server: %v", err)
+	}
+
+	writeConfig("")
+	if env := scheduledEnv(dir, []string{skip + "=false"}); !slices.Equal(env, []string{skip + "=false"}) {
+		t.Errorf("env = %q, want the variable as set", env)
+	}
+}
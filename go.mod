@@ -2,4 +2,9 @@ module github.com/EfinaServer/bluemap-action
 
 go 1.24.7
 
-require github.com/BurntSushi/toml v1.6.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/klauspost/pgzip v1.2.6
+)
+
+require github.com/klauspost/compress v1.18.0 // indirect
@@ -0,0 +1,160 @@
+package access
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Targets select how the published map is protected.
+const (
+	TargetNetlify    = "netlify"    // Basic-Auth rule in web/_headers
+	TargetCloudflare = "cloudflare" // Cloudflare Access, configured in the dashboard
+	TargetHtpasswd   = "htpasswd"   // htpasswd file for a self-hosted web server
+)
+
+// DefaultCredentialsEnv is the environment variable read for the user names
+// and passwords when credentials_env is not set.
+const DefaultCredentialsEnv = "BLUEMAP_ACCESS_CREDENTIALS"
+
+// HtpasswdFile is the name of the htpasswd file written next to config.toml,
+// outside web/ so it is never published.
+const HtpasswdFile = "htpasswd"
+
+// Options configures access protection for one server.
+type Options struct {
+	Target      string
+	Credentials []Credential // netlify and htpasswd
+	Emails      []string     // cloudflare: emails or @domains to allow
+	MapURL      string       // cloudflare: the host to protect
+}
+
+// Credential is a user name and password for basic authentication.
+type Credential struct {
+	User     string
+	Password string
+}
+
+// NeedsCredentials reports whether target protects the map with passwords.
+func NeedsCredentials(target string) bool {
+	return target == TargetNetlify || target == TargetHtpasswd
+}
+
+// ParseCredentials parses "user:password" pairs separated by whitespace or
+// newlines, the format of Netlify's Basic-Auth header. Passwords may contain
+// ':' but not whitespace.
+func ParseCredentials(s string) ([]Credential, error) {
+	var creds []Credential
+	seen := make(map[string]bool)
+	for _, pair := range strings.Fields(s) {
+		user, password, ok := strings.Cut(pair, ":")
+		if !ok || user == "" || password == "" {
+			return nil, fmt.Errorf("credentials must be user:password pairs separated by spaces, got an entry without a user or password")
+		}
+		if seen[user] {
+			return nil, fmt.Errorf("user %q is listed twice", user)
+		}
+		seen[user] = true
+		creds = append(creds, Credential{User: user, Password: password})
+	}
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("no credentials given")
+	}
+	return creds, nil
+}
+
+// CredentialsFromEnv parses the credentials in the environment variable
+// name (see ParseCredentials).
+func CredentialsFromEnv(name string) ([]Credential, error) {
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("%s environment variable is not set", name)
+	}
+	creds, err := ParseCredentials(val)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return creds, nil
+}
+
+// Apply writes the access protection for opts.Target: the Basic-Auth rule
+// in web/_headers for Netlify, or an htpasswd file in serverDir for
+// self-hosting. Cloudflare Access cannot be set up from the deployed files,
+// so its setup steps are printed instead.
+func Apply(serverDir string, opts Options) error {
+	switch opts.Target {
+	case TargetNetlify:
+		path := filepath.Join(serverDir, "web", "_headers")
+		if err := os.WriteFile(path, []byte(netlifyHeaders(opts.Credentials)), 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("  ✔  web/_headers: Basic-Auth for %d user(s)\n", len(opts.Credentials))
+	case TargetHtpasswd:
+		data, err := htpasswd(opts.Credentials)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(serverDir, HtpasswdFile)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("  ✔  %s: %d user(s), point the web server's basic auth at it\n", path, len(opts.Credentials))
+	case TargetCloudflare:
+		fmt.Print(CloudflareNotes(opts.MapURL, opts.Emails))
+	default:
+		return fmt.Errorf("unknown access target %q", opts.Target)
+	}
+	return nil
+}
+
+// netlifyHeaders returns a _headers file protecting the whole site.
+func netlifyHeaders(creds []Credential) string {
+	pairs := make([]string, len(creds))
+	for i, c := range creds {
+		pairs[i] = c.User + ":" + c.Password
+	}
+	return "# Generated by bluemap-action ([access] target = \"netlify\")\n" +
+		"/*\n  Basic-Auth: " + strings.Join(pairs, " ") + "\n"
+}
+
+// htpasswd returns an htpasswd file with an Apache MD5 ($apr1$) hash per
+// user, which both Apache and nginx accept.
+func htpasswd(creds []Credential) (string, error) {
+	var sb strings.Builder
+	for _, c := range creds {
+		hash, err := apr1(c.Password)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(c.User + ":" + hash + "\n")
+	}
+	return sb.String(), nil
+}
+
+// CloudflareNotes returns the steps to protect the map with Cloudflare
+// Access, which is configured in the Zero Trust dashboard rather than in
+// files deployed with the map.
+func CloudflareNotes(mapURL string, emails []string) string {
+	host := "<your map's hostname>"
+	if mapURL != "" {
+		host = strings.TrimPrefix(strings.TrimPrefix(mapURL, "https://"), "http://")
+		host = strings.TrimSuffix(host, "/")
+	}
+	var sb strings.Builder
+	sb.WriteString("  →  Cloudflare Access is set up in the Zero Trust dashboard, not in the deployed files:\n")
+	sb.WriteString("       1. Access → Applications → Add an application → Self-hosted\n")
+	sb.WriteString("       2. Application domain: " + host + "\n")
+	sb.WriteString("       3. Add an Allow policy including:\n")
+	if len(emails) == 0 {
+		sb.WriteString("            the emails or email domains that may view the map\n")
+	}
+	for _, e := range emails {
+		if strings.HasPrefix(e, "@") {
+			sb.WriteString("            Emails ending in: " + e + "\n")
+		} else {
+			sb.WriteString("            Emails: " + e + "\n")
+		}
+	}
+	return sb.String()
+}
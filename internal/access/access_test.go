@@ -0,0 +1,66 @@
+package access
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApr1(t *testing.T) {
+	// Expected hashes from `openssl passwd -apr1 -salt <salt> <password>`.
+	for _, tc := range []struct{ password, salt, want string }{
+		{"hunter2", "saltsalt", "$apr1$saltsalt$r/QcFGT5pNL28bNkeDMHR."},
+		{"a-much-longer-password-exceeding-16", "ab", "$apr1$ab$n5wyLpsbUSz4VKhpHkKWl0"},
+	} {
+		if got := apr1WithSalt(tc.password, tc.salt); got != tc.want {
+			t.Errorf("apr1WithSalt(%q, %q) = %s, want %s", tc.password, tc.salt, got, tc.want)
+		}
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	creds, err := ParseCredentials("alice:s3cr:et\n  bob:hunter2 ")
+	if err != nil {
+		t.Fatalf("ParseCredentials: %v", err)
+	}
+	if len(creds) != 2 || creds[0] != (Credential{"alice", "s3cr:et"}) || creds[1] != (Credential{"bob", "hunter2"}) {
+		t.Errorf("ParseCredentials = %+v", creds)
+	}
+	for _, bad := range []string{"", "alice", "alice:", ":pw", "alice:a alice:b"} {
+		if _, err := ParseCredentials(bad); err == nil {
+			t.Errorf("ParseCredentials accepted %q", bad)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "web"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	creds := []Credential{{"alice", "s3cret"}, {"bob", "hunter2"}}
+
+	if err := Apply(dir, Options{Target: TargetNetlify, Credentials: creds}); err != nil {
+		t.Fatalf("Apply(netlify): %v", err)
+	}
+	headers, err := os.ReadFile(filepath.Join(dir, "web", "_headers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(headers), "/*\n  Basic-Auth: alice:s3cret bob:hunter2\n") {
+		t.Errorf("unexpected _headers:\n%s", headers)
+	}
+
+	if err := Apply(dir, Options{Target: TargetHtpasswd, Credentials: creds}); err != nil {
+		t.Fatalf("Apply(htpasswd): %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, HtpasswdFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "alice:$apr1$") || strings.Contains(string(data), "s3cret") {
+		t.Errorf("unexpected htpasswd:\n%s", data)
+	}
+}
@@ -0,0 +1,85 @@
+package access
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"fmt"
+)
+
+// itoa64 is the alphabet of crypt(3) hashes and salts.
+const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1 hashes password with a random salt in Apache's MD5 crypt variant
+// ($apr1$), as `htpasswd -m` does.
+func apr1(password string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating salt: %w", err)
+	}
+	salt := make([]byte, len(b))
+	for i, c := range b {
+		salt[i] = itoa64[int(c)%len(itoa64)]
+	}
+	return apr1WithSalt(password, string(salt)), nil
+}
+
+// apr1WithSalt implements the MD5 crypt algorithm with the "$apr1$" magic.
+func apr1WithSalt(password, salt string) string {
+	const magic = "$apr1$"
+	pw := []byte(password)
+
+	alt := md5.Sum([]byte(password + salt + password))
+
+	h := md5.New()
+	h.Write(pw)
+	h.Write([]byte(magic + salt))
+	for n := len(pw); n > 0; n -= 16 {
+		h.Write(alt[:min(n, 16)])
+	}
+	for n := len(pw); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	sum := h.Sum(nil)
+
+	// 1000 rounds to slow down brute force.
+	for i := 0; i < 1000; i++ {
+		r := md5.New()
+		if i&1 != 0 {
+			r.Write(pw)
+		} else {
+			r.Write(sum)
+		}
+		if i%3 != 0 {
+			r.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			r.Write(pw)
+		}
+		if i&1 != 0 {
+			r.Write(sum)
+		} else {
+			r.Write(pw)
+		}
+		sum = r.Sum(nil)
+	}
+
+	out := []byte(magic + salt + "$")
+	encode := func(a, b, c byte, n int) {
+		v := uint(a)<<16 | uint(b)<<8 | uint(c)
+		for ; n > 0; n-- {
+			out = append(out, itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	encode(sum[0], sum[6], sum[12], 4)
+	encode(sum[1], sum[7], sum[13], 4)
+	encode(sum[2], sum[8], sum[14], 4)
+	encode(sum[3], sum[9], sum[15], 4)
+	encode(sum[4], sum[10], sum[5], 4)
+	encode(0, 0, sum[11], 2)
+	return string(out)
+}
@@ -0,0 +1,304 @@
+package amp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client interacts with the API of AMP (CubeCoders Application Management
+// Panel). Requests for an instance go through the controller (ADS) at
+// PanelURL, which forwards them to the instance; the client logs in to each
+// instance once and logs in again when its session expires.
+type Client struct {
+	PanelURL string
+	Username string
+	Password string
+	HTTP     *http.Client
+
+	mu       sync.Mutex
+	sessions map[string]string // instance ID → session ID
+}
+
+// NewClient creates a new AMP API client.
+func NewClient(panelURL, username, password string) *Client {
+	return &Client{
+		PanelURL: strings.TrimRight(panelURL, "/"),
+		Username: username,
+		Password: password,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+		sessions: make(map[string]string),
+	}
+}
+
+// Backup is a backup of an instance, as listed by the local file backup
+// plugin.
+type Backup struct {
+	ID             string `json:"Id"`
+	Name           string `json:"Name"`
+	Description    string `json:"Description"`
+	ModifiedDate   Time   `json:"ModifiedDate"`
+	TotalSizeBytes int64  `json:"TotalSizeBytes"`
+	StoredLocally  bool   `json:"StoredLocally"`
+}
+
+// Time is a timestamp in AMP's JSON, either "/Date(<ms>)/" or RFC 3339.
+type Time struct{ time.Time }
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if ms, ok := strings.CutPrefix(s, "/Date("); ok {
+		ms, _, _ = strings.Cut(ms, ")")
+		// An offset such as "+0000" may follow the milliseconds.
+		if i := strings.IndexAny(ms, "+-"); i > 0 {
+			ms = ms[:i]
+		}
+		n, err := strconv.ParseInt(ms, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing AMP date %q: %w", s, err)
+		}
+		t.Time = time.UnixMilli(n).UTC()
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("parsing AMP date %q: %w", s, err)
+	}
+	t.Time = parsed
+	return nil
+}
+
+type loginResponse struct {
+	Success      bool   `json:"success"`
+	SessionID    string `json:"sessionID"`
+	ResultReason string `json:"resultReason"`
+}
+
+// actionResult is what methods that start a task return.
+type actionResult struct {
+	Status bool   `json:"Status"`
+	Reason string `json:"Reason"`
+}
+
+// apiError is the body AMP returns instead of a result when a call fails,
+// e.g. {"Title": "Unauthorized Access", "Message": "..."}.
+type apiError struct {
+	Title   string `json:"Title"`
+	Message string `json:"Message"`
+}
+
+// errUnauthorized marks a call rejected because the session expired.
+type errUnauthorized struct{ apiError }
+
+func (e *errUnauthorized) Error() string { return e.Title + ": " + e.Message }
+
+// instancePath returns the API path of method on an instance, through the
+// controller.
+func instancePath(instanceID, method string) string {
+	return "/API/ADSModule/Servers/" + instanceID + "/API/" + method
+}
+
+// post sends an API call and returns its raw response body. AMP takes every
+// call as a POST with the arguments as a JSON object.
+func (c *Client) post(ctx context.Context, path string, args map[string]any) ([]byte, error) {
+	url := c.PanelURL + path
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
+	}
+
+	var apiErr apiError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Title != "" {
+		if apiErr.Title == "Unauthorized Access" {
+			return nil, &errUnauthorized{apiErr}
+		}
+		return nil, fmt.Errorf("API returned an error for %s: %s: %s", url, apiErr.Title, apiErr.Message)
+	}
+	return body, nil
+}
+
+// session returns the session ID for an instance, logging in when there is
+// none yet.
+func (c *Client) session(ctx context.Context, instanceID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id := c.sessions[instanceID]; id != "" {
+		return id, nil
+	}
+
+	body, err := c.post(ctx, instancePath(instanceID, "Core/Login"), map[string]any{
+		"username":   c.Username,
+		"password":   c.Password,
+		"token":      "",
+		"rememberMe": false,
+	})
+	if err != nil {
+		return "", err
+	}
+	var result loginResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("decoding login response: %w", err)
+	}
+	if !result.Success || result.SessionID == "" {
+		return "", fmt.Errorf("logging in to instance %s as %s failed: %s", instanceID, c.Username, result.ResultReason)
+	}
+	c.sessions[instanceID] = result.SessionID
+	return result.SessionID, nil
+}
+
+// call runs method on an instance and decodes its result into out, unless
+// out is nil. An expired session is renewed once.
+func (c *Client) call(ctx context.Context, instanceID, method string, args map[string]any, out any) error {
+	for attempt := 0; ; attempt++ {
+		sid, err := c.session(ctx, instanceID)
+		if err != nil {
+			return err
+		}
+		withSession := map[string]any{"SESSIONID": sid}
+		for k, v := range args {
+			withSession[k] = v
+		}
+		body, err := c.post(ctx, instancePath(instanceID, method), withSession)
+		if _, expired := err.(*errUnauthorized); expired && attempt == 0 {
+			c.mu.Lock()
+			delete(c.sessions, instanceID)
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if out == nil {
+			return nil
+		}
+
+		// Most methods wrap their return value as {"result": ...}.
+		var wrapped struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if json.Unmarshal(body, &wrapped) == nil && wrapped.Result != nil {
+			body = wrapped.Result
+		}
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("decoding %s response: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// ListBackups returns the backups of an instance stored on the instance
+// itself, sorted by modification time (newest first). Backups only kept in
+// remote storage cannot be downloaded and are left out.
+func (c *Client) ListBackups(ctx context.Context, instanceID string) ([]Backup, error) {
+	var all []Backup
+	if err := c.call(ctx, instanceID, "LocalFileBackupPlugin/GetBackups", nil, &all); err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, b := range all {
+		if b.StoredLocally {
+			backups = append(backups, b)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModifiedDate.After(backups[j].ModifiedDate.Time)
+	})
+
+	return backups, nil
+}
+
+// TakeBackup starts a backup of an instance titled title. AMP writes the
+// archive in the background; poll ListBackups for it.
+func (c *Client) TakeBackup(ctx context.Context, instanceID, title, description string) error {
+	var result actionResult
+	if err := c.call(ctx, instanceID, "LocalFileBackupPlugin/TakeBackup", map[string]any{
+		"Title":       title,
+		"Description": description,
+		"Sticky":      false,
+	}, &result); err != nil {
+		return err
+	}
+	if !result.Status {
+		return fmt.Errorf("starting backup of instance %s failed: %s", instanceID, result.Reason)
+	}
+	return nil
+}
+
+// BackupPath returns the path of a backup archive in the instance's files.
+func BackupPath(backupID string) string {
+	return "Backups/" + backupID + ".zip"
+}
+
+// FileSize returns the size of a file of an instance.
+func (c *Client) FileSize(ctx context.Context, instanceID, file string) (int64, error) {
+	var entries []struct {
+		Filename    string `json:"Filename"`
+		IsDirectory bool   `json:"IsDirectory"`
+		SizeBytes   int64  `json:"SizeBytes"`
+	}
+	dir, name := path.Split(file)
+	if err := c.call(ctx, instanceID, "FileManagerPlugin/GetDirectoryListing", map[string]any{"Dir": dir}, &entries); err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if e.Filename == name && !e.IsDirectory {
+			return e.SizeBytes, nil
+		}
+	}
+	return 0, fmt.Errorf("%s not found on instance %s", file, instanceID)
+}
+
+// ReadFileChunk reads up to length bytes of a file of an instance, starting
+// at offset.
+func (c *Client) ReadFileChunk(ctx context.Context, instanceID, file string, offset, length int64) ([]byte, error) {
+	var encoded string
+	if err := c.call(ctx, instanceID, "FileManagerPlugin/ReadFileChunk", map[string]any{
+		"Filename":  file,
+		"Offset":    offset,
+		"ChunkSize": length,
+	}, &encoded); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding chunk of %s: %w", file, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+package amp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// chunkSize is how much of a file one ReadFileChunk call reads. The chunk
+// comes back base64 encoded inside JSON, so it is kept moderate.
+const chunkSize = 4 << 20
+
+// ServeFile makes a file of an instance downloadable over plain HTTP, which
+// AMP itself only offers to its web interface: it serves the file on a
+// loopback address, reading it through ReadFileChunk, until ctx is done. The
+// returned URL carries a random path and answers GET requests with Range
+// support, so the extractor can download it like any other backup.
+func (c *Client) ServeFile(ctx context.Context, instanceID, file string) (string, error) {
+	size, err := c.FileSize(ctx, instanceID, file)
+	if err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating download path: %w", err)
+	}
+	route := "/" + hex.EncodeToString(secret)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("listening for the download of %s: %w", file, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET "+route, &fileHandler{client: c, instanceID: instanceID, file: file, size: size})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	context.AfterFunc(ctx, func() { srv.Close() })
+
+	return "http://" + ln.Addr().String() + route, nil
+}
+
+// fileHandler serves one file of an instance, whole or as a single byte
+// range.
+type fileHandler struct {
+	client     *Client
+	instanceID string
+	file       string
+	size       int64
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start, end := int64(0), h.size-1
+	status := http.StatusOK
+	if spec := r.Header.Get("Range"); spec != "" {
+		var ok bool
+		if start, end, ok = parseRange(spec, h.size); !ok {
+			w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(h.size, 10))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, h.size))
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.WriteHeader(status)
+
+	for offset := start; offset <= end; {
+		n := min(chunkSize, end-offset+1)
+		data, err := h.client.ReadFileChunk(r.Context(), h.instanceID, h.file, offset, n)
+		if err == nil && len(data) == 0 {
+			err = errors.New("empty chunk")
+		}
+		if err != nil {
+			// The status is already sent; cutting the body short makes
+			// the client see a truncated download.
+			panic(http.ErrAbortHandler)
+		}
+		if _, err := w.Write(data); err != nil {
+			return
+		}
+		offset += int64(len(data))
+	}
+}
+
+// parseRange parses a single "bytes=start-end" range, with an open end, of a
+// file of size bytes.
+func parseRange(spec string, size int64) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(spec, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	from, to, found := strings.Cut(spec, "-")
+	if !found || from == "" {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if to != "" {
+		if end, err = strconv.ParseInt(to, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true
+}
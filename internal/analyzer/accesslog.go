@@ -0,0 +1,212 @@
+package analyzer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hiresShareThreshold is the fraction of a map's tile requests below which
+// hires tiles are considered rarely viewed and a lowres-only setup is
+// recommended.
+const hiresShareThreshold = 0.05
+
+// tileRequestRe matches BlueMap tile request paths anywhere in a log line,
+// e.g. "/maps/overworld/tiles/0/x1/z-2.prbm.gz" or
+// "/maps/overworld/tiles/2/x0/z0.png". Matching the path instead of parsing a
+// specific log format keeps the analyzer compatible with Netlify, Cloudflare
+// and S3 log exports alike.
+var tileRequestRe = regexp.MustCompile(`/maps/([^/\s"?]+)/tiles/(\d+)/`)
+
+// MapAccess holds tile request counts for a single map.
+type MapAccess struct {
+	MapID    string
+	Requests int64
+	// LODs maps the BlueMap level of detail to its request count. LOD 0 is
+	// the hires (3D) tile set, LOD 1 and above are lowres tiles.
+	LODs map[int]int64
+}
+
+// HiresShare returns the fraction of the map's tile requests that were hires
+// (LOD 0) tiles.
+func (m MapAccess) HiresShare() float64 {
+	if m.Requests == 0 {
+		return 0
+	}
+	return float64(m.LODs[0]) / float64(m.Requests)
+}
+
+// AccessLogReport summarizes the tile requests found in hosting access logs.
+type AccessLogReport struct {
+	Files        []string
+	Lines        int64
+	TileRequests int64
+	Maps         []MapAccess
+	// Unviewed lists maps present in web/maps that received no tile requests.
+	Unviewed []string
+}
+
+// AnalyzeAccessLogs reads all access log files matching the given glob
+// patterns (relative to serverDir) and counts BlueMap tile requests per map
+// and level of detail. Files ending in ".gz" are decompressed transparently.
+// Maps found under web/maps that never appear in the logs are reported as
+// unviewed.
+func AnalyzeAccessLogs(serverDir string, patterns []string) (*AccessLogReport, error) {
+	report := &AccessLogReport{}
+	counts := make(map[string]*MapAccess)
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(serverDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("globbing %s: %w", pattern, err)
+		}
+		for _, path := range matches {
+			if err := scanAccessLog(path, report, counts); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			report.Files = append(report.Files, path)
+		}
+	}
+
+	if len(report.Files) == 0 {
+		return nil, fmt.Errorf("no access log files matching %v", patterns)
+	}
+
+	for _, m := range counts {
+		report.Maps = append(report.Maps, *m)
+	}
+	sort.Slice(report.Maps, func(i, j int) bool {
+		return report.Maps[i].MapID < report.Maps[j].MapID
+	})
+
+	// Maps that exist in the output but were never requested.
+	entries, err := os.ReadDir(filepath.Join(serverDir, "web", "maps"))
+	if err == nil {
+		for _, e := range entries {
+			if e.IsDir() && counts[e.Name()] == nil {
+				report.Unviewed = append(report.Unviewed, e.Name())
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// scanAccessLog counts the tile requests in a single log file.
+func scanAccessLog(path string, report *AccessLogReport, counts map[string]*MapAccess) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("creating gzip reader: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		report.Lines++
+		m := tileRequestRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		lod, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+
+		access := counts[m[1]]
+		if access == nil {
+			access = &MapAccess{MapID: m[1], LODs: make(map[int]int64)}
+			counts[m[1]] = access
+		}
+		access.Requests++
+		access.LODs[lod]++
+		report.TileRequests++
+	}
+	return scanner.Err()
+}
+
+// Recommendations returns human-readable hosting optimization hints derived
+// from the report.
+func (r *AccessLogReport) Recommendations() []string {
+	var recs []string
+	for _, m := range r.Maps {
+		if m.Requests > 0 && m.HiresShare() < hiresShareThreshold {
+			recs = append(recs, fmt.Sprintf(
+				"%s: hires tiles are only %.1f%% of requests; consider rendering it lowres-only",
+				m.MapID, m.HiresShare()*100))
+		}
+
+		// Lowres LODs are contiguous from 1; if the most zoomed-out levels
+		// never show up, the lod-count can be lowered.
+		maxLOD := 0
+		for lod := range m.LODs {
+			if lod > maxLOD {
+				maxLOD = lod
+			}
+		}
+		for lod := 1; lod < maxLOD; lod++ {
+			if m.LODs[lod] == 0 {
+				recs = append(recs, fmt.Sprintf(
+					"%s: lowres LOD %d was never requested; consider trimming it", m.MapID, lod))
+			}
+		}
+	}
+	for _, id := range r.Unviewed {
+		recs = append(recs, fmt.Sprintf(
+			"%s: no tile requests at all; consider removing the map or rendering it lowres-only", id))
+	}
+	return recs
+}
+
+// PrintAccessLogAnalysis prints the access log report to stdout.
+func PrintAccessLogAnalysis(r *AccessLogReport) {
+	fmt.Println("📈  Access Log Analysis")
+	fmt.Printf("    log files:       %d\n", len(r.Files))
+	fmt.Printf("    lines scanned:   %d\n", r.Lines)
+	fmt.Printf("    tile requests:   %d\n", r.TileRequests)
+
+	for _, m := range r.Maps {
+		lods := make([]int, 0, len(m.LODs))
+		for lod := range m.LODs {
+			lods = append(lods, lod)
+		}
+		sort.Ints(lods)
+
+		parts := make([]string, 0, len(lods))
+		for _, lod := range lods {
+			parts = append(parts, fmt.Sprintf("lod%d=%d", lod, m.LODs[lod]))
+		}
+		fmt.Printf("    %-25s  %d (%s)\n", m.MapID, m.Requests, strings.Join(parts, ", "))
+	}
+	for _, id := range r.Unviewed {
+		fmt.Printf("    %-25s  0\n", id)
+	}
+
+	recs := r.Recommendations()
+	if len(recs) > 0 {
+		fmt.Println("    recommendations:")
+		for _, rec := range recs {
+			fmt.Printf("      • %s\n", rec)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeAccessLogs(t *testing.T) {
+	dir := t.TempDir()
+
+	// Mixed formats: S3-style, Netlify JSON and a non-tile request.
+	lines := []string{
+		`bucket [10/Oct/2026:10:00:00 +0000] 1.2.3.4 - REST.GET.OBJECT maps/overworld/tiles/0/x1/z2.prbm.gz "GET /maps/overworld/tiles/0/x1/z2.prbm.gz HTTP/1.1" 200`,
+		`{"path":"/maps/overworld/tiles/1/x0/z0.png","status":200}`,
+		`{"path":"/maps/overworld/tiles/3/x0/z0.png","status":200}`,
+		`{"path":"/maps/nether/tiles/0/x0/z0.prbm.gz","status":200}`,
+		`{"path":"/index.html","status":200}`,
+	}
+	logPath := filepath.Join(dir, "logs", "access.log")
+	writeFileBytes(t, logPath, 0)
+	if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	// A rendered map that never shows up in the logs.
+	writeFileBytes(t, filepath.Join(dir, "web", "maps", "end", "settings.json"), 1)
+
+	report, err := AnalyzeAccessLogs(dir, []string{"logs/*.log"})
+	if err != nil {
+		t.Fatalf("AnalyzeAccessLogs: %v", err)
+	}
+
+	if report.Lines != 5 {
+		t.Errorf("Lines = %d, want 5", report.Lines)
+	}
+	if report.TileRequests != 4 {
+		t.Errorf("TileRequests = %d, want 4", report.TileRequests)
+	}
+	if len(report.Maps) != 2 || report.Maps[0].MapID != "nether" || report.Maps[1].MapID != "overworld" {
+		t.Fatalf("unexpected maps: %+v", report.Maps)
+	}
+	ow := report.Maps[1]
+	if ow.Requests != 3 || ow.LODs[0] != 1 || ow.LODs[1] != 1 || ow.LODs[3] != 1 {
+		t.Errorf("overworld counts = %+v", ow)
+	}
+	if len(report.Unviewed) != 1 || report.Unviewed[0] != "end" {
+		t.Errorf("Unviewed = %v, want [end]", report.Unviewed)
+	}
+
+	recs := strings.Join(report.Recommendations(), "\n")
+	if !strings.Contains(recs, "overworld: lowres LOD 2 was never requested") {
+		t.Errorf("missing LOD recommendation in:\n%s", recs)
+	}
+	if !strings.Contains(recs, "end: no tile requests") {
+		t.Errorf("missing unviewed recommendation in:\n%s", recs)
+	}
+}
+
+func TestAnalyzeAccessLogsNoFiles(t *testing.T) {
+	if _, err := AnalyzeAccessLogs(t.TempDir(), []string{"missing/*.log"}); err == nil {
+		t.Fatal("expected error when no log files match, got nil")
+	}
+}
@@ -2,8 +2,12 @@ package analyzer
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/EfinaServer/bluemap-action/internal/config"
@@ -11,9 +15,9 @@ import (
 
 // WorldSummaryRow is a single row for the GitHub Step Summary world table.
 type WorldSummaryRow struct {
-	Label string
-	Size  int64
-	Found bool
+	Label string `json:"label"`
+	Size  int64  `json:"size"`
+	Found bool   `json:"found"`
 }
 
 // DirSize calculates the total size of all files in a directory recursively.
@@ -205,79 +209,128 @@ func AnalyzeUnifiedWorld(serverDir, worldName string) (*UnifiedReport, error) {
 	return report, nil
 }
 
-// PrintWorldAnalysis prints world size analysis to stdout based on server type.
-// It also returns a slice of rows for use in the GitHub Step Summary.
-func PrintWorldAnalysis(serverType, serverDir string, worlds []string) (int64, []WorldSummaryRow) {
-	fmt.Println("🌍  World Size Analysis")
+// unifiedDimensions maps vanilla dimension keys of unified worlds to the
+// [[worlds]] dimension names used to filter them. Custom dimensions are always
+// reported.
+var unifiedDimensions = map[string]string{
+	"minecraft:overworld":  config.DimensionOverworld,
+	"minecraft:the_nether": config.DimensionNether,
+	"minecraft:the_end":    config.DimensionEnd,
+}
 
+// AnalyzeWorldSizes measures the worlds using each world's folder layout
+// (vanilla, plugin or unified), skipping dimensions the world does not
+// include. It returns one row per folder or dimension, for the console, the
+// GitHub Step Summary and the -analysis-format output, and the total size.
+func AnalyzeWorldSizes(serverDir string, worlds []config.WorldConfig) ([]WorldSummaryRow, int64) {
 	var grandTotal int64
 	var rows []WorldSummaryRow
 
-	switch serverType {
-	case config.ServerTypeVanilla:
-		for _, w := range worlds {
-			report, err := AnalyzeVanillaWorld(serverDir, w)
+	for _, w := range worlds {
+		switch w.Type {
+		case config.ServerTypeVanilla:
+			report, err := AnalyzeVanillaWorld(serverDir, w.Name)
 			if err != nil {
-				fmt.Printf("    %-25s  (not found)\n", w)
-				rows = append(rows, WorldSummaryRow{Label: w + " (overworld)", Found: false})
+				rows = append(rows, WorldSummaryRow{Label: w.Name + " (overworld)", Found: false})
 				continue
 			}
 
-			fmt.Printf("    %-25s  %s\n", report.Overworld.Name+" (overworld)", FormatSize(report.Overworld.Size))
 			rows = append(rows, WorldSummaryRow{Label: report.Overworld.Name + " (overworld)", Size: report.Overworld.Size, Found: true})
-			if report.Nether.Exists {
-				fmt.Printf("    %-25s  %s\n", report.Nether.Name+" (nether)", FormatSize(report.Nether.Size))
+			if report.Nether.Exists && w.HasDimension(config.DimensionNether) {
 				rows = append(rows, WorldSummaryRow{Label: report.Nether.Name + " (nether)", Size: report.Nether.Size, Found: true})
 			}
-			if report.End.Exists {
-				fmt.Printf("    %-25s  %s\n", report.End.Name+" (end)", FormatSize(report.End.Size))
+			if report.End.Exists && w.HasDimension(config.DimensionEnd) {
 				rows = append(rows, WorldSummaryRow{Label: report.End.Name + " (end)", Size: report.End.Size, Found: true})
 			}
 			grandTotal += report.Total
-		}
 
-	case config.ServerTypeUnified:
-		for _, w := range worlds {
-			report, err := AnalyzeUnifiedWorld(serverDir, w)
+		case config.ServerTypeUnified:
+			report, err := AnalyzeUnifiedWorld(serverDir, w.Name)
 			if err != nil {
-				fmt.Printf("    %-25s  (not found)\n", w)
-				rows = append(rows, WorldSummaryRow{Label: w, Found: false})
+				rows = append(rows, WorldSummaryRow{Label: w.Name, Found: false})
 				continue
 			}
 
 			for _, d := range report.Dimensions {
-				fmt.Printf("    %-25s  %s\n", d.Key, FormatSize(d.Size))
-				rows = append(rows, WorldSummaryRow{Label: d.Key, Size: d.Size, Found: true})
+				if dim, ok := unifiedDimensions[d.Key]; ok && !w.HasDimension(dim) {
+					continue
+				}
+				label := d.Key
+				if len(worlds) > 1 {
+					label = w.Name + " " + d.Key
+				}
+				rows = append(rows, WorldSummaryRow{Label: label, Size: d.Size, Found: true})
 			}
-			fmt.Printf("    %-25s  %s\n", w+" (other)", FormatSize(report.OtherSize))
-			rows = append(rows, WorldSummaryRow{Label: w + " (other)", Size: report.OtherSize, Found: true})
+			rows = append(rows, WorldSummaryRow{Label: w.Name + " (other)", Size: report.OtherSize, Found: true})
 			grandTotal += report.Total
-		}
 
-	default: // plugin
-		reports, total := AnalyzeWorlds(serverDir, worlds)
-		for _, r := range reports {
-			if !r.Exists {
-				fmt.Printf("    %-25s  (not found)\n", r.Name)
-				rows = append(rows, WorldSummaryRow{Label: r.Name, Found: false})
-			} else {
-				fmt.Printf("    %-25s  %s\n", r.Name, FormatSize(r.Size))
-				rows = append(rows, WorldSummaryRow{Label: r.Name, Size: r.Size, Found: true})
+		default: // plugin
+			reports, total := AnalyzeWorlds(serverDir, w.Folders())
+			for _, r := range reports {
+				rows = append(rows, WorldSummaryRow{Label: r.Name, Size: r.Size, Found: r.Exists})
 			}
+			grandTotal += total
 		}
-		grandTotal = total
 	}
+	return rows, grandTotal
+}
 
-	fmt.Printf("    %-25s  %s\n", "TOTAL", FormatSize(grandTotal))
+// PrintWorldAnalysis prints the world sizes of AnalyzeWorldSizes to stdout.
+func PrintWorldAnalysis(rows []WorldSummaryRow, total int64) {
+	writeWorlds(os.Stdout, rows, total)
+}
 
-	return grandTotal, rows
+func writeWorlds(w io.Writer, rows []WorldSummaryRow, total int64) {
+	fmt.Fprintln(w, "🌍  World Size Analysis")
+	for _, row := range rows {
+		if row.Found {
+			fmt.Fprintf(w, "    %-25s  %s\n", row.Label, FormatSize(row.Size))
+		} else {
+			fmt.Fprintf(w, "    %-25s  (not found)\n", row.Label)
+		}
+	}
+	fmt.Fprintf(w, "    %-25s  %s\n", "TOTAL", FormatSize(total))
 }
 
+// WebLargestFiles is the number of largest files a WebOutputReport lists.
+const WebLargestFiles = 10
+
 // WebOutputReport holds statistics for the web output directory.
 type WebOutputReport struct {
-	TotalSize   int64
-	FileCount   int64
-	MaxFileSize int64
+	TotalSize   int64 `json:"total_size"`
+	FileCount   int64 `json:"file_count"`
+	MaxFileSize int64 `json:"max_file_size"`
+
+	Largest []WebFile  `json:"largest,omitempty"` // the WebLargestFiles largest files, largest first
+	Types   []WebGroup `json:"types,omitempty"`   // per file type, largest first
+	Maps    []WebMap   `json:"maps,omitempty"`    // per map folder under maps/, largest first
+}
+
+// WebMap is the output of one map, web/maps/<id>/.
+type WebMap struct {
+	ID          string `json:"id"`
+	Size        int64  `json:"size"`
+	Files       int64  `json:"files"`
+	HiresTiles  int64  `json:"hires_tiles"`  // tiles/0/
+	LowresTiles int64  `json:"lowres_tiles"` // tiles/1/ and up
+}
+
+// Tiles returns the number of tiles of the map.
+func (m WebMap) Tiles() int64 {
+	return m.HiresTiles + m.LowresTiles
+}
+
+// WebFile is a file of the web output.
+type WebFile struct {
+	Path string `json:"path"` // slash-separated, relative to web/
+	Size int64  `json:"size"`
+}
+
+// WebGroup is the size and file count of a group of web output files.
+type WebGroup struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Files int64  `json:"files"`
 }
 
 // AnalyzeWebOutput reports statistics for the web output directory.
@@ -292,22 +345,156 @@ func AnalyzeWebOutput(serverDir string) (*WebOutputReport, error) {
 	}
 
 	var report WebOutputReport
-	err = filepath.Walk(webDir, func(_ string, info os.FileInfo, err error) error {
+	types, maps := map[string]*WebGroup{}, map[string]*WebMap{}
+	err = filepath.Walk(webDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			report.TotalSize += info.Size()
-			report.FileCount++
-			if info.Size() > report.MaxFileSize {
-				report.MaxFileSize = info.Size()
-			}
+		if info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(webDir, path)
+		rel = filepath.ToSlash(rel)
+		size := info.Size()
+		report.TotalSize += size
+		report.FileCount++
+		if size > report.MaxFileSize {
+			report.MaxFileSize = size
 		}
+		report.Largest = addLargest(report.Largest, WebFile{Path: rel, Size: size})
+		addToGroup(types, fileType(rel), size)
+		addToMap(maps, rel, size)
 		return nil
 	})
+	report.Types = sortedGroups(types)
+	for _, m := range maps {
+		report.Maps = append(report.Maps, *m)
+	}
+	sort.Slice(report.Maps, func(i, j int) bool {
+		if report.Maps[i].Size != report.Maps[j].Size {
+			return report.Maps[i].Size > report.Maps[j].Size
+		}
+		return report.Maps[i].ID < report.Maps[j].ID
+	})
 	return &report, err
 }
 
+// addToMap counts rel, a slash-separated path in web/, for its map if it is
+// in one: maps/<id>/..., with the tiles in maps/<id>/tiles/<lod>/, where
+// level 0 holds the hires tiles.
+func addToMap(maps map[string]*WebMap, rel string, size int64) {
+	parts := strings.SplitN(rel, "/", 5)
+	if len(parts) < 3 || parts[0] != "maps" {
+		return
+	}
+	m := maps[parts[1]]
+	if m == nil {
+		m = &WebMap{ID: parts[1]}
+		maps[parts[1]] = m
+	}
+	m.Size += size
+	m.Files++
+	if len(parts) == 5 && parts[2] == "tiles" {
+		if parts[3] == "0" {
+			m.HiresTiles++
+		} else {
+			m.LowresTiles++
+		}
+	}
+}
+
+// addLargest adds f to files, the largest files so far, keeping at most
+// WebLargestFiles of them, largest first.
+func addLargest(files []WebFile, f WebFile) []WebFile {
+	if len(files) == WebLargestFiles && f.Size <= files[len(files)-1].Size {
+		return files
+	}
+	i := sort.Search(len(files), func(i int) bool { return files[i].Size < f.Size })
+	files = slices.Insert(files, i, f)
+	if len(files) > WebLargestFiles {
+		files = files[:WebLargestFiles]
+	}
+	return files
+}
+
+// fileType returns the extension rel is grouped by: the last one, with the
+// one before it for a compressed variant (".prbm.gz", ".json.br"), or
+// "(none)".
+func fileType(rel string) string {
+	base := path.Base(rel)
+	ext := path.Ext(base)
+	switch ext {
+	case "":
+		return "(none)"
+	case ".gz", ".br", ".zst":
+		if inner := path.Ext(strings.TrimSuffix(base, ext)); inner != "" {
+			return strings.ToLower(inner + ext)
+		}
+	}
+	return strings.ToLower(ext)
+}
+
+func addToGroup(groups map[string]*WebGroup, name string, size int64) {
+	g := groups[name]
+	if g == nil {
+		g = &WebGroup{Name: name}
+		groups[name] = g
+	}
+	g.Size += size
+	g.Files++
+}
+
+// sortedGroups returns the groups largest first, by name on a tie.
+func sortedGroups(groups map[string]*WebGroup) []WebGroup {
+	list := make([]WebGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// PrintWebOutput prints the web output statistics to stdout.
+func PrintWebOutput(r *WebOutputReport) {
+	writeWebOutput(os.Stdout, r)
+}
+
+func writeWebOutput(w io.Writer, r *WebOutputReport) {
+	fmt.Fprintf(w, "📊  Web Output Analysis\n")
+	fmt.Fprintf(w, "    web/ total size:   %s\n", FormatSize(r.TotalSize))
+	fmt.Fprintf(w, "    web/ file count:   %d\n", r.FileCount)
+	fmt.Fprintf(w, "    web/ largest file: %s\n", FormatSize(r.MaxFileSize))
+	if len(r.Maps) > 0 {
+		fmt.Fprintf(w, "    by map:\n")
+		for _, m := range r.Maps {
+			fmt.Fprintf(w, "      %-20s  %10s  %d tiles (%d hires, %d lowres), %d files\n",
+				m.ID, FormatSize(m.Size), m.Tiles(), m.HiresTiles, m.LowresTiles, m.Files)
+		}
+	}
+	writeWebGroups(w, "by file type", r.Types)
+	if len(r.Largest) > 0 {
+		fmt.Fprintf(w, "    largest files:\n")
+		for _, f := range r.Largest {
+			fmt.Fprintf(w, "      %10s  %s\n", FormatSize(f.Size), f.Path)
+		}
+	}
+}
+
+func writeWebGroups(w io.Writer, title string, groups []WebGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "    %s:\n", title)
+	for _, g := range groups {
+		fmt.Fprintf(w, "      %-20s  %10s  %d files\n", g.Name, FormatSize(g.Size), g.Files)
+	}
+}
+
 // FormatSize formats bytes into human-readable size string.
 func FormatSize(bytes int64) string {
 	const (
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -70,3 +71,40 @@ func TestAnalyzeUnifiedWorldMissing(t *testing.T) {
 		t.Fatal("expected error for missing world directory, got nil")
 	}
 }
+
+func TestAnalyzeWebOutput(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "web")
+	writeFileBytes(t, filepath.Join(web, "index.html"), 10)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "settings.json"), 20)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z0.prbm.gz"), 500)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z1.PRBM.gz"), 400)
+	writeFileBytes(t, filepath.Join(web, "maps", "nether", "tiles", "1", "x0", "z0.png"), 30)
+	for i := range WebLargestFiles {
+		writeFileBytes(t, filepath.Join(web, "assets", fmt.Sprintf("chunk%d", i)), 1+i)
+	}
+
+	r, err := AnalyzeWebOutput(dir)
+	if err != nil {
+		t.Fatalf("AnalyzeWebOutput: %v", err)
+	}
+	if r.FileCount != int64(5+WebLargestFiles) || r.MaxFileSize != 500 {
+		t.Errorf("file count %d, largest %d; want %d, 500", r.FileCount, r.MaxFileSize, 5+WebLargestFiles)
+	}
+
+	if len(r.Largest) != WebLargestFiles {
+		t.Fatalf("Largest has %d files, want %d", len(r.Largest), WebLargestFiles)
+	}
+	if r.Largest[0].Path != "maps/world/tiles/0/x0/z0.prbm.gz" || r.Largest[1].Size != 400 || r.Largest[WebLargestFiles-1].Size != 6 {
+		t.Errorf("Largest = %+v", r.Largest)
+	}
+
+	wantTypes := []WebGroup{{".prbm.gz", 900, 2}, {"(none)", 55, 10}, {".png", 30, 1}, {".json", 20, 1}, {".html", 10, 1}}
+	if fmt.Sprint(r.Types) != fmt.Sprint(wantTypes) {
+		t.Errorf("Types = %v, want %v", r.Types, wantTypes)
+	}
+	wantMaps := []WebMap{{"world", 920, 3, 2, 0}, {"nether", 30, 1, 0, 1}}
+	if fmt.Sprint(r.Maps) != fmt.Sprint(wantMaps) {
+		t.Errorf("Maps = %v, want %v", r.Maps, wantMaps)
+	}
+}
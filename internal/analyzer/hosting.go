@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/config"
+)
+
+// HostingLimits are the limits of a static hosting plan a deploy can run
+// into. Zero fields are not checked.
+type HostingLimits struct {
+	Name             string `json:"name"`
+	MaxFiles         int64  `json:"max_files"` // files in a single deploy
+	MaxFileSize      int64  `json:"max_file_size"`
+	MonthlyBandwidth int64  `json:"monthly_bandwidth"` // bytes served per month
+}
+
+// HostingPlans holds the published limits of the free plans of the hosts a
+// map is commonly deployed to. They change from time to time; the [hosting]
+// table overrides each of them.
+var HostingPlans = map[string]HostingLimits{
+	config.HostingPlanNetlifyFree: {
+		Name:             "Netlify Free",
+		MaxFiles:         54_000,
+		MonthlyBandwidth: 100e9,
+	},
+	config.HostingPlanCloudflarePagesFree: {
+		Name:        "Cloudflare Pages Free",
+		MaxFiles:    20_000,
+		MaxFileSize: 25 << 20,
+	},
+}
+
+// TilesPerVisit is the number of map tiles a visit is assumed to load for
+// the bandwidth estimate: the low-res view of the spawn area and some
+// zooming and panning.
+const TilesPerVisit = 200
+
+// HostingReport is the web output measured against a hosting plan.
+type HostingReport struct {
+	Limits       HostingLimits `json:"limits"`
+	Files        int64         `json:"files"`
+	ChangedFiles int64         `json:"changed_files"` // -1 when unknown (file_manifest off)
+	UploadSize   int64         `json:"upload_size"`   // size of the changed files
+	LargestFile  string        `json:"largest_file"`
+	LargestSize  int64         `json:"largest_size"`
+	ShellSize    int64         `json:"shell_size"` // everything outside the map tiles
+	Tiles        int64         `json:"tiles"`
+	TileSize     int64         `json:"tile_size"`
+
+	MonthlyVisits    int64    `json:"monthly_visits"`
+	MonthlyBandwidth int64    `json:"monthly_bandwidth"` // estimate; 0 without monthly_visits
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// AnalyzeHosting measures the web output of serverDir for a deploy to a host
+// with the given limits. monthlyVisits, if set, gives an estimate of the
+// bandwidth the map uses per month: every visit loads the webapp shell and
+// TilesPerVisit tiles of average size.
+func AnalyzeHosting(serverDir string, limits HostingLimits, monthlyVisits int64) (*HostingReport, error) {
+	webDir := filepath.Join(serverDir, "web")
+	r := &HostingReport{Limits: limits, ChangedFiles: -1, MonthlyVisits: monthlyVisits}
+	err := filepath.Walk(webDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(webDir, path)
+		rel = filepath.ToSlash(rel)
+		r.Files++
+		if info.Size() > r.LargestSize {
+			r.LargestFile, r.LargestSize = rel, info.Size()
+		}
+		if isTile(rel) {
+			r.Tiles++
+			r.TileSize += info.Size()
+		} else {
+			r.ShellSize += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if monthlyVisits > 0 {
+		perVisit := r.ShellSize
+		if r.Tiles > 0 {
+			perVisit += min(TilesPerVisit, r.Tiles) * (r.TileSize / r.Tiles)
+		}
+		r.MonthlyBandwidth = monthlyVisits * perVisit
+	}
+	r.Warnings = r.check()
+	return r, nil
+}
+
+// isTile reports whether rel, a slash-separated path in web/, is a map
+// tile: maps/<id>/tiles/...
+func isTile(rel string) bool {
+	parts := strings.SplitN(rel, "/", 4)
+	return len(parts) == 4 && parts[0] == "maps" && parts[2] == "tiles"
+}
+
+// SetChanged records the number and size of the files a deploy uploads,
+// known from the file manifest.
+func (r *HostingReport) SetChanged(files, size int64) {
+	r.ChangedFiles, r.UploadSize = files, size
+}
+
+// check returns a warning for every limit the web output goes over.
+func (r *HostingReport) check() []string {
+	limits := r.Limits
+	var warnings []string
+	if limits.MaxFiles > 0 && r.Files > limits.MaxFiles {
+		warnings = append(warnings, fmt.Sprintf("web/ has %d files, over the %d files per deploy of %s; the deploy will be rejected", r.Files, limits.MaxFiles, limits.Name))
+	}
+	if limits.MaxFileSize > 0 && r.LargestSize > limits.MaxFileSize {
+		warnings = append(warnings, fmt.Sprintf("web/%s is %s, over the %s file size limit of %s; the deploy will be rejected", r.LargestFile, FormatSize(r.LargestSize), FormatSize(limits.MaxFileSize), limits.Name))
+	}
+	if limits.MonthlyBandwidth > 0 && r.MonthlyBandwidth > limits.MonthlyBandwidth {
+		warnings = append(warnings, fmt.Sprintf("%d visits a month would use about %s, over the %s monthly bandwidth of %s", r.MonthlyVisits, FormatSize(r.MonthlyBandwidth), FormatSize(limits.MonthlyBandwidth), limits.Name))
+	}
+	return warnings
+}
+
+// PrintHostingAnalysis prints the report to stdout.
+func PrintHostingAnalysis(r *HostingReport) {
+	limits := r.Limits
+	fmt.Printf("🏷   Hosting Limits (%s)\n", limits.Name)
+	fmt.Printf("    files:        %d%s\n", r.Files, ofLimit(limits.MaxFiles, func(n int64) string { return fmt.Sprint(n) }))
+	if r.ChangedFiles >= 0 {
+		fmt.Printf("    changed:      %d (%s to upload)\n", r.ChangedFiles, FormatSize(r.UploadSize))
+	}
+	fmt.Printf("    largest file: %s%s\n", FormatSize(r.LargestSize), ofLimit(limits.MaxFileSize, FormatSize))
+	if r.MonthlyVisits > 0 {
+		fmt.Printf("    bandwidth:    ~%s/month for %d visits%s\n", FormatSize(r.MonthlyBandwidth), r.MonthlyVisits, ofLimit(limits.MonthlyBandwidth, FormatSize))
+	}
+}
+
+// ofLimit formats " / limit" for a set limit.
+func ofLimit(limit int64, format func(int64) string) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " / " + format(limit)
+}
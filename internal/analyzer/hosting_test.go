@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeHosting(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "web")
+	writeFileBytes(t, filepath.Join(web, "index.html"), 100)
+	writeFileBytes(t, filepath.Join(web, "assets", "index.js"), 900)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "settings.json"), 50)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z0.prbm.gz"), 300)
+	writeFileBytes(t, filepath.Join(web, "maps", "world", "tiles", "1", "x0", "z0.png"), 100)
+
+	limits := HostingLimits{Name: "Tiny", MaxFiles: 4, MaxFileSize: 1000, MonthlyBandwidth: 100 << 10}
+	r, err := AnalyzeHosting(dir, limits, 100)
+	if err != nil {
+		t.Fatalf("AnalyzeHosting: %v", err)
+	}
+	if r.Files != 5 || r.Tiles != 2 || r.TileSize != 400 || r.ShellSize != 1050 {
+		t.Errorf("files %d, tiles %d (%d bytes), shell %d bytes; want 5, 2 (400), 1050", r.Files, r.Tiles, r.TileSize, r.ShellSize)
+	}
+	if r.LargestFile != "assets/index.js" || r.LargestSize != 900 {
+		t.Errorf("largest file %s (%d bytes), want assets/index.js (900)", r.LargestFile, r.LargestSize)
+	}
+	// Each visit loads the shell and both tiles at their average size.
+	if want := int64(100 * (1050 + 2*200)); r.MonthlyBandwidth != want {
+		t.Errorf("MonthlyBandwidth = %d, want %d", r.MonthlyBandwidth, want)
+	}
+	// Over the file count and the bandwidth, not the file size.
+	if len(r.Warnings) != 2 {
+		t.Errorf("Warnings = %q, want 2", r.Warnings)
+	}
+
+	r, err = AnalyzeHosting(dir, HostingPlans["cloudflare-pages-free"], 0)
+	if err != nil {
+		t.Fatalf("AnalyzeHosting: %v", err)
+	}
+	if r.MonthlyBandwidth != 0 || len(r.Warnings) != 0 {
+		t.Errorf("without visits: bandwidth %d, warnings %q; want none", r.MonthlyBandwidth, r.Warnings)
+	}
+}
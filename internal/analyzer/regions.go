@@ -0,0 +1,219 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+)
+
+// InhabitedBuckets are the upper bounds, in ticks (20 per second), of the
+// InhabitedTime distribution reported per dimension. The last bucket is
+// open-ended.
+var InhabitedBuckets = []struct {
+	Label string
+	Max   int64
+}{
+	{"never", 0},
+	{"< 1 min", 20 * 60},
+	{"< 10 min", 20 * 60 * 10},
+	{"< 1 h", 20 * 60 * 60},
+	{"≥ 1 h", -1},
+}
+
+// RegionStats summarises the region files of one dimension.
+type RegionStats struct {
+	Label     string `json:"label"` // e.g. "world", "world (nether)", "world minecraft:the_end"
+	Regions   int    `json:"regions"`
+	Chunks    int    `json:"chunks"`
+	MinX      int    `json:"min_x"` // bounding box of generated chunks in block coordinates
+	MaxX      int    `json:"max_x"`
+	MinZ      int    `json:"min_z"`
+	MaxZ      int    `json:"max_z"`
+	Inhabited []int  `json:"inhabited,omitempty"` // chunk counts per InhabitedBuckets entry; nil unless requested
+	Unknown   int    `json:"unknown,omitempty"`   // chunks whose InhabitedTime could not be read
+}
+
+// AnalyzeRegions finds every region/ folder below the given world folders and
+// counts their chunks and bounding box. With inhabited set, each chunk is
+// also decompressed to build the InhabitedTime distribution, which takes
+// considerably longer on large worlds.
+func AnalyzeRegions(serverDir string, folders []string, inhabited bool) ([]RegionStats, error) {
+	var stats []RegionStats
+	for _, folder := range folders {
+		root := filepath.Join(serverDir, folder)
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() || info.Name() != "region" {
+				return nil
+			}
+			rel, _ := filepath.Rel(serverDir, filepath.Dir(path))
+			s, err := analyzeRegionDir(path, dimensionLabel(filepath.ToSlash(rel)), inhabited)
+			if err != nil {
+				return err
+			}
+			if s.Regions > 0 {
+				stats = append(stats, s)
+			}
+			return filepath.SkipDir
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// dimensionLabel turns the folder holding a region/ directory, relative to
+// the server directory, into a readable dimension label.
+func dimensionLabel(rel string) string {
+	world, sub, _ := strings.Cut(rel, "/")
+	switch {
+	case sub == "":
+		return world
+	case sub == "DIM-1" && !strings.HasSuffix(world, "_nether"):
+		return world + " (nether)"
+	case sub == "DIM1" && !strings.HasSuffix(world, "_the_end"):
+		return world + " (end)"
+	case sub == "DIM-1" || sub == "DIM1":
+		return world
+	case strings.HasPrefix(sub, "dimensions/"):
+		if ns, dim, ok := strings.Cut(strings.TrimPrefix(sub, "dimensions/"), "/"); ok {
+			return world + " " + ns + ":" + dim
+		}
+	}
+	return rel
+}
+
+func analyzeRegionDir(dir, label string, inhabited bool) (RegionStats, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "r.*.*.mca"))
+	if err != nil {
+		return RegionStats{}, err
+	}
+
+	s := RegionStats{Label: label}
+	if inhabited {
+		s.Inhabited = make([]int, len(InhabitedBuckets))
+	}
+	first := true
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	ch := make(chan string)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range ch {
+				chunks, err := mca.ReadChunks(path, inhabited)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("reading %s: %w", path, err)
+					}
+					mu.Unlock()
+					continue
+				}
+				if len(chunks) > 0 {
+					s.Regions++
+				}
+				for _, c := range chunks {
+					s.Chunks++
+					x, z := c.X*16, c.Z*16
+					if first || x < s.MinX {
+						s.MinX = x
+					}
+					if first || x+15 > s.MaxX {
+						s.MaxX = x + 15
+					}
+					if first || z < s.MinZ {
+						s.MinZ = z
+					}
+					if first || z+15 > s.MaxZ {
+						s.MaxZ = z + 15
+					}
+					first = false
+					if inhabited {
+						if c.Inhabited == mca.InhabitedUnknown {
+							s.Unknown++
+						} else {
+							s.Inhabited[inhabitedBucket(c.Inhabited)]++
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range paths {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+
+	return s, firstErr
+}
+
+func inhabitedBucket(ticks int64) int {
+	for i, b := range InhabitedBuckets {
+		if b.Max < 0 || (i == 0 && ticks <= 0) || (i > 0 && ticks < b.Max) {
+			return i
+		}
+	}
+	return len(InhabitedBuckets) - 1
+}
+
+// Bounds formats the bounding box as "x -512..1023, z -256..767".
+func (s RegionStats) Bounds() string {
+	if s.Chunks == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("x %d..%d, z %d..%d", s.MinX, s.MaxX, s.MinZ, s.MaxZ)
+}
+
+// InhabitedSummary formats the InhabitedTime distribution as percentages of
+// the chunks that could be read, e.g. "never 62%, < 1 min 20%, …".
+func (s RegionStats) InhabitedSummary() string {
+	known := s.Chunks - s.Unknown
+	if s.Inhabited == nil || known == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(InhabitedBuckets))
+	for i, b := range InhabitedBuckets {
+		parts = append(parts, fmt.Sprintf("%s %d%%", b.Label, s.Inhabited[i]*100/known))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PrintRegionStats prints the per-dimension chunk statistics.
+func PrintRegionStats(stats []RegionStats) {
+	writeRegionStats(os.Stdout, stats)
+}
+
+func writeRegionStats(w io.Writer, stats []RegionStats) {
+	fmt.Fprintln(w, "🧱  Chunk Statistics")
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Chunks > stats[j].Chunks })
+	for _, s := range stats {
+		fmt.Fprintf(w, "    %-25s  %d chunks in %d regions, %s\n", s.Label, s.Chunks, s.Regions, s.Bounds())
+		if summary := s.InhabitedSummary(); summary != "" {
+			fmt.Fprintf(w, "    %-25s  inhabited: %s\n", "", summary)
+		}
+		if s.Unknown > 0 {
+			fmt.Fprintf(w, "    %-25s  %d chunks with unreadable InhabitedTime (LZ4 or external storage)\n", "", s.Unknown)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// chunkNBT returns a minimal chunk compound. Old-format chunks nest
+// InhabitedTime under "Level"; other tags come first so the scanner has to
+// skip them.
+func chunkNBT(inhabited int64, legacy bool) []byte {
+	var b bytes.Buffer
+	name := func(tag byte, s string) {
+		b.WriteByte(tag)
+		binary.Write(&b, binary.BigEndian, uint16(len(s)))
+		b.WriteString(s)
+	}
+	name(10, "") // root compound
+	if legacy {
+		name(10, "Level")
+	}
+	name(8, "Status")
+	binary.Write(&b, binary.BigEndian, uint16(4))
+	b.WriteString("full")
+	name(9, "sections") // list of one compound holding a long array
+	b.WriteByte(10)
+	binary.Write(&b, binary.BigEndian, int32(1))
+	name(12, "data")
+	binary.Write(&b, binary.BigEndian, int32(2))
+	binary.Write(&b, binary.BigEndian, [2]int64{1, 2})
+	b.WriteByte(0)
+	name(4, "InhabitedTime")
+	binary.Write(&b, binary.BigEndian, inhabited)
+	if legacy {
+		b.WriteByte(0)
+	}
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+// writeRegion writes a region file with the given chunks (index → NBT), each
+// zlib-compressed into its own sector.
+func writeRegion(t *testing.T, path string, chunks map[int][]byte) {
+	t.Helper()
+	data := make([]byte, 8192, 8192+len(chunks)*4096)
+	sector := 2
+	for i, nbt := range chunks {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(nbt)
+		zw.Close()
+
+		binary.BigEndian.PutUint32(data[i*4:], uint32(sector<<8|1))
+		buf := make([]byte, 4096)
+		binary.BigEndian.PutUint32(buf, uint32(z.Len()+1))
+		buf[4] = 2
+		copy(buf[5:], z.Bytes())
+		data = append(data, buf...)
+		sector++
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyzeRegions(t *testing.T) {
+	dir := t.TempDir()
+	writeRegion(t, filepath.Join(dir, "world", "region", "r.0.0.mca"), map[int][]byte{
+		0:  chunkNBT(0, false),          // chunk 0,0
+		33: chunkNBT(20*60*5, false),    // chunk 1,1
+		31: chunkNBT(20*60*60*2, false), // chunk 31,0
+	})
+	writeRegion(t, filepath.Join(dir, "world", "region", "r.-1.0.mca"), map[int][]byte{
+		31: chunkNBT(100, true), // chunk -1,0 (legacy layout)
+	})
+	writeRegion(t, filepath.Join(dir, "world", "DIM-1", "region", "r.0.0.mca"), map[int][]byte{
+		0: chunkNBT(0, false),
+	})
+
+	stats, err := AnalyzeRegions(dir, []string{"world", "missing"}, true)
+	if err != nil {
+		t.Fatalf("AnalyzeRegions: %v", err)
+	}
+	byLabel := make(map[string]RegionStats)
+	for _, s := range stats {
+		byLabel[s.Label] = s
+	}
+	if len(byLabel) != 2 {
+		t.Fatalf("labels = %v, want world and world (nether)", byLabel)
+	}
+
+	ow := byLabel["world"]
+	if ow.Chunks != 4 || ow.Regions != 2 {
+		t.Errorf("overworld: %d chunks in %d regions, want 4 in 2", ow.Chunks, ow.Regions)
+	}
+	if got, want := ow.Bounds(), "x -16..511, z 0..31"; got != want {
+		t.Errorf("Bounds() = %q, want %q", got, want)
+	}
+	if want := []int{1, 1, 1, 0, 1}; !slices.Equal(ow.Inhabited, want) || ow.Unknown != 0 {
+		t.Errorf("Inhabited = %v (unknown %d), want %v", ow.Inhabited, ow.Unknown, want)
+	}
+	if nether := byLabel["world (nether)"]; nether.Chunks != 1 {
+		t.Errorf("nether chunks = %d, want 1", nether.Chunks)
+	}
+}
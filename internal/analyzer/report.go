@@ -0,0 +1,218 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats of a Report.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists the output formats, the default first.
+var Formats = []string{FormatText, FormatJSON, FormatCSV, FormatMarkdown}
+
+// Report is the analysis of a server directory as data, written in one of
+// the Formats for other tools. The GitHub Step Summary renders its tables
+// from the same rows.
+type Report struct {
+	Worlds     []WorldSummaryRow `json:"worlds"`
+	WorldTotal int64             `json:"world_total"`
+	Regions    []RegionStats     `json:"regions,omitempty"`
+	Web        *WebOutputReport  `json:"web,omitempty"` // nil when there is no web output
+}
+
+// Write writes the report to w in format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		return r.writeText(w)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		return r.writeCSV(w)
+	case FormatMarkdown:
+		var sb strings.Builder
+		WriteWorldsMarkdown(&sb, r.Worlds, r.WorldTotal)
+		WriteRegionsMarkdown(&sb, r.Regions)
+		if r.Web != nil {
+			WriteWebMarkdown(&sb, r.Web)
+		}
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+	return fmt.Errorf("unknown format %q, want one of %s", format, strings.Join(Formats, ", "))
+}
+
+// writeText writes the report like the console output of a run.
+func (r *Report) writeText(w io.Writer) error {
+	var sb strings.Builder
+	writeWorlds(&sb, r.Worlds, r.WorldTotal)
+	if len(r.Regions) > 0 {
+		sb.WriteString("\n")
+		writeRegionStats(&sb, r.Regions)
+	}
+	if r.Web != nil {
+		sb.WriteString("\n")
+		writeWebOutput(&sb, r.Web)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeCSV writes the report as one "section,name,metric,value" record per
+// value, so every section fits a single table. Sizes are in bytes.
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rec := func(section, name, metric string, value any) {
+		cw.Write([]string{section, name, metric, fmt.Sprint(value)})
+	}
+	cw.Write([]string{"section", "name", "metric", "value"})
+	for _, row := range r.Worlds {
+		rec("world", row.Label, "found", row.Found)
+		if row.Found {
+			rec("world", row.Label, "size_bytes", row.Size)
+		}
+	}
+	rec("world", "TOTAL", "size_bytes", r.WorldTotal)
+	for _, s := range r.Regions {
+		rec("chunks", s.Label, "chunks", s.Chunks)
+		rec("chunks", s.Label, "regions", s.Regions)
+		if s.Chunks > 0 {
+			rec("chunks", s.Label, "min_x", s.MinX)
+			rec("chunks", s.Label, "max_x", s.MaxX)
+			rec("chunks", s.Label, "min_z", s.MinZ)
+			rec("chunks", s.Label, "max_z", s.MaxZ)
+		}
+		for i, n := range s.Inhabited {
+			rec("chunks", s.Label, "inhabited "+InhabitedBuckets[i].Label, n)
+		}
+		if s.Inhabited != nil {
+			rec("chunks", s.Label, "inhabited unknown", s.Unknown)
+		}
+	}
+	if r.Web != nil {
+		rec("web", "web", "total_size_bytes", r.Web.TotalSize)
+		rec("web", "web", "file_count", r.Web.FileCount)
+		rec("web", "web", "max_file_size_bytes", r.Web.MaxFileSize)
+		for _, m := range r.Web.Maps {
+			rec("web_map", m.ID, "size_bytes", m.Size)
+			rec("web_map", m.ID, "files", m.Files)
+			rec("web_map", m.ID, "hires_tiles", m.HiresTiles)
+			rec("web_map", m.ID, "lowres_tiles", m.LowresTiles)
+		}
+		for _, g := range r.Web.Types {
+			rec("web_type", g.Name, "size_bytes", g.Size)
+			rec("web_type", g.Name, "files", g.Files)
+		}
+		for _, f := range r.Web.Largest {
+			rec("web_largest", f.Path, "size_bytes", f.Size)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteWorldsMarkdown writes the world sizes as a Markdown section.
+func WriteWorldsMarkdown(sb *strings.Builder, rows []WorldSummaryRow, total int64) {
+	sb.WriteString("### 🌍 World Sizes\n\n")
+	sb.WriteString("| World | Size |\n")
+	sb.WriteString("|:---|---:|\n")
+	for _, row := range rows {
+		if row.Found {
+			sb.WriteString(fmt.Sprintf("| %s | %s |\n", row.Label, FormatSize(row.Size)))
+		} else {
+			sb.WriteString(fmt.Sprintf("| %s | *(not found)* |\n", row.Label))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("| **TOTAL** | **%s** |\n", FormatSize(total)))
+	sb.WriteString("\n")
+}
+
+// WriteRegionsMarkdown writes the chunk statistics as a Markdown section,
+// or nothing without statistics.
+func WriteRegionsMarkdown(sb *strings.Builder, stats []RegionStats) {
+	if len(stats) == 0 {
+		return
+	}
+	inhabited := stats[0].Inhabited != nil
+	sb.WriteString("### 🧱 Chunks\n\n")
+	if inhabited {
+		sb.WriteString("| Dimension | Chunks | Regions | Bounds (blocks) | Inhabited Time |\n")
+		sb.WriteString("|:---|---:|---:|:---|:---|\n")
+	} else {
+		sb.WriteString("| Dimension | Chunks | Regions | Bounds (blocks) |\n")
+		sb.WriteString("|:---|---:|---:|:---|\n")
+	}
+	for _, s := range stats {
+		row := fmt.Sprintf("| %s | %d | %d | %s |", s.Label, s.Chunks, s.Regions, s.Bounds())
+		if inhabited {
+			row += fmt.Sprintf(" %s |", s.InhabitedSummary())
+		}
+		sb.WriteString(row + "\n")
+	}
+	sb.WriteString("\n")
+}
+
+// WriteWebMarkdown writes the web output statistics as a Markdown section,
+// with the breakdown per map and file type and the largest files when the
+// report has them. extra adds rows of property and value to its table.
+func WriteWebMarkdown(sb *strings.Builder, web *WebOutputReport, extra ...[2]string) {
+	sb.WriteString("### 📊 Web Output\n\n")
+	sb.WriteString("| Property | Value |\n")
+	sb.WriteString("|:---|---:|\n")
+	sb.WriteString(fmt.Sprintf("| **Total Size** | %s |\n", FormatSize(web.TotalSize)))
+	sb.WriteString(fmt.Sprintf("| **File Count** | %d |\n", web.FileCount))
+	sb.WriteString(fmt.Sprintf("| **Largest File** | %s |\n", FormatSize(web.MaxFileSize)))
+	for _, row := range extra {
+		sb.WriteString(fmt.Sprintf("| **%s** | %s |\n", row[0], row[1]))
+	}
+	sb.WriteString("\n")
+	if len(web.Maps) > 0 {
+		sb.WriteString("| Map | Size | Tiles | Hires | Lowres | Files | Share |\n")
+		sb.WriteString("|:---|---:|---:|---:|---:|---:|---:|\n")
+		for _, m := range web.Maps {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %d | %d | %d | %d | %s |\n",
+				m.ID, FormatSize(m.Size), m.Tiles(), m.HiresTiles, m.LowresTiles, m.Files, share(m.Size, web.TotalSize)))
+		}
+		sb.WriteString("\n")
+	}
+	writeWebGroupsMarkdown(sb, "File Type", web.Types, web.TotalSize)
+	if len(web.Largest) > 0 {
+		sb.WriteString("| Largest File | Size |\n")
+		sb.WriteString("|:---|---:|\n")
+		for _, f := range web.Largest {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", f.Path, FormatSize(f.Size)))
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func writeWebGroupsMarkdown(sb *strings.Builder, title string, groups []WebGroup, total int64) {
+	if len(groups) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("| %s | Size | Files | Share |\n", title))
+	sb.WriteString("|:---|---:|---:|---:|\n")
+	for _, g := range groups {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | %s |\n", g.Name, FormatSize(g.Size), g.Files, share(g.Size, total)))
+	}
+	sb.WriteString("\n")
+}
+
+// share formats size as a percentage of total.
+func share(size, total int64) string {
+	if total <= 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(size)/float64(total))
+}
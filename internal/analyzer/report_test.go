@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testReport() *Report {
+	return &Report{
+		Worlds: []WorldSummaryRow{
+			{Label: "Overworld (world)", Size: 2048, Found: true},
+			{Label: "Nether (world_nether)"},
+		},
+		WorldTotal: 2048,
+		Regions:    []RegionStats{{Label: "world", Regions: 1, Chunks: 3, MinX: -1, MaxX: 1, MinZ: 0, MaxZ: 0}},
+		Web: &WebOutputReport{
+			TotalSize:   4096,
+			FileCount:   7,
+			MaxFileSize: 1024,
+			Maps:        []WebMap{{ID: "world", Size: 2048, Files: 5, HiresTiles: 3, LowresTiles: 1}},
+		},
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().Write(&buf, FormatJSON); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Worlds) != 2 || got.Worlds[0].Size != 2048 || got.Worlds[1].Found {
+		t.Errorf("worlds = %+v", got.Worlds)
+	}
+	if len(got.Regions) != 1 || got.Regions[0].Chunks != 3 || got.Web == nil || got.Web.FileCount != 7 {
+		t.Errorf("regions %+v, web %+v", got.Regions, got.Web)
+	}
+	if !strings.Contains(buf.String(), `"world_total": 2048`) {
+		t.Errorf("missing world_total in\n%s", buf.String())
+	}
+}
+
+func TestReportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().Write(&buf, FormatCSV); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not CSV: %v", err)
+	}
+	if got := strings.Join(records[0], ","); got != "section,name,metric,value" {
+		t.Errorf("header = %s", got)
+	}
+	values := map[string]string{}
+	for _, r := range records[1:] {
+		if len(r) != 4 {
+			t.Fatalf("record %q has %d fields, want 4", r, len(r))
+		}
+		values[r[0]+"/"+r[1]+"/"+r[2]] = r[3]
+	}
+	for key, want := range map[string]string{
+		"world/Overworld (world)/size_bytes": "2048",
+		"world/Nether (world_nether)/found":  "false",
+		"world/TOTAL/size_bytes":             "2048",
+		"chunks/world/chunks":                "3",
+		"chunks/world/min_x":                 "-1",
+		"web/web/file_count":                 "7",
+		"web_map/world/hires_tiles":          "3",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %q, want %q", key, values[key], want)
+		}
+	}
+	if _, ok := values["world/Nether (world_nether)/size_bytes"]; ok {
+		t.Error("size written for a world that was not found")
+	}
+}
+
+func TestReportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().Write(&buf, FormatMarkdown); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"### 🌍 World Sizes",
+		"| Nether (world_nether) | *(not found)* |",
+		"| **TOTAL** | **2.00 KB** |",
+		"### 🧱 Chunks",
+		"| world | 3 | 1 |",
+		"| **File Count** | 7 |",
+		"| `world` | 2.00 KB | 4 | 3 | 1 | 5 | 50.0% |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestReportUnknownFormat(t *testing.T) {
+	if err := testReport().Write(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -4,15 +4,42 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"regexp"
 )
 
+// cacheBustRe matches the settings.json and live data URLs the webapp fetches,
+// including a cache-busting query added by a previous run.
+var cacheBustRe = regexp.MustCompile(`(settings\.json|/live/markers\.json|/live/players\.json)(\?v=[0-9A-Za-z]*)?`)
+
+// BackupDirName is the folder next to config.toml that keeps the webapp
+// bundles as BlueMap wrote them, before RewriteCompressedRefs patched them.
+// It lies outside web/ so the backups are never published.
+const BackupDirName = ".bluemap-bundle-backup"
+
+// loaderRefs are the URL string literals in the webapp bundle that load map
+// data, and their compressed replacements. Only whole literals are patched,
+// so other strings that merely contain ".prbm" or "textures.json" stay
+// intact, and patched literals no longer match, which makes the rewrite
+// idempotent.
+var loaderRefs = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// this.tilePath + pathFromCoords(x, z) + ".prbm"
+	{regexp.MustCompile(`(["'\x60])\.prbm(["'\x60])`), "${1}.prbm.gz${2}"},
+	// texturesUrl: mapDataRoot + "/textures.json"
+	{regexp.MustCompile(`(["'\x60])/textures\.json(["'\x60])`), "${1}/textures.json.gz${2}"},
+}
+
 // RewriteCompressedRefs finds web/assets/index-*.js in the given server
-// directory and rewrites asset references to point to their gzip-compressed
-// variants (.prbm → .prbm.gz, /textures.json → /textures.json.gz).
+// directory and rewrites the loader URLs for hi-res tiles and textures to
+// their gzip-compressed variants (".prbm" → ".prbm.gz", "/textures.json" →
+// "/textures.json.gz"). Each bundle is copied to BackupDirName before it is
+// first changed.
 //
-// This is necessary because Netlify does not support wildcard rewrites,
-// so the JavaScript must reference the compressed files directly.
+// This is necessary for hosts without wildcard rewrites or content
+// negotiation, such as Netlify, so the JavaScript must reference the
+// compressed files directly.
 func RewriteCompressedRefs(serverDir string) error {
 	pattern := filepath.Join(serverDir, "web", "assets", "index-*.js")
 	matches, err := filepath.Glob(pattern)
@@ -24,7 +51,7 @@ func RewriteCompressedRefs(serverDir string) error {
 	}
 
 	for _, path := range matches {
-		if err := rewriteFile(path); err != nil {
+		if err := rewriteFile(path, filepath.Join(serverDir, BackupDirName)); err != nil {
 			return fmt.Errorf("rewriting %s: %w", path, err)
 		}
 	}
@@ -32,34 +59,69 @@ func RewriteCompressedRefs(serverDir string) error {
 	return nil
 }
 
-func rewriteFile(path string) error {
+// AddCacheBust appends a "?v=<token>" query to the settings.json and live
+// data (markers.json, players.json) URLs in web/assets/index-*.js, so hosts
+// and CDNs whose caching cannot be configured still serve the new render
+// immediately. A query from a previous run is replaced, so the rewrite is
+// idempotent per token.
+func AddCacheBust(serverDir, token string) error {
+	pattern := filepath.Join(serverDir, "web", "assets", "index-*.js")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("globbing %s: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no files matching %s", pattern)
+	}
+
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		content := cacheBustRe.ReplaceAllString(string(data), "${1}?v="+token)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		fmt.Printf("    %s: cache-busting query ?v=%s added\n", filepath.Base(path), token)
+	}
+
+	return nil
+}
+
+func rewriteFile(path, backupDir string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading file: %w", err)
 	}
 
 	content := string(data)
-	original := content
-
-	// Protect already-compressed references before replacing, so that running
-	// the tool more than once on the same file is safe (idempotent).
-	content = strings.ReplaceAll(content, ".prbm.gz", "\x00prbm.gz")
-	content = strings.ReplaceAll(content, ".prbm", ".prbm.gz")
-	content = strings.ReplaceAll(content, "\x00prbm.gz", ".prbm.gz")
-
-	content = strings.ReplaceAll(content, "/textures.json.gz", "\x00textures.json.gz")
-	content = strings.ReplaceAll(content, "/textures.json", "/textures.json.gz")
-	content = strings.ReplaceAll(content, "\x00textures.json.gz", "/textures.json.gz")
+	count := 0
+	for _, ref := range loaderRefs {
+		count += len(ref.re.FindAllStringIndex(content, -1))
+		content = ref.re.ReplaceAllString(content, ref.replacement)
+	}
 
-	if content == original {
+	if count == 0 {
 		fmt.Printf("    %s: no changes needed\n", filepath.Base(path))
 		return nil
 	}
 
+	// The bundle still had unpatched loader URLs, so it is BlueMap's
+	// original; keep it before changing it.
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+	backup := filepath.Join(backupDir, filepath.Base(path))
+	if err := os.WriteFile(backup, data, 0o644); err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("writing file: %w", err)
 	}
 
-	fmt.Printf("    %s: rewritten .prbm → .prbm.gz, /textures.json → /textures.json.gz\n", filepath.Base(path))
+	fmt.Printf("    %s: rewritten %d loader URL(s) to .prbm.gz / textures.json.gz (original in %s/)\n",
+		filepath.Base(path), count, BackupDirName)
 	return nil
 }
@@ -0,0 +1,79 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddCacheBust(t *testing.T) {
+	dir := t.TempDir()
+	assetsDir := filepath.Join(dir, "web", "assets")
+	if err := os.MkdirAll(assetsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	js := filepath.Join(assetsDir, "index-abc123.js")
+	src := `fetch("settings.json");load(root+"/live/markers.json");load(root+"/live/players.json");load("/textures.json.gz")`
+	if err := os.WriteFile(js, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second run must replace the first token instead of stacking queries.
+	for _, token := range []string{"first", "second"} {
+		if err := AddCacheBust(dir, token); err != nil {
+			t.Fatalf("AddCacheBust(%q): %v", token, err)
+		}
+	}
+
+	data, err := os.ReadFile(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	want := `fetch("settings.json?v=second");load(root+"/live/markers.json?v=second");load(root+"/live/players.json?v=second");load("/textures.json.gz")`
+	if got != want {
+		t.Errorf("rewritten bundle:\n got %s\nwant %s", got, want)
+	}
+	if strings.Contains(got, "first") {
+		t.Errorf("stale token left behind: %s", got)
+	}
+}
+
+func TestRewriteCompressedRefs(t *testing.T) {
+	dir := t.TempDir()
+	assetsDir := filepath.Join(dir, "web", "assets")
+	if err := os.MkdirAll(assetsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	js := filepath.Join(assetsDir, "index-abc123.js")
+	src := `let r=this.tilePath+pathFromCoords(e,t)+".prbm";texturesUrl:t+"/textures.json",` +
+		`ext:'.prbm',help:"see /textures.json for details",n:"tile.prbm"`
+	if err := os.WriteFile(js, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run must neither change the bundle nor replace the backup.
+	for i := 0; i < 2; i++ {
+		if err := RewriteCompressedRefs(dir); err != nil {
+			t.Fatalf("RewriteCompressedRefs: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `let r=this.tilePath+pathFromCoords(e,t)+".prbm.gz";texturesUrl:t+"/textures.json.gz",` +
+		`ext:'.prbm.gz',help:"see /textures.json for details",n:"tile.prbm"`
+	if string(data) != want {
+		t.Errorf("rewritten bundle:\n got %s\nwant %s", data, want)
+	}
+	backup, err := os.ReadFile(filepath.Join(dir, BackupDirName, "index-abc123.js"))
+	if err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if string(backup) != src {
+		t.Errorf("backup is not the original bundle:\n%s", backup)
+	}
+}
@@ -0,0 +1,125 @@
+package bluemap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout describes the parts of BlueMap's web output that the post-processing
+// steps depend on, for a range of releases the tool has been tested against.
+type Layout struct {
+	From, To string // inclusive release range, e.g. "5.0" and "5.16"
+
+	// Bundle is the webapp JavaScript glob below web/ whose references are
+	// rewritten to the compressed files and cache-busted.
+	Bundle string
+	// Refs are the strings the bundle must contain for those rewrites to
+	// apply, with the feature that relies on each.
+	Refs []Ref
+	// TilesDir is the folder below web/maps/<id>/ holding the map's tiles,
+	// one directory per coordinate digit (see internal/prune).
+	TilesDir string
+}
+
+// Ref is a string the webapp bundle is expected to reference.
+type Ref struct {
+	Text    string
+	Feature string
+}
+
+// compatibility lists the BlueMap releases the rewrite, cache-busting and
+// pruning logic is tested with. Add a row (or widen To) after checking a new
+// release's web output with the e2e test.
+var compatibility = []Layout{
+	{
+		From:   "5.0",
+		To:     "5.16",
+		Bundle: "assets/index-*.js",
+		Refs: []Ref{
+			{".prbm", "compressed tile references"},
+			{"/textures.json", "compressed texture references"},
+			{"settings.json", "cache_bust"},
+			{"/live/markers.json", "cache_bust"},
+			{"/live/players.json", "cache_bust"},
+		},
+		TilesDir: "tiles",
+	},
+}
+
+// CompatibleLayout returns the web output layout of a BlueMap version and
+// whether the version lies within a tested range. An untested version gets
+// the layout of the closest tested release, which is the best guess for the
+// health check.
+func CompatibleLayout(version string) (Layout, bool) {
+	for _, l := range compatibility {
+		if compareVersions(version, l.From) >= 0 && compareVersions(version, l.To) <= 0 {
+			return l, true
+		}
+	}
+	if compareVersions(version, compatibility[0].From) < 0 {
+		return compatibility[0], false
+	}
+	return compatibility[len(compatibility)-1], false
+}
+
+// TestedVersions describes the tested release ranges for messages, e.g.
+// "5.0–5.16".
+func TestedVersions() string {
+	ranges := make([]string, len(compatibility))
+	for i, l := range compatibility {
+		ranges[i] = l.From + "–" + l.To
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// CheckWebOutput compares a freshly rendered web/ folder with the layout
+// expected for version and describes every difference that would make the
+// post-processing steps silently do nothing: a missing webapp bundle, a
+// reference the bundle no longer contains, or a rendered map without tiles.
+// It runs before the rewrites so a changed BlueMap release is noticed on the
+// run that first uses it.
+func CheckWebOutput(serverDir, version string) ([]string, error) {
+	layout, _ := CompatibleLayout(version)
+	webDir := filepath.Join(serverDir, "web")
+
+	var problems []string
+	pattern := filepath.Join(webDir, filepath.FromSlash(layout.Bundle))
+	bundles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("globbing %s: %w", pattern, err)
+	}
+	if len(bundles) == 0 {
+		problems = append(problems, fmt.Sprintf("no webapp bundle matching web/%s", layout.Bundle))
+	}
+	var content strings.Builder
+	for _, b := range bundles {
+		data, err := os.ReadFile(b)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", b, err)
+		}
+		content.Write(data)
+	}
+	if len(bundles) > 0 {
+		for _, ref := range layout.Refs {
+			if !strings.Contains(content.String(), ref.Text) {
+				problems = append(problems, fmt.Sprintf("webapp bundle does not reference %q (%s)", ref.Text, ref.Feature))
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(webDir, "maps"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(webDir, "maps", e.Name(), layout.TilesDir)); err != nil {
+			problems = append(problems, fmt.Sprintf("map %q has no %s/ folder", e.Name(), layout.TilesDir))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,61 @@
+package bluemap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompatibleLayout(t *testing.T) {
+	for version, want := range map[string]bool{
+		"5.0":  true,
+		"5.9":  true,
+		"5.16": true,
+		"4.2":  false,
+		"5.17": false,
+		"6.0":  false,
+	} {
+		if _, tested := CompatibleLayout(version); tested != want {
+			t.Errorf("CompatibleLayout(%q) tested = %v, want %v", version, tested, want)
+		}
+	}
+}
+
+func TestCheckWebOutput(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, "web", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := CheckWebOutput(dir, "5.16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "no webapp bundle") {
+		t.Errorf("empty web/: problems = %q", problems)
+	}
+
+	write("assets/index-abc.js", `fetch("settings.json");fetch(m+"/live/markers.json");fetch(m+"/live/players.json");`+
+		`load(m+"/textures.json");tile(".prbm")`)
+	write("maps/world/tiles/0/x1/z2.prbm.gz", "")
+	if problems, _ := CheckWebOutput(dir, "5.16"); len(problems) != 0 {
+		t.Errorf("expected layout: problems = %q", problems)
+	}
+
+	// A release that renamed the tile format and stopped writing tiles/.
+	write("assets/index-abc.js", `fetch("settings.json");fetch(m+"/live/markers.json");fetch(m+"/live/players.json");`+
+		`load(m+"/textures.json");tile(".glb")`)
+	write("maps/nether/settings.json", "{}")
+	problems, _ = CheckWebOutput(dir, "6.0")
+	if len(problems) != 2 || !strings.Contains(problems[0], `".prbm"`) || !strings.Contains(problems[1], `"nether"`) {
+		t.Errorf("changed layout: problems = %q", problems)
+	}
+}
@@ -1,12 +1,19 @@
 package bluemap
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 // CLIJarName returns the expected jar filename for the given version.
@@ -22,51 +29,330 @@ func DownloadURL(version string) string {
 	)
 }
 
-// EnsureCLI downloads the BlueMap CLI jar into serverDir if it doesn't already
-// exist. Returns the absolute path to the jar file.
-func EnsureCLI(serverDir, version string) (string, error) {
+// DownloadURLs returns the URLs the jar for version is downloaded from, in
+// the order they are tried: override (bluemap_download_url) or else the
+// GitHub release, then each of mirrors (bluemap_mirrors). "{version}" and
+// "{jar}" in a URL are replaced by the version and CLIJarName.
+func DownloadURLs(version, override string, mirrors []string) []string {
+	expand := strings.NewReplacer("{version}", version, "{jar}", CLIJarName(version)).Replace
+	urls := []string{DownloadURL(version)}
+	if override != "" {
+		urls[0] = expand(override)
+	}
+	for _, m := range mirrors {
+		urls = append(urls, expand(m))
+	}
+	return urls
+}
+
+// ChecksumURL returns the URL of the SHA-256 checksum file published
+// alongside the jar in the BlueMap release assets.
+func ChecksumURL(version string) string {
+	return DownloadURL(version) + ".sha256"
+}
+
+// SharedCacheDir returns the directory shared by all server directories for
+// caching BlueMap CLI jars. BLUEMAP_ACTION_CACHE_DIR takes precedence, then
+// the Actions runner tool cache ($RUNNER_TOOL_CACHE), then the user cache
+// directory (e.g. ~/.cache/bluemap-action/jars). An empty string means no
+// shared cache is available.
+func SharedCacheDir() string {
+	if dir := os.Getenv("BLUEMAP_ACTION_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("RUNNER_TOOL_CACHE"); dir != "" {
+		return filepath.Join(dir, "bluemap-action", "jars")
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "bluemap-action", "jars")
+	}
+	return ""
+}
+
+// EnsureCLI makes the BlueMap CLI jar available in serverDir and verifies its
+// SHA-256 checksum. Returns the absolute path to the jar file.
+//
+// Jars are kept in a shared cache (see SharedCacheDir) keyed by version and
+// checksum, and symlinked (or copied, where symlinks are unsupported) into
+// serverDir, so batch runs over many server directories download each
+// version only once. Without a usable shared cache the jar is downloaded
+// straight into serverDir.
+//
+// The jar is downloaded from urls (see DownloadURLs), trying the next when
+// one fails or serves a jar that does not match the checksum. The expected
+// checksum is expectedSHA256 when set (bluemap_sha256 in config.toml), then
+// the one recorded in bluemap.lock for version; only when neither is known
+// is the checksum file published in the BlueMap release assets on GitHub
+// fetched, never one from a mirror or the shared cache, and recorded in
+// bluemap.lock if the lock pins version. If no checksum can be obtained, or
+// no URL serves a matching jar, an error is returned and the jar is never
+// handed to the caller.
+func EnsureCLI(ctx context.Context, serverDir, version, expectedSHA256 string, urls []string) (string, error) {
 	jarPath := filepath.Join(serverDir, CLIJarName(version))
+	cacheDir := SharedCacheDir()
 
-	if info, err := os.Stat(jarPath); err == nil && info.Size() > 0 {
-		fmt.Printf("  ✔  BlueMap CLI %s already cached (%s)\n", version, formatSize(info.Size()))
+	expected, source, err := resolveChecksum(ctx, serverDir, version, expectedSHA256)
+	if err != nil {
+		return "", err
+	}
+	if source == "release asset" {
+		if err := recordChecksum(serverDir, version, expected); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠️  %v\n", err)
+		}
+	}
+
+	ok, err := verifyExisting(jarPath, expected)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		fmt.Printf("  ✔  BlueMap CLI %s already present (sha256 verified via %s)\n", version, source)
 		return jarPath, nil
 	}
 
-	url := DownloadURL(version)
+	if cacheDir != "" {
+		cachedJar := filepath.Join(cacheDir, version, expected, CLIJarName(version))
+		if err := os.MkdirAll(filepath.Dir(cachedJar), 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠️  shared jar cache unavailable (%v); downloading into server directory\n", err)
+		} else {
+			ok, err := verifyExisting(cachedJar, expected)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				fmt.Printf("  ✔  BlueMap CLI %s found in shared cache %s\n", version, cacheDir)
+			} else if err := downloadJar(ctx, version, urls, cachedJar, expected, source); err != nil {
+				return "", err
+			}
+			if err := linkOrCopy(cachedJar, jarPath); err != nil {
+				return "", fmt.Errorf("linking cached jar into %s: %w", serverDir, err)
+			}
+			return jarPath, nil
+		}
+	}
+
+	if err := downloadJar(ctx, version, urls, jarPath, expected, source); err != nil {
+		return "", err
+	}
+	return jarPath, nil
+}
+
+// verifyExisting reports whether the jar at path exists and matches the
+// expected checksum. A mismatching jar is removed so it can be replaced.
+func verifyExisting(path, expected string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return false, nil
+	}
+
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return false, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	if sum == expected {
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "  ⚠️  %s checksum mismatch (got %s); replacing\n", path, sum)
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("removing mismatched jar: %w", err)
+	}
+	return false, nil
+}
+
+// downloadJar downloads the jar for version from the first of urls that
+// serves a jar matching expected.
+func downloadJar(ctx context.Context, version string, urls []string, destPath, expected, source string) error {
 	fmt.Printf("  ⬇️  downloading BlueMap CLI %s\n", version)
-	fmt.Printf("     URL: %s\n", url)
+	var errs []error
+	for i, url := range urls {
+		fmt.Printf("     URL: %s\n", url)
+		err := fetchJar(ctx, version, url, destPath, expected, source)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || len(urls) == 1 {
+			return err
+		}
+		if i < len(urls)-1 {
+			fmt.Fprintf(os.Stderr, "  ⚠️  %v; trying the next mirror\n", err)
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no download URL served BlueMap CLI %s: %w", version, errors.Join(errs...))
+}
 
+// fetchJar downloads the jar for version from url into destPath via a .tmp
+// file and rename, refusing to keep it unless its checksum matches expected.
+func fetchJar(ctx context.Context, version, url, destPath, expected, source string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
 	client := &http.Client{Timeout: 10 * time.Minute}
-	resp, err := client.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("downloading BlueMap CLI: %w", err)
+		return fmt.Errorf("downloading BlueMap CLI: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("download returned status %d for %s", resp.StatusCode, url)
+		return fmt.Errorf("download returned status %d for %s", resp.StatusCode, url)
 	}
 
-	tmpPath := jarPath + ".tmp"
+	tmpPath := destPath + ".tmp"
 	f, err := os.Create(tmpPath)
 	if err != nil {
-		return "", fmt.Errorf("creating temp file: %w", err)
+		return fmt.Errorf("creating temp file: %w", err)
 	}
 
-	written, err := io.Copy(f, resp.Body)
+	h := sha256.New()
+	written, err := io.Copy(io.MultiWriter(f, h), resp.Body)
 	f.Close()
 	if err != nil {
 		os.Remove(tmpPath)
-		return "", fmt.Errorf("writing jar file: %w", err)
+		return fmt.Errorf("writing jar file: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, jarPath); err != nil {
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != expected {
 		os.Remove(tmpPath)
-		return "", fmt.Errorf("renaming temp file: %w", err)
+		return fmt.Errorf("checksum mismatch for %s from %s: expected %s (%s), got %s", CLIJarName(version), url, expected, source, sum)
 	}
 
-	fmt.Printf("  ✔  downloaded %s\n", formatSize(written))
-	return jarPath, nil
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	fmt.Printf("  ✔  downloaded %s (sha256 verified via %s)\n", formatSize(written), source)
+	return nil
+}
+
+// linkOrCopy places src at dst as a symlink, falling back to a file copy
+// when symlinks are not supported (e.g. some Windows or container setups).
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Symlink(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := dst + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, dst)
+}
+
+// resolveChecksum returns the expected lowercase hex SHA-256 of the jar and a
+// short description of where it came from. The release asset is fetched only
+// when neither config.toml nor bluemap.lock knows the checksum of version.
+// The shared cache is never a source: a jar placed there would otherwise
+// verify against its own directory name.
+func resolveChecksum(ctx context.Context, serverDir, version, configured string) (sum, source string, err error) {
+	if configured != "" {
+		return strings.ToLower(configured), "config.toml", nil
+	}
+	var l VersionLock
+	if _, err := toml.DecodeFile(filepath.Join(serverDir, LockFileName), &l); err == nil && l.Version == version && isSHA256(l.SHA256) {
+		return strings.ToLower(l.SHA256), LockFileName, nil
+	}
+
+	url := ChecksumURL(version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("creating request: %w", err)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("fetching checksum for BlueMap CLI %s: %w (set bluemap_sha256 in config.toml)", version, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("no published checksum for BlueMap CLI %s (status %d for %s); set bluemap_sha256 in config.toml", version, resp.StatusCode, url)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", "", fmt.Errorf("reading checksum file: %w", err)
+	}
+
+	sum, ok := parseChecksumFile(string(body))
+	if !ok {
+		return "", "", fmt.Errorf("malformed checksum file at %s", url)
+	}
+	return sum, "release asset", nil
+}
+
+// recordChecksum adds sum to serverDir/bluemap.lock when the lock pins
+// version, so later runs verify the jar without fetching the checksum again.
+func recordChecksum(serverDir, version, sum string) error {
+	lockPath := filepath.Join(serverDir, LockFileName)
+	var l VersionLock
+	if _, err := toml.DecodeFile(lockPath, &l); err != nil || l.Version != version || l.SHA256 == sum {
+		return nil
+	}
+	l.SHA256 = sum
+	f, err := os.Create(lockPath)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", lockPath, err)
+	}
+	defer f.Close()
+	if err := toml.NewEncoder(f).Encode(l); err != nil {
+		return fmt.Errorf("writing %s: %w", lockPath, err)
+	}
+	return nil
+}
+
+// parseChecksumFile extracts the hex digest from a checksum file in either
+// "<digest>" or sha256sum's "<digest>  <filename>" format.
+func parseChecksumFile(content string) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || !isSHA256(fields[0]) {
+		return "", false
+	}
+	return strings.ToLower(fields[0]), true
+}
+
+// isSHA256 reports whether s is a 64-character hex-encoded SHA-256 digest.
+func isSHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// fileSHA256 returns the lowercase hex SHA-256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func formatSize(bytes int64) string {
@@ -0,0 +1,91 @@
+package bluemap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDownloadURLs(t *testing.T) {
+	got := DownloadURLs("5.4", "", []string{"https://mirror.example.com/bluemap/v{version}/{jar}"})
+	want := []string{DownloadURL("5.4"), "https://mirror.example.com/bluemap/v5.4/bluemap-5.4-cli.jar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DownloadURLs = %q, want %q", got, want)
+	}
+	if got := DownloadURLs("5.4", "https://cdn.example.com/{jar}", nil); !reflect.DeepEqual(got, []string{"https://cdn.example.com/bluemap-5.4-cli.jar"}) {
+		t.Errorf("DownloadURLs with an override = %q", got)
+	}
+}
+
+func TestDownloadJarMirrors(t *testing.T) {
+	jar := []byte("genuine jar")
+	sum := sha256.Sum256(jar)
+	expected := hex.EncodeToString(sum[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case "/tampered":
+			w.Write([]byte("tampered jar"))
+		default:
+			w.Write(jar)
+		}
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), CLIJarName("5.4"))
+	urls := []string{srv.URL + "/down", srv.URL + "/tampered", srv.URL + "/good"}
+	if err := downloadJar(context.Background(), "5.4", urls, dest, expected, "config.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != string(jar) {
+		t.Errorf("jar = %q, %v; want the one matching the checksum", data, err)
+	}
+
+	os.Remove(dest)
+	err := downloadJar(context.Background(), "5.4", urls[:2], dest, expected, "config.toml")
+	if err == nil || !strings.Contains(err.Error(), "status 503") || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("err = %v, want both failures", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("jar kept after every URL failed: %v", err)
+	}
+}
+
+func TestResolveChecksumWithoutFetching(t *testing.T) {
+	// A cancelled context makes any fetch of the release asset fail.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sum := strings.Repeat("ab", sha256.Size)
+
+	// A jar in the shared cache does not vouch for itself by its directory
+	// name.
+	serverDir, cacheDir := t.TempDir(), t.TempDir()
+	t.Setenv("BLUEMAP_ACTION_CACHE_DIR", cacheDir)
+	jarSum := sha256.Sum256([]byte("jar"))
+	jarDir := filepath.Join(cacheDir, "5.4", hex.EncodeToString(jarSum[:]))
+	os.MkdirAll(jarDir, 0o755)
+	os.WriteFile(filepath.Join(jarDir, CLIJarName("5.4")), []byte("jar"), 0o644)
+	if path, err := EnsureCLI(ctx, serverDir, "5.4", "", nil); err == nil {
+		t.Errorf("EnsureCLI = %q with only the cached jar's directory name as checksum", path)
+	}
+	if _, _, err := resolveChecksum(ctx, serverDir, "5.5", ""); err == nil {
+		t.Error("a version missing from the lock resolved without fetching")
+	}
+
+	os.WriteFile(filepath.Join(serverDir, LockFileName), []byte("spec = \"latest\"\nversion = \"5.5\"\n"), 0o644)
+	if err := recordChecksum(serverDir, "5.5", sum); err != nil {
+		t.Fatal(err)
+	}
+	if got, source, err := resolveChecksum(ctx, serverDir, "5.5", ""); err != nil || got != sum || source != LockFileName {
+		t.Errorf("from the lock: %q, %q, %v", got, source, err)
+	}
+}
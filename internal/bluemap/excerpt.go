@@ -0,0 +1,66 @@
+package bluemap
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RenderLogName is the file in the server directory the BlueMap CLI output
+// is written to, unless -keep-intermediate moves it to the debug directory.
+const RenderLogName = "render.log"
+
+// excerptLineCount is how many lines of the render output a failure report
+// quotes.
+const excerptLineCount = 50
+
+// exceptionRe matches a line naming a Java exception, e.g.
+// "java.lang.OutOfMemoryError: Java heap space", capturing its class.
+var exceptionRe = regexp.MustCompile(`(?:^|[\s:\]])((?:[A-Za-z_$][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error))(?::|\s|$)`)
+
+// RenderError is returned by Render when the BlueMap CLI fails or is killed
+// by the watchdog. It carries the part of the output that explains the
+// failure, for reports that cannot show the whole log.
+type RenderError struct {
+	Err       error
+	Exception string   // the exception BlueMap threw, with its root cause; empty if none was printed
+	Excerpt   []string // output lines from the top of the last stack trace, or the last lines
+}
+
+func (e *RenderError) Error() string { return e.Err.Error() }
+func (e *RenderError) Unwrap() error { return e.Err }
+
+// failureExcerpt picks the lines of output that explain a failure: the last
+// stack trace, from the line logged just before it, or else the last
+// excerptLineCount lines. exception names the trace's exception and, when it
+// has one, its last "Caused by:".
+func failureExcerpt(lines []string) (exception string, excerpt []string) {
+	top := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "at ") || strings.HasPrefix(line, "Caused by:") ||
+			strings.HasPrefix(line, "Suppressed:") || strings.HasPrefix(line, "... ") {
+			continue
+		}
+		if m := exceptionRe.FindStringSubmatch(line); m != nil {
+			top, exception = i, m[1]
+			break
+		}
+	}
+	if top < 0 {
+		return "", lines[max(len(lines)-excerptLineCount, 0):]
+	}
+
+	cause := ""
+	for _, line := range lines[top+1:] {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "Caused by:"); ok {
+			if m := exceptionRe.FindStringSubmatch(rest); m != nil {
+				cause = m[1]
+			}
+		}
+	}
+	if cause != "" && cause != exception {
+		exception += " (caused by " + cause + ")"
+	}
+	start := max(top-1, 0)
+	return exception, lines[start:min(start+excerptLineCount, len(lines))]
+}
@@ -0,0 +1,39 @@
+package bluemap
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFailureExcerpt(t *testing.T) {
+	var lines []string
+	for i := range 80 {
+		lines = append(lines, fmt.Sprintf("[INFO] Rendering region r.%d.0", i))
+	}
+	trace := []string{
+		"[ERROR] Failed to render map 'overworld'!",
+		"de.bluecolored.bluemap.core.resources.ResourceException: Failed to load resource pack",
+		"\tat de.bluecolored.bluemap.core.resources.ResourcePack.load(ResourcePack.java:120)",
+		"Caused by: java.io.IOException: broken zip",
+		"\t... 12 more",
+		"Caused by: java.util.zip.ZipException: invalid LOC header",
+	}
+	exception, excerpt := failureExcerpt(append(lines, trace...))
+	if want := "de.bluecolored.bluemap.core.resources.ResourceException (caused by java.util.zip.ZipException)"; exception != want {
+		t.Errorf("exception = %q, want %q", exception, want)
+	}
+	if !reflect.DeepEqual(excerpt, trace) {
+		t.Errorf("excerpt = %q, want the trace from the line before it", excerpt)
+	}
+
+	oom := append(lines, "Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space")
+	if exception, _ := failureExcerpt(oom); exception != "java.lang.OutOfMemoryError" {
+		t.Errorf("exception = %q, want java.lang.OutOfMemoryError", exception)
+	}
+
+	exception, excerpt = failureExcerpt(lines)
+	if exception != "" || len(excerpt) != excerptLineCount || excerpt[len(excerpt)-1] != lines[len(lines)-1] {
+		t.Errorf("without a trace got %q and %d lines, want the last %d lines", exception, len(excerpt), excerptLineCount)
+	}
+}
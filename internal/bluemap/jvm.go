@@ -0,0 +1,63 @@
+package bluemap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultHeapFraction is the share of total system memory given to the JVM
+// heap when max_memory is not configured. The rest is left for the OS page
+// cache, the JVM's off-heap memory and this tool itself.
+const defaultHeapFraction = 0.75
+
+// JVMArgs returns the JVM flags placed before -jar. An explicit -Xmx in
+// javaArgs wins; otherwise maxMemory (e.g. "6G") is used, falling back to a
+// default derived from the total memory of the machine.
+func JVMArgs(javaArgs []string, maxMemory string) []string {
+	var args []string
+
+	hasXmx := false
+	for _, a := range javaArgs {
+		if strings.HasPrefix(a, "-Xmx") {
+			hasXmx = true
+			break
+		}
+	}
+
+	if !hasXmx {
+		if maxMemory != "" {
+			args = append(args, "-Xmx"+maxMemory)
+		} else if total, ok := totalMemory(); ok {
+			args = append(args, fmt.Sprintf("-Xmx%dm", int64(float64(total)*defaultHeapFraction)>>20))
+		}
+	}
+
+	return append(args, javaArgs...)
+}
+
+// totalMemory returns the total physical memory in bytes as reported by
+// /proc/meminfo. It reports false on systems without procfs, in which case
+// the JVM's own default heap sizing is used.
+func totalMemory() (int64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return kb << 10, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,22 @@
+package bluemap
+
+import "regexp"
+
+var (
+	mapWorldRe     = regexp.MustCompile(`(?m)^\s*world\s*[:=]\s*"([^"]+)"`)
+	mapDimensionRe = regexp.MustCompile(`(?m)^\s*dimension\s*[:=]\s*"([^"]+)"`)
+)
+
+// MapWorld returns the world and dimension a map config in config/maps/
+// shows, read from its world and dimension keys. The world is "" when the
+// config names none, and the dimension defaults to "minecraft:overworld".
+func MapWorld(conf []byte) (world, dimension string) {
+	dimension = "minecraft:overworld"
+	if m := mapDimensionRe.FindSubmatch(conf); m != nil {
+		dimension = string(m[1])
+	}
+	if m := mapWorldRe.FindSubmatch(conf); m != nil {
+		world = string(m[1])
+	}
+	return world, dimension
+}
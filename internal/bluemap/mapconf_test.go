@@ -0,0 +1,18 @@
+package bluemap
+
+import "testing"
+
+func TestMapWorld(t *testing.T) {
+	for _, tt := range []struct {
+		conf, world, dimension string
+	}{
+		{"world: \"world\"\ndimension: \"minecraft:the_nether\"\n", "world", "minecraft:the_nether"},
+		{"name = \"Lobby\"\n  world = \"worlds/lobby\"\n", "worlds/lobby", "minecraft:overworld"},
+		{"# world: \"commented\"\nsorting: 0\n", "", "minecraft:overworld"},
+	} {
+		world, dimension := MapWorld([]byte(tt.conf))
+		if world != tt.world || dimension != tt.dimension {
+			t.Errorf("MapWorld(%q) = %q, %q; want %q, %q", tt.conf, world, dimension, tt.world, tt.dimension)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package bluemap
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/modrinth"
+)
+
+// ModDir is the directory, relative to the server directory, that the
+// BlueMap CLI loads mod and datapack jars from.
+const ModDir = "config/packs"
+
+// modListName is the file in ModDir listing the jars the last run
+// installed, so a jar no longer configured, or replaced by a newer
+// version, is removed rather than loaded next to its successor.
+const modListName = ".installed-mods"
+
+// Mod is a mod or datapack jar BlueMap needs to render the blocks it adds:
+// a download URL or a Modrinth project.
+type Mod struct {
+	URL      string
+	Modrinth string // project ID or slug
+	Version  string // Modrinth version ID or number; empty = newest for the Minecraft version
+	Loader   string // loader the newest Modrinth version must support, e.g. "fabric"
+	SHA256   string // pinned checksum of the jar; empty = not pinned
+}
+
+func (m Mod) String() string {
+	if m.URL != "" {
+		return m.URL
+	}
+	if m.Version != "" {
+		return "modrinth:" + m.Modrinth + "@" + m.Version
+	}
+	return "modrinth:" + m.Modrinth
+}
+
+// modFile is a mod resolved to the file to download.
+type modFile struct {
+	mod    Mod
+	url    string
+	name   string
+	sha256 string // expected digests; empty = not known
+	sha512 string
+}
+
+// resolveMod looks up the file of m; a Modrinth project without a version
+// resolves to its newest version for mcVersion.
+func resolveMod(ctx context.Context, client *modrinth.Client, mcVersion string, m Mod) (modFile, error) {
+	f := modFile{mod: m, url: m.URL, sha256: strings.ToLower(m.SHA256)}
+	if m.URL != "" {
+		name, ok := urlFileName(m.URL)
+		if !ok {
+			return modFile{}, fmt.Errorf("mod URL must be http(s) and end in a file name, got %q", m.URL)
+		}
+		f.name = name
+		return f, nil
+	}
+
+	var (
+		v   *modrinth.Version
+		err error
+	)
+	if m.Version != "" {
+		v, err = client.GetVersion(ctx, m.Modrinth, m.Version)
+	} else {
+		v, err = client.LatestVersion(ctx, m.Modrinth, mcVersion, m.Loader)
+	}
+	if err != nil {
+		return modFile{}, err
+	}
+	file, err := v.PrimaryFile()
+	if err != nil {
+		return modFile{}, err
+	}
+	f.url, f.name, f.sha512 = file.URL, filepath.Base(file.Filename), strings.ToLower(file.Hashes.SHA512)
+	return f, nil
+}
+
+// CheckMods resolves the Modrinth projects in mods without downloading
+// anything, for validate. It returns how many mods are listed.
+func CheckMods(ctx context.Context, client *modrinth.Client, mcVersion string, mods []Mod) (int, error) {
+	var errs []error
+	for _, m := range mods {
+		if m.Modrinth == "" {
+			continue
+		}
+		if _, err := resolveMod(ctx, client, mcVersion, m); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m, err))
+		}
+	}
+	return len(mods), errors.Join(errs...)
+}
+
+// InstallMods downloads the jars of mods into ModDir under serverDir, where
+// the BlueMap CLI loads the blocks and textures of modded worlds from.
+// Modrinth projects are resolved for mcVersion (Minecraft's version).
+//
+// A jar is checked against its pinned SHA-256 and, from Modrinth, the
+// SHA-512 Modrinth publishes; a mismatch is an error. Jars with a known
+// checksum are kept in a mods/ folder of the shared jar cache (see
+// SharedCacheDir) and downloaded once; an unpinned URL is downloaded on
+// every run. Jars installed by an earlier run and no longer listed are
+// removed.
+func InstallMods(ctx context.Context, client *modrinth.Client, serverDir, mcVersion string, mods []Mod) error {
+	dir := filepath.Join(serverDir, ModDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", ModDir, err)
+	}
+	previous := readModList(dir)
+
+	cacheDir := SharedCacheDir()
+	installed := make(map[string]Mod, len(mods))
+	var names []string
+	for _, m := range mods {
+		f, err := resolveMod(ctx, client, mcVersion, m)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", m, err)
+		}
+		if other, ok := installed[f.name]; ok {
+			return fmt.Errorf("%s and %s are both %s", other, m, f.name)
+		}
+		installed[f.name] = m
+		names = append(names, f.name)
+		if err := installMod(ctx, f, filepath.Join(dir, f.name), cacheDir); err != nil {
+			return fmt.Errorf("installing %s: %w", m, err)
+		}
+	}
+
+	for _, name := range previous {
+		if _, ok := installed[name]; !ok {
+			fmt.Printf("  🗑  removing %s, no longer listed in mods\n", filepath.Join(ModDir, name))
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return writeModList(dir, names)
+}
+
+// installMod places the jar of f at dest, through the shared cache when its
+// checksum is known.
+func installMod(ctx context.Context, f modFile, dest, cacheDir string) error {
+	key := f.sha256
+	if key == "" {
+		key = f.sha512
+	}
+	if key == "" || cacheDir == "" {
+		fmt.Printf("  ⬇️  %s → %s\n", f.mod, filepath.Join(ModDir, f.name))
+		sum, err := fetchMod(ctx, f, dest)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			fmt.Fprintf(os.Stderr, "  ⚠️  %s is not pinned; set sha256 = %q to cache it and stop on changes\n", f.mod, sum)
+		}
+		return nil
+	}
+
+	cached := filepath.Join(cacheDir, "mods", key, f.name)
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠️  shared jar cache unavailable (%v); downloading into %s\n", err, ModDir)
+		return installMod(ctx, f, dest, "")
+	}
+	ok, err := modMatches(cached, f)
+	if err != nil {
+		return err
+	}
+	if ok {
+		fmt.Printf("  ✔  %s found in shared cache\n", f.name)
+	} else {
+		fmt.Printf("  ⬇️  %s → %s\n", f.mod, filepath.Join(ModDir, f.name))
+		if _, err := fetchMod(ctx, f, cached); err != nil {
+			return err
+		}
+	}
+	return linkOrCopy(cached, dest)
+}
+
+// fetchMod downloads the jar of f into dest via a .tmp file and rename,
+// refusing to keep it unless it matches the expected checksums. It returns
+// the jar's SHA-256.
+func fetchMod(ctx context.Context, f modFile, dest string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", f.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned status %d for %s", resp.StatusCode, f.url)
+	}
+
+	tmpPath := dest + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("creating temp file: %w", err)
+	}
+	sum256, sum512, err := digests(io.TeeReader(resp.Body, out))
+	out.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("writing %s: %w", f.name, err)
+	}
+	if err := f.verify(sum256, sum512); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, dest); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("renaming temp file: %w", err)
+	}
+	return sum256, nil
+}
+
+// verify checks the digests of a downloaded jar against those expected.
+func (f modFile) verify(sum256, sum512 string) error {
+	if f.sha256 != "" && sum256 != f.sha256 {
+		return fmt.Errorf("checksum mismatch for %s from %s: pinned sha256 %s, got %s", f.name, f.url, f.sha256, sum256)
+	}
+	if f.sha512 != "" && sum512 != f.sha512 {
+		return fmt.Errorf("checksum mismatch for %s from %s: Modrinth lists sha512 %s, got %s", f.name, f.url, f.sha512, sum512)
+	}
+	return nil
+}
+
+// modMatches reports whether the jar at path exists and matches f.
+func modMatches(path string, f modFile) (bool, error) {
+	in, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	sum256, sum512, err := digests(in)
+	if err != nil {
+		return false, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return f.verify(sum256, sum512) == nil, nil
+}
+
+// digests returns the hex SHA-256 and SHA-512 of what r reads.
+func digests(r io.Reader) (sum256, sum512 string, err error) {
+	h256, h512 := sha256.New(), sha512.New()
+	if _, err := io.Copy(io.MultiWriter(h256, h512), r); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h256.Sum(nil)), hex.EncodeToString(h512.Sum(nil)), nil
+}
+
+// readModList returns the jars listed in dir's modListName.
+func readModList(dir string) []string {
+	f, err := os.Open(filepath.Join(dir, modListName))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" && filepath.IsLocal(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func writeModList(dir string, names []string) error {
+	content := strings.Join(names, "\n") + "\n"
+	return os.WriteFile(filepath.Join(dir, modListName), []byte(content), 0o644)
+}
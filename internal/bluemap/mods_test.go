@@ -0,0 +1,112 @@
+package bluemap
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/EfinaServer/bluemap-action/internal/modrinth"
+)
+
+func TestInstallMods(t *testing.T) {
+	jars := map[string]string{
+		"/files/create-6.0.4.jar": "create jar",
+		"/files/create-6.0.6.jar": "newer create jar",
+		"/direct/blocks.jar":      "direct jar",
+	}
+	sum512 := func(s string) string { h := sha512.Sum512([]byte(s)); return hex.EncodeToString(h[:]) }
+	sum256 := func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) }
+
+	var downloads atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := func(number string) map[string]any {
+			file := "/files/create-" + number + ".jar"
+			return map[string]any{
+				"id": "v" + number, "version_number": number,
+				"files": []map[string]any{{
+					"url": srv.URL + file, "filename": "create-" + number + ".jar", "primary": true,
+					"hashes": map[string]string{"sha512": sum512(jars[file])},
+				}},
+			}
+		}
+		switch {
+		case r.URL.Path == "/project/create/version/6.0.4":
+			json.NewEncoder(w).Encode(version("6.0.4"))
+		case r.URL.Path == "/project/create/version":
+			if r.URL.Query().Get("game_versions") != `["1.21.1"]` || r.URL.Query().Get("loaders") != `["neoforge"]` {
+				t.Errorf("unexpected filters %q", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode([]any{version("6.0.6"), version("6.0.4")})
+		case jars[r.URL.Path] != "":
+			downloads.Add(1)
+			w.Write([]byte(jars[r.URL.Path]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := modrinth.NewClient("bluemap-action/test")
+	client.BaseURL = srv.URL
+	t.Setenv("BLUEMAP_ACTION_CACHE_DIR", t.TempDir())
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, ModDir, name))
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	mods := []Mod{
+		{Modrinth: "create", Version: "6.0.4"},
+		{URL: srv.URL + "/direct/blocks.jar", SHA256: sum256("direct jar")},
+	}
+	if err := InstallMods(ctx, client, dir, "1.21.1", mods); err != nil {
+		t.Fatal(err)
+	}
+	if read("create-6.0.4.jar") != "create jar" || read("blocks.jar") != "direct jar" {
+		t.Fatalf("installed jars: %q, %q", read("create-6.0.4.jar"), read("blocks.jar"))
+	}
+
+	// Both jars have a known checksum, so a second run uses the cache.
+	if err := InstallMods(ctx, client, dir, "1.21.1", mods); err != nil {
+		t.Fatal(err)
+	}
+	if n := downloads.Load(); n != 2 {
+		t.Errorf("downloads after a cached run = %d, want 2", n)
+	}
+
+	// Moving to the newest version replaces the old jar.
+	mods[0] = Mod{Modrinth: "create", Loader: "neoforge"}
+	if err := InstallMods(ctx, client, dir, "1.21.1", mods); err != nil {
+		t.Fatal(err)
+	}
+	if read("create-6.0.6.jar") != "newer create jar" {
+		t.Errorf("newest version not installed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ModDir, "create-6.0.4.jar")); !os.IsNotExist(err) {
+		t.Errorf("replaced jar still installed: %v", err)
+	}
+
+	// A pinned checksum that does not match stops the install.
+	pinned := []Mod{{URL: srv.URL + "/direct/blocks.jar", SHA256: strings.Repeat("0", 64)}}
+	if err := InstallMods(ctx, client, dir, "1.21.1", pinned); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("pinned mismatch: err = %v", err)
+	}
+
+	if _, err := CheckMods(ctx, client, "1.21.1", []Mod{{Modrinth: "missing"}}); err == nil {
+		t.Error("CheckMods: expected an error for an unknown project")
+	}
+}
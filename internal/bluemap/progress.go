@@ -0,0 +1,113 @@
+package bluemap
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// progressRe matches the progress BlueMap CLI logs while rendering, e.g.
+	// "[INFO] Update map 'overworld': 42.17% (ETA: 00:12:05)", capturing the
+	// percentage and, when printed, the ETA.
+	progressRe = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?) ?%(?:.*?\bET[AR]:? *([0-9][0-9hms: ]*[0-9hms]))?`)
+	// taskMapRe matches the map named by a line such as "Updating map
+	// 'overworld'" or "Map: overworld", but not "Start updating 3 maps".
+	taskMapRe = regexp.MustCompile(`[Mm]ap(?: '([A-Za-z0-9_-]+)'|: '?([A-Za-z0-9_-]+))`)
+	// taskRe matches lines that start or report work on a map, as opposed to
+	// the lines naming each map while the config is loaded.
+	taskRe = regexp.MustCompile(`(?i)render|updat|purg|%`)
+)
+
+// MapTiming is how long the render spent on one map.
+type MapTiming struct {
+	Map      string
+	Duration time.Duration
+}
+
+// renderProgress follows the map BlueMap is rendering and its progress, for
+// compact status lines in place of the raw output. A map's time runs from
+// the first line reporting work on it to the first line about the next map,
+// or the end of the render.
+type renderProgress struct {
+	start   time.Time
+	notice  func(string) // receives a line each time a map is finished
+	current string       // map being rendered; empty until the first is seen
+	since   time.Time    // when work on current was first seen
+	percent string       // last percentage reported for current
+	eta     string
+	maps    []MapTiming
+}
+
+// observe inspects a line of output printed at now.
+func (p *renderProgress) observe(line string, now time.Time) {
+	if m := taskMapRe.FindStringSubmatch(line); m != nil && taskRe.MatchString(line) {
+		p.switchTo(m[1]+m[2], now)
+	}
+	if m := progressRe.FindStringSubmatch(line); m != nil && p.current != "" {
+		p.percent, p.eta = m[1], strings.TrimSpace(m[2])
+	}
+}
+
+func (p *renderProgress) switchTo(name string, now time.Time) {
+	if name == p.current {
+		return
+	}
+	p.finishMap(now)
+	p.current, p.since, p.percent, p.eta = name, now, "", ""
+}
+
+// finishMap adds the time spent on the current map, which may have been
+// rendered in more than one stretch.
+func (p *renderProgress) finishMap(now time.Time) {
+	if p.current == "" {
+		return
+	}
+	d := now.Sub(p.since)
+	i := slices.IndexFunc(p.maps, func(t MapTiming) bool { return t.Map == p.current })
+	if i < 0 {
+		p.maps = append(p.maps, MapTiming{Map: p.current})
+		i = len(p.maps) - 1
+	}
+	p.maps[i].Duration += d
+	if p.notice != nil {
+		p.notice(fmt.Sprintf("rendered map %s in %s", p.current, d.Round(time.Second)))
+	}
+}
+
+// line returns the status line at now: the time so far, the current map,
+// its progress and ETA, and how many maps are done.
+func (p *renderProgress) line(now time.Time) string {
+	status := fmt.Sprintf("  → render %s: ", now.Sub(p.start).Round(time.Second))
+	if p.current == "" {
+		return status + "waiting for BlueMap to start on a map"
+	}
+	status += "map " + p.current
+	if p.percent != "" {
+		status += " " + p.percent + "%"
+	}
+	if p.eta != "" {
+		status += ", ETA " + p.eta
+	}
+	if done := len(p.maps); done > 0 {
+		status += fmt.Sprintf(" (%d %s done)", done, plural(done, "map", "maps"))
+	}
+	return status
+}
+
+// finish ends the current map at now and returns the time spent on each map,
+// in the order they were first rendered.
+func (p *renderProgress) finish(now time.Time) []MapTiming {
+	p.finishMap(now)
+	p.current = ""
+	return slices.Clone(p.maps)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,57 @@
+package bluemap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenderProgress(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var notices []string
+	p := renderProgress{start: start, notice: func(msg string) { notices = append(notices, msg) }}
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	// Loading the config names every map, and the start line counts maps;
+	// neither is work on a map.
+	p.observe("[INFO] Loading map 'overworld'...", at(0))
+	p.observe("[INFO] Start updating 2 maps (1234 regions, ~456789 chunks)", at(time.Second))
+	if got, want := p.line(at(time.Minute)), "  → render 1m0s: waiting for BlueMap to start on a map"; got != want {
+		t.Errorf("line before any map = %q, want %q", got, want)
+	}
+
+	p.observe("[INFO] Update map 'overworld': 12.5% (ETA: 00:40:00)", at(2*time.Minute))
+	p.observe("[INFO] Update map 'overworld': 42.17% (ETA: 00:12:05)", at(20*time.Minute))
+	if got, want := p.line(at(25*time.Minute)), "  → render 25m0s: map overworld 42.17%, ETA 00:12:05"; got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+
+	p.observe("[INFO] Update map 'the_nether': 3%", at(32*time.Minute))
+	if got, want := p.line(at(33*time.Minute)), "  → render 33m0s: map the_nether 3% (1 map done)"; got != want {
+		t.Errorf("line after a map = %q, want %q", got, want)
+	}
+
+	got := p.finish(at(42 * time.Minute))
+	want := []MapTiming{{"overworld", 30 * time.Minute}, {"the_nether", 10 * time.Minute}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("finish = %v, want %v", got, want)
+	}
+	wantNotices := []string{"rendered map overworld in 30m0s", "rendered map the_nether in 10m0s"}
+	if !reflect.DeepEqual(notices, wantNotices) {
+		t.Errorf("notices = %q, want %q", notices, wantNotices)
+	}
+}
+
+func TestRenderProgressRevisit(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := renderProgress{start: start}
+	p.observe("[INFO] Rendering map 'a'", start)
+	p.observe("[INFO] Rendering map 'b'", start.Add(time.Minute))
+	p.observe("[INFO] Rendering map 'a'", start.Add(3*time.Minute))
+
+	got := p.finish(start.Add(4 * time.Minute))
+	want := []MapTiming{{"a", 2 * time.Minute}, {"b", 2 * time.Minute}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("finish = %v, want %v", got, want)
+	}
+}
@@ -1,34 +1,183 @@
 package bluemap
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
 )
 
+// RenderOptions configures optional behavior of Render.
+type RenderOptions struct {
+	LogPath   string   // if set, BlueMap CLI stdout and stderr are also written here
+	JavaArgs  []string // extra JVM flags placed before -jar (e.g. GC tuning)
+	MaxMemory string   // JVM max heap (e.g. "6G"); empty = derived from system memory
+	Maps      []string // map IDs passed to -m; empty = render all maps
+
+	StallTimeout time.Duration // kill the render after this long without output; 0 = disabled
+	Timeout      time.Duration // kill the render after this total runtime; 0 = disabled
+
+	// ProgressInterval, if set, replaces the raw BlueMap output on the
+	// terminal with a line every interval naming the map being rendered,
+	// its progress and ETA. The raw output still goes to LogPath.
+	ProgressInterval time.Duration
+
+	// Notice, if set, receives a line each time a map is finished, for CI
+	// annotations.
+	Notice func(message string)
+
+	// MapTimings, if set, receives how long each map took after a
+	// successful render, in the order the maps were rendered.
+	MapTimings *[]MapTiming
+}
+
+// RenderCommand returns the full command line Render executes.
+func RenderCommand(jarPath, mcVersion string, opts RenderOptions) []string {
+	args := []string{"java"}
+	// Proxy properties come first so -D flags in java_args override them.
+	args = append(args, proxy.JVMArgs()...)
+	args = append(args, JVMArgs(opts.JavaArgs, opts.MaxMemory)...)
+	args = append(args, "-jar", jarPath, "-v", mcVersion, "-r")
+	if len(opts.Maps) > 0 {
+		args = append(args, "-m", strings.Join(opts.Maps, ","))
+	}
+	return args
+}
+
 // Render executes the BlueMap CLI jar in render mode.
-// It runs: java -jar <jarPath> -v <mcVersion> -r
+// It runs: java [jvm flags] -jar <jarPath> -v <mcVersion> -r [-m <maps>]
 // The working directory is set to serverDir so BlueMap picks up the config/ directory.
-// Stdout and stderr are streamed directly to the terminal so progress is visible.
+// Stdout and stderr are streamed directly to the terminal so progress is visible,
+// and additionally captured to opts.LogPath when set. With opts.ProgressInterval
+// the terminal gets a compact status line every interval instead, and the
+// excerpt of the output that explains a failure.
+//
+// A watchdog observes the output: if nothing is printed for opts.StallTimeout,
+// or the render runs longer than opts.Timeout, the java process is killed and
+// the error names the last map and region seen in the output.
+// Cancelling ctx kills the java process.
+// A failed or killed render returns a *RenderError with the part of the
+// output that explains the failure.
 // It returns the wall-clock duration of the render process.
-func Render(jarPath, serverDir, mcVersion string) (time.Duration, error) {
-	cmd := exec.Command("java", "-jar", jarPath, "-v", mcVersion, "-r")
+func Render(ctx context.Context, jarPath, serverDir, mcVersion string, opts RenderOptions) (time.Duration, error) {
+	args := RenderCommand(jarPath, mcVersion, opts)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Dir = serverDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
-	fmt.Printf("  executing: java -jar %s -v %s -r\n", jarPath, mcVersion)
+	wd := newWatchdog()
+	stdout := []io.Writer{os.Stdout, wd}
+	stderr := []io.Writer{os.Stderr, wd}
+	quiet := opts.ProgressInterval > 0
+	if quiet {
+		stdout, stderr = []io.Writer{wd}, []io.Writer{wd}
+	}
+
+	if opts.LogPath != "" {
+		logFile, err := os.Create(opts.LogPath)
+		if err != nil {
+			return 0, fmt.Errorf("creating render log %s: %w", opts.LogPath, err)
+		}
+		defer logFile.Close()
+		stdout = append(stdout, logFile)
+		stderr = append(stderr, logFile)
+	}
+	cmd.Stdout = io.MultiWriter(stdout...)
+	cmd.Stderr = io.MultiWriter(stderr...)
+
+	fmt.Printf("  executing: %s\n", strings.Join(args, " "))
 	fmt.Printf("  working dir: %s\n", serverDir)
+	if opts.StallTimeout > 0 || opts.Timeout > 0 {
+		fmt.Printf("  watchdog: stall timeout %s, hard timeout %s\n", fmtLimit(opts.StallTimeout), fmtLimit(opts.Timeout))
+	}
+	if quiet {
+		full := "not kept"
+		if opts.LogPath != "" {
+			full = "in " + opts.LogPath
+		}
+		fmt.Printf("  progress: a status line every %s; full output %s\n", opts.ProgressInterval, full)
+	}
 	fmt.Println()
 
 	start := time.Now()
-	if err := cmd.Run(); err != nil {
-		return time.Since(start), fmt.Errorf("BlueMap render failed: %w", err)
+	wd.progress = renderProgress{start: start, notice: opts.Notice}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("starting BlueMap render: %w", err)
 	}
+
+	var (
+		killMu     sync.Mutex
+		killReason string
+	)
+	done := make(chan struct{})
+	go wd.monitor(start, opts.StallTimeout, opts.Timeout, func(reason string) {
+		killMu.Lock()
+		killReason = reason
+		killMu.Unlock()
+		cmd.Process.Kill()
+	}, done)
+	if quiet {
+		go func() {
+			ticker := time.NewTicker(opts.ProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-ticker.C:
+					fmt.Println(wd.progressLine(now))
+				}
+			}
+		}()
+	}
+
+	err := cmd.Wait()
+	close(done)
 	elapsed := time.Since(start)
 
+	killMu.Lock()
+	reason := killReason
+	killMu.Unlock()
+	if reason != "" {
+		return elapsed, failed(wd.renderError(fmt.Errorf("BlueMap render killed by watchdog: %s\n%s", reason, wd.report())), quiet)
+	}
+	if ctx.Err() != nil {
+		return elapsed, fmt.Errorf("BlueMap render cancelled: %w", ctx.Err())
+	}
+	if err != nil {
+		return elapsed, failed(wd.renderError(fmt.Errorf("BlueMap render failed: %w", err)), quiet)
+	}
+
+	timings := wd.finishProgress(time.Now())
+	if opts.MapTimings != nil {
+		*opts.MapTimings = timings
+	}
 	fmt.Println()
 	fmt.Printf("  ✔  BlueMap render completed in %s\n", elapsed.Round(time.Second))
+	for _, t := range timings {
+		fmt.Printf("  → map %s: %s\n", t.Map, t.Duration.Round(time.Second))
+	}
 	return elapsed, nil
 }
+
+// failed returns err, first printing its excerpt when the raw output was
+// not shown.
+func failed(err *RenderError, quiet bool) error {
+	if quiet && len(err.Excerpt) > 0 {
+		fmt.Printf("\n  BlueMap output before the failure:\n    %s\n", strings.Join(err.Excerpt, "\n    "))
+	}
+	return err
+}
+
+// fmtLimit formats a watchdog limit, showing "off" for zero.
+func fmtLimit(d time.Duration) string {
+	if d <= 0 {
+		return "off"
+	}
+	return d.String()
+}
@@ -0,0 +1,171 @@
+package bluemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ResourcePackDir is the directory, relative to the server directory, that
+// BlueMap loads resource packs from.
+const ResourcePackDir = "config/packs"
+
+// isPackURL reports whether a resourcepacks entry is a URL rather than a
+// local path.
+func isPackURL(entry string) bool {
+	return strings.Contains(entry, "://")
+}
+
+// ResourcePackName returns the name a resourcepacks entry is installed under
+// in ResourcePackDir: the last element of the URL path or local path. An
+// entry must be an http(s) URL or a path relative to the server directory.
+func ResourcePackName(entry string) (string, error) {
+	if isPackURL(entry) {
+		name, ok := urlFileName(entry)
+		if !ok {
+			return "", fmt.Errorf("resource pack URL must be http(s) and end in a file name, got %q", entry)
+		}
+		return name, nil
+	}
+	if entry == "" || filepath.IsAbs(entry) || !filepath.IsLocal(entry) {
+		return "", fmt.Errorf("resource pack path must be relative to the server directory and stay inside it, got %q", entry)
+	}
+	return filepath.Base(entry), nil
+}
+
+// urlFileName returns the last element of the path of an http(s) URL, or
+// false when rawURL is not one or its path names no file.
+func urlFileName(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "", false
+	}
+	return name, true
+}
+
+// CheckResourcePacks checks that the local resource packs in packs exist,
+// without downloading the URLs, for validate. It returns how many packs
+// are listed.
+func CheckResourcePacks(serverDir string, packs []string) (int, error) {
+	var errs []error
+	for _, entry := range packs {
+		if isPackURL(entry) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(serverDir, entry)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(packs), errors.Join(errs...)
+}
+
+// InstallResourcePacks puts the resource packs listed in packs into
+// ResourcePackDir under serverDir, so BlueMap renders the blocks they add:
+// URLs are downloaded and local paths, files or folders, are copied. Packs
+// already in ResourcePackDir are left as they are, and an installed pack
+// of the same name is replaced.
+func InstallResourcePacks(ctx context.Context, serverDir string, packs []string) error {
+	packDir := filepath.Join(serverDir, ResourcePackDir)
+	if err := os.MkdirAll(packDir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", ResourcePackDir, err)
+	}
+	for _, entry := range packs {
+		name, err := ResourcePackName(entry)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(packDir, name)
+		if isPackURL(entry) {
+			fmt.Printf("  ⬇️  %s → %s\n", entry, filepath.Join(ResourcePackDir, name))
+			if err := fetchPack(ctx, entry, dest); err != nil {
+				return fmt.Errorf("downloading resource pack %s: %w", entry, err)
+			}
+			continue
+		}
+
+		src := filepath.Join(serverDir, entry)
+		if filepath.Clean(src) == dest {
+			fmt.Printf("  ✔  %s already in place\n", entry)
+			continue
+		}
+		fmt.Printf("  📦  %s → %s\n", entry, filepath.Join(ResourcePackDir, name))
+		if err := copyPack(src, dest); err != nil {
+			return fmt.Errorf("copying resource pack %s: %w", entry, err)
+		}
+	}
+	return nil
+}
+
+// fetchPack downloads url into dest via a .tmp file and rename.
+func fetchPack(ctx context.Context, url, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	return writeFileAtomic(dest, resp.Body)
+}
+
+// copyPack copies the file or folder src to dest, replacing dest.
+func copyPack(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFileAtomic(dest, f)
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	return os.CopyFS(dest, os.DirFS(src))
+}
+
+// writeFileAtomic writes r to path via a .tmp file and rename, replacing
+// a file or folder already at path.
+func writeFileAtomic(path string, r io.Reader) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
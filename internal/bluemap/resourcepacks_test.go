@@ -0,0 +1,81 @@
+package bluemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallResourcePacks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/packs/remote.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("remote pack"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("packs/local.zip", "local pack")
+	write("packs/folder/pack.mcmeta", "{}")
+	write("config/packs/kept.zip", "kept")
+	// A stale copy of the folder pack from an earlier run is replaced.
+	write("config/packs/folder/stale.png", "stale")
+
+	packs := []string{srv.URL + "/packs/remote.zip", "packs/local.zip", "packs/folder", "config/packs/kept.zip"}
+	if err := InstallResourcePacks(context.Background(), dir, packs); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]string{
+		"remote.zip":         "remote pack",
+		"local.zip":          "local pack",
+		"folder/pack.mcmeta": "{}",
+		"kept.zip":           "kept",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, ResourcePackDir, rel))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ResourcePackDir, "folder/stale.png")); !os.IsNotExist(err) {
+		t.Errorf("stale file in replaced folder pack: %v", err)
+	}
+
+	if err := InstallResourcePacks(context.Background(), dir, []string{srv.URL + "/packs/missing.zip"}); err == nil {
+		t.Error("missing remote pack: expected an error")
+	}
+	if _, err := CheckResourcePacks(dir, []string{"packs/missing.zip"}); err == nil {
+		t.Error("CheckResourcePacks: expected an error for a missing local pack")
+	}
+}
+
+func TestResourcePackName(t *testing.T) {
+	for entry, want := range map[string]string{
+		"https://cdn.example.com/packs/Faithful%2032x.zip?v=2": "Faithful 32x.zip",
+		"packs/custom":  "custom",
+		"packs/a.zip":   "a.zip",
+		"./packs/b.jar": "b.jar",
+	} {
+		if got, err := ResourcePackName(entry); err != nil || got != want {
+			t.Errorf("ResourcePackName(%q) = %q, %v; want %q", entry, got, err, want)
+		}
+	}
+	for _, entry := range []string{"", "/abs/pack.zip", "../pack.zip", "ftp://example.com/pack.zip", "https://example.com/"} {
+		if _, err := ResourcePackName(entry); err == nil {
+			t.Errorf("ResourcePackName(%q): expected an error", entry)
+		}
+	}
+}
@@ -1,78 +1,237 @@
 package bluemap
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/BurntSushi/toml"
 )
 
 // scriptsDir is the conventional subdirectory name scanned for custom scripts.
 const scriptsDir = "scripts"
 
+// ScriptsManifestName is the optional file in scripts/ that sets the order,
+// environment and failure handling of the scripts.
+const ScriptsManifestName = "scripts.toml"
+
 // interpreters maps file extensions to the interpreter used to execute them.
+// Other files run directly when they are executable and start with a shebang.
 var interpreters = map[string]string{
 	".py": "python3",
 	".sh": "sh",
+	".js": "node",
+	".rb": "ruby",
+}
+
+// scriptsManifest is the content of scripts.toml.
+type scriptsManifest struct {
+	Scripts []scriptConfig `toml:"script"`
+}
+
+// scriptConfig is one [[script]] entry of scripts.toml.
+type scriptConfig struct {
+	File  string            `toml:"file"`  // File name in scripts/
+	Env   map[string]string `toml:"env"`   // Variables added to the environment of the script
+	Fatal *bool             `toml:"fatal"` // nil = true (a failure stops the build)
+}
+
+// script is a script to run and how.
+type script struct {
+	name    string
+	command []string // interpreter and script path, or the script path alone
+	env     []string
+	fatal   bool
 }
 
 // RunScripts discovers and executes custom scripts from the scripts/
-// subdirectory of serverDir. Scripts are executed in alphabetical order with
-// the working directory set to serverDir. If no scripts/ directory exists, the
-// step is silently skipped.
-func RunScripts(serverDir string) error {
+// subdirectory of serverDir, with the working directory set to serverDir.
+// Without scripts.toml every supported script runs in alphabetical order and
+// any failure is fatal; with it, only the listed scripts run, in the listed
+// order. Failures of scripts with fatal = false are returned as the first
+// value and do not stop the run. If no scripts/ directory exists, the step is
+// silently skipped. Cancelling ctx kills the running script.
+func RunScripts(ctx context.Context, serverDir string) ([]error, error) {
+	scripts, found, err := loadScripts(serverDir)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		fmt.Printf("  no %s/ directory found; skipping\n", scriptsDir)
+		return nil, nil
+	}
+	if len(scripts) == 0 {
+		fmt.Printf("  no scripts found in %s/\n", scriptsDir)
+		return nil, nil
+	}
+
+	var failures []error
+	for _, s := range scripts {
+		fmt.Printf("  executing: %s\n", strings.Join(s.command, " "))
+		fmt.Printf("  working dir: %s\n", serverDir)
+		fmt.Println()
+
+		cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+		cmd.Dir = serverDir
+		cmd.Env = append(os.Environ(), s.env...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			err = fmt.Errorf("%s failed: %w", filepath.Join(scriptsDir, s.name), err)
+			if s.fatal || ctx.Err() != nil {
+				return failures, err
+			}
+			failures = append(failures, err)
+		}
+	}
+
+	return failures, nil
+}
+
+// CheckScripts reads scripts/ and scripts.toml of serverDir without running
+// anything, for validate, and returns the number of scripts a render would
+// run. Interpreters missing from PATH are reported as errors.
+func CheckScripts(serverDir string) (int, error) {
+	scripts, _, err := loadScripts(serverDir)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range scripts {
+		if len(s.command) > 1 {
+			if _, err := exec.LookPath(s.command[0]); err != nil {
+				return 0, fmt.Errorf("%s needs %s, which is not installed", s.name, s.command[0])
+			}
+		}
+	}
+	return len(scripts), nil
+}
+
+// loadScripts returns the scripts to run from the scripts/ directory of
+// serverDir, and whether the directory exists.
+func loadScripts(serverDir string) ([]script, bool, error) {
 	dir := filepath.Join(serverDir, scriptsDir)
 
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			fmt.Printf("  no %s/ directory found; skipping\n", scriptsDir)
-			return nil
+			return nil, false, nil
 		}
-		return fmt.Errorf("reading %s: %w", dir, err)
+		return nil, false, fmt.Errorf("reading %s: %w", dir, err)
 	}
 
 	// Collect script files, skip directories.
-	var scripts []string
+	available := make(map[string][]string)
+	var names []string
 	for _, e := range entries {
-		if e.IsDir() {
+		if e.IsDir() || e.Name() == ScriptsManifestName {
 			continue
 		}
-		ext := strings.ToLower(filepath.Ext(e.Name()))
-		if _, ok := interpreters[ext]; !ok {
-			fmt.Fprintf(os.Stderr, "  ⚠  skipping %s (unsupported extension %q)\n", e.Name(), ext)
+		command, err := scriptCommand(dir, e.Name())
+		if err != nil {
+			return nil, true, err
+		}
+		if command == nil {
+			fmt.Fprintf(os.Stderr, "  ⚠  skipping %s (unsupported extension %q and no shebang)\n", e.Name(), filepath.Ext(e.Name()))
 			continue
 		}
-		scripts = append(scripts, e.Name())
+		available[e.Name()] = command
+		names = append(names, e.Name())
 	}
+	sort.Strings(names)
 
-	sort.Strings(scripts)
+	scripts, err := planScripts(dir, names, available)
+	return scripts, true, err
+}
 
-	if len(scripts) == 0 {
-		fmt.Printf("  no scripts found in %s/\n", scriptsDir)
-		return nil
+// planScripts returns the scripts to run: all of names in order, or the ones
+// scripts.toml in dir lists.
+func planScripts(dir string, names []string, available map[string][]string) ([]script, error) {
+	manifestPath := filepath.Join(dir, ScriptsManifestName)
+	var m scriptsManifest
+	md, err := toml.DecodeFile(manifestPath, &m)
+	if errors.Is(err, os.ErrNotExist) {
+		scripts := make([]script, len(names))
+		for i, name := range names {
+			scripts[i] = script{name: name, command: available[name], fatal: true}
+		}
+		return scripts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", manifestPath, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %q", manifestPath, undecoded[0].String())
 	}
 
-	for _, name := range scripts {
-		ext := strings.ToLower(filepath.Ext(name))
-		interpreter := interpreters[ext]
-		scriptPath := filepath.Join(scriptsDir, name)
-
-		fmt.Printf("  executing: %s %s\n", interpreter, scriptPath)
-		fmt.Printf("  working dir: %s\n", serverDir)
-		fmt.Println()
-
-		cmd := exec.Command(interpreter, scriptPath)
-		cmd.Dir = serverDir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	var scripts []script
+	listed := make(map[string]bool)
+	for _, sc := range m.Scripts {
+		command, ok := available[sc.File]
+		switch {
+		case sc.File == "" || filepath.Base(sc.File) != sc.File:
+			return nil, fmt.Errorf("%s: script file %q must be a file name in %s/", manifestPath, sc.File, scriptsDir)
+		case listed[sc.File]:
+			return nil, fmt.Errorf("%s: script %q is listed twice", manifestPath, sc.File)
+		case !ok:
+			return nil, fmt.Errorf("%s: script %q is not a supported script in %s/", manifestPath, sc.File, scriptsDir)
+		}
+		listed[sc.File] = true
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("%s failed: %w", scriptPath, err)
+		s := script{name: sc.File, command: command, fatal: sc.Fatal == nil || *sc.Fatal}
+		keys := make([]string, 0, len(sc.Env))
+		for k := range sc.Env {
+			if k == "" || strings.Contains(k, "=") {
+				return nil, fmt.Errorf("%s: script %q: invalid env name %q", manifestPath, sc.File, k)
+			}
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s.env = append(s.env, k+"="+sc.Env[k])
+		}
+		scripts = append(scripts, s)
+	}
+	for _, name := range names {
+		if !listed[name] {
+			fmt.Printf("  %s is not listed in %s; not running it\n", name, ScriptsManifestName)
 		}
 	}
+	return scripts, nil
+}
 
-	return nil
+// scriptCommand returns the command running the script name in dir, or nil
+// when it has no known extension and is not an executable with a shebang.
+func scriptCommand(dir, name string) ([]string, error) {
+	rel := filepath.Join(scriptsDir, name)
+	if interpreter, ok := interpreters[strings.ToLower(filepath.Ext(name))]; ok {
+		return []string{interpreter, rel}, nil
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(f, head); err != nil || !bytes.Equal(head, []byte("#!")) {
+		return nil, nil
+	}
+	// Relative to the working directory, serverDir; "./" keeps exec from
+	// searching PATH.
+	return []string{"." + string(filepath.Separator) + rel}, nil
 }
@@ -0,0 +1,110 @@
+package bluemap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeScript writes a file to the scripts/ directory of dir.
+func writeScript(t *testing.T, dir, name, content string, mode os.FileMode) {
+	t.Helper()
+	p := filepath.Join(dir, scriptsDir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLog(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "log.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunScriptsAlphabetical(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir := t.TempDir()
+	writeScript(t, dir, "b.sh", "echo b >> log.txt\n", 0o644)
+	writeScript(t, dir, "a.sh", "echo a >> log.txt\n", 0o644)
+	writeScript(t, dir, "c", "#!/bin/sh\necho c >> log.txt\n", 0o755)
+	writeScript(t, dir, "notes.txt", "not a script", 0o644)
+
+	failures, err := RunScripts(context.Background(), dir)
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("RunScripts = %v, %v", failures, err)
+	}
+	if got := readLog(t, dir); got != "a\nb\nc\n" {
+		t.Errorf("log = %q, want a, b, c", got)
+	}
+}
+
+func TestRunScriptsManifest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir := t.TempDir()
+	writeScript(t, dir, "a.sh", "echo a >> log.txt\n", 0o644)
+	writeScript(t, dir, "b.sh", "echo \"b $GREETING\" >> log.txt\nexit 3\n", 0o644)
+	writeScript(t, dir, "c.sh", "echo c >> log.txt\n", 0o644)
+	writeScript(t, dir, ScriptsManifestName, `
+[[script]]
+file = "b.sh"
+env = { GREETING = "hello" }
+fatal = false
+
+[[script]]
+file = "a.sh"
+`, 0o644)
+
+	failures, err := RunScripts(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("RunScripts: %v", err)
+	}
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "b.sh") {
+		t.Errorf("failures = %v, want b.sh", failures)
+	}
+	if got := readLog(t, dir); got != "b hello\na\n" {
+		t.Errorf("log = %q, want b then a, and c not run", got)
+	}
+
+	// A fatal failure stops the run.
+	writeScript(t, dir, ScriptsManifestName, "[[script]]\nfile = \"b.sh\"\n\n[[script]]\nfile = \"c.sh\"\n", 0o644)
+	if _, err := RunScripts(context.Background(), dir); err == nil {
+		t.Error("RunScripts ignored a fatal failure")
+	}
+	if got := readLog(t, dir); strings.Contains(got, "c") {
+		t.Errorf("log = %q, c.sh ran after a fatal failure", got)
+	}
+}
+
+func TestCheckScriptsManifestErrors(t *testing.T) {
+	for _, manifest := range []string{
+		"[[script]]\nfile = \"missing.sh\"\n",
+		"[[script]]\nfile = \"../a.sh\"\n",
+		"[[script]]\nfile = \"a.sh\"\n[[script]]\nfile = \"a.sh\"\n",
+		"[[script]]\nfile = \"a.sh\"\nfatl = false\n",
+		"[[script]]\nfile = \"a.sh\"\nenv = { \"A=B\" = \"x\" }\n",
+	} {
+		dir := t.TempDir()
+		writeScript(t, dir, "a.sh", "true\n", 0o644)
+		writeScript(t, dir, ScriptsManifestName, manifest, 0o644)
+		if _, err := CheckScripts(dir); err == nil {
+			t.Errorf("CheckScripts accepted %q", manifest)
+		}
+	}
+
+	if n, err := CheckScripts(t.TempDir()); n != 0 || err != nil {
+		t.Errorf("CheckScripts without scripts/ = %d, %v", n, err)
+	}
+}
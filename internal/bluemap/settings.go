@@ -0,0 +1,172 @@
+package bluemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Map views the webapp can offer, as listed in Settings.Views.
+const (
+	ViewPerspective = "perspective"
+	ViewFlat        = "flat"
+	ViewFreeFlight  = "free-flight"
+)
+
+// Views are the accepted Settings.Views.
+var Views = []string{ViewPerspective, ViewFlat, ViewFreeFlight}
+
+// viewKeys are the per-map settings.json keys enabling each view.
+var viewKeys = map[string]string{
+	ViewPerspective: "perspectiveView",
+	ViewFlat:        "flatView",
+	ViewFreeFlight:  "freeFlightView",
+}
+
+// Settings are changes to the webapp settings BlueMap generates in
+// web/settings.json and web/maps/<id>/settings.json. Zero fields keep what
+// BlueMap wrote.
+type Settings struct {
+	DefaultMap string                // map the webapp opens first
+	StartPos   map[string][2]float64 // x and z the camera starts at, per map
+	FlatView   *bool                 // start in the flat view instead of the perspective one
+	FreeFlight *bool                 // offer the free-flight controls
+	UseCookies *bool                 // remember the visitor's webapp settings
+	MinZoom    float64               // closest camera distance
+	MaxZoom    float64               // farthest camera distance
+	Views      []string              // views every map offers; of Views
+	HiddenMaps []string              // maps left out of the map list
+}
+
+// Enabled reports whether s changes anything.
+func (s Settings) Enabled() bool {
+	return s.DefaultMap != "" || len(s.StartPos) > 0 || s.FlatView != nil || s.FreeFlight != nil ||
+		s.UseCookies != nil || s.MinZoom != 0 || s.MaxZoom != 0 || len(s.Views) > 0 || len(s.HiddenMaps) > 0
+}
+
+// PatchSettings applies s to the settings.json files of the webapp in
+// serverDir/web, keeping every other key. The default map is moved to the
+// front of the map list and sorted before the others, which is how the
+// webapp picks the map to open.
+func PatchSettings(serverDir string, s Settings) error {
+	webDir := filepath.Join(serverDir, "web")
+	settings, err := readSettings(filepath.Join(webDir, "settings.json"))
+	if err != nil {
+		return err
+	}
+	var ids []string
+	if raw, ok := settings["maps"].([]any); ok {
+		for _, v := range raw {
+			if id, ok := v.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	for key, v := range map[string]*bool{"defaultToFlatView": s.FlatView, "enableFreeFlight": s.FreeFlight, "useCookies": s.UseCookies} {
+		if v != nil {
+			settings[key] = *v
+		}
+	}
+	if s.MinZoom != 0 {
+		settings["minZoomDistance"] = s.MinZoom
+	}
+	if s.MaxZoom != 0 {
+		settings["maxZoomDistance"] = s.MaxZoom
+	}
+	if s.DefaultMap != "" {
+		i := slices.Index(ids, s.DefaultMap)
+		if i < 0 {
+			return fmt.Errorf("default map %q is not in web/settings.json", s.DefaultMap)
+		}
+		ids = append([]string{s.DefaultMap}, slices.Delete(ids, i, i+1)...)
+		settings["maps"] = ids
+	}
+	if len(s.HiddenMaps) > 0 {
+		ids = slices.DeleteFunc(ids, func(id string) bool { return slices.Contains(s.HiddenMaps, id) })
+		settings["maps"] = ids
+	}
+	if err := writeSettings(filepath.Join(webDir, "settings.json"), settings); err != nil {
+		return err
+	}
+	if s.DefaultMap == "" && len(s.StartPos) == 0 && len(s.Views) == 0 {
+		return nil
+	}
+
+	maps := make(map[string]map[string]any, len(ids))
+	for _, id := range ids {
+		if m, err := readSettings(filepath.Join(webDir, "maps", id, "settings.json")); err == nil {
+			maps[id] = m
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for id, pos := range s.StartPos {
+		m, ok := maps[id]
+		if !ok {
+			return fmt.Errorf("start position for map %q: web/maps/%s/settings.json not found", id, id)
+		}
+		m["startPos"] = map[string]any{"x": pos[0], "z": pos[1]}
+	}
+	if s.DefaultMap != "" {
+		m, ok := maps[s.DefaultMap]
+		if !ok {
+			return fmt.Errorf("default map %q: web/maps/%s/settings.json not found", s.DefaultMap, s.DefaultMap)
+		}
+		// The webapp sorts the maps by "sorting" and opens the first one.
+		sorting := int64(0)
+		for id, other := range maps {
+			if n, ok := other["sorting"].(json.Number); ok && id != s.DefaultMap {
+				if v, err := n.Int64(); err == nil && v-1 < sorting {
+					sorting = v - 1
+				}
+			}
+		}
+		m["sorting"] = sorting
+	}
+	if len(s.Views) > 0 {
+		for _, m := range maps {
+			for _, view := range Views {
+				m[viewKeys[view]] = slices.Contains(s.Views, view)
+			}
+		}
+	}
+	for id, m := range maps {
+		if err := writeSettings(filepath.Join(webDir, "maps", id, "settings.json"), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSettings decodes a settings.json file, keeping numbers as written.
+func readSettings(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var settings map[string]any
+	if err := dec.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	if settings == nil {
+		return nil, fmt.Errorf("decoding %s: not a JSON object", path)
+	}
+	return settings, nil
+}
+
+func writeSettings(path string, settings map[string]any) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
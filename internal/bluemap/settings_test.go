@@ -0,0 +1,89 @@
+package bluemap
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPatchSettings(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "web")
+	for rel, content := range map[string]string{
+		"settings.json":             `{"version":"5.7","useCookies":true,"maxZoomDistance":100000,"maps":["world","nether","end"],"scripts":[]}`,
+		"maps/world/settings.json":  `{"name":"World","sorting":0,"startPos":{"x":0,"z":0},"flatView":true}`,
+		"maps/nether/settings.json": `{"name":"Nether","sorting":1,"startPos":{"x":0,"z":0}}`,
+		"maps/end/settings.json":    `{"name":"End","sorting":2,"startPos":{"x":0,"z":0}}`,
+	} {
+		path := filepath.Join(web, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(content), 0o644)
+	}
+
+	flat, cookies := true, false
+	err := PatchSettings(dir, Settings{
+		DefaultMap: "end",
+		StartPos:   map[string][2]float64{"end": {100, -50.5}},
+		FlatView:   &flat,
+		UseCookies: &cookies,
+		MaxZoom:    5000,
+		Views:      []string{ViewPerspective, ViewFlat},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(rel string) map[string]any {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(web, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	want := map[string]any{
+		"version":           "5.7",
+		"useCookies":        false,
+		"defaultToFlatView": true,
+		"maxZoomDistance":   5000.0,
+		"maps":              []any{"end", "world", "nether"},
+		"scripts":           []any{},
+	}
+	if got := read("settings.json"); !reflect.DeepEqual(got, want) {
+		t.Errorf("settings.json = %v, want %v", got, want)
+	}
+
+	end := read("maps/end/settings.json")
+	if got, want := end["startPos"], map[string]any{"x": 100.0, "z": -50.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("end startPos = %v, want %v", got, want)
+	}
+	if end["sorting"] != -1.0 {
+		t.Errorf("end sorting = %v, want -1", end["sorting"])
+	}
+	world := read("maps/world/settings.json")
+	if world["sorting"] != 0.0 || world["name"] != "World" {
+		t.Errorf("world settings changed: %v", world)
+	}
+	for id, m := range map[string]map[string]any{"world": world, "end": end} {
+		if m["perspectiveView"] != true || m["flatView"] != true || m["freeFlightView"] != false {
+			t.Errorf("%s views = %v %v %v, want true true false", id, m["perspectiveView"], m["flatView"], m["freeFlightView"])
+		}
+	}
+
+	if err := PatchSettings(dir, Settings{HiddenMaps: []string{"nether"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read("settings.json")["maps"], []any{"end", "world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("maps after hiding nether = %v, want %v", got, want)
+	}
+
+	if err := PatchSettings(dir, Settings{DefaultMap: "overworld"}); err == nil {
+		t.Error("PatchSettings accepted a default map missing from settings.json")
+	}
+}
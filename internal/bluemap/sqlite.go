@@ -0,0 +1,401 @@
+package bluemap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SQLiteDBName is the database in the server directory that BlueMap
+	// renders into with storage = "sqlite". It is kept between runs (the
+	// workflow caches it) so BlueMap only renders what changed.
+	SQLiteDBName = "bluemap.db"
+
+	// SQLiteStorageID is the storage config written to config/storages/ and
+	// set as the storage of every map with storage = "sqlite".
+	SQLiteStorageID = "sqlite"
+)
+
+// sqliteStorageConf is the storage config BlueMap uses for SQLiteDBName.
+// The connection URL is relative to the server directory, BlueMap's working
+// directory.
+const sqliteStorageConf = `# Written by bluemap-action for storage = "sqlite"; changes are overwritten.
+storage-type: sql
+connection-url: "jdbc:sqlite:` + SQLiteDBName + `"
+compression: gzip
+`
+
+// mapStorageRe matches the storage line of a map config.
+var mapStorageRe = regexp.MustCompile(`(?m)^storage:[^\n]*$`)
+
+// ConfigureSQLite writes the SQLiteStorageID storage config and points every
+// map config in config/maps/ at it. It returns the IDs of the maps that used
+// another storage.
+func ConfigureSQLite(serverDir string) ([]string, error) {
+	confPath := filepath.Join(serverDir, "config", "storages", SQLiteStorageID+".conf")
+	if err := os.MkdirAll(filepath.Dir(confPath), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(confPath, []byte(sqliteStorageConf), 0o644); err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(serverDir, "config", "maps", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	want := fmt.Sprintf("storage: %q", SQLiteStorageID)
+	var switched []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		conf := string(data)
+		line := mapStorageRe.FindString(conf)
+		if strings.TrimSpace(line) == want {
+			continue
+		}
+		if line == "" {
+			conf = strings.TrimRight(conf, "\n") + "\n" + want + "\n"
+		} else {
+			conf = mapStorageRe.ReplaceAllLiteralString(conf, want)
+		}
+		if err := os.WriteFile(path, []byte(conf), 0o644); err != nil {
+			return nil, err
+		}
+		switched = append(switched, strings.TrimSuffix(filepath.Base(path), ".conf"))
+	}
+	return switched, nil
+}
+
+// sqliteTables lists BlueMap's SQL tables the export reads.
+var sqliteTables = []string{
+	"bluemap_map", "bluemap_compression",
+	"bluemap_grid_storage", "bluemap_grid_storage_data",
+	"bluemap_item_storage", "bluemap_item_storage_data",
+}
+
+// Queries listing the rows the export writes, one per line with
+// tab-separated columns.
+const (
+	gridListQuery = `SELECT m.map_id, d.map, d.storage, s.key, d.x, d.z, c.key
+FROM bluemap_grid_storage_data d
+JOIN bluemap_map m ON m.id = d.map
+JOIN bluemap_grid_storage s ON s.id = d.storage
+JOIN bluemap_compression c ON c.id = d.compression;`
+	itemListQuery = `SELECT m.map_id, d.map, d.storage, s.key, c.key
+FROM bluemap_item_storage_data d
+JOIN bluemap_map m ON m.id = d.map
+JOIN bluemap_item_storage s ON s.id = d.storage
+JOIN bluemap_compression c ON c.id = d.compression;`
+)
+
+// exportFile is a row of the database written to a file of the web output.
+type exportFile struct {
+	mapID string
+	path  string // relative to the map's folder in web/maps
+	where string // SQL condition selecting the row
+	table string
+}
+
+// ExportSQLite writes the maps rendered into SQLiteDBName to web/maps in the
+// layout of BlueMap's file storage, so the map can be deployed as static
+// files. The folder of each exported map ends up holding exactly the
+// exported files, but files whose content did not change are left in place
+// with their modification time, so deployers comparing times skip them. It
+// needs the sqlite3 command-line shell and returns the number of files
+// exported and how many of them changed.
+func ExportSQLite(ctx context.Context, serverDir string) (exported, changed int, err error) {
+	dbPath := filepath.Join(serverDir, SQLiteDBName)
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, 0, fmt.Errorf("BlueMap wrote no database: %w", err)
+	}
+	tables, err := sqliteQuery(ctx, dbPath, "SELECT name FROM sqlite_master WHERE type = 'table';")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, table := range sqliteTables {
+		if !strings.Contains("\n"+tables, "\n"+table+"\n") {
+			return 0, 0, fmt.Errorf("%s has no %s table; is it a BlueMap 5 SQL storage?", SQLiteDBName, table)
+		}
+	}
+
+	grid, err := sqliteQuery(ctx, dbPath, gridListQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	items, err := sqliteQuery(ctx, dbPath, itemListQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	files, err := exportPlan(grid, items)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Export next to web/ first, on the same file system, then move the
+	// changed files into web/maps.
+	staging, err := os.MkdirTemp(serverDir, ".bluemap-export-")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.RemoveAll(staging)
+	var mapIDs []string
+	var script strings.Builder
+	for _, f := range files {
+		if !slices.Contains(mapIDs, f.mapID) {
+			mapIDs = append(mapIDs, f.mapID)
+		}
+		path := filepath.Join(staging, f.mapID, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return 0, 0, err
+		}
+		fmt.Fprintf(&script, "SELECT writefile(%s, data) FROM %s WHERE %s;\n", sqlQuote(path), f.table, f.where)
+	}
+
+	cmd := exec.CommandContext(ctx, "sqlite3", "-readonly", "-batch", dbPath)
+	cmd.Stdin = strings.NewReader(script.String())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, 0, fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	mapsDir := filepath.Join(serverDir, "web", "maps")
+	for _, id := range mapIDs {
+		n, err := syncExport(filepath.Join(staging, id), filepath.Join(mapsDir, id))
+		if err != nil {
+			return 0, 0, fmt.Errorf("map %s: %w", id, err)
+		}
+		changed += n
+	}
+	return len(files), changed, nil
+}
+
+// syncExport makes the folder dst hold the files exported to src: files
+// missing from src are removed, and files of src that are new or differ
+// from the ones in dst are moved there. It returns the number moved.
+func syncExport(src, dst string) (int, error) {
+	err := filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dst {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dst, path)
+		if _, err := os.Lstat(filepath.Join(src, rel)); errors.Is(err, fs.ErrNotExist) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if same, err := sameContent(path, target); err != nil || same {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		// A folder in dst where src has a file, or the other way round.
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		moved++
+		return os.Rename(path, target)
+	})
+	return moved, err
+}
+
+// sameContent reports whether the files a and b have the same content. A
+// missing or non-regular b is not an error and reports false.
+func sameContent(a, b string) (bool, error) {
+	info, err := os.Lstat(b)
+	if err != nil || !info.Mode().IsRegular() {
+		return false, nil
+	}
+	ainfo, err := os.Stat(a)
+	if err != nil || ainfo.Size() != info.Size() {
+		return false, err
+	}
+	da, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(da, db), nil
+}
+
+// exportPlan maps the rows listed by gridListQuery and itemListQuery to the
+// files BlueMap's file storage would have written. Render state and other
+// rows the web app does not read are left out.
+func exportPlan(grid, items string) ([]exportFile, error) {
+	var files []exportFile
+	err := eachRow(grid, 7, func(col []string) error {
+		mapID, key := col[0], col[3]
+		x, errX := strconv.Atoi(col[4])
+		z, errZ := strconv.Atoi(col[5])
+		if errX != nil || errZ != nil {
+			return fmt.Errorf("bad tile coordinates %q, %q", col[4], col[5])
+		}
+		var path string
+		switch {
+		case key == "bluemap:hires":
+			path = "tiles/0/" + tilePath(x, z) + ".prbm"
+		case strings.HasPrefix(key, "bluemap:lowres/"):
+			lod := strings.TrimPrefix(key, "bluemap:lowres/")
+			if _, err := strconv.Atoi(lod); err != nil {
+				return fmt.Errorf("bad lowres storage %q", key)
+			}
+			path = "tiles/" + lod + "/" + tilePath(x, z) + ".png"
+		default:
+			return nil
+		}
+		ext, err := compressionExt(col[6])
+		if err != nil {
+			return err
+		}
+		files = append(files, exportFile{
+			mapID: mapID,
+			path:  path + ext,
+			where: fmt.Sprintf("map = %s AND storage = %s AND x = %d AND z = %d", col[1], col[2], x, z),
+			table: "bluemap_grid_storage_data",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(items, 5, func(col []string) error {
+		mapID, key := col[0], col[3]
+		var path string
+		switch {
+		case key == "bluemap:settings":
+			path = "settings.json"
+		case key == "bluemap:textures":
+			path = "textures.json"
+		case key == "bluemap:markers":
+			path = "live/markers.json"
+		case key == "bluemap:players":
+			path = "live/players.json"
+		case strings.HasPrefix(key, "bluemap:asset/"):
+			path = "assets/" + strings.TrimPrefix(key, "bluemap:asset/")
+			if !filepath.IsLocal(path) {
+				return fmt.Errorf("bad asset storage %q", key)
+			}
+		default:
+			return nil
+		}
+		ext, err := compressionExt(col[4])
+		if err != nil {
+			return err
+		}
+		files = append(files, exportFile{
+			mapID: mapID,
+			path:  path + ext,
+			where: fmt.Sprintf("map = %s AND storage = %s", col[1], col[2]),
+			table: "bluemap_item_storage_data",
+		})
+		return nil
+	})
+	return files, err
+}
+
+// eachRow calls fn with the columns of each tab-separated line of rows,
+// which must have n columns and a valid map ID and numeric row IDs.
+func eachRow(rows string, n int, fn func(col []string) error) error {
+	scanner := bufio.NewScanner(strings.NewReader(rows))
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+		col := strings.Split(scanner.Text(), "\t")
+		if len(col) != n || !filepath.IsLocal(col[0]) || strings.ContainsAny(col[0], `/\`) {
+			return fmt.Errorf("unexpected row %q", scanner.Text())
+		}
+		for _, id := range col[1:3] {
+			if _, err := strconv.Atoi(id); err != nil {
+				return fmt.Errorf("unexpected row %q", scanner.Text())
+			}
+		}
+		if err := fn(col); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// tilePath returns the path of a tile in BlueMap's file storage: "x<x>z<z>"
+// split into a folder after every digit, the last part being the file
+// name, e.g. "x1/2/z-3" for tile 12,-3.
+func tilePath(x, z int) string {
+	var parts []string
+	part := ""
+	for _, c := range fmt.Sprintf("x%dz%d", x, z) {
+		part += string(c)
+		if c >= '0' && c <= '9' {
+			parts = append(parts, part)
+			part = ""
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// compressionExt returns the file extension BlueMap's file storage gives
+// data compressed with key.
+func compressionExt(key string) (string, error) {
+	switch strings.TrimPrefix(key, "bluemap:") {
+	case "none":
+		return "", nil
+	case "gzip":
+		return ".gz", nil
+	case "deflate":
+		return ".deflate", nil
+	case "zstd":
+		return ".zst", nil
+	}
+	return "", fmt.Errorf("unknown compression %q", key)
+}
+
+// sqliteQuery runs query against the database at dbPath and returns its
+// rows, one per line with tab-separated columns.
+func sqliteQuery(ctx context.Context, dbPath, query string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sqlite3", "-readonly", "-batch", "-noheader", "-separator", "\t", dbPath, query)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,157 @@
+package bluemap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTilePath(t *testing.T) {
+	for _, tt := range []struct {
+		x, z int
+		want string
+	}{
+		{0, 0, "x0/z0"},
+		{12, -3, "x1/2/z-3"},
+		{-5, 10, "x-5/z1/0"},
+	} {
+		if got := tilePath(tt.x, tt.z); got != tt.want {
+			t.Errorf("tilePath(%d, %d) = %q, want %q", tt.x, tt.z, got, tt.want)
+		}
+	}
+}
+
+func TestExportPlan(t *testing.T) {
+	grid := strings.Join([]string{
+		"overworld\t1\t1\tbluemap:hires\t12\t-3\tbluemap:gzip",
+		"overworld\t1\t2\tbluemap:lowres/1\t0\t0\tbluemap:none",
+		"overworld\t1\t3\tbluemap:tile-state\t0\t0\tbluemap:gzip",
+	}, "\n") + "\n"
+	items := strings.Join([]string{
+		"overworld\t1\t4\tbluemap:settings\tbluemap:none",
+		"overworld\t1\t5\tbluemap:textures\tbluemap:gzip",
+		"overworld\t1\t6\tbluemap:asset/marker.svg\tbluemap:none",
+	}, "\n") + "\n"
+
+	files, err := exportPlan(grid, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.mapID+"/"+f.path)
+	}
+	want := []string{
+		"overworld/tiles/0/x1/2/z-3.prbm.gz",
+		"overworld/tiles/1/x0/z0.png",
+		"overworld/settings.json",
+		"overworld/textures.json.gz",
+		"overworld/assets/marker.svg",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+	if got := files[0].where; got != "map = 1 AND storage = 1 AND x = 12 AND z = -3" {
+		t.Errorf("where = %q", got)
+	}
+
+	for _, bad := range []string{
+		"../evil\t1\t1\tbluemap:hires\t0\t0\tbluemap:none\n",
+		"overworld\t1; DROP\t1\tbluemap:hires\t0\t0\tbluemap:none\n",
+		"overworld\t1\t1\tbluemap:hires\t0\t0\tbluemap:brotli\n",
+	} {
+		if _, err := exportPlan(bad, ""); err == nil {
+			t.Errorf("exportPlan(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestConfigureSQLite(t *testing.T) {
+	dir := t.TempDir()
+	mapsDir := filepath.Join(dir, "config", "maps")
+	if err := os.MkdirAll(mapsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	confs := map[string]string{
+		"overworld.conf": "world: \"world\"\nstorage: \"file\"\nname: \"Overworld\"\n",
+		"nether.conf":    "world: \"world_nether\"\n",
+		"end.conf":       "world: \"world_the_end\"\nstorage: \"sqlite\"\n",
+	}
+	for name, conf := range confs {
+		if err := os.WriteFile(filepath.Join(mapsDir, name), []byte(conf), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	switched, err := ConfigureSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nether", "overworld"}; !reflect.DeepEqual(switched, want) {
+		t.Errorf("switched = %q, want %q", switched, want)
+	}
+	got, _ := os.ReadFile(filepath.Join(mapsDir, "overworld.conf"))
+	if want := "world: \"world\"\nstorage: \"sqlite\"\nname: \"Overworld\"\n"; string(got) != want {
+		t.Errorf("overworld.conf = %q, want %q", got, want)
+	}
+	got, _ = os.ReadFile(filepath.Join(mapsDir, "nether.conf"))
+	if want := "world: \"world_nether\"\nstorage: \"sqlite\"\n"; string(got) != want {
+		t.Errorf("nether.conf = %q, want %q", got, want)
+	}
+	storage, err := os.ReadFile(filepath.Join(dir, "config", "storages", "sqlite.conf"))
+	if err != nil || !strings.Contains(string(storage), `connection-url: "jdbc:sqlite:bluemap.db"`) {
+		t.Errorf("sqlite.conf = %q, %v", storage, err)
+	}
+}
+
+func TestSyncExport(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(src, "tiles/0/x0/z0.prbm.gz"), "same")
+	write(filepath.Join(src, "tiles/0/x0/z1.prbm.gz"), "new content")
+	write(filepath.Join(src, "settings.json"), "{}")
+	write(filepath.Join(dst, "tiles/0/x0/z0.prbm.gz"), "same")
+	write(filepath.Join(dst, "tiles/0/x0/z1.prbm.gz"), "old content")
+	write(filepath.Join(dst, "tiles/0/x9/z9.prbm.gz"), "stale")
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dst, "tiles/0/x0/z0.prbm.gz"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := syncExport(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("moved = %d, want 2", moved)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "tiles/0/x0/z0.prbm.gz")); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("unchanged tile was rewritten: %v, %v", info, err)
+	}
+	for rel, want := range map[string]string{"tiles/0/x0/z1.prbm.gz": "new content", "settings.json": "{}"} {
+		if got, err := os.ReadFile(filepath.Join(dst, rel)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "tiles/0/x9/z9.prbm.gz")); !os.IsNotExist(err) {
+		t.Errorf("stale tile kept: %v", err)
+	}
+
+	// The unchanged tile is left in src; a map exported for the first time
+	// gets it too.
+	if moved, err := syncExport(src, filepath.Join(dir, "missing")); err != nil || moved != 1 {
+		t.Errorf("syncExport into a missing folder = %d, %v; want 1", moved, err)
+	}
+}
@@ -0,0 +1,240 @@
+package bluemap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// releasesAPIURL lists BlueMap releases, newest first.
+const releasesAPIURL = "https://api.github.com/repos/BlueMap-Minecraft/BlueMap/releases?per_page=100"
+
+// LockFileName is the file in the server directory that pins a dynamically
+// resolved BlueMap version.
+const LockFileName = "bluemap.lock"
+
+// VersionLock is the content of a bluemap.lock file.
+type VersionLock struct {
+	Spec    string `toml:"spec"`             // bluemap_version as written in config.toml
+	Version string `toml:"version"`          // concrete version it resolved to
+	SHA256  string `toml:"sha256,omitempty"` // verified checksum of the version's CLI jar
+}
+
+type release struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// IsDynamicVersion reports whether spec needs to be resolved at runtime:
+// "latest" or a wildcard range such as "5.x" or "5.*".
+func IsDynamicVersion(spec string) bool {
+	if spec == "latest" {
+		return true
+	}
+	for _, part := range strings.Split(spec, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveVersion turns a bluemap_version spec into a concrete version.
+// Concrete versions are returned unchanged. Dynamic specs are resolved via
+// the GitHub Releases API to the highest stable release that matches and
+// ships a CLI jar.
+//
+// When lock is true, the resolved version is pinned in serverDir/bluemap.lock
+// and reused by later runs as long as the spec in config.toml is unchanged;
+// delete the lock file to pick up a newer release.
+func ResolveVersion(ctx context.Context, serverDir, spec string, lock bool) (string, error) {
+	if !IsDynamicVersion(spec) {
+		return spec, nil
+	}
+
+	lockPath := filepath.Join(serverDir, LockFileName)
+	if lock {
+		var l VersionLock
+		if _, err := toml.DecodeFile(lockPath, &l); err == nil && l.Spec == spec && l.Version != "" {
+			fmt.Printf("  ✔  using BlueMap %s pinned in %s (spec %q)\n", l.Version, LockFileName, spec)
+			return l.Version, nil
+		}
+	}
+
+	releases, err := fetchReleases(ctx)
+	if err != nil {
+		return "", fmt.Errorf("resolving bluemap_version %q: %w", spec, err)
+	}
+
+	version, ok := pickRelease(releases, spec)
+	if !ok {
+		return "", fmt.Errorf("no stable BlueMap release with a CLI jar matches bluemap_version %q", spec)
+	}
+	fmt.Printf("  ✔  resolved bluemap_version %q → %s\n", spec, version)
+
+	if lock {
+		f, err := os.Create(lockPath)
+		if err != nil {
+			return "", fmt.Errorf("writing %s: %w", lockPath, err)
+		}
+		defer f.Close()
+		if err := toml.NewEncoder(f).Encode(VersionLock{Spec: spec, Version: version}); err != nil {
+			return "", fmt.Errorf("writing %s: %w", lockPath, err)
+		}
+		fmt.Printf("  ✔  pinned %s in %s\n", version, LockFileName)
+	}
+
+	return version, nil
+}
+
+// CheckRelease verifies that a BlueMap release matching spec exists and ships
+// a CLI jar, without downloading it or touching bluemap.lock, and returns
+// the concrete version. Dynamic specs are resolved like ResolveVersion does;
+// a concrete version is checked with a HEAD request for its jar.
+func CheckRelease(ctx context.Context, spec string) (string, error) {
+	if IsDynamicVersion(spec) {
+		releases, err := fetchReleases(ctx)
+		if err != nil {
+			return "", fmt.Errorf("resolving bluemap_version %q: %w", spec, err)
+		}
+		version, ok := pickRelease(releases, spec)
+		if !ok {
+			return "", fmt.Errorf("no stable BlueMap release with a CLI jar matches bluemap_version %q", spec)
+		}
+		return version, nil
+	}
+
+	url := DownloadURL(spec)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("executing request to %s: %w", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("BlueMap %s has no release with a CLI jar (%s not found)", spec, CLIJarName(spec))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("checking %s: status %d", url, resp.StatusCode)
+	}
+	return spec, nil
+}
+
+func fetchReleases(ctx context.Context) ([]release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesAPIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	// Authenticated requests get a far higher rate limit on shared runners.
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request to %s: %w", releasesAPIURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, releasesAPIURL, string(body))
+	}
+
+	var releases []release
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("decoding releases: %w", err)
+	}
+	return releases, nil
+}
+
+// pickRelease returns the highest stable release version matching spec that
+// ships a CLI jar.
+func pickRelease(releases []release, spec string) (string, bool) {
+	var best string
+	for _, r := range releases {
+		if r.Draft || r.Prerelease {
+			continue
+		}
+		v := strings.TrimPrefix(r.TagName, "v")
+		if !matchesSpec(v, spec) || !hasAsset(r, CLIJarName(v)) {
+			continue
+		}
+		if best == "" || compareVersions(v, best) > 0 {
+			best = v
+		}
+	}
+	return best, best != ""
+}
+
+func hasAsset(r release, name string) bool {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSpec reports whether version satisfies spec. "latest" matches every
+// version; otherwise each dot-separated spec component must equal the
+// version's, with "x"/"*" matching anything from that point on.
+func matchesSpec(version, spec string) bool {
+	if spec == "latest" {
+		return true
+	}
+	vParts := strings.Split(version, ".")
+	for i, s := range strings.Split(spec, ".") {
+		if s == "x" || s == "X" || s == "*" {
+			return true
+		}
+		if i >= len(vParts) || vParts[i] != s {
+			return false
+		}
+	}
+	return len(vParts) == len(strings.Split(spec, "."))
+}
+
+// compareVersions compares dot-separated numeric versions, returning -1, 0
+// or 1. Non-numeric components compare as 0.
+func compareVersions(a, b string) int {
+	ap, bp := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		var x, y int
+		if i < len(ap) {
+			x, _ = strconv.Atoi(ap[i])
+		}
+		if i < len(bp) {
+			y, _ = strconv.Atoi(bp[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package bluemap
+
+import "testing"
+
+func TestPickRelease(t *testing.T) {
+	mk := func(tag string, pre bool) release {
+		r := release{TagName: tag, Prerelease: pre}
+		r.Assets = append(r.Assets, struct {
+			Name string `json:"name"`
+		}{Name: CLIJarName(tag[1:])})
+		return r
+	}
+	releases := []release{
+		mk("v5.16", false),
+		mk("v5.9", false),
+		mk("v6.0", true),
+		mk("v4.2", false),
+		{TagName: "v5.17"}, // no CLI jar asset
+	}
+
+	tests := []struct {
+		spec string
+		want string
+		ok   bool
+	}{
+		{"latest", "5.16", true},
+		{"5.x", "5.16", true},
+		{"4.*", "4.2", true},
+		{"6.x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := pickRelease(releases, tt.spec)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("pickRelease(%q) = %q, %v; want %q, %v", tt.spec, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsDynamicVersion(t *testing.T) {
+	for spec, want := range map[string]bool{
+		"latest": true,
+		"5.x":    true,
+		"5.*":    true,
+		"5.16":   false,
+	} {
+		if got := IsDynamicVersion(spec); got != want {
+			t.Errorf("IsDynamicVersion(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
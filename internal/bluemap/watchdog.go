@@ -0,0 +1,168 @@
+package bluemap
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchdogInterval is how often the watchdog checks for stalls; a variable
+// so tests can shorten it.
+var watchdogInterval = 10 * time.Second
+
+// recentLineCount is how many trailing output lines the watchdog report
+// shows.
+const recentLineCount = 10
+
+// tailLineCount is how many trailing output lines are kept for the failure
+// excerpt.
+const tailLineCount = 500
+
+// regionRe matches region references such as "r.-1.3" or "region -1, 3".
+var regionRe = regexp.MustCompile(`r\.(-?\d+)\.(-?\d+)|region\s*\(?(-?\d+),\s*(-?\d+)`)
+
+// watchdog tracks BlueMap CLI output activity. It is an io.Writer placed in
+// the stdout/stderr chain; every complete line counts as progress and is
+// passed to progress, which follows the map being rendered, and inspected
+// for the region being worked on in that map.
+type watchdog struct {
+	mu           sync.Mutex
+	lastActivity time.Time
+	partial      bytes.Buffer
+	recent       []string
+	lastRegion   string
+	progress     renderProgress
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{lastActivity: time.Now()}
+}
+
+func (w *watchdog) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastActivity = time.Now()
+	w.partial.Write(p)
+	for {
+		line, err := w.partial.ReadString('\n')
+		if err != nil {
+			// Incomplete line: keep it for the next write.
+			w.partial.Reset()
+			w.partial.WriteString(line)
+			break
+		}
+		w.observe(strings.TrimRight(line, "\r\n"), w.lastActivity)
+	}
+	return len(p), nil
+}
+
+func (w *watchdog) observe(line string, now time.Time) {
+	if line == "" {
+		return
+	}
+	rendering := w.progress.current
+	w.progress.observe(line, now)
+	if w.progress.current != rendering {
+		w.lastRegion = ""
+	}
+	w.recent = append(w.recent, line)
+	if len(w.recent) > 2*tailLineCount {
+		// Trim in batches so the slice is not copied on every line.
+		w.recent = append(w.recent[:0], w.recent[len(w.recent)-tailLineCount:]...)
+	}
+	if m := regionRe.FindStringSubmatch(line); m != nil {
+		if m[1] != "" {
+			w.lastRegion = "r." + m[1] + "." + m[2]
+		} else {
+			w.lastRegion = "r." + m[3] + "." + m[4]
+		}
+	}
+}
+
+// idle returns how long it has been since the last output.
+func (w *watchdog) idle() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Since(w.lastActivity)
+}
+
+// report describes where the render was when it was stopped.
+func (w *watchdog) report() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var sb strings.Builder
+	where := []string{}
+	if w.progress.current != "" {
+		where = append(where, "map "+w.progress.current)
+	}
+	if w.lastRegion != "" {
+		where = append(where, "region "+w.lastRegion)
+	}
+	if len(where) > 0 {
+		sb.WriteString("last seen: " + strings.Join(where, ", "))
+	} else {
+		sb.WriteString("last seen: unknown map/region")
+	}
+	if len(w.recent) > 0 {
+		recent := w.recent[max(len(w.recent)-recentLineCount, 0):]
+		sb.WriteString("\nlast output:\n  " + strings.Join(recent, "\n  "))
+	}
+	return sb.String()
+}
+
+// progressLine returns the render status line at now.
+func (w *watchdog) progressLine(now time.Time) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.progress.line(now)
+}
+
+// finishProgress ends the render progress at now and returns the time spent
+// on each map.
+func (w *watchdog) finishProgress(now time.Time) []MapTiming {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.progress.finish(now)
+}
+
+// renderError wraps err of a failed render with the excerpt of the output
+// that explains it.
+func (w *watchdog) renderError(err error) *RenderError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tail := w.recent[max(len(w.recent)-tailLineCount, 0):]
+	exception, excerpt := failureExcerpt(tail)
+	return &RenderError{Err: err, Exception: exception, Excerpt: slices.Clone(excerpt)}
+}
+
+// monitor watches the process until done is closed. It calls kill with a
+// reason when no output arrives for stallTimeout or the total runtime
+// exceeds timeout (either may be zero to disable it).
+func (w *watchdog) monitor(start time.Time, stallTimeout, timeout time.Duration, kill func(reason string), done <-chan struct{}) {
+	if stallTimeout <= 0 && timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if timeout > 0 && time.Since(start) > timeout {
+				kill(fmt.Sprintf("render exceeded the %s timeout", timeout))
+				return
+			}
+			if stallTimeout > 0 && w.idle() > stallTimeout {
+				kill(fmt.Sprintf("no BlueMap output for %s (stall timeout)", stallTimeout))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bluemap
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchdogReport(t *testing.T) {
+	wd := newWatchdog()
+	// Output arrives in arbitrary chunks, not necessarily line-aligned.
+	wd.Write([]byte("[INFO] Updating map 'overworld'...\n[WARN] Failed to load r.-2"))
+	wd.Write([]byte(".5 (corrupt chunk)\n"))
+	wd.Write([]byte("partial line without newline"))
+
+	report := wd.report()
+	if !strings.Contains(report, "map overworld") {
+		t.Errorf("report missing map: %q", report)
+	}
+	if !strings.Contains(report, "region r.-2.5") {
+		t.Errorf("report missing region: %q", report)
+	}
+	if strings.Contains(report, "partial line") {
+		t.Errorf("incomplete line should not be reported yet: %q", report)
+	}
+}
+
+func TestWatchdogStallKill(t *testing.T) {
+	defer func(d time.Duration) { watchdogInterval = d }(watchdogInterval)
+	watchdogInterval = 5 * time.Millisecond
+
+	wd := newWatchdog()
+	wd.Write([]byte("[INFO] Loading map 'nether'...\n"))
+	wd.Write([]byte("[INFO] Updating map 'overworld': 12.5%\n"))
+	wd.Write([]byte("[INFO] Rendering region r.3.-4\n"))
+	// Lines naming another map while overworld renders must not move the
+	// blame away from it.
+	wd.Write([]byte("[INFO] Saved markers of map 'nether'\n"))
+	wd.Write([]byte("[INFO] Start updating 2 maps\n"))
+
+	killed := make(chan string, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go wd.monitor(time.Now(), 20*time.Millisecond, 0, func(reason string) { killed <- reason }, done)
+
+	select {
+	case reason := <-killed:
+		if !strings.Contains(reason, "stall timeout") {
+			t.Errorf("kill reason = %q", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog never killed the stalled render")
+	}
+	report := wd.report()
+	if !strings.Contains(report, "last seen: map overworld, region r.3.-4") {
+		t.Errorf("report = %q, want the map being rendered", report)
+	}
+}
@@ -0,0 +1,164 @@
+package bluemap
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WebappResource is the webapp archive inside a BlueMap jar, which BlueMap
+// unpacks into web/ when it generates the web app.
+const WebappResource = "de/bluecolored/bluemap/webapp.zip"
+
+// maxWebappSize caps the size of the webapp archive read from a jar and of
+// every file unpacked from it; the webapp is a few MB.
+const maxWebappSize = 256 << 20
+
+// webappKept are the paths below web/ a webapp archive does not replace:
+// the settings BlueMap generated from webapp.conf and the rendered maps.
+var webappKept = []string{"settings.json", "maps"}
+
+// FetchWebapp downloads the webapp_url archive into dest.
+func FetchWebapp(ctx context.Context, url, dest string) error {
+	if err := fetchPack(ctx, url, dest); err != nil {
+		return fmt.Errorf("downloading webapp %s: %w", url, err)
+	}
+	return nil
+}
+
+// ReplaceWebapp replaces the webapp in serverDir/web with the one in the
+// archive at archivePath: a BlueMap jar, whose WebappResource is used, or a
+// zip of the webapp itself with index.html at its root. The old assets/
+// folder is removed first so no stale bundle is left behind; settings.json,
+// the rendered maps and files the archive does not contain are kept. The
+// archive's lang/ files replace deployed ones, so deploy those again after.
+// Returns the number of files written.
+func ReplaceWebapp(serverDir, archivePath string) (int, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("opening %s: %w", archivePath, err)
+	}
+	defer zr.Close()
+
+	webapp := &zr.Reader
+	for _, f := range zr.File {
+		if f.Name == WebappResource {
+			data, err := readZipFile(f)
+			if err != nil {
+				return 0, fmt.Errorf("reading %s from %s: %w", WebappResource, archivePath, err)
+			}
+			if webapp, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+				return 0, fmt.Errorf("opening %s from %s: %w", WebappResource, archivePath, err)
+			}
+			break
+		}
+	}
+	if !hasZipFile(webapp, "index.html") {
+		return 0, fmt.Errorf("%s is neither a BlueMap jar nor a webapp zip with index.html at its root", filepath.Base(archivePath))
+	}
+
+	webDir := filepath.Join(serverDir, "web")
+	if err := os.RemoveAll(filepath.Join(webDir, "assets")); err != nil {
+		return 0, err
+	}
+	root, err := os.OpenRoot(webDir)
+	if err != nil {
+		return 0, err
+	}
+	defer root.Close()
+
+	written := 0
+	for _, f := range webapp.File {
+		name := path.Clean(f.Name)
+		if f.FileInfo().IsDir() || !filepath.IsLocal(filepath.FromSlash(name)) || webappKeeps(name) {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return written, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		if err := mkdirAllIn(root, path.Dir(name)); err != nil {
+			return written, fmt.Errorf("creating folder for web/%s: %w", name, err)
+		}
+		if err := writeInRoot(root, filepath.FromSlash(name), data); err != nil {
+			return written, fmt.Errorf("writing web/%s: %w", name, err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// webappKeeps reports whether name, a slash-separated path below web/, is
+// one of webappKept or inside it.
+func webappKeeps(name string) bool {
+	for _, k := range webappKept {
+		if name == k || strings.HasPrefix(name, k+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasZipFile(zr *zip.Reader, name string) bool {
+	for _, f := range zr.File {
+		if path.Clean(f.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readZipFile reads f, refusing one larger than maxWebappSize.
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxWebappSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB", f.Name, maxWebappSize>>20)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxWebappSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxWebappSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB", f.Name, maxWebappSize>>20)
+	}
+	return data, nil
+}
+
+// writeInRoot writes data to the file name in root, replacing it.
+func writeInRoot(root *os.Root, name string, data []byte) error {
+	f, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mkdirAllIn creates the slash-separated folder dir and its parents in
+// root. os.Root has no MkdirAll before Go 1.25.
+func mkdirAllIn(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if err := mkdirAllIn(root, path.Dir(dir)); err != nil {
+		return err
+	}
+	err := root.Mkdir(filepath.FromSlash(dir), 0o755)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package bluemap
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipFiles builds a zip archive of name → content.
+func zipFiles(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReplaceWebapp(t *testing.T) {
+	webapp := zipFiles(t, map[string][]byte{
+		"index.html":         []byte("new index"),
+		"assets/index-b.js":  []byte("new bundle"),
+		"lang/en.conf":       []byte("new lang"),
+		"settings.json":      []byte("default settings"),
+		"maps/world/x.json":  []byte("not a map"),
+		"../outside.txt":     []byte("escape"),
+		"assets/style-b.css": []byte("new style"),
+	})
+	jar := zipFiles(t, map[string][]byte{
+		"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n"),
+		WebappResource:         webapp,
+	})
+
+	for name, archive := range map[string][]byte{"jar": jar, "zip": webapp} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			web := filepath.Join(dir, "web")
+			for rel, content := range map[string]string{
+				"index.html":        "old index",
+				"assets/index-a.js": "old bundle",
+				"settings.json":     "generated settings",
+				"maps/world/x.json": "rendered",
+				"netlify.toml":      "deployed",
+			} {
+				path := filepath.Join(web, filepath.FromSlash(rel))
+				os.MkdirAll(filepath.Dir(path), 0o755)
+				os.WriteFile(path, []byte(content), 0o644)
+			}
+			archivePath := filepath.Join(dir, "webapp."+name)
+			os.WriteFile(archivePath, archive, 0o644)
+
+			n, err := ReplaceWebapp(dir, archivePath)
+			if err != nil {
+				t.Fatalf("ReplaceWebapp: %v", err)
+			}
+			if n != 4 {
+				t.Errorf("wrote %d files, want 4", n)
+			}
+			for rel, want := range map[string]string{
+				"index.html":        "new index",
+				"assets/index-b.js": "new bundle",
+				"lang/en.conf":      "new lang",
+				"settings.json":     "generated settings",
+				"maps/world/x.json": "rendered",
+				"netlify.toml":      "deployed",
+			} {
+				data, err := os.ReadFile(filepath.Join(web, filepath.FromSlash(rel)))
+				if err != nil || string(data) != want {
+					t.Errorf("web/%s = %q, %v; want %q", rel, data, err, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(web, "assets", "index-a.js")); err == nil {
+				t.Error("the old bundle was kept")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "outside.txt")); err == nil {
+				t.Error("an entry was written outside web/")
+			}
+		})
+	}
+}
+
+func TestReplaceWebappNotAWebapp(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "other.zip")
+	os.WriteFile(archivePath, zipFiles(t, map[string][]byte{"readme.txt": nil}), 0o644)
+	os.MkdirAll(filepath.Join(dir, "web", "assets"), 0o755)
+	if _, err := ReplaceWebapp(dir, archivePath); err == nil {
+		t.Fatal("expected an error for a zip without index.html")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web", "assets")); err != nil {
+		t.Error("the webapp was touched although the archive was refused")
+	}
+}
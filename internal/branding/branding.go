@@ -0,0 +1,139 @@
+package branding
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/webpage"
+)
+
+// ImageExtensions lists the image types accepted for the favicon and logo.
+var ImageExtensions = []string{".png", ".ico", ".svg", ".jpg", ".jpeg", ".webp", ".gif"}
+
+// Options is the server branding applied to the rendered webapp. Empty
+// fields leave BlueMap's defaults in place.
+type Options struct {
+	Title       string // Page title, og:site_name and og:title
+	Favicon     string // Image file copied to web/favicon.<ext> and linked as the icon
+	Logo        string // Image file copied to web/logo.<ext> and used as og:image
+	AccentColor string // CSS color for theme-color and the webapp's toggle buttons
+	MapURL      string // Public URL of the map; makes og:image absolute
+}
+
+// Enabled reports whether any branding is configured.
+func (o Options) Enabled() bool {
+	return o.Title != "" || o.Favicon != "" || o.Logo != "" || o.AccentColor != ""
+}
+
+var (
+	titleRe      = regexp.MustCompile(`(?s)<title>.*?</title>`)
+	ogTitleRe    = regexp.MustCompile(`(<meta\s+(?:name|property)="og:(?:site_name|title)"\s+content=")[^"]*(")`)
+	ogImageRe    = regexp.MustCompile(`(<meta\s+(?:name|property)="og:image"\s+content=")[^"]*(")`)
+	iconRe       = regexp.MustCompile(`(<link\s+rel="icon"[^>]*?\shref=")[^"]*(")`)
+	themeColorRe = regexp.MustCompile(`(<meta\s+name="theme-color"\s+content=")[^"]*(")`)
+	styleRe      = regexp.MustCompile(`(?s)\s*<style id="bluemap-action-branding">.*?</style>`)
+)
+
+// Apply copies the favicon and logo into web/ under serverDir and patches
+// web/index.html, which BlueMap writes on every render, with the title,
+// icon, og:image and accent color. Re-applying replaces the previous
+// branding, so it is safe to run on an already patched page.
+func Apply(serverDir string, opts Options) error {
+	webDir := filepath.Join(serverDir, "web")
+	indexPath := filepath.Join(webDir, "index.html")
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", indexPath, err)
+	}
+	page := string(data)
+
+	if opts.Title != "" {
+		title := html.EscapeString(opts.Title)
+		page = titleRe.ReplaceAllLiteralString(page, "<title>"+title+"</title>")
+		page = replaceAttr(ogTitleRe, page, title)
+		fmt.Printf("  ✔  title: %s\n", opts.Title)
+	}
+
+	if opts.Favicon != "" {
+		name, err := copyImage(serverDir, opts.Favicon, "favicon")
+		if err != nil {
+			return err
+		}
+		href := "./" + name
+		if iconRe.MatchString(page) {
+			page = replaceAttr(iconRe, page, href)
+		} else {
+			page, _ = webpage.Insert(page, "</head>", `<link rel="icon" href="`+href+`">`)
+		}
+		fmt.Printf("  ✔  favicon: web/%s\n", name)
+	}
+
+	if opts.Logo != "" {
+		name, err := copyImage(serverDir, opts.Logo, "logo")
+		if err != nil {
+			return err
+		}
+		// Link previews need an absolute og:image URL.
+		image := "./" + name
+		if opts.MapURL != "" {
+			image = strings.TrimSuffix(opts.MapURL, "/") + "/" + name
+		}
+		image = html.EscapeString(image)
+		if ogImageRe.MatchString(page) {
+			page = replaceAttr(ogImageRe, page, image)
+		} else {
+			page, _ = webpage.Insert(page, "</head>", `<meta name="og:image" content="`+image+`">`)
+		}
+		fmt.Printf("  ✔  logo: web/%s\n", name)
+	}
+
+	page = styleRe.ReplaceAllLiteralString(page, "")
+	if opts.AccentColor != "" {
+		color := html.EscapeString(opts.AccentColor)
+		if themeColorRe.MatchString(page) {
+			page = replaceAttr(themeColorRe, page, color)
+		} else {
+			page, _ = webpage.Insert(page, "</head>", `<meta name="theme-color" content="`+color+`">`)
+		}
+		// The webapp declares its colors per theme on different selectors;
+		// !important on every element overrides all of them.
+		page, _ = webpage.Insert(page, "</head>", `<style id="bluemap-action-branding">* { --theme-switch-button-on: `+color+` !important; }</style>`)
+		fmt.Printf("  ✔  accent color: %s\n", opts.AccentColor)
+	}
+
+	if err := os.WriteFile(indexPath, []byte(page), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", indexPath, err)
+	}
+	return nil
+}
+
+// replaceAttr replaces the attribute value captured between the two groups
+// of re with value, which must already be HTML-escaped.
+func replaceAttr(re *regexp.Regexp, page, value string) string {
+	return re.ReplaceAllStringFunc(page, func(m string) string {
+		sub := re.FindStringSubmatch(m)
+		return sub[1] + value + sub[2]
+	})
+}
+
+// copyImage copies the image at src (relative to serverDir unless absolute)
+// to web/<base><ext> and returns the new file name.
+func copyImage(serverDir, src, base string) (string, error) {
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(serverDir, src)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", base, err)
+	}
+	name := base + strings.ToLower(filepath.Ext(src))
+	target := filepath.Join(serverDir, "web", name)
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", target, err)
+	}
+	return name, nil
+}
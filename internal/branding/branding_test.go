@@ -0,0 +1,78 @@
+package branding
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bluemapIndex = `<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <meta name="theme-color" content="#006EDE">
+        <meta name="og:site_name" content="BlueMap">
+        <meta name="og:title" content="BlueMap">
+        <link rel="icon" href="./assets/favicon-DEN7TZ5X.png">
+        <title>BlueMap</title>
+    </head>
+    <body></body>
+</html>
+`
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "web"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	index := filepath.Join(dir, "web", "index.html")
+	if err := os.WriteFile(index, []byte(bluemapIndex), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"icon.PNG", "logo.svg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{
+		Title:       "Efina & Friends",
+		Favicon:     "icon.PNG",
+		Logo:        "logo.svg",
+		AccentColor: "#3a7bd5",
+		MapURL:      "https://map.example.com/",
+	}
+	// Applying twice must give the same page.
+	for i := 0; i < 2; i++ {
+		if err := Apply(dir, opts); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		`<title>Efina &amp; Friends</title>`,
+		`<meta name="og:site_name" content="Efina &amp; Friends">`,
+		`<meta name="og:title" content="Efina &amp; Friends">`,
+		`<link rel="icon" href="./favicon.png">`,
+		`<meta name="theme-color" content="#3a7bd5">`,
+		`<meta name="og:image" content="https://map.example.com/logo.svg">`,
+		`--theme-switch-button-on: #3a7bd5 !important;`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("index.html lacks %s:\n%s", want, page)
+		}
+	}
+	if n := strings.Count(page, "<style"); n != 1 {
+		t.Errorf("index.html has %d style blocks, want 1:\n%s", n, page)
+	}
+	for _, name := range []string{"favicon.png", "logo.svg"} {
+		if _, err := os.Stat(filepath.Join(dir, "web", name)); err != nil {
+			t.Errorf("%s not copied: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/redact"
+)
+
+// Provider names returned by Environment.Provider.
+const (
+	ProviderNone    = ""        // Not running in CI.
+	ProviderGitHub  = "github"  // GitHub Actions.
+	ProviderGitea   = "gitea"   // Gitea or Forgejo Actions (GitHub-compatible runners).
+	ProviderGitLab  = "gitlab"  // GitLab CI.
+	ProviderGeneric = "generic" // Any other CI that sets CI=true.
+)
+
+// Default artifact file names used when the provider has no native summary or
+// output mechanism. They are written relative to the project directory so
+// they can be collected as job artifacts (e.g. GitLab's artifacts:reports:dotenv).
+const (
+	DefaultSummaryFile = "bluemap-summary.md"
+	DefaultDotenvFile  = "bluemap.env"
+)
+
+// Environment describes where run reports and outputs should be written for
+// the detected CI provider. An empty path disables the corresponding feature.
+type Environment struct {
+	Provider    string
+	SummaryPath string // Markdown summary destination
+	OutputPath  string // key=value outputs (GITHUB_OUTPUT or a dotenv artifact)
+	JobURL      string // Link back to the running job, if known
+}
+
+// Detect inspects well-known environment variables to determine the CI
+// provider. BLUEMAP_SUMMARY_FILE and BLUEMAP_OUTPUT_FILE override the
+// provider defaults on every platform.
+func Detect() Environment {
+	var env Environment
+
+	switch {
+	// Gitea and Forgejo runners also set GITHUB_ACTIONS=true, so they must be
+	// checked first.
+	case os.Getenv("GITEA_ACTIONS") == "true" || os.Getenv("FORGEJO_ACTIONS") == "true":
+		env.Provider = ProviderGitea
+		// GITHUB_STEP_SUMMARY is accepted but not rendered by Gitea, so the
+		// summary goes to a workspace artifact (and is echoed to the log).
+		env.SummaryPath = filepath.Join(os.Getenv("GITHUB_WORKSPACE"), DefaultSummaryFile)
+		env.OutputPath = os.Getenv("GITHUB_OUTPUT")
+		// Gitea addresses runs by their per-repository number, not the
+		// global run ID GitHub uses.
+		if server, repo, run := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_NUMBER"); server != "" && repo != "" && run != "" {
+			env.JobURL = server + "/" + repo + "/actions/runs/" + run
+		}
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		env.Provider = ProviderGitHub
+		env.SummaryPath = os.Getenv("GITHUB_STEP_SUMMARY")
+		env.OutputPath = os.Getenv("GITHUB_OUTPUT")
+		if server, repo, run := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); server != "" && repo != "" && run != "" {
+			env.JobURL = server + "/" + repo + "/actions/runs/" + run
+		}
+	case os.Getenv("GITLAB_CI") == "true":
+		env.Provider = ProviderGitLab
+		projectDir := os.Getenv("CI_PROJECT_DIR")
+		env.SummaryPath = filepath.Join(projectDir, DefaultSummaryFile)
+		env.OutputPath = filepath.Join(projectDir, DefaultDotenvFile)
+		env.JobURL = os.Getenv("CI_JOB_URL")
+	case os.Getenv("CI") == "true":
+		env.Provider = ProviderGeneric
+	default:
+		env.Provider = ProviderNone
+	}
+
+	if p := os.Getenv("BLUEMAP_SUMMARY_FILE"); p != "" {
+		env.SummaryPath = p
+	}
+	if p := os.Getenv("BLUEMAP_OUTPUT_FILE"); p != "" {
+		env.OutputPath = p
+	}
+
+	return env
+}
+
+// InCI reports whether a CI provider was detected.
+func (e Environment) InCI() bool {
+	return e.Provider != ProviderNone
+}
+
+// WriteSummary appends markdown to the summary destination, with secrets
+// masked (see redact.String). On Gitea and Forgejo, which have no summary
+// view, it is also echoed into a collapsible log group. It is a no-op when
+// no summary path is configured.
+func (e Environment) WriteSummary(markdown string) error {
+	markdown = redact.String(markdown)
+	if e.Provider == ProviderGitea {
+		fmt.Println("::group::BlueMap Build Summary")
+		fmt.Print(markdown)
+		fmt.Println("::endgroup::")
+	}
+	if e.SummaryPath == "" {
+		return nil
+	}
+	return appendFile(e.SummaryPath, markdown)
+}
+
+// SetOutput records a key=value output. Values must be single-line; both
+// GITHUB_OUTPUT and dotenv artifacts use this format. Outside GitHub Actions
+// the key is converted to an environment variable name (e.g. "backup-uuid" →
+// "BACKUP_UUID") because dotenv reports reject dashes. It is a no-op when no
+// output path is configured.
+func (e Environment) SetOutput(key, value string) error {
+	if e.OutputPath == "" {
+		return nil
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("output %s: multi-line values are not supported", key)
+	}
+	if e.Provider != ProviderGitHub && e.Provider != ProviderGitea {
+		key = strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+	}
+	return appendFile(e.OutputPath, key+"="+value+"\n")
+}
+
+// CanMask reports whether Mask redacts values on this provider. Outputs
+// elsewhere land in plain files, such as GitLab's dotenv artifact, which
+// anyone who can download the job's artifacts can read.
+func (e Environment) CanMask() bool {
+	return e.Provider == ProviderGitHub || e.Provider == ProviderGitea
+}
+
+// Mask asks the CI provider to redact value from the job log. Only GitHub
+// Actions and Gitea/Forgejo support runtime masking; other providers require
+// masked variables to be declared in their settings, so this is a no-op there.
+func (e Environment) Mask(value string) {
+	if e.CanMask() && value != "" {
+		fmt.Printf("::add-mask::%s\n", value)
+	}
+}
+
+// Annotation levels for Annotate.
+const (
+	AnnotationError   = "error"
+	AnnotationWarning = "warning"
+	AnnotationNotice  = "notice"
+)
+
+// Annotate attaches message to the workflow run as an error, warning or
+// notice annotation with the given title, so failures and progress show up
+// on the run page instead of only in the log. Only GitHub Actions and Gitea/Forgejo read
+// workflow commands; it is a no-op elsewhere.
+func (e Environment) Annotate(level, title, message string) {
+	if e.Provider == ProviderGitHub || e.Provider == ProviderGitea {
+		fmt.Println(annotation(level, title, message))
+	}
+}
+
+// annotation formats a workflow command such as "::error title=T::message",
+// escaping the characters that would end the property or the command.
+func annotation(level, title, message string) string {
+	cmd := "::" + level
+	if title != "" {
+		cmd += " title=" + escapeProperty(title)
+	}
+	return cmd + "::" + escapeData(message)
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeData(s))
+}
+
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
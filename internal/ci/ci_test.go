@@ -0,0 +1,108 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func clearCIEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range []string{"CI", "GITHUB_ACTIONS", "GITHUB_STEP_SUMMARY", "GITHUB_OUTPUT", "GITLAB_CI", "CI_PROJECT_DIR", "CI_JOB_URL", "BLUEMAP_SUMMARY_FILE", "BLUEMAP_OUTPUT_FILE", "GITEA_ACTIONS", "FORGEJO_ACTIONS", "GITHUB_WORKSPACE", "GITHUB_SERVER_URL", "GITHUB_REPOSITORY", "GITHUB_RUN_ID", "GITHUB_RUN_NUMBER"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestDetectGitLab(t *testing.T) {
+	clearCIEnv(t)
+	dir := t.TempDir()
+	t.Setenv("CI", "true")
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CI_PROJECT_DIR", dir)
+	t.Setenv("CI_JOB_URL", "https://gitlab.example.com/job/1")
+
+	env := Detect()
+	if env.Provider != ProviderGitLab {
+		t.Fatalf("Provider = %q, want %q", env.Provider, ProviderGitLab)
+	}
+	if env.JobURL != "https://gitlab.example.com/job/1" {
+		t.Errorf("JobURL = %q", env.JobURL)
+	}
+
+	if err := env.SetOutput("backup-uuid", "abc"); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, DefaultDotenvFile))
+	if err != nil {
+		t.Fatalf("reading dotenv: %v", err)
+	}
+	if string(data) != "BACKUP_UUID=abc\n" {
+		t.Errorf("dotenv = %q, want %q", data, "BACKUP_UUID=abc\n")
+	}
+	if env.CanMask() {
+		t.Error("CanMask() = true on GitLab, whose dotenv artifact is not masked")
+	}
+}
+
+func TestDetectNone(t *testing.T) {
+	clearCIEnv(t)
+	env := Detect()
+	if env.InCI() {
+		t.Fatalf("InCI() = true outside CI (provider %q)", env.Provider)
+	}
+	if err := env.SetOutput("key", "value"); err != nil {
+		t.Errorf("SetOutput outside CI should be a no-op, got %v", err)
+	}
+}
+
+func TestDetectGitea(t *testing.T) {
+	clearCIEnv(t)
+	dir := t.TempDir()
+	t.Setenv("CI", "true")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITEA_ACTIONS", "true")
+	t.Setenv("GITHUB_WORKSPACE", dir)
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(dir, "output"))
+	t.Setenv("GITHUB_SERVER_URL", "https://gitea.example.com")
+	t.Setenv("GITHUB_REPOSITORY", "owner/maps")
+	t.Setenv("GITHUB_RUN_ID", "98765")
+	t.Setenv("GITHUB_RUN_NUMBER", "12")
+
+	env := Detect()
+	if env.Provider != ProviderGitea {
+		t.Fatalf("Provider = %q, want %q", env.Provider, ProviderGitea)
+	}
+	if env.SummaryPath != filepath.Join(dir, DefaultSummaryFile) {
+		t.Errorf("SummaryPath = %q", env.SummaryPath)
+	}
+	if env.JobURL != "https://gitea.example.com/owner/maps/actions/runs/12" {
+		t.Errorf("JobURL = %q", env.JobURL)
+	}
+
+	// Gitea reads GITHUB_OUTPUT, so keys keep their GitHub form.
+	if err := env.SetOutput("backup-uuid", "abc"); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "output"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if string(data) != "backup-uuid=abc\n" {
+		t.Errorf("output = %q, want %q", data, "backup-uuid=abc\n")
+	}
+}
+
+func TestAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		level, title, message string
+		want                  string
+	}{
+		{AnnotationError, "Error rendering", "exit status 1", "::error title=Error rendering::exit status 1"},
+		{AnnotationWarning, "", "world \"nether\" not found", "::warning::world \"nether\" not found"},
+		{AnnotationError, "a: b, c", "100% done\nnext line", "::error title=a%3A b%2C c::100%25 done%0Anext line"},
+	} {
+		if got := annotation(tc.level, tc.title, tc.message); got != tc.want {
+			t.Errorf("annotation(%q, %q, %q) = %q, want %q", tc.level, tc.title, tc.message, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/snapshot"
+)
+
+// Targets accepted in cleanup in config.toml.
+const (
+	TargetWorlds  = "worlds"  // Extracted world folders, extra_paths and marker data
+	TargetArchive = "archive" // Backup archives left in the server directory
+	TargetJar     = "jar"     // BlueMap CLI jars in the server directory
+)
+
+// Targets lists every cleanup target in the order they are run.
+var Targets = []string{TargetWorlds, TargetArchive, TargetJar}
+
+// Options describes what the server directory holds for each target.
+type Options struct {
+	// Worlds are the world folders and other extracted paths, relative to
+	// the server directory.
+	Worlds []string
+	// KeepArchive keeps the archive in the default debug directory, for runs
+	// with -keep-intermediate.
+	KeepArchive bool
+}
+
+// Result reports what was removed for one target.
+type Result struct {
+	Target string
+	Paths  int   // files and directories removed
+	Bytes  int64 // disk space reclaimed
+}
+
+// Run deletes the intermediates of each target from serverDir and returns one
+// Result per target, in the order of Targets. Symlinks are removed without
+// following them, so a jar linked from the shared jar cache stays cached.
+func Run(serverDir string, targets []string, opts Options) ([]Result, error) {
+	var results []Result
+	for _, target := range Targets {
+		if !slices.Contains(targets, target) {
+			continue
+		}
+		paths, err := targetPaths(serverDir, target, opts)
+		if err != nil {
+			return results, err
+		}
+		res := Result{Target: target}
+		for _, path := range paths {
+			size, err := diskUsage(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return results, err
+			}
+			if err := os.RemoveAll(path); err != nil {
+				return results, fmt.Errorf("removing %s: %w", path, err)
+			}
+			res.Paths++
+			res.Bytes += size
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// targetPaths returns the absolute paths target covers in serverDir.
+func targetPaths(serverDir, target string, opts Options) ([]string, error) {
+	var paths []string
+	switch target {
+	case TargetWorlds:
+		for _, rel := range opts.Worlds {
+			if !filepath.IsLocal(filepath.FromSlash(rel)) {
+				return nil, fmt.Errorf("refusing to remove %q outside the server directory", rel)
+			}
+			paths = append(paths, filepath.Join(serverDir, filepath.FromSlash(rel)))
+		}
+	case TargetArchive:
+		// Temporary archives of an interrupted parallel download.
+		leftover, err := filepath.Glob(filepath.Join(serverDir, ".backup-*.tar.gz"))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, leftover...)
+		if !opts.KeepArchive {
+			snap := snapshot.Snapshot{Dir: filepath.Join(serverDir, snapshot.DefaultDirName)}
+			paths = append(paths, snap.ArchivePath(), snap.IndexPath())
+		}
+	case TargetJar:
+		jars, err := filepath.Glob(filepath.Join(serverDir, bluemap.CLIJarName("*")))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, jars...)
+	default:
+		return nil, fmt.Errorf("unknown cleanup target %q", target)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// diskUsage returns the total size of the files at and below path, counting
+// symlinks as themselves.
+func diskUsage(path string) (int64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+	var total int64
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,103 @@
+package cleanup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EfinaServer/bluemap-action/internal/snapshot"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+	write := func(rel string, size int) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("world/region/r.0.0.mca", 100)
+	write("world/level.dat", 10)
+	write("plugins/WorldGuard/config.yml", 5)
+	write(".backup-123.tar.gz", 1000)
+	write(snapshot.DefaultDirName+"/backup.tar.gz", 2000)
+	write("bluemap-5.3-cli.jar", 300)
+	write("web/index.html", 1)
+	cachedJar := filepath.Join(cache, "bluemap-5.4-cli.jar")
+	if err := os.WriteFile(cachedJar, make([]byte, 400), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(cachedJar, filepath.Join(dir, "bluemap-5.4-cli.jar")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Run(dir, Targets, Options{Worlds: []string{"world", "world_nether", "plugins/WorldGuard"}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(results), results)
+	}
+	if r := results[0]; r.Target != TargetWorlds || r.Paths != 2 || r.Bytes != 115 {
+		t.Errorf("worlds: %+v, want 2 paths, 115 bytes", r)
+	}
+	if r := results[1]; r.Target != TargetArchive || r.Paths != 2 || r.Bytes != 3000 {
+		t.Errorf("archive: %+v, want 2 paths, 3000 bytes", r)
+	}
+	if r := results[2]; r.Target != TargetJar || r.Paths != 2 || r.Bytes < 300 || r.Bytes >= 700 {
+		t.Errorf("jar: %+v, want 2 paths, the symlink not counted at its target's size", r)
+	}
+
+	for _, rel := range []string{"world", "plugins/WorldGuard", ".backup-123.tar.gz", "bluemap-5.3-cli.jar", "bluemap-5.4-cli.jar"} {
+		if _, err := os.Lstat(filepath.Join(dir, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", rel)
+		}
+	}
+	for _, p := range []string{filepath.Join(dir, "web", "index.html"), filepath.Join(dir, "plugins"), cachedJar} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was removed: %v", p, err)
+		}
+	}
+}
+
+func TestRunSelectedTargets(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, snapshot.DefaultDirName, "backup.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(archive), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, []byte("tar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jar := filepath.Join(dir, "bluemap-5.3-cli.jar")
+	if err := os.WriteFile(jar, []byte("jar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Run(dir, []string{TargetArchive}, Options{KeepArchive: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) != 1 || results[0].Paths != 0 {
+		t.Errorf("results = %+v, want nothing removed", results)
+	}
+	for _, p := range []string{archive, jar} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was removed: %v", p, err)
+		}
+	}
+}
+
+func TestRunRefusesOutsidePaths(t *testing.T) {
+	for _, rel := range []string{"../world", "/world", ""} {
+		if _, err := Run(t.TempDir(), []string{TargetWorlds}, Options{Worlds: []string{rel}}); err == nil {
+			t.Errorf("Run accepted world %q", rel)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AlgorithmGzip is the only compression algorithm built in. It is what every
+// host in deploy_target serves precompressed files with; brotli and zstd
+// would need third-party encoders.
+const AlgorithmGzip = "gzip"
+
+// Algorithms lists the algorithms New accepts, for validation messages and
+// the codec benchmark.
+var Algorithms = []string{AlgorithmGzip}
+
+// DefaultLevel selects each codec's own default compression level.
+const DefaultLevel = 0
+
+// Codec is a compression backend. Every feature that writes or reads
+// compressed files goes through this interface so the algorithm can be
+// chosen per file class.
+type Codec interface {
+	// Name returns the algorithm name, e.g. "gzip".
+	Name() string
+	// Extension returns the file suffix for compressed variants, e.g. ".gz".
+	Extension() string
+	// ContentEncoding returns the HTTP Content-Encoding value, e.g. "gzip".
+	ContentEncoding() string
+	// NewWriter wraps w so that everything written is compressed.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader wraps r so that reads return decompressed data.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// New returns the codec for algorithm at the given level (DefaultLevel for
+// the codec's default).
+func New(algorithm string, level int) (Codec, error) {
+	switch algorithm {
+	case AlgorithmGzip:
+		if level != DefaultLevel && (level < gzip.BestSpeed || level > gzip.BestCompression) {
+			return nil, fmt.Errorf("gzip level must be between %d and %d, got %d", gzip.BestSpeed, gzip.BestCompression, level)
+		}
+		if level == DefaultLevel {
+			level = gzip.DefaultCompression
+		}
+		return gzipCodec{level: level}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm %q (built in: %s)", algorithm, strings.Join(Algorithms, ", "))
+	}
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (gzipCodec) Name() string            { return AlgorithmGzip }
+func (gzipCodec) Extension() string       { return ".gz" }
+func (gzipCodec) ContentEncoding() string { return "gzip" }
+
+func (c gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.level)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// File classes that can be assigned different algorithms in config.toml.
+const (
+	ClassAssets = "assets" // webapp code and styles (.js, .css, .html)
+	ClassData   = "data"   // JSON data (settings.json, textures.json, markers)
+)
+
+// ClassOf returns the file class for path, or "" if the file is not a
+// compression candidate (already-compressed files, images, tiles).
+func ClassOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".js", ".css", ".html", ".svg":
+		return ClassAssets
+	case ".json":
+		return ClassData
+	default:
+		return ""
+	}
+}
+
+// CompressFile writes a compressed copy of path next to it (path +
+// codec.Extension()) via a temp file and rename, and returns the size of the
+// compressed file.
+func CompressFile(codec Codec, path string) (int64, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	dst := path + codec.Extension()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+
+	cw, err := codec.NewWriter(out)
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	if _, err := io.Copy(cw, in); err != nil {
+		cw.Close()
+		out.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	if err := cw.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, dst)
+}
+
+// TreeResult summarizes a CompressTree run.
+type TreeResult struct {
+	Files           int
+	OriginalBytes   int64
+	CompressedBytes int64
+}
+
+// CompressTree compresses every file under root whose class has a codec in
+// codecs, using up to workers goroutines (0 = number of CPUs). Existing
+// compressed variants are regenerated so the output always matches the
+// current sources.
+func CompressTree(root string, codecs map[string]Codec, workers int) (TreeResult, error) {
+	var jobs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if _, ok := codecs[ClassOf(path)]; ok {
+			jobs = append(jobs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return TreeResult{}, err
+	}
+	sort.Strings(jobs)
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		result   TreeResult
+		firstErr error
+	)
+	ch := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range ch {
+				info, err := os.Stat(path)
+				if err == nil {
+					var n int64
+					n, err = CompressFile(codecs[ClassOf(path)], path)
+					if err == nil {
+						mu.Lock()
+						result.Files++
+						result.OriginalBytes += info.Size()
+						result.CompressedBytes += n
+						mu.Unlock()
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("compressing %s: %w", path, err)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+
+	return result, firstErr
+}
@@ -0,0 +1,98 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressTreeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("bluemap ", 1000)
+	for _, name := range []string{"index.js", "settings.json", "logo.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	gz, err := New(AlgorithmGzip, DefaultLevel)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	res, err := CompressTree(dir, map[string]Codec{ClassAssets: gz}, 2)
+	if err != nil {
+		t.Fatalf("CompressTree: %v", err)
+	}
+	if res.Files != 1 {
+		t.Fatalf("Files = %d, want 1 (only the assets class is enabled)", res.Files)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "index.js.gz"))
+	if err != nil {
+		t.Fatalf("open compressed file: %v", err)
+	}
+	defer f.Close()
+	r, err := gz.NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("round trip mismatch")
+	}
+
+	for _, name := range []string{"settings.json.gz", "logo.png.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s should not have been created", name)
+		}
+	}
+}
+
+func TestNewUnavailable(t *testing.T) {
+	for _, algorithm := range []string{"brotli", "zstd", "none", ""} {
+		if _, err := New(algorithm, DefaultLevel); err == nil {
+			t.Errorf("expected error for %q, got nil", algorithm)
+		}
+	}
+	if _, err := New(AlgorithmGzip, 42); err == nil {
+		t.Error("expected error for out-of-range gzip level, got nil")
+	}
+}
+
+// BenchmarkCodecs compares every built-in codec at its fastest, default and
+// best level on JSON-like data; results guide the default algorithm and
+// level.
+func BenchmarkCodecs(b *testing.B) {
+	var data bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&data, `{"x":%d,"z":%d,"color":"#%06x"},`, i, -i, i*7919)
+	}
+
+	levels := map[string][]int{AlgorithmGzip: {1, DefaultLevel, 9}}
+	for _, algorithm := range Algorithms {
+		for _, level := range levels[algorithm] {
+			b.Run(fmt.Sprintf("%s-level-%d", algorithm, level), func(b *testing.B) {
+				codec, err := New(algorithm, level)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(data.Len()))
+				var out bytes.Buffer
+				for i := 0; i < b.N; i++ {
+					out.Reset()
+					w, _ := codec.NewWriter(&out)
+					w.Write(data.Bytes())
+					w.Close()
+				}
+				b.ReportMetric(float64(out.Len())/float64(data.Len()), "ratio")
+			})
+		}
+	}
+}
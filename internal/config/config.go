@@ -2,10 +2,38 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/EfinaServer/bluemap-action/internal/access"
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/branding"
+	"github.com/EfinaServer/bluemap-action/internal/cleanup"
+	"github.com/EfinaServer/bluemap-action/internal/compress"
+	"github.com/EfinaServer/bluemap-action/internal/deploy"
+	"github.com/EfinaServer/bluemap-action/internal/extractor"
+	"github.com/EfinaServer/bluemap-action/internal/lang"
+	"github.com/EfinaServer/bluemap-action/internal/lastrender"
+	"github.com/EfinaServer/bluemap-action/internal/manifest"
+	"github.com/EfinaServer/bluemap-action/internal/markers"
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+	"github.com/EfinaServer/bluemap-action/internal/panel"
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
+	"github.com/EfinaServer/bluemap-action/internal/prune"
+	"github.com/EfinaServer/bluemap-action/internal/runreport"
+	"github.com/EfinaServer/bluemap-action/internal/schedule"
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
 )
 
 const (
@@ -14,21 +42,807 @@ const (
 	ServerTypeUnified = "unified"
 
 	// DownloadMode constants control how the backup is downloaded.
-	DownloadModeAuto     = "auto"     // Probe the server and choose the best mode.
-	DownloadModeParallel = "parallel" // Force parallel multi-connection download.
-	DownloadModeSingle   = "single"   // Force single-connection streaming download.
+	DownloadModeAuto           = "auto"            // Probe the server and choose the best mode.
+	DownloadModeParallel       = "parallel"        // Force parallel multi-connection download.
+	DownloadModeParallelStream = "parallel-stream" // Parallel download extracted as it arrives, without a temp file.
+	DownloadModeSingle         = "single"          // Force single-connection streaming download.
+	DownloadModeIndexed        = "indexed"         // Fetch only the worlds' byte ranges of an indexed bgzip backup.
+
+	// DeployTarget constants name the host the web output is prepared for.
+	DeployTargetNetlify = "netlify" // No content negotiation: the webapp requests the .gz files directly.
+	DeployTargetStatic  = "static"  // Web server serving pre-compressed files itself, e.g. nginx gzip_static.
+	DeployTargetSSH     = "ssh"     // Like static, and published to the web server with rsync over SSH.
+	DeployTargetFTP     = "ftp"     // Like netlify, with an Apache .htaccess, and uploaded over FTP(S).
+	DeployTargetS3      = "s3"      // Like netlify, uploaded to S3-compatible storage with per-object headers.
+
+	// Storage constants select where BlueMap renders the map.
+	StorageFile   = "file"   // The storages the map configs name, usually files in web/maps.
+	StorageSQLite = "sqlite" // bluemap.db, kept between runs and exported to web/maps after the render.
+
+	// Dimension names accepted in world dimensions.
+	DimensionOverworld = "overworld"
+	DimensionNether    = "nether"
+	DimensionEnd       = "end"
 )
 
 // ServerConfig represents the TOML config for a single server directory.
 type ServerConfig struct {
-	ServerID            string `toml:"server_id"`
-	ServerType          string `toml:"server_type"`
-	WorldName           string `toml:"world_name"`
-	Name                string `toml:"name"`
-	MinecraftVersion    string `toml:"mc_version"`
-	BlueMapVersion      string `toml:"bluemap_version"`
-	DownloadMode        string `toml:"download_mode"`        // "auto" (default) | "parallel" | "single"
-	DownloadConnections int    `toml:"download_connections"` // 0 = auto (scale by file size) | 1-32 = fixed count
+	ServerID            string   `toml:"server_id"`
+	PanelType           string   `toml:"panel_type"` // "pterodactyl" (default) | "pufferpanel" | "crafty" | "amp"
+	ServerType          string   `toml:"server_type"`
+	WorldName           string   `toml:"world_name"` // Single world shorthand; mutually exclusive with worlds
+	Dimensions          []string `toml:"dimensions"` // Dimensions of the world_name world: "overworld" | "nether" | "end"; empty = all
+	Name                string   `toml:"name"`
+	Timezone            string   `toml:"timezone"`    // IANA zone for the render timestamp, e.g. "Asia/Taipei"; empty = UTC
+	TimeFormat          string   `toml:"time_format"` // Go layout for the render timestamp; empty = DefaultTimeFormat
+	MapURL              string   `toml:"map_url"`     // Public URL of the deployed map for the {mapURL} placeholder, e.g. "https://map.example.com"
+	MinecraftVersion    string   `toml:"mc_version"`
+	BlueMapVersion      string   `toml:"bluemap_version"`
+	BlueMapSHA256       string   `toml:"bluemap_sha256"`        // Optional expected jar checksum; empty = use the release's .sha256 asset
+	BlueMapLock         bool     `toml:"bluemap_lock"`          // Pin a "latest"/"5.x" bluemap_version in bluemap.lock
+	BlueMapDownloadURL  string   `toml:"bluemap_download_url"`  // CLI jar URL used instead of the GitHub release; {version} and {jar} are replaced
+	BlueMapMirrors      []string `toml:"bluemap_mirrors"`       // Further CLI jar URLs tried in order when the download fails or the checksum does not match
+	WebappVersion       string   `toml:"webapp_version"`        // BlueMap release whose webapp replaces the one the CLI generated, e.g. "5.3" or "5.x"; empty = the CLI's
+	WebappURL           string   `toml:"webapp_url"`            // zip of a webapp (or a BlueMap jar) replacing the one the CLI generated; empty = the CLI's
+	JavaArgs            []string `toml:"java_args"`             // Extra JVM flags for the render (e.g. ["-XX:+UseG1GC"])
+	MaxMemory           string   `toml:"max_memory"`            // JVM max heap, e.g. "6G"; empty = 75% of system memory
+	RenderStallTimeout  string   `toml:"render_stall_timeout"`  // Kill the render after this long without output, e.g. "30m"; empty = disabled
+	RenderTimeout       string   `toml:"render_timeout"`        // Kill the render after this total runtime, e.g. "5h"; empty = disabled
+	RenderProgress      string   `toml:"render_progress"`       // Print a render status line this often instead of the raw output, e.g. "5m"; empty = raw output
+	DownloadMode        string   `toml:"download_mode"`         // "auto" (default) | "parallel" | "parallel-stream" | "single" | "indexed"
+	DownloadConnections int      `toml:"download_connections"`  // 0 = auto (scale by file size) | 1-32 = fixed count
+	DownloadBuffer      string   `toml:"download_buffer"`       // memory for ranges downloaded ahead in parallel-stream mode, e.g. "512MiB"; empty = 256 MiB
+	ProxyURL            string   `toml:"proxy_url"`             // Proxy for all outbound requests, overriding HTTP(S)_PROXY; NO_PROXY still applies
+	DownloadRateLimit   string   `toml:"download_rate_limit"`   // total bandwidth across all connections, e.g. "50MiB/s"; empty = unlimited
+	DecompressBlockSize string   `toml:"decompress_block_size"` // gzip read-ahead block size, e.g. "1MiB"; empty = 250 kB
+	DecompressBlocks    int      `toml:"decompress_blocks"`     // gzip blocks decompressed ahead of the tar reader; 0 = 16
+	ExtractWorkers      int      `toml:"extract_workers"`       // goroutines writing extracted files; 0 = CPUs - 1 (max 8), 1 = inline
+	MaxFileSize         string   `toml:"max_file_size"`         // largest single file extracted from the backup, e.g. "20GiB"; empty = 10 GiB
+	MaxTotalSize        string   `toml:"max_total_size"`        // total size of the extracted files, e.g. "200GiB"; empty = 1024 GiB
+	MaxEntries          int      `toml:"max_entries"`           // files, folders and links extracted; 0 = 10,000,000
+	MaxWorldSize        string   `toml:"max_world_size"`        // budget for the extracted worlds after trimming, e.g. "20GiB"; empty = none
+	MaxWebSize          string   `toml:"max_web_size"`          // budget for web/ before it is published, e.g. "5GiB"; empty = none
+	AccessLogs          []string `toml:"access_logs"`           // Optional glob patterns for hosting access logs to analyze
+	Maps                []string `toml:"maps"`                  // Map IDs to render (config/maps/<id>.conf); empty = all maps
+	ResourcePacks       []string `toml:"resourcepacks"`         // Resource packs (http(s) URLs or paths relative to the server directory) installed into config/packs/ before rendering
+	DeployTarget        string   `toml:"deploy_target"`         // "netlify" (default) | "static" | "ssh" | "ftp" | "s3"
+	Storage             string   `toml:"storage"`               // "file" (default) | "sqlite": render into bluemap.db and export it to web/maps
+	CacheBust           bool     `toml:"cache_bust"`            // Append a per-run ?v= query to settings.json and live data URLs
+	PWA                 bool     `toml:"pwa"`                   // Make the map installable with a manifest and a service worker caching the shell and low-res tiles
+	FreshnessBanner     bool     `toml:"freshness_banner"`      // Show a dismissible banner with the backup and render dates in the webapp
+	FreshBackup         bool     `toml:"fresh_backup"`          // Create a new backup instead of using the latest existing one
+	PauseSaves          bool     `toml:"pause_saves"`           // Send save-off/save-all before the fresh backup and save-on after
+	FlushSaves          bool     `toml:"flush_saves"`           // Send save-all flush before the fresh backup, leaving autosave on
+	SkipIfUnchanged     bool     `toml:"skip_if_unchanged"`     // Exit early when the latest backup and config were already rendered
+	LockBackup          bool     `toml:"lock_backup"`           // Lock the backup on the panel while it downloads, so rotation cannot delete it
+	AnnounceCommand     string   `toml:"announce_command"`      // Console command sent by -announce after a deploy, e.g. "say Map updated!"
+	WebhookURL          string   `toml:"webhook_url"`           // JSON POST after a deploy; usually set from BLUEMAP_ACTION_WEBHOOK_URL
+	FileManifest        bool     `toml:"file_manifest"`         // Hash web/ files and diff against the previous run's manifest
+	PruneTiles          string   `toml:"prune_tiles"`           // "" (off) | "dry-run" | "delete": tiles whose source regions are gone
+	RegionCheck         string   `toml:"region_check"`          // "report" (default) | "quarantine" | "off": scan .mca headers before rendering
+	InhabitedStats      bool     `toml:"inhabited_stats"`       // Decompress every chunk to report the InhabitedTime distribution
+	RenderBounds        *Bounds  `toml:"render_bounds"`         // Trim every world without its own bounds to this block area before rendering
+	ExtraPaths          []string `toml:"extra_paths"`           // Further backup paths extracted with the worlds, e.g. ["plugins/WorldGuard", "server.properties"]
+	Cleanup             []string `toml:"cleanup"`               // Intermediates deleted after a successful deploy: "worlds", "archive", "jar"
+
+	FailOnMissingWorlds   *bool  `toml:"fail_on_missing_worlds"`  // nil = true (abort when a world folder is not in the backup)
+	FailOverBudget        *bool  `toml:"fail_over_budget"`        // nil = true (abort when max_world_size or max_web_size is exceeded; false = warn)
+	SecurityHeaders       *bool  `toml:"security_headers"`        // nil = true (emit CSP and security headers in netlify.toml)
+	ContentSecurityPolicy string `toml:"content_security_policy"` // Optional CSP override; empty = built-in default
+
+	Compression CompressionConfig `toml:"compression"`
+	Markers     MarkersConfig     `toml:"markers"`
+	Branding    BrandingConfig    `toml:"branding"`
+	Webapp      WebappConfig      `toml:"webapp"`
+	Access      AccessConfig      `toml:"access"`
+	Cache       CacheConfig       `toml:"cache"`
+	Hosting     HostingConfig     `toml:"hosting"`
+	SSH         SSHConfig         `toml:"ssh"`
+	FTP         FTPConfig         `toml:"ftp"`
+	S3          S3Config          `toml:"s3"`
+	Watch       WatchConfig       `toml:"watch"`
+
+	BackupRetention RetentionConfig `toml:"backup_retention"` // Old panel backups deleted after a successful deploy
+
+	Mods []ModConfig `toml:"mods"` // Mod and datapack jars installed into config/packs/ before rendering
+
+	Placeholders map[string]string `toml:"placeholders"` // Extra {name} values for the language files
+	TimeFormats  map[string]string `toml:"time_formats"` // Go layouts of the timestamps per language file, e.g. zh-TW = "2006年1月2日 15:04"
+
+	Worlds WorldList `toml:"worlds"` // Per-world settings; replaces world_name
+}
+
+// WorldConfig describes one world, given either as a [worlds.<name>] table
+// or as a [[worlds]] array entry.
+type WorldConfig struct {
+	Name       string   `toml:"name"`       // World folder name, e.g. "creative"
+	Type       string   `toml:"type"`       // Folder layout: "vanilla" | "plugin" | "unified"; empty = server_type
+	Dimensions []string `toml:"dimensions"` // "overworld" | "nether" | "end"; empty = all
+	Source     string   `toml:"source"`     // Folder path inside the backup, e.g. "worlds/creative"; empty = name
+	Maps       []string `toml:"maps"`       // BlueMap map IDs rendered from this world
+	Bounds     *Bounds  `toml:"bounds"`     // Only extract region files inside these block bounds
+	Skip       bool     `toml:"skip"`       // Leave the world out of extraction, analysis and render
+}
+
+// Bounds is an inclusive rectangle in block coordinates. Region files that lie
+// entirely outside it are not extracted, and are deleted before the render if
+// they are already on disk.
+type Bounds struct {
+	MinX int `toml:"min_x"`
+	MaxX int `toml:"max_x"`
+	MinZ int `toml:"min_z"`
+	MaxZ int `toml:"max_z"`
+}
+
+// valid reports whether the minimum does not exceed the maximum on either axis.
+func (b Bounds) valid() bool {
+	return b.MinX <= b.MaxX && b.MinZ <= b.MaxZ
+}
+
+// regionSize is the number of blocks along one side of a region file.
+const regionSize = 512
+
+// ContainsRegion reports whether region (rx, rz) overlaps the bounds.
+func (b Bounds) ContainsRegion(rx, rz int) bool {
+	return rx*regionSize <= b.MaxX && (rx+1)*regionSize-1 >= b.MinX &&
+		rz*regionSize <= b.MaxZ && (rz+1)*regionSize-1 >= b.MinZ
+}
+
+// regionFileRe matches region-format files (region/, entities/, poi/).
+var regionFileRe = regexp.MustCompile(`(^|/)r\.(-?\d+)\.(-?\d+)\.mca$`)
+
+// IncludesFile reports whether a file, given by its slash-separated path
+// relative to a world folder, belongs to one of the world's dimensions and
+// falls inside its bounds. Files that are not region files, and every file of
+// a world without bounds, pass the bounds check.
+func (w WorldConfig) IncludesFile(rel string) bool {
+	if dim := w.fileDimension(rel); dim != "" && !w.HasDimension(dim) {
+		return false
+	}
+	if w.Bounds == nil {
+		return true
+	}
+	m := regionFileRe.FindStringSubmatch(rel)
+	if m == nil {
+		return true
+	}
+	rx, _ := strconv.Atoi(m[2])
+	rz, _ := strconv.Atoi(m[3])
+	return w.Bounds.ContainsRegion(rx, rz)
+}
+
+// WorldList is the decoded worlds setting. It accepts both the structured
+// table form, keyed by world name:
+//
+//	[worlds.creative]
+//	source = "worlds/creative"
+//
+// and the array form:
+//
+//	[[worlds]]
+//	name = "creative"
+//
+// Tables are ordered by name, since TOML tables carry no order.
+type WorldList []WorldConfig
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (l *WorldList) UnmarshalTOML(data any) error {
+	switch v := data.(type) {
+	case []map[string]any:
+		for i, entry := range v {
+			w, err := decodeWorld(entry)
+			if err != nil {
+				return fmt.Errorf("worlds[%d]: %w", i, err)
+			}
+			*l = append(*l, w)
+		}
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry, ok := v[name].(map[string]any)
+			if !ok {
+				return fmt.Errorf("worlds.%s must be a table", name)
+			}
+			w, err := decodeWorld(entry)
+			if err != nil {
+				return fmt.Errorf("worlds.%s: %w", name, err)
+			}
+			if w.Name != "" && w.Name != name {
+				return fmt.Errorf("worlds.%s: name %q does not match the table key", name, w.Name)
+			}
+			w.Name = name
+			*l = append(*l, w)
+		}
+	default:
+		return fmt.Errorf("worlds must be [worlds.<name>] tables or a [[worlds]] array")
+	}
+	return nil
+}
+
+// decodeWorld decodes one world table by re-encoding it, so the struct tags
+// on WorldConfig stay the single source of truth for field names. Unknown
+// keys are rejected to catch typos such as "bound".
+func decodeWorld(entry map[string]any) (WorldConfig, error) {
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(entry); err != nil {
+		return WorldConfig{}, err
+	}
+	var w WorldConfig
+	md, err := toml.Decode(buf.String(), &w)
+	if err != nil {
+		return WorldConfig{}, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return WorldConfig{}, fmt.Errorf("unknown key %q", undecoded[0].String())
+	}
+	return w, nil
+}
+
+// BackupFolders maps each folder returned by Folders to its path inside the
+// backup. Without a source the two are the same; for plugin worlds the
+// dimension suffixes are appended to the source path as well.
+func (w WorldConfig) BackupFolders() map[string]string {
+	source := w.Source
+	if source == "" {
+		source = w.Name
+	}
+	folders := make(map[string]string)
+	for _, f := range w.Folders() {
+		folders[f] = source + strings.TrimPrefix(f, w.Name)
+	}
+	return folders
+}
+
+// HasDimension reports whether the world includes the given dimension. An
+// empty Dimensions list includes all of them.
+func (w WorldConfig) HasDimension(dim string) bool {
+	if len(w.Dimensions) == 0 {
+		return true
+	}
+	for _, d := range w.Dimensions {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+// dimensionDirs are the folders holding each dimension's data inside a
+// vanilla world folder (the overworld's are at its root) and inside a
+// unified one.
+var dimensionDirs = map[string][]struct{ dir, dim string }{
+	ServerTypeVanilla: {
+		{"DIM-1/", DimensionNether},
+		{"DIM1/", DimensionEnd},
+		{"region/", DimensionOverworld},
+		{"entities/", DimensionOverworld},
+		{"poi/", DimensionOverworld},
+	},
+	ServerTypeUnified: {
+		{"dimensions/minecraft/overworld/", DimensionOverworld},
+		{"dimensions/minecraft/the_nether/", DimensionNether},
+		{"dimensions/minecraft/the_end/", DimensionEnd},
+	},
+}
+
+// fileDimension returns the dimension a file of a vanilla or unified world
+// folder belongs to, or "" for world-wide files such as level.dat. Plugin
+// worlds keep each dimension in its own folder, filtered by Folders.
+func (w WorldConfig) fileDimension(rel string) string {
+	for _, d := range dimensionDirs[w.Type] {
+		if strings.HasPrefix(rel, d.dir) {
+			return d.dim
+		}
+	}
+	return ""
+}
+
+// Folders returns the top-level folder names to extract from the backup for
+// this world.
+//
+// For vanilla worlds, dimensions are stored as subdirectories within a single
+// world folder (world/DIM-1, world/DIM1), so only one folder is needed.
+//
+// For plugin worlds (Bukkit/Spigot/Paper), each dimension is a separate
+// top-level folder (world, world_nether, world_the_end), and only the
+// selected dimensions are extracted.
+//
+// For unified worlds (Minecraft 26.1+, vanilla and plugin alike), every
+// dimension lives under a single world folder at
+// world/dimensions/<namespace>/<dimension> (e.g.
+// world/dimensions/minecraft/the_nether), so only one folder is needed.
+func (w WorldConfig) Folders() []string {
+	if w.Type != ServerTypePlugin {
+		return []string{w.Name}
+	}
+
+	var folders []string
+	for _, d := range []struct{ dim, suffix string }{
+		{DimensionOverworld, ""},
+		{DimensionNether, "_nether"},
+		{DimensionEnd, "_the_end"},
+	} {
+		if w.HasDimension(d.dim) {
+			folders = append(folders, w.Name+d.suffix)
+		}
+	}
+	return folders
+}
+
+// RequiredFolders returns the folders that must be present in the backup:
+// the world folder itself, or every dimension folder when dimensions are
+// listed explicitly. Other dimension folders of plugin worlds are optional,
+// since servers may run with the nether or end disabled.
+func (w WorldConfig) RequiredFolders() []string {
+	if len(w.Dimensions) > 0 {
+		return w.Folders()
+	}
+	return []string{w.Name}
+}
+
+// CompressionConfig selects the compression backend per file class for
+// precompressing web output. An empty algorithm leaves that class untouched.
+type CompressionConfig struct {
+	Assets  string `toml:"assets"`  // .js, .css, .html, .svg: "gzip" | "" (off)
+	Data    string `toml:"data"`    // .json: "gzip" | "" (off)
+	Level   int    `toml:"level"`   // 1-9; 0 = codec default (gzip: 6)
+	Workers int    `toml:"workers"` // 0 = number of CPUs
+}
+
+// MarkersConfig selects the plugin, player and sign data turned into BlueMap
+// markers.
+type MarkersConfig struct {
+	Sources    []string `toml:"sources"`     // "worldguard" | "towny" | "griefprevention" | "players" | "signs" | "heatmap" | "changes" | "stats"; empty = off
+	Format     string   `toml:"format"`      // "json" (default) | "hocon"
+	SignPrefix string   `toml:"sign_prefix"` // first-line prefix of signs turned into markers; default "[map]"
+}
+
+// ModConfig is a [[mods]] entry: a mod or datapack jar the BlueMap CLI
+// loads from config/packs/ to render the blocks it adds. Set url or
+// modrinth.
+type ModConfig struct {
+	URL      string `toml:"url"`      // Download URL of the jar
+	Modrinth string `toml:"modrinth"` // Modrinth project ID or slug
+	Version  string `toml:"version"`  // Modrinth version ID or number; empty = newest for minecraft_version
+	Loader   string `toml:"loader"`   // Loader the newest Modrinth version must support, e.g. "fabric"
+	SHA256   string `toml:"sha256"`   // Pinned checksum; the render stops when the jar differs
+}
+
+// RetentionConfig deletes old panel backups after a successful deploy. A
+// backup is deleted when it is not among the newest keep or is older than
+// max_age; locked backups and the one just rendered are never deleted.
+type RetentionConfig struct {
+	Keep   int    `toml:"keep"`    // Newest unlocked backups kept; 0 = no count limit
+	MaxAge string `toml:"max_age"` // Delete backups older than this, e.g. "30d" or "72h"; empty = no age limit
+}
+
+// validate checks that m names exactly one source and a well-formed
+// checksum.
+func (m ModConfig) validate() error {
+	switch {
+	case (m.URL == "") == (m.Modrinth == ""):
+		return fmt.Errorf("set either url or modrinth")
+	case m.URL != "":
+		if u, err := url.Parse(m.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || path.Base(u.Path) == "/" {
+			return fmt.Errorf("url must be an http(s) URL of a jar, got %q", m.URL)
+		}
+		if m.Version != "" || m.Loader != "" {
+			return fmt.Errorf("version and loader only apply to modrinth")
+		}
+	}
+	if m.SHA256 != "" && !isHexDigest(m.SHA256, 64) {
+		return fmt.Errorf("sha256 must be a 64-character hex SHA-256 digest")
+	}
+	return nil
+}
+
+// Enabled reports whether [backup_retention] sets a limit.
+func (r RetentionConfig) Enabled() bool {
+	return r.Keep > 0 || r.MaxAge != ""
+}
+
+// ResolveMaxAge returns max_age, or 0 when it is not set. Like the other
+// Resolve methods of size, rate and duration fields, it ignores parse errors:
+// Load validates the values, so they cannot occur for a loaded config.
+func (r RetentionConfig) ResolveMaxAge() time.Duration {
+	d, _ := parseAge(r.MaxAge)
+	return d
+}
+
+// parseAge parses a Go duration string or a whole number of days such as
+// "30d", treating "" as 0.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return parseOptionalDuration(s)
+}
+
+// BrandingConfig is the server branding applied to the webapp's index.html
+// after the render. Empty fields keep BlueMap's defaults.
+type BrandingConfig struct {
+	Title       string `toml:"title"`        // Page and link preview title
+	Favicon     string `toml:"favicon"`      // Image path relative to the server directory, copied to web/
+	Logo        string `toml:"logo"`         // Image path relative to the server directory, copied to web/ as the link preview image
+	AccentColor string `toml:"accent_color"` // "#rgb" or "#rrggbb" for theme-color and the webapp's toggle buttons
+}
+
+// WebappConfig are the webapp settings patched into the settings.json files
+// BlueMap generated. Empty fields keep BlueMap's values.
+type WebappConfig struct {
+	DefaultMap string              `toml:"default_map"` // Map ID opened first
+	StartPos   map[string]StartPos `toml:"start_pos"`   // Camera start position per map ID
+	FlatView   *bool               `toml:"flat_view"`   // Start in the flat view
+	FreeFlight *bool               `toml:"free_flight"` // Offer the free-flight controls
+	UseCookies *bool               `toml:"use_cookies"` // Remember the visitor's settings in cookies
+	MinZoom    float64             `toml:"min_zoom"`    // Closest camera distance in blocks
+	MaxZoom    float64             `toml:"max_zoom"`    // Farthest camera distance in blocks
+	Views      []string            `toml:"views"`       // Views every map offers: "perspective", "flat", "free-flight"
+}
+
+// StartPos is a block position the camera starts at.
+type StartPos struct {
+	X float64 `toml:"x"`
+	Z float64 `toml:"z"`
+}
+
+// Settings returns the changes to apply to the webapp's settings.json files.
+func (w WebappConfig) Settings() bluemap.Settings {
+	s := bluemap.Settings{
+		DefaultMap: w.DefaultMap,
+		FlatView:   w.FlatView,
+		FreeFlight: w.FreeFlight,
+		UseCookies: w.UseCookies,
+		MinZoom:    w.MinZoom,
+		MaxZoom:    w.MaxZoom,
+		Views:      w.Views,
+	}
+	if len(w.StartPos) > 0 {
+		s.StartPos = make(map[string][2]float64, len(w.StartPos))
+		for id, pos := range w.StartPos {
+			s.StartPos[id] = [2]float64{pos.X, pos.Z}
+		}
+	}
+	return s
+}
+
+// CacheConfig sets the Cache-Control header per class of web output in the
+// generated hosting config. Empty fields use the webmeta defaults; "off"
+// leaves the class to the host's default.
+type CacheConfig struct {
+	Assets string `toml:"assets"` // Hashed webapp bundle in assets/; default long-lived and immutable, except the script with cache_bust
+	Tiles  string `toml:"tiles"`  // Map tiles; default one day, revalidated in the background
+	Data   string `toml:"data"`   // settings.json, textures.json and live data; default one minute
+}
+
+// Policy returns the cache settings as a webmeta.CachePolicy, for a build
+// with or without cache_bust.
+func (c CacheConfig) Policy(cacheBust bool) webmeta.CachePolicy {
+	return webmeta.CachePolicy{Assets: c.Assets, Tiles: c.Tiles, Data: c.Data, CacheBust: cacheBust}
+}
+
+// Hosting plans with built-in limits for the [hosting] check.
+const (
+	HostingPlanNetlifyFree         = "netlify-free"
+	HostingPlanCloudflarePagesFree = "cloudflare-pages-free"
+)
+
+// HostingConfig checks web/ against the limits of the hosting plan it is
+// deployed to before the deploy. The fields other than plan override the
+// plan's limits; with none set, nothing is checked.
+type HostingConfig struct {
+	Plan             string `toml:"plan"`              // "netlify-free" | "cloudflare-pages-free"; empty = only the limits below
+	MaxFiles         int    `toml:"max_files"`         // Files per deploy; 0 = the plan's
+	MaxFileSize      string `toml:"max_file_size"`     // Largest file, e.g. "25MiB"; empty = the plan's
+	MonthlyBandwidth string `toml:"monthly_bandwidth"` // Bandwidth per month, e.g. "100GB"; empty = the plan's
+	MonthlyVisits    int    `toml:"monthly_visits"`    // Expected visits per month for the bandwidth estimate; 0 = no estimate
+}
+
+// Enabled reports whether a plan or a limit is set.
+func (h HostingConfig) Enabled() bool {
+	return h.Plan != "" || h.MaxFiles > 0 || h.MaxFileSize != "" || h.MonthlyBandwidth != ""
+}
+
+// ResolveOverrides returns the limits set in the table, each 0 when not
+// set.
+func (h HostingConfig) ResolveOverrides() (maxFiles, maxFileSize, monthlyBandwidth int64) {
+	maxFileSize, _ = parseByteSize(h.MaxFileSize)
+	monthlyBandwidth, _ = parseByteSize(h.MonthlyBandwidth)
+	return int64(h.MaxFiles), maxFileSize, monthlyBandwidth
+}
+
+// SSHConfig is the rsync destination for deploy_target = "ssh". The private
+// key is read from identity_file or the deploy.SSHKeyEnv environment variable.
+type SSHConfig struct {
+	Host           string `toml:"host"`            // Web server host name or address
+	User           string `toml:"user"`            // Login user; empty = the SSH default
+	Port           int    `toml:"port"`            // 0 = 22
+	Path           string `toml:"path"`            // Remote directory serving the map, e.g. "/var/www/map"
+	Delete         *bool  `toml:"delete"`          // nil = true (delete remote files no longer in web/)
+	BandwidthLimit string `toml:"bandwidth_limit"` // Upload bandwidth, e.g. "5MiB/s"; empty = unlimited
+	IdentityFile   string `toml:"identity_file"`   // Private key path relative to the server directory
+}
+
+// ResolveDelete returns whether remote files missing from web/ are deleted,
+// defaulting to true when delete is not set in config.toml.
+func (s SSHConfig) ResolveDelete() bool {
+	return s.Delete == nil || *s.Delete
+}
+
+// ResolveBandwidthKiB returns the upload bandwidth limit in KiB per second as
+// rsync expects it, or 0 when bandwidth_limit is not set.
+func (s SSHConfig) ResolveBandwidthKiB() int64 {
+	rate, _ := parseRate(s.BandwidthLimit)
+	return rate >> 10
+}
+
+// FTPConfig is the upload destination for deploy_target = "ftp". The password
+// is read from the deploy.FTPPasswordEnv environment variable.
+type FTPConfig struct {
+	Host        string `toml:"host"`        // FTP server host name or address
+	Port        int    `toml:"port"`        // 0 = 21, or 990 with tls = "implicit"
+	User        string `toml:"user"`        // Login user; empty = anonymous
+	Path        string `toml:"path"`        // Remote directory serving the map, e.g. "/public_html/map"
+	TLS         string `toml:"tls"`         // "explicit" (default) | "implicit" | "off"
+	Connections int    `toml:"connections"` // Parallel uploads; 0 = deploy.DefaultFTPConnections, max 16
+}
+
+// S3Config is the bucket for deploy_target = "s3". Credentials are read from
+// the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+type S3Config struct {
+	Bucket      string `toml:"bucket"`
+	Endpoint    string `toml:"endpoint"`    // e.g. "https://<account>.r2.cloudflarestorage.com"; empty = AWS S3
+	Region      string `toml:"region"`      // empty = "auto" with an endpoint (R2), "us-east-1" without
+	Prefix      string `toml:"prefix"`      // Key prefix, e.g. "survival/"; empty = bucket root
+	Concurrency int    `toml:"concurrency"` // Parallel requests; 0 = deploy.DefaultS3Concurrency, max 64
+	Delete      bool   `toml:"delete"`      // Delete objects under prefix that are no longer in web/
+}
+
+// WatchConfig schedules the runs of the watch command, for servers that
+// build the map on their own machine rather than in a workflow.
+type WatchConfig struct {
+	Schedule string `toml:"schedule"` // Cron expression, @daily-style macro or "@every 6h", in timezone
+	Listen   string `toml:"listen"`   // Address of the status endpoint; empty = DefaultWatchListen, "off" = none
+}
+
+// DefaultWatchListen is the address of the watch command's status endpoint
+// when watch.listen is not set in config.toml.
+const DefaultWatchListen = "127.0.0.1:8080"
+
+// ResolveSchedule returns the parsed schedule, or nil when schedule is not
+// set.
+func (w WatchConfig) ResolveSchedule() *schedule.Schedule {
+	if w.Schedule == "" {
+		return nil
+	}
+	s, _ := schedule.Parse(w.Schedule)
+	return s
+}
+
+// ResolveListen returns the address of the status endpoint, defaulting to
+// DefaultWatchListen, or "" when listen is "off".
+func (w WatchConfig) ResolveListen() string {
+	switch w.Listen {
+	case "":
+		return DefaultWatchListen
+	case "off":
+		return ""
+	}
+	return w.Listen
+}
+
+// AccessConfig restricts who can view the published map. Passwords are read
+// from an environment variable at deploy time and never stored in the config.
+type AccessConfig struct {
+	Target         string   `toml:"target"`          // "" (public) | "netlify" | "cloudflare" | "htpasswd"
+	CredentialsEnv string   `toml:"credentials_env"` // Variable holding "user:password" pairs; empty = access.DefaultCredentialsEnv
+	Emails         []string `toml:"emails"`          // cloudflare: emails or "@domain"s the Access policy allows
+}
+
+// ResolveCredentialsEnv returns the environment variable holding the
+// credentials, defaulting to access.DefaultCredentialsEnv when
+// credentials_env is not set in config.toml.
+func (a AccessConfig) ResolveCredentialsEnv() string {
+	if a.CredentialsEnv == "" {
+		return access.DefaultCredentialsEnv
+	}
+	return a.CredentialsEnv
+}
+
+// ResolveSignPrefix returns the sign prefix, defaulting to
+// markers.DefaultSignPrefix when the field is not set in config.toml.
+func (m MarkersConfig) ResolveSignPrefix() string {
+	if m.SignPrefix == "" {
+		return markers.DefaultSignPrefix
+	}
+	return m.SignPrefix
+}
+
+// ResolveFormat returns the marker output format, defaulting to
+// markers.FormatJSON when the field is not set in config.toml.
+func (m MarkersConfig) ResolveFormat() string {
+	if m.Format == "" {
+		return markers.FormatJSON
+	}
+	return m.Format
+}
+
+// Codecs returns the configured codec for each enabled file class.
+func (c CompressionConfig) Codecs() (map[string]compress.Codec, error) {
+	codecs := make(map[string]compress.Codec)
+	for class, algorithm := range map[string]string{
+		compress.ClassAssets: c.Assets,
+		compress.ClassData:   c.Data,
+	} {
+		if algorithm == "" {
+			continue
+		}
+		codec, err := compress.New(algorithm, c.Level)
+		if err != nil {
+			return nil, fmt.Errorf("compression.%s: %w", class, err)
+		}
+		codecs[class] = codec
+	}
+	return codecs, nil
+}
+
+// ResolveSecurityHeaders reports whether security headers should be written
+// into the hosting config, defaulting to true when the field is not set.
+func (c *ServerConfig) ResolveSecurityHeaders() bool {
+	if c.SecurityHeaders == nil {
+		return true
+	}
+	return *c.SecurityHeaders
+}
+
+// ResolveFailOnMissingWorlds reports whether a world missing from the backup
+// aborts the run, defaulting to true when the field is not set.
+func (c *ServerConfig) ResolveFailOnMissingWorlds() bool {
+	if c.FailOnMissingWorlds == nil {
+		return true
+	}
+	return *c.FailOnMissingWorlds
+}
+
+// ResolveFailOverBudget reports whether going over max_world_size or
+// max_web_size aborts the run rather than warning, defaulting to true when
+// the field is not set.
+func (c *ServerConfig) ResolveFailOverBudget() bool {
+	if c.FailOverBudget == nil {
+		return true
+	}
+	return *c.FailOverBudget
+}
+
+// ResolveBudgets returns the size budgets of the extracted worlds and of
+// web/, each 0 when not set.
+func (c *ServerConfig) ResolveBudgets() (world, web int64) {
+	world, _ = parseByteSize(c.MaxWorldSize)
+	web, _ = parseByteSize(c.MaxWebSize)
+	return world, web
+}
+
+// ResolveRenderTimeouts returns the render watchdog stall timeout and hard
+// timeout. Unset fields resolve to 0 (disabled).
+func (c *ServerConfig) ResolveRenderTimeouts() (stall, total time.Duration) {
+	stall, _ = parseOptionalDuration(c.RenderStallTimeout)
+	total, _ = parseOptionalDuration(c.RenderTimeout)
+	return stall, total
+}
+
+// ResolveMods returns the [[mods]] entries for bluemap.InstallMods.
+func (c *ServerConfig) ResolveMods() []bluemap.Mod {
+	mods := make([]bluemap.Mod, len(c.Mods))
+	for i, m := range c.Mods {
+		mods[i] = bluemap.Mod{URL: m.URL, Modrinth: m.Modrinth, Version: m.Version, Loader: m.Loader, SHA256: m.SHA256}
+	}
+	return mods
+}
+
+// ResolveRenderProgress returns how often the render prints a status line
+// in place of the raw BlueMap output; 0 streams the raw output.
+func (c *ServerConfig) ResolveRenderProgress() time.Duration {
+	d, _ := parseOptionalDuration(c.RenderProgress)
+	return d
+}
+
+// parseOptionalDuration parses a Go duration string, treating "" as 0.
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
+}
+
+// DefaultTimeFormat is the render timestamp layout used when time_format is
+// not set in config.toml.
+const DefaultTimeFormat = "2006-01-02 15:04 MST"
+
+// layoutReference is formatted with time_format to check that the layout
+// contains at least one date or time element.
+var layoutReference = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+// ResolveTimezone returns the location of the render timestamp, defaulting
+// to UTC when timezone is not set in config.toml.
+func (c *ServerConfig) ResolveTimezone() *time.Location {
+	if c.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC // rejected by Load
+	}
+	return loc
+}
+
+// ResolveTimeFormat returns the render timestamp layout, defaulting to
+// DefaultTimeFormat when time_format is not set in config.toml.
+func (c *ServerConfig) ResolveTimeFormat() string {
+	if c.TimeFormat == "" {
+		return DefaultTimeFormat
+	}
+	return c.TimeFormat
+}
+
+// FormatTime formats t as a render timestamp in the configured timezone and
+// layout.
+func (c *ServerConfig) FormatTime(t time.Time) string {
+	return t.In(c.ResolveTimezone()).Format(c.ResolveTimeFormat())
+}
+
+// ResolveLocaleTimeFormat returns the timestamp layout of the language file
+// for locale, such as "zh-TW": its [time_formats] entry, or the one of its
+// language ("zh"); then time_format; then the layout lang.TimeFormats gives
+// the embedded file, and DefaultTimeFormat.
+func (c *ServerConfig) ResolveLocaleTimeFormat(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	if layout, ok := c.TimeFormats[locale]; ok {
+		return layout
+	}
+	if layout, ok := c.TimeFormats[language]; ok {
+		return layout
+	}
+	if c.TimeFormat != "" {
+		return c.TimeFormat
+	}
+	if layout, ok := lang.TimeFormats[locale]; ok {
+		return layout
+	}
+	return DefaultTimeFormat
+}
+
+// FormatLocaleTime formats t in the configured timezone with the layout of
+// the language file for locale.
+func (c *ServerConfig) FormatLocaleTime(locale string, t time.Time) string {
+	return t.In(c.ResolveTimezone()).Format(c.ResolveLocaleTimeFormat(locale))
+}
+
+// checkJarURL checks a bluemap_download_url or bluemap_mirrors entry; "" is
+// allowed and means unset.
+func checkJarURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	expanded := strings.NewReplacer("{version}", "5.0", "{jar}", "bluemap-5.0-cli.jar").Replace(s)
+	if u, err := url.Parse(expanded); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("bluemap_download_url and bluemap_mirrors must be http(s) URLs such as \"https://mirror.example.com/bluemap/v{version}/{jar}\", got %q", s)
+	}
+	return nil
 }
 
 // ResolveDownloadMode returns the effective download mode, defaulting to
@@ -40,52 +854,308 @@ func (c *ServerConfig) ResolveDownloadMode() string {
 	return c.DownloadMode
 }
 
+// ResolveStorage returns the storage, defaulting to StorageFile when
+// storage is not set in config.toml.
+func (c *ServerConfig) ResolveStorage() string {
+	if c.Storage == "" {
+		return StorageFile
+	}
+	return c.Storage
+}
+
+// ResolveDeployTarget returns the deploy target, defaulting to
+// DeployTargetNetlify when deploy_target is not set in config.toml.
+func (c *ServerConfig) ResolveDeployTarget() string {
+	if c.DeployTarget == "" {
+		return DeployTargetNetlify
+	}
+	return c.DeployTarget
+}
+
+// NeedsCompressedRefs reports whether the deploy target serves files only
+// under their literal names, so the webapp's loader URLs must point at the
+// .gz files (see assets.RewriteCompressedRefs).
+func (c *ServerConfig) NeedsCompressedRefs() bool {
+	target := c.ResolveDeployTarget()
+	return target != DeployTargetStatic && target != DeployTargetSSH
+}
+
+// ResolveRegionCheck returns the region file check mode, defaulting to
+// mca.ModeReport when the field is not set in config.toml.
+func (c *ServerConfig) ResolveRegionCheck() string {
+	if c.RegionCheck == "" {
+		return mca.ModeReport
+	}
+	return c.RegionCheck
+}
+
 // ResolveDownloadConnections returns the configured number of download
 // connections. A value of 0 means automatic (scale by file size).
 func (c *ServerConfig) ResolveDownloadConnections() int {
 	return c.DownloadConnections
 }
 
-// ResolveWorlds returns the list of world folder names to extract from the
-// backup, derived from ServerType and WorldName.
-//
-// For vanilla servers, dimensions are stored as subdirectories within a single
-// world folder (world/DIM-1, world/DIM1), so only one folder is needed.
-//
-// For plugin servers (Bukkit/Spigot/Paper), each dimension is a separate
-// top-level folder (world, world_nether, world_the_end).
-//
-// For unified servers (Minecraft 26.1+, vanilla and plugin alike), every
-// dimension lives under a single world folder at
-// world/dimensions/<namespace>/<dimension> (e.g.
-// world/dimensions/minecraft/the_nether), so only one folder is needed.
-func (c *ServerConfig) ResolveWorlds() []string {
-	name := c.WorldName
-	if name == "" {
-		name = "world"
+// ResolveDownloadRateLimit returns the download bandwidth limit in bytes per
+// second, or 0 when download_rate_limit is not set.
+func (c *ServerConfig) ResolveDownloadRateLimit() int64 {
+	rate, _ := parseRate(c.DownloadRateLimit)
+	return rate
+}
+
+// ResolveDownloadBuffer returns the parallel-stream buffer size in bytes, or
+// 0 when download_buffer is not set.
+func (c *ServerConfig) ResolveDownloadBuffer() int64 {
+	size, _ := parseByteSize(c.DownloadBuffer)
+	return size
+}
+
+// parseRate parses a bandwidth such as "50MiB/s" or "10MB", treating "" as 0.
+// The "/s" suffix is optional.
+func parseRate(s string) (int64, error) {
+	return parseByteSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+}
+
+// ResolveDecompression returns the gzip block size in bytes and the number of
+// blocks decompressed ahead of the tar reader. Unset fields resolve to 0, which
+// the extractor replaces with pgzip's defaults.
+func (c *ServerConfig) ResolveDecompression() (blockSize, blocks int) {
+	size, _ := parseByteSize(c.DecompressBlockSize)
+	return int(size), c.DecompressBlocks
+}
+
+// ResolveExtractLimits returns the caps on a single extracted file, on all
+// extracted files together and on the number of extracted entries. Unset
+// fields resolve to 0, which the extractor replaces with its defaults.
+func (c *ServerConfig) ResolveExtractLimits() (maxFile, maxTotal int64, maxEntries int) {
+	maxFile, _ = parseByteSize(c.MaxFileSize)
+	maxTotal, _ = parseByteSize(c.MaxTotalSize)
+	return maxFile, maxTotal, c.MaxEntries
+}
+
+// validateHosting checks the [hosting] table.
+func validateHosting(h HostingConfig) error {
+	if h.Plan != "" && h.Plan != HostingPlanNetlifyFree && h.Plan != HostingPlanCloudflarePagesFree {
+		return fmt.Errorf("hosting.plan must be %q or %q, got %q", HostingPlanNetlifyFree, HostingPlanCloudflarePagesFree, h.Plan)
+	}
+	if h.MaxFiles < 0 || h.MonthlyVisits < 0 {
+		return fmt.Errorf("hosting.max_files and hosting.monthly_visits must not be negative")
+	}
+	if _, err := parseByteSize(h.MaxFileSize); err != nil {
+		return fmt.Errorf("hosting.max_file_size: %w", err)
 	}
+	if _, err := parseByteSize(h.MonthlyBandwidth); err != nil {
+		return fmt.Errorf("hosting.monthly_bandwidth: %w", err)
+	}
+	return nil
+}
 
-	switch c.ServerType {
-	case ServerTypeVanilla, ServerTypeUnified:
-		return []string{name}
-	case ServerTypePlugin:
-		return []string{
-			name,
-			name + "_nether",
-			name + "_the_end",
+// parseByteSize parses a byte size such as "512KiB", "1MiB", "1.5MB" or
+// "4096", treating "" as 0. Both binary (KiB, MiB, GiB) and decimal (kB, MB,
+// GB) units are accepted; a bare K, M or G is binary.
+func parseByteSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	num := strings.TrimRightFunc(strings.TrimSpace(s), func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit := strings.TrimSpace(strings.TrimSpace(s)[len(num):])
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mult, ok := byteUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q in %q", unit, s)
+	}
+	return int64(v * float64(mult)), nil
+}
+
+// byteUnits maps lower-cased size units to their multiplier.
+var byteUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kib": 1 << 10, "kb": 1e3,
+	"m": 1 << 20, "mib": 1 << 20, "mb": 1e6,
+	"g": 1 << 30, "gib": 1 << 30, "gb": 1e9,
+}
+
+// ResolveWorldConfigs returns the worlds to process. When worlds is not set, a
+// single world is derived from world_name and server_type. Worlds without a
+// type inherit server_type, worlds without bounds inherit render_bounds, and
+// skipped worlds are left out.
+func (c *ServerConfig) ResolveWorldConfigs() []WorldConfig {
+	if len(c.Worlds) == 0 {
+		name := c.WorldName
+		if name == "" {
+			name = "world"
 		}
-	default:
-		return []string{name}
+		return []WorldConfig{{Name: name, Type: c.ServerType, Dimensions: c.Dimensions, Bounds: c.RenderBounds}}
+	}
+
+	var worlds []WorldConfig
+	for _, w := range c.Worlds {
+		if w.Skip {
+			continue
+		}
+		if w.Type == "" {
+			w.Type = c.ServerType
+		}
+		if w.Bounds == nil {
+			w.Bounds = c.RenderBounds
+		}
+		worlds = append(worlds, w)
+	}
+	return worlds
+}
+
+// ResolveMaps returns the map IDs to render. Top-level maps (or -maps) win;
+// otherwise, when any world lists maps, the maps of the worlds that are not
+// skipped are rendered. An empty result means all maps.
+func (c *ServerConfig) ResolveMaps() []string {
+	if len(c.Maps) > 0 {
+		return c.Maps
+	}
+	perWorld := false
+	for _, w := range c.Worlds {
+		perWorld = perWorld || len(w.Maps) > 0
+	}
+	if !perWorld {
+		return nil
+	}
+	var maps []string
+	for _, w := range c.ResolveWorldConfigs() {
+		maps = append(maps, w.Maps...)
 	}
+	return maps
+}
+
+// mapDimensions are the BlueMap dimension keys of the dimensions accepted in
+// world dimensions.
+var mapDimensions = map[string]string{
+	"minecraft:overworld":  DimensionOverworld,
+	"minecraft:the_nether": DimensionNether,
+	"minecraft:the_end":    DimensionEnd,
+}
+
+// ResolveRenderMaps returns the map IDs to render, like ResolveMaps, without
+// the maps in dir/config/maps that show a dimension their world leaves out,
+// which are returned as skipped. When maps would be all maps, the remaining
+// ones are listed instead.
+func (c *ServerConfig) ResolveRenderMaps(dir string) (maps, skipped []string, err error) {
+	maps = c.ResolveMaps()
+	confs, err := filepath.Glob(filepath.Join(dir, "config", "maps", "*.conf"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(confs)
+	worlds := c.ResolveWorldConfigs()
+	var all []string
+	for _, conf := range confs {
+		data, err := os.ReadFile(conf)
+		if err != nil {
+			return nil, nil, err
+		}
+		id := strings.TrimSuffix(filepath.Base(conf), ".conf")
+		all = append(all, id)
+		world, dimension := bluemap.MapWorld(data)
+		if world == "" {
+			continue
+		}
+		dim, ok := mapDimensions[dimension]
+		if !ok {
+			continue
+		}
+		folder := filepath.Base(filepath.FromSlash(world))
+		for _, w := range worlds {
+			if w.ownsFolder(folder) && !w.HasDimension(dim) {
+				skipped = append(skipped, id)
+				break
+			}
+		}
+	}
+	if len(skipped) == 0 {
+		return maps, nil, nil
+	}
+	if len(maps) == 0 {
+		maps = all
+	}
+	var kept []string
+	for _, id := range maps {
+		if !slices.Contains(skipped, id) {
+			kept = append(kept, id)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, nil, fmt.Errorf("every map to render shows a dimension left out by dimensions (%s)", strings.Join(skipped, ", "))
+	}
+	return kept, skipped, nil
+}
+
+// ownsFolder reports whether folder is one of the world's dimension folders,
+// including those Folders leaves out.
+func (w WorldConfig) ownsFolder(folder string) bool {
+	if w.Type == ServerTypePlugin {
+		return folder == w.Name || folder == w.Name+"_nether" || folder == w.Name+"_the_end"
+	}
+	return folder == w.Name
+}
+
+// ResolveWorlds returns the list of world folder names to extract from the
+// backup across all worlds (see WorldConfig.Folders).
+func (c *ServerConfig) ResolveWorlds() []string {
+	var folders []string
+	seen := make(map[string]bool)
+	for _, w := range c.ResolveWorldConfigs() {
+		for _, f := range w.Folders() {
+			if !seen[f] {
+				seen[f] = true
+				folders = append(folders, f)
+			}
+		}
+	}
+	return folders
+}
+
+// LoadedServer holds a parsed config along with its directory path.
+type LoadedServer struct {
+	Dir    string
+	Config ServerConfig
+
+	// EnvOverrides lists the TOML keys set from BLUEMAP_ACTION_*
+	// environment variables instead of config.toml.
+	EnvOverrides []string
+}
+
+// PanelRef is the part of a config.toml read by ReadPanel.
+type PanelRef struct {
+	ServerID  string
+	PanelType string
+	ProxyURL  string
 }
 
-// LoadedServer holds a parsed config along with its directory path.
-type LoadedServer struct {
-	Dir    string
-	Config ServerConfig
+// ReadPanel returns server_id, panel_type and proxy_url from the config.toml
+// in dir without validating the rest of the file, for commands such as
+// inspect-backup that help fill in an incomplete config.
+func ReadPanel(dir string) (PanelRef, error) {
+	configPath := filepath.Join(dir, "config.toml")
+
+	var cfg struct {
+		ServerID  string `toml:"server_id"`
+		PanelType string `toml:"panel_type"`
+		ProxyURL  string `toml:"proxy_url"`
+	}
+	if _, err := toml.DecodeFile(configPath, &cfg); err != nil {
+		return PanelRef{}, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+	if cfg.ServerID == "" {
+		return PanelRef{}, fmt.Errorf("%s: server_id is required", configPath)
+	}
+	return PanelRef{ServerID: cfg.ServerID, PanelType: cfg.PanelType, ProxyURL: cfg.ProxyURL}, nil
 }
 
 // Load reads and validates a single config.toml from the given directory.
+// BLUEMAP_ACTION_* environment variables override the file's fields before
+// validation (see EnvPrefix).
 func Load(dir string) (LoadedServer, error) {
 	configPath := filepath.Join(dir, "config.toml")
 
@@ -93,18 +1163,43 @@ func Load(dir string) (LoadedServer, error) {
 	if _, err := toml.DecodeFile(configPath, &cfg); err != nil {
 		return LoadedServer{}, fmt.Errorf("parsing %s: %w", configPath, err)
 	}
+	overrides, err := applyEnv(&cfg)
+	if err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: environment override %w", configPath, err)
+	}
 
 	if cfg.ServerID == "" {
 		return LoadedServer{}, fmt.Errorf("%s: server_id is required", configPath)
 	}
+	switch cfg.PanelType {
+	case "", panel.TypePterodactyl, panel.TypePufferPanel, panel.TypeCrafty, panel.TypeAMP:
+	default:
+		return LoadedServer{}, fmt.Errorf("%s: panel_type must be %q, %q, %q, or %q, got %q",
+			configPath, panel.TypePterodactyl, panel.TypePufferPanel, panel.TypeCrafty, panel.TypeAMP, cfg.PanelType)
+	}
 	if cfg.ServerType == "" {
 		return LoadedServer{}, fmt.Errorf("%s: server_type is required (\"vanilla\", \"plugin\", or \"unified\")", configPath)
 	}
 	if cfg.ServerType != ServerTypeVanilla && cfg.ServerType != ServerTypePlugin && cfg.ServerType != ServerTypeUnified {
 		return LoadedServer{}, fmt.Errorf("%s: server_type must be \"vanilla\", \"plugin\", or \"unified\", got %q", configPath, cfg.ServerType)
 	}
-	if cfg.WorldName == "" {
-		return LoadedServer{}, fmt.Errorf("%s: world_name is required", configPath)
+	if cfg.WorldName == "" && len(cfg.Worlds) == 0 {
+		return LoadedServer{}, fmt.Errorf("%s: world_name or worlds is required", configPath)
+	}
+	if cfg.WorldName != "" && len(cfg.Worlds) > 0 {
+		return LoadedServer{}, fmt.Errorf("%s: world_name and worlds are mutually exclusive", configPath)
+	}
+	if len(cfg.Dimensions) > 0 && len(cfg.Worlds) > 0 {
+		return LoadedServer{}, fmt.Errorf("%s: dimensions applies to world_name; set it per world in worlds", configPath)
+	}
+	if err := checkDimensions(cfg.Dimensions); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := checkWorlds(dir, cfg.Worlds); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if len(cfg.Worlds) > 0 && len(cfg.ResolveWorldConfigs()) == 0 {
+		return LoadedServer{}, fmt.Errorf("%s: every world is skipped", configPath)
 	}
 	if cfg.MinecraftVersion == "" {
 		return LoadedServer{}, fmt.Errorf("%s: mc_version is required", configPath)
@@ -112,48 +1207,751 @@ func Load(dir string) (LoadedServer, error) {
 	if cfg.BlueMapVersion == "" {
 		return LoadedServer{}, fmt.Errorf("%s: bluemap_version is required", configPath)
 	}
+	if cfg.BlueMapSHA256 != "" && !isHexDigest(cfg.BlueMapSHA256, 64) {
+		return LoadedServer{}, fmt.Errorf("%s: bluemap_sha256 must be a 64-character hex SHA-256 digest", configPath)
+	}
+	if cfg.BlueMapSHA256 != "" && bluemap.IsDynamicVersion(cfg.BlueMapVersion) {
+		return LoadedServer{}, fmt.Errorf("%s: bluemap_sha256 pins one jar and cannot be used with bluemap_version %q, which moves to new releases; pin a concrete version", configPath, cfg.BlueMapVersion)
+	}
+	if cfg.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: timezone must be an IANA time zone such as \"Asia/Taipei\", got %q", configPath, cfg.Timezone)
+		}
+	}
+	if cfg.TimeFormat != "" && layoutReference.Format(cfg.TimeFormat) == cfg.TimeFormat {
+		return LoadedServer{}, fmt.Errorf("%s: time_format must be a Go time layout such as %q, got %q", configPath, DefaultTimeFormat, cfg.TimeFormat)
+	}
+	if err := checkTimeFormats(cfg.TimeFormats); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.MapURL != "" {
+		if u, err := url.Parse(cfg.MapURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return LoadedServer{}, fmt.Errorf("%s: map_url must be an http(s) URL such as \"https://map.example.com\", got %q", configPath, cfg.MapURL)
+		}
+	}
+	for _, u := range append([]string{cfg.BlueMapDownloadURL}, cfg.BlueMapMirrors...) {
+		if err := checkJarURL(u); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+		}
+	}
+	if cfg.WebappVersion != "" && cfg.WebappURL != "" {
+		return LoadedServer{}, fmt.Errorf("%s: webapp_version and webapp_url are mutually exclusive", configPath)
+	}
+	if cfg.WebappURL != "" {
+		if u, err := url.Parse(cfg.WebappURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return LoadedServer{}, fmt.Errorf("%s: webapp_url must be an http(s) URL of a webapp zip or BlueMap jar, got %q", configPath, cfg.WebappURL)
+		}
+	}
+	for i, m := range cfg.Mods {
+		if err := m.validate(); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: mods[%d]: %w", configPath, i, err)
+		}
+	}
+	packNames := make(map[string]string, len(cfg.ResourcePacks))
+	for _, entry := range cfg.ResourcePacks {
+		name, err := bluemap.ResourcePackName(entry)
+		if err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: resourcepacks: %w", configPath, err)
+		}
+		if other, ok := packNames[name]; ok {
+			return LoadedServer{}, fmt.Errorf("%s: resourcepacks: %q and %q would both be installed as %s", configPath, other, entry, name)
+		}
+		packNames[name] = entry
+	}
+	if cfg.WebhookURL != "" {
+		// The URL often embeds a token, so it is not echoed.
+		if u, err := url.Parse(cfg.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return LoadedServer{}, fmt.Errorf("%s: webhook_url must be an http(s) URL", configPath)
+		}
+	}
+	if err := checkPlaceholders(cfg.Placeholders); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.MaxMemory != "" && !isMemorySize(cfg.MaxMemory) {
+		return LoadedServer{}, fmt.Errorf("%s: max_memory must be a JVM size such as \"4096m\" or \"6G\", got %q", configPath, cfg.MaxMemory)
+	}
+	if _, err := parseOptionalDuration(cfg.RenderStallTimeout); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: render_stall_timeout: %w", configPath, err)
+	}
+	if _, err := parseOptionalDuration(cfg.RenderTimeout); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: render_timeout: %w", configPath, err)
+	}
+	if d, err := parseOptionalDuration(cfg.RenderProgress); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: render_progress: %w", configPath, err)
+	} else if cfg.RenderProgress != "" && d < 10*time.Second {
+		return LoadedServer{}, fmt.Errorf("%s: render_progress must be at least 10s, got %q", configPath, cfg.RenderProgress)
+	}
 	if cfg.DownloadMode != "" &&
 		cfg.DownloadMode != DownloadModeAuto &&
 		cfg.DownloadMode != DownloadModeParallel &&
-		cfg.DownloadMode != DownloadModeSingle {
+		cfg.DownloadMode != DownloadModeParallelStream &&
+		cfg.DownloadMode != DownloadModeSingle &&
+		cfg.DownloadMode != DownloadModeIndexed {
 		return LoadedServer{}, fmt.Errorf(
-			"%s: download_mode must be %q, %q, or %q, got %q",
-			configPath, DownloadModeAuto, DownloadModeParallel, DownloadModeSingle, cfg.DownloadMode)
+			"%s: download_mode must be %q, %q, %q, %q, or %q, got %q",
+			configPath, DownloadModeAuto, DownloadModeParallel, DownloadModeParallelStream, DownloadModeSingle, DownloadModeIndexed, cfg.DownloadMode)
+	}
+	if size, err := parseByteSize(cfg.DownloadBuffer); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: download_buffer: %w", configPath, err)
+	} else if cfg.DownloadBuffer != "" && (size < 16<<20 || size > 16<<30) {
+		return LoadedServer{}, fmt.Errorf("%s: download_buffer must be between 16MiB and 16GiB, got %q", configPath, cfg.DownloadBuffer)
+	} else if cfg.DownloadBuffer != "" && cfg.ResolveDownloadMode() != DownloadModeParallelStream {
+		return LoadedServer{}, fmt.Errorf("%s: download_buffer requires download_mode = %q", configPath, DownloadModeParallelStream)
+	}
+	if cfg.Storage != "" && cfg.Storage != StorageFile && cfg.Storage != StorageSQLite {
+		return LoadedServer{}, fmt.Errorf("%s: storage must be %q or %q, got %q", configPath, StorageFile, StorageSQLite, cfg.Storage)
+	}
+	switch cfg.DeployTarget {
+	case "", DeployTargetNetlify, DeployTargetStatic, DeployTargetSSH, DeployTargetFTP, DeployTargetS3:
+	default:
+		return LoadedServer{}, fmt.Errorf("%s: deploy_target must be %q, %q, %q, %q, or %q, got %q",
+			configPath, DeployTargetNetlify, DeployTargetStatic, DeployTargetSSH, DeployTargetFTP, DeployTargetS3, cfg.DeployTarget)
+	}
+	if err := checkSSH(dir, cfg.ResolveDeployTarget(), cfg.SSH); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := checkFTP(cfg.ResolveDeployTarget(), cfg.FTP); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := checkS3(cfg.ResolveDeployTarget(), cfg.S3); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.Watch.Schedule != "" {
+		if _, err := schedule.Parse(cfg.Watch.Schedule); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: watch.schedule: %w", configPath, err)
+		}
+	}
+	if l := cfg.Watch.Listen; l != "" && l != "off" {
+		if _, _, err := net.SplitHostPort(l); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: watch.listen must be a host:port address such as \":8080\" or \"off\", got %q", configPath, l)
+		}
 	}
 	if cfg.DownloadConnections < 0 || cfg.DownloadConnections > 32 {
 		return LoadedServer{}, fmt.Errorf(
 			"%s: download_connections must be between 0 and 32, got %d",
 			configPath, cfg.DownloadConnections)
 	}
+	if cfg.ProxyURL != "" {
+		if err := proxy.Validate(cfg.ProxyURL); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: proxy_url: %w", configPath, err)
+		}
+	}
+	if rate, err := parseRate(cfg.DownloadRateLimit); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: download_rate_limit: %w", configPath, err)
+	} else if cfg.DownloadRateLimit != "" && rate < 64<<10 {
+		return LoadedServer{}, fmt.Errorf("%s: download_rate_limit must be at least 64KiB/s, got %q", configPath, cfg.DownloadRateLimit)
+	}
+	if size, err := parseByteSize(cfg.DecompressBlockSize); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: decompress_block_size: %w", configPath, err)
+	} else if cfg.DecompressBlockSize != "" && (size < 64<<10 || size > 64<<20) {
+		return LoadedServer{}, fmt.Errorf("%s: decompress_block_size must be between 64KiB and 64MiB, got %q", configPath, cfg.DecompressBlockSize)
+	}
+	if cfg.ExtractWorkers < 0 || cfg.ExtractWorkers > 64 {
+		return LoadedServer{}, fmt.Errorf(
+			"%s: extract_workers must be between 0 and 64, got %d",
+			configPath, cfg.ExtractWorkers)
+	}
+	if cfg.DecompressBlocks < 0 || cfg.DecompressBlocks > 256 {
+		return LoadedServer{}, fmt.Errorf(
+			"%s: decompress_blocks must be between 0 and 256, got %d",
+			configPath, cfg.DecompressBlocks)
+	}
+	for _, limit := range []struct{ key, value string }{
+		{"max_file_size", cfg.MaxFileSize},
+		{"max_total_size", cfg.MaxTotalSize},
+		{"max_world_size", cfg.MaxWorldSize},
+		{"max_web_size", cfg.MaxWebSize},
+	} {
+		if size, err := parseByteSize(limit.value); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: %s: %w", configPath, limit.key, err)
+		} else if limit.value != "" && size < 1<<20 {
+			return LoadedServer{}, fmt.Errorf("%s: %s must be at least 1MiB, got %q", configPath, limit.key, limit.value)
+		}
+	}
+	if err := validateHosting(cfg.Hosting); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.MaxEntries < 0 {
+		return LoadedServer{}, fmt.Errorf("%s: max_entries must not be negative, got %d", configPath, cfg.MaxEntries)
+	}
+
+	if _, err := cfg.Compression.Codecs(); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.Compression.Workers < 0 {
+		return LoadedServer{}, fmt.Errorf("%s: compression.workers must not be negative, got %d", configPath, cfg.Compression.Workers)
+	}
+	for _, c := range []struct{ key, value string }{{"assets", cfg.Cache.Assets}, {"tiles", cfg.Cache.Tiles}, {"data", cfg.Cache.Data}} {
+		if strings.ContainsAny(c.value, "\r\n") {
+			return LoadedServer{}, fmt.Errorf("%s: cache.%s must be a single line", configPath, c.key)
+		}
+	}
+	if strings.ContainsAny(cfg.ContentSecurityPolicy, "\r\n") {
+		return LoadedServer{}, fmt.Errorf("%s: content_security_policy must be a single line", configPath)
+	}
+	if strings.ContainsAny(cfg.AnnounceCommand, "\r\n") {
+		return LoadedServer{}, fmt.Errorf("%s: announce_command must be a single line", configPath)
+	}
+	if cfg.PruneTiles != prune.ModeOff && cfg.PruneTiles != prune.ModeDryRun && cfg.PruneTiles != prune.ModeDelete {
+		return LoadedServer{}, fmt.Errorf("%s: prune_tiles must be %q or %q, got %q",
+			configPath, prune.ModeDryRun, prune.ModeDelete, cfg.PruneTiles)
+	}
+	if cfg.RegionCheck != "" && cfg.RegionCheck != mca.ModeReport && cfg.RegionCheck != mca.ModeQuarantine && cfg.RegionCheck != mca.ModeOff {
+		return LoadedServer{}, fmt.Errorf("%s: region_check must be %q, %q, or %q, got %q",
+			configPath, mca.ModeReport, mca.ModeQuarantine, mca.ModeOff, cfg.RegionCheck)
+	}
+	if b := cfg.RenderBounds; b != nil && !b.valid() {
+		return LoadedServer{}, fmt.Errorf("%s: render_bounds min_x/min_z must not exceed max_x/max_z", configPath)
+	}
+	if err := validateExtraPaths(cfg.ExtraPaths, cfg.ResolveWorldConfigs()); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: extra_paths: %w", configPath, err)
+	}
+	for _, target := range cfg.Cleanup {
+		if !slices.Contains(cleanup.Targets, target) {
+			return LoadedServer{}, fmt.Errorf("%s: cleanup: unknown target %q (available: %s)",
+				configPath, target, strings.Join(cleanup.Targets, ", "))
+		}
+	}
+	for _, id := range cfg.Markers.Sources {
+		if _, ok := markers.Lookup(id); !ok {
+			return LoadedServer{}, fmt.Errorf("%s: markers.sources: unknown source %q (available: %s)",
+				configPath, id, strings.Join(markers.SourceIDs(), ", "))
+		}
+	}
+	if f := cfg.Markers.Format; f != "" && f != markers.FormatJSON && f != markers.FormatHOCON {
+		return LoadedServer{}, fmt.Errorf("%s: markers.format must be %q or %q, got %q",
+			configPath, markers.FormatJSON, markers.FormatHOCON, f)
+	}
+	if p := cfg.Markers.SignPrefix; p != "" && strings.TrimSpace(p) == "" {
+		return LoadedServer{}, fmt.Errorf("%s: markers.sign_prefix must not be blank", configPath)
+	}
+	staticSets, err := markers.LoadStatic(dir)
+	if err != nil {
+		return LoadedServer{}, err
+	}
+	for _, set := range staticSets {
+		if err := CheckMaps(dir, set.Maps); err != nil {
+			return LoadedServer{}, fmt.Errorf("%s: sets.%s.maps: %w", markers.StaticPath(dir), set.ID, err)
+		}
+	}
+	if err := checkBranding(dir, cfg.Branding); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := checkWebapp(dir, &cfg); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := checkAccess(cfg.Access, cfg.ResolveDeployTarget()); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.PauseSaves && !cfg.FreshBackup {
+		return LoadedServer{}, fmt.Errorf("%s: pause_saves requires fresh_backup = true", configPath)
+	}
+	if cfg.FlushSaves && !cfg.FreshBackup {
+		return LoadedServer{}, fmt.Errorf("%s: flush_saves requires fresh_backup = true", configPath)
+	}
+	if cfg.FlushSaves && cfg.PauseSaves {
+		return LoadedServer{}, fmt.Errorf("%s: flush_saves and pause_saves cannot both be set; pause_saves already flushes", configPath)
+	}
+	if cfg.PanelType != "" && cfg.PanelType != panel.TypePterodactyl {
+		// All need the Pterodactyl console websocket or backup locks.
+		if cfg.PauseSaves {
+			return LoadedServer{}, fmt.Errorf("%s: pause_saves is not supported with panel_type = %q", configPath, cfg.PanelType)
+		}
+		if cfg.FlushSaves {
+			return LoadedServer{}, fmt.Errorf("%s: flush_saves is not supported with panel_type = %q", configPath, cfg.PanelType)
+		}
+		if cfg.LockBackup {
+			return LoadedServer{}, fmt.Errorf("%s: lock_backup is not supported with panel_type = %q", configPath, cfg.PanelType)
+		}
+		if cfg.AnnounceCommand != "" {
+			return LoadedServer{}, fmt.Errorf("%s: announce_command is not supported with panel_type = %q", configPath, cfg.PanelType)
+		}
+	}
+	if err := checkRetention(cfg); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if cfg.SkipIfUnchanged && cfg.FreshBackup {
+		return LoadedServer{}, fmt.Errorf("%s: skip_if_unchanged has no effect with fresh_backup, which creates a new backup every run", configPath)
+	}
+	if err := CheckMaps(dir, cfg.Maps); err != nil {
+		return LoadedServer{}, fmt.Errorf("%s: maps: %w", configPath, err)
+	}
 
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return LoadedServer{}, fmt.Errorf("resolving path %s: %w", dir, err)
 	}
 
-	return LoadedServer{Dir: absDir, Config: cfg}, nil
+	return LoadedServer{Dir: absDir, Config: cfg, EnvOverrides: overrides}, nil
 }
 
-// LoadAll scans the given base directory for subdirectories containing a
-// config.toml and returns all parsed configs.
-func LoadAll(baseDir string) ([]LoadedServer, error) {
+// checkWorlds validates the worlds entries.
+func checkWorlds(dir string, worlds []WorldConfig) error {
+	names := make(map[string]bool)
+	sources := make(map[string]string)
+	for i, w := range worlds {
+		if w.Name == "" {
+			return fmt.Errorf("worlds[%d]: name is required", i)
+		}
+		if strings.ContainsAny(w.Name, "/\\") {
+			return fmt.Errorf("worlds[%d]: name must be a top-level folder name, got %q", i, w.Name)
+		}
+		if names[w.Name] {
+			return fmt.Errorf("worlds[%d]: duplicate world %q", i, w.Name)
+		}
+		names[w.Name] = true
+		label := "worlds." + w.Name
+		if w.Type != "" && w.Type != ServerTypeVanilla && w.Type != ServerTypePlugin && w.Type != ServerTypeUnified {
+			return fmt.Errorf("%s: type must be \"vanilla\", \"plugin\", or \"unified\", got %q", label, w.Type)
+		}
+		if err := checkDimensions(w.Dimensions); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		if s := w.Source; s != "" && !isBackupPath(s) {
+			return fmt.Errorf("%s: source must be a relative path inside the backup, got %q", label, s)
+		}
+		for folder, src := range w.BackupFolders() {
+			for other, otherFolder := range sources {
+				if src == other {
+					return fmt.Errorf("%s: backup folder %q is already used by %q", label, src, otherFolder)
+				}
+				if strings.HasPrefix(src, other+"/") || strings.HasPrefix(other, src+"/") {
+					return fmt.Errorf("%s: backup folder %q overlaps %q of %q", label, src, other, otherFolder)
+				}
+			}
+			sources[src] = folder
+		}
+		if b := w.Bounds; b != nil && !b.valid() {
+			return fmt.Errorf("%s: bounds min_x/min_z must not exceed max_x/max_z", label)
+		}
+		if err := CheckMaps(dir, w.Maps); err != nil {
+			return fmt.Errorf("%s: maps: %w", label, err)
+		}
+	}
+	return nil
+}
+
+// checkDimensions validates a dimensions list.
+func checkDimensions(dims []string) error {
+	for _, d := range dims {
+		if d != DimensionOverworld && d != DimensionNether && d != DimensionEnd {
+			return fmt.Errorf("dimensions must be %q, %q, or %q, got %q",
+				DimensionOverworld, DimensionNether, DimensionEnd, d)
+		}
+	}
+	return nil
+}
+
+// reservedPaths are the server directory entries the tool manages itself;
+// extra_paths must not overwrite them with files from the backup.
+var reservedPaths = map[string]bool{
+	"config.toml":           true,
+	"config":                true,
+	"web":                   true,
+	"scripts":               true,
+	"bluemap.lock":          true,
+	".bluemap-debug":        true,
+	lastrender.FileName:     true,
+	manifest.FileName:       true,
+	runreport.FileName:      true,
+	extractor.SeekIndexName: true,
+	markers.HOCONDirName:    true,
+	markers.StaticFileName:  true,
+	mca.QuarantineDirName:   true,
+}
+
+// validateExtraPaths checks that every extra path is a relative path inside
+// the backup that is extracted neither over a world folder nor over a file
+// the tool manages.
+func validateExtraPaths(paths []string, worlds []WorldConfig) error {
+	folders := make(map[string]string)
+	for _, w := range worlds {
+		for _, f := range w.Folders() {
+			folders[f] = w.Name
+		}
+	}
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if !isBackupPath(p) {
+			return fmt.Errorf("%q must be a relative path inside the backup", p)
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate path %q", p)
+		}
+		seen[p] = true
+		top, _, _ := strings.Cut(p, "/")
+		if reservedPaths[top] {
+			return fmt.Errorf("%q would overwrite %s in the server directory", p, top)
+		}
+		if w, ok := folders[top]; ok {
+			return fmt.Errorf("%q lies inside the folder of world %q, which is already extracted", p, w)
+		}
+	}
+	return nil
+}
+
+// isBackupPath reports whether s is a clean, relative, slash-separated path
+// that stays inside the backup archive.
+func isBackupPath(s string) bool {
+	return path.Clean(s) == s && !path.IsAbs(s) && s != "." && s != ".." &&
+		!strings.HasPrefix(s, "../") && !strings.Contains(s, "\\")
+}
+
+// CheckMaps verifies that every map ID has a BlueMap map config at
+// config/maps/<id>.conf under dir, so a typo fails before the backup is
+// downloaded rather than after.
+func CheckMaps(dir string, ids []string) error {
+	for _, id := range ids {
+		if id == "" || strings.ContainsAny(id, ",/\\") {
+			return fmt.Errorf("invalid map ID %q", id)
+		}
+		path := filepath.Join(dir, "config", "maps", id+".conf")
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("map %q: %s not found", id, path)
+		}
+	}
+	return nil
+}
+
+// accentColorRe matches the hex colors accepted for branding.accent_color.
+var accentColorRe = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// checkBranding validates the [branding] table: the images must exist
+// under dir with a supported extension, and the accent color must be hex.
+func checkBranding(dir string, b BrandingConfig) error {
+	if strings.ContainsAny(b.Title, "\r\n") {
+		return fmt.Errorf("branding.title must be a single line")
+	}
+	for _, img := range []struct{ key, path string }{{"favicon", b.Favicon}, {"logo", b.Logo}} {
+		if img.path == "" {
+			continue
+		}
+		if !slices.Contains(branding.ImageExtensions, strings.ToLower(filepath.Ext(img.path))) {
+			return fmt.Errorf("branding.%s must be an image (%s), got %q",
+				img.key, strings.Join(branding.ImageExtensions, ", "), img.path)
+		}
+		p := img.path
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			return fmt.Errorf("branding.%s: %s not found", img.key, p)
+		}
+	}
+	if b.AccentColor != "" && !accentColorRe.MatchString(b.AccentColor) {
+		return fmt.Errorf("branding.accent_color must be a hex color such as \"#3a7bd5\", got %q", b.AccentColor)
+	}
+	return nil
+}
+
+// checkWebapp validates the [webapp] table of cfg: the maps must exist under
+// dir and be rendered, and the views and zoom distances must be valid.
+func checkWebapp(dir string, cfg *ServerConfig) error {
+	w := cfg.Webapp
+	var skipped []string
+	if w.DefaultMap != "" || len(w.StartPos) > 0 {
+		// Maps of left-out dimensions are not rendered, so their settings
+		// cannot be patched.
+		var err error
+		if _, skipped, err = cfg.ResolveRenderMaps(dir); err != nil {
+			return err
+		}
+	}
+	if w.DefaultMap != "" {
+		if err := CheckMaps(dir, []string{w.DefaultMap}); err != nil {
+			return fmt.Errorf("webapp.default_map: %w", err)
+		}
+		if slices.Contains(skipped, w.DefaultMap) {
+			return fmt.Errorf("webapp.default_map: map %q shows a dimension left out by dimensions", w.DefaultMap)
+		}
+	}
+	for id := range w.StartPos {
+		if err := CheckMaps(dir, []string{id}); err != nil {
+			return fmt.Errorf("webapp.start_pos: %w", err)
+		}
+		if slices.Contains(skipped, id) {
+			return fmt.Errorf("webapp.start_pos: map %q shows a dimension left out by dimensions", id)
+		}
+	}
+	for _, view := range w.Views {
+		if !slices.Contains(bluemap.Views, view) {
+			return fmt.Errorf("webapp.views: unknown view %q (available: %s)", view, strings.Join(bluemap.Views, ", "))
+		}
+	}
+	if w.MinZoom < 0 || w.MaxZoom < 0 {
+		return fmt.Errorf("webapp.min_zoom and webapp.max_zoom must not be negative")
+	}
+	if w.MinZoom != 0 && w.MaxZoom != 0 && w.MinZoom > w.MaxZoom {
+		return fmt.Errorf("webapp.min_zoom must not exceed webapp.max_zoom")
+	}
+	return nil
+}
+
+// envNameRe matches environment variable names.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkAccess validates the [access] table.
+func checkAccess(a AccessConfig, deployTarget string) error {
+	switch a.Target {
+	case "", access.TargetCloudflare:
+	case access.TargetNetlify:
+		// Only Netlify reads _headers; the other deployers leave it out and
+		// a web server would serve it with the passwords in plain text.
+		if deployTarget != DeployTargetNetlify {
+			return fmt.Errorf("access.target = %q requires deploy_target = %q; use %q or %q with deploy_target = %q",
+				access.TargetNetlify, DeployTargetNetlify, access.TargetHtpasswd, access.TargetCloudflare, deployTarget)
+		}
+	case access.TargetHtpasswd:
+		// The htpasswd file is for a web server's own Basic-Auth.
+		if deployTarget == DeployTargetNetlify || deployTarget == DeployTargetS3 {
+			return fmt.Errorf("access.target = %q cannot be enforced with deploy_target = %q; use %q",
+				access.TargetHtpasswd, deployTarget, access.TargetCloudflare)
+		}
+	default:
+		return fmt.Errorf("access.target must be %q, %q, or %q, got %q",
+			access.TargetNetlify, access.TargetCloudflare, access.TargetHtpasswd, a.Target)
+	}
+	if a.CredentialsEnv != "" && !envNameRe.MatchString(a.CredentialsEnv) {
+		return fmt.Errorf("access.credentials_env must be an environment variable name, got %q", a.CredentialsEnv)
+	}
+	if len(a.Emails) > 0 && a.Target != access.TargetCloudflare {
+		return fmt.Errorf("access.emails requires access.target = %q", access.TargetCloudflare)
+	}
+	for _, e := range a.Emails {
+		if !strings.Contains(e, "@") || strings.ContainsAny(e, " \t\r\n") {
+			return fmt.Errorf("access.emails: %q must be an email address or an @domain", e)
+		}
+	}
+	return nil
+}
+
+// checkRetention validates the [backup_retention] table.
+func checkRetention(cfg ServerConfig) error {
+	r := cfg.BackupRetention
+	if r.Keep < 0 {
+		return fmt.Errorf("backup_retention.keep must not be negative, got %d", r.Keep)
+	}
+	if _, err := parseAge(r.MaxAge); err != nil {
+		return fmt.Errorf("backup_retention.max_age: %w", err)
+	}
+	if r.MaxAge != "" && r.ResolveMaxAge() == 0 {
+		return fmt.Errorf("backup_retention.max_age must be longer than zero, got %q", r.MaxAge)
+	}
+	if r.Enabled() && cfg.PanelType != "" && cfg.PanelType != panel.TypePterodactyl {
+		return fmt.Errorf("backup_retention is not supported with panel_type = %q", cfg.PanelType)
+	}
+	return nil
+}
+
+// checkSSH validates the [ssh] table, which deploy_target = "ssh" requires.
+func checkSSH(dir, target string, s SSHConfig) error {
+	if target != DeployTargetSSH {
+		if s != (SSHConfig{}) {
+			return fmt.Errorf("[ssh] requires deploy_target = %q", DeployTargetSSH)
+		}
+		return nil
+	}
+	if s.Host == "" || strings.ContainsAny(s.Host, " \t\r\n@:/") {
+		return fmt.Errorf("ssh.host must be a host name or address, got %q", s.Host)
+	}
+	if strings.ContainsAny(s.User, " \t\r\n@:/") {
+		return fmt.Errorf("ssh.user must not contain spaces, '@', ':' or '/', got %q", s.User)
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("ssh.port must be between 1 and 65535, got %d", s.Port)
+	}
+	if !strings.HasPrefix(s.Path, "/") && !strings.HasPrefix(s.Path, "~/") {
+		return fmt.Errorf("ssh.path must be an absolute remote directory such as \"/var/www/map\", got %q", s.Path)
+	}
+	if p := strings.TrimRight(s.Path, "/"); p == "" || p == "~" || strings.ContainsAny(s.Path, "\r\n") {
+		return fmt.Errorf("ssh.path must not be the remote root or home directory, got %q", s.Path)
+	}
+	if rate, err := parseRate(s.BandwidthLimit); err != nil {
+		return fmt.Errorf("ssh.bandwidth_limit: %w", err)
+	} else if s.BandwidthLimit != "" && rate < 1<<10 {
+		return fmt.Errorf("ssh.bandwidth_limit must be at least 1KiB/s, got %q", s.BandwidthLimit)
+	}
+	if s.IdentityFile != "" {
+		p := s.IdentityFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			return fmt.Errorf("ssh.identity_file: %s not found", p)
+		}
+	}
+	return nil
+}
+
+// checkFTP validates the [ftp] table, which deploy_target = "ftp" requires.
+func checkFTP(target string, f FTPConfig) error {
+	if target != DeployTargetFTP {
+		if f != (FTPConfig{}) {
+			return fmt.Errorf("[ftp] requires deploy_target = %q", DeployTargetFTP)
+		}
+		return nil
+	}
+	if f.Host == "" || strings.ContainsAny(f.Host, " \t\r\n@:/") {
+		return fmt.Errorf("ftp.host must be a host name or address, got %q", f.Host)
+	}
+	if strings.ContainsAny(f.User, "\r\n") {
+		return fmt.Errorf("ftp.user must not contain line breaks")
+	}
+	if f.Port < 0 || f.Port > 65535 {
+		return fmt.Errorf("ftp.port must be between 1 and 65535, got %d", f.Port)
+	}
+	if f.Path == "" || strings.ContainsAny(f.Path, "\r\n") {
+		return fmt.Errorf("ftp.path must be the remote directory serving the map, such as \"/public_html/map\", got %q", f.Path)
+	}
+	switch f.TLS {
+	case "", deploy.FTPTLSExplicit, deploy.FTPTLSImplicit, deploy.FTPTLSOff:
+	default:
+		return fmt.Errorf("ftp.tls must be %q, %q, or %q, got %q",
+			deploy.FTPTLSExplicit, deploy.FTPTLSImplicit, deploy.FTPTLSOff, f.TLS)
+	}
+	if f.Connections < 0 || f.Connections > 16 {
+		return fmt.Errorf("ftp.connections must be between 1 and 16 (or 0 for the default %d), got %d",
+			deploy.DefaultFTPConnections, f.Connections)
+	}
+	return nil
+}
+
+// bucketNameRe matches bucket names accepted by S3, R2, MinIO and B2.
+var bucketNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{1,62}$`)
+
+// checkS3 validates the [s3] table, which deploy_target = "s3" requires.
+func checkS3(target string, s S3Config) error {
+	if target != DeployTargetS3 {
+		if s != (S3Config{}) {
+			return fmt.Errorf("[s3] requires deploy_target = %q", DeployTargetS3)
+		}
+		return nil
+	}
+	if !bucketNameRe.MatchString(s.Bucket) {
+		return fmt.Errorf("s3.bucket must be a bucket name, got %q", s.Bucket)
+	}
+	if s.Endpoint != "" {
+		u, err := url.Parse(s.Endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("s3.endpoint must be an http(s) URL without a path, such as \"https://<account>.r2.cloudflarestorage.com\", got %q", s.Endpoint)
+		}
+	}
+	if strings.ContainsAny(s.Region, " /") {
+		return fmt.Errorf("s3.region must be a region name such as \"auto\" or \"eu-central-1\", got %q", s.Region)
+	}
+	if s.Concurrency < 0 || s.Concurrency > 64 {
+		return fmt.Errorf("s3.concurrency must be between 1 and 64 (or 0 for the default %d), got %d",
+			deploy.DefaultS3Concurrency, s.Concurrency)
+	}
+	return nil
+}
+
+// placeholderNameRe matches the names usable as {name} in language files.
+var placeholderNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// checkPlaceholders validates the [placeholders] table: names must be
+// identifiers and must not shadow a built-in placeholder or one BlueMap's
+// webapp fills in itself.
+func checkPlaceholders(placeholders map[string]string) error {
+	names := make([]string, 0, len(placeholders))
+	for name := range placeholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !placeholderNameRe.MatchString(name) {
+			return fmt.Errorf("placeholders.%s: name must start with a letter and contain only letters, digits and _", name)
+		}
+		if slices.Contains(lang.Builtin, name) || slices.Contains(lang.Reserved, name) {
+			return fmt.Errorf("placeholders.%s: name is reserved for a built-in placeholder", name)
+		}
+	}
+	return nil
+}
+
+// checkTimeFormats validates the [time_formats] table: every layout must
+// contain a date or time element, like time_format.
+func checkTimeFormats(formats map[string]string) error {
+	locales := make([]string, 0, len(formats))
+	for locale := range formats {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		if layout := formats[locale]; layoutReference.Format(layout) == layout {
+			return fmt.Errorf("time_formats.%s must be a Go time layout such as %q, got %q", locale, DefaultTimeFormat, layout)
+		}
+	}
+	return nil
+}
+
+// isHexDigest reports whether s consists of exactly n hexadecimal characters.
+func isHexDigest(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isMemorySize reports whether s is a JVM memory size: digits optionally
+// followed by a k, m or g unit (case-insensitive).
+func isMemorySize(s string) bool {
+	digits := strings.TrimRight(s, "kKmMgG")
+	if len(s)-len(digits) > 1 || digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ServerDirs returns the subdirectories of baseDir that contain a
+// config.toml, in name order.
+func ServerDirs(baseDir string) ([]string, error) {
 	entries, err := os.ReadDir(baseDir)
 	if err != nil {
 		return nil, fmt.Errorf("reading base directory %s: %w", baseDir, err)
 	}
 
-	var servers []LoadedServer
+	var dirs []string
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 
-		configPath := filepath.Join(baseDir, entry.Name(), "config.toml")
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		dir := filepath.Join(baseDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "config.toml")); os.IsNotExist(err) {
 			continue
 		}
+		dirs = append(dirs, dir)
+	}
+
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no config.toml found in any subdirectory of %s", baseDir)
+	}
+
+	return dirs, nil
+}
+
+// LoadAll scans the given base directory for subdirectories containing a
+// config.toml and returns all parsed configs.
+func LoadAll(baseDir string) ([]LoadedServer, error) {
+	dirs, err := ServerDirs(baseDir)
+	if err != nil {
+		return nil, err
+	}
 
-		srv, err := Load(filepath.Join(baseDir, entry.Name()))
+	var servers []LoadedServer
+	for _, dir := range dirs {
+		srv, err := Load(dir)
 		if err != nil {
 			return nil, err
 		}
@@ -161,9 +1959,5 @@ func LoadAll(baseDir string) ([]LoadedServer, error) {
 		servers = append(servers, srv)
 	}
 
-	if len(servers) == 0 {
-		return nil, fmt.Errorf("no config.toml found in any subdirectory of %s", baseDir)
-	}
-
 	return servers, nil
 }
@@ -0,0 +1,465 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveWorlds(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ServerConfig
+		want []string
+	}{
+		{
+			name: "single plugin world",
+			cfg:  ServerConfig{ServerType: ServerTypePlugin, WorldName: "world"},
+			want: []string{"world", "world_nether", "world_the_end"},
+		},
+		{
+			name: "multiple worlds with mixed layouts and dimensions",
+			cfg: ServerConfig{
+				ServerType: ServerTypePlugin,
+				Worlds: []WorldConfig{
+					{Name: "world"},
+					{Name: "creative", Dimensions: []string{DimensionOverworld}},
+					{Name: "resource", Dimensions: []string{DimensionOverworld, DimensionEnd}},
+					{Name: "event", Type: ServerTypeVanilla, Dimensions: []string{DimensionNether}},
+				},
+			},
+			want: []string{
+				"world", "world_nether", "world_the_end",
+				"creative",
+				"resource", "resource_the_end",
+				"event",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.ResolveWorlds(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveWorlds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755)
+	for id, conf := range map[string]string{
+		"world":     "world: \"world\"\ndimension: \"minecraft:overworld\"\n",
+		"nether":    "world: \"world\"\ndimension: \"minecraft:the_nether\"\n",
+		"end":       "world: \"world\"\ndimension: \"minecraft:the_end\"\n",
+		"lobby_end": "world: \"lobby_the_end\"\ndimension: \"minecraft:the_end\"\n",
+	} {
+		os.WriteFile(filepath.Join(dir, "config", "maps", id+".conf"), []byte(conf), 0o644)
+	}
+
+	cfg := ServerConfig{WorldName: "world", ServerType: ServerTypeVanilla, Dimensions: []string{DimensionOverworld, DimensionNether}}
+	world := cfg.ResolveWorldConfigs()[0]
+	for rel, want := range map[string]bool{
+		"level.dat":              true,
+		"region/r.0.0.mca":       true,
+		"DIM-1/region/r.0.0.mca": true,
+		"DIM1/region/r.0.0.mca":  false,
+		"DIM1/data/raids.dat":    false,
+	} {
+		if got := world.IncludesFile(rel); got != want {
+			t.Errorf("IncludesFile(%q) = %v, want %v", rel, got, want)
+		}
+	}
+	maps, skipped, err := cfg.ResolveRenderMaps(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lobby_end", "nether", "world"}; !reflect.DeepEqual(maps, want) {
+		t.Errorf("maps = %v, want %v", maps, want)
+	}
+	if want := []string{"end"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	unified := WorldConfig{Name: "world", Type: ServerTypeUnified, Dimensions: []string{DimensionOverworld}}
+	if unified.IncludesFile("dimensions/minecraft/the_nether/region/r.0.0.mca") {
+		t.Error("unified world includes a left-out dimension")
+	}
+	if !unified.IncludesFile("dimensions/mymod/mydim/region/r.0.0.mca") {
+		t.Error("unified world left out a modded dimension")
+	}
+
+	cfg = ServerConfig{Worlds: WorldList{{Name: "lobby", Type: ServerTypePlugin, Dimensions: []string{DimensionOverworld}}}}
+	maps, skipped, err = cfg.ResolveRenderMaps(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"lobby_end"}) || len(maps) != 3 {
+		t.Errorf("plugin world: maps = %v, skipped = %v", maps, skipped)
+	}
+
+	cfg.Maps = []string{"lobby_end"}
+	if _, _, err := cfg.ResolveRenderMaps(dir); err == nil {
+		t.Error("ResolveRenderMaps accepted only left-out maps")
+	}
+
+	// [webapp] cannot name a map that is not rendered.
+	base := "server_id = \"abc\"\nserver_type = \"vanilla\"\nmc_version = \"1.21.4\"\nbluemap_version = \"5.7\"\nworld_name = \"world\"\ndimensions = [\"overworld\", \"nether\"]\n"
+	for webapp, ok := range map[string]bool{
+		"[webapp]\ndefault_map = \"nether\"\n":               true,
+		"[webapp]\ndefault_map = \"end\"\n":                  false,
+		"[webapp.start_pos]\nend = { x = 0, z = 0 }\n":       false,
+		"[webapp.start_pos]\nlobby_end = { x = 0, z = 0 }\n": true,
+	} {
+		os.WriteFile(filepath.Join(dir, "config.toml"), []byte(base+webapp), 0o644)
+		if _, err := Load(dir); (err == nil) != ok {
+			t.Errorf("Load with %q: err = %v, want ok = %v", webapp, err, ok)
+		}
+	}
+}
+
+func TestLoadWorldTables(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"survival", "creative"} {
+		if err := os.WriteFile(filepath.Join(dir, "config", "maps", id+".conf"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig := func(worlds string) {
+		t.Helper()
+		base := "server_id = \"abc\"\nserver_type = \"plugin\"\nmc_version = \"1.21.4\"\nbluemap_version = \"5.7\"\n"
+		if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(base+worlds), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig(`
+[worlds.world]
+maps = ["survival"]
+bounds = { min_x = -1000, max_x = 1000, min_z = -1000, max_z = 1000 }
+
+[worlds.creative]
+source = "worlds/creative"
+type = "vanilla"
+maps = ["creative"]
+skip = true
+`)
+	srv, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg := srv.Config
+	if len(cfg.Worlds) != 2 || cfg.Worlds[0].Name != "creative" || cfg.Worlds[1].Name != "world" {
+		t.Fatalf("Worlds = %+v, want creative and world in name order", cfg.Worlds)
+	}
+	if got := cfg.Worlds[0].BackupFolders(); !reflect.DeepEqual(got, map[string]string{"creative": "worlds/creative"}) {
+		t.Errorf("BackupFolders() = %v", got)
+	}
+	if got, want := cfg.ResolveWorlds(), []string{"world", "world_nether", "world_the_end"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveWorlds() = %v, want %v (skipped world excluded)", got, want)
+	}
+	if got, want := cfg.ResolveMaps(), []string{"survival"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveMaps() = %v, want %v", got, want)
+	}
+	world := cfg.Worlds[1]
+	for rel, want := range map[string]bool{
+		"region/r.0.0.mca":     true,
+		"region/r.-2.1.mca":    true,
+		"region/r.-3.0.mca":    false,
+		"entities/r.2.0.mca":   false,
+		"level.dat":            true,
+		"data/raids.dat":       true,
+		"poi/r.1.-2.mca":       true,
+		"region/r.0.-3.mca":    false,
+		"DIM-1/region/r.5.mca": true, // not a region file name
+	} {
+		if got := world.IncludesFile(rel); got != want {
+			t.Errorf("IncludesFile(%q) = %v, want %v", rel, got, want)
+		}
+	}
+
+	writeConfig(`
+render_bounds = { min_x = -5000, max_x = 4999, min_z = -5000, max_z = 4999 }
+
+[worlds.world]
+bounds = { min_x = 0, max_x = 511, min_z = 0, max_z = 511 }
+
+[worlds.creative]
+`)
+	srv, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load with render_bounds: %v", err)
+	}
+	for _, w := range srv.Config.ResolveWorldConfigs() {
+		want := *srv.Config.RenderBounds
+		if w.Name == "world" {
+			want = Bounds{MinX: 0, MaxX: 511, MinZ: 0, MaxZ: 511}
+		}
+		if w.Bounds == nil || *w.Bounds != want {
+			t.Errorf("world %q bounds = %v, want %v", w.Name, w.Bounds, want)
+		}
+	}
+
+	writeConfig(`
+extra_paths = ["plugins/WorldGuard", "server.properties"]
+cleanup = ["worlds", "jar"]
+decompress_block_size = "1MiB"
+download_rate_limit = "50MiB/s"
+decompress_blocks = 32
+max_total_size = "2048GiB"
+max_entries = 500000
+max_web_size = "5GiB"
+
+[[worlds]]
+name = "world"
+dimensions = ["overworld"]
+`)
+	srv, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load with [[worlds]]: %v", err)
+	}
+	if got, want := srv.Config.ResolveWorlds(), []string{"world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveWorlds() = %v, want %v", got, want)
+	}
+	if len(srv.Config.ExtraPaths) != 2 {
+		t.Errorf("ExtraPaths = %v", srv.Config.ExtraPaths)
+	}
+	if got := srv.Config.ResolveDownloadRateLimit(); got != 50<<20 {
+		t.Errorf("ResolveDownloadRateLimit() = %d, want %d", got, 50<<20)
+	}
+	if size, blocks := srv.Config.ResolveDecompression(); size != 1<<20 || blocks != 32 {
+		t.Errorf("ResolveDecompression() = %d, %d; want %d, 32", size, blocks, 1<<20)
+	}
+	if file, total, entries := srv.Config.ResolveExtractLimits(); file != 0 || total != 2<<40 || entries != 500000 {
+		t.Errorf("ResolveExtractLimits() = %d, %d, %d; want 0, %d, 500000", file, total, entries, int64(2<<40))
+	}
+	if !srv.Config.ResolveFailOnMissingWorlds() {
+		t.Error("ResolveFailOnMissingWorlds() = false, want true by default")
+	}
+	if world, web := srv.Config.ResolveBudgets(); world != 0 || web != 5<<30 {
+		t.Errorf("ResolveBudgets() = %d, %d; want 0, %d", world, web, 5<<30)
+	}
+	if !srv.Config.ResolveFailOverBudget() {
+		t.Error("ResolveFailOverBudget() = false, want true by default")
+	}
+	for _, tc := range []struct {
+		world WorldConfig
+		want  []string
+	}{
+		{WorldConfig{Name: "world", Type: ServerTypePlugin}, []string{"world"}},
+		{WorldConfig{Name: "world", Type: ServerTypePlugin, Dimensions: []string{"nether", "end"}}, []string{"world_nether", "world_the_end"}},
+		{WorldConfig{Name: "world", Type: ServerTypeVanilla, Dimensions: []string{"nether"}}, []string{"world"}},
+	} {
+		if got := tc.world.RequiredFolders(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v RequiredFolders() = %v, want %v", tc.world, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"[worlds.world]\nbound = { min_x = 0 }\n",
+		"[worlds.world]\nsource = \"../world\"\n",
+		"[worlds.world]\nmaps = [\"missing\"]\n",
+		"[worlds.world]\nskip = true\n",
+		"render_bounds = { min_x = 10, max_x = 0, min_z = 0, max_z = 0 }\n",
+		"[worlds.world]\n[markers]\nsources = [\"dynmap\"]\n",
+		"[worlds.world]\n[markers]\nsources = [\"towny\"]\nformat = \"yaml\"\n",
+		"[worlds.world]\n[markers]\nsources = [\"signs\"]\nsign_prefix = \"  \"\n",
+		"[worlds.a]\nsource = \"world\"\n[worlds.world]\n",
+		"[worlds.a]\nsource = \"worlds\"\n[worlds.b]\nsource = \"worlds/b\"\n",
+		"extra_paths = [\"../plugins\"]\n[worlds.world]\n",
+		"extra_paths = [\"config/paper-global.yml\"]\n[worlds.world]\n",
+		"extra_paths = [\"world/playerdata\"]\n[worlds.world]\n",
+		"cleanup = [\"web\"]\n[worlds.world]\n",
+		"decompress_block_size = \"1 parsec\"\n[worlds.world]\n",
+		"decompress_block_size = \"1KiB\"\n[worlds.world]\n",
+		"decompress_blocks = -1\n[worlds.world]\n",
+		"extract_workers = 100\n[worlds.world]\n",
+		"max_file_size = \"ten gigs\"\n[worlds.world]\n",
+		"max_total_size = \"1KiB\"\n[worlds.world]\n",
+		"max_entries = -1\n[worlds.world]\n",
+		"max_web_size = \"100KiB\"\n[worlds.world]\n",
+		"[hosting]\nplan = \"vercel\"\n[worlds.world]\n",
+		"[hosting]\nmonthly_bandwidth = \"lots\"\n[worlds.world]\n",
+		"download_rate_limit = \"fast\"\n[worlds.world]\n",
+		"download_rate_limit = \"0/s\"\n[worlds.world]\n",
+		"download_buffer = \"1MiB\"\ndownload_mode = \"parallel-stream\"\n[worlds.world]\n",
+		"download_buffer = \"64MiB\"\n[worlds.world]\n",
+		"proxy_url = \"ftp://proxy.corp\"\n[worlds.world]\n",
+		"render_progress = \"often\"\n[worlds.world]\n",
+		"resourcepacks = [\"ftp://packs.example.com/pack.zip\"]\n[worlds.world]\n",
+		"[[mods]]\n[worlds.world]\n",
+		"storage = \"mysql\"\n[worlds.world]\n",
+		"[worlds.world]\n[watch]\nschedule = \"every day\"\n",
+		"[worlds.world]\n[watch]\nschedule = \"@daily\"\nlisten = \"8080\"\n",
+		"[[mods]]\nurl = \"https://cdn.example.com/a.jar\"\nmodrinth = \"create\"\n[worlds.world]\n",
+		"[[mods]]\nurl = \"cdn.example.com/a.jar\"\n[worlds.world]\n",
+		"[[mods]]\nurl = \"https://cdn.example.com/a.jar\"\nloader = \"fabric\"\n[worlds.world]\n",
+		"[[mods]]\nmodrinth = \"create\"\nsha256 = \"abc\"\n[worlds.world]\n",
+		"resourcepacks = [\"https://packs.example.com/\"]\n[worlds.world]\n",
+		"resourcepacks = [\"../packs/pack.zip\"]\n[worlds.world]\n",
+		"resourcepacks = [\"packs/pack.zip\", \"https://packs.example.com/pack.zip\"]\n[worlds.world]\n",
+		"render_progress = \"1s\"\n[worlds.world]\n",
+		"bluemap_download_url = \"mirror.example.com/{jar}\"\n[worlds.world]\n",
+		"bluemap_mirrors = [\"ftp://mirror.example.com/{jar}\"]\n[worlds.world]\n",
+		"webapp_url = \"webapp.zip\"\n[worlds.world]\n",
+		"webapp_version = \"5.3\"\nwebapp_url = \"https://example.com/webapp.zip\"\n[worlds.world]\n",
+		"timezone = \"Mars/Olympus\"\n[worlds.world]\n",
+		"time_format = \"YYYY-MM-DD\"\n[worlds.world]\n",
+		"map_url = \"map.example.com\"\n[worlds.world]\n",
+		"webhook_url = \"discord.com/api/webhooks/1/x\"\n[worlds.world]\n",
+		"fresh_backup = true\nskip_if_unchanged = true\n[worlds.world]\n",
+		"panel_type = \"multicraft\"\n[worlds.world]\n",
+		"panel_type = \"pufferpanel\"\nfresh_backup = true\npause_saves = true\n[worlds.world]\n",
+		"panel_type = \"pufferpanel\"\nannounce_command = \"say hi\"\n[worlds.world]\n",
+		"panel_type = \"crafty\"\nfresh_backup = true\npause_saves = true\n[worlds.world]\n",
+		"flush_saves = true\n[worlds.world]\n",
+		"fresh_backup = true\nflush_saves = true\npause_saves = true\n[worlds.world]\n",
+		"panel_type = \"amp\"\nfresh_backup = true\nflush_saves = true\n[worlds.world]\n",
+		"[backup_retention]\nkeep = -1\n[worlds.world]\n",
+		"panel_type = \"crafty\"\nlock_backup = true\n[worlds.world]\n",
+		"[backup_retention]\nmax_age = \"a month\"\n[worlds.world]\n",
+		"[backup_retention]\nmax_age = \"0d\"\n[worlds.world]\n",
+		"panel_type = \"pufferpanel\"\n[backup_retention]\nkeep = 3\n[worlds.world]\n",
+		"[placeholders]\n\"discord-invite\" = \"x\"\n[worlds.world]\n",
+		"[placeholders]\nprojectName = \"x\"\n[worlds.world]\n",
+		"[placeholders]\nmap = \"x\"\n[worlds.world]\n",
+		"[time_formats]\nzh-TW = \"today\"\n[worlds.world]\n",
+		"[branding]\naccent_color = \"blue\"\n[worlds.world]\n",
+		"[branding]\nfavicon = \"missing.png\"\n[worlds.world]\n",
+		"[branding]\nlogo = \"config.toml\"\n[worlds.world]\n",
+		"[webapp]\ndefault_map = \"missing\"\n[worlds.world]\n",
+		"[webapp.start_pos]\nmissing = { x = 0, z = 0 }\n[worlds.world]\n",
+		"[webapp]\nviews = [\"orbit\"]\n[worlds.world]\n",
+		"[webapp]\nmin_zoom = 500\nmax_zoom = 100\n[worlds.world]\n",
+		"dimensions = [\"overworld\"]\n[worlds.world]\n",
+		"[worlds.world]\ndimensions = [\"the_end\"]\n",
+		"[access]\ntarget = \"nginx\"\n[worlds.world]\n",
+		"[cache]\ntiles = \"\"\"\npublic,\nmax-age=60\"\"\"\n[worlds.world]\n",
+		"[access]\ntarget = \"netlify\"\ncredentials_env = \"MAP-USERS\"\n[worlds.world]\n",
+		"[access]\ntarget = \"netlify\"\nemails = [\"a@example.com\"]\n[worlds.world]\n",
+		"[access]\ntarget = \"cloudflare\"\nemails = [\"example.com\"]\n[worlds.world]\n",
+		"deploy_target = \"static\"\n[access]\ntarget = \"netlify\"\n[worlds.world]\n",
+		"[access]\ntarget = \"htpasswd\"\n[worlds.world]\n",
+		"[ssh]\nhost = \"map.example.com\"\npath = \"/var/www/map\"\n[worlds.world]\n",
+		"deploy_target = \"ssh\"\n[ssh]\npath = \"/var/www/map\"\n[worlds.world]\n",
+		"deploy_target = \"ssh\"\n[ssh]\nhost = \"map.example.com\"\npath = \"/\"\n[worlds.world]\n",
+		"deploy_target = \"ssh\"\n[ssh]\nhost = \"map.example.com\"\npath = \"www/map\"\n[worlds.world]\n",
+		"deploy_target = \"ssh\"\n[ssh]\nhost = \"map.example.com\"\npath = \"/var/www/map\"\nbandwidth_limit = \"100\"\n[worlds.world]\n",
+		"[ftp]\nhost = \"ftp.example.com\"\npath = \"/htdocs\"\n[worlds.world]\n",
+		"deploy_target = \"ftp\"\n[ftp]\nhost = \"ftp.example.com\"\n[worlds.world]\n",
+		"deploy_target = \"ftp\"\n[ftp]\nhost = \"ftp.example.com\"\npath = \"/htdocs\"\ntls = \"ssl\"\n[worlds.world]\n",
+		"deploy_target = \"ftp\"\n[ftp]\nhost = \"ftp.example.com\"\npath = \"/htdocs\"\nconnections = 64\n[worlds.world]\n",
+		"[s3]\nbucket = \"map\"\n[worlds.world]\n",
+		"deploy_target = \"s3\"\n[s3]\nbucket = \"a\"\n[worlds.world]\n",
+		"deploy_target = \"s3\"\n[s3]\nbucket = \"map\"\nendpoint = \"r2.example.com\"\n[worlds.world]\n",
+		"deploy_target = \"s3\"\n[s3]\nbucket = \"map\"\nconcurrency = 100\n[worlds.world]\n",
+	} {
+		writeConfig(bad)
+		if _, err := Load(dir); err == nil {
+			t.Errorf("Load accepted %q", bad)
+		}
+	}
+
+	// A checksum pins one jar, which a dynamic version moves away from.
+	pinned := "server_id = \"abc\"\nserver_type = \"plugin\"\nmc_version = \"1.21.4\"\nbluemap_version = \"latest\"\nbluemap_sha256 = \"abababababababababababababababababababababababababababababababab\"\n[worlds.world]\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(pinned), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Load accepted bluemap_sha256 with bluemap_version \"latest\"")
+	}
+
+	// markers.toml is checked with the config, including its map IDs.
+	writeConfig("[worlds.world]\n")
+	staticPath := filepath.Join(dir, "markers.toml")
+	if err := os.WriteFile(staticPath, []byte("[sets.poi]\nmaps = [\"survival\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err != nil {
+		t.Errorf("Load with markers.toml: %v", err)
+	}
+	if err := os.WriteFile(staticPath, []byte("[sets.poi]\nmaps = [\"missing\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Load accepted a markers.toml set for a missing map")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"":       0,
+		"4096":   4096,
+		"512KiB": 512 << 10,
+		"1MiB":   1 << 20,
+		"1.5MB":  1500000,
+		"2g":     2 << 30,
+		"50 MiB": 50 << 20,
+	} {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"MiB", "1TiB", "-1MiB", "1..2M"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":    0,
+		"30d": 30 * 24 * time.Hour,
+		"72h": 72 * time.Hour,
+	} {
+		got, err := parseAge(in)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"d", "1.5d", "-2d", "-1h"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q) succeeded", in)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	at := time.Date(2026, 3, 14, 16, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		cfg  ServerConfig
+		want string
+	}{
+		{ServerConfig{}, "2026-03-14 16:30 UTC"},
+		{ServerConfig{Timezone: "Asia/Taipei"}, "2026-03-15 00:30 CST"},
+		{ServerConfig{Timezone: "Europe/Berlin", TimeFormat: "02.01.2006 15:04"}, "14.03.2026 17:30"},
+	} {
+		if got := tt.cfg.FormatTime(at); got != tt.want {
+			t.Errorf("FormatTime with %q/%q = %q, want %q", tt.cfg.Timezone, tt.cfg.TimeFormat, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		cfg    ServerConfig
+		locale string
+		want   string
+	}{
+		{ServerConfig{}, "en", "2026-03-14 16:30 UTC"},
+		{ServerConfig{Timezone: "Asia/Taipei"}, "zh-TW", "2026年3月15日 00:30 CST"},
+		{ServerConfig{TimeFormat: "02.01.2006 15:04"}, "zh-TW", "14.03.2026 16:30"},
+		{ServerConfig{TimeFormat: "02.01.2006", TimeFormats: map[string]string{"zh": "2006/1/2"}}, "zh-HK", "2026/3/14"},
+		{ServerConfig{TimeFormats: map[string]string{"zh": "2006/1/2", "zh-TW": "1月2日"}}, "zh-TW", "3月14日"},
+		{ServerConfig{TimeFormats: map[string]string{"zh-TW": "1月2日"}}, "ja", "2026-03-14 16:30 UTC"},
+	} {
+		if got := tt.cfg.FormatLocaleTime(tt.locale, at); got != tt.want {
+			t.Errorf("FormatLocaleTime(%q) with %q/%v = %q, want %q", tt.locale, tt.cfg.TimeFormat, tt.cfg.TimeFormats, got, tt.want)
+		}
+	}
+}
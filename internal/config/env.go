@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix starts the environment variables that override config.toml
+// fields: BLUEMAP_ACTION_<KEY> with the TOML key upper-cased, e.g.
+// BLUEMAP_ACTION_BLUEMAP_VERSION for bluemap_version. Fields of a table join
+// the table and field names, as in BLUEMAP_ACTION_COMPRESSION_LEVEL.
+const EnvPrefix = "BLUEMAP_ACTION_"
+
+// applyEnv overrides the fields of cfg with the BLUEMAP_ACTION_* variables
+// that are set and returns the TOML keys it changed. String fields take the
+// value verbatim; other fields take a TOML value, such as true, 4,
+// ["overworld", "nether"] or { min_x = 0, max_x = 511, min_z = 0, max_z = 511 }.
+// A list of strings may also be given comma-separated.
+func applyEnv(cfg *ServerConfig) ([]string, error) {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix, "")
+}
+
+func applyEnvStruct(v reflect.Value, envPrefix, keyPrefix string) ([]string, error) {
+	var applied []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := envPrefix + strings.ToUpper(key)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			sub, err := applyEnvStruct(field, name+"_", keyPrefix+key+".")
+			if err != nil {
+				return nil, err
+			}
+			applied = append(applied, sub...)
+			continue
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setFromEnv(field, val); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		applied = append(applied, keyPrefix+key)
+	}
+	return applied, nil
+}
+
+// setFromEnv parses val into field.
+func setFromEnv(field reflect.Value, val string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(val)
+		return nil
+	}
+
+	// Decode "v = <val>" into a struct holding a field of the same type, so
+	// every type config.toml accepts (including custom unmarshalers) works.
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "V",
+		Type: field.Type(),
+		Tag:  `toml:"v"`,
+	}}))
+	_, err := toml.Decode("v = "+val, holder.Interface())
+	if err != nil && field.Type() == reflect.TypeOf([]string(nil)) {
+		var list []string
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q: %w", val, err)
+	}
+	field.Set(holder.Elem().Field(0))
+	return nil
+}
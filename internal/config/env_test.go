@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	config := "server_id = \"abc\"\nserver_type = \"plugin\"\nworld_name = \"world\"\nmc_version = \"1.21.4\"\nbluemap_version = \"5.7\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BLUEMAP_ACTION_BLUEMAP_VERSION", "5.16")
+	t.Setenv("BLUEMAP_ACTION_DOWNLOAD_CONNECTIONS", "4")
+	t.Setenv("BLUEMAP_ACTION_SECURITY_HEADERS", "false")
+	t.Setenv("BLUEMAP_ACTION_EXTRA_PATHS", "plugins/WorldGuard, server.properties")
+	t.Setenv("BLUEMAP_ACTION_RENDER_BOUNDS", "{ min_x = 0, max_x = 511, min_z = 0, max_z = 511 }")
+	t.Setenv("BLUEMAP_ACTION_COMPRESSION_LEVEL", "9")
+	srv, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	c := srv.Config
+	if c.BlueMapVersion != "5.16" || c.DownloadConnections != 4 || c.ResolveSecurityHeaders() ||
+		c.Compression.Level != 9 || c.RenderBounds == nil || c.RenderBounds.MaxX != 511 {
+		t.Errorf("overrides not applied: %+v", c)
+	}
+	if want := []string{"plugins/WorldGuard", "server.properties"}; !reflect.DeepEqual(c.ExtraPaths, want) {
+		t.Errorf("ExtraPaths = %q, want %q", c.ExtraPaths, want)
+	}
+	want := []string{"bluemap_version", "download_connections", "render_bounds", "extra_paths", "security_headers", "compression.level"}
+	if !reflect.DeepEqual(srv.EnvOverrides, want) {
+		t.Errorf("EnvOverrides = %q, want %q", srv.EnvOverrides, want)
+	}
+
+	// Overridden values are validated like the file's.
+	t.Setenv("BLUEMAP_ACTION_DOWNLOAD_CONNECTIONS", "64")
+	if _, err := Load(dir); err == nil {
+		t.Error("Load accepted download_connections = 64 from the environment")
+	}
+	t.Setenv("BLUEMAP_ACTION_DOWNLOAD_CONNECTIONS", "many")
+	if _, err := Load(dir); err == nil {
+		t.Error("Load accepted a non-numeric download_connections")
+	}
+}
@@ -0,0 +1,187 @@
+package crafty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Client interacts with the Crafty Controller 4 API (v2). It authenticates
+// with an API token created under the user's API Keys.
+type Client struct {
+	PanelURL string
+	Token    string
+	HTTP     *http.Client
+}
+
+// NewClient creates a new Crafty Controller API client.
+func NewClient(panelURL, token string) *Client {
+	return &Client{
+		PanelURL: strings.TrimRight(panelURL, "/"),
+		Token:    token,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// BackupConfig is a backup configuration of a server. Crafty 4.4 and later
+// can have several per server, each writing to its own location.
+type BackupConfig struct {
+	ID       string `json:"backup_id"`
+	Name     string `json:"backup_name"`
+	Location string `json:"backup_location"`
+	Default  bool   `json:"default"`
+}
+
+// BackupFile is a backup archive written by a backup configuration.
+type BackupFile struct {
+	Name     string    // file name, e.g. "2026-01-31_04-00-00.zip"
+	Size     int64     // bytes
+	Modified time.Time // when the archive was written
+}
+
+// envelope is the common Crafty response wrapper.
+type envelope struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+type fileEntry struct {
+	Path     string `json:"path"`
+	Dir      bool   `json:"dir"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified"` // Unix seconds
+}
+
+// doRequest sends an API request and returns the data of the response. A
+// non-nil payload is encoded as the JSON request body.
+func (c *Client) doRequest(ctx context.Context, method, path string, payload any) (json.RawMessage, error) {
+	url := c.PanelURL + path
+
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("decoding response from %s: %w", url, err)
+	}
+	if env.Status != "ok" {
+		return nil, fmt.Errorf("API returned %q for %s: %s", env.Status, url, env.Error)
+	}
+	return env.Data, nil
+}
+
+// ListBackupConfigs returns the backup configurations of a server.
+func (c *Client) ListBackupConfigs(ctx context.Context, serverID string) ([]BackupConfig, error) {
+	data, err := c.doRequest(ctx, http.MethodGet, "/api/v2/servers/"+serverID+"/backups", nil)
+	if err != nil {
+		return nil, err
+	}
+	var configs []BackupConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("decoding backup configs: %w", err)
+	}
+	return configs, nil
+}
+
+// DefaultBackupConfig returns the backup configuration marked as default, or
+// the only one.
+func (c *Client) DefaultBackupConfig(ctx context.Context, serverID string) (*BackupConfig, error) {
+	configs, err := c.ListBackupConfigs(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		if configs[i].Default || len(configs) == 1 {
+			return &configs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("server %s has no default backup configuration", serverID)
+}
+
+// ListBackupFiles returns the zip archives in the location of a backup
+// configuration, sorted by modification time (newest first).
+func (c *Client) ListBackupFiles(ctx context.Context, serverID string, cfg *BackupConfig) ([]BackupFile, error) {
+	data, err := c.doRequest(ctx, http.MethodPost, "/api/v2/servers/"+serverID+"/files",
+		map[string]string{"page": "backups", "path": cfg.Location})
+	if err != nil {
+		return nil, err
+	}
+	var entries []fileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decoding backup files: %w", err)
+	}
+
+	var files []BackupFile
+	for _, e := range entries {
+		name := path.Base(strings.ReplaceAll(e.Path, `\`, "/"))
+		if e.Dir || !strings.EqualFold(path.Ext(name), ".zip") {
+			continue
+		}
+		files = append(files, BackupFile{Name: name, Size: e.Size, Modified: time.Unix(e.Modified, 0).UTC()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Modified.After(files[j].Modified)
+	})
+
+	return files, nil
+}
+
+// StartBackup runs a backup configuration. Crafty writes the archive in the
+// background; poll ListBackupFiles for it.
+func (c *Client) StartBackup(ctx context.Context, serverID, backupID string) error {
+	_, err := c.doRequest(ctx, http.MethodPost, "/api/v2/servers/"+serverID+"/action/backup_server/"+backupID, nil)
+	return err
+}
+
+// DownloadURL returns the URL of a backup archive. The download route
+// belongs to the web panel and reads the token from a cookie; see
+// DownloadHeader.
+func (c *Client) DownloadURL(serverID, backupID, file string) string {
+	q := url.Values{"id": {serverID}, "backup_id": {backupID}, "file": {file}}
+	return c.PanelURL + "/panel/download_backup?" + q.Encode()
+}
+
+// DownloadHeader returns the header the download URL needs.
+func (c *Client) DownloadHeader() http.Header {
+	return http.Header{"Cookie": {"token=" + c.Token}}
+}
@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Deployer publishes the web output to a hosting target. Netlify is not one:
+// its deploy runs in the workflow with the Netlify CLI.
+type Deployer interface {
+	// Name describes the destination for messages, e.g. "deploy@host:/srv/map".
+	Name() string
+	// Deploy publishes webDir, replacing the previous deploy.
+	Deploy(ctx context.Context, webDir string) error
+}
+
+// writeSecret writes data to a new file readable only by the current user
+// and returns its path and a function removing it.
+func writeSecret(pattern string, data []byte) (string, func(), error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+	path := f.Name()
+	cleanup := func() { os.Remove(path) }
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("restricting %s: %w", filepath.Base(path), err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
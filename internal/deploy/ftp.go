@@ -0,0 +1,288 @@
+package deploy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FTPPasswordEnv holds the FTP password, so it never has to be committed
+// with the config.
+const FTPPasswordEnv = "BLUEMAP_FTP_PASSWORD"
+
+// TLS modes for FTP.
+const (
+	FTPTLSExplicit = "explicit" // Plain connection upgraded with AUTH TLS (FTPES), port 21
+	FTPTLSImplicit = "implicit" // TLS from the first byte (FTPS), port 990
+	FTPTLSOff      = "off"      // Plain FTP: the password is sent unencrypted
+)
+
+// DefaultFTPConnections is the number of parallel uploads when connections
+// is not set in config.toml. Shared hosts often limit the connections per
+// account to a handful.
+const DefaultFTPConnections = 4
+
+// uploadExcludes are files of other deploy targets, relative to web/, that
+// the FTP and S3 deployers leave out since they would be served as ordinary
+// files.
+var uploadExcludes = map[string]bool{"netlify.toml": true, "_headers": true}
+
+// FTPOptions configures an FTP deploy.
+type FTPOptions struct {
+	Host        string
+	Port        int    // 0 = 21, or 990 with FTPTLSImplicit
+	User        string // empty = "anonymous"
+	Password    string
+	Path        string // remote directory receiving the contents of web/
+	TLS         string // one of the FTPTLS constants; empty = FTPTLSExplicit
+	Connections int    // parallel uploads; 0 = DefaultFTPConnections
+}
+
+// FTP uploads the web output over FTP or FTPS. Files whose remote copy has
+// the same size and is at least as new as the local file are skipped, so
+// unchanged tiles are not uploaded again. Remote files are never deleted.
+type FTP struct {
+	opts FTPOptions
+	tls  *tls.Config
+}
+
+// NewFTP returns an FTP deployer.
+func NewFTP(opts FTPOptions) *FTP {
+	if opts.TLS == "" {
+		opts.TLS = FTPTLSExplicit
+	}
+	if opts.Port == 0 {
+		opts.Port = 21
+		if opts.TLS == FTPTLSImplicit {
+			opts.Port = 990
+		}
+	}
+	if opts.User == "" {
+		opts.User = "anonymous"
+		if opts.Password == "" {
+			opts.Password = "anonymous@"
+		}
+	}
+	if opts.Connections <= 0 {
+		opts.Connections = DefaultFTPConnections
+	}
+	return &FTP{
+		opts: opts,
+		tls: &tls.Config{
+			ServerName:         opts.Host,
+			ClientSessionCache: tls.NewLRUClientSessionCache(opts.Connections * 2),
+		},
+	}
+}
+
+// Name returns the FTP URL of the destination, without the password.
+func (f *FTP) Name() string {
+	scheme := "ftps"
+	if f.opts.TLS == FTPTLSOff {
+		scheme = "ftp"
+	}
+	return fmt.Sprintf("%s://%s@%s/%s", scheme, f.opts.User, f.addr(), strings.Trim(f.opts.Path, "/"))
+}
+
+func (f *FTP) addr() string {
+	return net.JoinHostPort(f.opts.Host, strconv.Itoa(f.opts.Port))
+}
+
+// Check logs in once, so validate can report bad credentials or TLS
+// settings before a render.
+func (f *FTP) Check(ctx context.Context) error {
+	c, err := f.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+func (f *FTP) dial(ctx context.Context) (*ftpConn, error) {
+	return dialFTP(ctx, f.addr(), f.opts.Host, f.opts.TLS, f.opts.User, f.opts.Password, f.tls)
+}
+
+// localFile is a file of web/ to upload.
+type localFile struct {
+	rel     string // slash-separated path relative to web/
+	size    int64
+	modTime time.Time
+}
+
+// Deploy uploads webDir to the remote path with opts.Connections parallel
+// connections, each working through whole directories.
+func (f *FTP) Deploy(ctx context.Context, webDir string) error {
+	dirs, err := collectDirs(webDir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	// Parents first, so most directories exist before their children are
+	// listed.
+	sort.Strings(names)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	var (
+		uploaded, skipped atomic.Int64
+		uploadedBytes     atomic.Int64
+		once              sync.Once
+		firstErr          error
+		wg                sync.WaitGroup
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	workers := min(f.opts.Connections, len(names))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := f.dial(ctx)
+			if err != nil {
+				fail(err)
+				return
+			}
+			defer c.Close()
+			for dir := range jobs {
+				n, size, skip, err := f.syncDir(ctx, c, webDir, dir, dirs[dir])
+				uploaded.Add(int64(n))
+				uploadedBytes.Add(size)
+				skipped.Add(int64(skip))
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, dir := range names {
+		select {
+		case jobs <- dir:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("    %d files uploaded (%.1f MiB), %d unchanged\n",
+		uploaded.Load(), float64(uploadedBytes.Load())/(1<<20), skipped.Load())
+	return nil
+}
+
+// syncDir uploads the files of one directory whose remote copy differs and
+// returns the number of files uploaded, their size and the number skipped.
+func (f *FTP) syncDir(ctx context.Context, c *ftpConn, webDir, dir string, files []localFile) (int, int64, int, error) {
+	remoteDir := path.Join(f.opts.Path, dir)
+	remote, exists, err := c.list(ctx, remoteDir)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if !exists {
+		if err := c.mkdirAll(remoteDir); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	var uploaded, skipped int
+	var bytes int64
+	for _, lf := range files {
+		entry, ok := remote[path.Base(lf.rel)]
+		if ok && entry.ModTime.IsZero() && entry.Size == lf.size {
+			// A LIST listing has no exact times; ask for this file's.
+			entry.ModTime, _ = c.modTime(path.Join(f.opts.Path, lf.rel))
+		}
+		if ok && upToDate(entry, lf) {
+			skipped++
+			continue
+		}
+		if err := f.upload(ctx, c, webDir, lf); err != nil {
+			return uploaded, bytes, skipped, err
+		}
+		uploaded++
+		bytes += lf.size
+	}
+	return uploaded, bytes, skipped, nil
+}
+
+// upToDate reports whether the remote copy matches the local file: the same
+// size, and modified no earlier than the local file. Servers without MFMT
+// stamp uploads with the upload time, which is never earlier either.
+func upToDate(remote ftpEntry, local localFile) bool {
+	return remote.Size == local.size && !remote.ModTime.Before(local.modTime.Truncate(time.Second))
+}
+
+func (f *FTP) upload(ctx context.Context, c *ftpConn, webDir string, lf localFile) error {
+	file, err := os.Open(filepath.Join(webDir, filepath.FromSlash(lf.rel)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	remotePath := path.Join(f.opts.Path, lf.rel)
+	if err := c.store(ctx, remotePath, file); err != nil {
+		return err
+	}
+	return c.setModTime(remotePath, lf.modTime)
+}
+
+// collectDirs returns the files of webDir to upload, grouped by their
+// slash-separated directory relative to webDir ("." for the top level).
+func collectDirs(webDir string) (map[string][]localFile, error) {
+	dirs := make(map[string][]localFile)
+	err := filepath.WalkDir(webDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(webDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if uploadExcludes[rel] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dir := path.Dir(rel)
+		dirs[dir] = append(dirs[dir], localFile{rel: rel, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", webDir, err)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,309 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseReplies(t *testing.T) {
+	if port, err := parseEPSV("Entering Extended Passive Mode (|||6446|)"); err != nil || port != 6446 {
+		t.Errorf("parseEPSV = %d, %v", port, err)
+	}
+	if port, err := parsePASV("Entering Passive Mode (192,168,1,2,19,137)."); err != nil || port != 19<<8|137 {
+		t.Errorf("parsePASV = %d, %v", port, err)
+	}
+	for _, bad := range []string{"Entering Passive Mode", "(1,2,3,4,5)", "(1,2,3,4,5,256)"} {
+		if _, err := parsePASV(bad); err == nil {
+			t.Errorf("parsePASV(%q) succeeded", bad)
+		}
+	}
+
+	files := parseMLSD("type=cdir;modify=20240101000000; .\r\n" +
+		"type=dir;modify=20240101000000; tiles\r\n" +
+		"type=file;size=1234;modify=20240102030405.123;perm=rw; x0 z0.prbm.gz\r\n")
+	want := ftpEntry{Size: 1234, ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if len(files) != 1 || files["x0 z0.prbm.gz"] != want {
+		t.Errorf("parseMLSD = %+v, want only the file %+v", files, want)
+	}
+
+	files = parseLIST("total 8\r\n" +
+		"drwxr-xr-x   2 map map     4096 Jan 02 03:04 tiles\r\n" +
+		"-rw-r--r--   1 map map     1234 Jan 02  2023 x0 z0.prbm.gz\r\n" +
+		"lrwxrwxrwx   1 map map        5 Jan 02 03:04 link -> tiles\r\n")
+	if len(files) != 1 || files["x0 z0.prbm.gz"] != (ftpEntry{Size: 1234}) {
+		t.Errorf("parseLIST = %+v, want only the file of 1234 bytes", files)
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	local := localFile{size: 10, modTime: time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)}
+	for _, tc := range []struct {
+		remote ftpEntry
+		want   bool
+	}{
+		{ftpEntry{Size: 10, ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, true},  // MFMT, second precision
+		{ftpEntry{Size: 10, ModTime: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}, true},  // upload time
+		{ftpEntry{Size: 10, ModTime: time.Date(2024, 1, 2, 3, 4, 4, 0, time.UTC)}, false}, // re-rendered locally
+		{ftpEntry{Size: 11, ModTime: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}, false},
+	} {
+		if got := upToDate(tc.remote, local); got != tc.want {
+			t.Errorf("upToDate(%+v) = %v, want %v", tc.remote, got, tc.want)
+		}
+	}
+}
+
+func TestHtaccess(t *testing.T) {
+	conf := htaccess()
+	for _, want := range []string{
+		"AddEncoding gzip .gz",
+		`<FilesMatch "\.prbm\.gz$">`,
+		"ForceType application/json",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf(".htaccess missing %q:\n%s", want, conf)
+		}
+	}
+}
+
+func TestFTPDeploy(t *testing.T) {
+	t.Run("MLSD", func(t *testing.T) { testFTPDeploy(t, newFakeFTP(t)) })
+	t.Run("LIST", func(t *testing.T) {
+		srv := newFakeFTP(t)
+		srv.noMLSD = true
+		testFTPDeploy(t, srv)
+	})
+}
+
+func testFTPDeploy(t *testing.T, srv *fakeFTP) {
+	web := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for rel, content := range map[string]string{
+		"index.html":                       "<html>",
+		"netlify.toml":                     "[build]",
+		"maps/world/tiles/0/x0/z0.prbm.gz": "tile",
+		"maps/world/tiles/0/x0/z1.prbm.gz": "tile",
+	} {
+		p := filepath.Join(web, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, old, old)
+	}
+
+	d := NewFTP(FTPOptions{Host: "127.0.0.1", Port: srv.port, User: "map", Password: "secret", Path: "/htdocs/map", TLS: FTPTLSOff, Connections: 2})
+	if err := d.Deploy(context.Background(), web); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	if got := srv.uploads(); got != 3 {
+		t.Errorf("first deploy uploaded %d files, want 3 (netlify.toml excluded)", got)
+	}
+	if _, ok := srv.file("/htdocs/map/maps/world/tiles/0/x0/z1.prbm.gz"); !ok {
+		t.Errorf("tile not uploaded; remote files: %v", srv.names())
+	}
+
+	// Only the changed file is uploaded again.
+	if err := os.WriteFile(filepath.Join(web, "index.html"), []byte("<html>new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deploy(context.Background(), web); err != nil {
+		t.Fatalf("second Deploy: %v", err)
+	}
+	if got := srv.uploads(); got != 4 {
+		t.Errorf("second deploy uploaded %d files, want 1", got-3)
+	}
+	if f, _ := srv.file("/htdocs/map/index.html"); f.data != "<html>new" {
+		t.Errorf("index.html = %q after the second deploy", f.data)
+	}
+}
+
+// fakeFTP is an in-memory FTP server with just the commands the deployer
+// sends over plain FTP.
+type fakeFTP struct {
+	port   int
+	noMLSD bool // answer MLSD as unknown, like servers that only know LIST
+
+	mu     sync.Mutex
+	files  map[string]fakeFile
+	dirs   map[string]bool
+	stored int
+}
+
+type fakeFile struct {
+	data    string
+	modTime time.Time
+}
+
+func newFakeFTP(t *testing.T) *fakeFTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeFTP{
+		port:  ln.Addr().(*net.TCPAddr).Port,
+		files: make(map[string]fakeFile),
+		dirs:  map[string]bool{"/": true},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeFTP) uploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stored
+}
+
+func (s *fakeFTP) file(name string) (fakeFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	return f, ok
+}
+
+func (s *fakeFTP) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *fakeFTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	var data net.Listener
+	accept := func() net.Conn {
+		defer data.Close()
+		c, err := data.Accept()
+		if err != nil {
+			return nil
+		}
+		return c
+	}
+
+	reply("220 fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch verb {
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			reply("230 logged in")
+		case "TYPE":
+			reply("200 ok")
+		case "FEAT":
+			reply("211-Features:\r\n MLST type*;size*;modify*;\r\n MFMT\r\n211 End")
+		case "EPSV":
+			data, _ = net.Listen("tcp", "127.0.0.1:0")
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "MLSD", "LIST":
+			if verb == "MLSD" && s.noMLSD {
+				data.Close()
+				reply("500 unknown command")
+				continue
+			}
+			s.mu.Lock()
+			exists := s.dirs[arg]
+			var listing strings.Builder
+			for name, f := range s.files {
+				if path.Dir(name) != arg {
+					continue
+				}
+				if verb == "LIST" {
+					fmt.Fprintf(&listing, "-rw-r--r--   1 map map %8d %s %s\r\n", len(f.data), f.modTime.Format("Jan 02 15:04"), path.Base(name))
+				} else {
+					fmt.Fprintf(&listing, "type=file;size=%d;modify=%s; %s\r\n", len(f.data), f.modTime.UTC().Format(ftpTimeLayout), path.Base(name))
+				}
+			}
+			s.mu.Unlock()
+			if !exists {
+				data.Close()
+				reply("550 no such directory")
+				continue
+			}
+			reply("150 listing")
+			if c := accept(); c != nil {
+				io.WriteString(c, listing.String())
+				c.Close()
+			}
+			reply("226 done")
+		case "MKD":
+			s.mu.Lock()
+			ok := s.dirs[path.Dir(arg)] && !s.dirs[arg]
+			if ok {
+				s.dirs[arg] = true
+			}
+			s.mu.Unlock()
+			if ok {
+				reply("257 created")
+			} else {
+				reply("550 cannot create")
+			}
+		case "STOR":
+			reply("150 send it")
+			c := accept()
+			if c == nil {
+				reply("425 no connection")
+				continue
+			}
+			body, _ := io.ReadAll(c)
+			c.Close()
+			s.mu.Lock()
+			s.files[arg] = fakeFile{data: string(body), modTime: time.Now()}
+			s.stored++
+			s.mu.Unlock()
+			reply("226 stored")
+		case "MFMT":
+			stamp, name, _ := strings.Cut(arg, " ")
+			mod, _ := parseFTPTime(stamp)
+			s.mu.Lock()
+			f := s.files[name]
+			f.modTime = mod
+			s.files[name] = f
+			s.mu.Unlock()
+			reply("213 modify=%s; %s", stamp, name)
+		case "MDTM":
+			f, ok := s.file(arg)
+			if !ok {
+				reply("550 no such file")
+				continue
+			}
+			reply("213 %s", f.modTime.UTC().Format(ftpTimeLayout))
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package deploy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/proxy"
+)
+
+// Minimal FTP client, just enough to mirror a directory tree: login with
+// optional explicit (AUTH TLS) or implicit TLS, passive data connections
+// (EPSV, falling back to PASV), MLSD listings (LIST and MDTM on servers
+// without them), MKD, STOR and MFMT. Connections go through the HTTP proxy
+// with CONNECT when one is set. Keeping it in-tree avoids an FTP dependency.
+
+// ftpTimeout bounds every command and data transfer step, so a stalled
+// server fails the deploy instead of hanging the job.
+const ftpTimeout = 2 * time.Minute
+
+type ftpConn struct {
+	conn     net.Conn
+	text     *textproto.Conn
+	host     string      // host name for data connections and TLS
+	tls      *tls.Config // nil = plain FTP
+	features map[string]bool
+	noMLSD   bool        // the server rejected MLSD; list with LIST
+	noMDTM   bool        // the server rejected MDTM
+	stop     func() bool // releases the context watcher
+}
+
+// ftpEntry is a file from a directory listing.
+type ftpEntry struct {
+	Size    int64
+	ModTime time.Time // UTC, second precision; zero when the listing has none
+}
+
+// dialTCP connects to addr, tunneling through the HTTPS proxy (proxy_url or
+// HTTPS_PROXY) with HTTP CONNECT unless NO_PROXY exempts the host.
+func dialTCP(ctx context.Context, addr string) (net.Conn, error) {
+	return proxy.Dial(ctx, &url.URL{Scheme: "https", Host: addr}, addr)
+}
+
+// dialFTP connects to addr and logs in. tlsMode is one of the FTPTLS
+// constants.
+func dialFTP(ctx context.Context, addr, host, tlsMode, user, password string, tlsConfig *tls.Config) (*ftpConn, error) {
+	conn, err := dialTCP(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	c := &ftpConn{conn: conn, host: host}
+	if tlsMode != FTPTLSOff {
+		c.tls = tlsConfig
+	}
+	if tlsMode == FTPTLSImplicit {
+		if err := c.startTLS(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	} else {
+		c.text = textproto.NewConn(conn)
+	}
+	// Unblock any pending read or write when ctx is canceled.
+	c.stop = context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Now()) })
+
+	if err := c.login(ctx, tlsMode, user, password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// startTLS replaces the control connection with a TLS client on top of it.
+func (c *ftpConn) startTLS(ctx context.Context) error {
+	tlsConn := tls.Client(c.conn, c.tls)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake with %s: %w", c.host, err)
+	}
+	c.conn = tlsConn
+	c.text = textproto.NewConn(tlsConn)
+	return nil
+}
+
+func (c *ftpConn) login(ctx context.Context, tlsMode, user, password string) error {
+	if _, _, err := c.read(2); err != nil {
+		return fmt.Errorf("server greeting: %w", err)
+	}
+	if tlsMode == FTPTLSExplicit {
+		if _, err := c.cmd(2, "AUTH TLS"); err != nil {
+			return fmt.Errorf("AUTH TLS (set tls = %q if the server has no TLS): %w", FTPTLSOff, err)
+		}
+		if err := c.startTLS(ctx); err != nil {
+			return err
+		}
+	}
+
+	code, msg, err := c.send("USER %s", user)
+	if err == nil && code/100 == 3 {
+		code, msg, err = c.send("PASS %s", password)
+	}
+	if err != nil {
+		return err
+	}
+	if code/100 != 2 {
+		return fmt.Errorf("login as %q: %d %s", user, code, msg)
+	}
+
+	if c.tls != nil {
+		// Protect the data connections as well as the control connection.
+		if _, err := c.cmd(2, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := c.cmd(2, "PROT P"); err != nil {
+			return err
+		}
+	}
+	if _, err := c.cmd(2, "TYPE I"); err != nil {
+		return err
+	}
+
+	c.features = make(map[string]bool)
+	if code, msg, err := c.send("FEAT"); err == nil && code == 211 {
+		for _, line := range strings.Split(msg, "\n") {
+			if name, _, _ := strings.Cut(strings.TrimSpace(line), " "); name != "" {
+				c.features[strings.ToUpper(name)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// Close ends the session, ignoring errors: the uploads are complete by the
+// time it is called.
+func (c *ftpConn) Close() error {
+	if c.text != nil {
+		c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+		c.text.Cmd("QUIT")
+	}
+	if c.stop != nil {
+		c.stop()
+	}
+	return c.conn.Close()
+}
+
+// send sends a command and returns the reply, whatever its code.
+func (c *ftpConn) send(format string, args ...any) (int, string, error) {
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.read(0)
+}
+
+// cmd sends a command and fails unless the reply code starts with expect.
+func (c *ftpConn) cmd(expect int, format string, args ...any) (string, error) {
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return "", err
+	}
+	_, msg, err := c.read(expect)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+	}
+	return msg, nil
+}
+
+// read reads a reply; expect is the required first digit of the code, or 0
+// for any.
+func (c *ftpConn) read(expect int) (int, string, error) {
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	return c.text.ReadResponse(expect)
+}
+
+// replyCode returns the FTP reply code carried by err, or 0.
+func replyCode(err error) int {
+	var te *textproto.Error
+	if errors.As(err, &te) {
+		return te.Code
+	}
+	return 0
+}
+
+// dataConn opens a passive data connection, protected with TLS when the
+// control connection is.
+func (c *ftpConn) dataConn(ctx context.Context) (net.Conn, error) {
+	port, err := c.passivePort()
+	if err != nil {
+		return nil, err
+	}
+	// Connect to the control host rather than the address PASV reports,
+	// which is often a private address behind NAT.
+	addr := net.JoinHostPort(c.host, strconv.Itoa(port))
+	conn, err := dialTCP(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("opening data connection to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if c.tls != nil {
+		// The shared session cache lets servers that require TLS session
+		// reuse on data connections (vsftpd, ProFTPD) accept it.
+		conn = tls.Client(conn, c.tls)
+	}
+	return conn, nil
+}
+
+// passivePort asks the server for a data port with EPSV, or PASV when the
+// server does not know EPSV.
+func (c *ftpConn) passivePort() (int, error) {
+	code, msg, err := c.send("EPSV")
+	if err == nil && code == 229 {
+		return parseEPSV(msg)
+	}
+	msg, err = c.cmd(2, "PASV")
+	if err != nil {
+		return 0, err
+	}
+	return parsePASV(msg)
+}
+
+// parseEPSV extracts the port from "Entering Extended Passive Mode (|||6446|)".
+func parseEPSV(msg string) (int, error) {
+	start := strings.Index(msg, "(")
+	end := strings.LastIndex(msg, ")")
+	if start < 0 || end < start+5 {
+		return 0, fmt.Errorf("malformed EPSV reply %q", msg)
+	}
+	fields := strings.Split(msg[start+1:end], msg[start+1:start+2])
+	if len(fields) != 5 {
+		return 0, fmt.Errorf("malformed EPSV reply %q", msg)
+	}
+	port, err := strconv.Atoi(fields[3])
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("malformed EPSV reply %q", msg)
+	}
+	return port, nil
+}
+
+// parsePASV extracts the port from "Entering Passive Mode (h1,h2,h3,h4,p1,p2)".
+func parsePASV(msg string) (int, error) {
+	start := strings.Index(msg, "(")
+	end := strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("malformed PASV reply %q", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("malformed PASV reply %q", msg)
+	}
+	hi, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	lo, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil || hi < 0 || hi > 255 || lo < 0 || lo > 255 {
+		return 0, fmt.Errorf("malformed PASV reply %q", msg)
+	}
+	return hi<<8 | lo, nil
+}
+
+// list returns the files in dir by name. ok is false when dir does not
+// exist. Servers that do not know MLSD are listed with LIST, which gives no
+// exact times; an empty LIST listing counts as a missing directory, since
+// some servers list a missing directory as empty.
+func (c *ftpConn) list(ctx context.Context, dir string) (files map[string]ftpEntry, ok bool, err error) {
+	if !c.noMLSD {
+		body, ok, err := c.retrieve(ctx, "MLSD", dir)
+		if !unknownCommand(err) {
+			if err != nil || !ok {
+				return nil, ok, err
+			}
+			return parseMLSD(body), true, nil
+		}
+		c.noMLSD = true
+	}
+	body, ok, err := c.retrieve(ctx, "LIST", dir)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	files = parseLIST(body)
+	return files, len(files) > 0, nil
+}
+
+// retrieve sends the listing command verb for dir and returns what the
+// server sends over the data connection. ok is false when dir does not
+// exist.
+func (c *ftpConn) retrieve(ctx context.Context, verb, dir string) (body string, ok bool, err error) {
+	data, err := c.dataConn(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer data.Close()
+	if _, err := c.cmd(1, "%s %s", verb, dir); err != nil {
+		if code := replyCode(err); code == 550 || code == 450 {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	b, err := io.ReadAll(data)
+	data.Close()
+	if err != nil {
+		return "", false, fmt.Errorf("reading listing of %s: %w", dir, err)
+	}
+	if _, _, err := c.read(2); err != nil {
+		return "", false, fmt.Errorf("%s %s: %w", verb, dir, err)
+	}
+	return string(b), true, nil
+}
+
+// unknownCommand reports whether err is a reply saying the server does not
+// implement the command.
+func unknownCommand(err error) bool {
+	switch replyCode(err) {
+	case 500, 502, 504:
+		return true
+	}
+	return false
+}
+
+// modTime returns the modification time of path with MDTM, for files listed
+// with LIST. ok is false when the server cannot tell.
+func (c *ftpConn) modTime(path string) (t time.Time, ok bool) {
+	if c.noMDTM {
+		return time.Time{}, false
+	}
+	msg, err := c.cmd(2, "MDTM %s", path)
+	if unknownCommand(err) {
+		c.noMDTM = true
+	}
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err = parseFTPTime(strings.TrimSpace(msg))
+	return t, err == nil
+}
+
+// parseMLSD parses MLSD lines such as
+// "type=file;size=1234;modify=20240102030405; name" into the regular files.
+func parseMLSD(body string) map[string]ftpEntry {
+	files := make(map[string]ftpEntry)
+	for _, line := range strings.Split(body, "\n") {
+		facts, name, ok := strings.Cut(strings.TrimRight(line, "\r"), " ")
+		if !ok || name == "" {
+			continue
+		}
+		var entry ftpEntry
+		isFile := false
+		for _, fact := range strings.Split(facts, ";") {
+			key, val, _ := strings.Cut(fact, "=")
+			switch strings.ToLower(key) {
+			case "type":
+				isFile = strings.EqualFold(val, "file")
+			case "size":
+				entry.Size, _ = strconv.ParseInt(val, 10, 64)
+			case "modify":
+				entry.ModTime, _ = parseFTPTime(val)
+			}
+		}
+		if isFile {
+			files[name] = entry
+		}
+	}
+	return files
+}
+
+// parseLIST parses the regular files of a Unix-style LIST listing, such as
+// "-rw-r--r--   1 owner group   1234 Jan 02 03:04 x0 z0.prbm.gz", by name,
+// with their size only: LIST times are rounded to the minute, or to the
+// day for older files, in the server's time zone.
+func parseLIST(body string) map[string]ftpEntry {
+	files := make(map[string]ftpEntry)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+		// Eight fields before the name, which may hold spaces.
+		fields := strings.Fields(line)
+		if len(fields) < 9 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		rest := line
+		for range 8 {
+			rest = strings.TrimLeft(rest, " ")
+			rest = rest[strings.IndexByte(rest, ' '):]
+		}
+		files[strings.TrimLeft(rest, " ")] = ftpEntry{Size: size}
+	}
+	return files
+}
+
+// ftpTimeLayout is the MLSD/MFMT timestamp format (RFC 3659), always UTC.
+const ftpTimeLayout = "20060102150405"
+
+func parseFTPTime(s string) (time.Time, error) {
+	s, _, _ = strings.Cut(s, ".") // drop fractional seconds
+	return time.ParseInLocation(ftpTimeLayout, s, time.UTC)
+}
+
+// mkdirAll creates dir and any missing parents. Directories that already
+// exist, possibly created by another connection meanwhile, are not errors.
+func (c *ftpConn) mkdirAll(dir string) error {
+	if _, err := c.cmd(2, "MKD %s", dir); err == nil {
+		return nil
+	}
+	prefix := ""
+	if strings.HasPrefix(dir, "/") {
+		prefix = "/"
+	}
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		prefix += part
+		code, msg, err := c.send("MKD %s", prefix)
+		if err != nil {
+			return err
+		}
+		if code/100 != 2 && code != 550 && code != 521 {
+			return fmt.Errorf("MKD %s: %d %s", prefix, code, msg)
+		}
+		prefix += "/"
+	}
+	return nil
+}
+
+// store uploads r to path, replacing the remote file.
+func (c *ftpConn) store(ctx context.Context, path string, r io.Reader) error {
+	data, err := c.dataConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	if _, err := c.cmd(1, "STOR %s", path); err != nil {
+		return err
+	}
+	if tc, ok := data.(*tls.Conn); ok {
+		// Handshake explicitly so empty files are sent over TLS too.
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("TLS handshake for %s: %w", path, err)
+		}
+	}
+	// Extend the data deadline as the upload makes progress.
+	buf := make([]byte, 256<<10)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			data.SetDeadline(time.Now().Add(ftpTimeout))
+			if _, err := data.Write(buf[:n]); err != nil {
+				return fmt.Errorf("uploading %s: %w", path, err)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("uploading %s: %w", path, err)
+	}
+	if _, _, err := c.read(2); err != nil {
+		return fmt.Errorf("STOR %s: %w", path, err)
+	}
+	return nil
+}
+
+// setModTime sets the modification time of path when the server supports
+// MFMT, so the next deploy can compare it with the local file.
+func (c *ftpConn) setModTime(path string, t time.Time) error {
+	if !c.features["MFMT"] {
+		return nil
+	}
+	_, err := c.cmd(2, "MFMT %s %s", t.UTC().Format(ftpTimeLayout), path)
+	return err
+}
@@ -0,0 +1,44 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+// WriteHtaccess writes web/.htaccess under serverDir so Apache and LiteSpeed,
+// the usual servers behind FTP-only hosting, send the pre-compressed files
+// the webapp references directly with their Content-Encoding.
+func WriteHtaccess(serverDir string) error {
+	path := filepath.Join(serverDir, "web", ".htaccess")
+	if err := os.WriteFile(path, []byte(htaccess()), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func htaccess() string {
+	var sb strings.Builder
+	sb.WriteString("# Generated by bluemap-action (deploy_target = \"ftp\").\n")
+	sb.WriteString("# The webapp requests the .gz tiles and textures directly and needs them sent with Content-Encoding: gzip.\n")
+	sb.WriteString("<IfModule mod_mime.c>\n")
+	sb.WriteString("    AddEncoding gzip .gz\n")
+	for _, rule := range webmeta.CompressedRules() {
+		sb.WriteString(fmt.Sprintf("    <FilesMatch \"%s\">\n", globToFilesMatch(rule.Pattern)))
+		sb.WriteString(fmt.Sprintf("        ForceType %s\n", rule.ContentType))
+		sb.WriteString("    </FilesMatch>\n")
+	}
+	sb.WriteString("</IfModule>\n")
+	return sb.String()
+}
+
+// globToFilesMatch converts a URL glob such as "/*.prbm.gz" into the file
+// name regular expression of a FilesMatch section.
+func globToFilesMatch(pattern string) string {
+	name := pattern[strings.LastIndex(pattern, "/")+1:]
+	return regexp.QuoteMeta(strings.TrimPrefix(name, "*")) + "$"
+}
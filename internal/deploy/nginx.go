@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+// NginxFile is the name of the nginx snippet written next to config.toml,
+// outside web/ so it is never published.
+const NginxFile = "nginx-bluemap.conf"
+
+// NginxOptions configures the generated nginx snippet.
+type NginxOptions struct {
+	Root  string // remote directory the map is deployed to
+	Cache webmeta.CachePolicy
+}
+
+// WriteNginx writes the nginx snippet for opts to serverDir and returns its
+// path.
+func WriteNginx(serverDir string, opts NginxOptions) (string, error) {
+	path := filepath.Join(serverDir, NginxFile)
+	if err := os.WriteFile(path, []byte(nginxConfig(opts)), 0o644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// nginxConfig returns the location blocks serving the map from opts.Root, to
+// be included in a server block. BlueMap stores tiles and textures.json only
+// gzip-compressed, so those locations answer requests for the plain names
+// with the .gz file through gzip_static, and missing tiles answer 204 so the
+// webapp renders them empty instead of logging errors.
+func nginxConfig(opts NginxOptions) string {
+	cache := make(map[string]string)
+	for _, rule := range webmeta.CacheRules(opts.Cache) {
+		cache[rule.Pattern] = rule.CacheControl
+	}
+	header := func(indent, pattern string) string {
+		if v, ok := cache[pattern]; ok {
+			return fmt.Sprintf("%sadd_header Cache-Control %q always;\n", indent, v)
+		}
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by bluemap-action (deploy_target = \"ssh\").\n")
+	sb.WriteString("# Include inside a server { } block. Requires ngx_http_gzip_static_module.\n")
+	sb.WriteString(fmt.Sprintf("location / {\n    root %s;\n", strings.TrimSuffix(opts.Root, "/")))
+	sb.WriteString("    gzip_static on;\n")
+	// The other asset files take the value of the image rules; unlike the
+	// globs, the location covers every type. The hashed bundle overrides it.
+	sb.WriteString("\n    location /assets/ {\n")
+	sb.WriteString(header("        ", "/assets/*.png"))
+	sb.WriteString("\n        location ~ ^/assets/index-[^/]+\\.js$ {\n")
+	sb.WriteString(header("            ", "/assets/index-*.js*"))
+	sb.WriteString("        }\n")
+	sb.WriteString("\n        location ~ ^/assets/index-[^/]+\\.css$ {\n")
+	sb.WriteString(header("            ", "/assets/index-*.css*"))
+	sb.WriteString("        }\n")
+	sb.WriteString("    }\n")
+
+	sb.WriteString("\n    location ~ ^/maps/[^/]+/tiles/ {\n")
+	sb.WriteString("        gzip_static always;\n")
+	sb.WriteString("        default_type application/octet-stream;\n")
+	sb.WriteString("        error_page 404 = @bluemap_empty;\n")
+	sb.WriteString(header("        ", "/maps/*/tiles/*"))
+	sb.WriteString("    }\n")
+
+	sb.WriteString("\n    location ~ ^/maps/[^/]+/textures\\.json$ {\n")
+	sb.WriteString("        gzip_static always;\n")
+	sb.WriteString(header("        ", "/maps/*/textures.json*"))
+	sb.WriteString("    }\n")
+
+	sb.WriteString("\n    location ~ ^/(maps/[^/]+/)?settings\\.json$ {\n")
+	sb.WriteString(header("        ", "/maps/*/settings.json"))
+	sb.WriteString("    }\n")
+
+	sb.WriteString("\n    location ~ ^/maps/[^/]+/live/ {\n")
+	sb.WriteString(header("        ", "/maps/*/live/*"))
+	sb.WriteString("    }\n")
+
+	sb.WriteString("\n    try_files $uri $uri/ /index.html;\n")
+	sb.WriteString("}\n")
+
+	sb.WriteString("\nlocation @bluemap_empty {\n    return 204;\n}\n")
+	return sb.String()
+}
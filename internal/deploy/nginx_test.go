@@ -0,0 +1,32 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+func TestNginxConfig(t *testing.T) {
+	conf := nginxConfig(NginxOptions{Root: "/var/www/map/", Cache: webmeta.CachePolicy{Data: webmeta.CacheOff}})
+
+	for _, want := range []string{
+		"root /var/www/map;",
+		"gzip_static always;",
+		"error_page 404 = @bluemap_empty;",
+		`add_header Cache-Control "` + webmeta.DefaultTilesCache + `" always;`,
+		`add_header Cache-Control "` + webmeta.DefaultAssetFilesCache + `" always;`,
+		`location ~ ^/assets/index-[^/]+\.js$ {`,
+		"try_files $uri $uri/ /index.html;",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("nginx config missing %q:\n%s", want, conf)
+		}
+	}
+	if strings.Contains(conf, webmeta.DefaultDataCache) {
+		t.Errorf("nginx config sets the data Cache-Control despite %q:\n%s", webmeta.CacheOff, conf)
+	}
+	if strings.Count(conf, "{") != strings.Count(conf, "}") {
+		t.Errorf("nginx config has unbalanced braces:\n%s", conf)
+	}
+}
@@ -0,0 +1,368 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+// Environment variables holding the S3 credentials, the names the AWS tools
+// use so existing CI secrets work unchanged.
+const (
+	S3AccessKeyEnv    = "AWS_ACCESS_KEY_ID"
+	S3SecretKeyEnv    = "AWS_SECRET_ACCESS_KEY"
+	S3SessionTokenEnv = "AWS_SESSION_TOKEN"
+)
+
+// DefaultS3Concurrency is the number of parallel requests when concurrency
+// is not set in config.toml.
+const DefaultS3Concurrency = 16
+
+// s3Attempts is how often a request is tried before the deploy fails. Bulk
+// uploads of thousands of tiles regularly meet a transient 5xx or SlowDown.
+const s3Attempts = 3
+
+// S3Options configures a deploy to S3-compatible object storage.
+type S3Options struct {
+	Bucket      string
+	Endpoint    string // e.g. "https://<account>.r2.cloudflarestorage.com"; empty = AWS S3 in Region
+	Region      string // empty = "auto" with an endpoint, "us-east-1" without
+	Prefix      string // key prefix the contents of web/ are stored under
+	Concurrency int    // parallel requests; 0 = DefaultS3Concurrency
+	Delete      bool   // delete objects under Prefix that are not in web/
+	Cache       webmeta.CachePolicy
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3 uploads the web output to an S3-compatible bucket with the
+// Content-Type, Content-Encoding and Cache-Control each file needs, so any
+// CDN in front of the bucket serves the BlueMap webapp as-is. Objects whose
+// ETag matches the MD5 of the local file are skipped.
+type S3 struct {
+	opts   S3Options
+	client *http.Client
+}
+
+// NewS3 returns an S3 deployer.
+func NewS3(opts S3Options) *S3 {
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+		if opts.Endpoint != "" {
+			opts.Region = "auto"
+		}
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+	if opts.Prefix != "" {
+		opts.Prefix += "/"
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultS3Concurrency
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.Concurrency
+	return &S3{opts: opts, client: &http.Client{Timeout: 5 * time.Minute, Transport: transport}}
+}
+
+// Name returns the bucket and prefix, and the endpoint host when it is not
+// AWS.
+func (s *S3) Name() string {
+	name := "s3://" + s.opts.Bucket + "/" + s.opts.Prefix
+	if s.opts.Endpoint != "" {
+		if u, err := url.Parse(s.opts.Endpoint); err == nil {
+			name += " (" + u.Host + ")"
+		}
+	}
+	return name
+}
+
+// bucketURL returns the URL of the bucket. Custom endpoints get path-style
+// URLs, which every S3-compatible store supports; AWS gets virtual-hosted
+// URLs, the only style new AWS buckets accept.
+func (s *S3) bucketURL() string {
+	if s.opts.Endpoint != "" {
+		return s.opts.Endpoint + "/" + s.opts.Bucket
+	}
+	return "https://" + s.opts.Bucket + ".s3." + s.opts.Region + ".amazonaws.com"
+}
+
+// Check lists a single object, so validate can report bad credentials or a
+// wrong bucket before a render.
+func (s *S3) Check(ctx context.Context) error {
+	_, _, err := s.listPage(ctx, "", 1)
+	return err
+}
+
+// Deploy uploads the files of webDir that differ from the bucket, then
+// deletes objects no longer in webDir when opts.Delete is set.
+func (s *S3) Deploy(ctx context.Context, webDir string) error {
+	if s.opts.AccessKeyID == "" || s.opts.SecretAccessKey == "" {
+		return fmt.Errorf("%s and %s must be set for deploy_target \"s3\"", S3AccessKeyEnv, S3SecretKeyEnv)
+	}
+	dirs, err := collectDirs(webDir)
+	if err != nil {
+		return err
+	}
+	remote, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	var files []localFile
+	local := make(map[string]bool)
+	for _, dirFiles := range dirs {
+		for _, lf := range dirFiles {
+			files = append(files, lf)
+			local[s.opts.Prefix+lf.rel] = true
+		}
+	}
+
+	var uploaded, skipped, deleted, uploadedBytes atomic.Int64
+	err = s.parallel(ctx, len(files), func(ctx context.Context, i int) error {
+		lf := files[i]
+		body, err := os.ReadFile(filepath.Join(webDir, filepath.FromSlash(lf.rel)))
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(body)
+		if etag, ok := remote[s.opts.Prefix+lf.rel]; ok && etag == hex.EncodeToString(sum[:]) {
+			skipped.Add(1)
+			return nil
+		}
+		if err := s.put(ctx, lf.rel, body, sum[:]); err != nil {
+			return err
+		}
+		uploaded.Add(1)
+		uploadedBytes.Add(int64(len(body)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.opts.Delete {
+		var stale []string
+		for key := range remote {
+			if !local[key] {
+				stale = append(stale, key)
+			}
+		}
+		err = s.parallel(ctx, len(stale), func(ctx context.Context, i int) error {
+			if err := s.delete(ctx, stale[i]); err != nil {
+				return err
+			}
+			deleted.Add(1)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("    %d files uploaded (%.1f MiB), %d unchanged, %d deleted\n",
+		uploaded.Load(), float64(uploadedBytes.Load())/(1<<20), skipped.Load(), deleted.Load())
+	return nil
+}
+
+// parallel runs fn for 0..n-1 on opts.Concurrency goroutines and returns the
+// first error, canceling the remaining calls.
+func (s *S3) parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < min(s.opts.Concurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// put uploads one file with the metadata webmeta assigns to it.
+func (s *S3) put(ctx context.Context, rel string, body, sum []byte) error {
+	meta := webmeta.Detect(rel)
+	header := http.Header{}
+	header.Set("Content-Type", meta.ContentType)
+	if meta.ContentEncoding != "" {
+		header.Set("Content-Encoding", meta.ContentEncoding)
+	}
+	if cc := webmeta.CacheControl(s.opts.Cache, rel); cc != "" {
+		header.Set("Cache-Control", cc)
+	}
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum))
+	u := s.bucketURL() + "/" + escapeKey(s.opts.Prefix+rel)
+	if _, err := s.do(ctx, http.MethodPut, u, header, body); err != nil {
+		return fmt.Errorf("uploading %s: %w", rel, err)
+	}
+	return nil
+}
+
+func (s *S3) delete(ctx context.Context, key string) error {
+	if _, err := s.do(ctx, http.MethodDelete, s.bucketURL()+"/"+escapeKey(key), nil, nil); err != nil {
+		return fmt.Errorf("deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// list returns the ETag of every object under the prefix by key.
+func (s *S3) list(ctx context.Context) (map[string]string, error) {
+	etags := make(map[string]string)
+	token := ""
+	for {
+		page, next, err := s.listPage(ctx, token, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page {
+			etags[obj.Key] = strings.Trim(obj.ETag, `"`)
+		}
+		if next == "" {
+			return etags, nil
+		}
+		token = next
+	}
+}
+
+type s3Object struct {
+	Key  string `xml:"Key"`
+	ETag string `xml:"ETag"`
+}
+
+// listPage returns one page of ListObjectsV2 and the token of the next page,
+// or "" for the last one.
+func (s *S3) listPage(ctx context.Context, token string, maxKeys int) ([]s3Object, string, error) {
+	q := url.Values{"list-type": {"2"}, "max-keys": {fmt.Sprint(maxKeys)}}
+	if s.opts.Prefix != "" {
+		q.Set("prefix", s.opts.Prefix)
+	}
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+	body, err := s.do(ctx, http.MethodGet, s.bucketURL()+"?"+canonicalQuery(q), nil, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("listing bucket %s: %w", s.opts.Bucket, err)
+	}
+	var result struct {
+		Contents              []s3Object `xml:"Contents"`
+		IsTruncated           bool       `xml:"IsTruncated"`
+		NextContinuationToken string     `xml:"NextContinuationToken"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, "", fmt.Errorf("parsing bucket listing: %w", err)
+	}
+	if !result.IsTruncated {
+		return result.Contents, "", nil
+	}
+	return result.Contents, result.NextContinuationToken, nil
+}
+
+// do sends a signed request, retrying network errors, 5xx and 429 replies,
+// and returns the response body.
+func (s *S3) do(ctx context.Context, method, rawURL string, header http.Header, body []byte) ([]byte, error) {
+	payloadHash := emptySHA256
+	if body != nil {
+		payloadHash = sha256Hex(body)
+	}
+	creds := s3Credentials{
+		AccessKeyID:     s.opts.AccessKeyID,
+		SecretAccessKey: s.opts.SecretAccessKey,
+		SessionToken:    s.opts.SessionToken,
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= s3Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * time.Second):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		signV4(req, creds, s.opts.Region, payloadHash, time.Now())
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode/100 == 2 {
+			return respBody, nil
+		}
+		lastErr = s3Error(resp.StatusCode, respBody)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// s3Error turns an S3 error response into an error with its code and
+// message.
+func s3Error(status int, body []byte) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("HTTP %d: %s: %s", status, e.Code, e.Message)
+	}
+	return fmt.Errorf("HTTP %d", status)
+}
@@ -0,0 +1,106 @@
+package deploy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+func TestS3Deploy(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{"map/stale.txt": "old"} // key → body
+	headers := map[string]http.Header{}
+	puts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" Credential=key/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket":
+			fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated>")
+			for k, body := range objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					sum := md5.Sum([]byte(body))
+					fmt.Fprintf(w, "<Contents><Key>%s</Key><ETag>&quot;%s&quot;</ETag></Contents>", k, hex.EncodeToString(sum[:]))
+				}
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[key] = string(body)
+			headers[key] = r.Header.Clone()
+			puts++
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	web := t.TempDir()
+	for rel, content := range map[string]string{
+		"index.html":                       "<html>",
+		"netlify.toml":                     "[build]",
+		"maps/world/tiles/0/x0/z0.prbm.gz": "tile",
+	} {
+		p := filepath.Join(web, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewS3(S3Options{
+		Bucket: "bucket", Endpoint: srv.URL, Prefix: "/map/", Delete: true,
+		AccessKeyID: "key", SecretAccessKey: "secret",
+	})
+	if err := d.Deploy(context.Background(), web); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+
+	mu.Lock()
+	if _, ok := objects["map/stale.txt"]; ok {
+		t.Error("stale object not deleted")
+	}
+	if _, ok := objects["map/netlify.toml"]; ok {
+		t.Error("netlify.toml uploaded")
+	}
+	tile := headers["map/maps/world/tiles/0/x0/z0.prbm.gz"]
+	if tile.Get("Content-Encoding") != "gzip" || tile.Get("Content-Type") != "application/octet-stream" ||
+		tile.Get("Cache-Control") != webmeta.DefaultTilesCache {
+		t.Errorf("tile uploaded with headers %v", tile)
+	}
+	if puts != 2 {
+		t.Errorf("uploaded %d objects, want 2", puts)
+	}
+	mu.Unlock()
+
+	// Unchanged files are skipped by ETag.
+	if err := d.Deploy(context.Background(), web); err != nil {
+		t.Fatalf("second Deploy: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if puts != 2 {
+		t.Errorf("second deploy uploaded %d objects, want 0", puts-2)
+	}
+}
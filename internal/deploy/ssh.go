@@ -0,0 +1,127 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by the SSH deployer, so keys never have to be
+// committed with the config.
+const (
+	SSHKeyEnv        = "BLUEMAP_SSH_PRIVATE_KEY" // private key contents, used unless identity_file is set
+	SSHKnownHostsEnv = "BLUEMAP_SSH_KNOWN_HOSTS" // known_hosts lines for the host; unset = accept a new host key
+)
+
+// sshExcludes are files of other deploy targets that a web server would
+// serve as ordinary files.
+var sshExcludes = []string{"/netlify.toml", "/_headers"}
+
+// SSHOptions configures an rsync-over-SSH deploy.
+type SSHOptions struct {
+	Host         string
+	User         string // empty = the SSH default
+	Port         int    // 0 = 22
+	Path         string // remote directory receiving the contents of web/
+	Delete       bool   // delete remote files that are not in web/
+	BandwidthKiB int64  // rsync --bwlimit in KiB/s; 0 = unlimited
+	IdentityFile string // private key file; empty = SSHKeyEnv or the SSH agent
+}
+
+// SSH deploys with rsync over SSH, which transfers only changed files.
+type SSH struct {
+	opts SSHOptions
+}
+
+// NewSSH returns an SSH deployer.
+func NewSSH(opts SSHOptions) *SSH {
+	return &SSH{opts: opts}
+}
+
+// Name returns the rsync destination.
+func (s *SSH) Name() string {
+	return s.destination()
+}
+
+func (s *SSH) destination() string {
+	host := s.opts.Host
+	if s.opts.User != "" {
+		host = s.opts.User + "@" + host
+	}
+	return host + ":" + strings.TrimSuffix(s.opts.Path, "/") + "/"
+}
+
+// Deploy syncs webDir to the remote path with rsync, streaming its progress.
+func (s *SSH) Deploy(ctx context.Context, webDir string) error {
+	if _, err := exec.LookPath("rsync"); err != nil {
+		return fmt.Errorf("rsync is required for deploy_target \"ssh\": %w", err)
+	}
+
+	identity := s.opts.IdentityFile
+	if identity == "" {
+		if key := os.Getenv(SSHKeyEnv); key != "" {
+			if !strings.HasSuffix(key, "\n") {
+				key += "\n" // OpenSSH rejects keys without the final newline
+			}
+			path, cleanup, err := writeSecret("bluemap-ssh-key-*", []byte(key))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			identity = path
+		}
+	}
+	knownHosts := ""
+	if hosts := os.Getenv(SSHKnownHostsEnv); hosts != "" {
+		path, cleanup, err := writeSecret("bluemap-known-hosts-*", []byte(hosts))
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		knownHosts = path
+	} else {
+		fmt.Fprintf(os.Stderr, "⚠️  %s is not set; accepting the host key of %s on first use\n", SSHKnownHostsEnv, s.opts.Host)
+	}
+
+	args := s.rsyncArgs(webDir, identity, knownHosts)
+	cmd := exec.CommandContext(ctx, "rsync", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("rsync to %s: %w", s.destination(), err)
+	}
+	return nil
+}
+
+// rsyncArgs returns the rsync arguments syncing webDir to the destination.
+func (s *SSH) rsyncArgs(webDir, identity, knownHosts string) []string {
+	ssh := []string{"ssh", "-o", "BatchMode=yes"}
+	if s.opts.Port != 0 {
+		ssh = append(ssh, "-p", strconv.Itoa(s.opts.Port))
+	}
+	if identity != "" {
+		ssh = append(ssh, "-i", identity, "-o", "IdentitiesOnly=yes")
+	}
+	if knownHosts != "" {
+		ssh = append(ssh, "-o", "UserKnownHostsFile="+knownHosts, "-o", "StrictHostKeyChecking=yes")
+	} else {
+		ssh = append(ssh, "-o", "StrictHostKeyChecking=accept-new")
+	}
+
+	// Tiles are already compressed, so -z would only cost CPU.
+	args := []string{"--recursive", "--links", "--times", "--partial", "--info=stats1", "-e", strings.Join(ssh, " ")}
+	if s.opts.Delete {
+		// Delete after the transfer so the old map stays complete until then.
+		args = append(args, "--delete-after")
+	}
+	if s.opts.BandwidthKiB > 0 {
+		args = append(args, "--bwlimit="+strconv.FormatInt(s.opts.BandwidthKiB, 10))
+	}
+	for _, e := range sshExcludes {
+		args = append(args, "--exclude="+e)
+	}
+	return append(args, strings.TrimSuffix(webDir, "/")+"/", s.destination())
+}
@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRsyncArgs(t *testing.T) {
+	s := NewSSH(SSHOptions{
+		Host:         "map.example.com",
+		User:         "deploy",
+		Port:         2222,
+		Path:         "/var/www/map/",
+		Delete:       true,
+		BandwidthKiB: 5120,
+	})
+	args := s.rsyncArgs("/srv/survival/web", "/tmp/key", "/tmp/known_hosts")
+	got := strings.Join(args, "\n")
+
+	for _, want := range []string{
+		"--delete-after",
+		"--bwlimit=5120",
+		"--exclude=/netlify.toml",
+		"ssh -o BatchMode=yes -p 2222 -i /tmp/key -o IdentitiesOnly=yes -o UserKnownHostsFile=/tmp/known_hosts -o StrictHostKeyChecking=yes",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rsync args missing %q:\n%s", want, got)
+		}
+	}
+	// The trailing slash copies the contents of web/, not web/ itself.
+	if src, dst := args[len(args)-2], args[len(args)-1]; src != "/srv/survival/web/" || dst != "deploy@map.example.com:/var/www/map/" {
+		t.Errorf("source, destination = %q, %q", src, dst)
+	}
+
+	s = NewSSH(SSHOptions{Host: "map.example.com", Path: "/var/www/map"})
+	got = strings.Join(s.rsyncArgs("web", "", ""), "\n")
+	for _, unwanted := range []string{"--delete", "--bwlimit", "-p", "-i "} {
+		if strings.Contains(got, "\n"+unwanted) || strings.Contains(got, " "+unwanted+" ") {
+			t.Errorf("rsync args contain %q without the option set:\n%s", unwanted, got)
+		}
+	}
+	if !strings.Contains(got, "StrictHostKeyChecking=accept-new") {
+		t.Errorf("rsync args without known hosts should accept a new host key:\n%s", got)
+	}
+}
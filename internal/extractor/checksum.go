@@ -0,0 +1,118 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ChecksumError reports a downloaded backup whose hash differs from the one
+// the panel recorded, typically a truncated or corrupted transfer.
+type ChecksumError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("backup %s checksum mismatch: expected %s, got %s (truncated or corrupted download)",
+		e.Algorithm, e.Expected, e.Actual)
+}
+
+// checksum hashes a download and compares it with an expected value.
+type checksum struct {
+	algo string
+	want []byte
+	hash.Hash
+}
+
+// parseChecksum parses a checksum in Pterodactyl's "<algorithm>:<hex>" form,
+// e.g. "sha1:2fd4e1c6…". A bare hex digest is taken as SHA-1, which Wings
+// uses. It returns nil for an empty string.
+func parseChecksum(s string) (*checksum, error) {
+	if s == "" {
+		return nil, nil
+	}
+	algo, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		algo, digest = "sha1", s
+	}
+	algo = strings.ToLower(algo)
+	var h hash.Hash
+	switch algo {
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil || len(want) != h.Size() {
+		return nil, fmt.Errorf("invalid %s checksum %q", algo, digest)
+	}
+	return &checksum{algo: algo, want: want, Hash: h}, nil
+}
+
+// verify compares the bytes hashed so far with the expected digest.
+func (c *checksum) verify() error {
+	got := c.Sum(nil)
+	if !bytes.Equal(got, c.want) {
+		return &ChecksumError{Algorithm: c.algo, Expected: hex.EncodeToString(c.want), Actual: hex.EncodeToString(got)}
+	}
+	fmt.Printf("  ✔  %s checksum verified\n", c.algo)
+	return nil
+}
+
+// hashPollInterval is how often hashWritten checks for newly downloaded bytes.
+const hashPollInterval = 50 * time.Millisecond
+
+// hashWritten feeds the file written by a parallel download into h in order,
+// while the download is running. Worker i fills [starts[i], starts[i+1]) and
+// publishes the end of its written prefix in written[i]; hashWritten reads
+// each section as far as it is written and waits for more. It returns once
+// all total bytes are hashed, or with an error when done is closed first
+// (the download failed) or ctx is cancelled.
+func hashWritten(ctx context.Context, f *os.File, h hash.Hash, starts []int64, written []atomic.Int64, total int64, done <-chan struct{}) error {
+	ticker := time.NewTicker(hashPollInterval)
+	defer ticker.Stop()
+	pos := int64(0)
+	for i := range starts {
+		end := total
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		for pos < end {
+			if avail := min(written[i].Load(), end); avail > pos {
+				if _, err := io.Copy(h, io.NewSectionReader(f, pos, avail-pos)); err != nil {
+					return fmt.Errorf("hashing download: %w", err)
+				}
+				pos = avail
+				continue
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				// The workers have stopped; hash what they wrote or give up.
+				if written[i].Load() <= pos {
+					return fmt.Errorf("download stopped at byte %d of %d", pos, total)
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
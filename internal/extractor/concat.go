@@ -0,0 +1,86 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// blockSize is the tar block size.
+const blockSize = 512
+
+// concatTar reads a stream of one or more tar archives written back to back,
+// as produced by `tar --concatenate`, `cat a.tar b.tar` or backup tools that
+// append one archive per run. archive/tar stops at the first end-of-archive
+// marker; concatTar skips the zero blocks that follow it and carries on with
+// the next archive, like GNU tar's --ignore-zeros.
+//
+// Multistream gzip (several gzip members in one file) needs no handling here:
+// pgzip, like compress/gzip, reads all members as one stream by default.
+type concatTar struct {
+	r        io.Reader
+	tr       *tar.Reader
+	archives int
+}
+
+func newConcatTar(r io.Reader) *concatTar {
+	return &concatTar{r: r, tr: tar.NewReader(r), archives: 1}
+}
+
+// Next advances to the next entry, crossing into the next archive when the
+// current one ends.
+func (c *concatTar) Next() (*tar.Header, error) {
+	for {
+		header, err := c.tr.Next()
+		if !errors.Is(err, io.EOF) {
+			return header, err
+		}
+
+		block, err := c.nextNonZeroBlock()
+		if err != nil {
+			return nil, err
+		}
+		c.tr = tar.NewReader(io.MultiReader(bytes.NewReader(block), c.r))
+		c.archives++
+	}
+}
+
+// Read reads the data of the current entry.
+func (c *concatTar) Read(p []byte) (int, error) {
+	return c.tr.Read(p)
+}
+
+// nextNonZeroBlock skips the zero padding after an end-of-archive marker and
+// returns the first block of the next archive, or io.EOF at the end of the
+// stream.
+func (c *concatTar) nextNonZeroBlock() ([]byte, error) {
+	block := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(c.r, block)
+		switch {
+		case err == io.EOF:
+			return nil, io.EOF
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			if isZero(block[:n]) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading tar entry: %d stray bytes after archive %d", n, c.archives)
+		case err != nil:
+			return nil, err
+		}
+		if !isZero(block) {
+			return block, nil
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -2,13 +2,15 @@ package extractor
 
 import (
 	"archive/tar"
-	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,8 +26,100 @@ const (
 
 // DownloadOptions configures the download behavior.
 type DownloadOptions struct {
-	Mode        string // "auto", "parallel", "single"
+	Mode        string // "auto", "parallel", "parallel-stream", "single"
 	Connections int    // 0 = auto (size-based scaling), >0 = manual override (1-32)
+	RateLimit   int64  // total download bandwidth in bytes per second across all connections; 0 = unlimited
+	Checksum    string // expected "<algorithm>:<hex>" checksum of the archive (Pterodactyl's backup checksum); empty = not verified
+	KeepArchive string // if set, the downloaded archive is preserved at this path
+
+	// StreamBuffer caps the memory, in bytes, that "parallel-stream" mode
+	// holds for ranges downloaded ahead of extraction; 0 selects
+	// DefaultStreamBuffer.
+	StreamBuffer int64
+
+	// Header is sent with every download request, e.g. the bearer token
+	// PufferPanel downloads need; nil for pre-signed URLs.
+	Header http.Header
+
+	// DecompressBlockSize and DecompressBlocks tune the parallel gzip reader:
+	// up to DecompressBlocks blocks of DecompressBlockSize bytes are
+	// decompressed ahead of the tar reader. 0 selects pgzip's defaults
+	// (250 kB × 16).
+	DecompressBlockSize int
+	DecompressBlocks    int
+
+	// Writers is the number of goroutines writing extracted files while the
+	// archive keeps being read; 0 picks one per CPU (up to 8) and 1 writes
+	// inline. Files are buffered in memory on their way to a writer, at most
+	// about 32 MiB per writer.
+	Writers int
+
+	// Sources maps a world folder to its path inside the backup when the two
+	// differ (e.g. "creative" → "worlds/creative"). Matching entries are
+	// extracted under the world folder name.
+	Sources map[string]string
+
+	// Include, if set, is called for every file of a matched world with its
+	// slash-separated path relative to the world folder; returning false
+	// skips the file.
+	Include func(world, rel string) bool
+
+	// Extra lists further backup paths (folders or single files, such as
+	// plugin data) that are extracted to the same relative path. Unlike
+	// worlds, paths missing from the backup are not reported.
+	Extra []string
+
+	// RequireWorlds lists world folders that must be present in the backup.
+	// If any is missing, extraction fails with a *MissingWorldsError once
+	// the archive has been read; other missing worlds only print a warning.
+	RequireWorlds []string
+
+	// OnMissing, if set, is called for every world not found in the backup,
+	// required or not, with the directories SuggestWorlds finds for it.
+	OnMissing func(world string, suggestions []string)
+
+	// MaxFileSize, MaxTotalSize and MaxEntries cap the size of a single
+	// file, the size of all files together and the number of files,
+	// folders and links extracted; zero uses DefaultMaxFileSize,
+	// DefaultMaxTotalSize and DefaultMaxEntries. Going over a cap fails the
+	// extraction with a *LimitError naming the entry.
+	MaxFileSize  int64
+	MaxTotalSize int64
+	MaxEntries   int
+
+	// OnRejected, if set, is called for every entry of a matched world that
+	// is skipped as unsafe: paths leaving the world folder, links pointing
+	// out of it, and device files.
+	OnRejected func(entry, reason string)
+
+	// IndexPath, if set, receives an Index of the archive tagged with
+	// BackupUUID once the whole archive has been read, so a later run can
+	// reuse a kept archive with ExtractArchive.
+	IndexPath  string
+	BackupUUID string
+
+	// SeekIndexPath is where "indexed" mode keeps the Index of the backup
+	// tagged with BackupUUID between runs.
+	SeekIndexPath string
+
+	// Timings, if set, receives how long each stage took.
+	Timings *Timings
+
+	// Notice, if set, receives a parallel download's progress at each
+	// quarter and its average speed at the end, e.g. to annotate a CI run
+	// without repeating the progress printed every few seconds.
+	Notice func(message string)
+
+	index        *Index // set by ExtractArchive and extractRanges
+	indexMembers bool   // add the gzip members to the index after extraction
+}
+
+// Timings records the stages of a download. A streamed download overlaps
+// the extraction and is counted as extraction, leaving Download zero.
+type Timings struct {
+	Probe    time.Duration // Range probe of the download URL
+	Download time.Duration // parallel download into the temp file
+	Extract  time.Duration // reading the archive and writing the worlds
 }
 
 // connectionCount returns the number of parallel download connections to use
@@ -52,43 +146,83 @@ func connectionCount(contentLength int64) int {
 //     and the file is ≥ 64 MB, otherwise stream directly (no temp file).
 //   - "parallel" — force parallel download; returns an error if the server does
 //     not support Range requests or does not report Content-Length.
+//   - "parallel-stream" — like "parallel", but the ranges are extracted in
+//     order as they complete, buffered in memory up to opts.StreamBuffer,
+//     instead of being written to a temp file.
 //   - "single"   — force a single HTTP connection and stream the response body
 //     directly into the tar reader without writing a temp file to disk.
+//   - "indexed"  — with an index of the same backup at opts.SeekIndexPath
+//     whose archive has independent gzip members (bgzip), fetch only the
+//     byte ranges holding the worlds; otherwise download in parallel and
+//     write that index for the next run.
 //
 // opts.Connections overrides the automatic connection count when > 0.
+// opts.RateLimit, when > 0, caps the bandwidth of all connections together.
+//
+// opts.Checksum, when set, is verified against a hash computed while the
+// archive downloads. In parallel mode a mismatch fails before extraction; a
+// streamed archive is extracted as it arrives, so the mismatch is reported
+// once the stream ends and the extracted files must not be used.
+//
+// opts.KeepArchive, when set, preserves a copy of the downloaded archive at
+// that path for debugging (the temp file is moved there in parallel mode; the
+// stream is teed into it in the streaming modes).
+//
+// opts.Sources and opts.Include remap world folders inside the backup and
+// filter individual files (used for per-world source paths and bounds).
+//
+// Cancelling ctx aborts the download and extraction; temp files are removed.
 //
 // The backup is expected to be a tar.gz archive. World folders are matched by
 // checking if a tar entry path starts with one of the world names (e.g.
-// "world/", "world_nether/").
-func DownloadAndExtractWorlds(downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+// "world/", "world_nether/"), or with the world's source path when set.
+func DownloadAndExtractWorlds(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+	if _, err := parseChecksum(opts.Checksum); err != nil {
+		return err
+	}
+	if opts.IndexPath != "" {
+		// Any previous index describes an archive that is about to be
+		// replaced.
+		if err := os.Remove(opts.IndexPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing stale archive index: %w", err)
+		}
+	}
 	switch opts.Mode {
 	case "parallel":
-		return downloadParallelExtract(downloadURL, outputDir, worlds, opts.Connections)
+		return downloadParallelExtract(ctx, downloadURL, outputDir, worlds, opts)
+	case "parallel-stream":
+		return downloadParallelStreamExtract(ctx, downloadURL, outputDir, worlds, opts)
 	case "single":
 		fmt.Println("  → single-connection download (streaming, forced)")
-		return downloadStreamExtract(downloadURL, outputDir, worlds)
+		return downloadStreamExtract(ctx, downloadURL, outputDir, worlds, opts)
+	case "indexed":
+		return downloadIndexedExtract(ctx, downloadURL, outputDir, worlds, opts)
 	default: // "auto"
-		return downloadAutoExtract(downloadURL, outputDir, worlds, opts.Connections)
+		return downloadAutoExtract(ctx, downloadURL, outputDir, worlds, opts)
 	}
 }
 
 // downloadAutoExtract probes the server and chooses the best strategy:
 // parallel (temp file) when Range is supported and size ≥ 64 MB, otherwise
 // a single streaming connection (no temp file).
-func downloadAutoExtract(downloadURL, outputDir string, worlds []string, connOverride int) error {
-	contentLength, rangeOK, err := probeDownload(downloadURL)
+func downloadAutoExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+	probeStart := time.Now()
+	contentLength, rangeOK, err := probeDownload(ctx, downloadURL, opts.Header)
+	if opts.Timings != nil {
+		opts.Timings.Probe = time.Since(probeStart)
+	}
 	if err != nil {
 		return fmt.Errorf("probing download URL: %w", err)
 	}
 
 	if rangeOK && contentLength >= minParallelSize {
 		numWorkers := connectionCount(contentLength)
-		if connOverride > 0 {
-			numWorkers = connOverride
+		if opts.Connections > 0 {
+			numWorkers = opts.Connections
 		}
 		fmt.Printf("  → parallel download (%d connections, %s)\n",
 			numWorkers, formatBytes(contentLength))
-		return parallelDownloadAndExtract(downloadURL, outputDir, worlds, contentLength, numWorkers)
+		return parallelDownloadAndExtract(ctx, downloadURL, outputDir, worlds, contentLength, numWorkers, opts)
 	}
 
 	// Log why we are falling back to a single connection.
@@ -104,36 +238,51 @@ func downloadAutoExtract(downloadURL, outputDir string, worlds []string, connOve
 		fmt.Printf("  → single-connection download (%s, below %s parallel threshold)\n",
 			formatBytes(contentLength), formatBytes(minParallelSize))
 	}
-	return downloadStreamExtract(downloadURL, outputDir, worlds)
+	return downloadStreamExtract(ctx, downloadURL, outputDir, worlds, opts)
 }
 
 // downloadParallelExtract forces parallel download. It probes the server first
 // and returns an error if Range requests or Content-Length are not available.
-func downloadParallelExtract(downloadURL, outputDir string, worlds []string, connOverride int) error {
-	contentLength, rangeOK, err := probeDownload(downloadURL)
+func downloadParallelExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+	contentLength, numWorkers, err := probeParallel(ctx, downloadURL, opts)
 	if err != nil {
-		return fmt.Errorf("probing download URL: %w", err)
+		return err
+	}
+	fmt.Printf("  → parallel download (%d connections, %s, forced)\n",
+		numWorkers, formatBytes(contentLength))
+	return parallelDownloadAndExtract(ctx, downloadURL, outputDir, worlds, contentLength, numWorkers, opts)
+}
+
+// probeParallel probes the server for a parallel download and returns the
+// archive size and the number of connections to use. It returns an error if
+// Range requests or Content-Length are not available.
+func probeParallel(ctx context.Context, downloadURL string, opts DownloadOptions) (contentLength int64, numWorkers int, err error) {
+	probeStart := time.Now()
+	contentLength, rangeOK, err := probeDownload(ctx, downloadURL, opts.Header)
+	if opts.Timings != nil {
+		opts.Timings.Probe = time.Since(probeStart)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("probing download URL: %w", err)
 	}
 	if !rangeOK {
-		return fmt.Errorf("server does not support HTTP Range requests; cannot use parallel download mode")
+		return 0, 0, fmt.Errorf("server does not support HTTP Range requests; cannot use parallel download mode")
 	}
 	if contentLength <= 0 {
-		return fmt.Errorf("server did not return Content-Length; cannot use parallel download mode")
+		return 0, 0, fmt.Errorf("server did not return Content-Length; cannot use parallel download mode")
 	}
 
-	numWorkers := connectionCount(contentLength)
-	if connOverride > 0 {
-		numWorkers = connOverride
+	numWorkers = connectionCount(contentLength)
+	if opts.Connections > 0 {
+		numWorkers = opts.Connections
 	}
-
-	fmt.Printf("  → parallel download (%d connections, %s, forced)\n",
-		numWorkers, formatBytes(contentLength))
-	return parallelDownloadAndExtract(downloadURL, outputDir, worlds, contentLength, numWorkers)
+	return contentLength, numWorkers, nil
 }
 
 // parallelDownloadAndExtract downloads the file in parallel into a temp file,
-// then extracts worlds from it. The temp file is removed on return.
-func parallelDownloadAndExtract(downloadURL, outputDir string, worlds []string, contentLength int64, numWorkers int) error {
+// then extracts worlds from it. The temp file is removed on return, or moved
+// to opts.KeepArchive when set.
+func parallelDownloadAndExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, contentLength int64, numWorkers int, opts DownloadOptions) error {
 	// Create a temp file in outputDir for the downloaded archive.
 	// Using the same filesystem avoids cross-device rename issues and keeps
 	// disk usage predictable.
@@ -144,13 +293,23 @@ func parallelDownloadAndExtract(downloadURL, outputDir string, worlds []string,
 	tmpPath := tmpFile.Name()
 	defer os.Remove(tmpPath)
 
-	if err := downloadParallel(downloadURL, tmpFile, contentLength, numWorkers); err != nil {
+	sum, _ := parseChecksum(opts.Checksum)
+	downloadStart := time.Now()
+	if err := downloadParallel(ctx, downloadURL, opts.Header, tmpFile, contentLength, numWorkers, newRateLimiter(opts.RateLimit), sum, opts.Notice); err != nil {
 		tmpFile.Close()
 		return fmt.Errorf("parallel download: %w", err)
 	}
+	if opts.Timings != nil {
+		opts.Timings.Download = time.Since(downloadStart)
+	}
 	if err := tmpFile.Close(); err != nil {
 		return fmt.Errorf("closing temp file: %w", err)
 	}
+	if sum != nil {
+		if err := sum.verify(); err != nil {
+			return err
+		}
+	}
 
 	// Re-open the temp file for sequential extraction.
 	f, err := os.Open(tmpPath)
@@ -159,15 +318,35 @@ func parallelDownloadAndExtract(downloadURL, outputDir string, worlds []string,
 	}
 	defer f.Close()
 
-	return extractWorlds(f, outputDir, worlds)
+	if err := extractWorlds(ctx, f, outputDir, worlds, opts, opts.writers()); err != nil {
+		return err
+	}
+	if opts.indexMembers {
+		if err := indexDownload(tmpPath, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠️  could not index the archive: %v\n", err)
+		}
+	}
+
+	if opts.KeepArchive != "" {
+		if err := moveFile(tmpPath, opts.KeepArchive); err != nil {
+			return fmt.Errorf("preserving archive: %w", err)
+		}
+		fmt.Printf("  ✔  archive kept at %s\n", opts.KeepArchive)
+	}
+	return nil
 }
 
 // downloadStreamExtract downloads via a single HTTP connection and pipes the
-// response body directly into the tar reader — no temp file is written to disk.
-func downloadStreamExtract(downloadURL, outputDir string, worlds []string) error {
+// response body directly into the tar reader — no temp file is written to disk
+// unless opts.KeepArchive is set, in which case the stream is also teed into it.
+func downloadStreamExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
 	client := &http.Client{Timeout: 30 * time.Minute}
 
-	resp, err := client.Get(downloadURL)
+	req, err := newRequest(ctx, downloadURL, opts.Header)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -177,8 +356,64 @@ func downloadStreamExtract(downloadURL, outputDir string, worlds []string) error
 		return fmt.Errorf("download returned status %d", resp.StatusCode)
 	}
 
-	const limit = 10 << 30 // 10 GB safety cap
-	return extractWorlds(io.LimitReader(resp.Body, limit), outputDir, worlds)
+	// The body is not capped, since cutting it short would only surface
+	// later as a truncated archive; the extraction caps stop an oversized
+	// backup instead, with a *LimitError naming the cap.
+	body := limitReader(ctx, resp.Body, newRateLimiter(opts.RateLimit))
+	return extractStream(ctx, body, outputDir, worlds, opts)
+}
+
+// extractStream extracts worlds from the archive read from body as it
+// arrives, verifying opts.Checksum and teeing the archive into
+// opts.KeepArchive when set.
+func extractStream(ctx context.Context, body io.Reader, outputDir string, worlds []string, opts DownloadOptions) error {
+	keepArchive := opts.KeepArchive
+	sum, _ := parseChecksum(opts.Checksum)
+	if sum != nil {
+		body = io.TeeReader(body, sum)
+	}
+	if keepArchive != "" {
+		archive, err := os.Create(keepArchive)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", keepArchive, err)
+		}
+		defer archive.Close()
+		body = io.TeeReader(body, archive)
+	}
+
+	if err := extractWorlds(ctx, body, outputDir, worlds, opts, opts.writers()); err != nil {
+		return err
+	}
+	if sum == nil && keepArchive == "" {
+		return nil
+	}
+	// The tar reader stops at the end-of-archive marker; drain the rest so
+	// the whole archive is hashed and the preserved copy is byte-identical
+	// to the backup.
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return fmt.Errorf("reading the rest of the archive: %w", err)
+	}
+	if sum != nil {
+		if err := sum.verify(); err != nil {
+			return err
+		}
+	}
+	if keepArchive != "" {
+		fmt.Printf("  ✔  archive kept at %s\n", keepArchive)
+	}
+	return nil
+}
+
+// newRequest returns a GET request for url carrying header.
+func newRequest(ctx context.Context, url string, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return req, nil
 }
 
 // probeDownload sends a GET request with Range: bytes=0-0 to discover whether
@@ -188,10 +423,10 @@ func downloadStreamExtract(downloadURL, outputDir string, worlds []string) error
 //
 // Returns (0, false, nil) on any non-fatal failure so the caller can
 // gracefully fall back to single-connection download.
-func probeDownload(url string) (contentLength int64, rangeSupported bool, err error) {
+func probeDownload(ctx context.Context, url string, header http.Header) (contentLength int64, rangeSupported bool, err error) {
 	client := &http.Client{Timeout: 30 * time.Second}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := newRequest(ctx, url, header)
 	if err != nil {
 		return 0, false, nil
 	}
@@ -199,7 +434,9 @@ func probeDownload(url string) (contentLength int64, rangeSupported bool, err er
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, false, nil
+		// A cancelled context is fatal; anything else falls back to a
+		// single connection.
+		return 0, false, ctx.Err()
 	}
 	defer resp.Body.Close()
 
@@ -253,8 +490,12 @@ func parseContentRange(header string) (int64, bool) {
 
 // downloadParallel downloads the resource at url using numWorkers parallel
 // HTTP Range requests and writes the result into f (pre-truncated to
-// contentLength bytes). A progress line is printed every 5 seconds.
-func downloadParallel(url string, f *os.File, contentLength int64, numWorkers int) error {
+// contentLength bytes). The progress, speed and ETA are printed every 5
+// seconds with the share each worker has done, and passed to notice as
+// described by progress. All workers draw from limiter, which may be nil for
+// no limit. When sum is set, the file is hashed in order as its sections are
+// written.
+func downloadParallel(ctx context.Context, url string, header http.Header, f *os.File, contentLength int64, numWorkers int, limiter *rateLimiter, sum *checksum, notice func(string)) error {
 	// Pre-allocate the file so each worker can WriteAt its own section
 	// without interfering with others.
 	if err := f.Truncate(contentLength); err != nil {
@@ -270,38 +511,54 @@ func downloadParallel(url string, f *os.File, contentLength int64, numWorkers in
 		downloaded atomic.Int64
 	)
 
+	sharedClient := &http.Client{Timeout: 30 * time.Minute}
+
+	// written[i] is the end of worker i's contiguous written prefix.
+	starts := make([]int64, numWorkers)
+	ends := make([]int64, numWorkers)
+	written := make([]atomic.Int64, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		start := int64(i) * chunkSize
+		end := start + chunkSize - 1
+		if i == numWorkers-1 {
+			end = contentLength - 1
+		}
+		starts[i], ends[i] = start, end
+		written[i].Store(start)
+	}
+
 	// Progress reporter goroutine.
+	prog := newProgress(contentLength, time.Now(), notice)
 	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
+		defer close(progressStopped)
+		ticker := time.NewTicker(progressInterval)
 		defer ticker.Stop()
+		workers := make([]float64, numWorkers)
 		for {
 			select {
-			case <-ticker.C:
-				got := downloaded.Load()
-				pct := float64(got) / float64(contentLength) * 100
-				fmt.Printf("  → %s / %s (%.0f%%)\n",
-					formatBytes(got), formatBytes(contentLength), pct)
+			case now := <-ticker.C:
+				for i := range workers {
+					workers[i] = float64(written[i].Load()-starts[i]) / float64(ends[i]+1-starts[i])
+				}
+				fmt.Println(prog.update(now, downloaded.Load(), workers))
 			case <-progressDone:
 				return
 			}
 		}
 	}()
-	defer close(progressDone)
-
-	sharedClient := &http.Client{Timeout: 30 * time.Minute}
+	stopProgress := func() {
+		close(progressDone)
+		<-progressStopped
+	}
 
 	for i := 0; i < numWorkers; i++ {
-		start := int64(i) * chunkSize
-		end := start + chunkSize - 1
-		if i == numWorkers-1 {
-			end = contentLength - 1
-		}
-
+		start, end := starts[i], ends[i]
 		wg.Add(1)
 		go func(workerID int, start, end int64) {
 			defer wg.Done()
-			if err := downloadChunk(sharedClient, url, f, start, end, &downloaded); err != nil {
+			if err := downloadChunk(ctx, sharedClient, url, header, f, start, end, &downloaded, &written[workerID], limiter); err != nil {
 				mu.Lock()
 				if firstErr == nil {
 					firstErr = fmt.Errorf("worker %d (bytes %d-%d): %w", workerID, start, end, err)
@@ -311,15 +568,38 @@ func downloadParallel(url string, f *os.File, contentLength int64, numWorkers in
 		}(i, start, end)
 	}
 
+	var hashErr error
+	hashed := make(chan struct{})
+	workersDone := make(chan struct{})
+	if sum != nil {
+		go func() {
+			defer close(hashed)
+			hashErr = hashWritten(ctx, f, sum, starts, written, contentLength, workersDone)
+		}()
+	} else {
+		close(hashed)
+	}
+
 	wg.Wait()
-	return firstErr
+	close(workersDone)
+	stopProgress()
+	<-hashed
+	if firstErr != nil {
+		return firstErr
+	}
+	if hashErr != nil {
+		return hashErr
+	}
+	fmt.Println(prog.finish(time.Now(), downloaded.Load()))
+	return nil
 }
 
 // downloadChunk fetches bytes [start, end] from url using a Range request and
 // writes them into f at the correct offset. downloaded is updated atomically
-// as bytes arrive.
-func downloadChunk(client *http.Client, url string, f *os.File, start, end int64, downloaded *atomic.Int64) error {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// as bytes arrive, and written is set to the offset up to which the range
+// has been written.
+func downloadChunk(ctx context.Context, client *http.Client, url string, header http.Header, f *os.File, start, end int64, downloaded, written *atomic.Int64, limiter *rateLimiter) error {
+	req, err := newRequest(ctx, url, header)
 	if err != nil {
 		return err
 	}
@@ -335,16 +615,18 @@ func downloadChunk(client *http.Client, url string, f *os.File, start, end int64
 		return fmt.Errorf("expected 206 Partial Content, got %d", resp.StatusCode)
 	}
 
+	body := limitReader(ctx, resp.Body, limiter)
 	buf := make([]byte, 256<<10) // 256 KB read buffer per worker
 	offset := start
 	for {
-		n, readErr := resp.Body.Read(buf)
+		n, readErr := body.Read(buf)
 		if n > 0 {
 			if _, writeErr := f.WriteAt(buf[:n], offset); writeErr != nil {
 				return writeErr
 			}
 			offset += int64(n)
 			downloaded.Add(int64(n))
+			written.Store(offset)
 		}
 		if errors.Is(readErr, io.EOF) {
 			break
@@ -353,103 +635,526 @@ func downloadChunk(client *http.Client, url string, f *os.File, start, end int64
 			return readErr
 		}
 	}
+	if offset != end+1 {
+		// The rest of the range would stay zeroed in the pre-allocated file.
+		return fmt.Errorf("connection closed after %d of %d bytes", offset-start, end+1-start)
+	}
 	return nil
 }
 
-// extractWorlds reads a tar.gz archive from r and extracts only the world
-// directories listed in worlds into outputDir.
-func extractWorlds(r io.Reader, outputDir string, worlds []string) error {
-	gz, err := gzip.NewReader(r)
+// extractWorlds reads a tar.gz or zip archive from r and extracts only the
+// world directories listed in worlds into outputDir. With writers > 1, file
+// contents are written by a pool of that many goroutines while the archive
+// keeps being decompressed.
+func extractWorlds(ctx context.Context, r io.Reader, outputDir string, worlds []string, opts DownloadOptions, writers int) error {
+	a, err := openArchive(r, opts.DecompressBlockSize, opts.DecompressBlocks)
 	if err != nil {
-		return fmt.Errorf("creating gzip reader: %w", err)
+		return err
 	}
-	defer gz.Close()
-
-	tr := tar.NewReader(gz)
+	defer a.close()
+	return extractEntries(ctx, []*archive{a}, outputDir, worlds, opts, writers)
+}
 
-	// Build a set for quick lookup of world prefixes.
-	worldSet := make(map[string]bool, len(worlds))
-	for _, w := range worlds {
-		worldSet[w] = true
+// extractEntries extracts the world directories listed in worlds from the
+// entries of open archives into outputDir. Several archives are read on
+// goroutines of their own, sharing the writer pool and the extraction caps;
+// they are the parts of one seekable archive (see extractSeekable).
+func extractEntries(ctx context.Context, readers []*archive, outputDir string, worlds []string, opts DownloadOptions, writers int) error {
+	start := time.Now()
+	if opts.Timings != nil {
+		defer func() { opts.Timings.Extract = time.Since(start) }()
 	}
 
+	a := readers[0]
+	prog := newExtractProgress(a.ahead)
+
+	prefixes := backupPrefixes(worlds, opts)
+
+	// mu guards the counters, folders, links and caps below while several
+	// readers run.
+	var mu sync.Mutex
 	extracted := make(map[string]int)
+	filtered := make(map[string]int)
+	topLevel := make(map[string]bool)  // top-level entries, for MissingWorldsError
+	worldDirs := make(map[string]bool) // folders holding a level.dat, for suggestions
 
-	for {
-		header, err := tr.Next()
-		if errors.Is(err, io.EOF) {
-			break
+	// Index offsets are positions in the tar stream; zip files get none.
+	whole := len(readers) == 1 && !a.zip && !a.partial
+	var built *Index
+	if opts.IndexPath != "" && whole {
+		built = &Index{BackupUUID: opts.BackupUUID}
+	}
+	stopAt := int64(-1)
+	if opts.index != nil && whole {
+		stopAt = opts.index.stopOffset(prefixes)
+	}
+
+	// Everything is created through root, which also refuses to follow a
+	// symlink already in outputDir to a place outside it.
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", outputDir, err)
+	}
+	root, err := os.OpenRoot(outputDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	dirs := &dirMaker{root: root, done: make(map[string]bool)}
+	limits := newExtractLimits(opts)
+	var links []link
+
+	rejected := 0
+	reject := func(entry, reason string) {
+		rejected++
+		if rejected <= maxRejectedShown {
+			fmt.Fprintf(os.Stderr, "  ⚠️  security: skipping backup entry %q: %s\n", entry, reason)
 		}
-		if err != nil {
-			return fmt.Errorf("reading tar entry: %w", err)
+		if opts.OnRejected != nil {
+			opts.OnRejected(entry, reason)
+		}
+	}
+
+	var pool *writerPool
+	if writers > 1 {
+		pool = newWriterPool(root, writers, limits.maxFile)
+		defer func() {
+			if pool != nil {
+				pool.wait()
+			}
+		}()
+	}
+
+	// Decompressing and writing a large world takes minutes; print the
+	// counters meanwhile.
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		defer close(progressStopped)
+		ticker := time.NewTicker(extractProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Println(prog.line())
+			case <-progressDone:
+				return
+			}
+		}
+	}()
+	stopProgress := func() {
+		if progressDone != nil {
+			close(progressDone)
+			<-progressStopped
+			progressDone = nil
+		}
+	}
+	defer stopProgress()
+
+	// position returns the compressed input read so far. Input read by
+	// several readers at once cannot be told apart by world.
+	position := func(a *archive) int64 {
+		if len(readers) > 1 {
+			return -1
+		}
+		return a.input.position()
+	}
+
+	// plan records the entry header and creates what it needs but its data.
+	// It returns the file to write the data to, or "" when there is none.
+	// It is called with mu held.
+	plan := func(a *archive, header *tar.Header) (world, name string, err error) {
+		if built != nil {
+			built.Entries = append(built.Entries, IndexEntry{Name: indexName(header.Name), End: a.counter.n + header.Size})
+		}
+		topLevel[topLevelName(header.Name)] = true
+		if dir, ok := levelDatDir(header.Name); ok {
+			worldDirs[dir] = true
 		}
 
 		// Determine which world this entry belongs to.
-		matchedWorld := matchWorld(header.Name, worldSet)
+		matchedWorld, rel := matchWorld(header.Name, prefixes)
+		prog.entry(indexName(header.Name), matchedWorld, position(a))
 		if matchedWorld == "" {
-			continue
+			return "", "", nil
 		}
 
-		targetPath := filepath.Join(outputDir, header.Name)
-
-		// Prevent path traversal.
-		if !strings.HasPrefix(filepath.Clean(targetPath), filepath.Clean(outputDir)+string(os.PathSeparator)) {
-			continue
+		// Prevent path traversal: entries stay inside the folder (or are the
+		// single file) they matched, so "world/../config.toml" cannot
+		// overwrite files in the server directory.
+		if !localPath(rel) {
+			reject(header.Name, "path leaves the world folder")
+			return "", "", nil
 		}
+		name = filepath.Join(filepath.FromSlash(matchedWorld), filepath.FromSlash(rel))
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(targetPath, 0o755); err != nil {
-				return fmt.Errorf("creating directory %s: %w", targetPath, err)
+			if err := limits.entry(header.Name); err != nil {
+				return "", "", err
+			}
+			if err := dirs.make(name); err != nil {
+				return "", "", fmt.Errorf("creating directory %s: %w", filepath.Join(outputDir, name), err)
 			}
 		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
-				return fmt.Errorf("creating parent directory for %s: %w", targetPath, err)
+			if opts.Include != nil && !opts.Include(matchedWorld, rel) {
+				filtered[matchedWorld]++
+				return "", "", nil
+			}
+			if err := limits.entry(header.Name); err != nil {
+				return "", "", err
+			}
+			if err := limits.file(header.Name, header.Size); err != nil {
+				return "", "", err
 			}
-			if err := writeFile(targetPath, tr, header.FileInfo().Mode()); err != nil {
-				return fmt.Errorf("writing file %s: %w", targetPath, err)
+			if err := dirs.make(filepath.Dir(name)); err != nil {
+				return "", "", fmt.Errorf("creating parent directory for %s: %w", filepath.Join(outputDir, name), err)
 			}
-			extracted[matchedWorld]++
+			return matchedWorld, name, nil
+		case tar.TypeSymlink, tar.TypeLink:
+			if opts.Include != nil && !opts.Include(matchedWorld, rel) {
+				filtered[matchedWorld]++
+				return "", "", nil
+			}
+			if err := limits.entry(header.Name); err != nil {
+				return "", "", err
+			}
+			l := link{entry: header.Name, world: matchedWorld, rel: rel, name: name, symlink: header.Typeflag == tar.TypeSymlink, mode: header.FileInfo().Mode()}
+			if l.symlink {
+				if reason := unsafeSymlink(rel, header.Linkname); reason != "" {
+					reject(header.Name, reason)
+					return "", "", nil
+				}
+				l.target = header.Linkname
+			} else {
+				world, target := matchWorld(header.Linkname, prefixes)
+				if world == "" || !localPath(target) {
+					reject(header.Name, fmt.Sprintf("hard link to %q outside the extracted folders", header.Linkname))
+					return "", "", nil
+				}
+				l.target = filepath.Join(filepath.FromSlash(world), filepath.FromSlash(target))
+			}
+			links = append(links, l)
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			reject(header.Name, "device and FIFO entries are not extracted")
+		}
+		return "", "", nil
+	}
+
+	read := func(ctx context.Context, a *archive) error {
+		cr, tr := a.counter, a.entries
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if stopAt >= 0 && cr.n >= stopAt {
+				// Every entry of the requested worlds has been read.
+				built = nil
+				return nil
+			}
+
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading archive entry: %w", err)
+			}
+			mu.Lock()
+			world, name, err := plan(a, header)
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				continue
+			}
+
+			// The entry is counted at its declared size, so its data must be
+			// exactly that long.
+			data := &sizedReader{r: tr, left: header.Size, name: header.Name}
+			if pool != nil {
+				if err := pool.write(name, data, header.Size, header.FileInfo().Mode()); err != nil {
+					return err
+				}
+			} else if err := writeFile(root, name, data, header.FileInfo().Mode(), limits.maxFile); err != nil {
+				return fmt.Errorf("writing file %s: %w", filepath.Join(outputDir, name), err)
+			}
+			mu.Lock()
+			extracted[world]++
+			mu.Unlock()
+			prog.file(world, header.Size)
+		}
+	}
+
+	if len(readers) == 1 {
+		err = read(ctx, a)
+	} else {
+		// The first failing reader stops the others.
+		readCtx, cancel := context.WithCancel(ctx)
+		errs := make([]error, len(readers))
+		var wg sync.WaitGroup
+		for i, r := range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if errs[i] = read(readCtx, r); errs[i] != nil {
+					cancel()
+				}
+			}()
+		}
+		wg.Wait()
+		cancel()
+		// The others then fail with context.Canceled.
+		for _, e := range errs {
+			if e != nil && (err == nil || errors.Is(err, context.Canceled)) {
+				err = e
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if pool != nil {
+		err := pool.wait()
+		pool = nil
+		if err != nil {
+			return err
+		}
+	}
+	stopProgress()
+	var decompressed int64
+	for _, r := range readers {
+		decompressed += r.counter.n
+	}
+	prog.finish(position(a))
+
+	// Links are created once every file is written, so no file of the
+	// archive is ever written through a link of the archive.
+	if err := createLinks(root, dirs, limits, links, extracted, reject); err != nil {
+		return err
+	}
+	if rejected > maxRejectedShown {
+		fmt.Fprintf(os.Stderr, "  ⚠️  security: %d backup entries skipped in total\n", rejected)
+	}
+
+	if ct, ok := a.entries.(*concatTar); ok && len(readers) == 1 && ct.archives > 1 {
+		fmt.Printf("  ✔  read %d concatenated tar archives\n", ct.archives)
+	}
+	if elapsed := time.Since(start); elapsed > 0 {
+		fmt.Printf("  ✔  decompressed %s in %s (%s/s, %s)\n", formatBytes(decompressed),
+			elapsed.Round(time.Millisecond), formatBytes(int64(float64(decompressed)/elapsed.Seconds())), writerMode(writers))
+	}
+
+	if built != nil {
+		if err := built.Save(opts.IndexPath); err != nil {
+			return fmt.Errorf("saving archive index: %w", err)
 		}
 	}
 
 	// Verify all worlds were found.
+	required := make(map[string]bool, len(opts.RequireWorlds))
+	for _, w := range opts.RequireWorlds {
+		required[w] = true
+	}
+	if opts.index != nil {
+		// Decompression may have stopped early; list from the index.
+		for _, e := range opts.index.Entries {
+			topLevel[topLevelName(e.Name)] = true
+			if dir, ok := levelDatDir(e.Name); ok {
+				worldDirs[dir] = true
+			}
+		}
+	}
+	var candidates []string
+	for name := range topLevel {
+		if strings.HasSuffix(name, "/") {
+			candidates = append(candidates, name)
+		}
+	}
+	for dir := range worldDirs {
+		candidates = append(candidates, dir)
+	}
+	sort.Strings(candidates)
+
+	missing := &MissingWorldsError{Sources: opts.Sources, Suggestions: make(map[string][]string)}
 	for _, w := range worlds {
-		if extracted[w] == 0 {
-			fmt.Fprintf(os.Stderr, "  ⚠️  world %q was not found in the backup\n", w)
-		} else {
-			fmt.Printf("  ✔  extracted %d files for world %q\n", extracted[w], w)
+		switch {
+		case extracted[w] == 0 && filtered[w] == 0:
+			name := w
+			if src := opts.Sources[w]; src != "" {
+				name = src
+			}
+			suggestions := SuggestWorlds(name, candidates)
+			if opts.OnMissing != nil {
+				opts.OnMissing(w, suggestions)
+			}
+			if required[w] {
+				missing.Worlds = append(missing.Worlds, w)
+				missing.Suggestions[w] = suggestions
+			} else {
+				fmt.Fprintf(os.Stderr, "  ⚠️  world %q was not found in the backup%s\n", w, didYouMean(suggestions))
+			}
+		case filtered[w] > 0:
+			fmt.Printf("  ✔  extracted %d files (%s) for world %q (%d outside bounds skipped)\n", extracted[w], prog.summary(w), w, filtered[w])
+		default:
+			fmt.Printf("  ✔  extracted %d files (%s) for world %q\n", extracted[w], prog.summary(w), w)
 		}
 	}
 
+	if len(missing.Worlds) > 0 {
+		for name := range topLevel {
+			if name != "" {
+				missing.Present = append(missing.Present, name)
+			}
+		}
+		sort.Strings(missing.Present)
+		return missing
+	}
 	return nil
 }
 
-// matchWorld returns the world name if the tar entry path begins with one of
-// the world names followed by a slash, or is the world directory itself.
-func matchWorld(entryPath string, worldSet map[string]bool) string {
+// backupPrefixes maps each folder path inside the backup to the world it
+// belongs to, and every extra path to itself.
+func backupPrefixes(worlds []string, opts DownloadOptions) map[string]string {
+	prefixes := make(map[string]string, len(worlds))
+	for _, w := range worlds {
+		if src, ok := opts.Sources[w]; ok && src != "" {
+			prefixes[src] = w
+		} else {
+			prefixes[w] = w
+		}
+	}
+	for _, p := range opts.Extra {
+		if _, ok := prefixes[p]; !ok {
+			prefixes[p] = p
+		}
+	}
+	return prefixes
+}
+
+// MissingWorldsError reports required worlds that are not in the backup,
+// along with what the backup does contain at its top level.
+type MissingWorldsError struct {
+	Worlds      []string            // world folders not found
+	Sources     map[string]string   // backup paths of worlds whose folder differs
+	Suggestions map[string][]string // likely intended directories per missing world
+	Present     []string            // top-level entries of the archive; directories end in "/"
+}
+
+// maxListedEntries caps the top-level entries named in a MissingWorldsError.
+const maxListedEntries = 30
+
+func (e *MissingWorldsError) Error() string {
+	names := make([]string, len(e.Worlds))
+	for i, w := range e.Worlds {
+		if src := e.Sources[w]; src != "" && src != w {
+			names[i] = fmt.Sprintf("%q (from %q)", w, src)
+		} else {
+			names[i] = fmt.Sprintf("%q", w)
+		}
+		names[i] += didYouMean(e.Suggestions[w])
+	}
+	present := e.Present
+	more := ""
+	if len(present) > maxListedEntries {
+		more = fmt.Sprintf(", … (%d more)", len(present)-maxListedEntries)
+		present = present[:maxListedEntries]
+	}
+	listing := "the archive is empty"
+	if len(present) > 0 {
+		listing = "top-level entries in the backup: " + strings.Join(present, ", ") + more
+	}
+	return fmt.Sprintf("world %s not found in the backup; %s", strings.Join(names, ", "), listing)
+}
+
+// topLevelName returns the first path component of a tar entry, with a
+// trailing slash when the entry lies inside (or is) a directory.
+func topLevelName(name string) string {
+	name = indexName(name)
+	if first, _, ok := strings.Cut(name, "/"); ok {
+		return first + "/"
+	}
+	return name
+}
+
+// levelDatDir returns the folder of a tar entry named level.dat, which marks
+// the root of a world.
+func levelDatDir(name string) (string, bool) {
+	name = indexName(name)
+	if path.Base(name) != "level.dat" || !strings.Contains(name, "/") {
+		return "", false
+	}
+	return path.Dir(name), true
+}
+
+// matchWorld returns the world whose backup folder the tar entry path begins
+// with (followed by a slash, or the folder itself), along with the path
+// relative to that folder. When folders are nested, the longest one wins.
+func matchWorld(entryPath string, prefixes map[string]string) (world, rel string) {
 	// Normalize: remove leading "./" if present.
 	clean := strings.TrimPrefix(entryPath, "./")
 
-	// Check each world prefix.
-	for w := range worldSet {
-		if clean == w || clean == w+"/" || strings.HasPrefix(clean, w+"/") {
-			return w
+	match := ""
+	for prefix, w := range prefixes {
+		if len(prefix) > len(match) && (clean == prefix || strings.HasPrefix(clean, prefix+"/")) {
+			match, world = prefix, w
+		}
+	}
+	if match == "" {
+		return "", ""
+	}
+	return world, strings.TrimPrefix(clean[len(match):], "/")
+}
+
+// sizedReader reads the data of an archive entry, failing unless it is
+// exactly the size its header declares. At the declared size it reads on to
+// the end of the entry, which makes a zip entry verify its checksum.
+type sizedReader struct {
+	r    io.Reader
+	left int64
+	name string
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if s.left == 0 {
+		var b [1]byte
+		if _, err := io.ReadFull(s.r, b[:]); err == nil {
+			return 0, fmt.Errorf("backup entry %q holds more data than its declared size", s.name)
+		} else if !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n, err := s.r.Read(p)
+	s.left -= int64(n)
+	if errors.Is(err, io.EOF) {
+		if s.left > 0 {
+			return n, fmt.Errorf("backup entry %q ends %d bytes short of its declared size: %w", s.name, s.left, io.ErrUnexpectedEOF)
 		}
+		err = nil
 	}
-	return ""
+	return n, err
 }
 
-func writeFile(path string, r io.Reader, mode os.FileMode) error {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+// writeFile writes the file name of root from r, failing once more than
+// limit bytes have been read. A file already at name is removed rather than
+// truncated, so copies hard-linked to it (e.g. worlds kept by
+// -keep-intermediate) keep their content.
+func writeFile(root *os.Root, name string, r io.Reader, mode os.FileMode, limit int64) error {
+	if err := root.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// Limit copy size to 10 GB as a safety measure against malformed archives.
 	// Use limit+1 so a file of exactly limit bytes is not falsely rejected.
-	const limit = 10 << 30 // 10 GB
 	n, err := io.Copy(f, io.LimitReader(r, limit+1))
 	if err != nil {
 		return err
@@ -460,6 +1165,41 @@ func writeFile(path string, r io.Reader, mode os.FileMode) error {
 	return nil
 }
 
+// moveFile renames src to dst, falling back to copy-and-remove when they are
+// on different filesystems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// writerMode describes how extracted files were written, for the summary line.
+func writerMode(writers int) string {
+	if writers > 1 {
+		return fmt.Sprintf("%d writers", writers)
+	}
+	return "inline writes"
+}
+
 // formatBytes formats a byte count as a human-readable string (e.g. "1.5 GiB").
 func formatBytes(b int64) string {
 	const unit = 1024
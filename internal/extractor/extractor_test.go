@@ -0,0 +1,347 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tarGz builds a tar.gz archive of regular files whose content is their own
+// name.
+func tarGz(names ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(name)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(name))
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestExtractWorldsSourcesAndInclude(t *testing.T) {
+	// 0 writes inline; 4 uses the writer pool.
+	for _, writers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("writers=%d", writers), func(t *testing.T) {
+			testExtractWorlds(t, writers)
+		})
+	}
+}
+
+func testExtractWorlds(t *testing.T, writers int) {
+	buf := tarGz(
+		"./world/level.dat",
+		"./world/region/r.0.0.mca",
+		"./world/region/r.9.9.mca",
+		"./worlds/creative/level.dat",
+		"./creative/level.dat", // same name outside the source path: ignored
+		"./logs/latest.log",
+		"./plugins/WorldGuard/worlds/world/regions.yml",
+		"./plugins/WorldGuard/../../escape.txt", // escapes its extra path: skipped
+		"./plugins/Other/config.yml",
+		"./server.properties",
+	)
+
+	out := filepath.Join(t.TempDir(), "server")
+	opts := DownloadOptions{
+		Sources: map[string]string{"creative": "worlds/creative"},
+		Include: func(world, rel string) bool { return rel != "region/r.9.9.mca" },
+		Extra:   []string{"plugins/WorldGuard", "server.properties"},
+	}
+	if err := extractWorlds(context.Background(), buf, out, []string{"world", "creative"}, opts, writers); err != nil {
+		t.Fatalf("extractWorlds: %v", err)
+	}
+
+	want := map[string]string{
+		"world/level.dat":        "./world/level.dat",
+		"world/region/r.0.0.mca": "./world/region/r.0.0.mca",
+		"creative/level.dat":     "./worlds/creative/level.dat",
+
+		"plugins/WorldGuard/worlds/world/regions.yml": "./plugins/WorldGuard/worlds/world/regions.yml",
+		"server.properties":                           "./server.properties",
+	}
+	var got []string
+	filepath.Walk(out, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(out, path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if len(got) != len(want) {
+		t.Fatalf("extracted %v, want %d files", got, len(want))
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(out, rel))
+		if err != nil {
+			t.Errorf("%s: %v", rel, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", rel, data, content)
+		}
+	}
+}
+
+func TestInspectArchive(t *testing.T) {
+	buf := tarGz(
+		"./world/level.dat",
+		"./world/region/r.0.0.mca",
+		"./world_nether/level.dat",
+		"./world_nether/DIM-1/region/r.0.0.mca",
+		"./world_the_end/level.dat",
+		"./worlds/creative/level.dat",
+		"./worlds/creative/dimensions/minecraft/overworld/region/r.0.0.mca",
+		"./lobby/level.dat",
+		"./plugins/Essentials/config.yml",
+		"./server.properties",
+	)
+	inv, err := InspectArchive(context.Background(), buf)
+	if err != nil {
+		t.Fatalf("InspectArchive: %v", err)
+	}
+	if inv.Files != 10 || len(inv.TopLevel) != 7 {
+		t.Errorf("got %d files and %d top-level entries, want 10 and 7", inv.Files, len(inv.TopLevel))
+	}
+
+	layouts := make(map[string]string)
+	for _, w := range inv.Worlds {
+		layouts[w.Path] = w.Layout
+	}
+	want := map[string]string{
+		"world":           LayoutPlugin,
+		"worlds/creative": LayoutUnified,
+		"lobby":           LayoutUnknown,
+	}
+	if !reflect.DeepEqual(layouts, want) {
+		t.Errorf("world layouts = %v, want %v", layouts, want)
+	}
+}
+
+func TestArchiveIndex(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.tar.gz")
+	indexPath := filepath.Join(dir, "backup.index.json")
+	if err := os.WriteFile(archive, tarGz(
+		"./world/level.dat",
+		"./world/region/r.0.0.mca",
+		"./plugins/dynmap/big.bin",
+		"./logs/latest.log",
+	).Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// First pass: full extraction builds the index.
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DownloadOptions{IndexPath: indexPath, BackupUUID: "uuid-1"}
+	err = extractWorlds(context.Background(), f, filepath.Join(dir, "first"), []string{"world"}, opts, 0)
+	f.Close()
+	if err != nil {
+		t.Fatalf("extractWorlds: %v", err)
+	}
+
+	idx, err := LoadIndex(indexPath)
+	if err != nil || idx == nil {
+		t.Fatalf("LoadIndex = %v, %v", idx, err)
+	}
+	if idx.BackupUUID != "uuid-1" || len(idx.Entries) != 4 || idx.Entries[1].Name != "world/region/r.0.0.mca" {
+		t.Fatalf("index = %+v", idx)
+	}
+	stop := idx.stopOffset(map[string]string{"world": "world"})
+	if stop != idx.Entries[1].End || stop >= idx.Entries[3].End {
+		t.Errorf("stopOffset = %d, want the end of the last world entry (%d)", stop, idx.Entries[1].End)
+	}
+
+	// Second pass: reuse the archive with the index.
+	out := filepath.Join(dir, "second")
+	if err := ExtractArchive(context.Background(), archive, idx, out, []string{"world"}, DownloadOptions{}); err != nil {
+		t.Fatalf("ExtractArchive: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "world", "region", "r.0.0.mca"))
+	if err != nil || string(data) != "./world/region/r.0.0.mca" {
+		t.Errorf("r.0.0.mca = %q, %v", data, err)
+	}
+}
+
+func TestConcatenatedArchives(t *testing.T) {
+	plainTar := func(name string, padding int) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(name)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(name))
+		tw.Close()
+		buf.Write(make([]byte, padding)) // record padding as written by GNU tar
+		return buf.Bytes()
+	}
+
+	// Two tar archives back to back inside a single gzip stream.
+	var concatenated bytes.Buffer
+	gz := gzip.NewWriter(&concatenated)
+	gz.Write(plainTar("./world/level.dat", 10240-3*512))
+	gz.Write(plainTar("./world_nether/level.dat", 0))
+	gz.Close()
+
+	// Two complete tar.gz files joined with cat (a multistream gzip).
+	var multistream bytes.Buffer
+	multistream.Write(tarGz("./world/level.dat").Bytes())
+	multistream.Write(tarGz("./world_nether/level.dat").Bytes())
+
+	for name, archive := range map[string][]byte{"tar": concatenated.Bytes(), "gzip": multistream.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			out := t.TempDir()
+			worlds := []string{"world", "world_nether"}
+			if err := extractWorlds(context.Background(), bytes.NewReader(archive), out, worlds, DownloadOptions{}, 0); err != nil {
+				t.Fatalf("extractWorlds: %v", err)
+			}
+			for _, w := range worlds {
+				if _, err := os.Stat(filepath.Join(out, w, "level.dat")); err != nil {
+					t.Errorf("%s not extracted: %v", w, err)
+				}
+			}
+
+			inv, err := InspectArchive(context.Background(), bytes.NewReader(archive))
+			if err != nil {
+				t.Fatalf("InspectArchive: %v", err)
+			}
+			if len(inv.Worlds) != 1 || len(inv.Worlds[0].Folders) != 2 {
+				t.Errorf("InspectArchive worlds = %+v, want world with its nether folder", inv.Worlds)
+			}
+		})
+	}
+}
+
+func TestMissingWorlds(t *testing.T) {
+	archive := tarGz("./world/level.dat", "./plugins/a.jar", "./server.properties", "./worlds/Creative/level.dat").Bytes()
+	opts := DownloadOptions{
+		Sources:       map[string]string{"creative": "worlds/creative"},
+		RequireWorlds: []string{"world", "creative"},
+	}
+	worlds := []string{"world", "world_nether", "creative"}
+
+	err := extractWorlds(context.Background(), bytes.NewReader(archive), t.TempDir(), worlds, opts, 0)
+	var missing *MissingWorldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("extractWorlds error = %v, want *MissingWorldsError", err)
+	}
+	if !reflect.DeepEqual(missing.Worlds, []string{"creative"}) {
+		t.Errorf("missing worlds = %v, want only the required creative (world_nether is optional)", missing.Worlds)
+	}
+	if want := []string{"plugins/", "server.properties", "world/", "worlds/"}; !reflect.DeepEqual(missing.Present, want) {
+		t.Errorf("present = %v, want %v", missing.Present, want)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `"creative" (from "worlds/creative") (did you mean "worlds/Creative"?)`) || !strings.Contains(msg, "plugins/, server.properties, world/") {
+		t.Errorf("error message = %q", msg)
+	}
+
+	opts.RequireWorlds = nil
+	reported := make(map[string][]string)
+	opts.OnMissing = func(world string, suggestions []string) { reported[world] = suggestions }
+	if err := extractWorlds(context.Background(), bytes.NewReader(archive), t.TempDir(), worlds, opts, 0); err != nil {
+		t.Errorf("extractWorlds without required worlds: %v", err)
+	}
+	if want := map[string][]string{"world_nether": {"world"}, "creative": {"worlds/Creative"}}; !reflect.DeepEqual(reported, want) {
+		t.Errorf("OnMissing reported %v, want %v", reported, want)
+	}
+}
+
+func TestSuggestWorlds(t *testing.T) {
+	dirs := []string{"World/", "survival_world/", "plugins/", "logs/", "backups/old_world"}
+	if got, want := SuggestWorlds("world", dirs), []string{"World", "backups/old_world", "survival_world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestWorlds(world) = %v, want %v", got, want)
+	}
+	if got := SuggestWorlds("wrld_nether", []string{"world_nether/", "world_the_end/"}); !reflect.DeepEqual(got, []string{"world_nether"}) {
+		t.Errorf("SuggestWorlds(wrld_nether) = %v", got)
+	}
+	if got := SuggestWorlds("creative", dirs); got != nil {
+		t.Errorf("SuggestWorlds(creative) = %v, want none", got)
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	archive := tarGz("./world/level.dat", "./world/region/r.0.0.mca").Bytes()
+	sum := sha1.Sum(archive)
+	good := "sha1:" + hex.EncodeToString(sum[:])
+	bad := "sha1:" + strings.Repeat("0", 40)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "backup.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer srv.Close()
+
+	for _, mode := range []string{"parallel", "parallel-stream", "single"} {
+		for _, tc := range []struct {
+			checksum string
+			ok       bool
+		}{{good, true}, {bad, false}, {"", true}} {
+			opts := DownloadOptions{Mode: mode, Connections: 3, Checksum: tc.checksum}
+			err := DownloadAndExtractWorlds(context.Background(), srv.URL, t.TempDir(), []string{"world"}, opts)
+			var ce *ChecksumError
+			switch {
+			case tc.ok && err != nil:
+				t.Errorf("%s, checksum %q: %v", mode, tc.checksum, err)
+			case !tc.ok && !errors.As(err, &ce):
+				t.Errorf("%s, checksum %q: err = %v, want *ChecksumError", mode, tc.checksum, err)
+			}
+		}
+	}
+
+	if err := DownloadAndExtractWorlds(context.Background(), srv.URL, t.TempDir(), []string{"world"},
+		DownloadOptions{Mode: "single", Checksum: "crc32:00"}); err == nil {
+		t.Error("unsupported checksum algorithm accepted")
+	}
+}
+
+func TestDownloadTimings(t *testing.T) {
+	archive := tarGz("./world/level.dat", "./world/region/r.0.0.mca").Bytes()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "backup.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer srv.Close()
+
+	for _, mode := range []string{"parallel", "single"} {
+		var timings Timings
+		opts := DownloadOptions{Mode: mode, Connections: 2, Timings: &timings}
+		if err := DownloadAndExtractWorlds(context.Background(), srv.URL, t.TempDir(), []string{"world"}, opts); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if timings.Extract <= 0 {
+			t.Errorf("%s: Extract = %v, want > 0", mode, timings.Extract)
+		}
+		if parallel := mode == "parallel"; (timings.Probe > 0) != parallel || (timings.Download > 0) != parallel {
+			t.Errorf("%s: Probe = %v, Download = %v; want both set only in parallel mode", mode, timings.Probe, timings.Download)
+		}
+	}
+}
+
+func TestMatchWorldNested(t *testing.T) {
+	prefixes := map[string]string{"worlds": "hub", "worlds/creative": "creative"}
+	for entry, want := range map[string][2]string{
+		"./worlds/creative/level.dat": {"creative", "level.dat"},
+		"worlds/creative":             {"creative", ""},
+		"worlds/level.dat":            {"hub", "level.dat"},
+		"worlds/creative2/level.dat":  {"hub", "creative2/level.dat"},
+		"other/level.dat":             {"", ""},
+	} {
+		if w, rel := matchWorld(entry, prefixes); w != want[0] || rel != want[1] {
+			t.Errorf("matchWorld(%q) = %q, %q; want %q, %q", entry, w, rel, want[0], want[1])
+		}
+	}
+}
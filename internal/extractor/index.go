@@ -0,0 +1,162 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Index lists the entries of a backup archive with their positions in the
+// decompressed tar stream. A single gzip stream cannot be entered mid-way, so
+// such an index does not allow seeking; instead it tells a re-run which
+// worlds are present and how far into the stream it has to decompress before
+// every requested world has been extracted.
+//
+// An archive of many independent gzip members (bgzip) can be entered at any
+// member. Its index is Seekable: every entry also records the compressed
+// byte range of the members holding it, so "indexed" mode fetches just those
+// ranges of the worlds (see IndexMembers).
+type Index struct {
+	BackupUUID string       `json:"backup_uuid"`
+	Seekable   bool         `json:"seekable,omitempty"`
+	Size       int64        `json:"size,omitempty"` // compressed size of a seekable archive
+	Entries    []IndexEntry `json:"entries"`
+}
+
+// IndexEntry is one tar entry.
+type IndexEntry struct {
+	Name string `json:"name"` // path as stored in the archive, without a leading "./"
+	End  int64  `json:"end"`  // decompressed offset just past the entry's data
+
+	// Of a seekable archive: the compressed offset of the gzip member the
+	// entry's header blocks begin in, the decompressed bytes of that member
+	// before them, and the compressed offset just past the member holding
+	// the entry's last block.
+	Member    int64 `json:"member,omitempty"`
+	Skip      int64 `json:"skip,omitempty"`
+	MemberEnd int64 `json:"member_end,omitempty"`
+}
+
+// LoadIndex reads an index. A missing file yields (nil, nil).
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return &idx, nil
+}
+
+// Save writes the index to path.
+func (x *Index) Save(path string) error {
+	data, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// stopOffset returns the decompressed offset after which no entry belongs to
+// any of the given backup folder prefixes.
+func (x *Index) stopOffset(prefixes map[string]string) int64 {
+	var stop int64
+	for _, e := range x.Entries {
+		if w, _ := matchWorld(e.Name, prefixes); w != "" && e.End > stop {
+			stop = e.End
+		}
+	}
+	return stop
+}
+
+// ExtractArchive extracts worlds from a tar.gz archive already on disk, such
+// as one preserved by a previous run with opts.KeepArchive. With an index of
+// that archive, decompression stops right after the last entry of the
+// requested worlds instead of running to the end of the stream; with a
+// seekable one, the worlds are read concurrently (see extractSeekable).
+func ExtractArchive(ctx context.Context, archivePath string, idx *Index, outputDir string, worlds []string, opts DownloadOptions) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer f.Close()
+
+	opts.index = idx
+	opts.KeepArchive = ""
+	if idx != nil {
+		// The index is already complete; do not rewrite it from a partial pass.
+		opts.IndexPath = ""
+	}
+	if idx != nil && idx.Seekable {
+		if readers := idx.worldReaders(f, backupPrefixes(worlds, opts)); len(readers) > 0 {
+			fmt.Printf("  → reading %d folder(s) of the indexed archive concurrently\n", len(readers))
+			return extractSeekable(ctx, readers, outputDir, worlds, opts)
+		}
+	}
+	return extractWorlds(ctx, f, outputDir, worlds, opts, opts.writers())
+}
+
+// worldReaders returns a reader of the seekable archive f for each world
+// or extra path among prefixes with entries in the index, reading only the
+// gzip members that hold them.
+func (x *Index) worldReaders(f *os.File, prefixes map[string]string) []*rangeReader {
+	groups := make(map[string]map[string]string)
+	for prefix, world := range prefixes {
+		if groups[world] == nil {
+			groups[world] = make(map[string]string)
+		}
+		groups[world][prefix] = world
+	}
+	var readers []*rangeReader
+	for _, world := range slices.Sorted(maps.Keys(groups)) {
+		if spans := x.spans(groups[world]); len(spans) > 0 {
+			readers = append(readers, &rangeReader{file: f, spans: spans})
+		}
+	}
+	return readers
+}
+
+// extractSeekable extracts worlds from a seekable archive on disk. The gzip
+// members of a bgzip archive are independent, so each world is decompressed
+// by a reader of its own starting at the offsets the index records, and the
+// worlds of a large server are inflated on several cores at once instead of
+// one after the other.
+func extractSeekable(ctx context.Context, readers []*rangeReader, outputDir string, worlds []string, opts DownloadOptions) error {
+	archives := make([]*archive, len(readers))
+	for i, rr := range readers {
+		rr.ctx = ctx
+		archives[i] = rr.archive()
+		defer archives[i].close()
+	}
+	return extractEntries(ctx, archives, outputDir, worlds, opts, opts.writers())
+}
+
+// countingReader counts the bytes read through it, giving the position in
+// the decompressed tar stream.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// indexName normalizes a tar entry name for the index.
+func indexName(name string) string {
+	return strings.TrimPrefix(name, "./")
+}
@@ -0,0 +1,203 @@
+package extractor
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Folder layouts reported for world candidates. They match the server_type
+// values accepted in config.toml; LayoutUnknown is a world with only an
+// overworld, which extracts the same way under either vanilla or plugin.
+const (
+	LayoutVanilla = "vanilla"
+	LayoutPlugin  = "plugin"
+	LayoutUnified = "unified"
+	LayoutUnknown = ""
+)
+
+// inspectDepth is how deep below the archive root world folders are looked
+// for (e.g. "worlds/creative/level.dat" is depth 2).
+const inspectDepth = 3
+
+// Entry is a top-level file or directory of the archive.
+type Entry struct {
+	Name  string
+	Dir   bool
+	Files int
+	Bytes int64
+}
+
+// WorldCandidate is a folder containing a level.dat.
+type WorldCandidate struct {
+	Path    string   // folder path inside the backup, e.g. "world" or "worlds/creative"
+	Layout  string   // LayoutVanilla, LayoutPlugin, LayoutUnified or LayoutUnknown
+	Folders []string // every folder belonging to the world (plugin dimension folders included)
+	Bytes   int64
+}
+
+// Inventory summarises the contents of a backup archive.
+type Inventory struct {
+	TopLevel []Entry // largest first
+	Worlds   []WorldCandidate
+	Files    int
+	Bytes    int64
+}
+
+// InspectBackup streams the backup at downloadURL and lists its contents from
+// the tar headers alone, sending header with the request. File bodies are
+// decompressed and discarded, so nothing is written to disk.
+func InspectBackup(ctx context.Context, downloadURL string, header http.Header) (*Inventory, error) {
+	client := &http.Client{Timeout: 30 * time.Minute}
+
+	req, err := newRequest(ctx, downloadURL, header)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	return InspectArchive(ctx, resp.Body)
+}
+
+// InspectArchive reads a tar.gz or zip archive from r and returns its
+// inventory.
+func InspectArchive(ctx context.Context, r io.Reader) (*Inventory, error) {
+	a, err := openArchive(r, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer a.close()
+
+	tr := a.entries
+
+	inv := &Inventory{}
+	top := make(map[string]*Entry)
+	dirBytes := make(map[string]int64) // directories up to inspectDepth
+	dirs := make(map[string]bool)
+	levelDats := make(map[string]bool)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive entry: %w", err)
+		}
+
+		name := strings.Trim(strings.TrimPrefix(header.Name, "./"), "/")
+		if name == "" || name == "." {
+			continue
+		}
+		parts := strings.Split(name, "/")
+
+		e := top[parts[0]]
+		if e == nil {
+			e = &Entry{Name: parts[0]}
+			top[parts[0]] = e
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			e.Dir = true
+			for i := 1; i <= len(parts) && i <= inspectDepth+1; i++ {
+				dirs[strings.Join(parts[:i], "/")] = true
+			}
+		case tar.TypeReg:
+			inv.Files++
+			inv.Bytes += header.Size
+			e.Files++
+			e.Bytes += header.Size
+			if len(parts) > 1 {
+				e.Dir = true
+			}
+			// Record every ancestor directory; archives do not always
+			// contain explicit directory entries.
+			for i := 1; i < len(parts) && i <= inspectDepth+1; i++ {
+				dir := strings.Join(parts[:i], "/")
+				dirs[dir] = true
+				dirBytes[dir] += header.Size
+			}
+			if parts[len(parts)-1] == "level.dat" && len(parts)-1 <= inspectDepth && len(parts) > 1 {
+				levelDats[path.Dir(name)] = true
+			}
+		}
+	}
+
+	for _, e := range top {
+		inv.TopLevel = append(inv.TopLevel, *e)
+	}
+	sort.Slice(inv.TopLevel, func(i, j int) bool {
+		if inv.TopLevel[i].Bytes != inv.TopLevel[j].Bytes {
+			return inv.TopLevel[i].Bytes > inv.TopLevel[j].Bytes
+		}
+		return inv.TopLevel[i].Name < inv.TopLevel[j].Name
+	})
+
+	inv.Worlds = worldCandidates(levelDats, dirs, dirBytes)
+	return inv, nil
+}
+
+// worldCandidates groups level.dat folders into worlds and guesses their
+// layout. Plugin dimension folders (world_nether, world_the_end) are folded
+// into their base world.
+func worldCandidates(levelDats, dirs map[string]bool, dirBytes map[string]int64) []WorldCandidate {
+	var worlds []WorldCandidate
+	for p := range levelDats {
+		if base, ok := pluginBase(p); ok && levelDats[base] {
+			continue
+		}
+
+		w := WorldCandidate{Path: p, Folders: []string{p}, Bytes: dirBytes[p]}
+		for _, suffix := range []string{"_nether", "_the_end"} {
+			if dirs[p+suffix] {
+				w.Folders = append(w.Folders, p+suffix)
+				w.Bytes += dirBytes[p+suffix]
+			}
+		}
+		switch {
+		case dirs[p+"/dimensions"]:
+			w.Layout = LayoutUnified
+		case len(w.Folders) > 1:
+			w.Layout = LayoutPlugin
+		case dirs[p+"/DIM-1"] || dirs[p+"/DIM1"]:
+			w.Layout = LayoutVanilla
+		}
+		worlds = append(worlds, w)
+	}
+	sort.Slice(worlds, func(i, j int) bool {
+		if worlds[i].Bytes != worlds[j].Bytes {
+			return worlds[i].Bytes > worlds[j].Bytes
+		}
+		return worlds[i].Path < worlds[j].Path
+	})
+	return worlds
+}
+
+// pluginBase returns the base world folder of a plugin dimension folder.
+func pluginBase(p string) (string, bool) {
+	for _, suffix := range []string{"_nether", "_the_end"} {
+		if base, ok := strings.CutSuffix(p, suffix); ok && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
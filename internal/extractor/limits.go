@@ -0,0 +1,78 @@
+package extractor
+
+import "fmt"
+
+// Default extraction caps, used when the DownloadOptions fields are zero.
+const (
+	DefaultMaxFileSize  = 10 << 30   // 10 GiB, above any region file
+	DefaultMaxTotalSize = 1 << 40    // 1 TiB
+	DefaultMaxEntries   = 10_000_000 // files, folders and links
+)
+
+// Names of the caps in a LimitError, which are also their config.toml keys.
+const (
+	LimitFileSize  = "max_file_size"
+	LimitTotalSize = "max_total_size"
+	LimitEntries   = "max_entries"
+)
+
+// LimitError reports the archive entry that took extraction over one of
+// its caps, so a truncated or hostile backup cannot fill the disk.
+type LimitError struct {
+	Entry string // name in the archive
+	Limit string // LimitFileSize, LimitTotalSize or LimitEntries
+	Max   int64
+	Value int64 // size of the entry, total size with it, or entry count
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitTotalSize:
+		return fmt.Sprintf("backup entry %q brings the extracted size to %s, over %s = %s", e.Entry, formatBytes(e.Value), e.Limit, formatBytes(e.Max))
+	case LimitEntries:
+		return fmt.Sprintf("backup entry %q is entry %d of the extracted folders, over %s = %d", e.Entry, e.Value, e.Limit, e.Max)
+	default:
+		return fmt.Sprintf("backup entry %q is %s, over %s = %s", e.Entry, formatBytes(e.Value), e.Limit, formatBytes(e.Max))
+	}
+}
+
+// extractLimits counts what an extraction writes against its caps.
+type extractLimits struct {
+	maxFile, maxTotal, maxEntries int64
+	total, entries                int64
+}
+
+func newExtractLimits(opts DownloadOptions) *extractLimits {
+	l := &extractLimits{maxFile: opts.MaxFileSize, maxTotal: opts.MaxTotalSize, maxEntries: int64(opts.MaxEntries)}
+	if l.maxFile <= 0 {
+		l.maxFile = DefaultMaxFileSize
+	}
+	if l.maxTotal <= 0 {
+		l.maxTotal = DefaultMaxTotalSize
+	}
+	if l.maxEntries <= 0 {
+		l.maxEntries = DefaultMaxEntries
+	}
+	return l
+}
+
+// entry counts one extracted file, folder or link.
+func (l *extractLimits) entry(name string) error {
+	l.entries++
+	if l.entries > l.maxEntries {
+		return &LimitError{Entry: name, Limit: LimitEntries, Max: l.maxEntries, Value: l.entries}
+	}
+	return nil
+}
+
+// file counts the size of a file about to be written.
+func (l *extractLimits) file(name string, size int64) error {
+	if size > l.maxFile {
+		return &LimitError{Entry: name, Limit: LimitFileSize, Max: l.maxFile, Value: size}
+	}
+	l.total += size
+	if l.total > l.maxTotal {
+		return &LimitError{Entry: name, Limit: LimitTotalSize, Max: l.maxTotal, Value: l.total}
+	}
+	return nil
+}
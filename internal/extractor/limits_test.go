@@ -0,0 +1,34 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExtractLimits(t *testing.T) {
+	names := []string{"world/level.dat", "world/region/r.0.0.mca", "world/region/r.0.1.mca"}
+	for _, tt := range []struct {
+		opts  DownloadOptions
+		limit string
+		entry string
+	}{
+		{DownloadOptions{MaxFileSize: 20}, LimitFileSize, "world/region/r.0.0.mca"},
+		{DownloadOptions{MaxTotalSize: 30}, LimitTotalSize, "world/region/r.0.0.mca"},
+		{DownloadOptions{MaxEntries: 2}, LimitEntries, "world/region/r.0.1.mca"},
+	} {
+		err := extractWorlds(context.Background(), tarGz(names...), t.TempDir(), []string{"world"}, tt.opts, 0)
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%s: got %v, want a *LimitError", tt.limit, err)
+			continue
+		}
+		if limitErr.Limit != tt.limit || limitErr.Entry != tt.entry {
+			t.Errorf("%s: got %s for %q, want %q", tt.limit, limitErr.Limit, limitErr.Entry, tt.entry)
+		}
+	}
+
+	if err := extractWorlds(context.Background(), tarGz(names...), t.TempDir(), []string{"world"}, DownloadOptions{MaxFileSize: 22, MaxEntries: 3}, 0); err != nil {
+		t.Errorf("within the limits: %v", err)
+	}
+}
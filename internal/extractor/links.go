@@ -0,0 +1,159 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+)
+
+// maxRejectedShown is the number of skipped unsafe entries printed one by
+// one; a hostile archive can hold any number of them.
+const maxRejectedShown = 20
+
+// localPath reports whether rel, a slash-separated path inside a world
+// folder, stays in it: it is empty (the folder itself) or is neither
+// absolute nor climbs out with "..".
+func localPath(rel string) bool {
+	return rel == "" || filepath.IsLocal(filepath.FromSlash(rel))
+}
+
+// unsafeSymlink returns why the symlink at rel inside its world folder may
+// not point to linkname, or "" if it may: the target has to be relative and
+// stay inside the world folder.
+func unsafeSymlink(rel, linkname string) string {
+	switch {
+	case rel == "":
+		return "the world folder itself is a symlink"
+	case linkname == "" || path.IsAbs(linkname) || filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "":
+		return fmt.Sprintf("symlink to absolute path %q", linkname)
+	case !localPath(path.Join(path.Dir(rel), linkname)):
+		return fmt.Sprintf("symlink to %q leaves the world folder", linkname)
+	}
+	return ""
+}
+
+// link is a symlink or hard link entry, created after every file has been
+// written.
+type link struct {
+	entry   string // name in the archive
+	world   string // world folder it belongs to
+	rel     string // slash-separated path inside the world folder
+	name    string // path relative to the output directory
+	symlink bool
+	target  string // symlink: link target as given; hard link: path relative to the output directory
+	mode    os.FileMode
+}
+
+// dirMaker creates folders under root like os.MkdirAll, remembering the
+// ones that exist so each is checked once. os.Root has no MkdirAll before
+// Go 1.25.
+type dirMaker struct {
+	root *os.Root
+	done map[string]bool
+}
+
+func (d *dirMaker) make(name string) error {
+	if name == "." || d.done[name] {
+		return nil
+	}
+	info, err := d.root.Stat(name)
+	switch {
+	case err == nil && !info.IsDir():
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	case err == nil:
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	default:
+		if err := d.make(filepath.Dir(name)); err != nil {
+			return err
+		}
+		if err := d.root.Mkdir(name, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	d.done[name] = true
+	return nil
+}
+
+// createLinks creates the links of the archive under root. Hard links are
+// written as copies of their target, which may have been filtered out.
+// Symlinks are created as given, then resolved within their world folder:
+// the lexical check of unsafeSymlink cannot see through a chain of links,
+// such as a link to "." followed by a link to "../x" inside it, so a symlink
+// that resolves outside its world folder is removed again and rejected.
+func createLinks(root *os.Root, dirs *dirMaker, limits *extractLimits, links []link, extracted map[string]int, reject func(entry, reason string)) error {
+	var created []link
+	for _, l := range links {
+		if err := dirs.make(filepath.Dir(l.name)); err != nil {
+			return fmt.Errorf("creating parent directory for %s: %w", filepath.Join(root.Name(), l.name), err)
+		}
+		if !l.symlink {
+			if l.target == l.name {
+				continue
+			}
+			if err := copyHardLink(root, limits, l); err != nil {
+				var limitErr *LimitError
+				if errors.Is(err, fs.ErrNotExist) {
+					fmt.Fprintf(os.Stderr, "  ⚠️  skipping hard link %q: its target was not extracted\n", l.entry)
+					continue
+				} else if errors.As(err, &limitErr) {
+					return err
+				}
+				return fmt.Errorf("writing file %s: %w", filepath.Join(root.Name(), l.name), err)
+			}
+			extracted[l.world]++
+			continue
+		}
+		if err := root.Remove(l.name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			reject(l.entry, fmt.Sprintf("symlink cannot replace the existing path: %v", err))
+			continue
+		}
+		if err := os.Symlink(filepath.FromSlash(l.target), filepath.Join(root.Name(), l.name)); err != nil {
+			return fmt.Errorf("creating symlink %s: %w", filepath.Join(root.Name(), l.name), err)
+		}
+		created = append(created, l)
+	}
+
+	for _, l := range created {
+		world, err := root.OpenRoot(filepath.FromSlash(l.world))
+		if err != nil {
+			return err
+		}
+		_, err = world.Stat(filepath.FromSlash(l.rel))
+		world.Close()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			if err := root.Remove(l.name); err != nil {
+				return fmt.Errorf("removing symlink %s: %w", filepath.Join(root.Name(), l.name), err)
+			}
+			reject(l.entry, fmt.Sprintf("symlink to %q resolves outside the world folder", l.target))
+			continue
+		}
+		extracted[l.world]++
+	}
+	return nil
+}
+
+// copyHardLink writes the hard link l as a copy of its target, counting the
+// copy against limits.
+func copyHardLink(root *os.Root, limits *extractLimits, l link) error {
+	f, err := root.Open(l.target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link target %s is not a regular file", l.target)
+	}
+	if err := limits.file(l.entry, info.Size()); err != nil {
+		return err
+	}
+	return writeFile(root, l.name, f, l.mode, limits.maxFile)
+}
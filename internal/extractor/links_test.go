@@ -0,0 +1,129 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// tarGzHeaders builds a tar.gz archive of the given headers; regular files
+// hold their own name.
+func tarGzHeaders(headers ...*tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, h := range headers {
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(h.Name))
+		}
+		if h.Mode == 0 {
+			h.Mode = 0o644
+		}
+		tw.WriteHeader(h)
+		if h.Typeflag == tar.TypeReg {
+			tw.Write([]byte(h.Name))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestExtractWorldsUnsafeEntries(t *testing.T) {
+	buf := tarGzHeaders(
+		&tar.Header{Name: "world/level.dat", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "world/../escape.txt", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "world/region/", Typeflag: tar.TypeDir, Mode: 0o755},
+		&tar.Header{Name: "world/copy.dat", Typeflag: tar.TypeSymlink, Linkname: "level.dat"},
+		&tar.Header{Name: "world/region/up.dat", Typeflag: tar.TypeSymlink, Linkname: "../level.dat"},
+		&tar.Header{Name: "world/passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		&tar.Header{Name: "world/config", Typeflag: tar.TypeSymlink, Linkname: "../config.toml"},
+		// Lexically inside, but "here" resolves to the world folder itself.
+		&tar.Header{Name: "world/here", Typeflag: tar.TypeSymlink, Linkname: "."},
+		&tar.Header{Name: "world/here/out", Typeflag: tar.TypeSymlink, Linkname: "../config.toml"},
+		&tar.Header{Name: "world/hard.dat", Typeflag: tar.TypeLink, Linkname: "world/level.dat"},
+		&tar.Header{Name: "world/shadow", Typeflag: tar.TypeLink, Linkname: "etc/shadow"},
+		&tar.Header{Name: "world/null", Typeflag: tar.TypeChar, Devmajor: 1, Devminor: 3},
+	)
+
+	out := t.TempDir()
+	var rejected []string
+	opts := DownloadOptions{OnRejected: func(entry, reason string) { rejected = append(rejected, entry) }}
+	if err := extractWorlds(context.Background(), buf, out, []string{"world"}, opts, 0); err != nil {
+		t.Fatalf("extractWorlds: %v", err)
+	}
+
+	for _, name := range []string{"level.dat", "copy.dat", "region/up.dat", "hard.dat"} {
+		data, err := os.ReadFile(filepath.Join(out, "world", name))
+		if err != nil {
+			t.Errorf("reading %s: %v", name, err)
+		} else if string(data) != "world/level.dat" {
+			t.Errorf("%s = %q, want the content of level.dat", name, data)
+		}
+	}
+	if info, err := os.Lstat(filepath.Join(out, "world", "hard.dat")); err == nil && !info.Mode().IsRegular() {
+		t.Errorf("hard.dat is %s, want a regular file", info.Mode())
+	}
+	for _, name := range []string{"escape.txt", "world/passwd", "world/config", "world/out", "world/shadow", "world/null"} {
+		if _, err := os.Lstat(filepath.Join(out, name)); err == nil {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+
+	sort.Strings(rejected)
+	want := []string{"world/../escape.txt", "world/config", "world/here/out", "world/null", "world/passwd", "world/shadow"}
+	if len(rejected) != len(want) {
+		t.Fatalf("rejected %q, want %q", rejected, want)
+	}
+	for i := range want {
+		if rejected[i] != want[i] {
+			t.Errorf("rejected %q, want %q", rejected, want)
+			break
+		}
+	}
+}
+
+func TestExtractWorldsExistingSymlink(t *testing.T) {
+	// A symlink left in the output directory must not redirect writes
+	// outside it.
+	out, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(out, "world")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	buf := tarGz("world/level.dat")
+	if err := extractWorlds(context.Background(), buf, out, []string{"world"}, DownloadOptions{}, 0); err == nil {
+		t.Error("expected an error writing through a symlink leaving the output directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "level.dat")); err == nil {
+		t.Error("level.dat was written outside the output directory")
+	}
+}
+
+func TestExtractWorldsKeepsHardLinkedCopies(t *testing.T) {
+	// A world kept by hard-linking its files must not change when the next
+	// extraction writes the same paths.
+	out, kept := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(out, "world"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "world", "level.dat"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(out, "world", "level.dat"), filepath.Join(kept, "level.dat")); err != nil {
+		t.Skip("hard links not supported:", err)
+	}
+	if err := extractWorlds(context.Background(), tarGz("./world/level.dat"), out, []string{"world"}, DownloadOptions{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "world", "level.dat")); err != nil || string(data) != "./world/level.dat" {
+		t.Errorf("extracted level.dat = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(kept, "level.dat")); err != nil || string(data) != "old" {
+		t.Errorf("kept level.dat = %q, %v; want it unchanged", data, err)
+	}
+}
@@ -0,0 +1,238 @@
+package extractor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// progressInterval is how often a parallel download prints its progress.
+	progressInterval = 5 * time.Second
+
+	// progressWindow is how far back the download speed is measured, so the
+	// speed and ETA follow the current throughput rather than the average.
+	progressWindow = 30 * time.Second
+)
+
+// progress reports the progress of a parallel download: a line with the
+// bytes received, speed and ETA every progressInterval, the share each
+// worker has done, and a final line with the average speed. notice, if set,
+// also receives a line each time another quarter of the download completes
+// and at the end, for CI annotations that must not be written every tick.
+type progress struct {
+	total   int64
+	start   time.Time
+	samples []progressSample // within progressWindow of the last update
+	notice  func(string)
+	quarter int // quarters of the download already passed to notice
+}
+
+type progressSample struct {
+	at  time.Time
+	got int64
+}
+
+func newProgress(total int64, start time.Time, notice func(string)) *progress {
+	return &progress{total: total, start: start, samples: []progressSample{{start, 0}}, notice: notice}
+}
+
+// update records that got bytes were downloaded at now and returns the
+// progress lines. workers holds the share of its range each worker has
+// written, from 0 to 1; without workers only the first line is returned.
+func (p *progress) update(now time.Time, got int64, workers []float64) string {
+	p.samples = append(p.samples, progressSample{now, got})
+	for len(p.samples) > 2 && now.Sub(p.samples[0].at) > progressWindow {
+		p.samples = p.samples[1:]
+	}
+	rate := speed(got-p.samples[0].got, now.Sub(p.samples[0].at))
+
+	eta := "?"
+	if rate > 0 {
+		eta = (time.Duration(float64(p.total-got) / rate * float64(time.Second))).Round(time.Second).String()
+	}
+	pct := float64(got) / float64(p.total) * 100
+	line := fmt.Sprintf("  → %s / %s (%.0f%%), %s/s, ETA %s",
+		formatBytes(got), formatBytes(p.total), pct, formatBytes(int64(rate)), eta)
+
+	if q := int(got * 4 / p.total); p.notice != nil && q > p.quarter && q < 4 {
+		p.quarter = q
+		p.notice(strings.TrimPrefix(line, "  → "))
+	}
+
+	if len(workers) == 0 {
+		return line
+	}
+	status := make([]string, len(workers))
+	for i, share := range workers {
+		if share >= 1 {
+			status[i] = "done"
+		} else {
+			status[i] = fmt.Sprintf("%.0f%%", share*100)
+		}
+	}
+	return line + "\n    workers: " + strings.Join(status, " ")
+}
+
+// finish returns the final line of a download of got bytes that ended at
+// now, and passes it to notice.
+func (p *progress) finish(now time.Time, got int64) string {
+	elapsed := now.Sub(p.start)
+	msg := fmt.Sprintf("downloaded %s in %s (%s/s average)",
+		formatBytes(got), elapsed.Round(100*time.Millisecond), formatBytes(int64(speed(got, elapsed))))
+	if p.notice != nil {
+		p.notice(msg)
+	}
+	return "  → " + msg
+}
+
+// speed returns n bytes over d in bytes per second, or 0 for no time.
+func speed(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// extractProgressInterval is how often an extraction prints its counters;
+// less often than a download, whose progress may be printed alongside.
+const extractProgressInterval = 10 * time.Second
+
+// extractProgress counts the files and bytes an extraction has written per
+// world, for the line printed every extractProgressInterval and the summary
+// at its end. The extraction loop updates it while the reporter reads it.
+type extractProgress struct {
+	mu      sync.Mutex
+	order   []string // worlds in the order first written to
+	worlds  map[string]*worldCounts
+	files   int
+	bytes   int64
+	current string // entry being read
+
+	world string // world of the current entry, "" when it is not extracted
+	read  int64  // compressed input read when the current entry began
+	ahead int64  // input the readers may hold ahead of the entries
+}
+
+// worldCounts are the extraction counters of one world.
+type worldCounts struct {
+	files      int
+	bytes      int64 // uncompressed
+	compressed int64 // compressed input read while its entries were, or -1 if unknown
+}
+
+func newExtractProgress(ahead int64) *extractProgress {
+	return &extractProgress{worlds: make(map[string]*worldCounts), ahead: ahead}
+}
+
+// counts returns the counters of world, adding them on first use.
+func (p *extractProgress) counts(world string) *worldCounts {
+	c, ok := p.worlds[world]
+	if !ok {
+		c = &worldCounts{}
+		p.worlds[world] = c
+		p.order = append(p.order, world)
+	}
+	return c
+}
+
+// entry records that the entry name of world ("" when it is not extracted)
+// begins after read bytes of compressed input, or -1 when that is unknown.
+// The input read since the previous entry is counted towards its world; the
+// decompressor reads ahead, so this is an approximation.
+func (p *extractProgress) entry(name, world string, read int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.advance(read)
+	p.current, p.world = name, world
+}
+
+// advance counts the compressed input up to read towards the current world.
+func (p *extractProgress) advance(read int64) {
+	if p.world != "" {
+		c := p.counts(p.world)
+		if read < 0 || c.compressed < 0 {
+			c.compressed = -1
+		} else {
+			c.compressed += read - p.read
+		}
+	}
+	p.read = read
+}
+
+// file records a file of size bytes written to world.
+func (p *extractProgress) file(world string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.counts(world)
+	c.files++
+	c.bytes += size
+	p.files++
+	p.bytes += size
+}
+
+// finish counts the compressed input left, read bytes in total, towards the
+// last entry's world.
+func (p *extractProgress) finish(read int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.advance(read)
+	p.world = ""
+}
+
+// line returns the progress lines: the totals and current entry, then the
+// files and bytes of each world.
+func (p *extractProgress) line() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	line := fmt.Sprintf("  → extracting: %d files, %s written, at %s", p.files, formatBytes(p.bytes), p.current)
+	if len(p.order) == 0 {
+		return line
+	}
+	worlds := make([]string, len(p.order))
+	for i, w := range p.order {
+		c := p.worlds[w]
+		worlds[i] = fmt.Sprintf("%s %d files, %s", w, c.files, formatBytes(c.bytes))
+	}
+	return line + "\n    worlds: " + strings.Join(worlds, "; ")
+}
+
+// summary returns the size of what was extracted for world, with the
+// compressed input it took when known. Input read ahead skews that by up to
+// the read-ahead, so it is left out for worlds less than ten times as large.
+func (p *extractProgress) summary(world string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.worlds[world]
+	if !ok {
+		return formatBytes(0)
+	}
+	if c.compressed < 0 || c.bytes < 10*p.ahead {
+		return formatBytes(c.bytes)
+	}
+	return fmt.Sprintf("%s, ≈ %s compressed", formatBytes(c.bytes), formatBytes(c.compressed))
+}
+
+// inputCounter counts the compressed bytes read through it. The count may
+// be read while a decompressor goroutine reads through it.
+type inputCounter struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *inputCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// position returns the bytes read so far, or -1 for a nil counter.
+func (c *inputCounter) position() int64 {
+	if c == nil {
+		return -1
+	}
+	return c.n.Load()
+}
@@ -0,0 +1,80 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	start := time.Unix(0, 0)
+	var notices []string
+	p := newProgress(100<<20, start, func(msg string) { notices = append(notices, msg) })
+
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	got := p.update(at(5), 50<<20, []float64{1, 0.25})
+	want := "  → 50.0 MiB / 100.0 MiB (50%), 10.0 MiB/s, ETA 5s\n    workers: done 25%"
+	if got != want {
+		t.Errorf("update = %q, want %q", got, want)
+	}
+	if len(notices) != 1 || !strings.HasPrefix(notices[0], "50.0 MiB / 100.0 MiB (50%)") {
+		t.Errorf("notices after 50%% = %q, want one for the second quarter", notices)
+	}
+
+	// Samples older than the window no longer count towards the speed:
+	// 30 MiB came in over 30 seconds, then 5 MiB in the last 5.
+	p.update(at(10), 60<<20, []float64{1, 0.5})
+	p.update(at(40), 90<<20, []float64{1, 0.9})
+	if line := p.update(at(45), 95<<20, []float64{1, 0.95}); !strings.Contains(line, "1.0 MiB/s, ETA 5s") {
+		t.Errorf("update = %q, want the speed of the last 5 seconds", line)
+	}
+	if len(notices) != 2 {
+		t.Errorf("notices after 95%% = %q, want one for the third quarter", notices)
+	}
+
+	final := p.finish(at(50), 100<<20)
+	if want := "  → downloaded 100.0 MiB in 50s (2.0 MiB/s average)"; final != want {
+		t.Errorf("finish = %q, want %q", final, want)
+	}
+	if len(notices) != 3 || notices[2] != strings.TrimPrefix(final, "  → ") {
+		t.Errorf("notices = %q, want the final line last", notices)
+	}
+}
+
+func TestExtractProgress(t *testing.T) {
+	p := newExtractProgress(100)
+	p.entry("world/level.dat", "world", 10)
+	p.file("world", 2000)
+	p.entry("plugins/big.bin", "", 500)
+	p.entry("world_nether/DIM-1/r.mca", "world_nether", 5000)
+	p.file("world_nether", 300)
+	p.entry("world/region/r.0.0.mca", "world", 5200)
+	p.file("world", 1000)
+
+	want := "  → extracting: 3 files, 3.2 KiB written, at world/region/r.0.0.mca\n" +
+		"    worlds: world 2 files, 2.9 KiB; world_nether 1 files, 300 B"
+	if got := p.line(); got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+
+	p.finish(5700)
+	// world read 10–500 and 5200–5700; plugins/big.bin's input is nobody's.
+	if got, want := p.summary("world"), "2.9 KiB, ≈ 990 B compressed"; got != want {
+		t.Errorf("summary(world) = %q, want %q", got, want)
+	}
+	// 300 B is less than ten times the read-ahead.
+	if got, want := p.summary("world_nether"), "300 B"; got != want {
+		t.Errorf("summary(world_nether) = %q, want %q", got, want)
+	}
+	if got, want := p.summary("world_the_end"), "0 B"; got != want {
+		t.Errorf("summary(world_the_end) = %q, want %q", got, want)
+	}
+
+	unknown := newExtractProgress(0)
+	unknown.entry("world/level.dat", "world", -1)
+	unknown.file("world", 2000)
+	unknown.finish(-1)
+	if got, want := unknown.summary("world"), "2.0 KiB"; got != want {
+		t.Errorf("summary without input counts = %q, want %q", got, want)
+	}
+}
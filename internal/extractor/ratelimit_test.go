@@ -0,0 +1,44 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterShared(t *testing.T) {
+	// Four readers share 4 MiB/s; after the initial burst of 1 MiB they
+	// must take about (4 MiB - 1 MiB) / 4 MiB/s = 0.75 s together.
+	const rate = 4 << 20
+	l := newRateLimiter(rate)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := limitReader(context.Background(), bytes.NewReader(make([]byte, 1<<20)), l)
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 600*time.Millisecond || elapsed > 3*time.Second {
+		t.Errorf("4 MiB at 4 MiB/s with a 1 MiB burst took %v, want about 750ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := limitReader(ctx, bytes.NewReader(make([]byte, 8<<20)), newRateLimiter(64<<10))
+	if _, err := io.Copy(io.Discard, r); err != context.Canceled {
+		t.Errorf("cancelled read: err = %v, want context.Canceled", err)
+	}
+
+	if limitReader(ctx, r, nil) != r {
+		t.Error("limitReader without a limiter should return the reader unchanged")
+	}
+}
@@ -0,0 +1,368 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// SeekIndexName is the file in the server directory where "indexed" mode
+// keeps the archive index between runs; the workflow caches it with the run
+// records.
+const SeekIndexName = ".bluemap-archive-index.json"
+
+// downloadIndexedExtract implements "indexed" mode. With a seekable index of
+// the same backup at opts.SeekIndexPath, only the byte ranges holding the
+// worlds are fetched. Otherwise the archive is downloaded to disk in
+// parallel, extracted, and indexed for the next run against the backup.
+func downloadIndexedExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+	idx, err := LoadIndex(opts.SeekIndexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠️  ignoring archive index: %v\n", err)
+		idx = nil
+	}
+	if idx != nil && idx.BackupUUID == opts.BackupUUID {
+		if idx.Seekable {
+			err := extractRanges(ctx, downloadURL, idx, outputDir, worlds, opts)
+			if !errors.Is(err, errNoRange) {
+				return err
+			}
+			// Files extracted from earlier ranges are written again.
+			fmt.Printf("  → %v; downloading the backup whole\n", errNoRange)
+			return downloadAutoExtract(ctx, downloadURL, outputDir, worlds, opts)
+		}
+		fmt.Println("  → the backup is not seekable (one gzip stream or a zip); downloading it whole")
+		return downloadAutoExtract(ctx, downloadURL, outputDir, worlds, opts)
+	}
+
+	probeStart := time.Now()
+	contentLength, rangeOK, err := probeDownload(ctx, downloadURL, opts.Header)
+	if opts.Timings != nil {
+		opts.Timings.Probe = time.Since(probeStart)
+	}
+	if err != nil {
+		return fmt.Errorf("probing download URL: %w", err)
+	}
+	if !rangeOK || contentLength <= 0 {
+		// Ranges could not be fetched later either.
+		fmt.Println("  → single-connection download (server does not support Range requests; not indexed)")
+		return downloadStreamExtract(ctx, downloadURL, outputDir, worlds, opts)
+	}
+	numWorkers := connectionCount(contentLength)
+	if opts.Connections > 0 {
+		numWorkers = opts.Connections
+	}
+	fmt.Printf("  → parallel download (%d connections, %s), indexed for later runs\n",
+		numWorkers, formatBytes(contentLength))
+	if opts.IndexPath == "" {
+		opts.IndexPath = opts.SeekIndexPath
+	}
+	opts.indexMembers = true
+	return parallelDownloadAndExtract(ctx, downloadURL, outputDir, worlds, contentLength, numWorkers, opts)
+}
+
+// indexDownload adds the gzip members of the archive at path, just
+// extracted, to the index written to opts.IndexPath and saves it to
+// opts.SeekIndexPath too. A zip archive, which has no tar index, is recorded
+// as not seekable.
+func indexDownload(path string, opts DownloadOptions) error {
+	idx, err := LoadIndex(opts.IndexPath)
+	if err != nil {
+		return err
+	}
+	if idx == nil || idx.BackupUUID != opts.BackupUUID {
+		idx = &Index{BackupUUID: opts.BackupUUID}
+	} else {
+		start := time.Now()
+		members, err := IndexMembers(path, idx)
+		if err != nil {
+			return err
+		}
+		if idx.Seekable {
+			fmt.Printf("  ✔  indexed %d gzip members in %s; later runs fetch only the worlds\n",
+				members, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	if !idx.Seekable {
+		fmt.Println("  → the archive is not seekable (one gzip stream or a zip); later runs download it whole")
+	}
+	if err := idx.Save(opts.IndexPath); err != nil {
+		return err
+	}
+	if opts.SeekIndexPath != opts.IndexPath {
+		return idx.Save(opts.SeekIndexPath)
+	}
+	return nil
+}
+
+// maxSeekMember is the largest decompressed gzip member IndexMembers reads.
+// bgzip writes members of 64 KiB; a member past this size is (the start of)
+// a single gzip stream, which cannot be fetched in parts.
+const maxSeekMember = 64 << 20
+
+// IndexMembers reads the gzip members of the tar.gz archive at path and
+// records in idx, which lists the archive's entries, the members holding
+// each entry. The index becomes Seekable when the archive has more than one
+// member, as bgzip writes them. It stops at the first member larger than
+// maxSeekMember, leaving the index not seekable, rather than decompress a
+// single stream to its end. It returns the number of members read.
+func IndexMembers(path string, idx *Index) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// compress/gzip reads exactly one member at a time from an
+	// io.ByteReader, so the bytes counted so far give each member's offset.
+	br := &byteCounter{r: bufio.NewReaderSize(f, 1<<20)}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return 0, fmt.Errorf("reading gzip header: %w", err)
+	}
+	type member struct{ offset, start int64 } // compressed offset, decompressed start
+	var members []member
+	var offset, start int64
+	for {
+		members = append(members, member{offset, start})
+		zr.Multistream(false)
+		n, err := io.CopyN(io.Discard, zr, maxSeekMember+1)
+		if n > maxSeekMember {
+			idx.Seekable = false
+			return len(members), nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("decompressing gzip member at %d: %w", offset, err)
+		}
+		start += n
+		offset = br.n
+		if err := zr.Reset(br); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("reading gzip member at %d: %w", offset, err)
+		}
+	}
+
+	// at returns the member holding the decompressed offset pos.
+	at := func(pos int64) int {
+		return sort.Search(len(members), func(i int) bool { return members[i].start > pos }) - 1
+	}
+	var prevEnd int64
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		// The entry's header blocks follow the padded data of the previous one.
+		first := members[at(roundBlock(prevEnd))]
+		e.Member, e.Skip = first.offset, roundBlock(prevEnd)-first.start
+		e.MemberEnd = info.Size()
+		if last := at(roundBlock(e.End) - 1); last+1 < len(members) {
+			e.MemberEnd = members[last+1].offset
+		}
+		prevEnd = e.End
+	}
+	idx.Seekable = len(members) > 1
+	idx.Size = info.Size()
+	return len(members), nil
+}
+
+// byteSpan is a compressed byte range of a seekable archive: decompressing
+// it and skipping skip bytes gives the tar entries between the decompressed
+// offsets start and end.
+type byteSpan struct {
+	from, to   int64 // compressed, end exclusive
+	skip       int64
+	start, end int64
+}
+
+// spans returns the byte ranges holding the entries that belong to the
+// backup folder prefixes; neighbouring entries share a range.
+func (x *Index) spans(prefixes map[string]string) []byteSpan {
+	var spans []byteSpan
+	var prevEnd int64
+	open := false
+	for _, e := range x.Entries {
+		start := roundBlock(prevEnd)
+		prevEnd = e.End
+		if w, _ := matchWorld(e.Name, prefixes); w == "" {
+			open = false
+			continue
+		}
+		if open {
+			s := &spans[len(spans)-1]
+			s.to, s.end = e.MemberEnd, roundBlock(e.End)
+			continue
+		}
+		spans = append(spans, byteSpan{from: e.Member, to: e.MemberEnd, skip: e.Skip, start: start, end: roundBlock(e.End)})
+		open = true
+	}
+	return spans
+}
+
+// extractRanges extracts worlds from the byte ranges of a seekable archive
+// that hold them, fetched with Range requests. The panel's checksum covers
+// the whole archive, so it cannot be verified.
+func extractRanges(ctx context.Context, downloadURL string, idx *Index, outputDir string, worlds []string, opts DownloadOptions) error {
+	spans := idx.spans(backupPrefixes(worlds, opts))
+	var fetched int64
+	for _, s := range spans {
+		fetched += s.to - s.from
+	}
+	fmt.Printf("  → ranged download of %d span(s), %s of %s (indexed)\n", len(spans), formatBytes(fetched), formatBytes(idx.Size))
+	if opts.Checksum != "" {
+		fmt.Println("  → the backup checksum covers the whole archive; ranges are not verified")
+	}
+
+	rr := &rangeReader{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: 30 * time.Minute},
+		url:     downloadURL,
+		header:  opts.Header,
+		limiter: newRateLimiter(opts.RateLimit),
+		spans:   spans,
+	}
+	a := rr.archive()
+	defer a.close()
+
+	opts.index = idx
+	opts.IndexPath = ""
+	return extractEntries(ctx, []*archive{a}, outputDir, worlds, opts, opts.writers())
+}
+
+// errNoRange reports a Range request not answered with 206 Partial Content,
+// after which the backup is downloaded whole.
+var errNoRange = errors.New("the server did not return the byte range")
+
+// rangeReader reads the decompressed tar data of byte spans one after the
+// other, each fetched with its own Range request or, with file set, read
+// from the archive on disk.
+type rangeReader struct {
+	ctx     context.Context
+	client  *http.Client
+	url     string
+	header  http.Header
+	limiter *rateLimiter
+	file    *os.File
+	spans   []byteSpan
+
+	input inputCounter // compressed bytes of every span
+	body  io.Closer
+	cur   io.Reader
+	left  int64 // bytes of the current span not read yet
+}
+
+// archive returns the tar entries of the spans as an archive.
+func (r *rangeReader) archive() *archive {
+	// The spans end with whole entries; two zero blocks end the tar stream.
+	cr := &countingReader{r: io.MultiReader(r, bytes.NewReader(make([]byte, 2*blockSize)))}
+	return &archive{entries: newConcatTar(cr), counter: cr, input: &r.input, ahead: 4096, partial: true, close: r.close}
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for r.cur == nil {
+		if len(r.spans) == 0 {
+			return 0, io.EOF
+		}
+		if err := r.open(r.spans[0]); err != nil {
+			return 0, err
+		}
+		r.spans = r.spans[1:]
+	}
+	if int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	n, err := r.cur.Read(p)
+	r.left -= int64(n)
+	if r.left == 0 {
+		r.close()
+		return n, nil
+	}
+	if errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("byte range ended %d bytes early: %w", r.left, io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+// open requests the span s and skips to its first entry.
+func (r *rangeReader) open(s byteSpan) error {
+	body, err := r.fetch(s)
+	if err != nil {
+		return err
+	}
+	r.input.r = limitReader(r.ctx, body, r.limiter)
+	gz, err := gzip.NewReader(&r.input)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("range %d-%d: %w", s.from, s.to-1, err)
+	}
+	if _, err := io.CopyN(io.Discard, gz, s.skip); err != nil {
+		body.Close()
+		return fmt.Errorf("range %d-%d: %w", s.from, s.to-1, err)
+	}
+	r.body, r.cur, r.left = body, gz, s.end-s.start
+	return nil
+}
+
+// fetch returns the compressed bytes of the span s.
+func (r *rangeReader) fetch(s byteSpan) (io.ReadCloser, error) {
+	if r.file != nil {
+		return io.NopCloser(io.NewSectionReader(r.file, s.from, s.to-s.from)), nil
+	}
+	req, err := newRequest(r.ctx, r.url, r.header)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", s.from, s.to-1))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("range %d-%d: %w (status %d)", s.from, s.to-1, errNoRange, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (r *rangeReader) close() {
+	if r.body != nil {
+		r.body.Close()
+	}
+	r.body, r.cur = nil, nil
+}
+
+// byteCounter counts the bytes read through a bufio.Reader, including those
+// read one at a time.
+type byteCounter struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (b *byteCounter) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *byteCounter) ReadByte() (byte, error) {
+	c, err := b.r.ReadByte()
+	if err == nil {
+		b.n++
+	}
+	return c, err
+}
+
+// roundBlock rounds a tar stream offset up to the next block boundary.
+func roundBlock(n int64) int64 {
+	return (n + blockSize - 1) / blockSize * blockSize
+}
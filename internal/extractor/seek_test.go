@@ -0,0 +1,237 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// bgzipTar builds a tar archive of regular files whose content is their name
+// repeated size times, compressed as independent gzip members of at most
+// memberSize decompressed bytes, as bgzip writes them.
+func bgzipTar(memberSize int, files map[string]int, order ...string) []byte {
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	for _, name := range order {
+		data := strings.Repeat(name, files[name])
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+
+	var buf bytes.Buffer
+	for b := raw.Bytes(); len(b) > 0; {
+		n := min(memberSize, len(b))
+		gz := gzip.NewWriter(&buf)
+		gz.Write(b[:n])
+		gz.Close()
+		b = b[n:]
+	}
+	return buf.Bytes()
+}
+
+func TestIndexedDownload(t *testing.T) {
+	files := map[string]int{
+		"./world/level.dat":           1,
+		"./world/region/r.0.0.mca":    300,
+		"./plugins/dynmap/big.bin":    2000,
+		"./world_nether/level.dat":    1,
+		"./world_nether/DIM-1/r.mca":  100,
+		"./plugins/other/cache.bin":   2000,
+		"./logs/latest.log":           10,
+		"./world_the_end/DIM1/r.mca":  50,
+		"./world_the_end/level.dat":   1,
+		"./plugins/last/trailing.bin": 500,
+	}
+	order := []string{
+		"./world/level.dat", "./world/region/r.0.0.mca", "./plugins/dynmap/big.bin",
+		"./world_nether/level.dat", "./world_nether/DIM-1/r.mca", "./plugins/other/cache.bin",
+		"./logs/latest.log", "./world_the_end/DIM1/r.mca", "./world_the_end/level.dat",
+		"./plugins/last/trailing.bin",
+	}
+	archive := bgzipTar(4096, files, order...)
+
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w}
+		http.ServeContent(cw, r, "backup.tar.gz", time.Time{}, bytes.NewReader(archive))
+		served.Add(cw.n)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	opts := DownloadOptions{
+		Mode:          "indexed",
+		Connections:   2,
+		SeekIndexPath: filepath.Join(dir, SeekIndexName),
+		BackupUUID:    "uuid-1",
+	}
+	worlds := []string{"world", "world_nether", "world_the_end"}
+	for _, run := range []string{"first", "second", "third", "fourth"} {
+		os.Mkdir(filepath.Join(dir, run), 0o755)
+	}
+
+	// First run: the whole archive is downloaded and indexed.
+	if err := DownloadAndExtractWorlds(context.Background(), srv.URL, filepath.Join(dir, "first"), worlds, opts); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	idx, err := LoadIndex(opts.SeekIndexPath)
+	if err != nil || idx == nil {
+		t.Fatalf("LoadIndex = %v, %v", idx, err)
+	}
+	if !idx.Seekable || idx.Size != int64(len(archive)) || len(idx.Entries) != len(order) {
+		t.Fatalf("index: seekable %v, size %d, %d entries; want seekable, %d, %d", idx.Seekable, idx.Size, len(idx.Entries), len(archive), len(order))
+	}
+
+	// Second run: only the members holding the worlds are fetched.
+	served.Store(0)
+	out := filepath.Join(dir, "second")
+	if err := DownloadAndExtractWorlds(context.Background(), srv.URL, out, worlds, opts); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if n := served.Load(); n == 0 || n >= int64(len(archive)) {
+		t.Errorf("second run fetched %d of %d bytes, want a part", n, len(archive))
+	}
+	for _, name := range order {
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if strings.HasPrefix(name, "./world") {
+			if want := strings.Repeat(name, files[name]); err != nil || string(data) != want {
+				t.Errorf("%s: %d bytes, %v; want %d bytes", name, len(data), err, len(want))
+			}
+		} else if err == nil {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+
+	// Another backup is downloaded whole and indexed anew.
+	opts.BackupUUID = "uuid-2"
+	if err := DownloadAndExtractWorlds(context.Background(), srv.URL, filepath.Join(dir, "third"), worlds, opts); err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+	if idx, _ := LoadIndex(opts.SeekIndexPath); idx == nil || idx.BackupUUID != "uuid-2" {
+		t.Errorf("index not rewritten for the new backup: %+v", idx)
+	}
+
+	// A server that stops answering Range requests gets the backup
+	// downloaded whole.
+	noRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		http.ServeContent(w, r, "backup.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer noRange.Close()
+	out = filepath.Join(dir, "fourth")
+	if err := DownloadAndExtractWorlds(context.Background(), noRange.URL, out, worlds, opts); err != nil {
+		t.Fatalf("run without ranges: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "world_the_end", "level.dat")); err != nil || string(data) != "./world_the_end/level.dat" {
+		t.Errorf("run without ranges: level.dat = %q, %v", data, err)
+	}
+}
+
+func TestIndexMembersSingleStream(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.tar.gz")
+	if err := os.WriteFile(archive, tarGz("./world/level.dat", "./logs/latest.log").Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(dir, "index.json")
+	err = extractWorlds(context.Background(), f, filepath.Join(dir, "out"), []string{"world"}, DownloadOptions{IndexPath: indexPath, BackupUUID: "u"}, 0)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := LoadIndex(indexPath)
+	if err != nil || idx == nil {
+		t.Fatalf("LoadIndex = %v, %v", idx, err)
+	}
+	members, err := IndexMembers(archive, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members != 1 || idx.Seekable {
+		t.Errorf("IndexMembers = %d, seekable %v; want 1, false", members, idx.Seekable)
+	}
+}
+
+func TestExtractArchiveSeekable(t *testing.T) {
+	files := map[string]int{
+		"./world/level.dat":          1,
+		"./world/region/r.0.0.mca":   300,
+		"./plugins/dynmap/big.bin":   2000,
+		"./world_nether/level.dat":   1,
+		"./world_nether/DIM-1/r.mca": 100,
+		"./logs/latest.log":          10,
+	}
+	order := []string{
+		"./world/level.dat", "./world/region/r.0.0.mca", "./plugins/dynmap/big.bin",
+		"./world_nether/level.dat", "./world_nether/DIM-1/r.mca", "./logs/latest.log",
+	}
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.tar.gz")
+	if err := os.WriteFile(archive, bgzipTar(4096, files, order...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	worlds := []string{"world", "world_nether", "world_the_end"}
+
+	// The first pass builds the tar index, IndexMembers makes it seekable.
+	indexPath := filepath.Join(dir, "index.json")
+	if err := ExtractArchive(context.Background(), archive, nil, filepath.Join(dir, "first"), worlds, DownloadOptions{IndexPath: indexPath, BackupUUID: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := LoadIndex(indexPath)
+	if err != nil || idx == nil {
+		t.Fatalf("LoadIndex = %v, %v", idx, err)
+	}
+	if _, err := IndexMembers(archive, idx); err != nil || !idx.Seekable {
+		t.Fatalf("IndexMembers: seekable %v, %v", idx.Seekable, err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if readers := idx.worldReaders(f, backupPrefixes(worlds, DownloadOptions{})); len(readers) != 2 {
+		t.Errorf("worldReaders = %d readers, want one per world in the archive", len(readers))
+	}
+
+	out := filepath.Join(dir, "second")
+	if err := ExtractArchive(context.Background(), archive, idx, out, worlds, DownloadOptions{Writers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range order {
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if strings.HasPrefix(name, "./world") {
+			if want := strings.Repeat(name, files[name]); err != nil || string(data) != want {
+				t.Errorf("%s: %d bytes, %v; want %d bytes", name, len(data), err, len(want))
+			}
+		} else if err == nil {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+}
+
+// countingWriter counts the body bytes written to a response.
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
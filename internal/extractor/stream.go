@@ -0,0 +1,222 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// streamSegmentSize is the size of the ranges a streamed parallel download
+// fetches. A segment is held in memory from its download until the archive
+// reader has read it.
+const streamSegmentSize = 8 << 20
+
+// DefaultStreamBuffer is the memory a streamed parallel download holds at
+// most when DownloadOptions.StreamBuffer is 0.
+const DefaultStreamBuffer = 256 << 20
+
+// downloadParallelStreamExtract downloads the archive over parallel Range
+// requests like parallel mode, but feeds the ranges to the archive reader in
+// order as they complete instead of writing a temp file, so the archive never
+// takes disk space next to the extracted worlds.
+func downloadParallelStreamExtract(ctx context.Context, downloadURL, outputDir string, worlds []string, opts DownloadOptions) error {
+	contentLength, numWorkers, err := probeParallel(ctx, downloadURL, opts)
+	if err != nil {
+		return err
+	}
+	buffer := opts.StreamBuffer
+	if buffer <= 0 {
+		buffer = DefaultStreamBuffer
+	}
+	fmt.Printf("  → parallel download streamed into extraction (%d connections, %s, %s buffer)\n",
+		numWorkers, formatBytes(contentLength), formatBytes(buffer))
+
+	client := &http.Client{Timeout: 30 * time.Minute}
+	stream := newRangeStream(ctx, client, downloadURL, opts.Header, contentLength, numWorkers, buffer, newRateLimiter(opts.RateLimit))
+	defer stream.Close()
+
+	// Progress reporter goroutine. The workers take segments in turn, so
+	// there is no share per worker to report.
+	prog := newProgress(contentLength, time.Now(), opts.Notice)
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		defer close(progressStopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				fmt.Println(prog.update(now, stream.downloaded.Load(), nil))
+			case <-progressDone:
+				return
+			}
+		}
+	}()
+
+	err = extractStream(ctx, stream, outputDir, worlds, opts)
+	close(progressDone)
+	<-progressStopped
+	if err != nil {
+		return err
+	}
+	fmt.Println(prog.finish(time.Now(), stream.downloaded.Load()))
+	return nil
+}
+
+// rangeStream reads a remote file in order while a pool of workers downloads
+// its segments ahead with Range requests. At most a buffer's worth of
+// segments are downloaded ahead of the reader; a worker waits for the reader
+// to free a slot before it starts another segment.
+type rangeStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	size    int64
+	results []chan segmentResult // one per segment, filled once by a worker
+	slots   chan struct{}        // segments downloading or waiting to be read
+
+	next    int    // segment to read after cur
+	cur     []byte // unread data of the segment being read
+	holding bool   // cur holds a slot
+	err     error
+
+	downloaded atomic.Int64
+}
+
+type segmentResult struct {
+	data []byte
+	err  error
+}
+
+func newRangeStream(ctx context.Context, client *http.Client, url string, header http.Header, size int64, workers int, buffer int64, limiter *rateLimiter) *rangeStream {
+	ctx, cancel := context.WithCancel(ctx)
+	segments := int((size + streamSegmentSize - 1) / streamSegmentSize)
+	s := &rangeStream{
+		ctx:     ctx,
+		cancel:  cancel,
+		size:    size,
+		results: make([]chan segmentResult, segments),
+		slots:   make(chan struct{}, max(buffer/streamSegmentSize, 2)),
+	}
+	for i := range s.results {
+		s.results[i] = make(chan segmentResult, 1)
+	}
+
+	// Hand out segments in order, each once a slot is free.
+	jobs := make(chan int)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(jobs)
+		for i := range segments {
+			select {
+			case s.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range workers {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for i := range jobs {
+				start, end := s.segment(i)
+				data, err := fetchRange(ctx, client, url, header, start, end, limiter, &s.downloaded)
+				s.results[i] <- segmentResult{data, err}
+			}
+		}()
+	}
+	return s
+}
+
+// segment returns the first and last byte of segment i.
+func (s *rangeStream) segment(i int) (start, end int64) {
+	start = int64(i) * streamSegmentSize
+	return start, min(start+streamSegmentSize, s.size) - 1
+}
+
+func (s *rangeStream) Read(p []byte) (int, error) {
+	for len(s.cur) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.holding {
+			<-s.slots
+			s.holding = false
+		}
+		if s.next == len(s.results) {
+			return 0, io.EOF
+		}
+		select {
+		case r := <-s.results[s.next]:
+			if r.err != nil {
+				start, end := s.segment(s.next)
+				s.err = fmt.Errorf("bytes %d-%d: %w", start, end, r.err)
+				return 0, s.err
+			}
+			s.cur, s.holding = r.data, true
+			s.next++
+		case <-s.ctx.Done():
+			s.err = s.ctx.Err()
+			return 0, s.err
+		}
+	}
+	n := copy(p, s.cur)
+	s.cur = s.cur[n:]
+	return n, nil
+}
+
+// Close stops the workers and waits for them to return.
+func (s *rangeStream) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// fetchRange downloads bytes [start, end] of url into memory, adding the
+// bytes to downloaded as they arrive.
+func fetchRange(ctx context.Context, client *http.Client, url string, header http.Header, start, end int64, limiter *rateLimiter, downloaded *atomic.Int64) ([]byte, error) {
+	req, err := newRequest(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("expected 206 Partial Content, got %d", resp.StatusCode)
+	}
+
+	body := limitReader(ctx, resp.Body, limiter)
+	data := make([]byte, end+1-start)
+	for off := 0; off < len(data); {
+		n, err := body.Read(data[off:min(off+256<<10, len(data))])
+		off += n
+		downloaded.Add(int64(n))
+		if errors.Is(err, io.EOF) && off < len(data) {
+			return nil, fmt.Errorf("connection closed after %d of %d bytes", off, len(data))
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+	return data, nil
+}
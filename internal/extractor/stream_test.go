@@ -0,0 +1,55 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRangeStream(t *testing.T) {
+	data := make([]byte, 2*streamSegmentSize+12345)
+	rand.New(rand.NewSource(1)).Read(data)
+	var failAt atomic.Int64 // start of a range answered with an error, or -1
+	failAt.Store(-1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if at := failAt.Load(); at >= 0 && strings.HasPrefix(r.Header.Get("Range"), "bytes="+strconv.FormatInt(at, 10)+"-") {
+			http.Error(w, "gone", http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, "backup.tar.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	// A buffer of two segments keeps the third waiting until the first is read.
+	s := newRangeStream(context.Background(), srv.Client(), srv.URL, nil, int64(len(data)), 3, 2*streamSegmentSize, nil)
+	var got bytes.Buffer
+	if _, err := got.ReadFrom(s); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Errorf("read %d bytes differing from the %d served", got.Len(), len(data))
+	}
+	if n := s.downloaded.Load(); n != int64(len(data)) {
+		t.Errorf("downloaded = %d, want %d", n, len(data))
+	}
+
+	failAt.Store(streamSegmentSize)
+	s = newRangeStream(context.Background(), srv.Client(), srv.URL, nil, int64(len(data)), 3, DefaultStreamBuffer, nil)
+	defer s.Close()
+	got.Reset()
+	_, err := got.ReadFrom(s)
+	if err == nil || !strings.Contains(err.Error(), "got 500") {
+		t.Errorf("err = %v, want the failed segment's status", err)
+	}
+	if got.Len() != streamSegmentSize {
+		t.Errorf("read %d bytes before the error, want the first segment's %d", got.Len(), streamSegmentSize)
+	}
+}
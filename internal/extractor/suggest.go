@@ -0,0 +1,91 @@
+package extractor
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions caps the candidates offered for a missing world.
+const maxSuggestions = 3
+
+// SuggestWorlds returns the directories most likely meant by a world folder
+// that is not in the backup, best match first. A directory qualifies when
+// its last path element equals the name ignoring case, contains it (or is
+// contained in it), or is within a few edits of it; "world" suggests
+// "World" and "survival_world".
+func SuggestWorlds(name string, dirs []string) []string {
+	want := strings.ToLower(path.Base(name))
+	type candidate struct {
+		dir  string
+		dist int
+	}
+	var found []candidate
+	seen := make(map[string]bool)
+	for _, d := range dirs {
+		d = strings.TrimSuffix(d, "/")
+		if d == "" || d == name || seen[d] {
+			continue
+		}
+		seen[d] = true
+
+		base := strings.ToLower(path.Base(d))
+		dist := editDistance(want, base)
+		similar := dist <= max(1, len(want)/3) ||
+			(min(len(base), len(want)) >= 3 && (strings.Contains(base, want) || strings.Contains(want, base)))
+		if similar {
+			found = append(found, candidate{d, dist})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].dist != found[j].dist {
+			return found[i].dist < found[j].dist
+		}
+		return found[i].dir < found[j].dir
+	})
+
+	var out []string
+	for i := 0; i < len(found) && i < maxSuggestions; i++ {
+		out = append(out, found[i].dir)
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// didYouMean formats suggestions as ` (did you mean "a", "b" or "c"?)`, or
+// returns "" when there are none.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = `"` + s + `"`
+	}
+	list := quoted[len(quoted)-1]
+	if len(quoted) > 1 {
+		list = strings.Join(quoted[:len(quoted)-1], ", ") + " or " + list
+	}
+	return " (did you mean " + list + "?)"
+}
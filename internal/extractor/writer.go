@@ -0,0 +1,149 @@
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// maxBufferedFile is the largest file handed to the writer pool. Bigger files
+// are written inline so memory stays bounded at roughly
+// maxBufferedFile × 2 × workers.
+const maxBufferedFile = 16 << 20 // 16 MiB
+
+// writerPool writes extracted files on several goroutines so decompression
+// (which is inherently sequential for a single gzip stream) overlaps with
+// file creation and disk writes. Worlds hold hundreds of thousands of small
+// files (region, entity and POI files, player data), and creating them one
+// at a time behind the tar reader leaves both the CPU and the disk idle.
+//
+// Each name goes to the worker picked by its hash, which writes its files in
+// order, so when an archive holds a name twice the last entry wins as it
+// does when extracting inline.
+type writerPool struct {
+	root  *os.Root
+	limit int64           // largest file size
+	jobs  []chan writeJob // one queue per worker
+	bufs  sync.Pool
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// writeJob is a buffered file for a worker to write, or, with done set, a
+// marker the worker closes once it has written the files queued before it.
+type writeJob struct {
+	name string
+	mode os.FileMode
+	buf  *bytes.Buffer
+	done chan struct{}
+}
+
+// writers returns the number of writer goroutines: opts.Writers if set,
+// otherwise one per CPU, leaving one for decompression, capped at 8. A
+// result of 1 writes files inline on the reading goroutine.
+func (o DownloadOptions) writers() int {
+	if o.Writers > 0 {
+		return o.Writers
+	}
+	n := runtime.NumCPU() - 1
+	if n > 8 {
+		n = 8
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func newWriterPool(root *os.Root, workers int, limit int64) *writerPool {
+	p := &writerPool{root: root, limit: limit, jobs: make([]chan writeJob, workers)}
+	p.bufs.New = func() any { return new(bytes.Buffer) }
+	for i := range p.jobs {
+		jobs := make(chan writeJob, 1)
+		p.jobs[i] = jobs
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range jobs {
+				if job.done != nil {
+					close(job.done)
+					continue
+				}
+				if p.firstErr() == nil {
+					if err := writeFile(p.root, job.name, job.buf, job.mode, p.limit); err != nil {
+						p.setErr(fmt.Errorf("writing file %s: %w", filepath.Join(p.root.Name(), job.name), err))
+					}
+				}
+				job.buf.Reset()
+				p.bufs.Put(job.buf)
+			}
+		}()
+	}
+	return p
+}
+
+// queue returns the queue of the worker that writes name.
+func (p *writerPool) queue(name string) chan writeJob {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return p.jobs[h.Sum32()%uint32(len(p.jobs))]
+}
+
+// write queues the file name of the pool's root, the size bytes of r, for
+// its worker, or writes it inline, once the worker has written an earlier
+// entry of the same name, when it is too large to buffer. It returns the
+// first error any worker has hit so the caller can stop reading the archive
+// early.
+func (p *writerPool) write(name string, r io.Reader, size int64, mode os.FileMode) error {
+	if err := p.firstErr(); err != nil {
+		return err
+	}
+	if size > maxBufferedFile {
+		done := make(chan struct{})
+		p.queue(name) <- writeJob{done: done}
+		<-done
+		if err := writeFile(p.root, name, r, mode, p.limit); err != nil {
+			return fmt.Errorf("writing file %s: %w", filepath.Join(p.root.Name(), name), err)
+		}
+		return nil
+	}
+	// r fails past size, so the buffer stays within maxBufferedFile.
+	buf := p.bufs.Get().(*bytes.Buffer)
+	if _, err := buf.ReadFrom(r); err != nil {
+		buf.Reset()
+		p.bufs.Put(buf)
+		return err
+	}
+	p.queue(name) <- writeJob{name: name, mode: mode, buf: buf}
+	return nil
+}
+
+// wait flushes all queued files and returns the first write error.
+func (p *writerPool) wait() error {
+	for _, jobs := range p.jobs {
+		close(jobs)
+	}
+	p.wg.Wait()
+	return p.firstErr()
+}
+
+func (p *writerPool) firstErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *writerPool) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
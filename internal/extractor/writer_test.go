@@ -0,0 +1,50 @@
+package extractor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterPoolDuplicateNames(t *testing.T) {
+	dir := t.TempDir()
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	p := newWriterPool(root, 8, maxBufferedFile*2)
+	var last string
+	for i := range 200 {
+		// Other names keep the workers busy in between.
+		other := fmt.Sprintf("other-%d", i)
+		if err := p.write(other, strings.NewReader(other), int64(len(other)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		last = strings.Repeat(fmt.Sprint(i), 1000)
+		if err := p.write("dup", strings.NewReader(last), int64(len(last)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An entry too large to buffer is written inline after the queued ones.
+	big := strings.Repeat("x", maxBufferedFile+1)
+	if err := p.write("dup-big", strings.NewReader("small"), 5, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.write("dup-big", strings.NewReader(big), int64(len(big)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "dup")); err != nil || string(data) != last {
+		t.Errorf("dup = %.20q (%d bytes), %v; want the last entry", data, len(data), err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "dup-big")); err != nil || len(data) != len(big) {
+		t.Errorf("dup-big = %d bytes, %v; want %d", len(data), err, len(big))
+	}
+}
@@ -0,0 +1,214 @@
+package extractor
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/klauspost/pgzip"
+)
+
+// Signatures of the zip records read by zipStream.
+const (
+	zipLocalHeaderSig = 0x04034b50
+	zipCentralDirSig  = 0x02014b50
+	zipEndSig         = 0x06054b50
+)
+
+// zipMagic starts a zip archive, such as a Crafty Controller backup.
+var zipMagic = []byte("PK\x03\x04")
+
+// entryReader iterates the entries of a backup archive; Read reads the data
+// of the current entry.
+type entryReader interface {
+	Next() (*tar.Header, error)
+	io.Reader
+}
+
+// archive is an open backup archive.
+type archive struct {
+	entries entryReader
+	counter *countingReader // bytes of the tar stream, or of the zip file, read so far
+	input   *inputCounter   // compressed bytes read so far; nil when unknown
+	ahead   int64           // bytes the readers may take from input ahead of the entries
+	zip     bool
+	partial bool // only some entries, fetched by range; offsets are not the archive's
+	close   func()
+}
+
+// openArchive detects the format of the archive r from its first bytes: a
+// gzip compressed tar stream (Pterodactyl, PufferPanel) or a zip file
+// (Crafty Controller). blockSize and blocks tune the gzip read-ahead.
+func openArchive(r io.Reader, blockSize, blocks int) (*archive, error) {
+	input := &inputCounter{r: r}
+	br := bufio.NewReader(input)
+	if magic, _ := br.Peek(len(zipMagic)); bytes.Equal(magic, zipMagic) {
+		cr := &countingReader{r: br}
+		return &archive{entries: &zipStream{r: cr}, counter: cr, input: input, ahead: int64(br.Size()), zip: true, close: func() {}}, nil
+	}
+
+	// pgzip decompresses on its own goroutines, read-ahead, so inflating the
+	// stream no longer competes with tar parsing and file writes for a core.
+	gz, err := pgzip.NewReaderN(br, blockSize, blocks)
+	if err != nil {
+		return nil, fmt.Errorf("creating gzip reader: %w", err)
+	}
+	cr := &countingReader{r: gz}
+	// pgzip keeps up to blocks blocks of blockSize decompressed, 4 of 1 MiB
+	// by default; their input has been read.
+	if blockSize <= 512 {
+		blockSize = 1 << 20
+	}
+	if blocks <= 0 {
+		blocks = 4
+	}
+	ahead := int64(br.Size() + blockSize*blocks)
+	return &archive{entries: newConcatTar(cr), counter: cr, input: input, ahead: ahead, close: func() { gz.Close() }}, nil
+}
+
+// zipStream reads a zip archive front to back from its local file headers,
+// so a zip backup is extracted while it downloads like a tar.gz, without
+// the central directory at its end. Entries whose sizes follow their data
+// (data descriptors, written by streaming zip tools) cannot be read this way
+// and are rejected; the archives Crafty writes have none.
+type zipStream struct {
+	r    io.Reader
+	raw  io.Reader // compressed data left of the current entry
+	data io.Reader // decompressed data of the current entry
+	fl   io.ReadCloser
+}
+
+// Next advances to the next entry. The central directory ends the stream.
+func (z *zipStream) Next() (*tar.Header, error) {
+	if z.raw != nil {
+		if _, err := io.Copy(io.Discard, z.raw); err != nil {
+			return nil, err
+		}
+		z.raw, z.data = nil, nil
+	}
+	if z.fl != nil {
+		z.fl.Close()
+		z.fl = nil
+	}
+
+	var h [30]byte
+	if _, err := io.ReadFull(z.r, h[:4]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading zip header: %w", err)
+	}
+	switch binary.LittleEndian.Uint32(h[:4]) {
+	case zipLocalHeaderSig:
+	case zipCentralDirSig, zipEndSig:
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("zip: unexpected record signature %#x", h[:4])
+	}
+	if _, err := io.ReadFull(z.r, h[4:]); err != nil {
+		return nil, fmt.Errorf("reading zip header: %w", err)
+	}
+
+	flags := binary.LittleEndian.Uint16(h[6:])
+	method := binary.LittleEndian.Uint16(h[8:])
+	crc := binary.LittleEndian.Uint32(h[14:])
+	compressed := int64(binary.LittleEndian.Uint32(h[18:]))
+	size := int64(binary.LittleEndian.Uint32(h[22:]))
+	nameLen := int(binary.LittleEndian.Uint16(h[26:]))
+	extraLen := int(binary.LittleEndian.Uint16(h[28:]))
+
+	buf := make([]byte, nameLen+extraLen)
+	if _, err := io.ReadFull(z.r, buf); err != nil {
+		return nil, fmt.Errorf("reading zip header: %w", err)
+	}
+	name := string(buf[:nameLen])
+	if flags&0x1 != 0 {
+		return nil, fmt.Errorf("zip: %s is encrypted", name)
+	}
+	if flags&0x8 != 0 {
+		return nil, fmt.Errorf("zip: %s has a data descriptor; streamed zip files are not supported", name)
+	}
+	if compressed == 0xffffffff || size == 0xffffffff {
+		size, compressed = zip64Sizes(buf[nameLen:], size, compressed)
+	}
+
+	z.raw = io.LimitReader(z.r, compressed)
+	switch method {
+	case 0: // stored
+		z.data = z.raw
+	case 8: // deflate
+		z.fl = flate.NewReader(z.raw)
+		z.data = z.fl
+	default:
+		return nil, fmt.Errorf("zip: %s uses unsupported compression method %d", name, method)
+	}
+	z.data = &crcReader{r: z.data, h: crc32.NewIEEE(), want: crc, name: name}
+
+	// Zip tools on Windows may write backslashes.
+	name = strings.ReplaceAll(name, `\`, "/")
+	header := &tar.Header{Name: name, Size: size, Mode: 0o644, Typeflag: tar.TypeReg}
+	if strings.HasSuffix(name, "/") {
+		header.Mode, header.Typeflag = 0o755, tar.TypeDir
+	}
+	return header, nil
+}
+
+// Read reads the decompressed data of the current entry.
+func (z *zipStream) Read(p []byte) (int, error) {
+	if z.data == nil {
+		return 0, io.EOF
+	}
+	return z.data.Read(p)
+}
+
+// zip64Sizes reads the sizes stored in the zip64 extra field, which holds
+// the uncompressed then the compressed size, each only when the header field
+// is 0xffffffff.
+func zip64Sizes(extra []byte, size, compressed int64) (int64, int64) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+n {
+			break
+		}
+		field := extra[4 : 4+n]
+		if id == 0x0001 {
+			if size == 0xffffffff && len(field) >= 8 {
+				size = int64(binary.LittleEndian.Uint64(field))
+				field = field[8:]
+			}
+			if compressed == 0xffffffff && len(field) >= 8 {
+				compressed = int64(binary.LittleEndian.Uint64(field))
+			}
+			break
+		}
+		extra = extra[4+n:]
+	}
+	return size, compressed
+}
+
+// crcReader verifies the CRC-32 of a zip entry once its data is read to the
+// end.
+type crcReader struct {
+	r    io.Reader
+	h    hash.Hash32
+	want uint32
+	name string
+}
+
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	if errors.Is(err, io.EOF) && c.h.Sum32() != c.want {
+		return n, fmt.Errorf("zip: %s: checksum mismatch", c.name)
+	}
+	return n, err
+}
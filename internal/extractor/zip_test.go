@@ -0,0 +1,124 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipFile builds a zip archive the way Crafty Controller writes backups:
+// deflated entries with their sizes in the local headers. Each file's
+// content is its own name.
+func zipFile(t *testing.T, names ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		var data bytes.Buffer
+		fw, _ := flate.NewWriter(&data, flate.DefaultCompression)
+		fw.Write([]byte(name))
+		fw.Close()
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE([]byte(name)),
+			CompressedSize64:   uint64(data.Len()),
+			UncompressedSize64: uint64(len(name)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data.Bytes())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractZip(t *testing.T) {
+	buf := zipFile(t,
+		"world/level.dat",
+		"world/region/r.0.0.mca",
+		"world_nether/DIM-1/region/r.0.0.mca",
+		"logs/latest.log",
+		"world/../escape.txt",
+	)
+	out := t.TempDir()
+	if err := extractWorlds(context.Background(), buf, out, []string{"world"}, DownloadOptions{}, 1); err != nil {
+		t.Fatalf("extractWorlds: %v", err)
+	}
+	for _, name := range []string{"world/level.dat", "world/region/r.0.0.mca"} {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(data) != name {
+			t.Errorf("%s = %q, %v; want its name", name, data, err)
+		}
+	}
+	for _, name := range []string{"world_nether", "logs", "escape.txt"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err == nil {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+
+	inv, err := InspectArchive(context.Background(), zipFile(t, "world/level.dat", "world/region/r.0.0.mca", "server.properties"))
+	if err != nil {
+		t.Fatalf("InspectArchive: %v", err)
+	}
+	if inv.Files != 3 || len(inv.Worlds) != 1 || inv.Worlds[0].Path != "world" {
+		t.Errorf("InspectArchive = %d files, worlds %+v", inv.Files, inv.Worlds)
+	}
+}
+
+func TestExtractZipErrors(t *testing.T) {
+	// archive/zip's Create streams entries with a data descriptor.
+	var streamed bytes.Buffer
+	zw := zip.NewWriter(&streamed)
+	w, _ := zw.Create("world/level.dat")
+	w.Write([]byte("level"))
+	zw.Close()
+	err := extractWorlds(context.Background(), &streamed, t.TempDir(), []string{"world"}, DownloadOptions{}, 1)
+	if err == nil || !strings.Contains(err.Error(), "data descriptor") {
+		t.Errorf("data descriptor: err = %v", err)
+	}
+
+	corrupt := zipFile(t, "world/level.dat").Bytes()
+	i := bytes.Index(corrupt, []byte("world/level.dat")) + len("world/level.dat")
+	corrupt[i] ^= 0xff // first byte of the deflated data
+	err = extractWorlds(context.Background(), bytes.NewReader(corrupt), t.TempDir(), []string{"world"}, DownloadOptions{}, 1)
+	if err == nil {
+		t.Error("a corrupt entry was extracted")
+	}
+
+	// An entry inflating past the size in its header, or ending short of
+	// it, fails instead of being counted at the wrong size or truncated,
+	// whether written inline or by the writer pool.
+	data := bytes.Repeat([]byte("region"), 1000)
+	for _, declared := range []int{10, len(data) + 10} {
+		var buf, deflated bytes.Buffer
+		fw, _ := flate.NewWriter(&deflated, flate.DefaultCompression)
+		fw.Write(data)
+		fw.Close()
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.CreateRaw(&zip.FileHeader{
+			Name:               "world/region/r.0.0.mca",
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE(data),
+			CompressedSize64:   uint64(deflated.Len()),
+			UncompressedSize64: uint64(declared),
+		})
+		w.Write(deflated.Bytes())
+		zw.Close()
+		for _, writers := range []int{1, 4} {
+			err := extractWorlds(context.Background(), bytes.NewReader(buf.Bytes()), t.TempDir(), []string{"world"}, DownloadOptions{}, writers)
+			if err == nil || !strings.Contains(err.Error(), "declared size") {
+				t.Errorf("declared %d of %d bytes, %d writers: err = %v", declared, len(data), writers, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Makes the freshness banner in index.html dismissible. A dismissal is
+// remembered per render, so the banner returns once the map is rendered
+// again. Loaded as a separate file so it works under the default
+// Content-Security-Policy.
+(function () {
+  "use strict";
+
+  var banner = document.getElementById("bluemap-action-freshness");
+  if (!banner) {
+    return;
+  }
+  var key = "bluemap-action-freshness";
+  var rendered = banner.getAttribute("data-rendered");
+
+  try {
+    if (localStorage.getItem(key) === rendered) {
+      banner.remove();
+      return;
+    }
+  } catch (err) {
+    // Storage may be disabled; the banner then shows on every visit.
+  }
+
+  banner.querySelector("button").addEventListener("click", function () {
+    banner.remove();
+    try {
+      localStorage.setItem(key, rendered);
+    } catch (err) {
+      // Nothing to remember the dismissal in.
+    }
+  });
+})();
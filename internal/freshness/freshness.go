@@ -0,0 +1,76 @@
+package freshness
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/EfinaServer/bluemap-action/internal/webpage"
+)
+
+//go:embed files/freshness.js
+var script []byte
+
+// Options are the dates shown in the banner, formatted for display. An
+// empty Backup leaves the backup date out.
+type Options struct {
+	Backup   string // when the backup was taken
+	Rendered string // when the map was rendered
+}
+
+// bannerID is the id of the banner element; a banner with it is replaced
+// when the page is patched again.
+const bannerID = "bluemap-action-freshness"
+
+const scriptTag = `<script src="./freshness.js" defer></script>`
+
+var bannerRe = regexp.MustCompile(`(?s)\s*<div id="` + bannerID + `".*?</div>\s*` + regexp.QuoteMeta(scriptTag))
+
+// Apply adds a small banner to web/index.html under serverDir saying how
+// old the map data is, and writes web/freshness.js, which lets visitors
+// dismiss it until the map is rendered again. Re-applying replaces the
+// previous banner.
+func Apply(serverDir string, opts Options) error {
+	webDir := filepath.Join(serverDir, "web")
+	if err := os.WriteFile(filepath.Join(webDir, "freshness.js"), script, 0o644); err != nil {
+		return fmt.Errorf("writing freshness.js: %w", err)
+	}
+
+	indexPath := filepath.Join(webDir, "index.html")
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", indexPath, err)
+	}
+	page := bannerRe.ReplaceAllLiteralString(string(data), "")
+	page, ok := webpage.Insert(page, "</body>", banner(opts), scriptTag)
+	if !ok {
+		return fmt.Errorf("%s has no </body>", indexPath)
+	}
+	if err := os.WriteFile(indexPath, []byte(page), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", indexPath, err)
+	}
+	return nil
+}
+
+// Text returns the banner text.
+func Text(opts Options) string {
+	if opts.Backup == "" {
+		return "Map rendered " + opts.Rendered
+	}
+	return "Map data from backup taken " + opts.Backup + ", rendered " + opts.Rendered
+}
+
+// banner returns the banner element. Its inline styles keep it independent
+// of the webapp's stylesheet; the render date keys the dismissal.
+func banner(opts Options) string {
+	return `<div id="` + bannerID + `" data-rendered="` + html.EscapeString(opts.Rendered) + `" ` +
+		`style="position:fixed;left:50%;bottom:12px;transform:translateX(-50%);z-index:10000;` +
+		`max-width:calc(100% - 24px);padding:6px 8px 6px 12px;border-radius:4px;background:rgba(24,24,24,.85);color:#fff;` +
+		`font:13px/1.4 sans-serif;display:flex;gap:8px;align-items:center">` +
+		`<span>` + html.EscapeString(Text(opts)) + `</span>` +
+		`<button type="button" aria-label="Dismiss" style="border:0;background:none;color:inherit;font:inherit;font-size:16px;cursor:pointer;padding:0 4px">×</button>` +
+		`</div>`
+}
@@ -0,0 +1,70 @@
+package freshness
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bluemapIndex = `<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title>BlueMap</title>
+    </head>
+    <body>
+        <div id="app"></div>
+    </body>
+</html>
+`
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "web"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	index := filepath.Join(dir, "web", "index.html")
+	if err := os.WriteFile(index, []byte(bluemapIndex), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Applying again replaces the banner of the earlier run.
+	if err := Apply(dir, Options{Rendered: "2026-10-01 10:00"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	opts := Options{Backup: "2026-10-02 03:00 <UTC>", Rendered: "2026-10-02 04:15"}
+	if err := Apply(dir, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	data, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	if n := strings.Count(page, `id="`+bannerID+`"`) + strings.Count(page, scriptTag); n != 2 {
+		t.Fatalf("page has %d banners and script tags, want one of each:\n%s", n, page)
+	}
+	for _, want := range []string{
+		`<span>Map data from backup taken 2026-10-02 03:00 &lt;UTC&gt;, rendered 2026-10-02 04:15</span>`,
+		`data-rendered="2026-10-02 04:15"`,
+		"</div>\n        <div id=\"" + bannerID + "\"",
+		"</div>\n        " + scriptTag + "\n    </body>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web", "freshness.js")); err != nil {
+		t.Errorf("freshness.js not written: %v", err)
+	}
+	if strings.Contains(page, "2026-10-01 10:00") {
+		t.Errorf("page still holds the earlier banner:\n%s", page)
+	}
+}
+
+func TestText(t *testing.T) {
+	if got := Text(Options{Rendered: "today"}); got != "Map rendered today" {
+		t.Errorf("Text without backup = %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the GitHub REST API base URL.
+const DefaultAPIURL = "https://api.github.com"
+
+// App holds the credentials of a GitHub App used to mint short-lived
+// installation tokens, which replace broad personal access tokens for
+// features that publish to other repositories.
+type App struct {
+	AppID          string
+	InstallationID string
+	PrivateKey     *rsa.PrivateKey
+	APIURL         string
+	HTTP           *http.Client
+}
+
+// InstallationToken is a token scoped to a single App installation.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FromEnv builds an App from GITHUB_APP_ID, GITHUB_APP_PRIVATE_KEY (PEM
+// contents or a path to a PEM file) and the optional
+// GITHUB_APP_INSTALLATION_ID. It returns (nil, nil) when GITHUB_APP_ID is not
+// set, so callers can treat the App as an optional feature.
+func FromEnv() (*App, error) {
+	appID := os.Getenv("GITHUB_APP_ID")
+	if appID == "" {
+		return nil, nil
+	}
+
+	keyValue := os.Getenv("GITHUB_APP_PRIVATE_KEY")
+	if keyValue == "" {
+		return nil, fmt.Errorf("GITHUB_APP_ID is set but GITHUB_APP_PRIVATE_KEY is empty")
+	}
+	pemData := []byte(keyValue)
+	if !strings.Contains(keyValue, "-----BEGIN") {
+		data, err := os.ReadFile(keyValue)
+		if err != nil {
+			return nil, fmt.Errorf("reading GITHUB_APP_PRIVATE_KEY file: %w", err)
+		}
+		pemData = data
+	}
+
+	key, err := ParsePrivateKey(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+
+	return &App{
+		AppID:          appID,
+		InstallationID: os.Getenv("GITHUB_APP_INSTALLATION_ID"),
+		PrivateKey:     key,
+		APIURL:         strings.TrimRight(apiURL, "/"),
+		HTTP:           &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// ParsePrivateKey decodes a PEM-encoded RSA private key in PKCS#1 (the format
+// GitHub generates) or PKCS#8 form.
+func ParsePrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in GitHub App private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns a signed RS256 JSON Web Token identifying the App. The token is
+// backdated by one minute to tolerate clock drift and expires after nine
+// minutes (GitHub rejects lifetimes over ten).
+func (a *App) JWT(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT claims: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+
+	signingInput := header + "." + payload
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(nil, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// InstallationToken mints an installation access token. When InstallationID
+// is empty, the installation is looked up for the given "owner/repo".
+func (a *App) InstallationToken(ctx context.Context, repo string) (*InstallationToken, error) {
+	jwt, err := a.JWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	installationID := a.InstallationID
+	if installationID == "" {
+		if repo == "" {
+			return nil, fmt.Errorf("GITHUB_APP_INSTALLATION_ID is not set and no repository is known to look it up")
+		}
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		if err := a.do(ctx, http.MethodGet, "/repos/"+repo+"/installation", jwt, &inst); err != nil {
+			return nil, fmt.Errorf("looking up installation for %s: %w", repo, err)
+		}
+		installationID = fmt.Sprintf("%d", inst.ID)
+	}
+
+	var tok InstallationToken
+	if err := a.do(ctx, http.MethodPost, "/app/installations/"+installationID+"/access_tokens", jwt, &tok); err != nil {
+		return nil, fmt.Errorf("creating installation token: %w", err)
+	}
+	if tok.Token == "" {
+		return nil, fmt.Errorf("empty installation token returned for installation %s", installationID)
+	}
+	return &tok, nil
+}
+
+func (a *App) do(ctx context.Context, method, path, jwt string, out any) error {
+	url := a.APIURL + path
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := a.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
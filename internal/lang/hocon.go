@@ -0,0 +1,326 @@
+package lang
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hoconNode is a value parsed from a language file, located by its span in
+// the source so merged files keep the formatting and comments of both.
+// Only the HOCON subset BlueMap's language files use is understood:
+// objects, arrays, quoted, triple-quoted and unquoted strings, and comments.
+type hoconNode struct {
+	start, end int           // span of the value in the source
+	members    []hoconMember // object members in source order; nil for other values
+	object     bool
+	close      int // offset of the object's closing brace (or the end of the source)
+}
+
+// hoconMember is an object member. A dotted key such as info.content has
+// one path element per segment.
+type hoconMember struct {
+	path  []string
+	value *hoconNode
+}
+
+type hoconParser struct {
+	src string
+	pos int
+}
+
+// parseHOCON parses a language file. The root object's braces are optional.
+func parseHOCON(src string) (*hoconNode, error) {
+	p := &hoconParser{src: src}
+	p.skip()
+	var root *hoconNode
+	var err error
+	if p.peek() == '{' {
+		root, err = p.object()
+	} else {
+		root, err = p.members(p.pos, -1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q after the root object", p.src[p.pos])
+	}
+	return root, nil
+}
+
+func (p *hoconParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *hoconParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// skip skips whitespace, commas and comments.
+func (p *hoconParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',':
+			p.pos++
+		case c == '#' || strings.HasPrefix(p.src[p.pos:], "//"):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object parses a braced object starting at p.pos.
+func (p *hoconParser) object() (*hoconNode, error) {
+	start := p.pos
+	p.pos++ // {
+	return p.members(start, '}')
+}
+
+// members parses object members until the closing byte (or the end of the
+// source when closing is -1).
+func (p *hoconParser) members(start int, closing int) (*hoconNode, error) {
+	n := &hoconNode{start: start, object: true}
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			if closing != -1 {
+				return nil, p.errorf("unterminated object")
+			}
+			n.close, n.end = p.pos, p.pos
+			return n, nil
+		}
+		if int(p.peek()) == closing {
+			n.close = p.pos
+			p.pos++
+			n.end = p.pos
+			return n, nil
+		}
+		path, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		for p.pos < len(p.src) && (p.peek() == ' ' || p.peek() == '\t') {
+			p.pos++
+		}
+		if c := p.peek(); c == ':' || c == '=' {
+			p.pos++
+		} else if c != '{' {
+			return nil, p.errorf("expected ':' after key %q", strings.Join(path, "."))
+		}
+		p.skip()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.members = append(n.members, hoconMember{path: path, value: v})
+	}
+}
+
+// key parses a quoted or unquoted, possibly dotted, key.
+func (p *hoconParser) key() ([]string, error) {
+	if p.peek() == '"' {
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n:={}[],\"", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a key, got %q", p.peek())
+	}
+	return strings.Split(p.src[start:p.pos], "."), nil
+}
+
+// quoted parses a JSON-style quoted string and returns its content.
+func (p *hoconParser) quoted() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			return p.src[start+1 : p.pos-1], nil
+		case '\n':
+			return "", p.errorf("unterminated string")
+		default:
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// value parses any value starting at p.pos.
+func (p *hoconParser) value() (*hoconNode, error) {
+	start := p.pos
+	switch {
+	case p.peek() == '{':
+		return p.object()
+	case p.peek() == '[':
+		p.pos++
+		for {
+			p.skip()
+			if p.pos >= len(p.src) {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.peek() == ']' {
+				p.pos++
+				return &hoconNode{start: start, end: p.pos}, nil
+			}
+			if _, err := p.value(); err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		i := strings.Index(p.src[p.pos+3:], `"""`)
+		if i < 0 {
+			return nil, p.errorf("unterminated triple-quoted string")
+		}
+		p.pos += 3 + i + 3
+		// Quotes directly before the closing ones belong to the string.
+		for p.peek() == '"' {
+			p.pos++
+		}
+	case p.peek() == '"':
+		if _, err := p.quoted(); err != nil {
+			return nil, err
+		}
+	default:
+		for p.pos < len(p.src) && !strings.ContainsRune("\r\n,}]#", rune(p.src[p.pos])) &&
+			!strings.HasPrefix(p.src[p.pos:], "//") {
+			p.pos++
+		}
+		if strings.TrimSpace(p.src[start:p.pos]) == "" {
+			return nil, p.errorf("expected a value")
+		}
+	}
+	end := p.pos
+	for end > start && (p.src[end-1] == ' ' || p.src[end-1] == '\t') {
+		end--
+	}
+	return &hoconNode{start: start, end: end}, nil
+}
+
+// member returns the member of an object at key, or nil.
+func (n *hoconNode) member(key string) *hoconNode {
+	var found *hoconNode
+	for _, m := range n.members {
+		if len(m.path) == 1 && m.path[0] == key {
+			found = m.value // later duplicates win, as in HOCON
+		}
+	}
+	return found
+}
+
+// textEdit replaces src[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// mergeHOCON overlays the language file override on base: values of keys
+// present in both are replaced, objects are merged key by key, and keys only
+// in override are added to the enclosing object. Everything else in base,
+// including its formatting and comments, is kept.
+func mergeHOCON(base, override string) (string, error) {
+	b, err := parseHOCON(base)
+	if err != nil {
+		return "", err
+	}
+	o, err := parseHOCON(override)
+	if err != nil {
+		return "", err
+	}
+
+	var edits []textEdit
+	var merge func(bn, on *hoconNode)
+	merge = func(bn, on *hoconNode) {
+		for _, m := range on.members {
+			target, path := bn, m.path
+			// Walk the dotted path as far as base has objects for it.
+			for len(path) > 1 {
+				next := target.member(path[0])
+				if next == nil || !next.object {
+					break
+				}
+				target, path = next, path[1:]
+			}
+			raw := override[m.value.start:m.value.end]
+			existing := target.member(path[0])
+			switch {
+			case len(path) == 1 && existing != nil && existing.object && m.value.object:
+				merge(existing, m.value)
+			case len(path) == 1 && existing != nil:
+				edits = append(edits, textEdit{existing.start, existing.end, raw})
+			default:
+				indent := strings.Repeat("  ", depthOf(b, target)+1)
+				// Spell out missing parents as nested objects rather than a
+				// dotted key, which not every HOCON reader accepts.
+				text := indent + strings.Join(path, ": { ") + ": " + raw + strings.Repeat(" }", len(path)-1) + "\n"
+				// Insert on a line of its own before the closing brace.
+				at := target.close
+				if i := strings.LastIndexByte(base[:at], '\n'); i >= 0 && strings.TrimSpace(base[i:at]) == "" {
+					at = i + 1
+				} else {
+					text = "\n" + text
+				}
+				edits = append(edits, textEdit{at, at, text})
+			}
+		}
+	}
+	merge(b, o)
+
+	// Apply from the end so earlier offsets stay valid; insertions at the
+	// same offset keep their order.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := base
+	for i := 0; i < len(edits); {
+		j := i
+		for j < len(edits) && edits[j].start == edits[i].start && edits[j].start == edits[j].end {
+			j++
+		}
+		if j > i {
+			// A run of insertions at one offset, in override order.
+			var sb strings.Builder
+			for _, e := range edits[i:j] {
+				sb.WriteString(e.text)
+			}
+			out = out[:edits[i].start] + sb.String() + out[edits[i].start:]
+			i = j
+			continue
+		}
+		e := edits[i]
+		out = out[:e.start] + e.text + out[e.end:]
+		i++
+	}
+	return out, nil
+}
+
+// depthOf returns how deeply target is nested below root (0 for root).
+func depthOf(root, target *hoconNode) int {
+	if root == target {
+		return 0
+	}
+	for _, m := range root.members {
+		if m.value.object {
+			if d := depthOf(m.value, target); d >= 0 {
+				return d + len(m.path)
+			}
+		}
+	}
+	return -1
+}
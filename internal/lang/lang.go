@@ -6,33 +6,213 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 )
 
 //go:embed files/*.conf
 var langFiles embed.FS
 
+// OverrideDirName is the folder in a server directory whose .conf files
+// override or extend the embedded language files.
+const OverrideDirName = "lang"
+
 // DeployConfig holds the values to substitute into language file placeholders.
 type DeployConfig struct {
 	ToolVersion      string
 	MinecraftVersion string
 	ProjectName      string
 	RenderTime       string
+	BackupName       string
+	BackupDate       string
+	WorldSize        string
+	MapURL           string
+
+	// Placeholders are further values from the [placeholders] table of
+	// config.toml. They cannot replace the built-in ones.
+	Placeholders map[string]string
+
+	// FormatTime, when set, formats RenderedAt and BackupCreated for the
+	// locale of each language file (its name without .conf) in place of
+	// RenderTime and BackupDate, so every language reads its own date style.
+	// A zero time keeps the string.
+	FormatTime    func(locale string, t time.Time) string
+	RenderedAt    time.Time
+	BackupCreated time.Time
+
+	// OverrideDir holds per-server .conf files (see OverrideDirName). A file
+	// named like an embedded one is merged into it key by key; any other
+	// file, such as a new locale, is deployed as is. Empty or missing means
+	// no overrides.
+	OverrideDir string
+}
+
+// Builtin lists the placeholder names filled from DeployConfig, and Reserved
+// the ones BlueMap's webapp substitutes itself, which must be left alone.
+var (
+	Builtin  = []string{"toolVersion", "minecraftVersion", "mcVersion", "projectName", "renderTime", "backupName", "backupDate", "worldSize", "mapURL"}
+	Reserved = []string{"map", "version"}
+)
+
+// TimeFormats are the Go layouts {renderTime} and {backupDate} use in the
+// embedded language files when no time_format is configured. Files not
+// listed use the ISO-style default.
+var TimeFormats = map[string]string{
+	"zh-TW": "2006年1月2日 15:04 MST",
+	"zh-CN": "2006年1月2日 15:04 MST",
+	"zh-HK": "2006年1月2日 15:04 MST",
+}
+
+// values returns the placeholder values of cfg by name.
+func (cfg DeployConfig) values() map[string]string {
+	v := make(map[string]string, len(cfg.Placeholders)+len(Builtin))
+	for k, val := range cfg.Placeholders {
+		v[k] = val
+	}
+	for k, val := range map[string]string{
+		"toolVersion":      cfg.ToolVersion,
+		"minecraftVersion": cfg.MinecraftVersion,
+		"mcVersion":        cfg.MinecraftVersion,
+		"projectName":      cfg.ProjectName,
+		"renderTime":       cfg.RenderTime,
+		"backupName":       cfg.BackupName,
+		"backupDate":       cfg.BackupDate,
+		"worldSize":        cfg.WorldSize,
+		"mapURL":           cfg.MapURL,
+	} {
+		v[k] = val
+	}
+	return v
+}
+
+// localValues returns values with the times formatted for locale, or values
+// itself without cfg.FormatTime.
+func (cfg DeployConfig) localValues(values map[string]string, locale string) map[string]string {
+	if cfg.FormatTime == nil {
+		return values
+	}
+	local := make(map[string]string, len(values))
+	for k, v := range values {
+		local[k] = v
+	}
+	if !cfg.RenderedAt.IsZero() {
+		local["renderTime"] = cfg.FormatTime(locale, cfg.RenderedAt)
+	}
+	if !cfg.BackupCreated.IsZero() {
+		local["backupDate"] = cfg.FormatTime(locale, cfg.BackupCreated)
+	}
+	return local
+}
+
+// render executes content as a text/template over values. A {name}
+// placeholder for a known value is shorthand for {{.name}}; other braces,
+// such as BlueMap's own {map} and {version} or HOCON objects, are left as
+// they are. Full template actions work too, e.g.
+// {{if .backupDate}}Backup of {{.backupDate}}{{end}}.
+//
+// Inside a quoted HOCON string the shorthand is {{hocon .name}}, which
+// escapes quotes, backslashes and line breaks so a value cannot end the
+// string; full actions there can use the hocon function too. Triple-quoted
+// and unquoted values take the value as is.
+func render(name, content string, values map[string]string) (string, error) {
+	spans := quotedSpans(content)
+	var sb strings.Builder
+	last := 0
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(content, -1) {
+		key := content[m[2]:m[3]]
+		if _, ok := values[key]; !ok {
+			continue
+		}
+		action := "{{." + key + "}}"
+		if inSpans(spans, m[0]) {
+			action = "{{hocon ." + key + "}}"
+		}
+		sb.WriteString(content[last:m[0]] + action)
+		last = m[1]
+	}
+	sb.WriteString(content[last:])
+
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"hocon": hoconEscaper.Replace}).Option("missingkey=zero").Parse(sb.String())
+	if err != nil {
+		return "", err
+	}
+	sb.Reset()
+	if err := tmpl.Execute(&sb, values); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// placeholderRe matches a {name} placeholder.
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z][A-Za-z0-9_]*)\}`)
+
+// hoconEscaper escapes a value for a quoted HOCON string.
+var hoconEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// quotedSpans returns the [start, end) offsets of the contents of the
+// quoted (not triple-quoted) strings in a HOCON source, skipping comments.
+func quotedSpans(src string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return spans
+			}
+			i += 3 + end + 3
+		case src[i] == '"':
+			start := i + 1
+			for i = start; i < len(src) && src[i] != '"' && src[i] != '\n'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			spans = append(spans, [2]int{start, min(i, len(src))})
+			i++
+		case src[i] == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return spans
+}
+
+// inSpans reports whether offset lies in one of spans.
+func inSpans(spans [][2]int, offset int) bool {
+	for _, s := range spans {
+		if offset >= s[0] && offset < s[1] {
+			return true
+		}
+	}
+	return false
 }
 
-// Deploy copies all embedded language files into targetDir, replacing
-// placeholders {toolVersion}, {minecraftVersion}, {projectName}, and
-// {renderTime} with the corresponding values from cfg.
+// Deploy copies all embedded language files into targetDir, merged with the
+// files in cfg.OverrideDir, and fills in their placeholders (see render and
+// Builtin) from cfg.
 func Deploy(targetDir string, cfg DeployConfig) error {
 	entries, err := fs.ReadDir(langFiles, "files")
 	if err != nil {
 		return fmt.Errorf("reading embedded lang files: %w", err)
 	}
 
+	overrides, err := readOverrides(cfg.OverrideDir)
+	if err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(targetDir, 0o755); err != nil {
 		return fmt.Errorf("creating lang directory %s: %w", targetDir, err)
 	}
 
+	files := make(map[string]string)
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -42,14 +222,37 @@ func Deploy(targetDir string, cfg DeployConfig) error {
 		if err != nil {
 			return fmt.Errorf("reading embedded %s: %w", entry.Name(), err)
 		}
+		files[entry.Name()] = string(data)
+	}
 
-		content := string(data)
-		content = strings.ReplaceAll(content, "{toolVersion}", cfg.ToolVersion)
-		content = strings.ReplaceAll(content, "{minecraftVersion}", cfg.MinecraftVersion)
-		content = strings.ReplaceAll(content, "{projectName}", cfg.ProjectName)
-		content = strings.ReplaceAll(content, "{renderTime}", cfg.RenderTime)
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		base, ok := files[name]
+		if !ok {
+			files[name] = overrides[name]
+			fmt.Printf("  ✔  %s added from %s/\n", name, OverrideDirName)
+			continue
+		}
+		merged, err := mergeHOCON(base, overrides[name])
+		if err != nil {
+			return fmt.Errorf("merging %s: %w", filepath.Join(cfg.OverrideDir, name), err)
+		}
+		files[name] = merged
+		fmt.Printf("  ✔  %s merged with %s/%s\n", name, OverrideDirName, name)
+	}
+
+	values := cfg.values()
+	for name, content := range files {
+		content, err := render(name, content, cfg.localValues(values, strings.TrimSuffix(name, ".conf")))
+		if err != nil {
+			return fmt.Errorf("filling in placeholders of %s: %w", name, err)
+		}
 
-		targetPath := filepath.Join(targetDir, entry.Name())
+		targetPath := filepath.Join(targetDir, name)
 		if err := os.WriteFile(targetPath, []byte(content), 0o644); err != nil {
 			return fmt.Errorf("writing %s: %w", targetPath, err)
 		}
@@ -57,3 +260,30 @@ func Deploy(targetDir string, cfg DeployConfig) error {
 
 	return nil
 }
+
+// readOverrides returns the .conf files in dir by name. A missing dir has
+// none.
+func readOverrides(dir string) (map[string]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lang overrides: %w", err)
+	}
+	overrides := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".conf" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading lang override: %w", err)
+		}
+		overrides[e.Name()] = string(data)
+	}
+	return overrides, nil
+}
@@ -0,0 +1,157 @@
+package lang
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeHOCON(t *testing.T) {
+	base := `{
+  // page title
+  pageTitle: "BlueMap - {map}"
+  menu: {
+    title: "Menu"
+    tooltip: "Menu"
+  }
+  info: {
+    content: """
+<p>Rendered {renderTime}</p>
+"""
+  }
+}
+`
+	override := `
+# only what changes
+menu { title: "Map menu" }
+info.content: """<p>{projectName}: {renderTime}</p>"""
+info.footer: "hi"
+screenshot: { title: "Shot" }
+`
+	got, err := mergeHOCON(base, override)
+	if err != nil {
+		t.Fatalf("mergeHOCON: %v", err)
+	}
+	want := `{
+  // page title
+  pageTitle: "BlueMap - {map}"
+  menu: {
+    title: "Map menu"
+    tooltip: "Menu"
+  }
+  info: {
+    content: """<p>{projectName}: {renderTime}</p>"""
+    footer: "hi"
+  }
+  screenshot: { title: "Shot" }
+}
+`
+	if got != want {
+		t.Errorf("merged:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := mergeHOCON(base, `menu: { title: "unterminated }`); err == nil {
+		t.Error("mergeHOCON accepted an unterminated string")
+	}
+
+	// Every embedded file must be mergeable.
+	entries, err := langFiles.ReadDir("files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, _ := langFiles.ReadFile("files/" + e.Name())
+		if _, err := parseHOCON(string(data)); err != nil {
+			t.Errorf("parsing embedded %s: %v", e.Name(), err)
+		}
+	}
+}
+
+func TestDeployOverrides(t *testing.T) {
+	dir := t.TempDir()
+	overrides := filepath.Join(dir, OverrideDirName)
+	if err := os.MkdirAll(overrides, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"en.conf": "info: { title: \"About {projectName}\" }\n",
+		"ja.conf": "{ menu: { title: \"メニュー\" } }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(overrides, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := filepath.Join(dir, "web", "lang")
+	if err := Deploy(target, DeployConfig{ProjectName: "Survival", OverrideDir: overrides}); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	en, err := os.ReadFile(filepath.Join(target, "en.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(en), `title: "About Survival"`) || !strings.Contains(string(en), "Mouse-Controls") {
+		t.Errorf("en.conf was not merged with the override:\n%s", en)
+	}
+	if _, err := os.Stat(filepath.Join(target, "ja.conf")); err != nil {
+		t.Errorf("new locale not deployed: %v", err)
+	}
+}
+
+func TestDeployLocaleTimes(t *testing.T) {
+	target := t.TempDir()
+	cfg := DeployConfig{
+		RenderTime: "unused",
+		RenderedAt: time.Date(2026, 3, 14, 16, 30, 0, 0, time.UTC),
+		FormatTime: func(locale string, at time.Time) string {
+			return locale + " " + at.Format("2006-01-02")
+		},
+	}
+	if err := Deploy(target, cfg); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	for name, want := range map[string]string{
+		"en.conf":    "Rendered en 2026-03-14",
+		"zh-TW.conf": "繪製於 zh-TW 2026-03-14",
+	} {
+		data, err := os.ReadFile(filepath.Join(target, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %q", name, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	values := DeployConfig{
+		MinecraftVersion: "1.21.4",
+		BackupDate:       "2026-10-16 04:00 CST",
+		Placeholders:     map[string]string{"discord": "https://discord.gg/example", "motto": `say "hi" \o/`},
+	}.values()
+	for in, want := range map[string]string{
+		`title: "Map of {mcVersion}"`:                          `title: "Map of 1.21.4"`,
+		`content: "Join {discord}"`:                            `content: "Join https://discord.gg/example"`,
+		`{{if .backupDate}}from {backupDate}{{end}}`:           `from 2026-10-16 04:00 CST`,
+		`{{if .mapURL}}at {{.mapURL}}{{else}}offline{{end}}`:   `offline`,
+		`searchMarkers: "Search {map}", version: "v{version}"`: `searchMarkers: "Search {map}", version: "v{version}"`,
+		`menu: { title: "{unknown}" }`:                         `menu: { title: "{unknown}" }`,
+		`title: "{motto}" # {motto}`:                           `title: "say \"hi\" \\o/" # say "hi" \o/`,
+		`content: """<b>{motto}</b>"""`:                        `content: """<b>say "hi" \o/</b>"""`,
+		`title: "{{if .motto}}{{hocon .motto}}{{end}}"`:        `title: "say \"hi\" \\o/"`,
+	} {
+		got, err := render("en.conf", in, values)
+		if err != nil {
+			t.Errorf("render(%q): %v", in, err)
+		} else if got != want {
+			t.Errorf("render(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := render("en.conf", "{{if .mapURL}}", values); err == nil {
+		t.Error("render accepted an unterminated action")
+	}
+}
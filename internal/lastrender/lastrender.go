@@ -0,0 +1,136 @@
+package lastrender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/EfinaServer/bluemap-action/internal/lang"
+	"github.com/EfinaServer/bluemap-action/internal/markers"
+)
+
+// FileName is the record file name. Like the file manifest it lives next
+// to config.toml, outside web/, and the workflow caches it between runs.
+const FileName = ".bluemap-last-render.json"
+
+// Record describes the input of the last render that was deployed.
+type Record struct {
+	BackupUUID     string   `json:"backup_uuid"`
+	BackupChecksum string   `json:"backup_checksum,omitempty"`
+	ConfigHash     string   `json:"config_hash"`
+	Maps           []string `json:"maps,omitempty"`
+	BlueMapVersion string   `json:"bluemap_version"`
+	RenderTime     string   `json:"render_time"`
+}
+
+// Path returns the record location for the given server directory.
+func Path(serverDir string) string {
+	return filepath.Join(serverDir, FileName)
+}
+
+// Load reads the record of serverDir. A missing file is not an error and
+// returns nil.
+func Load(serverDir string) (*Record, error) {
+	data, err := os.ReadFile(Path(serverDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FileName, err)
+	}
+	return &r, nil
+}
+
+// Save writes r to path, which is Path of the server directory or a file
+// that is later moved there.
+func (r Record) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Matches reports whether a render of cur would have the same input as the
+// recorded one: the same backup, config and maps. The checksums are only
+// compared when the panel reported both.
+func (r *Record) Matches(cur Record) bool {
+	if r == nil || r.BackupUUID == "" || r.BackupUUID != cur.BackupUUID {
+		return false
+	}
+	if r.BackupChecksum != "" && cur.BackupChecksum != "" && r.BackupChecksum != cur.BackupChecksum {
+		return false
+	}
+	return r.ConfigHash == cur.ConfigHash && slices.Equal(r.Maps, cur.Maps)
+}
+
+// inputDirs are the folders of a server directory whose files shape the
+// render or the published map: the BlueMap configuration, the language
+// overrides and the custom scripts.
+var inputDirs = []string{"config", lang.OverrideDirName, "scripts"}
+
+// ConfigHash returns the SHA-256 of toolVersion, config.toml, markers.toml,
+// every file under config/, lang/ and scripts/ in serverDir, and the files
+// at the server directory relative paths assets (the branding images), so
+// editing any configuration, asset or upgrading the tool invalidates the
+// record. Take it before the render, which writes into config/.
+func ConfigHash(serverDir, toolVersion string, assets []string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", toolVersion)
+	add := func(rel string) error {
+		data, err := os.ReadFile(filepath.Join(serverDir, rel))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(data))
+		h.Write(data)
+		return nil
+	}
+	if err := add("config.toml"); err != nil {
+		return "", err
+	}
+	if err := add(markers.StaticFileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	for _, dir := range inputDirs {
+		root := filepath.Join(serverDir, dir)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return fs.SkipAll
+			}
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(serverDir, p)
+			if err != nil {
+				return err
+			}
+			return add(rel)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, rel := range assets {
+		if err := add(rel); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,105 @@
+package lastrender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if r, err := Load(dir); err != nil || r != nil {
+		t.Fatalf("Load without a record = %v, %v; want nil, nil", r, err)
+	}
+	want := Record{BackupUUID: "b1", BackupChecksum: "sha1:abc", ConfigHash: "h", Maps: []string{"world"}, BlueMapVersion: "5.4", RenderTime: "now"}
+	if err := want.Save(Path(dir)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !got.Matches(want) || got.BlueMapVersion != "5.4" || got.RenderTime != "now" {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	rec := &Record{BackupUUID: "b1", BackupChecksum: "sha1:abc", ConfigHash: "h"}
+	tests := []struct {
+		name string
+		cur  Record
+		want bool
+	}{
+		{"same", Record{BackupUUID: "b1", BackupChecksum: "sha1:abc", ConfigHash: "h"}, true},
+		{"no checksum", Record{BackupUUID: "b1", ConfigHash: "h"}, true},
+		{"new backup", Record{BackupUUID: "b2", BackupChecksum: "sha1:abc", ConfigHash: "h"}, false},
+		{"other checksum", Record{BackupUUID: "b1", BackupChecksum: "sha1:def", ConfigHash: "h"}, false},
+		{"config changed", Record{BackupUUID: "b1", BackupChecksum: "sha1:abc", ConfigHash: "x"}, false},
+		{"maps changed", Record{BackupUUID: "b1", ConfigHash: "h", Maps: []string{"world"}}, false},
+	}
+	for _, tt := range tests {
+		if got := rec.Matches(tt.cur); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	var none *Record
+	if none.Matches(Record{}) {
+		t.Error("nil record matched")
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func() string {
+		t.Helper()
+		h, err := ConfigHash(dir, "v1.0.0", []string{"logo.png"})
+		if err != nil {
+			t.Fatalf("ConfigHash: %v", err)
+		}
+		return h
+	}
+
+	write("config.toml", `server_id = "abc"`)
+	write("logo.png", "logo")
+	base := hash()
+	write("config/maps/world.conf", `world: "world"`)
+	withMap := hash()
+	if withMap == base {
+		t.Error("adding a map config did not change the hash")
+	}
+	write("web/index.html", "changed")
+	if hash() != withMap {
+		t.Error("a file outside config/ changed the hash")
+	}
+	write("config/maps/world.conf", `world: "world_nether"`)
+	edited := hash()
+	if edited == withMap {
+		t.Error("editing a map config did not change the hash")
+	}
+	write("markers.toml", "[sets.poi]\n")
+	withMarkers := hash()
+	if withMarkers == edited {
+		t.Error("adding markers.toml did not change the hash")
+	}
+	for _, rel := range []string{"lang/en.conf", "scripts/post.sh", "logo.png"} {
+		before := hash()
+		write(rel, "changed "+rel)
+		if hash() == before {
+			t.Errorf("editing %s did not change the hash", rel)
+		}
+	}
+	if h, err := ConfigHash(dir, "v1.1.0", []string{"logo.png"}); err != nil || h == hash() {
+		t.Errorf("ConfigHash of another tool version = %s, %v; want a different hash", h, err)
+	}
+}
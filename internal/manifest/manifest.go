@@ -0,0 +1,196 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FileName is the manifest file name. It lives next to config.toml, outside
+// web/, so it is never published; the workflow caches it between runs.
+const FileName = ".bluemap-manifest.json"
+
+// ChangedListName is the file, written next to config.toml, that lists the
+// web/ paths added or changed since the previous run, one per line. Deploy
+// backends that cannot diff on their own can upload just these files.
+const ChangedListName = "bluemap-changed-files.txt"
+
+// Manifest maps every file under the web root (slash-separated, relative
+// path) to its SHA-256 digest.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// Path returns the manifest location for the given server directory.
+func Path(serverDir string) string {
+	return filepath.Join(serverDir, FileName)
+}
+
+// Build hashes every file under root using the given number of workers
+// (0 = number of CPUs).
+func Build(root string, workers int) (*Manifest, error) {
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	m := &Manifest{Files: make(map[string]string, len(paths))}
+	ch := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range ch {
+				sum, err := fileSHA256(path)
+				rel, relErr := filepath.Rel(root, path)
+				mu.Lock()
+				switch {
+				case err != nil && firstErr == nil:
+					firstErr = fmt.Errorf("hashing %s: %w", path, err)
+				case relErr != nil && firstErr == nil:
+					firstErr = relErr
+				case err == nil && relErr == nil:
+					m.Files[filepath.ToSlash(rel)] = sum
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range paths {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return m, nil
+}
+
+// Load reads a manifest. A missing file yields (nil, nil), meaning there is
+// no previous run to compare against.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to path.
+func (m *Manifest) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Diff describes how a manifest changed relative to the previous one.
+type Diff struct {
+	Added     []string
+	Changed   []string
+	Removed   []string
+	Unchanged int
+}
+
+// Upload returns the paths that must be (re)deployed: added and changed
+// files, sorted.
+func (d Diff) Upload() []string {
+	paths := append(append([]string{}, d.Added...), d.Changed...)
+	sort.Strings(paths)
+	return paths
+}
+
+// Compare returns the difference between prev and m. A nil prev treats every
+// file as added.
+func (m *Manifest) Compare(prev *Manifest) Diff {
+	var d Diff
+	for path, sum := range m.Files {
+		old, ok := "", false
+		if prev != nil {
+			old, ok = prev.Files[path]
+		}
+		switch {
+		case !ok:
+			d.Added = append(d.Added, path)
+		case old != sum:
+			d.Changed = append(d.Changed, path)
+		default:
+			d.Unchanged++
+		}
+	}
+	if prev != nil {
+		for path := range prev.Files {
+			if _, ok := m.Files[path]; !ok {
+				d.Removed = append(d.Removed, path)
+			}
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Changed)
+	sort.Strings(d.Removed)
+	return d
+}
+
+// WriteList writes paths to file, one per line.
+func WriteList(file string, paths []string) error {
+	content := strings.Join(paths, "\n")
+	if len(paths) > 0 {
+		content += "\n"
+	}
+	return os.WriteFile(file, []byte(content), 0o644)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "web")
+	writeFile(t, filepath.Join(web, "index.html"), "index")
+	writeFile(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z0.prbm.gz"), "tile-a")
+	writeFile(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z1.prbm.gz"), "tile-b")
+
+	first, err := Build(web, 2)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if d := first.Compare(nil); len(d.Added) != 3 || d.Unchanged != 0 {
+		t.Errorf("first run diff = %+v, want 3 added", d)
+	}
+	if err := first.Save(Path(dir)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Change one tile, drop one, add one.
+	writeFile(t, filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z0.prbm.gz"), "tile-a2")
+	os.Remove(filepath.Join(web, "maps", "world", "tiles", "0", "x0", "z1.prbm.gz"))
+	writeFile(t, filepath.Join(web, "maps", "world", "tiles", "0", "x1", "z0.prbm.gz"), "tile-c")
+
+	prev, err := Load(Path(dir))
+	if err != nil || prev == nil {
+		t.Fatalf("Load: %v, %v", prev, err)
+	}
+	second, err := Build(web, 0)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	d := second.Compare(prev)
+	want := Diff{
+		Added:     []string{"maps/world/tiles/0/x1/z0.prbm.gz"},
+		Changed:   []string{"maps/world/tiles/0/x0/z0.prbm.gz"},
+		Removed:   []string{"maps/world/tiles/0/x0/z1.prbm.gz"},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("diff = %+v, want %+v", d, want)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(filepath.Join(t.TempDir(), FileName))
+	if m != nil || err != nil {
+		t.Errorf("Load(missing) = %v, %v; want nil, nil", m, err)
+	}
+}
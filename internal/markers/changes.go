@@ -0,0 +1,131 @@
+package markers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+)
+
+// changes reads the save time of every chunk from the region file headers
+// and outlines the areas saved after Options.Since, the backup of the
+// previous deploy, so viewers can see what was built or explored since the
+// last map update. Without a previous deploy the set stays empty.
+type changes struct{}
+
+func (changes) ID() string      { return "changes" }
+func (changes) Label() string   { return "Changed Since Last Update" }
+func (changes) Paths() []string { return nil }
+
+var changesColor = Color{R: 0, G: 188, B: 212, A: 1}
+
+func (changes) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	if opts.Since.IsZero() {
+		return nil, nil
+	}
+	files, err := worldRegionFiles(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	changed := make(map[string]map[[2]int]time.Time)
+	err = forEachRegionFile(files, func(rf regionFile) error {
+		chunks, err := mca.ReadChunks(rf.path, false)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range chunks {
+			if !c.Modified.After(opts.Since) {
+				continue
+			}
+			world := changed[rf.world]
+			if world == nil {
+				world = make(map[[2]int]time.Time)
+				changed[rf.world] = world
+			}
+			world[[2]int{c.X, c.Z}] = c.Modified
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	markers := make(map[string][]Marker)
+	for world, chunks := range changed {
+		for i, area := range connectedAreas(chunks) {
+			var last time.Time
+			cells := make(map[[2]int]bool, len(area))
+			for _, c := range area {
+				cells[c] = true
+				if t := chunks[c]; t.After(last) {
+					last = t
+				}
+			}
+			label := fmt.Sprintf("Changed: %d chunks", len(area))
+			if len(area) == 1 {
+				label = "Changed: 1 chunk"
+			}
+			text := detail("Changed since last update",
+				[2]string{"Chunks", strconv.Itoa(len(area))},
+				[2]string{"Last saved", last.Format("2006-01-02 15:04 UTC")},
+			)
+			for j, poly := range outline(cells, 16) {
+				markers[world] = append(markers[world], Marker{
+					ID:     fmt.Sprintf("changes-%d-%d", i, j),
+					Label:  label,
+					Detail: text,
+					Shape:  poly.Shape,
+					Holes:  poly.Holes,
+					Y:      64,
+					Color:  changesColor,
+				})
+			}
+		}
+	}
+	return markers, nil
+}
+
+// connectedAreas groups grid cells into areas of edge-connected cells, each
+// sorted and the areas ordered by their first cell, so marker IDs are stable.
+func connectedAreas[V any](cells map[[2]int]V) [][][2]int {
+	keys := make([][2]int, 0, len(cells))
+	for c := range cells {
+		keys = append(keys, c)
+	}
+	less := func(a, b [2]int) bool {
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[0] < b[0]
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+
+	seen := make(map[[2]int]bool, len(cells))
+	var areas [][][2]int
+	for _, start := range keys {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		area := [][2]int{start}
+		for i := 0; i < len(area); i++ {
+			c := area[i]
+			for _, n := range [][2]int{{c[0] + 1, c[1]}, {c[0] - 1, c[1]}, {c[0], c[1] + 1}, {c[0], c[1] - 1}} {
+				if _, ok := cells[n]; ok && !seen[n] {
+					seen[n] = true
+					area = append(area, n)
+				}
+			}
+		}
+		sort.Slice(area, func(i, j int) bool { return less(area[i], area[j]) })
+		areas = append(areas, area)
+	}
+	return areas
+}
@@ -0,0 +1,104 @@
+package markers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// griefPrevention reads GriefPrevention's file storage:
+// plugins/GriefPreventionData/ClaimData/<id>.yml. Top-level claims are drawn;
+// subdivisions (claims with a parent) are left out. Claims without an owner
+// are administrative claims.
+type griefPrevention struct{}
+
+func (griefPrevention) ID() string      { return "griefprevention" }
+func (griefPrevention) Label() string   { return "Claims" }
+func (griefPrevention) Paths() []string { return []string{"plugins/GriefPreventionData/ClaimData"} }
+
+var (
+	claimColor      = Color{R: 255, G: 215, B: 0, A: 1}
+	adminClaimColor = Color{R: 255, G: 69, B: 0, A: 1}
+)
+
+func (griefPrevention) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	files, err := filepath.Glob(filepath.Join(serverDir, "plugins", "GriefPreventionData", "ClaimData", "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return claimID(files[i]) < claimID(files[j]) })
+
+	areas := make(map[string][]Marker)
+	for _, path := range files {
+		id := strings.TrimSuffix(filepath.Base(path), ".yml")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: %w", id, err)
+		}
+		if parent := str(field(doc, "Parent Claim ID")); parent != "" && parent != "-1" {
+			continue
+		}
+
+		world, x1, y, z1, err := claimCorner(str(field(doc, "Lesser Boundary Corner")))
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: lesser corner: %w", id, err)
+		}
+		_, x2, _, z2, err := claimCorner(str(field(doc, "Greater Boundary Corner")))
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: greater corner: %w", id, err)
+		}
+
+		owner := str(field(doc, "Owner"))
+		label, color := "Admin claim", adminClaimColor
+		if owner != "" {
+			owner = playerNames([]string{owner}, opts.Names)[0]
+			label, color = owner+"'s claim", claimColor
+		}
+		var trusted []string
+		for _, key := range []string{"Builders", "Containers", "Accessors", "Managers"} {
+			list, _ := field(doc, key).([]any)
+			for _, v := range list {
+				trusted = append(trusted, str(v))
+			}
+		}
+
+		areas[world] = append(areas[world], Marker{
+			ID:    "griefprevention-" + id,
+			Label: label,
+			Detail: detail(label,
+				[2]string{"Claim ID", id},
+				[2]string{"Size", fmt.Sprintf("%.0f × %.0f", x2-x1+1, z2-z1+1)},
+				[2]string{"Trusted", strings.Join(playerNames(trusted, opts.Names), ", ")},
+			),
+			Shape: rect(x1, z1, x2, z2),
+			Y:     y,
+			Color: color,
+		})
+	}
+	return areas, nil
+}
+
+// claimCorner parses a "world;x;y;z" corner.
+func claimCorner(s string) (world string, x, y, z float64, err error) {
+	parts := strings.Split(s, ";")
+	if len(parts) != 4 {
+		return "", 0, 0, 0, fmt.Errorf("expected \"world;x;y;z\", got %q", s)
+	}
+	x, err1 := strconv.ParseFloat(parts[1], 64)
+	y, err2 := strconv.ParseFloat(parts[2], 64)
+	z, err3 := strconv.ParseFloat(parts[3], 64)
+	return parts[0], x, y, z, firstErr(err1, err2, err3)
+}
+
+// claimID returns the numeric ID of a claim file for sorting.
+func claimID(path string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".yml"))
+	return n
+}
@@ -0,0 +1,282 @@
+package markers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+)
+
+// HeadsDirName is the folder below the web root that player heads are
+// written to.
+const HeadsDirName = "playerheads"
+
+// headSize is the edge length of a rendered head icon in pixels.
+const headSize = 32
+
+// headTTL is how long a cached head is used before its skin is fetched again.
+const headTTL = 7 * 24 * time.Hour
+
+// headWorkers bounds concurrent requests to Mojang, which rate-limits the
+// session server per IP.
+const headWorkers = 4
+
+// profileURL is Mojang's session server profile endpoint; tests point it at
+// a local server.
+var profileURL = "https://sessionserver.mojang.com/session/minecraft/profile/"
+
+// HeadStats counts how FetchHeads obtained the heads.
+type HeadStats struct {
+	Fetched int // downloaded from Mojang
+	Cached  int // served from the local cache
+	Failed  int // could not be obtained; the marker keeps the default icon
+	Offline int // offline-mode players, who have no Mojang skin
+}
+
+// HeadCacheDir returns the directory player heads are cached in: heads/ in
+// the shared cache of the BlueMap jars (see bluemap.SharedCacheDir), or ""
+// when there is none.
+func HeadCacheDir() string {
+	dir := bluemap.SharedCacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "heads")
+}
+
+// FetchHeads gives player markers their skin's head as the icon. Heads are
+// rendered from the skins on Mojang's servers, cached in cacheDir for a week
+// and copied to web/playerheads/<uuid>.png. When Mojang cannot be reached a
+// stale cached head is used; offline-mode players, whose UUIDs Mojang does
+// not know, keep the default icon without a request.
+func FetchHeads(ctx context.Context, serverDir, cacheDir string, results []MapResult) (HeadStats, error) {
+	var stats HeadStats
+	var uuids []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		for _, s := range r.Sets {
+			for _, m := range s.Markers {
+				if m.Player != "" && !seen[m.Player] {
+					seen[m.Player] = true
+					uuids = append(uuids, m.Player)
+				}
+			}
+		}
+	}
+	if len(uuids) == 0 {
+		return stats, nil
+	}
+
+	webDir := filepath.Join(serverDir, "web", HeadsDirName)
+	if err := os.MkdirAll(webDir, 0o755); err != nil {
+		return stats, err
+	}
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			cacheDir = ""
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		icons = make(map[string]string)
+		wg    sync.WaitGroup
+		jobs  = make(chan string)
+	)
+	client := &http.Client{Timeout: 30 * time.Second}
+	for i := 0; i < headWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uuid := range jobs {
+				head, fromCache, err := playerHead(ctx, client, cacheDir, uuid)
+				if err == nil {
+					err = os.WriteFile(filepath.Join(webDir, uuid+".png"), head, 0o644)
+				}
+				mu.Lock()
+				switch {
+				case errors.Is(err, errOfflinePlayer):
+					stats.Offline++
+				case err != nil:
+					stats.Failed++
+				case fromCache:
+					stats.Cached++
+				default:
+					stats.Fetched++
+				}
+				if err == nil {
+					icons[uuid] = HeadsDirName + "/" + uuid + ".png"
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, uuid := range uuids {
+		jobs <- uuid
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range results {
+		for _, s := range r.Sets {
+			for i, m := range s.Markers {
+				if icon, ok := icons[m.Player]; ok {
+					s.Markers[i].Icon = icon
+				}
+			}
+		}
+	}
+	return stats, nil
+}
+
+// errOfflinePlayer marks UUIDs that are not Mojang accounts.
+var errOfflinePlayer = errors.New("not an online-mode UUID")
+
+// playerHead returns the PNG head of a player from the cache when it is
+// fresh, from Mojang otherwise, and from a stale cache entry as a fallback.
+func playerHead(ctx context.Context, client *http.Client, cacheDir, uuid string) (head []byte, fromCache bool, err error) {
+	// Online-mode UUIDs are version 4; offline-mode servers derive version 3
+	// UUIDs from the player name.
+	if len(uuid) != 36 || uuid[14] != '4' {
+		return nil, false, errOfflinePlayer
+	}
+
+	var cachePath string
+	var cached []byte
+	if cacheDir != "" {
+		cachePath = filepath.Join(cacheDir, uuid+".png")
+		if info, err := os.Stat(cachePath); err == nil {
+			if cached, err = os.ReadFile(cachePath); err == nil && time.Since(info.ModTime()) < headTTL {
+				return cached, true, nil
+			}
+		}
+	}
+
+	head, err = fetchHead(ctx, client, uuid)
+	if err != nil {
+		if cached != nil {
+			return cached, true, nil
+		}
+		return nil, false, err
+	}
+	if cachePath != "" {
+		// A failed cache write only costs a request on the next run.
+		_ = os.WriteFile(cachePath, head, 0o644)
+	}
+	return head, false, nil
+}
+
+// fetchHead looks up the player's skin through the session server and
+// renders its head.
+func fetchHead(ctx context.Context, client *http.Client, uuid string) ([]byte, error) {
+	var profile struct {
+		Properties []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"properties"`
+	}
+	if err := getJSON(ctx, client, profileURL+strings.ReplaceAll(uuid, "-", ""), &profile); err != nil {
+		return nil, fmt.Errorf("fetching profile: %w", err)
+	}
+
+	var skinURL string
+	for _, p := range profile.Properties {
+		if p.Name != "textures" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding textures: %w", err)
+		}
+		var textures struct {
+			Textures struct {
+				Skin struct {
+					URL string `json:"url"`
+				} `json:"SKIN"`
+			} `json:"textures"`
+		}
+		if err := json.Unmarshal(raw, &textures); err != nil {
+			return nil, fmt.Errorf("decoding textures: %w", err)
+		}
+		skinURL = textures.Textures.Skin.URL
+	}
+	if skinURL == "" {
+		return nil, errors.New("profile has no skin")
+	}
+
+	body, err := get(ctx, client, skinURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching skin: %w", err)
+	}
+	defer body.Close()
+	skin, err := png.Decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding skin: %w", err)
+	}
+	return renderHead(skin)
+}
+
+// renderHead crops the face and the hat layer drawn over it from a skin and
+// scales them to headSize × headSize.
+func renderHead(skin image.Image) ([]byte, error) {
+	b := skin.Bounds()
+	if b.Dx() < 64 || b.Dy() < 32 {
+		return nil, fmt.Errorf("skin is %d×%d, want at least 64×32", b.Dx(), b.Dy())
+	}
+	face := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(face, face.Bounds(), skin, b.Min.Add(image.Pt(8, 8)), draw.Src)
+	draw.Draw(face, face.Bounds(), skin, b.Min.Add(image.Pt(40, 8)), draw.Over)
+
+	const scale = headSize / 8
+	head := image.NewNRGBA(image.Rect(0, 0, headSize, headSize))
+	for y := 0; y < headSize; y++ {
+		for x := 0; x < headSize; x++ {
+			head.SetNRGBA(x, y, face.NRGBAAt(x/scale, y/scale))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, head); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+func get(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,158 @@
+package markers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+)
+
+// heatmap reads the InhabitedTime of every chunk in the extracted worlds,
+// the ticks players have spent near it, and covers the areas players spend
+// the most time in with shaded squares: yellow for some activity, red for
+// the most visited places. The set starts hidden so it does not cover the
+// map until it is toggled on.
+type heatmap struct{}
+
+func (heatmap) ID() string          { return "heatmap" }
+func (heatmap) Label() string       { return "Activity Heatmap" }
+func (heatmap) Paths() []string     { return nil }
+func (heatmap) DefaultHidden() bool { return true }
+
+const (
+	// heatmapCellChunks is the side of a heatmap square in chunks. A square
+	// takes the highest InhabitedTime of its chunks.
+	heatmapCellChunks = 4
+	// heatmapMinTicks leaves out squares players spent less than ten minutes
+	// in, which a walk or flight past leaves behind everywhere.
+	heatmapMinTicks = 10 * 60 * 20
+	// heatmapMaxCells caps the squares per world to keep markers.json small;
+	// the most visited ones are kept.
+	heatmapMaxCells = 5000
+)
+
+// Colors of the least and most visited squares.
+var (
+	heatmapCold = Color{R: 255, G: 235, B: 59}
+	heatmapHot  = Color{R: 244, G: 67, B: 54}
+)
+
+// heatCell is a heatmap square in cell coordinates and its InhabitedTime.
+type heatCell struct {
+	x, z  int
+	ticks int64
+}
+
+func (heatmap) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	files, err := worldRegionFiles(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	cells := make(map[string]map[[2]int]int64)
+	err = forEachRegionFile(files, func(rf regionFile) error {
+		chunks, err := mca.ReadChunks(rf.path, true)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		world := cells[rf.world]
+		if world == nil {
+			world = make(map[[2]int]int64)
+			cells[rf.world] = world
+		}
+		for _, c := range chunks {
+			key := [2]int{floorDiv(c.X, heatmapCellChunks), floorDiv(c.Z, heatmapCellChunks)}
+			world[key] = max(world[key], c.Inhabited)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	markers := make(map[string][]Marker)
+	for world, byKey := range cells {
+		var list []heatCell
+		for k, ticks := range byKey {
+			if ticks >= heatmapMinTicks {
+				list = append(list, heatCell{k[0], k[1], ticks})
+			}
+		}
+		if len(list) > 0 {
+			markers[world] = heatmapMarkers(list)
+		}
+	}
+	return markers, nil
+}
+
+// heatmapMarkers turns the squares of a world into shape markers, keeping
+// the heatmapMaxCells most visited. Colors follow a log scale between the
+// least and most visited square kept, since a few spawn or base chunks
+// collect far more time than the rest.
+func heatmapMarkers(cells []heatCell) []Marker {
+	sort.Slice(cells, func(i, j int) bool {
+		a, b := cells[i], cells[j]
+		if a.ticks != b.ticks {
+			return a.ticks > b.ticks
+		}
+		if a.x != b.x {
+			return a.x < b.x
+		}
+		return a.z < b.z
+	})
+	if len(cells) > heatmapMaxCells {
+		cells = cells[:heatmapMaxCells]
+	}
+	lo, hi := float64(cells[len(cells)-1].ticks), float64(cells[0].ticks)
+
+	out := make([]Marker, 0, len(cells))
+	for _, c := range cells {
+		heat := 1.0
+		if hi > lo {
+			heat = math.Log(float64(c.ticks)/lo) / math.Log(hi/lo)
+		}
+		fill := Color{
+			R: heatmapCold.R + int(math.Round(heat*float64(heatmapHot.R-heatmapCold.R))),
+			G: heatmapCold.G + int(math.Round(heat*float64(heatmapHot.G-heatmapCold.G))),
+			B: heatmapCold.B + int(math.Round(heat*float64(heatmapHot.B-heatmapCold.B))),
+			A: math.Round((0.15+0.4*heat)*100) / 100,
+		}
+		size := heatmapCellChunks * 16
+		x, z := c.x*size, c.z*size
+		time := formatTicks(c.ticks)
+		out = append(out, Marker{
+			ID:     fmt.Sprintf("heatmap-%d_%d", c.x, c.z),
+			Label:  "Activity: " + time,
+			Detail: detail("Activity", [2]string{"Inhabited", time}, [2]string{"Area", fmt.Sprintf("%d, %d to %d, %d", x, z, x+size-1, z+size-1)}),
+			Shape:  rect(float64(x), float64(z), float64(x+size-1), float64(z+size-1)),
+			Y:      64,
+			Color:  fill,
+			Fill:   true,
+		})
+	}
+	return out
+}
+
+// formatTicks formats an InhabitedTime as hours and minutes.
+func formatTicks(ticks int64) string {
+	minutes := ticks / (60 * 20)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// floorDiv divides rounding toward negative infinity, so negative chunk
+// coordinates fall into the square on their own side of the origin.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,313 @@
+// Package markers generates BlueMap marker sets from data extracted from the
+// backup, such as WorldGuard regions, Towny towns, GriefPrevention claims,
+// the last known positions and statistics of players, the time players spent
+// in each chunk and the chunks saved since the previous deploy, and from the
+// hand-placed markers of markers.toml.
+package markers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+)
+
+// Output formats accepted for markers.format in config.toml.
+const (
+	FormatJSON  = "json"  // Merge into web/maps/<id>/live/markers.json after the render (default).
+	FormatHOCON = "hocon" // Write marker-sets blocks to bluemap-markers/<id>.conf for the map configs.
+)
+
+// HOCONDirName is the folder, next to config.toml, that HOCON marker sets are
+// written to. It is outside config/maps, so BlueMap does not load the files
+// as maps.
+const HOCONDirName = "bluemap-markers"
+
+// userCachePath is the server's name cache, extracted alongside plugin data
+// to show player names instead of UUIDs.
+const userCachePath = "usercache.json"
+
+// Source reads one plugin's data and turns it into markers per world.
+type Source interface {
+	// ID is the name used in config.toml and the marker set ID.
+	ID() string
+	// Label is the marker set label shown in the web app.
+	Label() string
+	// Paths lists the backup paths (relative to the server root) the source
+	// reads; they are extracted together with the worlds.
+	Paths() []string
+	// Load reads the extracted data below serverDir and returns the markers
+	// keyed by Bukkit world name.
+	Load(serverDir string, opts Options) (map[string][]Marker, error)
+}
+
+// Options holds settings shared by the sources.
+type Options struct {
+	// Names maps lower-case player UUIDs to names; Generate fills it from
+	// usercache.json.
+	Names map[string]string
+
+	// SignPrefix is the text a sign's first line starts with to become a
+	// marker of the signs source.
+	SignPrefix string
+
+	// Since is when the world of the previous deploy was backed up; the
+	// changes source outlines the chunks saved after it. Zero leaves the
+	// changes set empty.
+	Since time.Time
+}
+
+var sources = []Source{worldGuard{}, towny{}, griefPrevention{}, players{}, signs{}, heatmap{}, changes{}, stats{}}
+
+// defaultHidden is implemented by sources whose marker set starts hidden in
+// the web app.
+type defaultHidden interface {
+	DefaultHidden() bool
+}
+
+// Lookup returns the source with the given ID.
+func Lookup(id string) (Source, bool) {
+	for _, s := range sources {
+		if s.ID() == id {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// SourceIDs returns the IDs of all sources.
+func SourceIDs() []string {
+	ids := make([]string, len(sources))
+	for i, s := range sources {
+		ids[i] = s.ID()
+	}
+	return ids
+}
+
+// DataPaths returns the backup paths to extract for the given source IDs.
+func DataPaths(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	paths := []string{userCachePath}
+	for _, id := range ids {
+		if s, ok := Lookup(id); ok {
+			paths = append(paths, s.Paths()...)
+		}
+	}
+	return paths
+}
+
+// Point is a position on the X/Z plane in block coordinates.
+type Point struct {
+	X float64 `json:"x"`
+	Z float64 `json:"z"`
+}
+
+// Color is an RGBA color; A is 0–1.
+type Color struct {
+	R int     `json:"r"`
+	G int     `json:"g"`
+	B int     `json:"b"`
+	A float64 `json:"a"`
+}
+
+// Marker is drawn as a BlueMap shape marker when it has a Shape, as a line
+// marker when it has a Line, and as a POI marker at Position otherwise.
+type Marker struct {
+	ID     string
+	Label  string
+	Detail string // HTML shown when the marker is clicked
+
+	Shape []Point
+	Holes [][]Point
+	Y     float64 // height the shape is drawn at
+	Color Color   // line color; the fill uses the same color, more transparent
+	Fill  bool    // draw the shape filled with Color and without an outline
+
+	Line []Position
+
+	Position Position
+	Icon     string  // image URL relative to the web root; "" for BlueMap's default
+	Anchor   *[2]int // pixel of the icon placed at Position; nil = its center for a head
+	Player   string  // UUID whose head FetchHeads uses as the icon
+}
+
+// Position is a point in block coordinates.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// MarkerSet holds the markers of one source.
+type MarkerSet struct {
+	Source  Source
+	Markers []Marker
+	Hidden  bool // hidden until toggled on in the web app
+}
+
+// MapResult holds the marker sets generated for one map.
+type MapResult struct {
+	MapID string
+	World string // Bukkit world name the map renders
+	Sets  []MarkerSet
+}
+
+// Count returns the number of markers generated for the map.
+func (r MapResult) Count() int {
+	n := 0
+	for _, s := range r.Sets {
+		n += len(s.Markers)
+	}
+	return n
+}
+
+// Generate loads the given sources from the extracted data in serverDir and
+// assigns their markers to the maps in config/maps/<id>.conf by world name.
+// The sets of markers.toml are added to the maps they list.
+func Generate(serverDir string, ids []string, opts Options) ([]MapResult, error) {
+	names, err := loadUserCache(filepath.Join(serverDir, userCachePath))
+	if err != nil {
+		return nil, err
+	}
+	opts.Names = names
+
+	loaded := make(map[string]map[string][]Marker, len(ids))
+	for _, id := range ids {
+		s, ok := Lookup(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown marker source %q", id)
+		}
+		markers, err := s.Load(serverDir, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		loaded[id] = markers
+	}
+	static, err := LoadStatic(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	confs, err := filepath.Glob(filepath.Join(serverDir, "config", "maps", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(confs)
+
+	var results []MapResult
+	for _, conf := range confs {
+		world, err := mapWorld(conf)
+		if err != nil {
+			return nil, err
+		}
+		res := MapResult{MapID: strings.TrimSuffix(filepath.Base(conf), ".conf"), World: world}
+		if world != "" {
+			for _, id := range ids {
+				s, _ := Lookup(id)
+				h, _ := s.(defaultHidden)
+				res.Sets = append(res.Sets, MarkerSet{Source: s, Markers: loaded[id][world], Hidden: h != nil && h.DefaultHidden()})
+			}
+		}
+		for _, set := range static {
+			if slices.Contains(set.Maps, res.MapID) {
+				res.Sets = append(res.Sets, MarkerSet{
+					Source:  staticSource{id: set.ID, label: set.Label},
+					Markers: set.Markers,
+					Hidden:  set.Hidden,
+				})
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// mapWorld returns the Bukkit world name a map config renders, or "" when
+// the map shows a dimension stored inside another world's folder (the
+// vanilla layout), which plugins do not treat as a world of its own.
+func mapWorld(confPath string) (string, error) {
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return "", err
+	}
+	world, dimension := bluemap.MapWorld(data)
+	if world == "" {
+		return "", nil
+	}
+	world = filepath.Base(filepath.FromSlash(world))
+	switch {
+	case dimension == "minecraft:overworld",
+		dimension == "minecraft:the_nether" && strings.HasSuffix(world, "_nether"),
+		dimension == "minecraft:the_end" && strings.HasSuffix(world, "_the_end"):
+		return world, nil
+	}
+	return "", nil
+}
+
+// loadUserCache reads usercache.json into a UUID → name map. A missing file
+// yields an empty map.
+func loadUserCache(path string) (map[string]string, error) {
+	names := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []struct {
+		Name string `json:"name"`
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", userCachePath, err)
+	}
+	for _, e := range entries {
+		names[strings.ToLower(e.UUID)] = e.Name
+	}
+	return names, nil
+}
+
+// playerNames resolves UUIDs through the name cache, keeping unknown ones.
+func playerNames(uuids []string, names map[string]string) []string {
+	out := make([]string, 0, len(uuids))
+	for _, u := range uuids {
+		if n, ok := names[strings.ToLower(u)]; ok {
+			out = append(out, n)
+		} else {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// detail formats a marker popup: the label in bold followed by one line per
+// non-empty field.
+func detail(label string, fields ...[2]string) string {
+	var sb strings.Builder
+	sb.WriteString("<b>" + html.EscapeString(label) + "</b>")
+	for _, f := range fields {
+		if f[1] != "" {
+			sb.WriteString("<br>" + html.EscapeString(f[0]) + ": " + html.EscapeString(f[1]))
+		}
+	}
+	return sb.String()
+}
+
+// rect returns the outline of the block rectangle spanning both corners,
+// inclusive, as a shape in block-edge coordinates.
+func rect(x1, z1, x2, z2 float64) []Point {
+	minX, maxX := min(x1, x2), max(x1, x2)+1
+	minZ, maxZ := min(z1, z2), max(z1, z2)+1
+	return []Point{{minX, minZ}, {maxX, minZ}, {maxX, maxZ}, {minX, maxZ}}
+}
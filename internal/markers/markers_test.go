@@ -0,0 +1,749 @@
+package markers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	doc, err := parseYAML([]byte(`# comment
+regions:
+    spawn:
+        min: {x: -10.0, y: 0.0, z: -20.0}
+        owners:
+            players:
+            - Notch
+            - 'jeb_'
+        flags: {greeting: "Hi: #1"}
+    poly:
+        points:
+        -   x: 1
+            z: 2
+        - {x: 3, z: 4}
+        tags: [a, "b c"]
+        empty:
+`))
+	if err != nil {
+		t.Fatalf("parseYAML: %v", err)
+	}
+	want := map[string]any{"regions": map[string]any{
+		"spawn": map[string]any{
+			"min":    map[string]any{"x": "-10.0", "y": "0.0", "z": "-20.0"},
+			"owners": map[string]any{"players": []any{"Notch", "jeb_"}},
+			"flags":  map[string]any{"greeting": "Hi: #1"},
+		},
+		"poly": map[string]any{
+			"points": []any{map[string]any{"x": "1", "z": "2"}, map[string]any{"x": "3", "z": "4"}},
+			"tags":   []any{"a", "b c"},
+			"empty":  nil,
+		},
+	}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("parseYAML =\n%#v\nwant\n%#v", doc, want)
+	}
+
+	if _, err := parseYAML([]byte("a: 1\n  b: 2\n")); err == nil {
+		t.Error("parseYAML accepted bad indentation")
+	}
+}
+
+func TestOutline(t *testing.T) {
+	// A 3×3 ring of cells with the middle missing, plus a separate cell.
+	cells := map[[2]int]bool{{5, 5}: true}
+	for x := 0; x < 3; x++ {
+		for z := 0; z < 3; z++ {
+			if x != 1 || z != 1 {
+				cells[[2]int{x, z}] = true
+			}
+		}
+	}
+	polys := outline(cells, 16)
+	if len(polys) != 2 {
+		t.Fatalf("outline returned %d polygons, want 2", len(polys))
+	}
+	ring := polys[0]
+	if want := []Point{{0, 0}, {48, 0}, {48, 48}, {0, 48}}; !reflect.DeepEqual(ring.Shape, want) {
+		t.Errorf("ring shape = %v, want %v", ring.Shape, want)
+	}
+	if len(ring.Holes) != 1 || len(ring.Holes[0]) != 4 || signedArea(ring.Holes[0]) != -256 {
+		t.Errorf("ring holes = %v, want one 16×16 hole", ring.Holes)
+	}
+	if len(polys[1].Holes) != 0 || signedArea(polys[1].Shape) != 256 {
+		t.Errorf("single cell = %+v", polys[1])
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("usercache.json", `[{"name":"Notch","uuid":"069A79F4-44E9-4726-A5BE-FCA90E38AAF5"}]`)
+	write("config/maps/overworld.conf", "world: \"world\"\ndimension: \"minecraft:overworld\"\n")
+	write("config/maps/nether.conf", "world: \"world_nether\"\ndimension: \"minecraft:the_nether\"\n")
+	write("config/maps/vanilla_end.conf", "world: \"world\"\ndimension: \"minecraft:the_end\"\n")
+	write("plugins/WorldGuard/worlds/world/regions.yml", `regions:
+    __global__: {type: global}
+    spawn:
+        type: cuboid
+        min: {x: -10.0, y: 60.0, z: -10.0}
+        max: {x: 9.0, y: 255.0, z: 9.0}
+        owners: {unique-ids: [069a79f4-44e9-4726-a5be-fca90e38aaf5]}
+        priority: 10
+`)
+	write("plugins/WorldGuard/worlds/world_nether/regions.yml", `regions:
+    hub:
+        type: poly2d
+        min-y: 30
+        max-y: 100
+        points:
+        - {x: 0, z: 0}
+        - {x: 10, z: 0}
+        - {x: 5, z: 8}
+`)
+	write("plugins/Towny/data/towns/Riverside.txt", "name=Riverside\nmayor=Notch\nnation=Valley\n")
+	write("plugins/Towny/data/townblocks/world/0_0_16.data", "town=Riverside\n")
+	write("plugins/Towny/data/townblocks/world/1_0_16.data", "town=Riverside\n")
+	write("plugins/GriefPreventionData/ClaimData/7.yml", `Lesser Boundary Corner: world;100;64;200
+Greater Boundary Corner: world;119;64;229
+Owner: 069a79f4-44e9-4726-a5be-fca90e38aaf5
+Builders: []
+Parent Claim ID: -1
+`)
+	write("plugins/GriefPreventionData/ClaimData/8.yml", `Lesser Boundary Corner: world;100;64;200
+Greater Boundary Corner: world;104;64;204
+Owner: ''
+Parent Claim ID: 7
+`)
+
+	results, err := Generate(dir, []string{"worldguard", "towny", "griefprevention"}, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	byMap := make(map[string]MapResult)
+	for _, r := range results {
+		byMap[r.MapID] = r
+	}
+	if got := byMap["overworld"].Count(); got != 3 {
+		t.Errorf("overworld has %d markers, want 3 (spawn, Riverside, claim 7)", got)
+	}
+	if got := byMap["nether"].Count(); got != 1 {
+		t.Errorf("nether has %d markers, want 1", got)
+	}
+	if r := byMap["vanilla_end"]; r.World != "" || r.Count() != 0 {
+		t.Errorf("vanilla end map = %+v, want no world", r)
+	}
+
+	spawn := byMap["overworld"].Sets[0].Markers[0]
+	if want := []Point{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}}; !reflect.DeepEqual(spawn.Shape, want) || spawn.Y != 60 {
+		t.Errorf("spawn = %+v", spawn)
+	}
+	if !strings.Contains(spawn.Detail, "Owners: Notch") {
+		t.Errorf("spawn detail %q does not name the owner", spawn.Detail)
+	}
+	town := byMap["overworld"].Sets[1].Markers[0]
+	if want := []Point{{0, 0}, {32, 0}, {32, 16}, {0, 16}}; !reflect.DeepEqual(town.Shape, want) {
+		t.Errorf("town shape = %v, want %v", town.Shape, want)
+	}
+	if claim := byMap["overworld"].Sets[2].Markers[0]; claim.Label != "Notch's claim" {
+		t.Errorf("claim label = %q", claim.Label)
+	}
+
+	// JSON output merges into existing live data and replaces older sets.
+	write("web/maps/overworld/live/markers.json", `{"custom":{"label":"Mine"},"towny":{"label":"stale"}}`)
+	written, err := WriteJSON(dir, results)
+	if err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if !reflect.DeepEqual(written, []string{"overworld"}) {
+		t.Errorf("WriteJSON wrote %v, want only the rendered overworld map", written)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "web", "maps", "overworld", "live", "markers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sets map[string]struct {
+		Label   string                    `json:"label"`
+		Markers map[string]map[string]any `json:"markers"`
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		t.Fatal(err)
+	}
+	if sets["custom"].Label != "Mine" || sets["towny"].Label != "Towns" || len(sets["worldguard"].Markers) != 1 {
+		t.Errorf("markers.json = %s", data)
+	}
+	if m := sets["worldguard"].Markers["worldguard-spawn"]; m["type"] != "shape" || m["shapeY"] != 60.0 {
+		t.Errorf("spawn marker = %v", m)
+	}
+
+	written, err = WriteHOCON(dir, results)
+	if err != nil {
+		t.Fatalf("WriteHOCON: %v", err)
+	}
+	if !reflect.DeepEqual(written, []string{"nether", "overworld"}) {
+		t.Errorf("WriteHOCON wrote %v", written)
+	}
+	conf, err := os.ReadFile(filepath.Join(dir, HOCONDirName, "nether.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(conf), "marker-sets: {") || !strings.Contains(string(conf), `"shape-y": 30`) {
+		t.Errorf("nether.conf =\n%s", conf)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config/maps/overworld.conf", "world: \"world\"\n")
+	write("config/maps/vanilla_nether.conf", "world: \"world\"\ndimension: \"minecraft:the_nether\"\n")
+	write(StaticFileName, `
+[sets.landmarks]
+label = "Landmarks"
+maps = ["overworld", "vanilla_nether"]
+default_hidden = true
+
+[[sets.landmarks.markers]]
+id = "spawn"
+label = "Spawn"
+position = [0, 64, 0]
+icon = "assets/spawn.png"
+anchor = [16, 32]
+
+[[sets.landmarks.markers]]
+type = "line"
+label = "Rail <north>"
+points = [[0, 64, 0], [0, 64, -500]]
+color = "#ff000080"
+
+[[sets.landmarks.markers]]
+type = "area"
+label = "Market"
+points = [[10, 10], [30, 10], [30, 30]]
+y = 70
+`)
+
+	results, err := Generate(dir, nil, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(results) != 2 || results[0].Count() != 3 || results[1].Count() != 3 {
+		t.Fatalf("results = %+v, want 3 markers on both maps", results)
+	}
+	set := results[0].Sets[0]
+	if set.Source.ID() != "landmarks" || set.Source.Label() != "Landmarks" || !set.Hidden {
+		t.Errorf("set = %+v", set)
+	}
+	line := set.Markers[1]
+	if line.ID != "landmarks-2" || len(line.Line) != 2 || line.Color != (Color{R: 255, A: 128.0 / 255}) {
+		t.Errorf("line = %+v", line)
+	}
+	if line.Detail != "<b>Rail &lt;north&gt;</b>" {
+		t.Errorf("line detail = %q", line.Detail)
+	}
+
+	write("web/maps/overworld/live/markers.json", `{}`)
+	if _, err := WriteJSON(dir, results); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "web", "maps", "overworld", "live", "markers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sets map[string]struct {
+		DefaultHidden bool                      `json:"defaultHidden"`
+		Markers       map[string]map[string]any `json:"markers"`
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		t.Fatal(err)
+	}
+	markers := sets["landmarks"].Markers
+	if !sets["landmarks"].DefaultHidden || markers["landmarks-2"]["type"] != "line" || markers["landmarks-3"]["type"] != "shape" {
+		t.Errorf("markers.json = %s", data)
+	}
+	if anchor, _ := markers["spawn"]["anchor"].(map[string]any); anchor["y"] != 32.0 {
+		t.Errorf("spawn anchor = %v, want y 32", markers["spawn"]["anchor"])
+	}
+}
+
+func TestLoadStaticErrors(t *testing.T) {
+	for _, content := range []string{
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\nposition = [0, 64, 0]\n",
+		"[sets.a]\n[[sets.a.markers]]\nlabel = \"x\"\nposition = [0, 64, 0]\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\nlabel = \"x\"\nposition = [0, 64]\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\ntype = \"line\"\nlabel = \"x\"\npoints = [[0, 64, 0]]\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\ntype = \"area\"\nlabel = \"x\"\npoints = [[0, 0, 0], [1, 0, 0], [1, 0, 1]]\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\ntype = \"circle\"\nlabel = \"x\"\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\ntype = \"line\"\nlabel = \"x\"\npoints = [[0, 64, 0], [1, 64, 1]]\ncolor = \"red\"\n",
+		"[sets.a]\nmaps = [\"overworld\"]\n[[sets.a.markers]]\nlabel = \"x\"\nposition = [0, 64, 0]\nicn = \"a.png\"\n",
+		"[sets.towny]\nmaps = [\"overworld\"]\n",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, StaticFileName), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadStatic(dir); err == nil {
+			t.Errorf("LoadStatic accepted\n%s", content)
+		}
+	}
+}
+
+// encodeNBT encodes an uncompressed NBT document with the given root
+// compound. It supports the value types the tests need.
+func encodeNBT(t *testing.T, root map[string]any) []byte {
+	t.Helper()
+	var raw bytes.Buffer
+	var payload func(v any)
+	tag := func(v any) byte {
+		switch v.(type) {
+		case int32:
+			return tagInt
+		case int64:
+			return tagLong
+		case float64:
+			return tagDouble
+		case string:
+			return tagString
+		case []any:
+			return tagList
+		case map[string]any:
+			return tagCompound
+		}
+		t.Fatalf("writeNBT: unsupported %T", v)
+		return 0
+	}
+	str := func(s string) {
+		binary.Write(&raw, binary.BigEndian, uint16(len(s)))
+		raw.WriteString(s)
+	}
+	payload = func(v any) {
+		switch v := v.(type) {
+		case int32, int64, float64:
+			binary.Write(&raw, binary.BigEndian, v)
+		case string:
+			str(v)
+		case []any:
+			elem := tagEnd
+			if len(v) > 0 {
+				elem = tag(v[0])
+			}
+			raw.WriteByte(elem)
+			binary.Write(&raw, binary.BigEndian, int32(len(v)))
+			for _, e := range v {
+				payload(e)
+			}
+		case map[string]any:
+			for k, e := range v {
+				raw.WriteByte(tag(e))
+				str(k)
+				payload(e)
+			}
+			raw.WriteByte(tagEnd)
+		}
+	}
+	raw.WriteByte(tagCompound)
+	str("")
+	payload(root)
+	return raw.Bytes()
+}
+
+// writeNBT writes a gzip-compressed NBT file, as Minecraft stores player data.
+func writeNBT(t *testing.T, path string, root map[string]any) {
+	t.Helper()
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(encodeNBT(t, root))
+	zw.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayers(t *testing.T) {
+	dir := t.TempDir()
+	const (
+		notch   = "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+		offline = "5f0cc2a4-3e2e-3f5c-9a0c-1c6d1f1e2a3b"
+	)
+	if err := os.WriteFile(filepath.Join(dir, "usercache.json"), []byte(`[{"name":"Notch","uuid":"`+notch+`"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for id, world := range map[string]string{"overworld": "world", "nether": "world_nether"} {
+		path := filepath.Join(dir, "config", "maps", id+".conf")
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		dim := "minecraft:overworld"
+		if id == "nether" {
+			dim = "minecraft:the_nether"
+		}
+		if err := os.WriteFile(path, []byte("world: \""+world+"\"\ndimension: \""+dim+"\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNBT(t, filepath.Join(dir, "world", "playerdata", notch+".dat"), map[string]any{
+		"Pos":       []any{10.5, 64.0, -20.5},
+		"Dimension": "minecraft:overworld",
+		"bukkit":    map[string]any{"lastPlayed": int64(1700000000000)},
+	})
+	writeNBT(t, filepath.Join(dir, "world", "playerdata", offline+".dat"), map[string]any{
+		"Pos":       []any{1.0, 70.0, 2.0},
+		"Dimension": "minecraft:the_nether",
+		"bukkit":    map[string]any{"lastKnownName": "Steve"},
+	})
+
+	results, err := Generate(dir, []string{"players"}, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(results) != 2 || results[1].MapID != "overworld" || results[0].Count() != 1 || results[1].Count() != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	p := results[1].Sets[0].Markers[0]
+	if p.Label != "Notch" || p.Position != (Position{10.5, 64, -20.5}) || !strings.Contains(p.Detail, "Last seen: 2023-11-14 22:13 UTC") {
+		t.Errorf("overworld player = %+v", p)
+	}
+	if p := results[0].Sets[0].Markers[0]; p.Label != "Steve" || p.Icon != defaultIcon {
+		t.Errorf("nether player = %+v", p)
+	}
+
+	// Heads are fetched once, cached, and served from the cache afterwards.
+	skin := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(skin, image.Rect(8, 8, 16, 16), image.NewUniform(color.NRGBA{R: 200, A: 255}), image.Point{}, draw.Src)
+	requests := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/skin.png" {
+			png.Encode(w, skin)
+			return
+		}
+		if r.URL.Path != "/profile/"+strings.ReplaceAll(notch, "-", "") {
+			http.NotFound(w, r)
+			return
+		}
+		textures := base64.StdEncoding.EncodeToString([]byte(`{"textures":{"SKIN":{"url":"` + srv.URL + `/skin.png"}}}`))
+		fmt.Fprintf(w, `{"properties":[{"name":"textures","value":%q}]}`, textures)
+	}))
+	defer srv.Close()
+	defer func(u string) { profileURL = u }(profileURL)
+	profileURL = srv.URL + "/profile/"
+
+	cache := filepath.Join(dir, "cache")
+	stats, err := FetchHeads(context.Background(), dir, cache, results)
+	if err != nil {
+		t.Fatalf("FetchHeads: %v", err)
+	}
+	if stats != (HeadStats{Fetched: 1, Offline: 1}) || requests != 2 {
+		t.Errorf("first FetchHeads = %+v after %d requests", stats, requests)
+	}
+	if icon := results[1].Sets[0].Markers[0].Icon; icon != "playerheads/"+notch+".png" {
+		t.Errorf("icon = %q", icon)
+	}
+	f, err := os.Open(filepath.Join(dir, "web", "playerheads", notch+".png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := head.Bounds(); b.Dx() != headSize || color.NRGBAModel.Convert(head.At(5, 5)) != (color.NRGBA{R: 200, A: 255}) {
+		t.Errorf("head is %v with %v at (5,5)", b, head.At(5, 5))
+	}
+
+	stats, err = FetchHeads(context.Background(), dir, cache, results)
+	if err != nil || stats.Cached != 1 || requests != 2 {
+		t.Errorf("second FetchHeads = %+v, %v after %d requests", stats, err, requests)
+	}
+
+	written, err := WriteJSON(dir, results)
+	if err != nil || len(written) != 0 {
+		t.Fatalf("WriteJSON = %v, %v; want nothing before the maps are rendered", written, err)
+	}
+	os.MkdirAll(filepath.Join(dir, "web", "maps", "overworld"), 0o755)
+	if _, err := WriteJSON(dir, results); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "web", "maps", "overworld", "live", "markers.json"))
+	if !strings.Contains(string(data), `"type":"poi"`) || !strings.Contains(string(data), `"icon":"playerheads/`+notch+`.png"`) {
+		t.Errorf("markers.json = %s", data)
+	}
+}
+
+func TestSigns(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "maps", "overworld.conf"), []byte("world: \"world\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// One 1.20+ chunk and one pre-1.18 chunk in region r.-1.0.
+	modern := encodeNBT(t, map[string]any{"block_entities": []any{
+		map[string]any{"id": "minecraft:sign", "x": int32(-20), "y": int32(70), "z": int32(5), "front_text": map[string]any{
+			"messages": []any{`"[Map] Spawn"`, `{"text":"Town ","extra":[{"text":"hall"}]}`, `""`, `""`},
+		}},
+		map[string]any{"id": "minecraft:sign", "x": int32(-21), "y": int32(70), "z": int32(5), "front_text": map[string]any{
+			"messages": []any{`"Shop"`, `"[map]"`, `""`, `""`},
+		}},
+		map[string]any{"id": "minecraft:chest", "x": int32(-22), "y": int32(70), "z": int32(5), "CustomName": `"[map]"`},
+	}})
+	legacy := encodeNBT(t, map[string]any{"Level": map[string]any{"TileEntities": []any{
+		map[string]any{"id": "Sign", "x": int32(-500), "y": int32(64), "z": int32(40), "Text1": `{"text":"[map]"}`, "Text2": `"Farm"`, "Text3": `""`, "Text4": `""`},
+	}}})
+	writeRegion(t, filepath.Join(dir, "world", "region", "r.-1.0.mca"), map[int][]byte{31: modern, 2 * 32: legacy})
+	if err := os.WriteFile(filepath.Join(dir, "world", "level.dat"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Generate(dir, []string{"signs"}, Options{SignPrefix: "[map]"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := results[0].Sets[0].Markers
+	if len(got) != 2 {
+		t.Fatalf("markers = %+v, want 2", got)
+	}
+	if got[0].Label != "Farm" || got[0].Position != (Position{-499.5, 64.5, 40.5}) {
+		t.Errorf("legacy sign = %+v", got[0])
+	}
+	if got[1].Label != "Spawn Town hall" || got[1].ID != "signs--20_70_5" {
+		t.Errorf("modern sign = %+v", got[1])
+	}
+	if m := setData(results[0].Sets[0], camelCase)["markers"].(map[string]any)[got[1].ID].(map[string]any); m["type"] != "poi" || m["icon"] != nil {
+		t.Errorf("sign marker data = %v, want a POI with the default icon", m)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "maps", "overworld.conf"), []byte("world: \"world\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	inhabited := func(ticks int64) []byte {
+		return encodeNBT(t, map[string]any{"InhabitedTime": ticks})
+	}
+	// Region r.-1.0 holds chunks -32..-1, 0..31.
+	writeRegion(t, filepath.Join(dir, "world", "region", "r.-1.0.mca"), map[int][]byte{
+		31:     inhabited(3 * 60 * 60 * 20), // chunk -1,0: 3h
+		30:     inhabited(100),              // chunk -2,0: same square
+		0:      inhabited(15 * 60 * 20),     // chunk -32,0: 15m
+		5 * 32: inhabited(1000),             // chunk -32,5: below the minimum
+	})
+	if err := os.WriteFile(filepath.Join(dir, "world", "level.dat"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Generate(dir, []string{"heatmap"}, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	set := results[0].Sets[0]
+	if !set.Hidden {
+		t.Error("heatmap set is not hidden by default")
+	}
+	got := set.Markers
+	if len(got) != 2 {
+		t.Fatalf("markers = %+v, want 2", got)
+	}
+	if got[0].ID != "heatmap--1_0" || got[0].Label != "Activity: 3h 00m" || (got[0].Color != Color{heatmapHot.R, heatmapHot.G, heatmapHot.B, 0.55}) || !got[0].Fill {
+		t.Errorf("hot square = %+v", got[0])
+	}
+	if want := []Point{{-64, 0}, {0, 0}, {0, 64}, {-64, 64}}; !reflect.DeepEqual(got[0].Shape, want) {
+		t.Errorf("hot square shape = %v, want %v", got[0].Shape, want)
+	}
+	if got[1].ID != "heatmap--8_0" || got[1].Label != "Activity: 15m" || got[1].Color.R != heatmapCold.R || got[1].Color.A != 0.15 {
+		t.Errorf("cold square = %+v", got[1])
+	}
+	m := setData(set, camelCase)["markers"].(map[string]any)[got[0].ID].(map[string]any)
+	if m["lineWidth"] != 0 || m["fillColor"] != got[0].Color {
+		t.Errorf("heatmap marker data = %v, want a fill without outline", m)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "maps", "overworld.conf"), []byte("world: \"world\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	saved := map[int]time.Time{
+		0:  since.Add(time.Hour),      // chunk -32,0
+		1:  since.Add(2 * time.Hour),  // chunk -31,0: same area
+		5:  since.Add(time.Minute),    // chunk -27,0: an area of its own
+		10: since.Add(-1 * time.Hour), // chunk -22,0: before the last update
+	}
+	nbt := encodeNBT(t, map[string]any{"InhabitedTime": int64(0)})
+	chunks := make(map[int][]byte)
+	for i := range saved {
+		chunks[i] = nbt
+	}
+	path := filepath.Join(dir, "world", "region", "r.-1.0.mca")
+	writeRegion(t, path, chunks)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, at := range saved {
+		binary.BigEndian.PutUint32(data[4096+i*4:], uint32(at.Unix()))
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "world", "level.dat"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Generate(dir, []string{"changes"}, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := results[0].Sets[0].Markers; len(got) != 0 {
+		t.Errorf("markers without Since = %+v, want none", got)
+	}
+
+	results, err = Generate(dir, []string{"changes"}, Options{Since: since})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := results[0].Sets[0].Markers
+	if len(got) != 2 {
+		t.Fatalf("markers = %+v, want 2", got)
+	}
+	if want := []Point{{-512, 0}, {-480, 0}, {-480, 16}, {-512, 16}}; got[0].Label != "Changed: 2 chunks" || !reflect.DeepEqual(got[0].Shape, want) {
+		t.Errorf("first area = %+v, want 2 chunks at %v", got[0], want)
+	}
+	if !strings.Contains(got[0].Detail, "2026-10-01 14:00 UTC") {
+		t.Errorf("first area detail = %q, want the last save time", got[0].Detail)
+	}
+	if got[1].Label != "Changed: 1 chunk" || got[1].Shape[0] != (Point{-432, 0}) {
+		t.Errorf("second area = %+v", got[1])
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "maps", "overworld.conf"), []byte("world: \"world\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeNBT(t, filepath.Join(dir, "world", "level.dat"), map[string]any{"Data": map[string]any{
+		"SpawnX": int32(100), "SpawnY": int32(70), "SpawnZ": int32(-20),
+	}})
+	notch := "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+	if err := os.WriteFile(filepath.Join(dir, "usercache.json"), []byte(`[{"name":"Notch","uuid":"`+notch+`"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		notch:                                  `{"stats":{"minecraft:custom":{"minecraft:play_time":144000,"minecraft:walk_one_cm":250000,"minecraft:fly_one_cm":50000,"minecraft:deaths":3}},"DataVersion":3953}`,
+		"11111111-2222-3333-4444-555555555555": `{"stats":{"minecraft:custom":{"minecraft:play_one_minute":72000,"minecraft:deaths":7,"minecraft:mob_kills":12}}}`,
+		"legacy":                               `{"stat.deaths":2}`,
+	}
+	for uuid, content := range files {
+		path := filepath.Join(dir, "world", "stats", uuid+".json")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Generate(dir, []string{"stats"}, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := results[0].Sets[0].Markers
+	if len(got) != 1 {
+		t.Fatalf("markers = %+v, want 1", got)
+	}
+	if got[0].Position != (Position{100.5, 70, -19.5}) {
+		t.Errorf("position = %v, want the world spawn", got[0].Position)
+	}
+	want := "<b>Leaderboards</b>" +
+		"<br><br><b>Play Time</b><br>1. Notch: 2h 00m<br>2. 11111111-2222-3333-4444-555555555555: 1h 00m" +
+		"<br><br><b>Distance Traveled</b><br>1. Notch: 3.0 km" +
+		"<br><br><b>Deaths</b><br>1. 11111111-2222-3333-4444-555555555555: 7<br>2. Notch: 3" +
+		"<br><br><b>Mob Kills</b><br>1. 11111111-2222-3333-4444-555555555555: 12"
+	if got[0].Detail != want {
+		t.Errorf("detail =\n%s\nwant\n%s", got[0].Detail, want)
+	}
+}
+
+// writeRegion writes a region file with the given zlib-compressed chunks,
+// keyed by their index in the region (z*32 + x).
+func writeRegion(t *testing.T, path string, chunks map[int][]byte) {
+	t.Helper()
+	header := make([]byte, 8192)
+	var body bytes.Buffer
+	sector := 2
+	for i := 0; i < 1024; i++ {
+		nbt, ok := chunks[i]
+		if !ok {
+			continue
+		}
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(nbt)
+		zw.Close()
+		data := binary.BigEndian.AppendUint32(nil, uint32(z.Len()+1))
+		data = append(append(data, 2), z.Bytes()...)
+		count := (len(data) + 4095) / 4096
+		data = append(data, make([]byte, count*4096-len(data))...)
+		binary.BigEndian.PutUint32(header[i*4:], uint32(sector<<8|count))
+		body.Write(data)
+		sector += count
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(header, body.Bytes()...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
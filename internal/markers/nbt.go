@@ -0,0 +1,204 @@
+package markers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// NBT tag types.
+const (
+	tagEnd byte = iota
+	tagByte
+	tagShort
+	tagInt
+	tagLong
+	tagFloat
+	tagDouble
+	tagByteArray
+	tagString
+	tagList
+	tagCompound
+	tagIntArray
+	tagLongArray
+)
+
+// maxNBTDepth bounds the nesting of lists and compounds so a corrupt file
+// cannot exhaust the stack.
+const maxNBTDepth = 512
+
+// parseNBT decodes an NBT file, gzip-compressed as Minecraft writes player
+// data or uncompressed, and returns its root compound. Compounds decode to
+// map[string]any, lists to []any, and the other tags to int8, int16, int32,
+// int64, float32, float64, []byte, string, []int32 and []int64.
+func parseNBT(data []byte) (map[string]any, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	d := &nbtDecoder{r: bufio.NewReader(r)}
+
+	typ, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	if typ != tagCompound {
+		return nil, fmt.Errorf("root tag is type %d, not a compound", typ)
+	}
+	if _, err := d.string(); err != nil {
+		return nil, err
+	}
+	return d.compound(0)
+}
+
+type nbtDecoder struct {
+	r *bufio.Reader
+}
+
+func (d *nbtDecoder) payload(typ byte, depth int) (any, error) {
+	if depth > maxNBTDepth {
+		return nil, errors.New("NBT nested too deeply")
+	}
+	switch typ {
+	case tagByte:
+		b, err := d.byte()
+		return int8(b), err
+	case tagShort:
+		var v int16
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case tagInt:
+		return d.int()
+	case tagLong:
+		var v int64
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case tagFloat:
+		var v uint32
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return math.Float32frombits(v), err
+	case tagDouble:
+		var v uint64
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return math.Float64frombits(v), err
+	case tagByteArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(d.r, b)
+		return b, err
+	case tagString:
+		return d.string()
+	case tagList:
+		elem, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]any, 0, min(n, 1024))
+		for i := 0; i < n; i++ {
+			v, err := d.payload(elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case tagCompound:
+		return d.compound(depth + 1)
+	case tagIntArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int32, n)
+		err = binary.Read(d.r, binary.BigEndian, v)
+		return v, err
+	case tagLongArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int64, n)
+		err = binary.Read(d.r, binary.BigEndian, v)
+		return v, err
+	}
+	return nil, fmt.Errorf("unknown NBT tag type %d", typ)
+}
+
+func (d *nbtDecoder) compound(depth int) (map[string]any, error) {
+	m := make(map[string]any)
+	for {
+		typ, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if typ == tagEnd {
+			return m, nil
+		}
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.payload(typ, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		m[name] = v
+	}
+}
+
+func (d *nbtDecoder) byte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+func (d *nbtDecoder) int() (int32, error) {
+	var v int32
+	err := binary.Read(d.r, binary.BigEndian, &v)
+	return v, err
+}
+
+// length reads an array or list length. Lengths are bounded by what is left
+// of a player file in practice, so anything negative or absurd is rejected
+// before allocating.
+func (d *nbtDecoder) length() (int, error) {
+	n, err := d.int()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 1<<24 {
+		return 0, fmt.Errorf("invalid NBT length %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *nbtDecoder) string() (string, error) {
+	var n uint16
+	if err := binary.Read(d.r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,133 @@
+package markers
+
+import "sort"
+
+// polygon is an outline with the holes cut out of it.
+type polygon struct {
+	Shape []Point
+	Holes [][]Point
+}
+
+// outline traces the boundaries of a set of grid cells, each size blocks
+// wide, and returns one polygon per connected area. Edges are walked
+// clockwise (x east, z south) with the cells on their right, so outer
+// boundaries have a positive and holes a negative signed area.
+func outline(cells map[[2]int]bool, size float64) []polygon {
+	type vertex [2]int
+	next := make(map[vertex][]vertex)
+	add := func(a, b vertex) { next[a] = append(next[a], b) }
+	for c := range cells {
+		x, z := c[0], c[1]
+		if !cells[[2]int{x, z - 1}] {
+			add(vertex{x, z}, vertex{x + 1, z})
+		}
+		if !cells[[2]int{x + 1, z}] {
+			add(vertex{x + 1, z}, vertex{x + 1, z + 1})
+		}
+		if !cells[[2]int{x, z + 1}] {
+			add(vertex{x + 1, z + 1}, vertex{x, z + 1})
+		}
+		if !cells[[2]int{x - 1, z}] {
+			add(vertex{x, z + 1}, vertex{x, z})
+		}
+	}
+
+	less := func(a, b vertex) bool {
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[0] < b[0]
+	}
+	starts := make([]vertex, 0, len(next))
+	for v, to := range next {
+		starts = append(starts, v)
+		// Cells touching only at a corner give a vertex two exits; pick
+		// them in a fixed order so the output does not depend on map order.
+		sort.Slice(to, func(i, j int) bool { return less(to[i], to[j]) })
+	}
+	sort.Slice(starts, func(i, j int) bool { return less(starts[i], starts[j]) })
+
+	var outers, holes [][]Point
+	for _, start := range starts {
+		for len(next[start]) > 0 {
+			var loop []vertex
+			for v := start; ; {
+				loop = append(loop, v)
+				to := next[v][0]
+				next[v] = next[v][1:]
+				v = to
+				if v == start {
+					break
+				}
+			}
+
+			points := make([]Point, 0, len(loop))
+			for i, v := range loop {
+				prev, following := loop[(i+len(loop)-1)%len(loop)], loop[(i+1)%len(loop)]
+				if (prev[0] == v[0] && v[0] == following[0]) || (prev[1] == v[1] && v[1] == following[1]) {
+					continue // collinear
+				}
+				points = append(points, Point{X: float64(v[0]) * size, Z: float64(v[1]) * size})
+			}
+			if signedArea(points) > 0 {
+				outers = append(outers, points)
+			} else {
+				holes = append(holes, points)
+			}
+		}
+	}
+
+	polys := make([]polygon, len(outers))
+	for i, o := range outers {
+		polys[i].Shape = o
+	}
+	for _, h := range holes {
+		// A point just inside the hole: the midpoint of its first edge,
+		// moved half a cell away from the enclosed cells.
+		a, b := h[0], h[1%len(h)]
+		dx, dz := sign(b.X-a.X), sign(b.Z-a.Z)
+		p := Point{X: (a.X+b.X)/2 + dz*size/2, Z: (a.Z+b.Z)/2 - dx*size/2}
+
+		best := -1
+		for i, o := range outers {
+			if contains(o, p) && (best < 0 || signedArea(o) < signedArea(outers[best])) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			polys[best].Holes = append(polys[best].Holes, h)
+		}
+	}
+	return polys
+}
+
+func signedArea(points []Point) float64 {
+	var a float64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		a += p.X*q.Z - q.X*p.Z
+	}
+	return a / 2
+}
+
+// contains reports whether p lies inside the polygon (even-odd rule).
+func contains(points []Point, p Point) bool {
+	in := false
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		if (a.Z > p.Z) != (b.Z > p.Z) && p.X < a.X+(p.Z-a.Z)*(b.X-a.X)/(b.Z-a.Z) {
+			in = !in
+		}
+	}
+	return in
+}
+
+func sign(f float64) float64 {
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package markers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// players reads the vanilla player data stored in the main world folder,
+// <world>/playerdata/<uuid>.dat, and places a marker at each player's last
+// logout position. The world folders are extracted anyway, so the source
+// needs no extra backup paths.
+type players struct{}
+
+func (players) ID() string      { return "players" }
+func (players) Label() string   { return "Players (last seen)" }
+func (players) Paths() []string { return nil }
+
+// defaultIcon is the head BlueMap's web app ships for players without a skin.
+const defaultIcon = "assets/steve.png"
+
+func (players) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	files, err := filepath.Glob(filepath.Join(serverDir, "*", "playerdata", "*.dat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Worlds copied from another server can carry stale player data of their
+	// own, so a player found twice keeps the most recent position.
+	type seen struct {
+		world  string
+		at     time.Time
+		marker Marker
+	}
+	byUUID := make(map[string]seen)
+	for _, path := range files {
+		uuid := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".dat"))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := parseNBT(data)
+		if err != nil {
+			return nil, fmt.Errorf("player %s: %w", uuid, err)
+		}
+		pos, ok := doc["Pos"].([]any)
+		if !ok || len(pos) != 3 {
+			continue
+		}
+		x, _ := pos[0].(float64)
+		y, _ := pos[1].(float64)
+		z, _ := pos[2].(float64)
+
+		base := filepath.Base(filepath.Dir(filepath.Dir(path)))
+		world := playerWorld(base, doc["Dimension"])
+		if world == "" {
+			continue
+		}
+
+		bukkit, _ := doc["bukkit"].(map[string]any)
+		name := opts.Names[uuid]
+		if name == "" {
+			name, _ = bukkit["lastKnownName"].(string)
+		}
+		if name == "" {
+			name = uuid
+		}
+
+		at := lastPlayed(doc)
+		if at.IsZero() {
+			if info, err := os.Stat(path); err == nil {
+				at = info.ModTime()
+			}
+		}
+		if prev, ok := byUUID[uuid]; ok && !at.After(prev.at) {
+			continue
+		}
+		var lastSeen string
+		if !at.IsZero() {
+			lastSeen = at.UTC().Format("2006-01-02 15:04 UTC")
+		}
+
+		byUUID[uuid] = seen{world: world, at: at, marker: Marker{
+			ID:    "players-" + uuid,
+			Label: name,
+			Detail: detail(name,
+				[2]string{"Last seen", lastSeen},
+				[2]string{"Position", fmt.Sprintf("%.0f, %.0f, %.0f", x, y, z)},
+			),
+			Position: Position{X: x, Y: y, Z: z},
+			Icon:     defaultIcon,
+			Player:   uuid,
+		}}
+	}
+	all := make([]seen, 0, len(byUUID))
+	for _, s := range byUUID {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if a, b := strings.ToLower(all[i].marker.Label), strings.ToLower(all[j].marker.Label); a != b {
+			return a < b
+		}
+		return all[i].marker.ID < all[j].marker.ID
+	})
+
+	markers := make(map[string][]Marker)
+	for _, s := range all {
+		markers[s.world] = append(markers[s.world], s.marker)
+	}
+	return markers, nil
+}
+
+// playerWorld returns the Bukkit world a player is in from the Dimension tag
+// of their data in the base world folder. Vanilla and Bukkit keep the nether
+// and end in <base>_nether and <base>_the_end; other worlds are named by
+// their dimension key. Before 1.16 the tag was -1, 0 or 1.
+func playerWorld(base string, dimension any) string {
+	switch d := dimension.(type) {
+	case string:
+		switch d {
+		case "minecraft:overworld":
+			return base
+		case "minecraft:the_nether":
+			return base + "_nether"
+		case "minecraft:the_end":
+			return base + "_the_end"
+		}
+		if _, name, ok := strings.Cut(d, ":"); ok {
+			return name
+		}
+		return d
+	case int32:
+		switch d {
+		case 0:
+			return base
+		case -1:
+			return base + "_nether"
+		case 1:
+			return base + "_the_end"
+		}
+	case nil:
+		return base
+	}
+	return ""
+}
+
+// lastPlayed returns when the player logged out, as recorded by Paper or
+// CraftBukkit, or the zero time on vanilla servers.
+func lastPlayed(doc map[string]any) time.Time {
+	if paper, ok := doc["Paper"].(map[string]any); ok {
+		if ms, ok := paper["LastSeen"].(int64); ok && ms > 0 {
+			return time.UnixMilli(ms)
+		}
+	}
+	if bukkit, ok := doc["bukkit"].(map[string]any); ok {
+		if ms, ok := bukkit["lastPlayed"].(int64); ok && ms > 0 {
+			return time.UnixMilli(ms)
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,272 @@
+package markers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+)
+
+// signs scans the region files of the extracted worlds for signs whose first
+// line starts with Options.SignPrefix and places a POI marker at each, so
+// builders can label the map in game. The sign's remaining text is the label.
+type signs struct{}
+
+func (signs) ID() string      { return "signs" }
+func (signs) Label() string   { return "Signs" }
+func (signs) Paths() []string { return nil }
+
+// DefaultSignPrefix is the sign prefix used when markers.sign_prefix is not
+// set in config.toml.
+const DefaultSignPrefix = "[map]"
+
+// regionFile is a region file of an extracted world.
+type regionFile struct{ world, path string }
+
+// worldRegionFiles lists the region files of the worlds extracted into
+// serverDir, the folders with a level.dat, by Bukkit world name. Worlds with
+// the Bukkit _nether or _the_end suffix are read for that dimension, the
+// others for the overworld; the other dimensions of the vanilla layout
+// belong to maps that get no markers (see mapWorld).
+func worldRegionFiles(serverDir string) ([]regionFile, error) {
+	var files []regionFile
+	levels, err := filepath.Glob(filepath.Join(serverDir, "*", "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range levels {
+		world := filepath.Base(filepath.Dir(level))
+		dimension := "minecraft:overworld"
+		switch {
+		case strings.HasSuffix(world, "_nether"):
+			dimension = "minecraft:the_nether"
+		case strings.HasSuffix(world, "_the_end"):
+			dimension = "minecraft:the_end"
+		}
+		dir, err := mca.RegionDir(filepath.Dir(level), dimension)
+		if err != nil {
+			continue // nothing generated in the dimension yet
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, "r.*.mca"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			files = append(files, regionFile{world, p})
+		}
+	}
+	return files, nil
+}
+
+// forEachRegionFile calls fn for each of files on one goroutine per CPU and
+// returns the first error, naming the file. fn must be safe for concurrent
+// use.
+func forEachRegionFile(files []regionFile, fn func(rf regionFile) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	ch := make(chan regionFile)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rf := range ch {
+				if err := fn(rf); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%s: %w", rf.path, err)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, f := range files {
+		ch <- f
+	}
+	close(ch)
+	wg.Wait()
+	return firstErr
+}
+
+// foundSign is a sign found in a chunk.
+type foundSign struct {
+	x, y, z int
+	lines   []string
+}
+
+func (signs) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	prefix := opts.SignPrefix
+	if prefix == "" {
+		prefix = DefaultSignPrefix
+	}
+	needle := []byte(strings.ToLower(prefix))
+
+	files, err := worldRegionFiles(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	found := make(map[string][]foundSign)
+	err = forEachRegionFile(files, func(rf regionFile) error {
+		var local []foundSign
+		err := mca.ForEachChunk(rf.path, func(_, _ int, nbt []byte) error {
+			// Most chunks hold no matching sign; skip decoding them.
+			if !bytes.Contains(bytes.ToLower(nbt), needle) {
+				return nil
+			}
+			local = append(local, chunkSigns(nbt, prefix)...)
+			return nil
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		found[rf.world] = append(found[rf.world], local...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	markers := make(map[string][]Marker)
+	for world, list := range found {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			if a.z != b.z {
+				return a.z < b.z
+			}
+			return a.y < b.y
+		})
+		for _, s := range list {
+			label := strings.TrimSpace(strings.Join(s.lines, " "))
+			if label == "" {
+				label = "Sign"
+			}
+			markers[world] = append(markers[world], Marker{
+				ID:       fmt.Sprintf("signs-%d_%d_%d", s.x, s.y, s.z),
+				Label:    label,
+				Detail:   detail(label, [2]string{"Position", fmt.Sprintf("%d, %d, %d", s.x, s.y, s.z)}),
+				Position: Position{X: float64(s.x) + 0.5, Y: float64(s.y) + 0.5, Z: float64(s.z) + 0.5},
+			})
+		}
+	}
+	return markers, nil
+}
+
+// chunkSigns returns the signs in a chunk's block entities whose front text
+// starts with prefix (ignoring case), with the prefix removed and empty lines
+// dropped. Chunks that fail to decode yield nothing.
+func chunkSigns(nbt []byte, prefix string) []foundSign {
+	doc, err := parseNBT(nbt)
+	if err != nil {
+		return nil
+	}
+	entities, ok := doc["block_entities"].([]any) // 1.18+
+	if !ok {
+		level, _ := doc["Level"].(map[string]any)
+		entities, _ = level["TileEntities"].([]any)
+	}
+
+	var out []foundSign
+	for _, e := range entities {
+		be, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		lines := signLines(be)
+		if len(lines) == 0 {
+			continue
+		}
+		first := strings.TrimSpace(lines[0])
+		if len(first) < len(prefix) || !strings.EqualFold(first[:len(prefix)], prefix) {
+			continue
+		}
+		lines[0] = first[len(prefix):]
+
+		s := foundSign{x: nbtInt(be["x"]), y: nbtInt(be["y"]), z: nbtInt(be["z"])}
+		for _, l := range lines {
+			if l = strings.TrimSpace(l); l != "" {
+				s.lines = append(s.lines, l)
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// signLines returns the front text of a sign block entity, or nil for other
+// block entities. Since 1.20 the text is in front_text.messages; before, it
+// was in Text1–Text4.
+func signLines(be map[string]any) []string {
+	if front, ok := be["front_text"].(map[string]any); ok {
+		messages, _ := front["messages"].([]any)
+		lines := make([]string, len(messages))
+		for i, m := range messages {
+			lines[i] = componentText(m)
+		}
+		return lines
+	}
+	if _, ok := be["Text1"]; !ok {
+		return nil
+	}
+	lines := make([]string, 4)
+	for i := range lines {
+		lines[i] = componentText(be[fmt.Sprintf("Text%d", i+1)])
+	}
+	return lines
+}
+
+// componentText returns the plain text of a chat component, stored as a JSON
+// string before 1.21.5 and as NBT (a string or compound) since.
+func componentText(v any) string {
+	switch v := v.(type) {
+	case string:
+		t := strings.TrimSpace(v)
+		if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") || strings.HasPrefix(t, `"`) {
+			var parsed any
+			if err := json.Unmarshal([]byte(t), &parsed); err == nil {
+				return componentText(parsed)
+			}
+		}
+		return v
+	case []any:
+		var sb strings.Builder
+		for _, part := range v {
+			sb.WriteString(componentText(part))
+		}
+		return sb.String()
+	case map[string]any:
+		text := componentText(v["text"])
+		if extra, ok := v["extra"].([]any); ok {
+			text += componentText(extra)
+		}
+		return text
+	}
+	return ""
+}
+
+// nbtInt converts an NBT integer of any width to int.
+func nbtInt(v any) int {
+	switch v := v.(type) {
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,220 @@
+package markers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// StaticFileName is the file, next to config.toml, that defines hand-placed
+// marker sets: points of interest, lines and areas with their coordinates.
+const StaticFileName = "markers.toml"
+
+// Marker types accepted in markers.toml.
+const (
+	TypePOI  = "poi"  // An icon at position (default)
+	TypeLine = "line" // A line through points [x, y, z]
+	TypeArea = "area" // A filled polygon through points [x, z] at height y
+)
+
+var staticColor = Color{R: 0, G: 120, B: 255, A: 1}
+
+// StaticSet is a marker set defined in markers.toml.
+type StaticSet struct {
+	ID      string
+	Label   string
+	Maps    []string // map IDs (config/maps/<id>.conf) the set is shown on
+	Hidden  bool     // hidden until toggled on in the web app
+	Markers []Marker
+}
+
+// staticFile is the content of markers.toml.
+type staticFile struct {
+	Sets map[string]staticSetConfig `toml:"sets"`
+}
+
+type staticSetConfig struct {
+	Label         string               `toml:"label"`          // Marker set label; default the set ID
+	Maps          []string             `toml:"maps"`           // Map IDs the set is shown on
+	DefaultHidden bool                 `toml:"default_hidden"` // Hide the set until toggled on
+	Markers       []staticMarkerConfig `toml:"markers"`
+}
+
+type staticMarkerConfig struct {
+	ID       string      `toml:"id"`       // Marker ID; default "<set>-<n>"
+	Type     string      `toml:"type"`     // "poi" (default) | "line" | "area"
+	Label    string      `toml:"label"`    // Required
+	Detail   string      `toml:"detail"`   // HTML shown on click; default the label in bold
+	Position []float64   `toml:"position"` // poi: [x, y, z]
+	Points   [][]float64 `toml:"points"`   // line: [[x, y, z], ...]; area: [[x, z], ...]
+	Y        float64     `toml:"y"`        // area: height the shape is drawn at
+	Icon     string      `toml:"icon"`     // poi: image URL, or a path relative to the web root
+	Anchor   []int       `toml:"anchor"`   // poi: [x, y] pixel of the icon placed at position
+	Color    string      `toml:"color"`    // line, area: "#rrggbb" or "#rrggbbaa"
+}
+
+// staticColorRe matches the colors accepted in markers.toml.
+var staticColorRe = regexp.MustCompile(`^#([0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$`)
+
+// StaticPath returns the location of markers.toml in serverDir.
+func StaticPath(serverDir string) string {
+	return filepath.Join(serverDir, StaticFileName)
+}
+
+// LoadStatic reads markers.toml from serverDir, sorted by set ID. A missing
+// file yields no sets.
+func LoadStatic(serverDir string) ([]StaticSet, error) {
+	path := StaticPath(serverDir)
+	var f staticFile
+	md, err := toml.DecodeFile(path, &f)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+	}
+
+	ids := make([]string, 0, len(f.Sets))
+	for id := range f.Sets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sets := make([]StaticSet, 0, len(ids))
+	for _, id := range ids {
+		set, err := staticSetFrom(id, f.Sets[id])
+		if err != nil {
+			return nil, fmt.Errorf("%s: sets.%s: %w", path, id, err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+func staticSetFrom(id string, c staticSetConfig) (StaticSet, error) {
+	if _, ok := Lookup(id); ok {
+		return StaticSet{}, fmt.Errorf("set ID is used by the %s source", id)
+	}
+	if len(c.Maps) == 0 {
+		return StaticSet{}, fmt.Errorf("maps must list the maps the set is shown on")
+	}
+	set := StaticSet{ID: id, Label: c.Label, Maps: c.Maps, Hidden: c.DefaultHidden}
+	if set.Label == "" {
+		set.Label = id
+	}
+
+	seen := make(map[string]bool)
+	for i, mc := range c.Markers {
+		m, err := staticMarker(mc)
+		if err != nil {
+			return StaticSet{}, fmt.Errorf("marker %d: %w", i+1, err)
+		}
+		m.ID = mc.ID
+		if m.ID == "" {
+			m.ID = id + "-" + strconv.Itoa(i+1)
+		}
+		if seen[m.ID] {
+			return StaticSet{}, fmt.Errorf("marker ID %q is used twice", m.ID)
+		}
+		seen[m.ID] = true
+		set.Markers = append(set.Markers, m)
+	}
+	return set, nil
+}
+
+// staticMarker converts one [[sets.<id>.markers]] entry.
+func staticMarker(c staticMarkerConfig) (Marker, error) {
+	if c.Label == "" {
+		return Marker{}, fmt.Errorf("label is required")
+	}
+	m := Marker{Label: c.Label, Detail: c.Detail, Color: staticColor}
+	if m.Detail == "" {
+		m.Detail = "<b>" + html.EscapeString(c.Label) + "</b>"
+	}
+	if c.Color != "" {
+		color, err := parseColor(c.Color)
+		if err != nil {
+			return Marker{}, err
+		}
+		m.Color = color
+	}
+
+	switch c.Type {
+	case "", TypePOI:
+		if len(c.Position) != 3 {
+			return Marker{}, fmt.Errorf("poi needs position = [x, y, z]")
+		}
+		if len(c.Points) > 0 {
+			return Marker{}, fmt.Errorf("poi takes position, not points")
+		}
+		m.Position = Position{X: c.Position[0], Y: c.Position[1], Z: c.Position[2]}
+		m.Icon = c.Icon
+		if c.Anchor != nil {
+			if len(c.Anchor) != 2 {
+				return Marker{}, fmt.Errorf("anchor must be [x, y]")
+			}
+			m.Anchor = &[2]int{c.Anchor[0], c.Anchor[1]}
+		}
+	case TypeLine:
+		if len(c.Points) < 2 {
+			return Marker{}, fmt.Errorf("line needs at least 2 points")
+		}
+		for _, p := range c.Points {
+			if len(p) != 3 {
+				return Marker{}, fmt.Errorf("line points must be [x, y, z]")
+			}
+			m.Line = append(m.Line, Position{X: p[0], Y: p[1], Z: p[2]})
+		}
+	case TypeArea:
+		if len(c.Points) < 3 {
+			return Marker{}, fmt.Errorf("area needs at least 3 points")
+		}
+		for _, p := range c.Points {
+			if len(p) != 2 {
+				return Marker{}, fmt.Errorf("area points must be [x, z]")
+			}
+			m.Shape = append(m.Shape, Point{X: p[0], Z: p[1]})
+		}
+		m.Y = c.Y
+	default:
+		return Marker{}, fmt.Errorf("type must be %q, %q or %q, got %q", TypePOI, TypeLine, TypeArea, c.Type)
+	}
+	if c.Type != "" && c.Type != TypePOI && (c.Icon != "" || c.Anchor != nil || c.Position != nil) {
+		return Marker{}, fmt.Errorf("%s takes points, not position, icon or anchor", c.Type)
+	}
+	return m, nil
+}
+
+// parseColor parses "#rrggbb" or "#rrggbbaa".
+func parseColor(s string) (Color, error) {
+	if !staticColorRe.MatchString(s) {
+		return Color{}, fmt.Errorf("color must be \"#rrggbb\" or \"#rrggbbaa\", got %q", s)
+	}
+	v, _ := strconv.ParseUint(s[1:], 16, 32)
+	if len(s) == 7 {
+		return Color{R: int(v >> 16 & 0xff), G: int(v >> 8 & 0xff), B: int(v & 0xff), A: 1}, nil
+	}
+	return Color{R: int(v >> 24 & 0xff), G: int(v >> 16 & 0xff), B: int(v >> 8 & 0xff), A: float64(v&0xff) / 255}, nil
+}
+
+// staticSource names a markers.toml set in a MarkerSet. It reads nothing
+// from the backup; LoadStatic builds its markers.
+type staticSource struct {
+	id, label string
+}
+
+func (s staticSource) ID() string      { return s.id }
+func (s staticSource) Label() string   { return s.label }
+func (s staticSource) Paths() []string { return nil }
+
+func (s staticSource) Load(string, Options) (map[string][]Marker, error) { return nil, nil }
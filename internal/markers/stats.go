@@ -0,0 +1,177 @@
+package markers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stats reads the vanilla player statistics stored in the main world
+// folder, <world>/stats/<uuid>.json, and places a POI at the world spawn
+// whose popup holds leaderboards of play time, distance traveled, deaths and
+// kills. The world folders are extracted anyway, so the source needs no extra
+// backup paths.
+type stats struct{}
+
+func (stats) ID() string      { return "stats" }
+func (stats) Label() string   { return "Leaderboards" }
+func (stats) Paths() []string { return nil }
+
+// statsTop is the number of players listed per leaderboard.
+const statsTop = 10
+
+// statBoard is a leaderboard: a value computed from a player's
+// minecraft:custom statistics and how it is shown.
+type statBoard struct {
+	title  string
+	value  func(custom map[string]int64) int64
+	format func(int64) string
+}
+
+var statBoards = []statBoard{
+	{"Play Time", func(c map[string]int64) int64 {
+		// play_one_minute counted ticks too; 1.17 renamed it.
+		return max(c["minecraft:play_time"], c["minecraft:play_one_minute"])
+	}, formatTicks},
+	{"Distance Traveled", func(c map[string]int64) int64 {
+		var cm int64
+		for k, v := range c {
+			if strings.HasSuffix(k, "_one_cm") {
+				cm += v
+			}
+		}
+		return cm
+	}, func(cm int64) string { return fmt.Sprintf("%.1f km", float64(cm)/100_000) }},
+	{"Deaths", func(c map[string]int64) int64 { return c["minecraft:deaths"] }, formatCount},
+	{"Mob Kills", func(c map[string]int64) int64 { return c["minecraft:mob_kills"] }, formatCount},
+	{"Player Kills", func(c map[string]int64) int64 { return c["minecraft:player_kills"] }, formatCount},
+}
+
+func formatCount(n int64) string { return strconv.FormatInt(n, 10) }
+
+func (stats) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	dirs, err := filepath.Glob(filepath.Join(serverDir, "*", "stats"))
+	if err != nil {
+		return nil, err
+	}
+	markers := make(map[string][]Marker)
+	for _, dir := range dirs {
+		worldDir := filepath.Dir(dir)
+		if _, err := os.Stat(filepath.Join(worldDir, "level.dat")); err != nil {
+			continue
+		}
+		custom, err := loadStats(dir)
+		if err != nil {
+			return nil, err
+		}
+		boards := statsDetail(custom, opts.Names)
+		if boards == "" {
+			continue
+		}
+		spawn, err := worldSpawn(filepath.Join(worldDir, "level.dat"))
+		if err != nil {
+			return nil, err
+		}
+		world := filepath.Base(worldDir)
+		markers[world] = append(markers[world], Marker{
+			ID:       "stats-leaderboards",
+			Label:    "Leaderboards",
+			Detail:   "<b>Leaderboards</b>" + boards,
+			Position: spawn,
+		})
+	}
+	return markers, nil
+}
+
+// loadStats reads the minecraft:custom statistics of every player in dir,
+// keyed by lower-case UUID. Files from before 1.13, which use another
+// layout, have no such statistics and are left out.
+func loadStats(dir string) (map[string]map[string]int64, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	custom := make(map[string]map[string]int64, len(files))
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var doc struct {
+			Stats map[string]map[string]int64 `json:"stats"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("decoding stats/%s: %w", filepath.Base(path), err)
+		}
+		if c := doc.Stats["minecraft:custom"]; len(c) > 0 {
+			custom[strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))] = c
+		}
+	}
+	return custom, nil
+}
+
+// statsDetail formats the top players of each of statBoards as popup HTML,
+// leaving out boards on which nobody scored.
+func statsDetail(custom map[string]map[string]int64, names map[string]string) string {
+	type entry struct {
+		name  string
+		value int64
+	}
+	var sb strings.Builder
+	for _, b := range statBoards {
+		var entries []entry
+		for uuid, c := range custom {
+			if v := b.value(c); v > 0 {
+				entries = append(entries, entry{playerNames([]string{uuid}, names)[0], v})
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].value != entries[j].value {
+				return entries[i].value > entries[j].value
+			}
+			return strings.ToLower(entries[i].name) < strings.ToLower(entries[j].name)
+		})
+		sb.WriteString("<br><br><b>" + html.EscapeString(b.title) + "</b>")
+		for i, e := range entries[:min(len(entries), statsTop)] {
+			sb.WriteString(fmt.Sprintf("<br>%d. %s: %s", i+1, html.EscapeString(e.name), html.EscapeString(b.format(e.value))))
+		}
+	}
+	return sb.String()
+}
+
+// worldSpawn reads the world spawn from level.dat: Data.spawn.pos since
+// 1.21.9, Data.SpawnX/Y/Z before. Without either it is 0, 64, 0.
+func worldSpawn(path string) (Position, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Position{}, err
+	}
+	if len(data) == 0 {
+		return Position{Y: 64}, nil
+	}
+	doc, err := parseNBT(data)
+	if err != nil {
+		return Position{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	level, _ := doc["Data"].(map[string]any)
+	if spawn, ok := level["spawn"].(map[string]any); ok {
+		if pos, ok := spawn["pos"].([]int32); ok && len(pos) == 3 {
+			return Position{X: float64(pos[0]) + 0.5, Y: float64(pos[1]), Z: float64(pos[2]) + 0.5}, nil
+		}
+	}
+	x, okX := level["SpawnX"].(int32)
+	y, _ := level["SpawnY"].(int32)
+	z, okZ := level["SpawnZ"].(int32)
+	if !okX || !okZ {
+		return Position{Y: 64}, nil
+	}
+	return Position{X: float64(x) + 0.5, Y: float64(y), Z: float64(z) + 0.5}, nil
+}
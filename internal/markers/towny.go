@@ -0,0 +1,173 @@
+package markers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// towny reads Towny's flatfile database: town blocks from
+// plugins/Towny/data/townblocks/<world>/<x>_<z>_<size>.data and town details
+// from plugins/Towny/data/towns/<name>.txt. Each town is drawn as the outline
+// of its claimed town blocks, one marker per connected area.
+type towny struct{}
+
+func (towny) ID() string    { return "towny" }
+func (towny) Label() string { return "Towns" }
+func (towny) Paths() []string {
+	return []string{"plugins/Towny/data/townblocks", "plugins/Towny/data/towns"}
+}
+
+// townyPalette gives neighboring towns distinguishable colors.
+var townyPalette = []Color{
+	{R: 46, G: 204, B: 113, A: 1},
+	{R: 52, G: 152, B: 219, A: 1},
+	{R: 155, G: 89, B: 182, A: 1},
+	{R: 241, G: 196, B: 15, A: 1},
+	{R: 230, G: 126, B: 34, A: 1},
+	{R: 231, G: 76, B: 60, A: 1},
+	{R: 26, G: 188, B: 156, A: 1},
+	{R: 236, G: 240, B: 241, A: 1},
+}
+
+func (towny) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	dataDir := filepath.Join(serverDir, "plugins", "Towny", "data")
+	towns, err := loadTowns(filepath.Join(dataDir, "towns"))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := filepath.Glob(filepath.Join(dataDir, "townblocks", "*", "*.data"))
+	if err != nil {
+		return nil, err
+	}
+
+	// world → town → claimed cells, with the town block size per world.
+	claims := make(map[string]map[string]map[[2]int]bool)
+	sizes := make(map[string]int)
+	for _, path := range blocks {
+		world := filepath.Base(filepath.Dir(path))
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".data"), "_")
+		if len(parts) != 3 {
+			continue
+		}
+		x, err1 := strconv.Atoi(parts[0])
+		z, err2 := strconv.Atoi(parts[1])
+		size, err3 := strconv.Atoi(parts[2])
+		if firstErr(err1, err2, err3) != nil || size <= 0 {
+			continue
+		}
+		props, err := readProperties(path)
+		if err != nil {
+			return nil, err
+		}
+		town := props["town"]
+		if t, ok := towns[strings.ToLower(town)]; ok {
+			town = t["name"]
+		}
+		if town == "" {
+			continue
+		}
+		if claims[world] == nil {
+			claims[world] = make(map[string]map[[2]int]bool)
+		}
+		if claims[world][town] == nil {
+			claims[world][town] = make(map[[2]int]bool)
+		}
+		claims[world][town][[2]int{x, z}] = true
+		sizes[world] = size
+	}
+
+	areas := make(map[string][]Marker)
+	for world, byTown := range claims {
+		townNames := make([]string, 0, len(byTown))
+		for t := range byTown {
+			townNames = append(townNames, t)
+		}
+		sort.Strings(townNames)
+
+		for _, town := range townNames {
+			props := towns[strings.ToLower(town)]
+			cells := byTown[town]
+			text := detail(town,
+				[2]string{"Mayor", playerNames([]string{props["mayor"]}, opts.Names)[0]},
+				[2]string{"Nation", props["nation"]},
+				[2]string{"Town blocks", strconv.Itoa(len(cells))},
+				[2]string{"Board", props["board"]},
+			)
+			color := townyPalette[hashIndex(town, len(townyPalette))]
+			for i, poly := range outline(cells, float64(sizes[world])) {
+				areas[world] = append(areas[world], Marker{
+					ID:     fmt.Sprintf("towny-%s-%d", town, i),
+					Label:  town,
+					Detail: text,
+					Shape:  poly.Shape,
+					Holes:  poly.Holes,
+					Y:      64,
+					Color:  color,
+				})
+			}
+		}
+	}
+	return areas, nil
+}
+
+// loadTowns reads the town files, keyed by lower-case name and, when
+// present, UUID, since newer Towny versions reference towns by UUID.
+func loadTowns(dir string) (map[string]map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	towns := make(map[string]map[string]string)
+	for _, path := range files {
+		props, err := readProperties(path)
+		if err != nil {
+			return nil, err
+		}
+		if props["name"] == "" {
+			props["name"] = strings.TrimSuffix(filepath.Base(path), ".txt")
+		}
+		towns[strings.ToLower(props["name"])] = props
+		if id := props["uuid"]; id != "" {
+			towns[strings.ToLower(id)] = props
+		}
+	}
+	return towns, nil
+}
+
+// readProperties reads a Java-style key=value file.
+func readProperties(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return props, nil
+}
+
+func hashIndex(s string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,162 @@
+package markers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// worldGuard reads WorldGuard region definitions from
+// plugins/WorldGuard/worlds/<world>/regions.yml (the YAML storage backend).
+// Cuboid and polygon regions are drawn; the __global__ region is not.
+type worldGuard struct{}
+
+func (worldGuard) ID() string      { return "worldguard" }
+func (worldGuard) Label() string   { return "WorldGuard Regions" }
+func (worldGuard) Paths() []string { return []string{"plugins/WorldGuard/worlds"} }
+
+var worldGuardColor = Color{R: 255, G: 140, B: 0, A: 1}
+
+func (worldGuard) Load(serverDir string, opts Options) (map[string][]Marker, error) {
+	files, err := filepath.Glob(filepath.Join(serverDir, "plugins", "WorldGuard", "worlds", "*", "regions.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	areas := make(map[string][]Marker)
+	for _, file := range files {
+		world := filepath.Base(filepath.Dir(file))
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", world, err)
+		}
+		regions, _ := field(doc, "regions").(map[string]any)
+
+		ids := make([]string, 0, len(regions))
+		for id := range regions {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			area, ok, err := worldGuardArea(id, regions[id], opts.Names)
+			if err != nil {
+				return nil, fmt.Errorf("%s: region %q: %w", world, id, err)
+			}
+			if ok {
+				areas[world] = append(areas[world], area)
+			}
+		}
+	}
+	return areas, nil
+}
+
+func worldGuardArea(id string, region any, names map[string]string) (Marker, bool, error) {
+	area := Marker{
+		ID:    "worldguard-" + id,
+		Label: id,
+		Color: worldGuardColor,
+		Detail: detail(id,
+			[2]string{"Owners", strings.Join(domainMembers(field(region, "owners"), names), ", ")},
+			[2]string{"Members", strings.Join(domainMembers(field(region, "members"), names), ", ")},
+			[2]string{"Priority", str(field(region, "priority"))},
+		),
+	}
+
+	switch typ := str(field(region, "type")); typ {
+	case "cuboid":
+		lo, hi := field(region, "min"), field(region, "max")
+		x1, err1 := num(field(lo, "x"))
+		z1, err2 := num(field(lo, "z"))
+		x2, err3 := num(field(hi, "x"))
+		z2, err4 := num(field(hi, "z"))
+		y, err5 := num(field(lo, "y"))
+		if err := firstErr(err1, err2, err3, err4, err5); err != nil {
+			return Marker{}, false, err
+		}
+		area.Shape = rect(x1, z1, x2, z2)
+		area.Y = y
+	case "poly2d":
+		points, _ := field(region, "points").([]any)
+		if len(points) < 3 {
+			return Marker{}, false, fmt.Errorf("polygon with %d points", len(points))
+		}
+		for _, p := range points {
+			x, err1 := num(field(p, "x"))
+			z, err2 := num(field(p, "z"))
+			if err := firstErr(err1, err2); err != nil {
+				return Marker{}, false, err
+			}
+			area.Shape = append(area.Shape, Point{X: x, Z: z})
+		}
+		y, err := num(field(region, "min-y"))
+		if err != nil {
+			return Marker{}, false, err
+		}
+		area.Y = y
+	case "global":
+		return Marker{}, false, nil
+	default:
+		return Marker{}, false, fmt.Errorf("unsupported region type %q", typ)
+	}
+	return area, true, nil
+}
+
+// domainMembers lists the players and groups of a WorldGuard owners/members
+// domain, resolving UUIDs to names where known.
+func domainMembers(domain any, names map[string]string) []string {
+	var out []string
+	for _, key := range []string{"players", "unique-ids"} {
+		list, _ := field(domain, key).([]any)
+		var values []string
+		for _, v := range list {
+			values = append(values, str(v))
+		}
+		if key == "unique-ids" {
+			values = playerNames(values, names)
+		}
+		out = append(out, values...)
+	}
+	groups, _ := field(domain, "groups").([]any)
+	for _, g := range groups {
+		out = append(out, "g:"+str(g))
+	}
+	return out
+}
+
+// field returns m[key] when v is a mapping.
+func field(v any, key string) any {
+	m, _ := v.(map[string]any)
+	return m[key]
+}
+
+// str returns a scalar as a string, or "" for anything else.
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// num parses a numeric scalar.
+func num(v any) (float64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %v", v)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
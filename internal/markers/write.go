@@ -0,0 +1,212 @@
+package markers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteJSON merges the generated marker sets into each map's
+// web/maps/<id>/live/markers.json, replacing sets from an earlier run and
+// keeping any others (e.g. marker-sets from the map config, which BlueMap
+// writes there during the render). Maps that were not rendered are skipped.
+// It returns the IDs of the maps written.
+func WriteJSON(serverDir string, results []MapResult) ([]string, error) {
+	var written []string
+	for _, res := range results {
+		if len(res.Sets) == 0 {
+			continue
+		}
+		mapDir := filepath.Join(serverDir, "web", "maps", res.MapID)
+		if _, err := os.Stat(mapDir); err != nil {
+			continue
+		}
+		path := filepath.Join(mapDir, "live", "markers.json")
+
+		sets := make(map[string]json.RawMessage)
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &sets); err != nil {
+				return written, fmt.Errorf("decoding %s: %w", path, err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return written, err
+		}
+
+		for _, set := range res.Sets {
+			delete(sets, set.Source.ID())
+			if len(set.Markers) == 0 {
+				continue
+			}
+			raw, err := json.Marshal(setData(set, camelCase))
+			if err != nil {
+				return written, err
+			}
+			sets[set.Source.ID()] = raw
+		}
+
+		out, err := json.Marshal(sets)
+		if err != nil {
+			return written, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, res.MapID)
+	}
+	return written, nil
+}
+
+// WriteHOCON writes a marker-sets block per map to
+// bluemap-markers/<id>.conf, for inclusion in the map config so BlueMap
+// stores the markers itself. Files from an earlier run are removed first. It
+// returns the IDs of the maps written.
+func WriteHOCON(serverDir string, results []MapResult) ([]string, error) {
+	dir := filepath.Join(serverDir, HOCONDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, res := range results {
+		if res.Count() == 0 {
+			continue
+		}
+		sets := make(map[string]any)
+		for _, set := range res.Sets {
+			if len(set.Markers) > 0 {
+				sets[set.Source.ID()] = setData(set, dashCase)
+			}
+		}
+		// HOCON is a superset of JSON, so the sets are written as JSON.
+		body, err := json.MarshalIndent(sets, "", "  ")
+		if err != nil {
+			return written, err
+		}
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# Generated by bluemap-action from plugin data and markers.toml; do not edit.\n")
+		fmt.Fprintf(&buf, "# Include it in config/maps/%s.conf.\n", res.MapID)
+		buf.WriteString("marker-sets: ")
+		buf.Write(body)
+		buf.WriteString("\n")
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, res.MapID+".conf"), buf.Bytes(), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, res.MapID)
+	}
+	return written, nil
+}
+
+// BlueMap's markers.json uses camelCase keys; its HOCON map configs use
+// dash-separated ones.
+func camelCase(key string) string { return key }
+
+func dashCase(key string) string {
+	var sb strings.Builder
+	for _, r := range key {
+		if r >= 'A' && r <= 'Z' {
+			sb.WriteByte('-')
+			r += 'a' - 'A'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// setData builds a BlueMap marker set with a shape or POI marker per marker.
+func setData(set MarkerSet, key func(string) string) map[string]any {
+	markers := make(map[string]any, len(set.Markers))
+	for _, a := range set.Markers {
+		var m map[string]any
+		switch {
+		case len(a.Line) > 0:
+			m = map[string]any{
+				"type":      "line",
+				"label":     a.Label,
+				"detail":    a.Detail,
+				"position":  a.Line[0],
+				"line":      a.Line,
+				"depthTest": false,
+				"lineWidth": 2,
+				"lineColor": a.Color,
+			}
+		case len(a.Shape) == 0:
+			m = map[string]any{
+				"type":     "poi",
+				"label":    a.Label,
+				"detail":   a.Detail,
+				"position": a.Position,
+			}
+			if a.Icon != "" {
+				// Without an icon BlueMap uses its default POI pin.
+				anchor := [2]int{headSize / 2, headSize / 2}
+				if a.Anchor != nil {
+					anchor = *a.Anchor
+				}
+				m["icon"] = a.Icon
+				m["anchor"] = map[string]int{"x": anchor[0], "y": anchor[1]}
+			}
+		default:
+			fill := a.Color
+			fill.A = 0.25
+			m = map[string]any{
+				"type":   "shape",
+				"label":  a.Label,
+				"detail": a.Detail,
+				"position": Position{
+					X: center(a.Shape, func(p Point) float64 { return p.X }),
+					Y: a.Y,
+					Z: center(a.Shape, func(p Point) float64 { return p.Z }),
+				},
+				"shape":     a.Shape,
+				"shapeY":    a.Y,
+				"depthTest": false,
+				"lineWidth": 2,
+				"lineColor": a.Color,
+				"fillColor": fill,
+			}
+			if a.Fill {
+				m["lineWidth"] = 0
+				m["fillColor"] = a.Color
+			}
+			if len(a.Holes) > 0 {
+				m["holes"] = a.Holes
+			}
+		}
+		data := make(map[string]any, len(m))
+		for k, v := range m {
+			data[key(k)] = v
+		}
+		markers[a.ID] = data
+	}
+	return map[string]any{
+		"label":              set.Source.Label(),
+		"toggleable":         true,
+		key("defaultHidden"): set.Hidden,
+		"markers":            markers,
+	}
+}
+
+// center returns the middle of a shape's bounding box along one axis.
+func center(points []Point, axis func(Point) float64) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	lo, hi := axis(points[0]), axis(points[0])
+	for _, p := range points[1:] {
+		lo, hi = min(lo, axis(p)), max(hi, axis(p))
+	}
+	return (lo + hi) / 2
+}
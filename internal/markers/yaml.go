@@ -0,0 +1,316 @@
+package markers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes the subset of YAML written by Bukkit plugins (SnakeYAML
+// block style): nested mappings, block sequences, single-line flow
+// collections, plain and quoted scalars, and comments. Mappings decode to
+// map[string]any, sequences to []any and scalars to string; anchors, tags and
+// multi-line scalars are not supported.
+func parseYAML(data []byte) (any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := stripComment(raw)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" || trimmed == "..." {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tab indentation", i+1)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		lines = append(lines, yamlLine{num: i + 1, indent: indent, text: strings.TrimRight(trimmed, " ")})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence starting at the current line, whose
+// entries are all at the given indentation.
+func (p *yamlParser) block(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isSeqItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		key, rest, ok := splitKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		p.pos++
+		v, err := p.value(rest, indent, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	var s []any
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isSeqItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+			}
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if _, _, isMap := splitKey(rest); isMap && !strings.HasPrefix(rest, "{") && !strings.HasPrefix(rest, "[") {
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.text) - len(rest), text: rest}
+			m, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, m)
+			continue
+		}
+		p.pos++
+		v, err := p.value(rest, indent, false)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// value parses the value after "key:" or "-". An empty value is either a
+// nested block on the following lines or null. A mapping value may also be a
+// sequence at the mapping's own indentation, as SnakeYAML writes them.
+func (p *yamlParser) value(rest string, indent int, inMapping bool) (any, error) {
+	if rest != "" {
+		return parseFlow(rest, p.lines[p.pos-1].num)
+	}
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	switch {
+	case next.indent > indent:
+		return p.block(next.indent)
+	case inMapping && next.indent == indent && isSeqItem(next.text):
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey splits "key: value" at the first colon outside quotes that is
+// followed by a space or the end of the line.
+func splitKey(text string) (key, rest string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return unquote(strings.TrimSpace(text[:i])), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripComment removes a "#" comment that starts a line or follows a space,
+// outside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// parseFlow parses a scalar or a single-line flow collection.
+func parseFlow(s string, line int) (any, error) {
+	f := &flowParser{s: s}
+	v, err := f.value()
+	if err == nil {
+		f.skipSpace()
+		if f.pos < len(f.s) {
+			err = fmt.Errorf("unexpected %q", f.s[f.pos:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	return v, nil
+}
+
+type flowParser struct {
+	s   string
+	pos int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.pos] {
+	case '{':
+		return f.collection('}')
+	case '[':
+		return f.collection(']')
+	}
+	return f.scalar()
+}
+
+// collection parses a flow mapping (end '}') or sequence (end ']').
+func (f *flowParser) collection(end byte) (any, error) {
+	f.pos++ // opening bracket
+	m := make(map[string]any)
+	var s []any
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.s) {
+			return nil, fmt.Errorf("missing %q", end)
+		}
+		if f.s[f.pos] == end {
+			f.pos++
+			break
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		if end == '}' {
+			if f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, fmt.Errorf("expected \":\" in flow mapping")
+			}
+			f.pos++
+			val, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(v)] = val
+		} else {
+			s = append(s, v)
+		}
+		f.skipSpace()
+		if f.pos < len(f.s) && f.s[f.pos] == ',' {
+			f.pos++
+		}
+	}
+	if end == '}' {
+		return m, nil
+	}
+	return s, nil
+}
+
+func (f *flowParser) scalar() (any, error) {
+	start := f.pos
+	if q := f.s[f.pos]; q == '"' || q == '\'' {
+		for f.pos++; f.pos < len(f.s); f.pos++ {
+			if f.s[f.pos] == '\\' && q == '"' {
+				f.pos++
+				continue
+			}
+			if f.s[f.pos] == q {
+				if q == '\'' && f.pos+1 < len(f.s) && f.s[f.pos+1] == '\'' {
+					f.pos++
+					continue
+				}
+				f.pos++
+				return unquote(f.s[start:f.pos]), nil
+			}
+		}
+		return nil, fmt.Errorf("unterminated string")
+	}
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if c == ',' || c == '}' || c == ']' || (c == ':' && (f.pos+1 == len(f.s) || f.s[f.pos+1] == ' ')) {
+			break
+		}
+		f.pos++
+	}
+	v := strings.TrimSpace(f.s[start:f.pos])
+	if v == "~" || v == "null" {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// unquote removes YAML single or double quotes from a scalar.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
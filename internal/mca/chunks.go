@@ -0,0 +1,346 @@
+package mca
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InhabitedUnknown marks a chunk whose InhabitedTime could not be read, e.g.
+// because it uses LZ4 or external (.mcc) storage.
+const InhabitedUnknown = -1
+
+var regionNameRe = regexp.MustCompile(`^r\.(-?\d+)\.(-?\d+)\.mca$`)
+
+// Chunk is a chunk present in a region file.
+type Chunk struct {
+	X, Z      int       // absolute chunk coordinates
+	Inhabited int64     // InhabitedTime in ticks, or InhabitedUnknown
+	Modified  time.Time // last save time from the region header; zero if unset
+}
+
+// ReadChunks lists the chunks stored in a region file named r.X.Z.mca. With
+// inhabited set, every chunk is decompressed to read its InhabitedTime;
+// otherwise only the header is read and Inhabited is InhabitedUnknown.
+// Chunks with broken headers are left out (see CheckFile).
+func ReadChunks(path string, inhabited bool) ([]Chunk, error) {
+	m := regionNameRe.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return nil, fmt.Errorf("%s: not a region file name", filepath.Base(path))
+	}
+	rx, _ := strconv.Atoi(m[1])
+	rz, _ := strconv.Atoi(m[2])
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < headerSize {
+		return nil, nil
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, err
+	}
+
+	var found []Chunk
+	for i := 0; i < chunks; i++ {
+		loc := binary.BigEndian.Uint32(header[i*4:])
+		offset, count := int64(loc>>8), int64(loc&0xff)
+		if loc == 0 || offset < 2 || offset*sectorSize >= info.Size() {
+			continue
+		}
+		c := Chunk{X: rx*32 + i%32, Z: rz*32 + i/32, Inhabited: InhabitedUnknown}
+		if ts := binary.BigEndian.Uint32(header[sectorSize+i*4:]); ts != 0 {
+			c.Modified = time.Unix(int64(ts), 0).UTC()
+		}
+		if inhabited {
+			if t, err := readInhabitedTime(f, offset, count); err == nil {
+				c.Inhabited = t
+			}
+		}
+		found = append(found, c)
+	}
+	return found, nil
+}
+
+// RegionDir returns the region folder of a dimension, such as
+// "minecraft:the_nether", in the world folder: the unified layout of
+// Minecraft 26.1+ (dimensions/<namespace>/<name>/region) first, then the
+// legacy region, DIM-1/region and DIM1/region.
+func RegionDir(world, dimension string) (string, error) {
+	ns, dim, ok := strings.Cut(dimension, ":")
+	if !ok {
+		ns, dim = "minecraft", dimension
+	}
+	candidates := []string{filepath.Join(world, "dimensions", ns, dim, "region")}
+	if ns == "minecraft" {
+		switch dim {
+		case "overworld":
+			candidates = append(candidates, filepath.Join(world, "region"))
+		case "the_nether":
+			candidates = append(candidates, filepath.Join(world, "DIM-1", "region"))
+		case "the_end":
+			candidates = append(candidates, filepath.Join(world, "DIM1", "region"))
+		}
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && info.IsDir() {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("region folder for %s in %s not found", dimension, world)
+}
+
+// ForEachChunk decompresses every chunk of a region file and calls fn with
+// its absolute chunk coordinates and uncompressed NBT. Chunks that cannot be
+// read, such as LZ4-compressed or externally stored (.mcc) ones, are skipped.
+func ForEachChunk(path string, fn func(x, z int, nbt []byte) error) error {
+	chunkList, err := ReadChunks(path, false)
+	if err != nil || len(chunkList) == 0 {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, sectorSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return err
+	}
+	for _, c := range chunkList {
+		i := (c.Z&31)*32 + c.X&31
+		loc := binary.BigEndian.Uint32(header[i*4:])
+		r, closeChunk, err := openChunk(f, int64(loc>>8), int64(loc&0xff))
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(r)
+		closeChunk()
+		if err != nil {
+			continue
+		}
+		if err := fn(c.X, c.Z, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openChunk returns a reader over the decompressed NBT of the chunk at the
+// given sector, and a function that releases the decompressor.
+func openChunk(f *os.File, offset, count int64) (io.Reader, func(), error) {
+	var prefix [5]byte
+	if _, err := f.ReadAt(prefix[:], offset*sectorSize); err != nil {
+		return nil, nil, err
+	}
+	length := int64(binary.BigEndian.Uint32(prefix[:4]))
+	if length == 0 || length+4 > count*sectorSize {
+		return nil, nil, errors.New("invalid chunk length")
+	}
+	data := io.NewSectionReader(f, offset*sectorSize+5, length-1)
+
+	switch prefix[4] {
+	case 1:
+		gz, err := gzip.NewReader(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close() }, nil
+	case 2:
+		zr, err := zlib.NewReader(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { zr.Close() }, nil
+	case 3:
+		return data, func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported compression type %d", prefix[4])
+}
+
+// readInhabitedTime decompresses the chunk at the given sector and scans its
+// NBT for InhabitedTime (top level since 1.18, under "Level" before).
+func readInhabitedTime(f *os.File, offset, count int64) (int64, error) {
+	r, closeChunk, err := openChunk(f, offset, count)
+	if err != nil {
+		return 0, err
+	}
+	defer closeChunk()
+
+	br := bufio.NewReader(r)
+	tag, err := br.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagCompound {
+		return 0, errors.New("chunk root is not a compound")
+	}
+	if err := skipString(br); err != nil {
+		return 0, err
+	}
+	return findInhabitedTime(br, 0)
+}
+
+// NBT tag types.
+const (
+	tagEnd       = 0
+	tagByte      = 1
+	tagShort     = 2
+	tagInt       = 3
+	tagLong      = 4
+	tagFloat     = 5
+	tagDouble    = 6
+	tagByteArray = 7
+	tagString    = 8
+	tagList      = 9
+	tagCompound  = 10
+	tagIntArray  = 11
+	tagLongArray = 12
+)
+
+var errNotFound = errors.New("InhabitedTime not found")
+
+// findInhabitedTime scans the entries of a compound for a long named
+// InhabitedTime, descending into "Level" (the pre-1.18 chunk layout). It
+// returns as soon as the value is found.
+func findInhabitedTime(r *bufio.Reader, depth int) (int64, error) {
+	for {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if tag == tagEnd {
+			return 0, errNotFound
+		}
+		name, err := readString(r)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case tag == tagLong && name == "InhabitedTime":
+			var v int64
+			err := binary.Read(r, binary.BigEndian, &v)
+			return v, err
+		case tag == tagCompound && name == "Level" && depth == 0:
+			if v, err := findInhabitedTime(r, depth+1); !errors.Is(err, errNotFound) {
+				return v, err
+			}
+		default:
+			if err := skipPayload(r, tag); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// skipPayload discards the payload of a tag of the given type.
+func skipPayload(r *bufio.Reader, tag byte) error {
+	switch tag {
+	case tagByte:
+		return discard(r, 1)
+	case tagShort:
+		return discard(r, 2)
+	case tagInt, tagFloat:
+		return discard(r, 4)
+	case tagLong, tagDouble:
+		return discard(r, 8)
+	case tagByteArray, tagIntArray, tagLongArray:
+		n, err := readInt32(r)
+		if err != nil {
+			return err
+		}
+		size := map[byte]int64{tagByteArray: 1, tagIntArray: 4, tagLongArray: 8}[tag]
+		return discard(r, int64(n)*size)
+	case tagString:
+		return skipString(r)
+	case tagList:
+		elem, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		n, err := readInt32(r)
+		if err != nil {
+			return err
+		}
+		for i := int32(0); i < n; i++ {
+			if err := skipPayload(r, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case tagCompound:
+		for {
+			t, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if t == tagEnd {
+				return nil
+			}
+			if err := skipString(r); err != nil {
+				return err
+			}
+			if err := skipPayload(r, t); err != nil {
+				return err
+			}
+		}
+	case tagEnd:
+		return nil
+	default:
+		return fmt.Errorf("unknown NBT tag %d", tag)
+	}
+}
+
+func readInt32(r io.Reader) (int32, error) {
+	var n int32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errors.New("negative NBT length")
+	}
+	return n, nil
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return string(b), err
+}
+
+func skipString(r *bufio.Reader) error {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return err
+	}
+	return discard(r, int64(n))
+}
+
+func discard(r *bufio.Reader, n int64) error {
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
@@ -0,0 +1,210 @@
+package mca
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Modes accepted for region_check in config.toml.
+const (
+	ModeReport     = "report"     // Report corrupt region files (default).
+	ModeQuarantine = "quarantine" // Report and move them out of the world.
+	ModeOff        = "off"        // Skip the scan.
+)
+
+// QuarantineDirName is the folder, next to config.toml, that corrupt region
+// files are moved into. It is outside web/, so it is never deployed.
+const QuarantineDirName = "bluemap-quarantine"
+
+const (
+	sectorSize = 4096
+	headerSize = 2 * sectorSize // chunk locations + timestamps
+	chunks     = 1024
+)
+
+// Valid chunk compression types. The high bit marks chunk data stored in an
+// external c.X.Z.mcc file.
+var compressionTypes = map[byte]bool{
+	1:   true, // gzip
+	2:   true, // zlib
+	3:   true, // uncompressed
+	4:   true, // LZ4 (1.20.5+)
+	127: true, // custom algorithm
+}
+
+// Corrupt describes a region file that failed validation.
+type Corrupt struct {
+	Path      string // relative to the scanned root
+	BadChunks int    // chunks with invalid headers (0 = the file header itself is bad)
+	Reason    string // first problem found
+}
+
+// CheckFile validates the header of a region file and the length prefix of
+// every chunk it references. It returns the number of bad chunks and the
+// first problem found; an empty reason means the file looks intact. Empty
+// files are valid: Minecraft creates them for regions without chunks.
+func CheckFile(path string) (badChunks int, reason string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, "", nil
+	}
+	if size < headerSize {
+		return 0, fmt.Sprintf("truncated header (%d bytes)", size), nil
+	}
+
+	header := make([]byte, sectorSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, "", err
+	}
+
+	sectors := (size + sectorSize - 1) / sectorSize
+	used := make([]bool, sectors)
+	var prefix [5]byte
+
+	fail := func(i int, format string, args ...any) {
+		badChunks++
+		if reason == "" {
+			reason = fmt.Sprintf("chunk %d,%d: ", i%32, i/32) + fmt.Sprintf(format, args...)
+		}
+	}
+
+	for i := 0; i < chunks; i++ {
+		loc := binary.BigEndian.Uint32(header[i*4:])
+		offset, count := int64(loc>>8), int64(loc&0xff)
+		if loc == 0 {
+			continue
+		}
+		if offset < 2 || count == 0 {
+			fail(i, "invalid location (sector %d, %d sectors)", offset, count)
+			continue
+		}
+		if offset+count > sectors {
+			fail(i, "sectors %d-%d past end of file (%d sectors)", offset, offset+count-1, sectors)
+			continue
+		}
+		overlap := false
+		for s := offset; s < offset+count; s++ {
+			overlap = overlap || used[s]
+			used[s] = true
+		}
+		if overlap {
+			fail(i, "overlaps another chunk at sector %d", offset)
+			continue
+		}
+
+		if _, err := f.ReadAt(prefix[:], offset*sectorSize); err != nil {
+			fail(i, "unreadable chunk header: %v", err)
+			continue
+		}
+		length := int64(binary.BigEndian.Uint32(prefix[:4]))
+		compression := prefix[4]
+		if !compressionTypes[compression&0x7f] {
+			fail(i, "unknown compression type %d", compression)
+			continue
+		}
+		switch {
+		case length == 0 || length+4 > count*sectorSize:
+			fail(i, "length %d does not fit in %d sectors", length, count)
+		case offset*sectorSize+4+length > size:
+			fail(i, "data truncated at end of file")
+		}
+	}
+	return badChunks, reason, nil
+}
+
+// Scan checks every .mca file in a "region" folder below the given roots
+// (relative to base) using the given number of workers (0 = number of CPUs),
+// and returns the corrupt ones sorted by path.
+func Scan(base string, roots []string, workers int) (checked int, corrupt []Corrupt, err error) {
+	var paths []string
+	for _, root := range roots {
+		dir := filepath.Join(base, root)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(path, ".mca") && filepath.Base(filepath.Dir(path)) == "region" {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	ch := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range ch {
+				bad, reason, err := CheckFile(path)
+				rel, _ := filepath.Rel(base, path)
+				mu.Lock()
+				switch {
+				case err != nil && firstErr == nil:
+					firstErr = fmt.Errorf("checking %s: %w", rel, err)
+				case err == nil && reason != "":
+					corrupt = append(corrupt, Corrupt{Path: rel, BadChunks: bad, Reason: reason})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range paths {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, nil, firstErr
+	}
+	sort.Slice(corrupt, func(i, j int) bool { return corrupt[i].Path < corrupt[j].Path })
+	return len(paths), corrupt, nil
+}
+
+// Quarantine moves the corrupt region files from base into dir, keeping
+// their relative paths, so BlueMap renders the rest of the world.
+func Quarantine(base, dir string, corrupt []Corrupt) error {
+	for _, c := range corrupt {
+		dst := filepath.Join(dir, c.Path)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(base, c.Path), dst); err != nil {
+			return fmt.Errorf("quarantining %s: %w", c.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package mca
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// region builds a region file with one chunk per entry, each stored in its
+// own sector after the header. mutate may corrupt the result.
+func region(t *testing.T, path string, n int, mutate func([]byte) []byte) {
+	t.Helper()
+	data := make([]byte, headerSize+n*sectorSize)
+	for i := 0; i < n; i++ {
+		offset := 2 + i
+		binary.BigEndian.PutUint32(data[i*4:], uint32(offset<<8|1))
+		binary.BigEndian.PutUint32(data[offset*sectorSize:], 100)
+		data[offset*sectorSize+4] = 2 // zlib
+	}
+	if mutate != nil {
+		data = mutate(data)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanAndQuarantine(t *testing.T) {
+	base := t.TempDir()
+	regionDir := filepath.Join(base, "world", "region")
+
+	region(t, filepath.Join(regionDir, "r.0.0.mca"), 3, nil)
+	region(t, filepath.Join(regionDir, "r.1.0.mca"), 0, func(b []byte) []byte { return nil }) // empty: valid
+	region(t, filepath.Join(regionDir, "r.2.0.mca"), 3, func(b []byte) []byte {
+		return b[:headerSize+2*sectorSize] // third chunk cut off
+	})
+	region(t, filepath.Join(regionDir, "r.3.0.mca"), 2, func(b []byte) []byte {
+		b[(2+1)*sectorSize+4] = 9 // unknown compression
+		return b
+	})
+	region(t, filepath.Join(regionDir, "r.4.0.mca"), 2, func(b []byte) []byte {
+		binary.BigEndian.PutUint32(b[4:], 2<<8|1) // second chunk reuses sector 2
+		return b
+	})
+	region(t, filepath.Join(regionDir, "r.5.0.mca"), 0, func(b []byte) []byte { return b[:100] })
+	region(t, filepath.Join(base, "world", "entities", "r.6.0.mca"), 0, func(b []byte) []byte { return b[:100] }) // not scanned
+
+	checked, corrupt, err := Scan(base, []string{"world", "missing"}, 2)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if checked != 6 {
+		t.Errorf("checked %d files, want 6", checked)
+	}
+	want := map[string]int{"r.2.0.mca": 1, "r.3.0.mca": 1, "r.4.0.mca": 1, "r.5.0.mca": 0}
+	if len(corrupt) != len(want) {
+		t.Fatalf("corrupt = %+v, want %v", corrupt, want)
+	}
+	for _, c := range corrupt {
+		bad, ok := want[filepath.Base(c.Path)]
+		if !ok || c.BadChunks != bad || c.Reason == "" {
+			t.Errorf("unexpected result %+v", c)
+		}
+	}
+
+	qdir := filepath.Join(base, QuarantineDirName)
+	if err := Quarantine(base, qdir, corrupt); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(qdir, "world", "region", "r.2.0.mca")); err != nil {
+		t.Errorf("quarantined file missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(regionDir, "r.2.0.mca")); !os.IsNotExist(err) {
+		t.Errorf("corrupt file still in the world: %v", err)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	base := t.TempDir()
+	for _, rel := range []string{
+		"world/region/r.0.0.mca",
+		"world/region/r.-1.0.mca",
+		"world/region/r.5.0.mca",
+		"world/entities/r.5.0.mca",
+		"world/poi/r.0.-4.mca",
+		"world/DIM-1/region/r.9.9.mca",
+		"world/data/r.5.0.mca", // not a region folder
+	} {
+		region(t, filepath.Join(base, filepath.FromSlash(rel)), 1, nil)
+	}
+
+	keep := func(rx, rz int) bool { return rx >= -1 && rx <= 1 && rz >= -1 && rz <= 1 }
+	removed, freed, err := Trim(base, []string{"world", "missing"}, keep)
+	if err != nil {
+		t.Fatalf("Trim: %v", err)
+	}
+	if removed != 4 || freed != 4*(headerSize+sectorSize) {
+		t.Errorf("Trim removed %d files (%d bytes), want 4 (%d bytes)", removed, freed, 4*(headerSize+sectorSize))
+	}
+	for rel, want := range map[string]bool{
+		"world/region/r.0.0.mca":       true,
+		"world/region/r.-1.0.mca":      true,
+		"world/region/r.5.0.mca":       false,
+		"world/entities/r.5.0.mca":     false,
+		"world/poi/r.0.-4.mca":         false,
+		"world/DIM-1/region/r.9.9.mca": false,
+		"world/data/r.5.0.mca":         true,
+	} {
+		_, err := os.Stat(filepath.Join(base, filepath.FromSlash(rel)))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mca
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// regionFolders are the folders that hold region-format r.X.Z.mca files.
+var regionFolders = map[string]bool{"region": true, "entities": true, "poi": true}
+
+// Trim deletes the region-format files (in region/, entities/ and poi/ below
+// the given roots, relative to base) for which keep(rx, rz) returns false. It
+// returns the number of files removed and the bytes freed. BlueMap renders
+// nothing for a missing region, so this cuts both render time and output.
+func Trim(base string, roots []string, keep func(rx, rz int) bool) (removed int, freed int64, err error) {
+	for _, root := range roots {
+		dir := filepath.Join(base, root)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !regionFolders[filepath.Base(filepath.Dir(path))] {
+				return nil
+			}
+			m := regionNameRe.FindStringSubmatch(info.Name())
+			if m == nil {
+				return nil
+			}
+			rx, _ := strconv.Atoi(m[1])
+			rz, _ := strconv.Atoi(m[2])
+			if keep(rx, rz) {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+			freed += info.Size()
+			return nil
+		})
+		if err != nil {
+			return removed, freed, err
+		}
+	}
+	return removed, freed, nil
+}
@@ -0,0 +1,138 @@
+package modrinth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Modrinth API (v2) endpoint.
+const DefaultBaseURL = "https://api.modrinth.com/v2"
+
+// Client looks up project versions on Modrinth. The API needs no token, but
+// asks every client to identify itself with a unique User-Agent.
+type Client struct {
+	BaseURL   string
+	UserAgent string
+	HTTP      *http.Client
+}
+
+// NewClient creates a Modrinth API client identifying itself as userAgent.
+func NewClient(userAgent string) *Client {
+	return &Client{
+		BaseURL:   DefaultBaseURL,
+		UserAgent: userAgent,
+		HTTP:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Version is a published version of a project.
+type Version struct {
+	ID            string `json:"id"`
+	VersionNumber string `json:"version_number"`
+	Files         []File `json:"files"`
+}
+
+// File is a file of a version, with the hashes Modrinth computed on upload.
+type File struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	Primary  bool   `json:"primary"`
+	Size     int64  `json:"size"`
+	Hashes   struct {
+		SHA1   string `json:"sha1"`
+		SHA512 string `json:"sha512"`
+	} `json:"hashes"`
+}
+
+// PrimaryFile returns the file marked primary, or the first file when none
+// is.
+func (v *Version) PrimaryFile() (File, error) {
+	if len(v.Files) == 0 {
+		return File{}, fmt.Errorf("version %s has no files", v.VersionNumber)
+	}
+	for _, f := range v.Files {
+		if f.Primary {
+			return f, nil
+		}
+	}
+	return v.Files[0], nil
+}
+
+// GetVersion returns version of project; project is a project ID or slug,
+// version a version ID or version number.
+func (c *Client) GetVersion(ctx context.Context, project, version string) (*Version, error) {
+	var v Version
+	if err := c.get(ctx, "/project/"+url.PathEscape(project)+"/version/"+url.PathEscape(version), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// LatestVersion returns the newest version of project for gameVersion and,
+// when set, loader (e.g. "fabric").
+func (c *Client) LatestVersion(ctx context.Context, project, gameVersion, loader string) (*Version, error) {
+	query := url.Values{}
+	if gameVersion != "" {
+		query.Set("game_versions", jsonList(gameVersion))
+	}
+	if loader != "" {
+		query.Set("loaders", jsonList(loader))
+	}
+	var versions []Version
+	if err := c.get(ctx, "/project/"+url.PathEscape(project)+"/version?"+query.Encode(), &versions); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		target := "Minecraft " + gameVersion
+		if loader != "" {
+			target += " on " + loader
+		}
+		return nil, fmt.Errorf("project %s has no version for %s", project, target)
+	}
+	// Modrinth lists the newest version first.
+	return &versions[0], nil
+}
+
+// jsonList encodes a single value as the JSON array the version filters
+// take.
+func jsonList(s string) string {
+	data, _ := json.Marshal([]string{s})
+	return string(data)
+}
+
+// get sends a GET request and decodes the JSON response into out.
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.BaseURL, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("modrinth request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("modrinth: %s not found", strings.SplitN(path, "?", 2)[0])
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("modrinth API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -4,37 +4,105 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
 )
 
-const netlifyToml = `# SPA fallback
+const netlifyToml = `# Share link helper (see internal/sharelink)
+[[redirects]]
+from = "/go"
+to = "/go/index.html"
+status = 200
+
+# SPA fallback
 [[redirects]]
 from = "/*"
 to = "/index.html"
 status = 200
+`
 
-# Compressed asset headers (JS references .prbm.gz and textures.json.gz directly)
-[[headers]]
-  for = "/*.json.gz"
-  [headers.values]
-    Content-Encoding = "gzip"
+// DefaultContentSecurityPolicy is a CSP that allows the BlueMap webapp to run
+// entirely from its own origin. Textures and fonts may be inlined as data: or
+// blob: URLs, and Vue-generated inline styles require 'unsafe-inline'.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: blob:; " +
+	"font-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"worker-src 'self' blob:; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"frame-ancestors 'self'"
 
-[[headers]]
-  for = "/*.prbm.gz"
-  [headers.values]
-    Content-Encoding = "gzip"
-`
+// permissionsPolicy disables browser features the map viewer never uses.
+const permissionsPolicy = "camera=(), microphone=(), geolocation=(), payment=(), usb=()"
+
+// Options controls optional sections of the generated netlify.toml.
+type Options struct {
+	SecurityHeaders       bool   // emit CSP and related security headers for all paths
+	ContentSecurityPolicy string // CSP override; empty = DefaultContentSecurityPolicy
+	Cache                 webmeta.CachePolicy
+}
 
 // DeployConfig writes a netlify.toml into the web/ directory under serverDir.
-func DeployConfig(serverDir string) error {
+func DeployConfig(serverDir string, opts Options) error {
 	webDir := filepath.Join(serverDir, "web")
 	if err := os.MkdirAll(webDir, 0o755); err != nil {
 		return fmt.Errorf("creating web directory %s: %w", webDir, err)
 	}
 
 	targetPath := filepath.Join(webDir, "netlify.toml")
-	if err := os.WriteFile(targetPath, []byte(netlifyToml), 0o644); err != nil {
+	if err := os.WriteFile(targetPath, []byte(buildToml(opts)), 0o644); err != nil {
 		return fmt.Errorf("writing %s: %w", targetPath, err)
 	}
 
 	return nil
 }
+
+// buildToml renders the netlify.toml content for the given options.
+func buildToml(opts Options) string {
+	var sb strings.Builder
+	sb.WriteString(netlifyToml)
+
+	sb.WriteString("\n# Compressed asset headers (JS references .prbm.gz and textures.json.gz directly)\n")
+	for i, rule := range webmeta.CompressedRules() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[[headers]]\n")
+		sb.WriteString(fmt.Sprintf("  for = %q\n", rule.Pattern))
+		sb.WriteString("  [headers.values]\n")
+		sb.WriteString(fmt.Sprintf("    Content-Encoding = %q\n", rule.ContentEncoding))
+		sb.WriteString(fmt.Sprintf("    Content-Type = %q\n", rule.ContentType))
+	}
+
+	sb.WriteString("\n# Cache-Control per class of web output\n")
+	for i, rule := range webmeta.CacheRules(opts.Cache) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[[headers]]\n")
+		sb.WriteString(fmt.Sprintf("  for = %q\n", rule.Pattern))
+		sb.WriteString("  [headers.values]\n")
+		sb.WriteString(fmt.Sprintf("    Cache-Control = %q\n", rule.CacheControl))
+	}
+
+	if opts.SecurityHeaders {
+		csp := opts.ContentSecurityPolicy
+		if csp == "" {
+			csp = DefaultContentSecurityPolicy
+		}
+		sb.WriteString("\n# Security headers\n")
+		sb.WriteString("[[headers]]\n")
+		sb.WriteString("  for = \"/*\"\n")
+		sb.WriteString("  [headers.values]\n")
+		sb.WriteString(fmt.Sprintf("    Content-Security-Policy = %q\n", csp))
+		sb.WriteString("    X-Content-Type-Options = \"nosniff\"\n")
+		sb.WriteString("    Referrer-Policy = \"strict-origin-when-cross-origin\"\n")
+		sb.WriteString(fmt.Sprintf("    Permissions-Policy = %q\n", permissionsPolicy))
+	}
+
+	return sb.String()
+}
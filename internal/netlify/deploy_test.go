@@ -0,0 +1,76 @@
+package netlify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+func TestBuildTomlParses(t *testing.T) {
+	for _, opts := range []Options{{}, {SecurityHeaders: true}} {
+		content := buildToml(opts)
+
+		var parsed struct {
+			Headers []struct {
+				For    string            `toml:"for"`
+				Values map[string]string `toml:"values"`
+			} `toml:"headers"`
+		}
+		if _, err := toml.Decode(content, &parsed); err != nil {
+			t.Fatalf("generated netlify.toml does not parse: %v\n%s", err, content)
+		}
+
+		found := false
+		for _, h := range parsed.Headers {
+			if h.For == "/*.prbm.gz" {
+				found = true
+				if h.Values["Content-Encoding"] != "gzip" {
+					t.Errorf("/*.prbm.gz Content-Encoding = %q, want gzip", h.Values["Content-Encoding"])
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing /*.prbm.gz header rule in:\n%s", content)
+		}
+		if opts.SecurityHeaders != strings.Contains(content, "Content-Security-Policy") {
+			t.Errorf("SecurityHeaders=%v but CSP presence mismatched in:\n%s", opts.SecurityHeaders, content)
+		}
+	}
+}
+
+func TestBuildTomlCacheRules(t *testing.T) {
+	content := buildToml(Options{Cache: webmeta.CachePolicy{Tiles: "no-cache", Data: webmeta.CacheOff}})
+
+	var parsed struct {
+		Headers []struct {
+			For    string            `toml:"for"`
+			Values map[string]string `toml:"values"`
+		} `toml:"headers"`
+	}
+	if _, err := toml.Decode(content, &parsed); err != nil {
+		t.Fatalf("generated netlify.toml does not parse: %v\n%s", err, content)
+	}
+	got := make(map[string]string)
+	for _, h := range parsed.Headers {
+		if v, ok := h.Values["Cache-Control"]; ok {
+			got[h.For] = v
+		}
+	}
+	want := map[string]string{
+		"/assets/index-*.js*":  webmeta.DefaultAssetsCache,
+		"/assets/index-*.css*": webmeta.DefaultAssetsCache,
+		"/assets/*.png":        webmeta.DefaultAssetFilesCache,
+		"/maps/*/tiles/*":      "no-cache",
+	}
+	if _, ok := got["/settings.json"]; ok {
+		t.Errorf("Cache-Control rules = %v, want none for the data class", got)
+	}
+	for pattern, value := range want {
+		if got[pattern] != value {
+			t.Errorf("Cache-Control for %s = %q, want %q", pattern, got[pattern], value)
+		}
+	}
+}
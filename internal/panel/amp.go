@@ -0,0 +1,81 @@
+package panel
+
+import (
+	"context"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/amp"
+)
+
+// AMP reads the local backups of an AMP (CubeCoders) instance; server_id is
+// the instance ID. AMP offers no download link for a backup, so the archive
+// is read through the file manager API and served to the extractor on a
+// loopback address. It has no console support, so pause_saves and
+// announce_command are not available.
+type AMP struct {
+	Client *amp.Client
+}
+
+// NewAMP returns an AMP panel logging in with a user's name and password.
+func NewAMP(panelURL, username, password string) *AMP {
+	return &AMP{Client: amp.NewClient(panelURL, username, password)}
+}
+
+func (p *AMP) Name() string { return "AMP" }
+
+func (p *AMP) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
+	backups, err := p.Client.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Backup, len(backups))
+	for i, b := range backups {
+		out[i] = Backup{UUID: b.ID, Name: b.Name, Bytes: b.TotalSizeBytes, CreatedAt: b.ModifiedDate.Time}
+	}
+	return out, nil
+}
+
+// GetDownloadURL serves the backup archive until ctx is done.
+func (p *AMP) GetDownloadURL(ctx context.Context, serverID, backupID string) (Download, error) {
+	url, err := p.Client.ServeFile(ctx, serverID, amp.BackupPath(backupID))
+	if err != nil {
+		return Download{}, err
+	}
+	return Download{URL: url}, nil
+}
+
+// CreateBackup takes a backup titled name and polls until a backup that was
+// not listed before appears; AMP lists a backup once its archive is written.
+func (p *AMP) CreateBackup(ctx context.Context, serverID, name string) (*Backup, error) {
+	before, err := p.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(before))
+	for _, b := range before {
+		known[b.UUID] = true
+	}
+	if err := p.Client.TakeBackup(ctx, serverID, name, "Created by bluemap-action"); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		backups, err := p.ListBackups(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range backups {
+			if !known[b.UUID] {
+				return &backups[i], nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package panel
+
+import (
+	"context"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/crafty"
+)
+
+// Crafty reads the zip backups of the default backup configuration through
+// the Crafty Controller 4 API. It has no console support, so pause_saves and
+// announce_command are not available.
+type Crafty struct {
+	Client *crafty.Client
+}
+
+// NewCrafty returns a Crafty Controller panel using an API token.
+func NewCrafty(panelURL, token string) *Crafty {
+	return &Crafty{Client: crafty.NewClient(panelURL, token)}
+}
+
+func (p *Crafty) Name() string { return "Crafty Controller" }
+
+// ListBackups lists the archives of the default backup configuration. The
+// file name serves as the backup ID.
+func (p *Crafty) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
+	cfg, err := p.Client.DefaultBackupConfig(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := p.Client.ListBackupFiles(ctx, serverID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Backup, len(files))
+	for i, f := range files {
+		out[i] = Backup{UUID: f.Name, Name: f.Name, Bytes: f.Size, CreatedAt: f.Modified}
+	}
+	return out, nil
+}
+
+func (p *Crafty) GetDownloadURL(ctx context.Context, serverID, backupID string) (Download, error) {
+	cfg, err := p.Client.DefaultBackupConfig(ctx, serverID)
+	if err != nil {
+		return Download{}, err
+	}
+	return Download{URL: p.Client.DownloadURL(serverID, cfg.ID, backupID), Header: p.Client.DownloadHeader()}, nil
+}
+
+// CreateBackup runs the default backup configuration and polls until an
+// archive that was not listed before appears and its size stays the same
+// between two polls, as Crafty writes it in place. Crafty names archives by
+// their time, so name is not used.
+func (p *Crafty) CreateBackup(ctx context.Context, serverID, name string) (*Backup, error) {
+	before, err := p.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(before))
+	for _, b := range before {
+		known[b.UUID] = true
+	}
+	cfg, err := p.Client.DefaultBackupConfig(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Client.StartBackup(ctx, serverID, cfg.ID); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	sizes := make(map[string]int64)
+	for {
+		backups, err := p.ListBackups(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range backups {
+			if known[b.UUID] {
+				continue
+			}
+			if last, ok := sizes[b.UUID]; ok && last == b.Bytes && b.Bytes > 0 {
+				return &backups[i], nil
+			}
+			sizes[b.UUID] = b.Bytes
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
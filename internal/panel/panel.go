@@ -0,0 +1,169 @@
+// Package panel abstracts the game server panel the world backups come from,
+// so the pipeline lists, creates and downloads backups the same way on
+// Pterodactyl, PufferPanel, Crafty Controller and AMP.
+package panel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Types accepted in panel_type.
+const (
+	TypePterodactyl = "pterodactyl"
+	TypePufferPanel = "pufferpanel"
+	TypeCrafty      = "crafty"
+	TypeAMP         = "amp"
+)
+
+// Environment variables holding the panel address and credentials.
+const (
+	PterodactylURLEnv          = "PTERODACTYL_PANEL_URL"
+	PterodactylKeyEnv          = "PTERODACTYL_API_KEY"
+	PufferPanelURLEnv          = "PUFFERPANEL_PANEL_URL"
+	PufferPanelClientIDEnv     = "PUFFERPANEL_CLIENT_ID"
+	PufferPanelClientSecretEnv = "PUFFERPANEL_CLIENT_SECRET"
+	CraftyURLEnv               = "CRAFTY_PANEL_URL"
+	CraftyTokenEnv             = "CRAFTY_API_TOKEN"
+	AMPURLEnv                  = "AMP_PANEL_URL"
+	AMPUsernameEnv             = "AMP_USERNAME"
+	AMPPasswordEnv             = "AMP_PASSWORD"
+)
+
+// pollInterval is how often CreateBackup checks whether the backup finished.
+var pollInterval = 5 * time.Second
+
+// Backup is a completed backup of a server.
+type Backup struct {
+	UUID      string // backup identifier: the UUID on Pterodactyl, the numeric ID on PufferPanel, the file name on Crafty, the backup GUID on AMP
+	Name      string
+	Bytes     int64
+	Checksum  string // "<algorithm>:<hex>", e.g. "sha1:…"; empty when the panel reports none
+	CreatedAt time.Time
+	Locked    bool // protected from deletion on the panel
+}
+
+// Download is where a backup archive is fetched from.
+type Download struct {
+	URL    string
+	Header http.Header // sent with every download request; nil for pre-signed URLs
+}
+
+// Panel is a server panel backups are read from.
+type Panel interface {
+	// Name returns the panel product name for messages, e.g. "Pterodactyl".
+	Name() string
+	// ListBackups returns the successful backups of the server, newest
+	// first.
+	ListBackups(ctx context.Context, serverID string) ([]Backup, error)
+	// GetDownloadURL returns where the archive of a backup is downloaded
+	// from.
+	GetDownloadURL(ctx context.Context, serverID, backupID string) (Download, error)
+	// CreateBackup starts a backup named name and waits until the panel
+	// reports it completed.
+	CreateBackup(ctx context.Context, serverID, name string) (*Backup, error)
+}
+
+// Console is a connection to a server console, used to pause world saves and
+// to announce a deploy.
+type Console interface {
+	State() string // power state, e.g. StateRunning; empty if not reported yet
+	Command(command string) error
+	WaitForOutput(ctx context.Context, substr string, timeout time.Duration) error
+	Close() error
+}
+
+// ConsolePanel is implemented by panels whose console can be used; only
+// Pterodactyl supports it.
+type ConsolePanel interface {
+	OpenConsole(ctx context.Context, serverID string) (Console, error)
+}
+
+// BackupDeleter is implemented by panels that can delete backups, used by
+// backup_retention; only Pterodactyl supports it.
+type BackupDeleter interface {
+	DeleteBackup(ctx context.Context, serverID, backupID string) error
+}
+
+// BackupLocker is implemented by panels that can lock a backup against
+// deletion, used by lock_backup; only Pterodactyl supports it.
+type BackupLocker interface {
+	SetBackupLocked(ctx context.Context, serverID, backupID string, locked bool) error
+}
+
+// StateRunning is the Console state of a running server.
+const StateRunning = "running"
+
+// FromEnv returns the panel of type panelType ("" = Pterodactyl) with the
+// address and credentials from its environment variables.
+func FromEnv(panelType string) (Panel, error) {
+	switch panelType {
+	case "", TypePterodactyl:
+		env, err := requireEnv(PterodactylURLEnv, PterodactylKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		return NewPterodactyl(env[0], env[1]), nil
+	case TypePufferPanel:
+		env, err := requireEnv(PufferPanelURLEnv, PufferPanelClientIDEnv, PufferPanelClientSecretEnv)
+		if err != nil {
+			return nil, err
+		}
+		return NewPufferPanel(env[0], env[1], env[2]), nil
+	case TypeCrafty:
+		env, err := requireEnv(CraftyURLEnv, CraftyTokenEnv)
+		if err != nil {
+			return nil, err
+		}
+		return NewCrafty(env[0], env[1]), nil
+	case TypeAMP:
+		env, err := requireEnv(AMPURLEnv, AMPUsernameEnv, AMPPasswordEnv)
+		if err != nil {
+			return nil, err
+		}
+		return NewAMP(env[0], env[1], env[2]), nil
+	default:
+		return nil, fmt.Errorf("unknown panel type %q", panelType)
+	}
+}
+
+// requireEnv returns the values of the environment variables names, or an
+// error naming the first one that is not set.
+func requireEnv(names ...string) ([]string, error) {
+	values := make([]string, len(names))
+	for i, name := range names {
+		if values[i] = os.Getenv(name); values[i] == "" {
+			return nil, fmt.Errorf("%s environment variable is required", name)
+		}
+	}
+	return values, nil
+}
+
+// LatestBackup returns the most recent backup of the server.
+func LatestBackup(ctx context.Context, p Panel, serverID string) (*Backup, error) {
+	backups, err := p.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no successful backup found for server %s", serverID)
+	}
+	return &backups[0], nil
+}
+
+// FindBackup returns the backup of the server with the given ID.
+func FindBackup(ctx context.Context, p Panel, serverID, backupID string) (*Backup, error) {
+	backups, err := p.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range backups {
+		if backups[i].UUID == backupID {
+			return &backups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found for server %s", backupID, serverID)
+}
@@ -0,0 +1,373 @@
+package panel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePufferPanel serves the OAuth2 token endpoint and the backup API of one
+// server, "srv". A created backup is listed on the second list request after
+// it, like an archive that takes a moment to write.
+func fakePufferPanel(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	backups := []map[string]any{
+		{"id": 1, "name": "old", "fileSize": 10, "createdAt": "2026-01-01T00:00:00Z"},
+		{"id": 2, "name": "new", "fileSize": 20, "createdAt": "2026-02-01T00:00:00Z"},
+	}
+	var pending string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "id" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, "bad client", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "tok", "expires_in": 3600})
+	})
+	authed := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer tok" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/servers/srv/backup", authed(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(backups)
+		if pending != "" {
+			backups = append(backups, map[string]any{"id": 3, "name": pending, "fileSize": 30, "createdAt": "2026-03-01T00:00:00Z"})
+			pending = ""
+		}
+	}))
+	mux.HandleFunc("POST /api/servers/srv/backup/create", authed(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Name string }
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		pending = body.Name
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPufferPanel(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	srv := fakePufferPanel(t)
+	p := NewPufferPanel(srv.URL+"/", "id", "secret")
+	ctx := context.Background()
+
+	latest, err := LatestBackup(ctx, p, "srv")
+	if err != nil {
+		t.Fatalf("LatestBackup: %v", err)
+	}
+	if latest.UUID != "2" || latest.Name != "new" || latest.Bytes != 20 {
+		t.Errorf("LatestBackup = %+v, want backup 2", latest)
+	}
+
+	dl, err := p.GetDownloadURL(ctx, "srv", latest.UUID)
+	if err != nil {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	if dl.URL != srv.URL+"/api/servers/srv/backup/download/2" || dl.Header.Get("Authorization") != "Bearer tok" {
+		t.Errorf("GetDownloadURL = %+v", dl)
+	}
+	if _, err := p.GetDownloadURL(ctx, "srv", "abc"); err == nil {
+		t.Error("GetDownloadURL accepted a non-numeric backup ID")
+	}
+
+	created, err := p.CreateBackup(ctx, "srv", "fresh")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	if created.UUID != "3" || created.Name != "fresh" {
+		t.Errorf("CreateBackup = %+v, want backup 3", created)
+	}
+
+	bad := NewPufferPanel(srv.URL, "id", "wrong")
+	if _, err := bad.ListBackups(ctx, "srv"); err == nil {
+		t.Error("ListBackups succeeded with a wrong client secret")
+	}
+}
+
+func TestPterodactylSetBackupLocked(t *testing.T) {
+	var mu sync.Mutex
+	locked, toggles := false, 0
+	backup := func(w http.ResponseWriter) {
+		json.NewEncoder(w).Encode(map[string]any{"object": "backup", "attributes": map[string]any{"uuid": "u1", "is_locked": locked}})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/client/servers/srv/backups/u1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		backup(w)
+	})
+	mux.HandleFunc("POST /api/client/servers/srv/backups/u1/lock", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		locked = !locked
+		toggles++
+		backup(w)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	p := NewPterodactyl(srv.URL, "key")
+	ctx := context.Background()
+	for _, step := range []struct {
+		locked      bool
+		wantToggles int
+	}{
+		{true, 1},
+		{true, 1}, // already locked: no toggle
+		{false, 2},
+		{false, 2},
+	} {
+		if err := p.SetBackupLocked(ctx, "srv", "u1", step.locked); err != nil {
+			t.Fatalf("SetBackupLocked(%v): %v", step.locked, err)
+		}
+		if locked != step.locked || toggles != step.wantToggles {
+			t.Errorf("after SetBackupLocked(%v): locked %v after %d toggles, want %d", step.locked, locked, toggles, step.wantToggles)
+		}
+	}
+}
+
+// fakeCrafty serves the backup config and file listing of server "srv". A
+// started backup appears on the next listing and stops growing on the one
+// after.
+func fakeCrafty(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	files := []map[string]any{
+		{"path": "backups/2026-01-01_00-00-00.zip", "size": 10, "modified": 1767225600},
+		{"path": "backups/2026-02-01_00-00-00.zip", "size": 20, "modified": 1769904000},
+		{"path": "backups/notes.txt", "size": 1, "modified": 1769904000},
+	}
+	started := 0
+	ok := func(w http.ResponseWriter, data any) {
+		json.NewEncoder(w).Encode(map[string]any{"status": "ok", "data": data})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/servers/srv/backups", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"status": "error", "error": "NOT_AUTHORIZED"})
+			return
+		}
+		ok(w, []map[string]any{{"backup_id": "cfg", "backup_name": "Default", "backup_location": "backups", "default": true}})
+	})
+	mux.HandleFunc("POST /api/v2/servers/srv/files", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch started {
+		case 1:
+			files = append(files, map[string]any{"path": "backups/2026-03-01_00-00-00.zip", "size": 15, "modified": 1772323200})
+			started++
+		case 2:
+			files[len(files)-1]["size"] = 30
+			started++
+		}
+		ok(w, files)
+	})
+	mux.HandleFunc("POST /api/v2/servers/srv/action/backup_server/cfg", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		started = 1
+		mu.Unlock()
+		ok(w, nil)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCrafty(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	srv := fakeCrafty(t)
+	p := NewCrafty(srv.URL, "tok")
+	ctx := context.Background()
+
+	backups, err := p.ListBackups(ctx, "srv")
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(backups) != 2 || backups[0].UUID != "2026-02-01_00-00-00.zip" || backups[0].Bytes != 20 {
+		t.Errorf("ListBackups = %+v, want the two zip files, newest first", backups)
+	}
+
+	dl, err := p.GetDownloadURL(ctx, "srv", backups[0].UUID)
+	if err != nil {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	want := srv.URL + "/panel/download_backup?backup_id=cfg&file=2026-02-01_00-00-00.zip&id=srv"
+	if dl.URL != want || dl.Header.Get("Cookie") != "token=tok" {
+		t.Errorf("GetDownloadURL = %+v, want %s with the token cookie", dl, want)
+	}
+
+	created, err := p.CreateBackup(ctx, "srv", "ignored")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	if created.UUID != "2026-03-01_00-00-00.zip" || created.Bytes != 30 {
+		t.Errorf("CreateBackup = %+v, want the finished new archive", created)
+	}
+
+	if _, err := NewCrafty(srv.URL, "wrong").ListBackups(ctx, "srv"); err == nil {
+		t.Error("ListBackups succeeded with a wrong token")
+	}
+}
+
+// fakeAMP serves the backup and file manager API of instance "inst" through
+// the controller. The first session it hands out expires after one call, and
+// a taken backup is listed on the next listing.
+func fakeAMP(t *testing.T, archive []byte) *httptest.Server {
+	var mu sync.Mutex
+	backups := []map[string]any{
+		{"Id": "b1", "Name": "old", "ModifiedDate": "/Date(1767225600000)/", "TotalSizeBytes": 10, "StoredLocally": true},
+		{"Id": "b2", "Name": "new", "ModifiedDate": "/Date(1769904000000)/", "TotalSizeBytes": int64(len(archive)), "StoredLocally": true},
+		{"Id": "b3", "Name": "remote", "ModifiedDate": "/Date(1772323200000)/", "TotalSizeBytes": 30, "StoredLocally": false},
+	}
+	logins, calls := 0, 0
+	var pending string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /API/ADSModule/Servers/inst/API/{module}/{method}", func(w http.ResponseWriter, r *http.Request) {
+		var args map[string]any
+		json.NewDecoder(r.Body).Decode(&args)
+		mu.Lock()
+		defer mu.Unlock()
+		method := r.PathValue("module") + "/" + r.PathValue("method")
+		if method == "Core/Login" {
+			if args["username"] != "user" || args["password"] != "pass" {
+				json.NewEncoder(w).Encode(map[string]any{"success": false, "resultReason": "bad credentials"})
+				return
+			}
+			logins++
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "sessionID": "s" + strconv.Itoa(logins)})
+			return
+		}
+		calls++
+		if args["SESSIONID"] == "s1" && calls > 1 {
+			json.NewEncoder(w).Encode(map[string]any{"Title": "Unauthorized Access", "Message": "session expired"})
+			return
+		}
+		result := func(v any) { json.NewEncoder(w).Encode(map[string]any{"result": v}) }
+		switch method {
+		case "LocalFileBackupPlugin/GetBackups":
+			result(backups)
+			if pending != "" {
+				backups = append(backups, map[string]any{"Id": "b4", "Name": pending, "ModifiedDate": "2026-03-02T00:00:00Z", "TotalSizeBytes": 40, "StoredLocally": true})
+				pending = ""
+			}
+		case "LocalFileBackupPlugin/TakeBackup":
+			pending = args["Title"].(string)
+			result(map[string]any{"Status": true})
+		case "FileManagerPlugin/GetDirectoryListing":
+			result([]map[string]any{{"Filename": "b2.zip", "SizeBytes": len(archive)}})
+		case "FileManagerPlugin/ReadFileChunk":
+			offset, n := int(args["Offset"].(float64)), int(args["ChunkSize"].(float64))
+			result(base64.StdEncoding.EncodeToString(archive[offset:min(offset+n, len(archive))]))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAMP(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	archive := []byte("PK\x03\x04 backup archive")
+	srv := fakeAMP(t, archive)
+	p := NewAMP(srv.URL, "user", "pass")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backups, err := p.ListBackups(ctx, "inst")
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(backups) != 2 || backups[0].UUID != "b2" || !backups[0].CreatedAt.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ListBackups = %+v, want the two local backups, newest first", backups)
+	}
+
+	// The second call finds the first session expired and logs in again.
+	dl, err := p.GetDownloadURL(ctx, "inst", "b2")
+	if err != nil {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, dl.URL, nil)
+	req.Header.Set("Range", "bytes=4-")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("downloading: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != string(archive[4:]) {
+		t.Errorf("range download = %d %q, want 206 %q", resp.StatusCode, body, archive[4:])
+	}
+
+	created, err := p.CreateBackup(ctx, "inst", "fresh")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	if created.UUID != "b4" || created.Name != "fresh" {
+		t.Errorf("CreateBackup = %+v, want backup b4", created)
+	}
+
+	if _, err := NewAMP(srv.URL, "user", "wrong").ListBackups(ctx, "inst"); err == nil {
+		t.Error("ListBackups succeeded with a wrong password")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(PufferPanelURLEnv, "https://panel.example.com")
+	t.Setenv(PufferPanelClientIDEnv, "id")
+	t.Setenv(PufferPanelClientSecretEnv, "")
+	if _, err := FromEnv(TypePufferPanel); err == nil {
+		t.Error("FromEnv accepted a missing client secret")
+	}
+	t.Setenv(PufferPanelClientSecretEnv, "secret")
+	if p, err := FromEnv(TypePufferPanel); err != nil || p.Name() != "PufferPanel" {
+		t.Errorf("FromEnv(pufferpanel) = %v, %v", p, err)
+	}
+
+	t.Setenv(PterodactylURLEnv, "https://panel.example.com")
+	t.Setenv(PterodactylKeyEnv, "key")
+	if p, err := FromEnv(""); err != nil || p.Name() != "Pterodactyl" {
+		t.Errorf("FromEnv(\"\") = %v, %v", p, err)
+	}
+	if _, ok := any(&Pterodactyl{}).(ConsolePanel); !ok {
+		t.Error("Pterodactyl does not implement ConsolePanel")
+	}
+	t.Setenv(CraftyURLEnv, "https://crafty.example.com:8443")
+	t.Setenv(CraftyTokenEnv, "tok")
+	if p, err := FromEnv(TypeCrafty); err != nil || p.Name() != "Crafty Controller" {
+		t.Errorf("FromEnv(crafty) = %v, %v", p, err)
+	}
+	t.Setenv(AMPURLEnv, "https://amp.example.com")
+	t.Setenv(AMPUsernameEnv, "user")
+	t.Setenv(AMPPasswordEnv, "pass")
+	if p, err := FromEnv(TypeAMP); err != nil || p.Name() != "AMP" {
+		t.Errorf("FromEnv(amp) = %v, %v", p, err)
+	}
+	if _, err := FromEnv("multicraft"); err == nil {
+		t.Error("FromEnv accepted an unknown panel type")
+	}
+}
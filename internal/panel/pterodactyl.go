@@ -0,0 +1,99 @@
+package panel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/EfinaServer/bluemap-action/internal/pterodactyl"
+)
+
+// Pterodactyl reads backups through the Pterodactyl client API.
+type Pterodactyl struct {
+	Client *pterodactyl.Client
+}
+
+// NewPterodactyl returns a Pterodactyl panel using a client API key.
+func NewPterodactyl(panelURL, apiKey string) *Pterodactyl {
+	return &Pterodactyl{Client: pterodactyl.NewClient(panelURL, apiKey)}
+}
+
+func (p *Pterodactyl) Name() string { return "Pterodactyl" }
+
+func (p *Pterodactyl) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
+	backups, err := p.Client.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	var out []Backup
+	for _, b := range backups {
+		if b.IsSuccessful {
+			out = append(out, fromPterodactyl(b))
+		}
+	}
+	return out, nil
+}
+
+func (p *Pterodactyl) GetDownloadURL(ctx context.Context, serverID, backupID string) (Download, error) {
+	url, err := p.Client.GetBackupDownloadURL(ctx, serverID, backupID)
+	if err != nil {
+		return Download{}, err
+	}
+	return Download{URL: url}, nil
+}
+
+func (p *Pterodactyl) CreateBackup(ctx context.Context, serverID, name string) (*Backup, error) {
+	created, err := p.Client.CreateBackup(ctx, serverID, name)
+	if err != nil {
+		return nil, err
+	}
+	done, err := p.Client.WaitForBackup(ctx, serverID, created.UUID, pollInterval)
+	if err != nil {
+		return nil, err
+	}
+	b := fromPterodactyl(*done)
+	return &b, nil
+}
+
+func (p *Pterodactyl) DeleteBackup(ctx context.Context, serverID, backupID string) error {
+	return p.Client.DeleteBackup(ctx, serverID, backupID)
+}
+
+// SetBackupLocked locks or unlocks a backup. The API only toggles the lock,
+// so the current state is read first and left alone when it already matches.
+func (p *Pterodactyl) SetBackupLocked(ctx context.Context, serverID, backupID string, locked bool) error {
+	b, err := p.Client.GetBackup(ctx, serverID, backupID)
+	if err != nil {
+		return err
+	}
+	if b.IsLocked == locked {
+		return nil
+	}
+	b, err = p.Client.ToggleBackupLock(ctx, serverID, backupID)
+	if err != nil {
+		return err
+	}
+	if b.IsLocked != locked {
+		return fmt.Errorf("backup %s is still %s after toggling its lock", backupID, lockState(b.IsLocked))
+	}
+	return nil
+}
+
+func lockState(locked bool) string {
+	if locked {
+		return "locked"
+	}
+	return "unlocked"
+}
+
+// OpenConsole connects to the console websocket of the server.
+func (p *Pterodactyl) OpenConsole(ctx context.Context, serverID string) (Console, error) {
+	con, err := p.Client.OpenConsole(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	return con, nil
+}
+
+func fromPterodactyl(b pterodactyl.Backup) Backup {
+	return Backup{UUID: b.UUID, Name: b.Name, Bytes: b.Bytes, Checksum: b.Checksum, CreatedAt: b.CreatedAt, Locked: b.IsLocked}
+}
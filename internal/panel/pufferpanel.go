@@ -0,0 +1,82 @@
+package panel
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/EfinaServer/bluemap-action/internal/pufferpanel"
+)
+
+// PufferPanel reads backups through the PufferPanel API. It has no console
+// support, so pause_saves and announce_command are not available.
+type PufferPanel struct {
+	Client *pufferpanel.Client
+}
+
+// NewPufferPanel returns a PufferPanel panel using an OAuth2 client.
+func NewPufferPanel(panelURL, clientID, clientSecret string) *PufferPanel {
+	return &PufferPanel{Client: pufferpanel.NewClient(panelURL, clientID, clientSecret)}
+}
+
+func (p *PufferPanel) Name() string { return "PufferPanel" }
+
+func (p *PufferPanel) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
+	backups, err := p.Client.ListBackups(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Backup, len(backups))
+	for i, b := range backups {
+		out[i] = fromPufferPanel(b)
+	}
+	return out, nil
+}
+
+// GetDownloadURL returns the archive URL with the bearer token it needs;
+// PufferPanel has no pre-signed download links.
+func (p *PufferPanel) GetDownloadURL(ctx context.Context, serverID, backupID string) (Download, error) {
+	id, err := strconv.Atoi(backupID)
+	if err != nil {
+		return Download{}, fmt.Errorf("invalid PufferPanel backup ID %q", backupID)
+	}
+	header, err := p.Client.AuthHeader(ctx)
+	if err != nil {
+		return Download{}, err
+	}
+	return Download{URL: p.Client.DownloadURL(serverID, id), Header: header}, nil
+}
+
+// CreateBackup starts the backup and polls the backup list until an entry
+// with the name appears, which PufferPanel adds once the archive is written.
+func (p *PufferPanel) CreateBackup(ctx context.Context, serverID, name string) (*Backup, error) {
+	if err := p.Client.CreateBackup(ctx, serverID, name); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		backups, err := p.Client.ListBackups(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range backups {
+			if b.Name == name {
+				out := fromPufferPanel(b)
+				return &out, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func fromPufferPanel(b pufferpanel.Backup) Backup {
+	return Backup{UUID: strconv.Itoa(b.ID), Name: b.Name, Bytes: b.FileSize, CreatedAt: b.CreatedAt}
+}
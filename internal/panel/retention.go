@@ -0,0 +1,32 @@
+package panel
+
+import "time"
+
+// ExpiredBackups returns the backups a retention policy deletes: those not
+// among the newest keep unlocked backups, or created more than maxAge before
+// now. keep = 0 and maxAge = 0 each disable their limit. Locked backups are
+// never returned and do not count towards keep, and neither is the backup
+// with ID protect, which still takes one of the keep places. backups must be
+// sorted newest first, as ListBackups returns them.
+func ExpiredBackups(backups []Backup, keep int, maxAge time.Duration, now time.Time, protect string) []Backup {
+	var expired []Backup
+	kept := 0
+	for _, b := range backups {
+		if b.UUID == protect && !b.Locked {
+			kept++
+		}
+	}
+	for _, b := range backups {
+		if b.Locked || b.UUID == protect {
+			continue
+		}
+		tooMany := keep > 0 && kept >= keep
+		tooOld := maxAge > 0 && now.Sub(b.CreatedAt) > maxAge
+		if tooMany || tooOld {
+			expired = append(expired, b)
+			continue
+		}
+		kept++
+	}
+	return expired
+}
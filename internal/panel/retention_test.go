@@ -0,0 +1,46 @@
+package panel
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExpiredBackups(t *testing.T) {
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return now.AddDate(0, 0, -d) }
+	backups := []Backup{
+		{UUID: "a", CreatedAt: day(1)},
+		{UUID: "b", CreatedAt: day(2), Locked: true},
+		{UUID: "c", CreatedAt: day(5)},
+		{UUID: "d", CreatedAt: day(10)},
+		{UUID: "e", CreatedAt: day(40), Locked: true},
+		{UUID: "f", CreatedAt: day(45)},
+	}
+	ids := func(bs []Backup) []string {
+		var out []string
+		for _, b := range bs {
+			out = append(out, b.UUID)
+		}
+		return out
+	}
+
+	for _, tt := range []struct {
+		keep    int
+		maxAge  time.Duration
+		protect string
+		want    []string
+	}{
+		{keep: 2, protect: "a", want: []string{"d", "f"}},
+		{keep: 2, protect: "f", want: []string{"c", "d"}},
+		{maxAge: 30 * 24 * time.Hour, protect: "a", want: []string{"f"}},
+		{keep: 3, maxAge: 7 * 24 * time.Hour, protect: "a", want: []string{"d", "f"}},
+		{keep: 1, maxAge: time.Hour, protect: "a", want: []string{"c", "d", "f"}},
+		{protect: "a", want: nil},
+	} {
+		got := ids(ExpiredBackups(backups, tt.keep, tt.maxAge, now, tt.protect))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ExpiredBackups(keep %d, max age %v, protect %s) = %v, want %v", tt.keep, tt.maxAge, tt.protect, got, tt.want)
+		}
+	}
+}
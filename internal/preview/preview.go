@@ -0,0 +1,146 @@
+// Package preview serves the web output of a server directory locally the
+// way its deploy target would, so a render can be checked in a browser
+// before it is published.
+package preview
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+)
+
+// DefaultAddr is the address the preview server listens on by default, the
+// port BlueMap's own web server uses.
+const DefaultAddr = "127.0.0.1:8100"
+
+// Options configures a preview Handler.
+type Options struct {
+	// GzipStatic answers a request with the .gz variant of the file, as the
+	// generated nginx config does for deploy_target = "static" and "ssh":
+	// always for tiles and textures.json, which BlueMap stores only
+	// compressed, and for other files when the client accepts gzip. The
+	// other targets serve files only under their literal names.
+	GzipStatic bool
+	// HideDotfiles treats files and folders whose name starts with a dot as
+	// missing, as the Netlify CLI leaves them out of a deploy. The other
+	// targets publish them.
+	HideDotfiles bool
+	Cache        webmeta.CachePolicy
+}
+
+var (
+	// tileRe matches the URLs of map tiles, which answer 204 when missing so
+	// the webapp renders them empty, like the generated nginx config.
+	tileRe = regexp.MustCompile(`^/maps/[^/]+/tiles/`)
+	// gzipAlwaysRe matches the URLs the generated nginx config serves with
+	// gzip_static always, regardless of Accept-Encoding.
+	gzipAlwaysRe = regexp.MustCompile(`^/maps/[^/]+/(tiles/|textures\.json$)`)
+)
+
+// Handler returns a handler serving the files under webDir. Pre-compressed
+// files get the Content-Type of the inner file and their Content-Encoding,
+// every file gets the Cache-Control of the generated hosting config, and
+// URLs that match no file fall back to index.html, like the single-page app
+// redirect of netlify.toml.
+func Handler(webDir string, opts Options) http.Handler {
+	root, err := os.OpenRoot(webDir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		urlPath := path.Clean("/" + r.URL.Path)
+		name := strings.TrimPrefix(urlPath, "/")
+		if name == "" {
+			name = "."
+		}
+		if info, err := root.Stat(name); err == nil && info.IsDir() {
+			name = path.Join(name, "index.html")
+		}
+
+		if opts.GzipStatic && (gzipAlwaysRe.MatchString(urlPath) || acceptsGzip(r)) && serveFile(w, r, root, name+".gz", name, opts) {
+			return
+		}
+		if serveFile(w, r, root, name, name, opts) {
+			return
+		}
+		if tileRe.MatchString(urlPath) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !serveFile(w, r, root, "index.html", "index.html", opts) {
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// serveFile serves the file name of root with the headers of the file
+// requested as rel, and reports whether the file exists. With
+// opts.HideDotfiles, files in dot folders or starting with a dot count as
+// missing.
+func serveFile(w http.ResponseWriter, r *http.Request, root *os.Root, name, rel string, opts Options) bool {
+	if opts.HideDotfiles {
+		for _, part := range strings.Split(name, "/") {
+			if strings.HasPrefix(part, ".") && part != "." {
+				return false
+			}
+		}
+	}
+	f, err := root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	meta := webmeta.Detect(name)
+	h := w.Header()
+	h.Set("Content-Type", meta.ContentType)
+	if meta.Compressed() {
+		h.Set("Content-Encoding", meta.ContentEncoding)
+	}
+	if name != rel {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if cc := webmeta.CacheControl(opts.Cache, filepath.ToSlash(rel)); cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	http.ServeContent(w, r, "", info.ModTime(), f)
+	return true
+}
+
+// acceptsGzip reports whether the Accept-Encoding of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "gzip" && coding != "*" {
+				continue
+			}
+			q := 1.0
+			if k, v, ok := strings.Cut(params, "="); ok && strings.TrimSpace(k) == "q" {
+				q, _ = strconv.ParseFloat(strings.TrimSpace(v), 64)
+			}
+			if q > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package preview
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":                           "<html>",
+		"assets/index-abc.js":                  "js",
+		"maps/world/settings.json":             "{}",
+		"maps/world/textures.json.gz":          "gz",
+		"maps/world/tiles/0/x1/z2.prbm.gz":     "tile",
+		"maps/world/.bluemap-last-render.json": "{}",
+		"assets/style.css":                     "css",
+		"assets/style.css.gz":                  "css.gz",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	static, netlify := Options{GzipStatic: true}, Options{HideDotfiles: true}
+	for _, tt := range []struct {
+		opts     Options
+		url      string
+		accept   string
+		status   int
+		body     string
+		ctype    string
+		encoding string
+	}{
+		{netlify, "/", "", 200, "<html>", "text/html; charset=utf-8", ""},
+		{netlify, "/maps/world/textures.json.gz", "", 200, "gz", "application/json", "gzip"},
+		{netlify, "/maps/world/tiles/0/x1/z2.prbm.gz", "", 200, "tile", "application/octet-stream", "gzip"},
+		// Only gzip_static targets answer the plain name with the .gz file.
+		{netlify, "/maps/world/textures.json", "", 200, "<html>", "text/html; charset=utf-8", ""},
+		{static, "/maps/world/textures.json", "", 200, "gz", "application/json", "gzip"},
+		{static, "/maps/world/tiles/0/x1/z2.prbm", "", 200, "tile", "application/octet-stream", "gzip"},
+		// Other files are served compressed only to clients accepting gzip.
+		{static, "/assets/style.css", "", 200, "css", "text/css; charset=utf-8", ""},
+		{static, "/assets/style.css", "br, gzip;q=0", 200, "css", "text/css; charset=utf-8", ""},
+		{static, "/assets/style.css", "br, gzip", 200, "css.gz", "text/css; charset=utf-8", "gzip"},
+		{netlify, "/maps/world/tiles/0/x9/z9.prbm.gz", "", 204, "", "", ""},
+		// Netlify leaves dot files out of a deploy; the other targets publish them.
+		{netlify, "/maps/world/.bluemap-last-render.json", "", 200, "<html>", "text/html; charset=utf-8", ""},
+		{static, "/maps/world/.bluemap-last-render.json", "", 200, "{}", "application/json", ""},
+		{netlify, "/../../etc/passwd", "", 200, "<html>", "text/html; charset=utf-8", ""},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Encoding", tt.accept)
+		}
+		Handler(dir, tt.opts).ServeHTTP(rec, req)
+		if rec.Code != tt.status || rec.Body.String() != tt.body {
+			t.Errorf("%s (%+v, Accept-Encoding %q) = %d %q, want %d %q", tt.url, tt.opts, tt.accept, rec.Code, rec.Body, tt.status, tt.body)
+			continue
+		}
+		if tt.status != 200 {
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.ctype {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.url, got, tt.ctype)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.url, got, tt.encoding)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(dir, Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/index-abc.js", nil))
+	if got := rec.Header().Get("Cache-Control"); got == "" {
+		t.Error("assets/ served without Cache-Control")
+	}
+}
@@ -0,0 +1,333 @@
+// Package proxy routes every outbound connection of the tool through the
+// proxy configured by HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or proxy_url in
+// config.toml). The HTTP clients pick it up through http.DefaultTransport,
+// whose proxy this package sets; it also covers the connections that do
+// not: the Wings console websocket and FTP, dialed by hand, and the BlueMap
+// CLI, whose JVM ignores the environment variables.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Validate checks a proxy_url value: an http or https URL with a host.
+// SOCKS proxies are left out because neither the websocket tunnel nor the
+// JVM properties set here could use them.
+func Validate(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("proxy URL must use http or https, got %q", raw)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return nil
+}
+
+// envKeys are the variables Apply overrides.
+var envKeys = []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"}
+
+var (
+	mu       sync.Mutex
+	override *url.URL // proxy_url applied last; nil to use the environment
+
+	saveEnv sync.Once
+	origEnv map[string]*string // envKeys as the process started; nil = unset
+)
+
+func init() {
+	// net/http reads the proxy environment once, on its first use. Read it
+	// now, before Apply changes it, so ForURL can fall back to the
+	// environment as it was after a server with proxy_url.
+	http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: "example.com"}})
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.Proxy = func(req *http.Request) (*url.URL, error) { return ForURL(req.URL) }
+	}
+}
+
+// Apply makes proxyURL the proxy for HTTP and HTTPS requests of this process
+// and the scripts and BlueMap CLI it starts, overriding HTTP_PROXY and
+// HTTPS_PROXY; NO_PROXY still applies. An empty proxyURL restores the proxy
+// of the environment, so commands going through several servers call Apply
+// with each server's proxy_url before its requests.
+func Apply(proxyURL string) error {
+	saveEnv.Do(func() {
+		origEnv = make(map[string]*string)
+		for _, k := range envKeys {
+			if v, ok := os.LookupEnv(k); ok {
+				origEnv[k] = &v
+			}
+		}
+	})
+	if proxyURL == "" {
+		mu.Lock()
+		override = nil
+		mu.Unlock()
+		for _, k := range envKeys {
+			var err error
+			if v := origEnv[k]; v != nil {
+				err = os.Setenv(k, *v)
+			} else {
+				err = os.Unsetenv(k)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := Validate(proxyURL); err != nil {
+		return err
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	for _, k := range envKeys {
+		if err := os.Setenv(k, proxyURL); err != nil {
+			return err
+		}
+	}
+	mu.Lock()
+	override = u
+	mu.Unlock()
+	return nil
+}
+
+// Describe returns the proxy in effect for HTTPS requests with any password
+// redacted, or "" when there is none, for the run header.
+func Describe() string {
+	u, err := ForURL(&url.URL{Scheme: "https", Host: "example.com"})
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// ForURL returns the proxy for a request to target, or nil when it is
+// reached directly. ws and wss URLs use the HTTP and HTTPS proxy.
+func ForURL(target *url.URL) (*url.URL, error) {
+	t := *target
+	switch t.Scheme {
+	case "ws":
+		t.Scheme = "http"
+	case "wss":
+		t.Scheme = "https"
+	}
+	mu.Lock()
+	p := override
+	mu.Unlock()
+	if p == nil {
+		return http.ProxyFromEnvironment(&http.Request{URL: &t})
+	}
+	if (t.Scheme != "http" && t.Scheme != "https") || bypass(t.Host) {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// bypass reports whether a request to hostport goes direct despite a
+// proxy_url: for localhost and loopback addresses, and hosts NO_PROXY
+// matches, as net/http decides for HTTP_PROXY.
+func bypass(hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	for _, h := range strings.Split(noProxyEnv(), ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if h == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(h); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if hh, hp, err := net.SplitHostPort(h); err == nil {
+			if hp != port {
+				continue
+			}
+			h = hh
+		}
+		h = strings.TrimPrefix(h, "*")
+		switch {
+		case strings.HasPrefix(h, "."):
+			if strings.HasSuffix(host, h) {
+				return true
+			}
+		case host == h || strings.HasSuffix(host, "."+h):
+			return true
+		}
+	}
+	return false
+}
+
+// Dial opens a TCP connection to addr (host:port) for a request to target,
+// tunneling through the proxy with HTTP CONNECT when one applies.
+func Dial(ctx context.Context, target *url.URL, addr string) (net.Conn, error) {
+	p, err := ForURL(target)
+	if err != nil {
+		return nil, fmt.Errorf("resolving proxy: %w", err)
+	}
+	var d net.Dialer
+	if p == nil {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	proxyAddr := p.Host
+	if p.Port() == "" {
+		port := "80"
+		if p.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(p.Hostname(), port)
+	}
+	if p.Scheme != "http" && p.Scheme != "https" {
+		return nil, fmt.Errorf("proxy %s: only http and https proxies can tunnel this connection", p.Redacted())
+	}
+
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to proxy %s: %w", proxyAddr, err)
+	}
+	if p.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy %s: %w", proxyAddr, err)
+		}
+		conn = tlsConn
+	}
+	if err := connect(ctx, conn, p, addr); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connect asks the proxy on conn to open a tunnel to addr.
+func connect(ctx context.Context, conn net.Conn, p *url.URL, addr string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if p.User != nil {
+		pass, _ := p.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(p.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("sending CONNECT to proxy: %w", err)
+	}
+	// The proxy sends nothing after its response until the tunnel is used,
+	// so the buffered reader cannot swallow bytes of the tunneled stream.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fmt.Errorf("reading CONNECT response from proxy: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// JVMArgs returns the system properties that give the JVM the proxy from the
+// environment, since Java ignores HTTP_PROXY and friends. Proxy credentials
+// are not passed on: Java only sends them for tunnels when configured with
+// an Authenticator, which the BlueMap CLI does not install.
+func JVMArgs() []string {
+	var args []string
+	for _, scheme := range []string{"http", "https"} {
+		p, err := ForURL(&url.URL{Scheme: scheme, Host: "example.com"})
+		if err != nil || p == nil || (p.Scheme != "http" && p.Scheme != "https") {
+			continue
+		}
+		port := p.Port()
+		if port == "" {
+			port = "80"
+			if p.Scheme == "https" {
+				port = "443"
+			}
+		}
+		args = append(args, "-D"+scheme+".proxyHost="+p.Hostname(), "-D"+scheme+".proxyPort="+port)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if hosts := nonProxyHosts(); hosts != "" {
+		// http.nonProxyHosts applies to HTTPS as well.
+		args = append(args, "-Dhttp.nonProxyHosts="+hosts)
+	}
+	return args
+}
+
+// nonProxyHosts converts NO_PROXY to Java's http.nonProxyHosts syntax:
+// "example.com,.internal" becomes "example.com|*.example.com|*.internal".
+// Ports and CIDR ranges have no Java equivalent and are dropped.
+func nonProxyHosts() string {
+	var hosts []string
+	for _, h := range strings.Split(noProxyEnv(), ",") {
+		h = strings.TrimSpace(h)
+		if h == "" || strings.Contains(h, "/") {
+			continue
+		}
+		if host, _, err := net.SplitHostPort(h); err == nil {
+			h = host
+		}
+		switch {
+		case h == "*":
+			hosts = append(hosts, "*")
+		case strings.HasPrefix(h, "*."):
+			hosts = append(hosts, h)
+		case strings.HasPrefix(h, "."):
+			hosts = append(hosts, "*"+h)
+		default:
+			hosts = append(hosts, h)
+			if net.ParseIP(h) == nil {
+				hosts = append(hosts, "*."+h)
+			}
+		}
+	}
+	return strings.Join(hosts, "|")
+}
+
+// noProxyEnv returns NO_PROXY, or no_proxy when it is unset.
+func noProxyEnv() string {
+	if v := os.Getenv("NO_PROXY"); v != "" {
+		return v
+	}
+	return os.Getenv("no_proxy")
+}
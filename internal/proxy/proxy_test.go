@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A proxy that accepts one CONNECT, checks its credentials and then
+	// echoes the tunneled bytes.
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		if req.Method != http.MethodConnect || req.Host != "panel.example.com:443" ||
+			req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		io.Copy(conn, br)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	p := &url.URL{Scheme: "http", Host: ln.Addr().String(), User: url.UserPassword("user", "pass")}
+	if err := connect(context.Background(), conn, p, "panel.example.com:443"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	io.WriteString(conn, "ping")
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("tunnel echoed %q, %v; want \"ping\"", buf, err)
+	}
+}
+
+func TestNonProxyHosts(t *testing.T) {
+	t.Setenv("NO_PROXY", "example.com, .internal,*.corp.net,10.0.0.1,10.0.0.0/8,cache:8080")
+	want := "example.com|*.example.com|*.internal|*.corp.net|10.0.0.1|cache|*.cache"
+	if got := nonProxyHosts(); got != want {
+		t.Errorf("nonProxyHosts() = %q, want %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"http://proxy.corp:3128":     true,
+		"https://user:pw@proxy.corp": true,
+		"socks5://127.0.0.1:1080":    false,
+		"ftp://proxy.corp":           false,
+		"proxy.corp:3128":            false,
+		"http://":                    false,
+	} {
+		if err := Validate(raw); (err == nil) != ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", raw, err, ok)
+		}
+	}
+}
+
+func TestApplyPerServer(t *testing.T) {
+	t.Setenv("NO_PROXY", ".internal,cache.example.com:8080,10.0.0.0/8")
+	target := func(raw string) *url.URL {
+		u, _ := url.Parse(raw)
+		return u
+	}
+	env, _ := http.ProxyFromEnvironment(&http.Request{URL: target("https://panel.example.com")})
+
+	if err := Apply("http://proxy-a:3128"); err != nil {
+		t.Fatal(err)
+	}
+	for raw, proxied := range map[string]bool{
+		"https://panel.example.com":       true,
+		"wss://panel.example.com/ws":      true,
+		"https://panel.internal":          false,
+		"http://cache.example.com:8080/x": false,
+		"http://cache.example.com/x":      true,
+		"http://10.1.2.3":                 false,
+		"http://localhost:8100":           false,
+	} {
+		if p, _ := ForURL(target(raw)); (p != nil) != proxied {
+			t.Errorf("ForURL(%s) = %v, want proxied %v", raw, p, proxied)
+		}
+	}
+
+	// The next server has its own proxy_url, the one after none.
+	if err := Apply("http://proxy-b:3128"); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := ForURL(target("https://panel.example.com")); p == nil || p.Host != "proxy-b:3128" {
+		t.Errorf("second server: ForURL = %v, want proxy-b", p)
+	}
+	if err := Apply(""); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := ForURL(target("https://panel.example.com")); fmt.Sprint(p) != fmt.Sprint(env) {
+		t.Errorf("server without proxy_url: ForURL = %v, want the environment's %v", p, env)
+	}
+}
@@ -0,0 +1,235 @@
+package prune
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/EfinaServer/bluemap-action/internal/bluemap"
+	"github.com/EfinaServer/bluemap-action/internal/mca"
+)
+
+// Modes accepted for prune_tiles in config.toml.
+const (
+	ModeOff    = ""        // Keep all tiles.
+	ModeDryRun = "dry-run" // Report stale tiles without deleting them.
+	ModeDelete = "delete"  // Delete stale tiles.
+)
+
+// ReportName is the dry-run report file written next to config.toml.
+const ReportName = "bluemap-stale-tiles.txt"
+
+// BlueMap's default tile grids. Hires tiles (LOD 0) cover 32×32 blocks;
+// lowres LOD n covers 500 × 5^(n-1) blocks.
+const (
+	hiresTileSize  = 32
+	lowresTileSize = 500
+	lowresFactor   = 5
+	regionSize     = 512 // blocks per region file side
+)
+
+var (
+	// tileNameRe matches a tile path below tiles/<lod>/ with the directory
+	// separators removed: BlueMap splits coordinates into one directory per
+	// digit, e.g. x1/2/z-3/4.prbm.gz for tile (12, -34).
+	tileNameRe = regexp.MustCompile(`^x(-?\d+)z(-?\d+)\.`)
+	regionRe   = regexp.MustCompile(`^r\.(-?\d+)\.(-?\d+)\.mca$`)
+)
+
+// MapResult lists the stale tiles found for one map.
+type MapResult struct {
+	MapID     string
+	RegionDir string   // region folder the map renders from
+	Stale     []string // tile paths relative to the server directory
+	Bytes     int64
+	Skipped   string // reason the map was not checked, if any
+}
+
+// Run finds tiles under web/maps whose source regions no longer exist in the
+// extracted worlds, and deletes them unless dryRun is set. Maps are matched to
+// their world through config/maps/<id>.conf; a map whose region folder cannot
+// be found is skipped rather than pruned entirely.
+func Run(serverDir string, dryRun bool) ([]MapResult, error) {
+	confs, err := filepath.Glob(filepath.Join(serverDir, "config", "maps", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(confs)
+
+	var results []MapResult
+	for _, conf := range confs {
+		id := strings.TrimSuffix(filepath.Base(conf), ".conf")
+		res := MapResult{MapID: id}
+
+		tilesDir := filepath.Join(serverDir, "web", "maps", id, "tiles")
+		if _, err := os.Stat(tilesDir); err != nil {
+			continue
+		}
+
+		regionDir, err := mapRegionDir(serverDir, conf)
+		if err != nil {
+			res.Skipped = err.Error()
+			results = append(results, res)
+			continue
+		}
+		res.RegionDir = regionDir
+
+		regions, err := listRegions(regionDir)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", regionDir, err)
+		}
+		if len(regions) == 0 {
+			res.Skipped = "no region files in " + regionDir
+			results = append(results, res)
+			continue
+		}
+
+		if err := findStale(serverDir, tilesDir, regions, &res); err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", tilesDir, err)
+		}
+
+		if !dryRun {
+			for _, rel := range res.Stale {
+				if err := os.Remove(filepath.Join(serverDir, rel)); err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("removing %s: %w", rel, err)
+				}
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// mapRegionDir reads the world and dimension of a BlueMap map config and
+// returns the region folder they refer to.
+func mapRegionDir(serverDir, confPath string) (string, error) {
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return "", err
+	}
+	world, dimension := bluemap.MapWorld(data)
+	if world == "" {
+		return "", fmt.Errorf("no world in %s", filepath.Base(confPath))
+	}
+	if !filepath.IsAbs(world) {
+		world = filepath.Join(serverDir, world)
+	}
+	return mca.RegionDir(world, dimension)
+}
+
+// listRegions returns the set of non-empty region files in dir, keyed by
+// "x,z".
+func listRegions(dir string) (map[[2]int]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	regions := make(map[[2]int]bool)
+	for _, e := range entries {
+		m := regionRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		if info, err := e.Info(); err != nil || info.Size() == 0 {
+			continue
+		}
+		x, _ := strconv.Atoi(m[1])
+		z, _ := strconv.Atoi(m[2])
+		regions[[2]int{x, z}] = true
+	}
+	return regions, nil
+}
+
+// findStale walks every LOD under tilesDir and records tiles that overlap no
+// existing region.
+func findStale(serverDir, tilesDir string, regions map[[2]int]bool, res *MapResult) error {
+	lods, err := os.ReadDir(tilesDir)
+	if err != nil {
+		return err
+	}
+	for _, l := range lods {
+		lod, err := strconv.Atoi(l.Name())
+		if err != nil || !l.IsDir() {
+			continue
+		}
+		size := hiresTileSize
+		if lod > 0 {
+			size = lowresTileSize
+			for i := 1; i < lod; i++ {
+				size *= lowresFactor
+			}
+		}
+
+		lodDir := filepath.Join(tilesDir, l.Name())
+		err = filepath.Walk(lodDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(lodDir, path)
+			m := tileNameRe.FindStringSubmatch(strings.ReplaceAll(filepath.ToSlash(rel), "/", ""))
+			if m == nil {
+				return nil
+			}
+			tx, _ := strconv.Atoi(m[1])
+			tz, _ := strconv.Atoi(m[2])
+			if overlapsRegion(tx, tz, size, regions) {
+				return nil
+			}
+			relServer, _ := filepath.Rel(serverDir, path)
+			res.Stale = append(res.Stale, relServer)
+			res.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlapsRegion reports whether the tile (tx, tz) of the given block size
+// overlaps at least one existing region.
+func overlapsRegion(tx, tz, size int, regions map[[2]int]bool) bool {
+	minX, maxX := floorDiv(tx*size, regionSize), floorDiv((tx+1)*size-1, regionSize)
+	minZ, maxZ := floorDiv(tz*size, regionSize), floorDiv((tz+1)*size-1, regionSize)
+	for x := minX; x <= maxX; x++ {
+		for z := minZ; z <= maxZ; z++ {
+			if regions[[2]int{x, z}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// WriteReport writes the list of stale tiles, one path per line, to path.
+func WriteReport(path string, results []MapResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, r := range results {
+		for _, tile := range r.Stale {
+			fmt.Fprintln(w, tile)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
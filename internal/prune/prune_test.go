@@ -0,0 +1,111 @@
+package prune
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, n int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, n), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755)
+	os.WriteFile(filepath.Join(dir, "config", "maps", "overworld.conf"),
+		[]byte("world: \"world\"\ndimension: \"minecraft:overworld\"\n"), 0o644)
+
+	// Only region (0, 0) still exists: blocks 0..511 on both axes.
+	writeFile(t, filepath.Join(dir, "world", "region", "r.0.0.mca"), 8192)
+	writeFile(t, filepath.Join(dir, "world", "region", "r.5.5.mca"), 0) // empty: ignored
+
+	tiles := filepath.Join(dir, "web", "maps", "overworld", "tiles")
+	keep := []string{
+		filepath.Join(tiles, "0", "x0", "z0.prbm.gz"),           // hires (0,0): blocks 0..31
+		filepath.Join(tiles, "0", "x1", "5", "z1", "5.prbm.gz"), // hires (15,15): blocks 480..511
+		filepath.Join(tiles, "1", "x0", "z0.png"),               // lowres (0,0): blocks 0..499
+	}
+	stale := []string{
+		filepath.Join(tiles, "0", "x1", "6", "z0.prbm.gz"), // hires (16,0): region (1,0)
+		filepath.Join(tiles, "0", "x-1", "z0.prbm.gz"),     // hires (-1,0): region (-1,0)
+		filepath.Join(tiles, "1", "x0", "z-1.png"),         // lowres (0,-1): blocks z -500..-1
+		filepath.Join(tiles, "1", "x2", "z2.png"),          // lowres (2,2): regions 1..2
+	}
+	for _, p := range append(append([]string{}, keep...), stale...) {
+		writeFile(t, p, 10)
+	}
+
+	results, err := Run(dir, true)
+	if err != nil {
+		t.Fatalf("Run(dry-run): %v", err)
+	}
+	if len(results) != 1 || results[0].Skipped != "" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	var got []string
+	for _, rel := range results[0].Stale {
+		got = append(got, filepath.Join(dir, rel))
+	}
+	sort.Strings(got)
+	sort.Strings(stale)
+	if len(got) != len(stale) {
+		t.Fatalf("stale = %v, want %v", got, stale)
+	}
+	for i := range got {
+		if got[i] != stale[i] {
+			t.Errorf("stale[%d] = %s, want %s", i, got[i], stale[i])
+		}
+	}
+	if results[0].Bytes != int64(10*len(stale)) {
+		t.Errorf("Bytes = %d, want %d", results[0].Bytes, 10*len(stale))
+	}
+
+	// Dry run must not delete anything.
+	for _, p := range stale {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("dry run removed %s", p)
+		}
+	}
+
+	if _, err := Run(dir, false); err != nil {
+		t.Fatalf("Run(delete): %v", err)
+	}
+	for _, p := range stale {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s was not deleted", p)
+		}
+	}
+	for _, p := range keep {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was deleted: %v", p, err)
+		}
+	}
+}
+
+func TestRunSkipsMissingWorld(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "web", "maps", "end", "tiles", "0", "x0", "z0.prbm.gz"), 10)
+	os.MkdirAll(filepath.Join(dir, "config", "maps"), 0o755)
+	os.WriteFile(filepath.Join(dir, "config", "maps", "end.conf"),
+		[]byte("world: \"world\"\ndimension: \"minecraft:the_end\"\n"), 0o644)
+
+	results, err := Run(dir, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) != 1 || results[0].Skipped == "" {
+		t.Fatalf("expected the map to be skipped, got %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web", "maps", "end", "tiles", "0", "x0", "z0.prbm.gz")); err != nil {
+		t.Error("tile of a map without region folder was deleted")
+	}
+}
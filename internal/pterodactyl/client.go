@@ -1,20 +1,28 @@
 package pterodactyl
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// Client interacts with the Pterodactyl panel client API.
+// Client interacts with the Pterodactyl panel client API. It stays within
+// the panel's per-minute rate limit, which one API key shares across all of
+// its servers.
 type Client struct {
 	PanelURL string
 	APIKey   string
 	HTTP     *http.Client
+	Log      func(format string, args ...any) // Optional; receives a line per request with the budget left
+
+	limiter rateLimiter
 }
 
 // NewClient creates a new Pterodactyl API client.
@@ -33,6 +41,7 @@ type Backup struct {
 	IsSuccessful bool       `json:"is_successful"`
 	IsLocked     bool       `json:"is_locked"`
 	Bytes        int64      `json:"bytes"`
+	Checksum     string     `json:"checksum"` // "<algorithm>:<hex>", e.g. "sha1:…"; empty for some storage adapters
 	CreatedAt    time.Time  `json:"created_at"`
 	CompletedAt  *time.Time `json:"completed_at"`
 }
@@ -43,6 +52,17 @@ type backupListResponse struct {
 		Object     string `json:"object"`
 		Attributes Backup `json:"attributes"`
 	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			CurrentPage int `json:"current_page"`
+			TotalPages  int `json:"total_pages"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+type backupResponse struct {
+	Object     string `json:"object"`
+	Attributes Backup `json:"attributes"`
 }
 
 type downloadResponse struct {
@@ -52,52 +72,94 @@ type downloadResponse struct {
 	} `json:"attributes"`
 }
 
-func (c *Client) doRequest(method, path string) ([]byte, error) {
+// Budget returns the rate limit budget used so far.
+func (c *Client) Budget() Budget {
+	return c.limiter.snapshot()
+}
+
+// doRequest sends an API request. A non-nil payload is encoded as the JSON
+// request body. A request rejected by the rate limit is retried after the
+// wait the panel asks for.
+func (c *Client) doRequest(ctx context.Context, method, path string, payload any) ([]byte, error) {
 	url := c.PanelURL + path
 
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
 
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request to %s: %w", url, err)
-	}
-	defer resp.Body.Close()
+		var reqBody io.Reader
+		if data != nil {
+			reqBody = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
-	}
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
-	}
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("executing request to %s: %w", url, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading response body: %w", err)
+		}
 
-	return body, nil
-}
+		limited := c.limiter.observe(resp)
+		if c.Log != nil {
+			c.Log("%s %s: %d (%s)", method, path, resp.StatusCode, c.Budget())
+		}
+		if limited && attempt < maxRateRetries {
+			continue
+		}
 
-// ListBackups returns all backups for a given server, sorted by creation time
-// (newest first).
-func (c *Client) ListBackups(serverID string) ([]Backup, error) {
-	body, err := c.doRequest("GET", "/api/client/servers/"+serverID+"/backups")
-	if err != nil {
-		return nil, err
-	}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
+		}
 
-	var result backupListResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("decoding backup list: %w", err)
+		return body, nil
 	}
+}
 
+// ListBackups returns all backups for a given server, sorted by creation time
+// (newest first). The panel returns the list in pages, which are followed
+// until meta.pagination reports the last one.
+func (c *Client) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
 	var backups []Backup
-	for _, d := range result.Data {
-		backups = append(backups, d.Attributes)
+	for page := 1; ; page++ {
+		body, err := c.doRequest(ctx, "GET", "/api/client/servers/"+serverID+"/backups?page="+strconv.Itoa(page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result backupListResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("decoding backup list: %w", err)
+		}
+
+		for _, d := range result.Data {
+			backups = append(backups, d.Attributes)
+		}
+
+		p := result.Meta.Pagination
+		if len(result.Data) == 0 || p.CurrentPage >= p.TotalPages {
+			break
+		}
+		page = p.CurrentPage
 	}
 
 	sort.Slice(backups, func(i, j int) bool {
@@ -108,8 +170,8 @@ func (c *Client) ListBackups(serverID string) ([]Backup, error) {
 }
 
 // GetLatestBackup returns the most recent successful backup for a server.
-func (c *Client) GetLatestBackup(serverID string) (*Backup, error) {
-	backups, err := c.ListBackups(serverID)
+func (c *Client) GetLatestBackup(ctx context.Context, serverID string) (*Backup, error) {
+	backups, err := c.ListBackups(ctx, serverID)
 	if err != nil {
 		return nil, err
 	}
@@ -124,8 +186,8 @@ func (c *Client) GetLatestBackup(serverID string) (*Backup, error) {
 }
 
 // GetBackupDownloadURL returns a signed download URL for the given backup.
-func (c *Client) GetBackupDownloadURL(serverID, backupUUID string) (string, error) {
-	body, err := c.doRequest("GET", "/api/client/servers/"+serverID+"/backups/"+backupUUID+"/download")
+func (c *Client) GetBackupDownloadURL(ctx context.Context, serverID, backupUUID string) (string, error) {
+	body, err := c.doRequest(ctx, "GET", "/api/client/servers/"+serverID+"/backups/"+backupUUID+"/download", nil)
 	if err != nil {
 		return "", err
 	}
@@ -141,3 +203,80 @@ func (c *Client) GetBackupDownloadURL(serverID, backupUUID string) (string, erro
 
 	return result.Attributes.URL, nil
 }
+
+// CreateBackup starts a new backup of the server and returns it in its
+// pending state. Use WaitForBackup to block until it has completed.
+func (c *Client) CreateBackup(ctx context.Context, serverID, name string) (*Backup, error) {
+	body, err := c.doRequest(ctx, "POST", "/api/client/servers/"+serverID+"/backups", map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	var result backupResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decoding created backup: %w", err)
+	}
+	return &result.Attributes, nil
+}
+
+// DeleteBackup deletes a backup. The panel refuses to delete a locked one.
+func (c *Client) DeleteBackup(ctx context.Context, serverID, backupUUID string) error {
+	_, err := c.doRequest(ctx, "DELETE", "/api/client/servers/"+serverID+"/backups/"+backupUUID, nil)
+	return err
+}
+
+// ToggleBackupLock locks an unlocked backup or unlocks a locked one, and
+// returns it in its new state. The panel's backup rotation and delete
+// requests skip locked backups.
+func (c *Client) ToggleBackupLock(ctx context.Context, serverID, backupUUID string) (*Backup, error) {
+	body, err := c.doRequest(ctx, "POST", "/api/client/servers/"+serverID+"/backups/"+backupUUID+"/lock", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result backupResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decoding backup: %w", err)
+	}
+	return &result.Attributes, nil
+}
+
+// GetBackup returns the current state of a single backup.
+func (c *Client) GetBackup(ctx context.Context, serverID, backupUUID string) (*Backup, error) {
+	body, err := c.doRequest(ctx, "GET", "/api/client/servers/"+serverID+"/backups/"+backupUUID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result backupResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decoding backup: %w", err)
+	}
+	return &result.Attributes, nil
+}
+
+// WaitForBackup polls the backup every interval until the panel reports it
+// as completed. It returns an error if the backup failed or ctx is done first.
+func (c *Client) WaitForBackup(ctx context.Context, serverID, backupUUID string, interval time.Duration) (*Backup, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b, err := c.GetBackup(ctx, serverID, backupUUID)
+		if err != nil {
+			return nil, err
+		}
+		if b.CompletedAt != nil {
+			if !b.IsSuccessful {
+				return nil, fmt.Errorf("backup %s failed on the panel", backupUUID)
+			}
+			return b, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pterodactyl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestListBackupsPages(t *testing.T) {
+	// Five backups, two per page, oldest first as the panel returns them.
+	const total, perPage = 5, 2
+	pages := (total + perPage - 1) / perPage
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requested []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		requested = append(requested, page)
+		fmt.Fprint(w, `{"object":"list","data":[`)
+		for i := (page - 1) * perPage; i < min(page*perPage, total); i++ {
+			if i > (page-1)*perPage {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"object":"backup","attributes":{"uuid":"b%d","created_at":%q}}`, i, base.Add(time.Duration(i)*time.Hour).Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, `],"meta":{"pagination":{"total":%d,"count":%d,"per_page":%d,"current_page":%d,"total_pages":%d}}}`, total, perPage, perPage, page, pages)
+	}))
+	defer srv.Close()
+
+	backups, err := NewClient(srv.URL, "key").ListBackups(context.Background(), "srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != pages {
+		t.Errorf("requested pages %v, want 1 to %d", requested, pages)
+	}
+	if len(backups) != total || backups[0].UUID != "b4" || backups[total-1].UUID != "b0" {
+		t.Errorf("got %d backups starting with %+v, want %d newest first", len(backups), backups[0], total)
+	}
+}
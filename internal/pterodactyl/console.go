@@ -0,0 +1,242 @@
+package pterodactyl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server power states reported by the console websocket "status" event.
+const (
+	StateRunning  = "running"
+	StateOffline  = "offline"
+	StateStarting = "starting"
+	StateStopping = "stopping"
+)
+
+// consoleEvent is the JSON envelope used by the Wings console websocket.
+type consoleEvent struct {
+	Event string   `json:"event"`
+	Args  []string `json:"args,omitempty"`
+}
+
+type websocketResponse struct {
+	Data struct {
+		Token  string `json:"token"`
+		Socket string `json:"socket"`
+	} `json:"data"`
+}
+
+// Console is an authenticated connection to a server's console websocket.
+// It is used to send commands such as save-off and to wait for their output.
+type Console struct {
+	client   *Client
+	serverID string
+	ws       *wsConn
+
+	output chan string   // console output lines
+	done   chan struct{} // closed when the read loop exits
+
+	mu    sync.Mutex
+	state string
+	err   error
+}
+
+// OpenConsole connects to the server's console websocket, authenticates and
+// waits briefly for the initial power state. The connection re-authenticates
+// automatically when Wings reports that the token is expiring.
+func (c *Client) OpenConsole(ctx context.Context, serverID string) (*Console, error) {
+	token, socket, err := c.websocketCredentials(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := dialWebsocket(ctx, socket, c.PanelURL)
+	if err != nil {
+		return nil, err
+	}
+
+	con := &Console{
+		client:   c,
+		serverID: serverID,
+		ws:       ws,
+		output:   make(chan string, 256),
+		done:     make(chan struct{}),
+	}
+	if err := con.send("auth", token); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("authenticating console: %w", err)
+	}
+
+	authed := make(chan struct{})
+	go con.readLoop(authed)
+
+	select {
+	case <-authed:
+	case <-con.done:
+		ws.Close()
+		return nil, fmt.Errorf("console websocket closed before authentication: %w", con.Err())
+	case <-ctx.Done():
+		ws.Close()
+		return nil, ctx.Err()
+	case <-time.After(30 * time.Second):
+		ws.Close()
+		return nil, fmt.Errorf("timed out waiting for console authentication")
+	}
+
+	// Wings sends the current power state right after authentication.
+	deadline := time.Now().Add(5 * time.Second)
+	for con.State() == "" && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return con, nil
+}
+
+// websocketCredentials fetches a console websocket token and URL.
+func (c *Client) websocketCredentials(ctx context.Context, serverID string) (token, socket string, err error) {
+	body, err := c.doRequest(ctx, "GET", "/api/client/servers/"+serverID+"/websocket", nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var result websocketResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", "", fmt.Errorf("decoding websocket credentials: %w", err)
+	}
+	if result.Data.Token == "" || result.Data.Socket == "" {
+		return "", "", fmt.Errorf("empty websocket credentials returned for server %s", serverID)
+	}
+	return result.Data.Token, result.Data.Socket, nil
+}
+
+// readLoop dispatches incoming events until the connection closes.
+func (con *Console) readLoop(authed chan<- struct{}) {
+	defer close(con.done)
+	defer close(con.output)
+
+	for {
+		data, err := con.ws.readMessage()
+		if err != nil {
+			con.setErr(err)
+			return
+		}
+
+		var ev consoleEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			continue
+		}
+
+		switch ev.Event {
+		case "auth success":
+			if authed != nil {
+				close(authed)
+				authed = nil
+			}
+		case "status":
+			if len(ev.Args) > 0 {
+				con.mu.Lock()
+				con.state = ev.Args[0]
+				con.mu.Unlock()
+			}
+		case "console output":
+			for _, line := range ev.Args {
+				select {
+				case con.output <- line:
+				default: // nobody is waiting; drop rather than block
+				}
+			}
+		case "token expiring", "token expired":
+			token, _, err := con.client.websocketCredentials(context.Background(), con.serverID)
+			if err == nil {
+				err = con.send("auth", token)
+			}
+			if err != nil {
+				con.setErr(fmt.Errorf("refreshing console token: %w", err))
+			}
+		case "jwt error":
+			con.setErr(fmt.Errorf("console authentication failed: %s", strings.Join(ev.Args, " ")))
+			con.ws.Close()
+			return
+		}
+	}
+}
+
+func (con *Console) send(event string, args ...string) error {
+	data, err := json.Marshal(consoleEvent{Event: event, Args: args})
+	if err != nil {
+		return err
+	}
+	return con.ws.writeText(data)
+}
+
+func (con *Console) setErr(err error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if con.err == nil {
+		con.err = err
+	}
+}
+
+// Err returns the first error seen by the connection, if any.
+func (con *Console) Err() error {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	return con.err
+}
+
+// State returns the last power state reported by Wings, or "" if none has
+// been received yet.
+func (con *Console) State() string {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	return con.state
+}
+
+// Command sends a console command, e.g. "save-off". Output received before
+// the command is discarded, so a following WaitForOutput only sees lines
+// produced after it.
+func (con *Console) Command(command string) error {
+	for drained := false; !drained; {
+		select {
+		case <-con.output:
+		default:
+			drained = true
+		}
+	}
+	if err := con.send("send command", command); err != nil {
+		return fmt.Errorf("sending console command %q: %w", command, err)
+	}
+	return nil
+}
+
+// WaitForOutput blocks until a console output line containing substr is
+// received since the last Command, the timeout elapses or ctx is done.
+func (con *Console) WaitForOutput(ctx context.Context, substr string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-con.output:
+			if !ok {
+				return fmt.Errorf("console closed while waiting for %q: %w", substr, con.Err())
+			}
+			if strings.Contains(line, substr) {
+				return nil
+			}
+		case <-timer.C:
+			return fmt.Errorf("timed out after %s waiting for console output %q", timeout, substr)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close closes the console connection.
+func (con *Console) Close() error {
+	return con.ws.Close()
+}
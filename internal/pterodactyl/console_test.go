@@ -0,0 +1,119 @@
+package pterodactyl
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFakeWings upgrades the request and plays a minimal Wings console:
+// it answers auth with "auth success" and a running status, and replies to
+// "save-all" commands with the vanilla confirmation line. Every command is
+// reported on commands.
+func serveFakeWings(t *testing.T, w http.ResponseWriter, r *http.Request, commands chan<- string) {
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsAcceptGUID))
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Errorf("hijack: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	rw.Flush()
+
+	// Server-to-client frames are unmasked; reuse wsConn for reading the
+	// masked client frames.
+	ws := &wsConn{conn: conn, br: bufio.NewReader(rw)}
+	send := func(ev consoleEvent) {
+		data, _ := json.Marshal(ev)
+		conn.Write(append([]byte{0x80 | wsOpText, byte(len(data))}, data...))
+	}
+
+	for {
+		data, err := ws.readMessage()
+		if err != nil {
+			return
+		}
+		var ev consoleEvent
+		json.Unmarshal(data, &ev)
+		switch ev.Event {
+		case "auth":
+			if len(ev.Args) != 1 || ev.Args[0] != "tok" {
+				send(consoleEvent{Event: "jwt error", Args: []string{"bad token"}})
+				return
+			}
+			send(consoleEvent{Event: "auth success"})
+			send(consoleEvent{Event: "status", Args: []string{StateRunning}})
+		case "send command":
+			commands <- ev.Args[0]
+			if strings.HasPrefix(ev.Args[0], "save-all") {
+				send(consoleEvent{Event: "console output", Args: []string{"[12:00:00 INFO]: Saving the game (this may take a moment!)"}})
+				send(consoleEvent{Event: "console output", Args: []string{"[12:00:01 INFO]: Saved the game"}})
+			}
+		}
+	}
+}
+
+func TestConsoleSaveCommands(t *testing.T) {
+	commands := make(chan string, 10)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/client/servers/abc/websocket":
+			socket := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+			io.WriteString(w, `{"data":{"token":"tok","socket":"`+socket+`"}}`)
+		case "/ws":
+			if r.Header.Get("Origin") != srv.URL {
+				http.Error(w, "bad origin", http.StatusForbidden)
+				return
+			}
+			serveFakeWings(t, w, r, commands)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	con, err := NewClient(srv.URL, "key").OpenConsole(ctx, "abc")
+	if err != nil {
+		t.Fatalf("OpenConsole: %v", err)
+	}
+	defer con.Close()
+
+	if got := con.State(); got != StateRunning {
+		t.Errorf("State() = %q, want %q", got, StateRunning)
+	}
+
+	for _, cmd := range []string{"save-off", "save-all flush"} {
+		if err := con.Command(cmd); err != nil {
+			t.Fatalf("Command(%q): %v", cmd, err)
+		}
+	}
+	if err := con.WaitForOutput(ctx, "Saved the game", 5*time.Second); err != nil {
+		t.Fatalf("WaitForOutput: %v", err)
+	}
+
+	for _, want := range []string{"save-off", "save-all flush"} {
+		select {
+		case got := <-commands:
+			if got != want {
+				t.Errorf("command = %q, want %q", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("command %q never reached the server", want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pterodactyl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateWindow is the period the panel's client API rate limit applies to
+// (APP_API_CLIENT_RATELIMIT requests per minute).
+var rateWindow = time.Minute
+
+// maxRateRetries is how often a request rejected with 429 Too Many Requests
+// is retried after the wait the panel asks for.
+const maxRateRetries = 3
+
+// Budget is the rate limit budget of a client as reported by the panel.
+type Budget struct {
+	Requests  int           // API requests sent
+	Limit     int           // requests allowed per window; 0 = not reported
+	Remaining int           // requests left in the window after the last response
+	Throttled time.Duration // time spent waiting to stay within the limit
+}
+
+func (b Budget) String() string {
+	if b.Limit == 0 {
+		return fmt.Sprintf("%d request(s), no rate limit reported", b.Requests)
+	}
+	return fmt.Sprintf("%d request(s), %d/%d left this minute, throttled %s",
+		b.Requests, b.Remaining, b.Limit, b.Throttled.Round(time.Millisecond))
+}
+
+// rateLimiter tracks the X-RateLimit-* headers of the panel's responses and
+// delays requests before the budget runs out, so a batch over many servers
+// slows down instead of failing with 429. Its zero value is ready to use.
+type rateLimiter struct {
+	mu     sync.Mutex
+	budget Budget
+	next   time.Time // earliest time for the next request
+}
+
+// wait blocks until the next request may be sent.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	d := time.Until(l.next)
+	if d > 0 {
+		l.budget.Throttled += d
+	}
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe records a response. Once the remaining budget drops to a small
+// reserve, requests are spaced out to the rate the limit refills at; a 429
+// holds every request for the Retry-After the panel sends. It reports
+// whether the request was rate limited and should be retried.
+func (l *rateLimiter) observe(resp *http.Response) (limited bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.budget.Requests++
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil && n > 0 {
+		l.budget.Limit = n
+	}
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		l.budget.Remaining = n
+	}
+
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.budget.Remaining = 0
+		l.next = now.Add(retryAfter(resp.Header.Get("Retry-After")))
+		return true
+	}
+	if lim := l.budget.Limit; lim > 0 && l.budget.Remaining <= max(2, lim/10) {
+		l.next = now.Add(rateWindow / time.Duration(lim))
+	}
+	return false
+}
+
+// snapshot returns the current budget.
+func (l *rateLimiter) snapshot() Budget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.budget
+}
+
+// retryAfter parses a Retry-After header in seconds, falling back to a
+// whole window when it is missing or an HTTP date.
+func retryAfter(h string) time.Duration {
+	if n, err := strconv.Atoi(h); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+	return rateWindow
+}
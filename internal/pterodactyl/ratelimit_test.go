@@ -0,0 +1,87 @@
+package pterodactyl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	defer func(d time.Duration) { rateWindow = d }(rateWindow)
+	rateWindow = 400 * time.Millisecond
+
+	// The panel allows 20 requests per window and rejects the third request
+	// once with 429.
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		w.Header().Set("X-RateLimit-Limit", "20")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(0, 20-n)))
+		if n == 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Too Many Attempts.", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "key")
+	var logged int
+	c.Log = func(string, ...any) { logged++ }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.ListBackups(ctx, "srv"); err != nil {
+			t.Fatalf("ListBackups %d: %v", i, err)
+		}
+	}
+	b := c.Budget()
+	if b.Requests != 4 || b.Limit != 20 || b.Remaining != 16 || logged != 4 {
+		t.Errorf("after a retried 429: budget %+v, %d log lines; want 4 requests, 16/20 left, 4 log lines", b, logged)
+	}
+	if b.Throttled != 0 {
+		t.Errorf("throttled %s with plenty of budget left", b.Throttled)
+	}
+
+	// With 2 of 20 left, requests are spaced a twentieth of the window apart.
+	mu.Lock()
+	calls = 17
+	mu.Unlock()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.ListBackups(ctx, "srv"); err != nil {
+			t.Fatalf("ListBackups: %v", err)
+		}
+	}
+	if elapsed, min := time.Since(start), 2*rateWindow/20; elapsed < min {
+		t.Errorf("3 requests near the limit took %s, want at least %s", elapsed, min)
+	}
+	if c.Budget().Throttled == 0 {
+		t.Error("Budget().Throttled = 0 after requests near the limit")
+	}
+}
+
+func TestRateLimitGivesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "Too Many Attempts.", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "key")
+	if _, err := c.ListBackups(context.Background(), "srv"); err == nil {
+		t.Fatal("ListBackups succeeded although every request was rate limited")
+	}
+	if got := c.Budget().Requests; got != maxRateRetries+1 {
+		t.Errorf("sent %d requests, want %d", got, maxRateRetries+1)
+	}
+}
@@ -0,0 +1,189 @@
+package pufferpanel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client interacts with the PufferPanel API. It authenticates with an OAuth2
+// client (Account → OAuth2 Clients) and renews the access token before it
+// expires.
+type Client struct {
+	PanelURL     string
+	ClientID     string
+	ClientSecret string
+	HTTP         *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewClient creates a new PufferPanel API client.
+func NewClient(panelURL, clientID, clientSecret string) *Client {
+	return &Client{
+		PanelURL:     strings.TrimRight(panelURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		HTTP:         &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Backup represents a single backup entry from the PufferPanel API.
+type Backup struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	FileName  string    `json:"fileName"`
+	FileSize  int64     `json:"fileSize"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"` // seconds
+}
+
+// Token returns a valid access token, requesting a new one when none was
+// issued yet or the current one expires within a minute.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expires) > time.Minute {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+	}
+	tokenURL := c.PanelURL + "/oauth2/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("executing request to %s: %w", tokenURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("OAuth2 token request returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result tokenResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("decoding token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("empty access token returned for client %s", c.ClientID)
+	}
+	c.token = result.AccessToken
+	c.expires = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// AuthHeader returns the Authorization header the API and backup downloads
+// expect.
+func (c *Client) AuthHeader(ctx context.Context) (http.Header, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return http.Header{"Authorization": {"Bearer " + token}}, nil
+}
+
+// doRequest sends an API request. A non-nil payload is encoded as the JSON
+// request body.
+func (c *Client) doRequest(ctx context.Context, method, path string, payload any) ([]byte, error) {
+	url := c.PanelURL + path
+
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned status %d for %s: %s", resp.StatusCode, url, string(body))
+	}
+
+	return body, nil
+}
+
+// ListBackups returns all backups for a given server, sorted by creation time
+// (newest first).
+func (c *Client) ListBackups(ctx context.Context, serverID string) ([]Backup, error) {
+	body, err := c.doRequest(ctx, http.MethodGet, "/api/servers/"+serverID+"/backup", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	if err := json.Unmarshal(body, &backups); err != nil {
+		return nil, fmt.Errorf("decoding backup list: %w", err)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// CreateBackup asks the panel to back up the server as name. The panel
+// creates the archive in the background; poll ListBackups for it.
+func (c *Client) CreateBackup(ctx context.Context, serverID, name string) error {
+	_, err := c.doRequest(ctx, http.MethodPost, "/api/servers/"+serverID+"/backup/create", map[string]string{"name": name})
+	return err
+}
+
+// DownloadURL returns the URL of the backup archive. Unlike Pterodactyl's
+// signed links it needs the header from AuthHeader.
+func (c *Client) DownloadURL(serverID string, backupID int) string {
+	return c.PanelURL + "/api/servers/" + serverID + "/backup/download/" + strconv.Itoa(backupID)
+}
@@ -0,0 +1,13 @@
+// Registers the map's service worker (sw.js next to index.html). Loaded as a
+// separate file so it works under the default Content-Security-Policy.
+(function () {
+  "use strict";
+
+  if ("serviceWorker" in navigator) {
+    window.addEventListener("load", function () {
+      navigator.serviceWorker.register("./sw.js").catch(function (err) {
+        console.warn("service worker registration failed:", err);
+      });
+    });
+  }
+})();
@@ -0,0 +1,81 @@
+// Service worker generated by bluemap-action. Each deploy gets its own cache,
+// so a new render replaces everything cached from the previous one.
+//
+// Cached: the viewer shell (precached on install) and low-res tiles (on first
+// use). Everything else, including hi-res tiles, settings.json and live data,
+// goes to the network as usual.
+"use strict";
+
+var CACHE = {{.Cache}};
+var PRECACHE = {{.Precache}};
+
+// Low-res tiles live in maps/<id>/tiles/<lod>/ with lod >= 1; lod 0 holds
+// the hi-res tiles.
+var LOWRES = /\/maps\/[^/]+\/tiles\/[1-9][0-9]*\//;
+
+self.addEventListener("install", function (event) {
+  event.waitUntil(
+    caches.open(CACHE).then(function (cache) {
+      return cache.addAll(PRECACHE);
+    }).then(function () {
+      return self.skipWaiting();
+    })
+  );
+});
+
+self.addEventListener("activate", function (event) {
+  event.waitUntil(
+    caches.keys().then(function (keys) {
+      return Promise.all(keys.filter(function (key) {
+        return key.indexOf("bluemap-") === 0 && key !== CACHE;
+      }).map(function (key) {
+        return caches.delete(key);
+      }));
+    }).then(function () {
+      return self.clients.claim();
+    })
+  );
+});
+
+self.addEventListener("fetch", function (event) {
+  var request = event.request;
+  if (request.method !== "GET") {
+    return;
+  }
+  var url = new URL(request.url);
+  if (url.origin !== self.location.origin) {
+    return;
+  }
+
+  // Pages: network first so a new deploy shows up, the cached shell offline.
+  if (request.mode === "navigate") {
+    event.respondWith(
+      fetch(request).catch(function () {
+        return caches.match("./", { ignoreSearch: true });
+      })
+    );
+    return;
+  }
+
+  var shell = PRECACHE.some(function (path) {
+    return new URL(path, self.location).pathname === url.pathname;
+  });
+  if (!shell && !LOWRES.test(url.pathname)) {
+    return;
+  }
+  event.respondWith(
+    caches.open(CACHE).then(function (cache) {
+      return cache.match(request).then(function (cached) {
+        if (cached) {
+          return cached;
+        }
+        return fetch(request).then(function (response) {
+          if (response.ok) {
+            cache.put(request, response.clone());
+          }
+          return response;
+        });
+      });
+    })
+  );
+});
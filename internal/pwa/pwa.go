@@ -0,0 +1,189 @@
+package pwa
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/EfinaServer/bluemap-action/internal/webmeta"
+	"github.com/EfinaServer/bluemap-action/internal/webpage"
+)
+
+//go:embed files/*
+var files embed.FS
+
+// DefaultThemeColor is BlueMap's own theme color, used when no accent color
+// is configured.
+const DefaultThemeColor = "#006EDE"
+
+// Options configures the generated web app manifest and service worker.
+type Options struct {
+	Name       string // App name shown when installed
+	ThemeColor string // empty = DefaultThemeColor
+	Icon       string // Icon path relative to web/, e.g. "logo.png"; empty = BlueMap's logos
+	Version    string // Per-deploy token naming the service worker cache
+}
+
+var (
+	manifestLinkRe = regexp.MustCompile(`(<link\s+rel="manifest"[^>]*?\shref=")[^"]*(")`)
+	registerScript = `<script src="./sw-register.js" defer></script>`
+)
+
+// bluemapIcons are the icons shipped in BlueMap's web/assets/.
+var bluemapIcons = []manifestIcon{
+	{Src: "assets/logoCircle512.png", Sizes: "512x512", Type: "image/png"},
+	{Src: "assets/logoCircle64.png", Sizes: "64x64", Type: "image/png"},
+}
+
+type manifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// Deploy makes the web output under serverDir an installable web app: it
+// writes web/manifest.json and links it instead of BlueMap's own manifest,
+// and adds a service worker (web/sw.js) that caches the viewer shell and the
+// low-res tiles, registered by web/sw-register.js. Re-running replaces the
+// files of the previous run.
+func Deploy(serverDir string, opts Options) error {
+	webDir := filepath.Join(serverDir, "web")
+
+	if err := writeManifest(webDir, opts); err != nil {
+		return err
+	}
+
+	precache, err := shellFiles(webDir)
+	if err != nil {
+		return err
+	}
+	if err := writeServiceWorker(webDir, "bluemap-"+opts.Version, precache); err != nil {
+		return err
+	}
+	fmt.Printf("  ✔  sw.js caches %d shell files and low-res tiles\n", len(precache))
+
+	indexPath := filepath.Join(webDir, "index.html")
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", indexPath, err)
+	}
+	page := string(data)
+	if manifestLinkRe.MatchString(page) {
+		page = manifestLinkRe.ReplaceAllString(page, "${1}./manifest.json${2}")
+	} else {
+		page, _ = webpage.Insert(page, "</head>", `<link rel="manifest" href="./manifest.json">`)
+	}
+	if !strings.Contains(page, registerScript) {
+		page, _ = webpage.Insert(page, "</head>", registerScript)
+	}
+	if err := os.WriteFile(indexPath, []byte(page), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", indexPath, err)
+	}
+	return nil
+}
+
+// writeManifest writes web/manifest.json.
+func writeManifest(webDir string, opts Options) error {
+	icons := make([]manifestIcon, 0, len(bluemapIcons))
+	if opts.Icon != "" {
+		icons = append(icons, manifestIcon{Src: opts.Icon, Sizes: "any", Type: webmeta.Detect(opts.Icon).ContentType})
+	}
+	for _, icon := range bluemapIcons {
+		if _, err := os.Stat(filepath.Join(webDir, filepath.FromSlash(icon.Src))); err == nil {
+			icons = append(icons, icon)
+		}
+	}
+	themeColor := opts.ThemeColor
+	if themeColor == "" {
+		themeColor = DefaultThemeColor
+	}
+
+	manifest := map[string]any{
+		"name":             opts.Name,
+		"short_name":       opts.Name,
+		"start_url":        "./",
+		"scope":            "./",
+		"display":          "standalone",
+		"background_color": "#181818",
+		"theme_color":      themeColor,
+		"icons":            icons,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest.json: %w", err)
+	}
+	path := filepath.Join(webDir, "manifest.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	fmt.Printf("  ✔  manifest.json (%s, %d icon(s))\n", opts.Name, len(icons))
+	return nil
+}
+
+// shellFiles lists the viewer shell precached by the service worker: the
+// page itself, the webapp bundle and styles in assets/, and the language
+// files, as URLs relative to web/. Source maps and pre-compressed variants
+// are left out.
+func shellFiles(webDir string) ([]string, error) {
+	shell := []string{"./"}
+	for _, dir := range []string{"assets", "lang"} {
+		entries, err := os.ReadDir(filepath.Join(webDir, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || strings.HasSuffix(name, ".map") || webmeta.Detect(name).Compressed() {
+				continue
+			}
+			shell = append(shell, dir+"/"+name)
+		}
+	}
+	sort.Strings(shell[1:])
+	return shell, nil
+}
+
+// writeServiceWorker renders files/sw.js with the cache name and precache
+// list, and copies its registration script.
+func writeServiceWorker(webDir, cache string, precache []string) error {
+	src, err := files.ReadFile("files/sw.js")
+	if err != nil {
+		return fmt.Errorf("reading embedded sw.js: %w", err)
+	}
+	tmpl, err := template.New("sw.js").Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("parsing sw.js: %w", err)
+	}
+	cacheJSON, _ := json.Marshal(cache)
+	precacheJSON, _ := json.MarshalIndent(precache, "", "  ")
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, map[string]string{
+		"Cache":    string(cacheJSON),
+		"Precache": string(precacheJSON),
+	}); err != nil {
+		return fmt.Errorf("rendering sw.js: %w", err)
+	}
+	path := filepath.Join(webDir, "sw.js")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	register, err := files.ReadFile("files/sw-register.js")
+	if err != nil {
+		return fmt.Errorf("reading embedded sw-register.js: %w", err)
+	}
+	path = filepath.Join(webDir, "sw-register.js")
+	if err := os.WriteFile(path, register, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package pwa
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeploy(t *testing.T) {
+	dir := t.TempDir()
+	web := filepath.Join(dir, "web")
+	for name, content := range map[string]string{
+		"index.html":                   "<html>\n    <head>\n        <link rel=\"manifest\" href=\"./assets/manifest-vz4Wm4Dd.webmanifest\">\n    </head>\n</html>\n",
+		"assets/index-Pvo3QD2A.js":     "js",
+		"assets/index-Pvo3QD2A.js.map": "map",
+		"assets/index-BgiqB2rB.css":    "css",
+		"assets/index-BgiqB2rB.css.gz": "gz",
+		"assets/logoCircle512.png":     "png",
+		"lang/en.conf":                 "conf",
+		"maps/world/tiles/1/x0/z0.png": "tile",
+	} {
+		path := filepath.Join(web, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, version := range []string{"first", "second"} {
+		if err := Deploy(dir, Options{Name: "Survival", Version: version}); err != nil {
+			t.Fatalf("Deploy: %v", err)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(web, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `<link rel="manifest" href="./manifest.json">`) {
+		t.Errorf("manifest link not replaced:\n%s", page)
+	}
+	if n := strings.Count(string(page), "sw-register.js"); n != 1 {
+		t.Errorf("registration script included %d times, want 1:\n%s", n, page)
+	}
+
+	var manifest struct {
+		Name       string `json:"name"`
+		ThemeColor string `json:"theme_color"`
+		Icons      []struct {
+			Src string `json:"src"`
+		} `json:"icons"`
+	}
+	data, err := os.ReadFile(filepath.Join(web, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	if manifest.Name != "Survival" || manifest.ThemeColor != DefaultThemeColor ||
+		len(manifest.Icons) != 1 || manifest.Icons[0].Src != "assets/logoCircle512.png" {
+		t.Errorf("unexpected manifest.json:\n%s", data)
+	}
+
+	sw, err := os.ReadFile(filepath.Join(web, "sw.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`var CACHE = "bluemap-second";`, `"assets/index-Pvo3QD2A.js"`} {
+		if !strings.Contains(string(sw), want) {
+			t.Errorf("sw.js lacks %s", want)
+		}
+	}
+
+	shell, err := shellFiles(web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "./ assets/index-BgiqB2rB.css assets/index-Pvo3QD2A.js assets/logoCircle512.png lang/en.conf"
+	if got := strings.Join(shell, " "); got != want {
+		t.Errorf("shell files = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+// Package runreport keeps the headline numbers of a deployed build, so the
+// next build can show how the map changed since.
+package runreport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the report file name. Like the file manifest it lives next
+// to config.toml, outside web/, and the workflow caches it between runs.
+const FileName = ".bluemap-report.json"
+
+// Report is the headline numbers of a build.
+type Report struct {
+	Time          time.Time `json:"time"`
+	BackupUUID    string    `json:"backup_uuid,omitempty"`
+	BackupCreated time.Time `json:"backup_created,omitzero"` // when the panel made the backup; zero if it did not say
+	WorldSize     int64     `json:"world_size"`
+	WebSize       int64     `json:"web_size"`
+	WebFiles      int64     `json:"web_files"`
+	Tiles         int64     `json:"tiles"`
+	RenderSeconds float64   `json:"render_seconds,omitempty"` // 0 when the build did not render
+	Maps          []Map     `json:"maps,omitempty"`
+}
+
+// Map is the output of one map in a Report.
+type Map struct {
+	ID    string `json:"id"`
+	Size  int64  `json:"size"`
+	Tiles int64  `json:"tiles"`
+}
+
+// Path returns the report location for the given server directory.
+func Path(serverDir string) string {
+	return filepath.Join(serverDir, FileName)
+}
+
+// Load reads the report of serverDir. A missing file is not an error and
+// returns nil.
+func Load(serverDir string) (*Report, error) {
+	data, err := os.ReadFile(Path(serverDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FileName, err)
+	}
+	return &r, nil
+}
+
+// Save writes r to path, which is Path of the server directory or a file
+// that is later moved there.
+func (r Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Kinds of a Change, which tell how to format its values.
+const (
+	KindSize     = "size"     // bytes
+	KindCount    = "count"    // files or tiles
+	KindDuration = "duration" // seconds
+)
+
+// Change is a number of the current build next to the previous one.
+type Change struct {
+	Metric   string
+	Map      string // map ID for the numbers of a single map
+	Kind     string
+	Previous float64
+	Current  float64
+}
+
+// Delta returns the change from the previous build.
+func (c Change) Delta() float64 {
+	return c.Current - c.Previous
+}
+
+// Percent returns the change relative to the previous build, or 0 when the
+// previous value was 0.
+func (c Change) Percent() float64 {
+	if c.Previous == 0 {
+		return 0
+	}
+	return 100 * c.Delta() / c.Previous
+}
+
+// Compare returns the changes from prev to cur: world and web output size,
+// files, tiles, the render time when both builds rendered, and the size and
+// tiles of every map in either of them.
+func Compare(prev, cur *Report) []Change {
+	changes := []Change{
+		{Metric: "World Size", Kind: KindSize, Previous: float64(prev.WorldSize), Current: float64(cur.WorldSize)},
+		{Metric: "Web Output", Kind: KindSize, Previous: float64(prev.WebSize), Current: float64(cur.WebSize)},
+		{Metric: "Files", Kind: KindCount, Previous: float64(prev.WebFiles), Current: float64(cur.WebFiles)},
+		{Metric: "Tiles", Kind: KindCount, Previous: float64(prev.Tiles), Current: float64(cur.Tiles)},
+	}
+	if prev.RenderSeconds > 0 && cur.RenderSeconds > 0 {
+		changes = append(changes, Change{Metric: "Render Time", Kind: KindDuration, Previous: prev.RenderSeconds, Current: cur.RenderSeconds})
+	}
+
+	prevMaps := make(map[string]Map, len(prev.Maps))
+	for _, m := range prev.Maps {
+		prevMaps[m.ID] = m
+	}
+	ids := make([]string, 0, len(cur.Maps)+len(prev.Maps))
+	curMaps := make(map[string]Map, len(cur.Maps))
+	for _, m := range cur.Maps {
+		curMaps[m.ID] = m
+		ids = append(ids, m.ID)
+	}
+	for _, m := range prev.Maps {
+		if _, ok := curMaps[m.ID]; !ok {
+			ids = append(ids, m.ID)
+		}
+	}
+	for _, id := range ids {
+		p, c := prevMaps[id], curMaps[id]
+		changes = append(changes,
+			Change{Metric: "Size", Map: id, Kind: KindSize, Previous: float64(p.Size), Current: float64(c.Size)},
+			Change{Metric: "Tiles", Map: id, Kind: KindCount, Previous: float64(p.Tiles), Current: float64(c.Tiles)},
+		)
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package runreport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if r, err := Load(dir); err != nil || r != nil {
+		t.Fatalf("Load without a report = %v, %v; want nil, nil", r, err)
+	}
+	want := Report{
+		Time:      time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		WorldSize: 100,
+		WebSize:   50,
+		Tiles:     3,
+		Maps:      []Map{{ID: "world", Size: 40, Tiles: 3}},
+	}
+	if err := want.Save(Path(dir)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !got.Time.Equal(want.Time) || got.WorldSize != 100 || got.Tiles != 3 || len(got.Maps) != 1 || got.Maps[0] != want.Maps[0] {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	prev := &Report{
+		WorldSize: 1000, WebSize: 500, WebFiles: 10, Tiles: 8, RenderSeconds: 60,
+		Maps: []Map{{ID: "world", Size: 400, Tiles: 6}, {ID: "old", Size: 100, Tiles: 2}},
+	}
+	cur := &Report{
+		WorldSize: 1100, WebSize: 450, WebFiles: 10, Tiles: 9,
+		Maps: []Map{{ID: "world", Size: 420, Tiles: 7}, {ID: "end", Size: 30, Tiles: 2}},
+	}
+	changes := Compare(prev, cur)
+
+	byName := map[string]Change{}
+	for _, c := range changes {
+		byName[c.Map+"/"+c.Metric] = c
+	}
+	if c := byName["/World Size"]; c.Delta() != 100 || c.Percent() != 10 {
+		t.Errorf("world size delta %v (%v%%), want 100 (10%%)", c.Delta(), c.Percent())
+	}
+	if c := byName["/Web Output"]; c.Delta() != -50 {
+		t.Errorf("web output delta %v, want -50", c.Delta())
+	}
+	if _, ok := byName["/Render Time"]; ok {
+		t.Error("render time compared although the current build did not render")
+	}
+	if c := byName["world/Tiles"]; c.Previous != 6 || c.Current != 7 {
+		t.Errorf("world tiles %v → %v, want 6 → 7", c.Previous, c.Current)
+	}
+	if c := byName["end/Size"]; c.Previous != 0 || c.Current != 30 || c.Percent() != 0 {
+		t.Errorf("new map end: %+v", c)
+	}
+	if c := byName["old/Tiles"]; c.Previous != 2 || c.Current != 0 {
+		t.Errorf("removed map old: %+v", c)
+	}
+	// Current maps first, in their order, then the removed ones.
+	if n := len(changes); n != 4+6 || changes[4].Map != "world" || changes[6].Map != "end" || changes[8].Map != "old" {
+		t.Errorf("changes = %+v", changes)
+	}
+}
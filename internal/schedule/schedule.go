@@ -0,0 +1,187 @@
+// Package schedule parses the cron-like schedules of the watch command and
+// works out when they next fire.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed schedule: a five-field cron expression (minute, hour,
+// day of month, month, day of week) or a fixed interval.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n set = value n matches
+	domAny, dowAny                bool   // the field was "*"
+	every                         time.Duration
+}
+
+// macros are the @ shorthands for common cron expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Parse parses a schedule. It accepts a cron expression such as
+// "30 4 * * *" or "0 */6 * * mon-fri", with *, lists, ranges, steps and
+// English month and day names; one of @hourly, @daily (@midnight), @weekly,
+// @monthly and @yearly (@annually); or "@every <duration>", e.g.
+// "@every 2h", of at least a minute.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every interval: %w", err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("@every interval must be at least 1m, got %s", d)
+		}
+		return &Schedule{every: d}, nil
+	}
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	} else if strings.HasPrefix(expr, "@") {
+		return nil, fmt.Errorf("unknown schedule %q", expr)
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %q", expr)
+	}
+	var s Schedule
+	for i, f := range []struct {
+		name     string
+		bits     *uint64
+		min, max int
+		names    []string
+	}{
+		{"minute", &s.minute, 0, 59, nil},
+		{"hour", &s.hour, 0, 23, nil},
+		{"day of month", &s.dom, 1, 31, nil},
+		{"month", &s.month, 1, 12, monthNames},
+		{"day of week", &s.dow, 0, 7, dayNames},
+	} {
+		bits, err := parseField(fields[i], f.min, f.max, f.names)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.bits = bits
+	}
+	// 7 is Sunday as well as 0.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+	return &s, nil
+}
+
+// parseField parses one comma-separated cron field into a bit set of the
+// values in [min, max] it matches. names, when given, are the names of the
+// values from min upwards.
+func parseField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loText, hiText, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = fieldValue(loText, min, max, names); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = fieldValue(hiText, min, max, names); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("range %q runs backwards", rng)
+				}
+			case !hasStep:
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// fieldValue parses a number or name of a cron field.
+func fieldValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%q is not a value between %d and %d", s, min, max)
+	}
+	return n, nil
+}
+
+// Next returns the first time after the given one at which the schedule
+// fires, in the location of after. A cron expression fires at the start of
+// a matching minute; when both the day of month and the day of week are
+// restricted, a day matching either fires, as in cron. The zero time is
+// returned when the schedule never fires, e.g. on February 30.
+func (s *Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every)
+	}
+
+	// Start of the next minute. Stepping by durations rather than rebuilding
+	// the time with time.Date keeps the search moving forward through
+	// daylight saving time changes.
+	t := after.Add(time.Minute - time.Duration(after.Second())*time.Second - time.Duration(after.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of month and day
+// of week fields.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	// Friday.
+	from := time.Date(2026, 10, 16, 13, 47, 30, 0, time.UTC)
+	for _, tt := range []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", from, time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)},
+		{"30 4 * * *", from, time.Date(2026, 10, 17, 4, 30, 0, 0, time.UTC)},
+		{"0 */6 * * *", from, time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", from, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", from, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week when both are restricted.
+		{"0 12 1 * sat", from, time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC), time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)},
+		{"@every 90m", from, from.Add(90 * time.Minute)},
+		// Evaluated in the location of the start time.
+		{"0 3 * * *", from.In(taipei), time.Date(2026, 10, 17, 3, 0, 0, 0, taipei)},
+		{"0 0 30 feb *", from, time.Time{}},
+	} {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	// 02:30 does not exist on 2026-03-08; the next 02:30 is a day later.
+	s, _ := Parse("30 2 * * *")
+	from := time.Date(2026, 3, 8, 1, 0, 0, 0, ny)
+	if got, want := s.Next(from), time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@sometimes",
+		"@every 30s",
+		"@every soon",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected an error", expr)
+		}
+	}
+}
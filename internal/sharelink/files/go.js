@@ -0,0 +1,74 @@
+// Resolves short share links such as /go?x=120&z=-340&world=overworld to the
+// BlueMap webapp's hash-based camera URL:
+//
+//   #<map>:<x>:<y>:<z>:<distance>:<rotation>:<angle>:<tilt>:<ortho>:<controls>
+//
+// Query parameters:
+//   x, z      block coordinates (required; missing values fall back to 0)
+//   y         camera target height (default 64)
+//   world     map id, alias "map" (default: first map in settings.json)
+//   zoom      camera distance (default 300)
+//   view      "flat" or "perspective" (default perspective)
+(function () {
+  "use strict";
+
+  var params = new URLSearchParams(window.location.search);
+
+  // The helper lives at <base>/go/index.html and is served as <base>/go, so
+  // strip that suffix to find the webapp root, even when hosted in a subpath.
+  var base = window.location.pathname.replace(/\/go(\/(index\.html)?)?$/, "/");
+
+  function num(name, fallback) {
+    var value = Number(params.get(name));
+    if (params.get(name) === null || params.get(name) === "" || !isFinite(value)) {
+      return fallback;
+    }
+    return Math.round(value * 100) / 100;
+  }
+
+  function redirect(map) {
+    var flat = params.get("view") === "flat";
+    var hash = [
+      map,
+      num("x", 0),
+      num("y", 64),
+      num("z", 0),
+      Math.max(1, num("zoom", 300)),
+      0,
+      flat ? 0 : 0.5,
+      0,
+      flat ? 1 : 0,
+      flat ? "flat" : "perspective"
+    ].join(":");
+    window.location.replace(base + "#" + hash);
+  }
+
+  var requested = params.get("world") || params.get("map") || "";
+  if (!/^[A-Za-z0-9_.-]*$/.test(requested)) {
+    requested = "";
+  }
+
+  var xhr = new XMLHttpRequest();
+  xhr.open("GET", base + "settings.json");
+  xhr.onload = function () {
+    var maps = [];
+    try {
+      maps = JSON.parse(xhr.responseText).maps || [];
+    } catch (e) {}
+    if (requested && (maps.length === 0 || maps.indexOf(requested) >= 0)) {
+      redirect(requested);
+    } else if (maps.length > 0) {
+      redirect(maps[0]);
+    } else {
+      window.location.replace(base);
+    }
+  };
+  xhr.onerror = function () {
+    if (requested) {
+      redirect(requested);
+    } else {
+      window.location.replace(base);
+    }
+  };
+  xhr.send();
+})();
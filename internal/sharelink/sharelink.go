@@ -0,0 +1,45 @@
+package sharelink
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed files/*
+var helperFiles embed.FS
+
+// Path is the URL path of the share link helper relative to the web root.
+const Path = "/go"
+
+// Deploy writes the share link helper into web/go/ under serverDir. The
+// helper turns short links like /go?x=120&z=-340&world=overworld into the
+// BlueMap webapp's hash-based camera URL, so locations can be shared without
+// copying the long #map:x:y:z:... fragment.
+func Deploy(serverDir string) error {
+	targetDir := filepath.Join(serverDir, "web", "go")
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return fmt.Errorf("creating share link directory %s: %w", targetDir, err)
+	}
+
+	entries, err := fs.ReadDir(helperFiles, "files")
+	if err != nil {
+		return fmt.Errorf("reading embedded share link files: %w", err)
+	}
+
+	for _, entry := range entries {
+		data, err := fs.ReadFile(helperFiles, "files/"+entry.Name())
+		if err != nil {
+			return fmt.Errorf("reading embedded %s: %w", entry.Name(), err)
+		}
+
+		targetPath := filepath.Join(targetDir, entry.Name())
+		if err := os.WriteFile(targetPath, data, 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", targetPath, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package sharelink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeploy(t *testing.T) {
+	dir := t.TempDir()
+	if err := Deploy(dir); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+
+	html, err := os.ReadFile(filepath.Join(dir, "web", "go", "index.html"))
+	if err != nil {
+		t.Fatalf("reading index.html: %v", err)
+	}
+	// Inline scripts would be blocked by the default Content-Security-Policy.
+	if !strings.Contains(string(html), `<script src="go.js"`) {
+		t.Errorf("index.html does not load go.js as an external script:\n%s", html)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "web", "go", "go.js")); err != nil {
+		t.Errorf("go.js not deployed: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDirName is the debug directory created inside the server directory
+// when --keep-intermediate is used without an explicit -debug-dir.
+const DefaultDirName = ".bluemap-debug"
+
+// Snapshot preserves the intermediate artifacts of a run (downloaded backup
+// archive, extracted worlds, BlueMap output) in a debug directory so a failed
+// render can be reproduced locally.
+type Snapshot struct {
+	Dir string
+}
+
+// New creates the debug directory and returns a Snapshot rooted at it.
+func New(dir string) (*Snapshot, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(absDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating debug directory %s: %w", absDir, err)
+	}
+	return &Snapshot{Dir: absDir}, nil
+}
+
+// ArchivePath is where the downloaded backup archive is kept.
+func (s *Snapshot) ArchivePath() string {
+	return filepath.Join(s.Dir, "backup.tar.gz")
+}
+
+// IndexPath is where the tar index of the kept archive is stored. It records
+// the backup UUID, so a later run against the same backup can reuse the
+// archive instead of downloading it again.
+func (s *Snapshot) IndexPath() string {
+	return filepath.Join(s.Dir, "backup.index.json")
+}
+
+// RenderLogPath is where BlueMap CLI stdout and stderr are captured.
+func (s *Snapshot) RenderLogPath() string {
+	return filepath.Join(s.Dir, "bluemap-render.log")
+}
+
+// WorldsDir is where extracted worlds are preserved.
+func (s *Snapshot) WorldsDir() string {
+	return filepath.Join(s.Dir, "worlds")
+}
+
+// KeepWorlds preserves each extracted world from serverDir under WorldsDir.
+// Files are hard-linked where possible so large worlds cost no extra disk
+// space, falling back to a copy across filesystems. The extractor replaces
+// files instead of rewriting them, so a later extraction into serverDir
+// leaves the kept worlds untouched.
+func (s *Snapshot) KeepWorlds(serverDir string, worlds []string) error {
+	for _, w := range worlds {
+		src := filepath.Join(serverDir, w)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		dst := filepath.Join(s.WorldsDir(), w)
+		if err := os.RemoveAll(dst); err != nil {
+			return fmt.Errorf("clearing %s: %w", dst, err)
+		}
+		if err := linkTree(src, dst); err != nil {
+			return fmt.Errorf("preserving world %s: %w", w, err)
+		}
+	}
+	return nil
+}
+
+// WriteReproScript writes reproduce.sh into the debug directory with the
+// exact commands needed to restore the extracted worlds and re-run the
+// render, and returns its path.
+func (s *Snapshot) WriteReproScript(serverDir string, worlds []string, renderCmd []string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("# Reproduce the bluemap-action render locally.\n")
+	sb.WriteString("set -e\n\n")
+	sb.WriteString(fmt.Sprintf("cd %s\n\n", shellQuote(serverDir)))
+
+	sb.WriteString("# Restore the worlds exactly as they were extracted. The full backup\n")
+	sb.WriteString(fmt.Sprintf("# archive is also kept at %s.\n", s.ArchivePath()))
+	for _, w := range worlds {
+		sb.WriteString(fmt.Sprintf("rm -rf %s && cp -R %s %s\n",
+			shellQuote(w), shellQuote(filepath.Join(s.WorldsDir(), w)), shellQuote(w)))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("# Re-run the render.\n")
+	quoted := make([]string, len(renderCmd))
+	for i, arg := range renderCmd {
+		quoted[i] = shellQuote(arg)
+	}
+	sb.WriteString(strings.Join(quoted, " ") + "\n")
+
+	path := filepath.Join(s.Dir, "reproduce.sh")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o755); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// shellQuote quotes s for POSIX sh when it contains anything beyond a safe
+// set of characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// linkTree recreates the directory tree at src under dst, hard-linking files
+// and copying them when linking fails.
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if err := os.Link(path, target); err == nil {
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
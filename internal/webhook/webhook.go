@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SecretEnv holds the optional key the payload is signed with.
+const SecretEnv = "BLUEMAP_WEBHOOK_SECRET"
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the request
+// body keyed with the secret, the scheme GitHub webhooks use, so receivers
+// can reject requests that did not come from the build.
+const SignatureHeader = "X-BlueMap-Signature-256"
+
+// Events of the payloads: EventMapUpdated is sent after a deploy and
+// EventRenderFailed when the BlueMap CLI fails.
+const (
+	EventMapUpdated   = "map_updated"
+	EventRenderFailed = "render_failed"
+)
+
+// Payload is the JSON body posted to the webhook.
+type Payload struct {
+	Event           string    `json:"event"`
+	Project         string    `json:"project"`
+	ServerID        string    `json:"server_id"`
+	MapURL          string    `json:"map_url,omitempty"`
+	BlueMapVersion  string    `json:"bluemap_version"`
+	BackupName      string    `json:"backup_name"`
+	BackupCreatedAt time.Time `json:"backup_created_at"`
+	RenderTime      string    `json:"render_time"` // formatted with timezone and time_format
+	RenderSeconds   float64   `json:"render_duration_seconds"`
+	DeployedTo      string    `json:"deployed_to,omitempty"`
+
+	// Set for EventRenderFailed: the error, the exception BlueMap threw and
+	// the render output lines that explain the failure.
+	Error      string   `json:"error,omitempty"`
+	Exception  string   `json:"exception,omitempty"`
+	LogExcerpt []string `json:"log_excerpt,omitempty"`
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Send posts payload as JSON to rawURL, signed with secret when it is set.
+// Any status other than 2xx is an error. Errors name only the host, since
+// webhook URLs of Discord or Slack contain a token.
+func Send(ctx context.Context, rawURL string, payload Payload, secret, userAgent string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(body, secret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("posting to webhook at %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook at %s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the value of SignatureHeader for body.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSend(t *testing.T) {
+	var got Payload
+	var signature, contentType string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		signature = r.Header.Get(SignatureHeader)
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	payload := Payload{
+		Event:           EventMapUpdated,
+		Project:         "survival",
+		MapURL:          "https://map.example.com",
+		BackupCreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		RenderSeconds:   90,
+	}
+	if err := Send(context.Background(), srv.URL+"/hook", payload, "s3cret", "bluemap-action/test"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !reflect.DeepEqual(got, payload) {
+		t.Errorf("received %+v, want %+v", got, payload)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if want := Sign(body, "s3cret"); signature != want || !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(SignatureHeader) != "" {
+			t.Error("request signed without a secret")
+		}
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := Send(context.Background(), srv.URL+"/api/webhooks/123/token", Payload{}, "", "bluemap-action/test")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("err = %v, want the 404 status", err)
+	}
+	if strings.Contains(err.Error(), "token") {
+		t.Errorf("error %q reveals the webhook path", err)
+	}
+}
@@ -0,0 +1,205 @@
+package webmeta
+
+import (
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Metadata is the HTTP response metadata a hosting target should attach to a
+// file from the web output.
+type Metadata struct {
+	ContentType     string
+	ContentEncoding string // empty for files served as-is
+}
+
+// Compressed reports whether the file is a pre-compressed variant that must
+// be served with a Content-Encoding header.
+func (m Metadata) Compressed() bool {
+	return m.ContentEncoding != ""
+}
+
+// encodings maps pre-compressed file suffixes to their Content-Encoding.
+var encodings = map[string]string{
+	".gz":  "gzip",
+	".br":  "br",
+	".zst": "zstd",
+}
+
+// contentTypes overrides mime.TypeByExtension for BlueMap-specific and
+// commonly misdetected extensions.
+var contentTypes = map[string]string{
+	".prbm": "application/octet-stream",
+	".json": "application/json",
+	".js":   "text/javascript; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+	".conf": "text/plain; charset=utf-8",
+}
+
+// Detect returns the metadata for the file at path. Pre-compressed variants
+// such as "x.prbm.gz" or "textures.json.gz" get the Content-Type of the inner
+// file and the matching Content-Encoding, so browsers decompress them
+// transparently; everything else is served with its own Content-Type.
+func Detect(path string) Metadata {
+	name := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(name)
+
+	var m Metadata
+	if enc, ok := encodings[ext]; ok {
+		m.ContentEncoding = enc
+		name = strings.TrimSuffix(name, ext)
+		ext = filepath.Ext(name)
+	}
+	m.ContentType = contentType(ext)
+	return m
+}
+
+func contentType(ext string) string {
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// Rule describes response metadata for all files matching a URL glob. Targets
+// that configure headers by pattern (Netlify, Cloudflare Pages, Bunny edge
+// rules) render these rules in their own syntax.
+type Rule struct {
+	Pattern string // URL glob, e.g. "/*.prbm.gz"
+	Metadata
+}
+
+// CompressedRules returns the header rules for the pre-compressed files the
+// BlueMap webapp references directly (see assets.RewriteCompressedRefs).
+func CompressedRules() []Rule {
+	var rules []Rule
+	for _, p := range []string{"/*.json.gz", "/*.prbm.gz"} {
+		rules = append(rules, Rule{Pattern: p, Metadata: Detect(p)})
+	}
+	return rules
+}
+
+// Default Cache-Control values per class of web output. The webapp bundle in
+// assets/ (index-<hash>.js and .css) has content-hashed names and never
+// changes under the same URL, unless cache_bust rewrites the script in place.
+// The other files in assets/, such as the logo and player images, keep their
+// names across webapp versions, so they are revalidated. Tiles keep their URL
+// across renders, so they are cached for a day and revalidated in the
+// background rather than marked immutable. Map settings, textures and live
+// data change with every render and are cached briefly.
+const (
+	DefaultAssetsCache     = "public, max-age=31536000, immutable"
+	DefaultAssetFilesCache = "public, no-cache"
+	DefaultTilesCache      = "public, max-age=86400, stale-while-revalidate=604800"
+	DefaultDataCache       = "public, max-age=60, must-revalidate"
+
+	// CacheOff disables the Cache-Control header of a class, leaving the
+	// host's default.
+	CacheOff = "off"
+)
+
+// CachePolicy is the Cache-Control value per class of web output. Empty
+// fields take the defaults above.
+type CachePolicy struct {
+	Assets string // assets/index-*.js and .css: the hashed webapp bundle
+	Tiles  string // maps/<id>/tiles/*: hi-res and low-res tiles
+	Data   string // settings.json, textures.json and live data
+
+	// CacheBust reports that cache_bust rewrites the bundle script on every
+	// run without renaming it, so by default it is revalidated like the
+	// other asset files instead of cached as immutable.
+	CacheBust bool
+}
+
+// CacheRule is a Cache-Control value for all files matching a URL glob.
+type CacheRule struct {
+	Pattern      string
+	CacheControl string
+}
+
+// assetFilePatterns match the files in assets/ besides the bundle. Globs
+// cannot exclude the bundle, so they list the image and font types the
+// webapp ships; other types are left to the host's default.
+var assetFilePatterns = []string{
+	"/assets/*.png", "/assets/*.jpg", "/assets/*.jpeg", "/assets/*.gif", "/assets/*.webp",
+	"/assets/*.svg", "/assets/*.ico", "/assets/*.woff", "/assets/*.woff2", "/assets/*.ttf",
+}
+
+// CacheRules returns the Cache-Control rules for the web output under
+// policy. The patterns do not overlap, so no file gets two values. With the
+// assets class off, no rule covers assets/.
+func CacheRules(policy CachePolicy) []CacheRule {
+	script := policy.Assets
+	if script == "" && policy.CacheBust {
+		script = DefaultAssetFilesCache
+	}
+	files := DefaultAssetFilesCache
+	if policy.Assets == CacheOff {
+		files = CacheOff
+	}
+	var rules []CacheRule
+	for _, class := range []struct {
+		value, fallback string
+		patterns        []string
+	}{
+		{script, DefaultAssetsCache, []string{"/assets/index-*.js*"}},
+		{policy.Assets, DefaultAssetsCache, []string{"/assets/index-*.css*"}},
+		{files, DefaultAssetFilesCache, assetFilePatterns},
+		{policy.Tiles, DefaultTilesCache, []string{"/maps/*/tiles/*"}},
+		{policy.Data, DefaultDataCache, []string{"/settings.json", "/maps/*/settings.json", "/maps/*/textures.json*", "/maps/*/live/*"}},
+	} {
+		value := class.value
+		if value == "" {
+			value = class.fallback
+		}
+		if value == CacheOff {
+			continue
+		}
+		for _, p := range class.patterns {
+			rules = append(rules, CacheRule{Pattern: p, CacheControl: value})
+		}
+	}
+	return rules
+}
+
+// CacheControl returns the Cache-Control value for the file at the
+// slash-separated path rel under web/, or "" when no rule matches. It is
+// for targets that attach headers per file, such as object storage.
+func CacheControl(policy CachePolicy, rel string) string {
+	for _, rule := range CacheRules(policy) {
+		if matchGlob(rule.Pattern, "/"+rel) {
+			return rule.CacheControl
+		}
+	}
+	return ""
+}
+
+// matchGlob matches a URL against a rule pattern: a "*" matches within one
+// path segment, except a trailing "*", which matches the rest of the URL.
+func matchGlob(pattern, urlPath string) bool {
+	if re, ok := globs.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(urlPath)
+	}
+	glob, rest := pattern, ""
+	if strings.HasSuffix(glob, "*") {
+		glob, rest = strings.TrimSuffix(glob, "*"), ".*"
+	}
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := regexp.MustCompile("^" + strings.Join(parts, "[^/]*") + rest + "$")
+	globs.Store(pattern, re)
+	return re.MatchString(urlPath)
+}
+
+// globs caches the compiled rule patterns; a deploy matches every file.
+var globs sync.Map
@@ -0,0 +1,50 @@
+package webmeta
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		want Metadata
+	}{
+		{"maps/world/tiles/0/x1/z2.prbm.gz", Metadata{"application/octet-stream", "gzip"}},
+		{"maps/world/textures.json.gz", Metadata{"application/json", "gzip"}},
+		{"assets/index-abc.js.br", Metadata{"text/javascript; charset=utf-8", "br"}},
+		{"maps/world/tiles/1/x0/z0.png", Metadata{"image/png", ""}},
+		{"settings.json", Metadata{"application/json", ""}},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.path); got != tt.want {
+			t.Errorf("Detect(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	policy := CachePolicy{Data: CacheOff}
+	tests := []struct {
+		rel, want string
+	}{
+		{"assets/index-abc.js", DefaultAssetsCache},
+		{"assets/index-abc.css.gz", DefaultAssetsCache},
+		{"assets/logo.png", DefaultAssetFilesCache},
+		{"maps/world/tiles/0/x1/z2.prbm.gz", DefaultTilesCache},
+		{"maps/world/textures.json.gz", ""},
+		{"maps/world/tilesx/a", ""},
+		{"index.html", ""},
+	}
+	for _, tt := range tests {
+		if got := CacheControl(policy, tt.rel); got != tt.want {
+			t.Errorf("CacheControl(%q) = %q, want %q", tt.rel, got, tt.want)
+		}
+	}
+
+	// cache_bust rewrites the script in place, so it is not immutable.
+	policy.CacheBust = true
+	if got := CacheControl(policy, "assets/index-abc.js"); got != DefaultAssetFilesCache {
+		t.Errorf("CacheControl of the bundle with cache_bust = %q, want %q", got, DefaultAssetFilesCache)
+	}
+	if got := CacheControl(policy, "assets/index-abc.css"); got != DefaultAssetsCache {
+		t.Errorf("CacheControl of the styles with cache_bust = %q, want %q", got, DefaultAssetsCache)
+	}
+}
@@ -0,0 +1,25 @@
+// Package webpage edits the HTML of the BlueMap webapp's index.html.
+package webpage
+
+import "strings"
+
+// Insert adds each element on its own line before the last closing tag
+// (e.g. "</head>"), indented one level deeper than the tag. It reports
+// false and returns page unchanged when the tag is missing.
+func Insert(page, closing string, elements ...string) (string, bool) {
+	i := strings.LastIndex(page, closing)
+	if i < 0 {
+		return page, false
+	}
+	indent := page[strings.LastIndexByte(page[:i], '\n')+1 : i]
+	if strings.TrimSpace(indent) != "" {
+		indent = ""
+	}
+	var sb strings.Builder
+	sb.WriteString(page[:i])
+	for _, e := range elements {
+		sb.WriteString("    " + e + "\n" + indent)
+	}
+	sb.WriteString(page[i:])
+	return sb.String(), true
+}
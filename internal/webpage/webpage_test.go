@@ -0,0 +1,19 @@
+package webpage
+
+import "testing"
+
+func TestInsert(t *testing.T) {
+	page := "<html>\n  <head>\n    <title>BlueMap</title>\n  </head>\n<body></body>\n</html>\n"
+	got, ok := Insert(page, "</head>", `<link rel="manifest">`, `<meta name="a">`)
+	want := "<html>\n  <head>\n    <title>BlueMap</title>\n      <link rel=\"manifest\">\n      <meta name=\"a\">\n  </head>\n<body></body>\n</html>\n"
+	if !ok || got != want {
+		t.Errorf("Insert = %q, %v; want %q", got, ok, want)
+	}
+	// On the same line as other markup the tag keeps no indent.
+	if got, _ := Insert(page, "</body>", "<script></script>"); got != "<html>\n  <head>\n    <title>BlueMap</title>\n  </head>\n<body>    <script></script>\n</body>\n</html>\n" {
+		t.Errorf("Insert before </body> = %q", got)
+	}
+	if got, ok := Insert("<p>", "</head>", "x"); ok || got != "<p>" {
+		t.Errorf("Insert without the tag = %q, %v", got, ok)
+	}
+}
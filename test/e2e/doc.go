@@ -0,0 +1,13 @@
+// Package e2e holds the end-to-end test of the full bluemap-action pipeline:
+// a mock Pterodactyl panel serves a backup of a tiny generated world, the real
+// binary extracts it, renders it with BlueMap CLI, rewrites the compressed
+// asset references, and the resulting web/ directory is checked.
+//
+// The test needs Java and network access (BlueMap CLI and Minecraft resource
+// downloads), so it only builds with the e2e tag:
+//
+//	go test -tags e2e -v ./test/e2e/
+//
+// Set BLUEMAP_E2E_VERSION to choose the BlueMap CLI version (default 5.16) and
+// BLUEMAP_E2E_SHA256 to pin its checksum.
+package e2e
@@ -0,0 +1,190 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const serverID = "e2e00000"
+
+// mockPanel serves the Pterodactyl endpoints used by the pipeline and the
+// backup archive itself (with Range support via http.ServeContent).
+func mockPanel(t *testing.T, archive []byte) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+
+	mux.HandleFunc("/api/client/servers/"+serverID+"/backups", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data": []map[string]any{{
+				"object": "backup",
+				"attributes": map[string]any{
+					"uuid":          "00000000-0000-0000-0000-000000000001",
+					"name":          "e2e backup",
+					"is_successful": true,
+					"bytes":         len(archive),
+					"created_at":    time.Now().Format(time.RFC3339),
+				},
+			}},
+		})
+	})
+	mux.HandleFunc("/api/client/servers/"+serverID+"/backups/00000000-0000-0000-0000-000000000001/download", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"object":     "signed_url",
+			"attributes": map[string]any{"url": srv.URL + "/backup.tar.gz"},
+		})
+	})
+	mux.HandleFunc("/backup.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "backup.tar.gz", time.Now(), bytes.NewReader(archive))
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeServerDir creates a server directory with config.toml and a minimal
+// single-map BlueMap configuration.
+func writeServerDir(t *testing.T, dir string) {
+	t.Helper()
+
+	version := os.Getenv("BLUEMAP_E2E_VERSION")
+	if version == "" {
+		version = "5.16"
+	}
+	cfg := `server_id = "` + serverID + `"
+server_type = "vanilla"
+world_name = "world"
+mc_version = "1.21.1"
+bluemap_version = "` + version + `"
+name = "e2e"
+`
+	if sum := os.Getenv("BLUEMAP_E2E_SHA256"); sum != "" {
+		cfg += `bluemap_sha256 = "` + sum + `"` + "\n"
+	}
+
+	files := map[string]string{
+		"config.toml": cfg,
+		"config/core.conf": `accept-download: true
+data: "data"
+render-thread-count: 1
+metrics: false
+`,
+		"config/webapp.conf": `enabled: true
+webroot: "web"
+update-settings-file: true
+`,
+		"config/storages/file.conf": `storage-type: file
+root: "web/maps"
+compression: gzip
+`,
+		"config/maps/overworld.conf": `world: "world"
+dimension: "minecraft:overworld"
+name: "Overworld"
+storage: "file"
+ignore-missing-light-data: true
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	if _, err := exec.LookPath("java"); err != nil {
+		t.Skip("java not found in PATH; skipping end-to-end test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// Build the real binary.
+	bin := filepath.Join(t.TempDir(), "bluemap-action")
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, "../../cmd/bluemap-action")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+
+	panel := mockPanel(t, backupArchive(t))
+	serverDir := t.TempDir()
+	writeServerDir(t, serverDir)
+
+	run := exec.CommandContext(ctx, bin, "-dir", serverDir)
+	run.Env = append(os.Environ(),
+		"PTERODACTYL_PANEL_URL="+panel.URL,
+		"PTERODACTYL_API_KEY=e2e",
+		"CI=",
+		"GITHUB_ACTIONS=",
+		"GITLAB_CI=",
+	)
+	out, err := run.CombinedOutput()
+	t.Logf("pipeline output:\n%s", out)
+	if err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	// The rendered version must match the compatibility table in
+	// internal/bluemap; bump it there after checking a new release.
+	if strings.Contains(string(out), "web output:") {
+		t.Errorf("BlueMap web output differs from the expected layout; see the warnings above")
+	}
+
+	// Only the world folder may be extracted from the backup.
+	for _, unwanted := range []string{"server.properties", "logs"} {
+		if _, err := os.Stat(filepath.Join(serverDir, unwanted)); err == nil {
+			t.Errorf("%s was extracted but is not a world", unwanted)
+		}
+	}
+
+	// Verify the web output structure.
+	for _, want := range []string{
+		"world/level.dat",
+		"world/region/r.0.0.mca",
+		"web/index.html",
+		"web/settings.json",
+		"web/netlify.toml",
+		"web/lang/en.conf",
+		"web/maps/overworld",
+	} {
+		if _, err := os.Stat(filepath.Join(serverDir, want)); err != nil {
+			t.Errorf("expected %s: %v", want, err)
+		}
+	}
+
+	tiles, _ := filepath.Glob(filepath.Join(serverDir, "web", "maps", "overworld", "tiles", "*", "*"))
+	if len(tiles) == 0 {
+		t.Errorf("no tiles rendered for the overworld map")
+	}
+
+	bundles, _ := filepath.Glob(filepath.Join(serverDir, "web", "assets", "index-*.js"))
+	if len(bundles) == 0 {
+		t.Fatalf("no web/assets/index-*.js bundle generated")
+	}
+	for _, b := range bundles {
+		data, err := os.ReadFile(b)
+		if err != nil {
+			t.Fatalf("reading %s: %v", b, err)
+		}
+		if !strings.Contains(string(data), ".prbm.gz") {
+			t.Errorf("%s does not reference .prbm.gz after the asset rewrite", filepath.Base(b))
+		}
+	}
+}
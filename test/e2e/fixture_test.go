@@ -0,0 +1,218 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// NBT tag type IDs used by the fixture.
+const (
+	tagEnd      = 0
+	tagByte     = 1
+	tagInt      = 3
+	tagLong     = 4
+	tagString   = 8
+	tagList     = 9
+	tagCompound = 10
+)
+
+// nbtWriter writes the subset of big-endian NBT needed for a minimal world.
+type nbtWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *nbtWriter) name(tag byte, name string) {
+	w.buf.WriteByte(tag)
+	w.str(name)
+}
+
+func (w *nbtWriter) str(s string) {
+	binary.Write(&w.buf, binary.BigEndian, uint16(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *nbtWriter) byteTag(name string, v int8) {
+	w.name(tagByte, name)
+	w.buf.WriteByte(byte(v))
+}
+
+func (w *nbtWriter) intTag(name string, v int32) {
+	w.name(tagInt, name)
+	binary.Write(&w.buf, binary.BigEndian, v)
+}
+
+func (w *nbtWriter) longTag(name string, v int64) {
+	w.name(tagLong, name)
+	binary.Write(&w.buf, binary.BigEndian, v)
+}
+
+func (w *nbtWriter) stringTag(name, v string) {
+	w.name(tagString, name)
+	w.str(v)
+}
+
+func (w *nbtWriter) compound(name string, body func()) {
+	w.name(tagCompound, name)
+	body()
+	w.buf.WriteByte(tagEnd)
+}
+
+// compoundList writes a list of n compounds, calling body(i) for each.
+func (w *nbtWriter) compoundList(name string, n int, body func(i int)) {
+	w.name(tagList, name)
+	w.buf.WriteByte(tagCompound)
+	binary.Write(&w.buf, binary.BigEndian, int32(n))
+	for i := 0; i < n; i++ {
+		body(i)
+		w.buf.WriteByte(tagEnd)
+	}
+}
+
+// stringList writes a list of strings.
+func (w *nbtWriter) stringList(name string, values ...string) {
+	w.name(tagList, name)
+	w.buf.WriteByte(tagString)
+	binary.Write(&w.buf, binary.BigEndian, int32(len(values)))
+	for _, v := range values {
+		w.str(v)
+	}
+}
+
+// dataVersion is the world data version of Minecraft 1.21.1.
+const dataVersion = 3955
+
+// levelDat returns a gzip-compressed level.dat for a flat test world.
+func levelDat(t *testing.T) []byte {
+	t.Helper()
+	var w nbtWriter
+	w.compound("", func() {
+		w.compound("Data", func() {
+			w.intTag("DataVersion", dataVersion)
+			w.stringTag("LevelName", "e2e")
+			w.intTag("SpawnX", 0)
+			w.intTag("SpawnY", 64)
+			w.intTag("SpawnZ", 0)
+			w.longTag("Time", 0)
+			w.compound("Version", func() {
+				w.intTag("Id", dataVersion)
+				w.stringTag("Name", "1.21.1")
+			})
+		})
+	})
+
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	gz.Write(w.buf.Bytes())
+	if err := gz.Close(); err != nil {
+		t.Fatalf("compressing level.dat: %v", err)
+	}
+	return out.Bytes()
+}
+
+// chunkNBT returns the uncompressed NBT of a fully generated chunk whose
+// section at Y=3 (blocks 48–63) is solid stone and everything else is air.
+func chunkNBT(x, z int32) []byte {
+	var w nbtWriter
+	w.compound("", func() {
+		w.intTag("DataVersion", dataVersion)
+		w.intTag("xPos", x)
+		w.intTag("zPos", z)
+		w.intTag("yPos", -4)
+		w.stringTag("Status", "minecraft:full")
+		w.longTag("InhabitedTime", 0)
+		w.compoundList("sections", 1, func(int) {
+			w.byteTag("Y", 3)
+			w.compound("block_states", func() {
+				w.compoundList("palette", 1, func(int) {
+					w.stringTag("Name", "minecraft:stone")
+				})
+			})
+			w.compound("biomes", func() {
+				w.stringList("palette", "minecraft:plains")
+			})
+		})
+	})
+	return w.buf.Bytes()
+}
+
+// regionFile builds r.0.0.mca containing the chunks [0,size)×[0,size).
+func regionFile(t *testing.T, size int) []byte {
+	t.Helper()
+	const sector = 4096
+
+	header := make([]byte, 2*sector) // location table + timestamp table
+	var body bytes.Buffer
+	nextSector := 2
+
+	for z := 0; z < size; z++ {
+		for x := 0; x < size; x++ {
+			var compressed bytes.Buffer
+			zw := zlib.NewWriter(&compressed)
+			zw.Write(chunkNBT(int32(x), int32(z)))
+			if err := zw.Close(); err != nil {
+				t.Fatalf("compressing chunk: %v", err)
+			}
+
+			var chunk bytes.Buffer
+			binary.Write(&chunk, binary.BigEndian, uint32(compressed.Len()+1))
+			chunk.WriteByte(2) // zlib
+			chunk.Write(compressed.Bytes())
+			sectors := (chunk.Len() + sector - 1) / sector
+			chunk.Write(make([]byte, sectors*sector-chunk.Len()))
+
+			idx := 4 * (x + z*32)
+			header[idx] = byte(nextSector >> 16)
+			header[idx+1] = byte(nextSector >> 8)
+			header[idx+2] = byte(nextSector)
+			header[idx+3] = byte(sectors)
+			binary.BigEndian.PutUint32(header[sector+idx:], 1)
+
+			body.Write(chunk.Bytes())
+			nextSector += sectors
+		}
+	}
+
+	return append(header, body.Bytes()...)
+}
+
+// backupArchive returns a tar.gz shaped like a Pterodactyl backup: the world
+// folder plus unrelated server files that must not be extracted.
+func backupArchive(t *testing.T) []byte {
+	t.Helper()
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"server.properties", []byte("level-name=world\n")},
+		{"world/level.dat", levelDat(t)},
+		{"world/region/r.0.0.mca", regionFile(t, 4)},
+		{"logs/latest.log", []byte("irrelevant\n")},
+	}
+
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("writing tar header: %v", err)
+		}
+		if _, err := io.Copy(tw, bytes.NewReader(f.data)); err != nil {
+			t.Fatalf("writing tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("closing tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("closing gzip: %v", err)
+	}
+	return out.Bytes()
+}